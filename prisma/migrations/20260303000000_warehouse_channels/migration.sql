@@ -0,0 +1,2 @@
+ALTER TYPE "public"."channel_type" ADD VALUE IF NOT EXISTS 'clickhouse';
+ALTER TYPE "public"."channel_type" ADD VALUE IF NOT EXISTS 'bigquery';
@@ -0,0 +1 @@
+ALTER TABLE "public"."jobs" ADD COLUMN "timezone" TEXT;
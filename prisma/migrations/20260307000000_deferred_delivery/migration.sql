@@ -0,0 +1,3 @@
+ALTER TABLE "public"."jobs" ADD COLUMN "delivery_delay_minutes" INTEGER;
+ALTER TABLE "public"."run_histories" ADD COLUMN "deliver_at" TIMESTAMPTZ(6);
+CREATE INDEX "idx_run_histories_deliver_at" ON "public"."run_histories"("deliver_at");
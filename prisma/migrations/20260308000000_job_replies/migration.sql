@@ -0,0 +1,22 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "accept_replies" BOOLEAN NOT NULL DEFAULT false;
+
+-- CreateTable
+CREATE TABLE "public"."job_replies" (
+    "id" UUID NOT NULL,
+    "job_id" UUID NOT NULL,
+    "source" TEXT NOT NULL,
+    "author" TEXT,
+    "text" TEXT NOT NULL,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "consumed_at" TIMESTAMPTZ(6),
+    "run_history_id" UUID,
+
+    CONSTRAINT "job_replies_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_job_replies_job_id_consumed_at" ON "public"."job_replies"("job_id", "consumed_at");
+
+-- AddForeignKey
+ALTER TABLE "public"."job_replies" ADD CONSTRAINT "job_replies_job_id_fkey" FOREIGN KEY ("job_id") REFERENCES "public"."jobs"("id") ON DELETE CASCADE ON UPDATE CASCADE;
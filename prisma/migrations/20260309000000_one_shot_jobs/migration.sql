@@ -0,0 +1,3 @@
+ALTER TYPE "public"."schedule_type" ADD VALUE IF NOT EXISTS 'once';
+ALTER TABLE "public"."jobs" ADD COLUMN "run_at" TIMESTAMPTZ(6);
+ALTER TABLE "public"."jobs" ADD COLUMN "completed_at" TIMESTAMPTZ(6);
@@ -0,0 +1,3 @@
+ALTER TABLE "public"."jobs" ADD COLUMN "tags" TEXT[] DEFAULT ARRAY[]::TEXT[];
+ALTER TABLE "public"."run_histories" ADD COLUMN "tags" TEXT[] DEFAULT ARRAY[]::TEXT[];
+CREATE INDEX "idx_run_histories_tags" ON "public"."run_histories" USING GIN ("tags");
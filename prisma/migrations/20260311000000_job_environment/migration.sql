@@ -0,0 +1 @@
+ALTER TABLE "public"."jobs" ADD COLUMN "environment" TEXT NOT NULL DEFAULT 'production';
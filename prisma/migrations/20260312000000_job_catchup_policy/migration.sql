@@ -0,0 +1 @@
+ALTER TABLE "public"."jobs" ADD COLUMN "catchup_policy" TEXT NOT NULL DEFAULT 'run_once';
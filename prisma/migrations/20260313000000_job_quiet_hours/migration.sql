@@ -0,0 +1,3 @@
+ALTER TABLE "public"."jobs" ADD COLUMN "quiet_hours_start" TEXT;
+ALTER TABLE "public"."jobs" ADD COLUMN "quiet_hours_end" TEXT;
+ALTER TABLE "public"."jobs" ADD COLUMN "blackout_dates" TEXT[] DEFAULT ARRAY[]::TEXT[];
@@ -0,0 +1 @@
+ALTER TABLE "public"."jobs" ADD COLUMN "retry_attempt" INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,23 @@
+-- CreateTable
+CREATE TABLE "public"."dead_letters" (
+    "id" UUID NOT NULL,
+    "job_id" UUID NOT NULL,
+    "run_history_id" UUID,
+    "reason" TEXT NOT NULL,
+    "output_text" TEXT,
+    "error_chain" JSONB NOT NULL,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "requeued_at" TIMESTAMPTZ(6),
+    "requeue_run_history_id" UUID,
+
+    CONSTRAINT "dead_letters_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_dead_letters_job_id_created_at" ON "public"."dead_letters"("job_id", "created_at");
+
+-- AddForeignKey
+ALTER TABLE "public"."dead_letters" ADD CONSTRAINT "dead_letters_job_id_fkey" FOREIGN KEY ("job_id") REFERENCES "public"."jobs"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."dead_letters" ADD CONSTRAINT "dead_letters_run_history_id_fkey" FOREIGN KEY ("run_history_id") REFERENCES "public"."run_histories"("id") ON DELETE SET NULL ON UPDATE CASCADE;
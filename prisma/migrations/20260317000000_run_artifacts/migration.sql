@@ -0,0 +1,24 @@
+-- CreateTable
+CREATE TABLE "public"."run_artifacts" (
+    "id" UUID NOT NULL,
+    "run_history_id" UUID NOT NULL,
+    "name" TEXT NOT NULL,
+    "media_type" TEXT NOT NULL,
+    "size_bytes" INTEGER NOT NULL,
+    "storage" TEXT NOT NULL DEFAULT 'db',
+    "storage_key" TEXT,
+    "content" BYTEA,
+    "expires_at" TIMESTAMPTZ(6) NOT NULL,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "run_artifacts_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_run_artifacts_run_history_id" ON "public"."run_artifacts"("run_history_id");
+
+-- CreateIndex
+CREATE INDEX "idx_run_artifacts_expires_at" ON "public"."run_artifacts"("expires_at");
+
+-- AddForeignKey
+ALTER TABLE "public"."run_artifacts" ADD CONSTRAINT "run_artifacts_run_history_id_fkey" FOREIGN KEY ("run_history_id") REFERENCES "public"."run_histories"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "weekdays_only" BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,7 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "prompt_tokens" INTEGER,
+ADD COLUMN "completion_tokens" INTEGER,
+ADD COLUMN "cost_usd" DOUBLE PRECISION;
+
+-- CreateIndex
+CREATE INDEX "idx_run_histories_run_at" ON "public"."run_histories"("run_at");
@@ -0,0 +1,16 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "outage_notice" BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN "outage_notified_for" TIMESTAMPTZ(6);
+
+-- CreateTable
+CREATE TABLE "public"."provider_health" (
+    "provider" TEXT NOT NULL,
+    "window_start" TIMESTAMPTZ(6) NOT NULL,
+    "calls" INTEGER NOT NULL DEFAULT 0,
+    "failures" INTEGER NOT NULL DEFAULT 0,
+    "degraded_since" TIMESTAMPTZ(6),
+    "last_probe_at" TIMESTAMPTZ(6),
+    "updated_at" TIMESTAMPTZ(6) NOT NULL,
+
+    CONSTRAINT "provider_health_pkey" PRIMARY KEY ("provider")
+);
@@ -0,0 +1,6 @@
+-- AlterEnum
+ALTER TYPE "public"."run_status" ADD VALUE 'budget_exceeded';
+
+-- AlterTable
+ALTER TABLE "public"."users" ADD COLUMN "override_monthly_budget_usd" DOUBLE PRECISION,
+ADD COLUMN "budget_notified_month" TEXT;
@@ -0,0 +1,3 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "routed_model" TEXT,
+ADD COLUMN "model_upgraded" BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."delivery_attempts" ADD COLUMN "rendered_message" JSONB;
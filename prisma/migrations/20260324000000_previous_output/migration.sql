@@ -0,0 +1,3 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "include_previous_output" BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN "previous_output_count" INTEGER NOT NULL DEFAULT 1;
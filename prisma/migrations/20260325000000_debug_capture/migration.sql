@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "debug_runs_remaining" INTEGER NOT NULL DEFAULT 0;
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "debug_capture" JSONB;
@@ -0,0 +1,7 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "deliver_if" TEXT NOT NULL DEFAULT 'always',
+ADD COLUMN "deliver_if_pattern" TEXT;
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "output_hash" TEXT,
+ADD COLUMN "delivery_skip_reason" TEXT;
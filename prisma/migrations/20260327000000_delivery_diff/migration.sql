@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "delivery_diff" TEXT NOT NULL DEFAULT 'off';
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "output_diff" TEXT;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "failure_notice" BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,9 @@
+-- AlterTable
+ALTER TABLE "public"."users" ADD COLUMN "last_seen_at" TIMESTAMPTZ(6);
+
+-- Existing accounts start their inactivity window at deploy time.
+UPDATE "public"."users" SET "last_seen_at" = now();
+
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "dormant_reason" TEXT,
+ADD COLUMN "dormant_at" TIMESTAMPTZ(6);
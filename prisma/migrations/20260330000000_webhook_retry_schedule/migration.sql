@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "webhook_retry_schedule" TEXT;
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "delivery_retries" INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+-- AlterEnum
+ALTER TYPE "public"."run_status" ADD VALUE 'partial_delivery';
+ALTER TYPE "public"."run_status" ADD VALUE 'skipped_quota';
+ALTER TYPE "public"."run_status" ADD VALUE 'skipped_unchanged';
+ALTER TYPE "public"."run_status" ADD VALUE 'blocked_moderation';
+ALTER TYPE "public"."run_status" ADD VALUE 'cancelled';
+ALTER TYPE "public"."run_status" ADD VALUE 'timeout';
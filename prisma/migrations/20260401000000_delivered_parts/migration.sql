@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "delivered_parts" INTEGER NOT NULL DEFAULT 0;
+
+-- AlterTable
+ALTER TABLE "public"."delivery_attempts" ADD COLUMN "parts_delivered" INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,28 @@
+-- CreateTable
+CREATE TABLE "public"."run_requests" (
+    "id" UUID NOT NULL,
+    "job_id" UUID NOT NULL,
+    "user_id" UUID NOT NULL,
+    "status" TEXT NOT NULL DEFAULT 'pending',
+    "run_history_id" UUID,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "started_at" TIMESTAMPTZ(6),
+    "finished_at" TIMESTAMPTZ(6),
+
+    CONSTRAINT "run_requests_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "run_requests_run_history_id_key" ON "public"."run_requests"("run_history_id");
+
+-- CreateIndex
+CREATE INDEX "idx_run_requests_status_created_at" ON "public"."run_requests"("status", "created_at");
+
+-- CreateIndex
+CREATE INDEX "idx_run_requests_job_id_created_at" ON "public"."run_requests"("job_id", "created_at");
+
+-- AddForeignKey
+ALTER TABLE "public"."run_requests" ADD CONSTRAINT "run_requests_job_id_fkey" FOREIGN KEY ("job_id") REFERENCES "public"."jobs"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."run_requests" ADD CONSTRAINT "run_requests_run_history_id_fkey" FOREIGN KEY ("run_history_id") REFERENCES "public"."run_histories"("id") ON DELETE SET NULL ON UPDATE CASCADE;
@@ -0,0 +1,10 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "quality_sample_rate" INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN "quality_rubric" TEXT,
+ADD COLUMN "quality_alerted_for" TEXT,
+ADD COLUMN "quality_degraded_at" TIMESTAMPTZ(6);
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "quality_score" DOUBLE PRECISION,
+ADD COLUMN "quality_reason" TEXT,
+ADD COLUMN "quality_judge_model" TEXT;
@@ -0,0 +1,7 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "canary_cohort" TEXT,
+ADD COLUMN "cohort_model" TEXT,
+ADD COLUMN "llm_duration_ms" INTEGER;
+
+-- CreateIndex
+CREATE INDEX "idx_run_histories_canary_cohort" ON "public"."run_histories"("canary_cohort", "run_at");
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "full_output_link" BOOLEAN NOT NULL DEFAULT false;
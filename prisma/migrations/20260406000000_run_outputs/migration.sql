@@ -0,0 +1,13 @@
+-- CreateTable
+CREATE TABLE "public"."run_outputs" (
+    "run_history_id" UUID NOT NULL,
+    "content" BYTEA NOT NULL,
+    "size_bytes" INTEGER NOT NULL,
+    "truncated" BOOLEAN NOT NULL DEFAULT false,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "run_outputs_pkey" PRIMARY KEY ("run_history_id")
+);
+
+-- AddForeignKey
+ALTER TABLE "public"."run_outputs" ADD CONSTRAINT "run_outputs_run_history_id_fkey" FOREIGN KEY ("run_history_id") REFERENCES "public"."run_histories"("id") ON DELETE CASCADE ON UPDATE CASCADE;
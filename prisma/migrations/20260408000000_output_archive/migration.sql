@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "archive_key" TEXT;
@@ -0,0 +1,9 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "throttle_limit" INTEGER,
+ADD COLUMN "throttle_window" TEXT NOT NULL DEFAULT 'hour';
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "throttled_at" TIMESTAMPTZ(6);
+
+-- CreateIndex
+CREATE INDEX "idx_run_histories_throttled_at" ON "public"."run_histories"("throttled_at");
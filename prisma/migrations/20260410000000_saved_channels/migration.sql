@@ -0,0 +1,28 @@
+-- CreateTable
+CREATE TABLE "public"."saved_channels" (
+    "id" UUID NOT NULL,
+    "user_id" UUID NOT NULL,
+    "name" TEXT NOT NULL,
+    "channel_type" "public"."channel_type" NOT NULL,
+    "channel_config" JSONB NOT NULL,
+    "is_default" BOOLEAN NOT NULL DEFAULT false,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMPTZ(6) NOT NULL,
+
+    CONSTRAINT "saved_channels_pkey" PRIMARY KEY ("id")
+);
+
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "channel_id" UUID;
+
+-- CreateIndex
+CREATE UNIQUE INDEX "uniq_saved_channels_user_name" ON "public"."saved_channels"("user_id", "name");
+
+-- CreateIndex
+CREATE INDEX "idx_jobs_channel_id" ON "public"."jobs"("channel_id");
+
+-- AddForeignKey
+ALTER TABLE "public"."saved_channels" ADD CONSTRAINT "saved_channels_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "public"."jobs" ADD CONSTRAINT "jobs_channel_id_fkey" FOREIGN KEY ("channel_id") REFERENCES "public"."saved_channels"("id") ON DELETE SET NULL ON UPDATE CASCADE;
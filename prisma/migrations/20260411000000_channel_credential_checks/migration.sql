@@ -0,0 +1,3 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "credential_checked_at" TIMESTAMPTZ(6),
+ADD COLUMN "credential_error" TEXT;
@@ -0,0 +1,16 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "locked_by" TEXT;
+
+-- CreateTable
+CREATE TABLE "public"."worker_heartbeats" (
+    "id" TEXT NOT NULL,
+    "hostname" TEXT NOT NULL,
+    "environment" TEXT NOT NULL,
+    "started_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "last_heartbeat_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "worker_heartbeats_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_worker_heartbeats_last_heartbeat_at" ON "public"."worker_heartbeats"("last_heartbeat_at");
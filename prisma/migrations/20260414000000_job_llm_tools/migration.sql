@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "llm_tools" JSONB NOT NULL DEFAULT '[]';
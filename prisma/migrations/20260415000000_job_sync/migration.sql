@@ -0,0 +1,6 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "sync_key" TEXT,
+ADD COLUMN "sync_hash" TEXT;
+
+-- CreateIndex
+CREATE UNIQUE INDEX "uniq_jobs_user_sync_key" ON "public"."jobs"("user_id", "sync_key");
@@ -0,0 +1,4 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "expected_runtime_seconds" INTEGER,
+ADD COLUMN "slow_run_notice" BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN "slow_run_notified_at" TIMESTAMPTZ(6);
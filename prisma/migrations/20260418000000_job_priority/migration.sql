@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "priority" INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "concurrency_group" TEXT;
+
+-- CreateIndex
+CREATE INDEX "idx_jobs_user_id_concurrency_group" ON "public"."jobs"("user_id", "concurrency_group");
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."users" ADD COLUMN "qa_sharing" BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,9 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "depends_on_job_id" UUID,
+ADD COLUMN "include_upstream_output" BOOLEAN NOT NULL DEFAULT false;
+
+-- CreateIndex
+CREATE INDEX "idx_jobs_depends_on_job_id" ON "public"."jobs"("depends_on_job_id");
+
+-- AddForeignKey
+ALTER TABLE "public"."jobs" ADD CONSTRAINT "jobs_depends_on_job_id_fkey" FOREIGN KEY ("depends_on_job_id") REFERENCES "public"."jobs"("id") ON DELETE SET NULL ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "inputs" JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "max_output_tokens" INTEGER,
+ADD COLUMN "temperature" DOUBLE PRECISION,
+ADD COLUMN "reasoning_effort" TEXT,
+ADD COLUMN "verbosity" TEXT;
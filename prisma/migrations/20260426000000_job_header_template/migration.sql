@@ -0,0 +1,4 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "show_header" BOOLEAN NOT NULL DEFAULT true,
+ADD COLUMN "header_template" TEXT,
+ADD COLUMN "header_locale" TEXT;
@@ -0,0 +1 @@
+ALTER TYPE "public"."channel_type" ADD VALUE IF NOT EXISTS 'pushover';
@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "fallback_models" TEXT[] DEFAULT ARRAY[]::TEXT[];
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "model_fallback" BOOLEAN NOT NULL DEFAULT false;
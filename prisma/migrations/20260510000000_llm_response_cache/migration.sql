@@ -0,0 +1,18 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "response_cache_ttl_minutes" INTEGER;
+
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "cache_hit" BOOLEAN NOT NULL DEFAULT false;
+
+-- CreateTable
+CREATE TABLE "public"."llm_response_cache" (
+    "key" TEXT NOT NULL,
+    "response" JSONB NOT NULL,
+    "expires_at" TIMESTAMPTZ(6) NOT NULL,
+    "created_at" TIMESTAMPTZ(6) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT "llm_response_cache_pkey" PRIMARY KEY ("key")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_llm_response_cache_expires_at" ON "public"."llm_response_cache"("expires_at");
@@ -0,0 +1,4 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "llm_attempts" INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN "delivery_duration_ms" INTEGER,
+ADD COLUMN "failure_stage" TEXT;
@@ -0,0 +1,4 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "auto_disable_threshold" INTEGER,
+ADD COLUMN "auto_disable_policy" TEXT,
+ADD COLUMN "auto_paused_until" TIMESTAMPTZ(6);
@@ -0,0 +1,3 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "response_status" INTEGER,
+ADD COLUMN "response_body" TEXT;
@@ -0,0 +1,5 @@
+-- AlterEnum
+ALTER TYPE "public"."run_status" ADD VALUE 'config_error';
+
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "config_error" TEXT;
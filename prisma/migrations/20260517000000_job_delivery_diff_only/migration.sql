@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "public"."jobs" ADD COLUMN "delivery_diff_only" BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "delivery_title" TEXT,
+ADD COLUMN "delivery_body" TEXT;
@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "public"."run_histories" ADD COLUMN "redelivered_from_id" TEXT;
+
+-- CreateIndex
+CREATE INDEX "idx_run_histories_redelivered_from_id" ON "public"."run_histories"("redelivered_from_id");
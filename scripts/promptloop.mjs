@@ -0,0 +1,445 @@
+// Operator CLI for a running promptloop deployment. Talks to the /api/cron endpoints with CRON_SECRET.
+//
+//   node scripts/promptloop.mjs start --config ./promptloop.toml   start the server with a settings file
+//   node scripts/promptloop.mjs worker [--interval 60]   run worker ticks in a loop, adapting to the queue
+//   node scripts/promptloop.mjs run --job-id <id>         run one job now and print its output
+//   node scripts/promptloop.mjs validate                  check every job's schedule and channel config
+//   node scripts/promptloop.mjs doctor                    preflight readiness report (DB, keys, providers, hosts)
+//   node scripts/promptloop.mjs next-runs [--limit 20]    print the upcoming schedule
+//   node scripts/promptloop.mjs canary [--days 7]         compare model canary cohorts
+//   node scripts/promptloop.mjs loadtest [--jobs 100]     measure worker throughput with synthetic jobs
+//   node scripts/promptloop.mjs sync --dir ./jobs --owner <email>   reconcile jobs with YAML specs (or --git <url>)
+//
+// PROMPTLOOP_URL (default http://localhost:3000) selects the deployment.
+
+import { execFileSync, spawn } from "node:child_process";
+import { createHash } from "node:crypto";
+import { existsSync, readdirSync, readFileSync } from "node:fs";
+import { createServer } from "node:http";
+import { tmpdir } from "node:os";
+import { dirname, join, relative, resolve } from "node:path";
+import { fileURLToPath } from "node:url";
+
+import { nextPollDelayMs } from "./worker-loop.mjs";
+
+const baseUrl = (process.env.PROMPTLOOP_URL || "http://localhost:3000").replace(/\/$/, "");
+const secret = process.env.CRON_SECRET || "";
+
+const USAGE = `Usage: promptloop <command> [options]
+
+Commands:
+  start [--config <path>] [--port <n>]
+                                  start the server (next start); --config loads a .toml, .yaml or .json settings
+                                  file, the same as PROMPTLOOP_CONFIG, and env vars still override it
+  worker [--interval <seconds>] [--max-interval <seconds>]
+                                  run worker ticks in a loop: every 60s by default, right away while due jobs
+                                  are left over, backing off up to --max-interval (default 300s) while idle
+  run --job-id <id>               run one job now and print its output
+  validate                        check all job schedules and channel configs
+  doctor                          check database and schema, encryption key, provider keys and channel hosts
+  next-runs [--limit <n>]         print upcoming scheduled runs
+  canary [--days <n>]             compare the model canary with its control cohort
+  loadtest [--jobs <n>] [--workers <n>] [--channel-latency <ms>] [--timeout <seconds>] [--channel-host <host>] [--keep 1]
+                                  seed synthetic jobs (mock model, local fake channel) and measure throughput
+                                  and lock contention; needs LOADTEST_ENABLED=1 on the deployment
+  sync --owner <email> (--dir <path> | --git <url> [--ref <branch>] [--path <subdir>]) [--watch <seconds>] [--dry-run 1]
+                                  reconcile the owner's managed jobs with a directory or Git repo of job specs
+                                  (create, update, disable); --watch repeats it, pulling the repo each time`;
+
+function parseArgs(argv) {
+  const [command, ...rest] = argv;
+  const flags = {};
+  for (let i = 0; i < rest.length; i++) {
+    const arg = rest[i];
+    if (!arg.startsWith("--")) {
+      throw new Error(`Unexpected argument: ${arg}`);
+    }
+    const [name, inline] = arg.slice(2).split("=", 2);
+    flags[name] = inline ?? rest[++i];
+    if (flags[name] === undefined) {
+      throw new Error(`Missing value for --${name}`);
+    }
+  }
+  return { command, flags };
+}
+
+async function call(method, path, body) {
+  const headers = secret ? { authorization: `Bearer ${secret}` } : {};
+  if (body !== undefined) {
+    headers["content-type"] = "application/json";
+  }
+  const res = await fetch(baseUrl + path, { method, headers, body: body === undefined ? undefined : JSON.stringify(body) });
+  const text = await res.text();
+  let data = null;
+  try {
+    data = JSON.parse(text);
+  } catch {
+    // Non-JSON error pages are reported as text below.
+  }
+  if (res.status === 401) {
+    throw new Error("Unauthorized: set CRON_SECRET to the deployment's value");
+  }
+  if (!data) {
+    throw new Error(`${method} ${path}: ${res.status} ${text.slice(0, 200)}`);
+  }
+  return { status: res.status, data };
+}
+
+const sleep = (ms) => new Promise((resolve) => setTimeout(resolve, ms));
+
+async function worker(flags) {
+  const interval = Number(flags.interval ?? 60);
+  if (!Number.isFinite(interval) || interval < 1) {
+    throw new Error("--interval must be at least 1 second");
+  }
+  const maxInterval = Number(flags["max-interval"] ?? Math.max(interval, 300));
+  if (!Number.isFinite(maxInterval) || maxInterval < interval) {
+    throw new Error("--max-interval must be at least --interval");
+  }
+  let stopping = false;
+  for (const signal of ["SIGINT", "SIGTERM"]) {
+    process.once(signal, () => {
+      stopping = true;
+      console.error(`${signal}: stopping after the current tick`);
+    });
+  }
+  let idleMs = 0;
+  while (!stopping) {
+    const startedAt = Date.now();
+    let data = null;
+    try {
+      ({ data } = await call("GET", "/api/cron/run-jobs"));
+      console.log(new Date().toISOString(), JSON.stringify(data));
+    } catch (err) {
+      console.error(new Date().toISOString(), err instanceof Error ? err.message : err);
+    }
+    const next = nextPollDelayMs(data, idleMs, interval * 1000, maxInterval * 1000);
+    idleMs = next.idleMs;
+    const wait = next.waitMs - (Date.now() - startedAt);
+    for (let waited = 0; !stopping && waited < wait; waited += 250) {
+      await sleep(250);
+    }
+  }
+  return 0;
+}
+
+async function run(flags) {
+  const jobId = flags["job-id"];
+  if (!jobId) {
+    throw new Error("run needs --job-id <id>");
+  }
+  const { status, data } = await call("POST", `/api/cron/jobs/${encodeURIComponent(jobId)}/run`);
+  if (status === 404) {
+    throw new Error(`Job ${jobId} not found`);
+  }
+  if (data.status === "queued") {
+    console.error(`Job ${jobId} is locked by another worker; queued as run request ${data.requestId}`);
+    return 2;
+  }
+  const runInfo = data.run ?? {};
+  console.error(`status: ${runInfo.status ?? data.status}${runInfo.llmModel ? ` · model: ${runInfo.llmModel}` : ""}`);
+  if (runInfo.errorMessage) {
+    console.error(`error: ${runInfo.errorMessage}`);
+  }
+  if (runInfo.outputText) {
+    console.log(runInfo.outputText);
+  }
+  return data.ok ? 0 : 1;
+}
+
+async function validate() {
+  const { data } = await call("GET", "/api/cron/validate");
+  for (const job of data.failing) {
+    console.log(`${job.id} ${JSON.stringify(job.name)}${job.enabled ? "" : " (disabled)"}`);
+    for (const problem of job.problems) {
+      console.log(`  - ${problem}`);
+    }
+  }
+  console.log(`${data.checked} jobs checked, ${data.failing.length} with problems`);
+  return data.failing.length ? 1 : 0;
+}
+
+const DOCTOR_MARKS = { ok: "ok  ", warn: "WARN", fail: "FAIL" };
+
+async function doctor() {
+  let data;
+  try {
+    ({ data } = await call("GET", "/api/cron/doctor"));
+  } catch (err) {
+    console.log(`${DOCTOR_MARKS.fail}  deployment: ${err instanceof Error ? err.message : err} (${baseUrl})`);
+    return 1;
+  }
+  console.log(`${DOCTOR_MARKS.ok}  deployment: ${baseUrl}`);
+  for (const check of data.checks) {
+    console.log(`${DOCTOR_MARKS[check.status]}  ${check.name}: ${check.detail}`);
+  }
+  console.log(data.ok ? "ready" : "not ready");
+  return data.ok ? 0 : 1;
+}
+
+async function nextRuns(flags) {
+  const limit = flags.limit ?? "20";
+  const { data } = await call("GET", `/api/cron/next-runs?limit=${encodeURIComponent(limit)}`);
+  for (const job of data.runs) {
+    const schedule = job.scheduleType === "cron" ? `cron ${job.scheduleCron}` : `${job.scheduleType} ${job.scheduleTime}`;
+    const locked = job.lockedAt ? " [running]" : "";
+    console.log(`${job.nextRunAt}  ${job.id}  ${JSON.stringify(job.name)}  ${schedule} ${job.timezone ?? "UTC"}  ${job.environment}${locked}`);
+  }
+  return 0;
+}
+
+async function canary(flags) {
+  const days = flags.days ?? "7";
+  const { data } = await call("GET", `/api/cron/canary?days=${encodeURIComponent(days)}`);
+  if (data.config) {
+    console.log(`canary: ${data.config.canaryModel} on ${data.config.canaryPercent}% of ${data.config.baseModel} runs`);
+  } else {
+    console.log("canary: not configured (showing recorded cohorts only)");
+  }
+  const fmt = (value, prefix = "", suffix = "") => (value == null ? "-" : `${prefix}${value}${suffix}`);
+  for (const row of data.cohorts) {
+    console.log(
+      `${row.cohort.padEnd(8)} ${row.model.padEnd(20)} runs ${row.runs}  success ${(row.successRate * 100).toFixed(1)}%  fallbacks ${row.fellBack}  ` +
+        `cost ${fmt(row.avgCostUsd, "$")}  latency ${fmt(row.avgLlmDurationMs, "", "ms")}  quality ${fmt(row.avgQualityScore)}`,
+    );
+  }
+  return 0;
+}
+
+function positiveFlag(flags, name, fallback, min = 1) {
+  const value = Number(flags[name] ?? fallback);
+  if (!Number.isFinite(value) || value < min) {
+    throw new Error(`--${name} must be at least ${min}`);
+  }
+  return Math.floor(value);
+}
+
+function percentile(sorted, p) {
+  return sorted.length ? sorted[Math.min(sorted.length - 1, Math.floor((sorted.length * p) / 100))] : 0;
+}
+
+// Starts a fake channel on this machine, seeds --jobs one-shot jobs that deliver to it, and drives the deployment
+// with --workers parallel worker loops until every job ran (or --timeout). Reports end-to-end throughput, delivery
+// latency, duplicate deliveries, and lock contention sampled from Postgres.
+async function loadtest(flags) {
+  const jobs = positiveFlag(flags, "jobs", 100);
+  const workers = positiveFlag(flags, "workers", 2);
+  const channelLatency = positiveFlag(flags, "channel-latency", 0, 0);
+  const timeoutMs = positiveFlag(flags, "timeout", 300) * 1000;
+  const host = flags["channel-host"] ?? "127.0.0.1";
+
+  const deliveries = new Map();
+  let received = 0;
+  const server = createServer((req, res) => {
+    let body = "";
+    req.on("data", (chunk) => (body += chunk));
+    req.on("end", () => {
+      const at = Date.now();
+      let jobId = "unknown";
+      try {
+        jobId = JSON.parse(body).meta?.jobId ?? jobId;
+      } catch {
+        // Counted under "unknown".
+      }
+      received++;
+      deliveries.set(jobId, [...(deliveries.get(jobId) ?? []), at]);
+      setTimeout(() => res.writeHead(204).end(), channelLatency);
+    });
+  });
+  await new Promise((resolve) => server.listen(Number(flags.port ?? 0), "0.0.0.0", resolve));
+  const webhookUrl = `http://${host}:${server.address().port}/hook`;
+
+  let batch = null;
+  try {
+    const seededAt = Date.now();
+    const { status, data: seed } = await call("POST", "/api/cron/loadtest", { jobs, webhookUrl });
+    if (status !== 200) {
+      throw new Error(`seeding failed: ${seed.error ?? status}`);
+    }
+    batch = seed.batch;
+    console.error(`seeded ${jobs} jobs (batch ${batch}), fake channel at ${webhookUrl}`);
+
+    const ticks = { count: 0, processed: 0, duplicates: 0, empty: 0, errors: 0 };
+    let done = false;
+    const workerLoop = async () => {
+      while (!done) {
+        try {
+          const { data } = await call("GET", "/api/cron/run-jobs");
+          ticks.count++;
+          ticks.processed += data.processed ?? 0;
+          ticks.duplicates += data.duplicates ?? 0;
+          if (!data.processed) {
+            ticks.empty++;
+            await sleep(250);
+          }
+        } catch (err) {
+          ticks.errors++;
+          console.error(err instanceof Error ? err.message : err);
+          await sleep(1000);
+        }
+      }
+    };
+    const loops = Array.from({ length: workers }, () => workerLoop());
+
+    let progress = null;
+    let maxLockWaits = 0;
+    let maxLocked = 0;
+    while (Date.now() - seededAt < timeoutMs) {
+      await sleep(1000);
+      ({ data: progress } = await call("GET", `/api/cron/loadtest?batch=${encodeURIComponent(batch)}`));
+      maxLockWaits = Math.max(maxLockWaits, progress.lockWaits);
+      maxLocked = Math.max(maxLocked, progress.locked);
+      console.error(`pending ${progress.pending}/${jobs}  locked ${progress.locked}  delivered ${deliveries.size}  lock waits ${progress.lockWaits}`);
+      if (progress.pending === 0 && progress.locked === 0) {
+        break;
+      }
+    }
+    done = true;
+    await Promise.all(loops);
+
+    const elapsedMs = Date.now() - seededAt;
+    const latencies = [...deliveries.entries()]
+      .filter(([jobId]) => jobId !== "unknown")
+      .map(([, times]) => times[0] - seededAt)
+      .sort((a, b) => a - b);
+    const duplicated = [...deliveries.values()].filter((times) => times.length > 1).length;
+    const succeeded = progress?.runs?.success ?? 0;
+    console.log(`jobs            ${jobs} (${progress?.pending ?? jobs} still pending)`);
+    console.log(`runs            ${JSON.stringify(progress?.runs ?? {})}`);
+    console.log(`elapsed         ${(elapsedMs / 1000).toFixed(1)}s`);
+    console.log(`throughput      ${(succeeded / (elapsedMs / 1000)).toFixed(2)} runs/s with ${workers} worker loops`);
+    console.log(`delivery        p50 ${percentile(latencies, 50)}ms  p95 ${percentile(latencies, 95)}ms  max ${latencies.at(-1) ?? 0}ms after seeding`);
+    console.log(`deliveries      ${received} received, ${duplicated} jobs delivered more than once`);
+    console.log(`model latency   ${progress?.avgLlmDurationMs == null ? "-" : `${Math.round(progress.avgLlmDurationMs)}ms avg`}`);
+    console.log(`ticks           ${ticks.count} (${ticks.empty} empty, ${ticks.errors} errors), ${ticks.duplicates} duplicate slots`);
+    console.log(`contention      max ${maxLockWaits} sessions waiting on locks, max ${maxLocked} jobs locked at once`);
+    return progress?.pending === 0 && duplicated === 0 ? 0 : 1;
+  } finally {
+    server.close();
+    if (batch && flags.keep !== "1") {
+      await call("DELETE", `/api/cron/loadtest?batch=${encodeURIComponent(batch)}`).catch((err) => {
+        console.error(`cleanup failed: ${err instanceof Error ? err.message : err}`);
+      });
+    }
+  }
+}
+
+// Spec files: *.yaml, *.yml and *.json anywhere under the directory (dot directories skipped), sorted by path.
+function readSpecFiles(dir) {
+  const files = [];
+  const walk = (current) => {
+    for (const entry of readdirSync(current, { withFileTypes: true }).sort((a, b) => a.name.localeCompare(b.name))) {
+      const path = join(current, entry.name);
+      if (entry.isDirectory() && !entry.name.startsWith(".")) {
+        walk(path);
+      } else if (entry.isFile() && /\.(ya?ml|json)$/i.test(entry.name)) {
+        files.push({ path: relative(dir, path), content: readFileSync(path, "utf8") });
+      }
+    }
+  };
+  walk(dir);
+  return files;
+}
+
+// Shallow checkout of --ref in a cache directory per repo URL, refreshed on every call.
+function checkoutRepo(url, ref) {
+  const dir = join(tmpdir(), `promptloop-sync-${createHash("sha256").update(url).digest("hex").slice(0, 12)}`);
+  const git = (...args) => execFileSync("git", args, { stdio: ["ignore", "ignore", "inherit"] });
+  if (!existsSync(join(dir, ".git"))) {
+    git("clone", "--quiet", "--depth", "1", ...(ref ? ["--branch", ref] : []), url, dir);
+  } else {
+    git("-C", dir, "fetch", "--quiet", "--depth", "1", "origin", ref || "HEAD");
+    git("-C", dir, "reset", "--quiet", "--hard", "FETCH_HEAD");
+  }
+  return dir;
+}
+
+async function syncOnce(flags) {
+  const root = flags.git ? checkoutRepo(flags.git, flags.ref) : flags.dir;
+  const files = readSpecFiles(flags.path ? join(root, flags.path) : root);
+  const { status, data } = await call("POST", "/api/cron/sync", { owner: flags.owner, files, dryRun: flags["dry-run"] === "1" });
+  if (status !== 200 && status !== 422) {
+    throw new Error(`sync failed: ${data.error ?? status}`);
+  }
+  const verb = data.dryRun ? "would be " : "";
+  for (const [label, keys] of [["created", data.created], ["updated", data.updated], ["disabled", data.disabled]]) {
+    for (const key of keys) {
+      console.log(`${verb}${label}: ${key}`);
+    }
+  }
+  for (const problem of data.errors) {
+    console.log(`error: ${problem.path}: ${problem.error}`);
+  }
+  console.log(`${files.length} specs, ${data.unchanged} unchanged`);
+  return data.errors.length ? 1 : 0;
+}
+
+async function sync(flags) {
+  if (!flags.owner || (!flags.dir && !flags.git)) {
+    throw new Error("sync needs --owner <email> and --dir <path> or --git <url>");
+  }
+  if (flags.watch === undefined) {
+    return syncOnce(flags);
+  }
+  const interval = positiveFlag(flags, "watch", 60);
+  let stopping = false;
+  for (const signal of ["SIGINT", "SIGTERM"]) {
+    process.once(signal, () => {
+      stopping = true;
+    });
+  }
+  while (!stopping) {
+    try {
+      console.log(new Date().toISOString());
+      await syncOnce(flags);
+    } catch (err) {
+      console.error(new Date().toISOString(), err instanceof Error ? err.message : err);
+    }
+    for (let waited = 0; !stopping && waited < interval * 1000; waited += 250) {
+      await sleep(250);
+    }
+  }
+  return 0;
+}
+
+// Next.js takes no custom flags, so --config reaches the server as PROMPTLOOP_CONFIG. SIGINT/SIGTERM are
+// passed on so the server can drain its workers before exiting.
+function start(flags) {
+  const env = { ...process.env };
+  if (flags.config) {
+    const path = resolve(flags.config);
+    if (!existsSync(path)) {
+      throw new Error(`--config: ${path} does not exist`);
+    }
+    env.PROMPTLOOP_CONFIG = path;
+  }
+  const next = join(dirname(fileURLToPath(import.meta.url)), "..", "node_modules", "next", "dist", "bin", "next");
+  const args = ["start", ...(flags.port ? ["--port", String(positiveFlag(flags, "port", 3000))] : [])];
+  const child = spawn(process.execPath, [next, ...args], { env, stdio: "inherit" });
+  for (const signal of ["SIGINT", "SIGTERM"]) {
+    process.on(signal, () => child.kill(signal));
+  }
+  return new Promise((resolveExit, reject) => {
+    child.once("error", reject);
+    child.once("exit", (code) => resolveExit(code ?? 1));
+  });
+}
+
+const commands = { start, worker, run, validate, doctor, "next-runs": nextRuns, canary, loadtest, sync };
+
+async function main() {
+  const { command, flags } = parseArgs(process.argv.slice(2));
+  const handler = commands[command];
+  if (!handler) {
+    console.error(USAGE);
+    return command ? 64 : 0;
+  }
+  return handler(flags);
+}
+
+main()
+  .then((code) => process.exit(code))
+  .catch((err) => {
+    console.error(err instanceof Error ? err.message : err);
+    process.exit(1);
+  });
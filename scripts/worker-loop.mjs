@@ -0,0 +1,15 @@
+// Polling schedule for `promptloop worker`, kept apart from the CLI so it can be tested without running it.
+
+// Next wait after a tick: none while the tick left due jobs behind (it stopped at its job or time limit), the
+// base interval after useful work or an error, and a doubling backoff while idle, cut short when the next job is due.
+export function nextPollDelayMs(data, idleMs, intervalMs, maxIntervalMs, now = Date.now()) {
+  if (!data?.ok || data.degraded) {
+    return { waitMs: intervalMs, idleMs: 0 };
+  }
+  if (data.processed > 0) {
+    return { waitMs: data.queueDrained === false ? 0 : intervalMs, idleMs: 0 };
+  }
+  const backoff = idleMs ? Math.min(idleMs * 2, maxIntervalMs) : intervalMs;
+  const untilDue = data.nextDueAt ? Date.parse(data.nextDueAt) - now : Infinity;
+  return { waitMs: untilDue > 0 ? Math.min(backoff, untilDue) : backoff, idleMs: backoff };
+}
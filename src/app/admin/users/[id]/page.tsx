@@ -31,6 +31,7 @@ export default async function AdminUserDetailPage({ params }: Params) {
       overrideEnabledJobsLimit: true,
       overrideTotalJobsLimit: true,
       overrideDailyRunLimit: true,
+      overrideMonthlyBudgetUsd: true,
     },
   });
   if (!user) {
@@ -87,6 +88,7 @@ export default async function AdminUserDetailPage({ params }: Params) {
             enabledJobsLimit: user.overrideEnabledJobsLimit,
             totalJobsLimit: user.overrideTotalJobsLimit,
             dailyRunLimit: user.overrideDailyRunLimit,
+            monthlyBudgetUsd: user.overrideMonthlyBudgetUsd,
           }}
         />
 
@@ -10,6 +10,7 @@ type Props = {
     enabledJobsLimit: number | null;
     totalJobsLimit: number | null;
     dailyRunLimit: number | null;
+    monthlyBudgetUsd: number | null;
   };
 };
 
@@ -25,6 +26,18 @@ function parseOptionalInt(value: string): number | null {
   return n;
 }
 
+function parseOptionalAmount(value: string): number | null {
+  const trimmed = value.trim();
+  if (!trimmed) {
+    return null;
+  }
+  const n = Number(trimmed);
+  if (!Number.isFinite(n) || n < 0) {
+    return NaN;
+  }
+  return n;
+}
+
 export function PlanEditor({ userId, initialPlan, initialOverrides }: Props) {
   const [plan, setPlan] = useState<Props["initialPlan"]>(initialPlan);
   const [enabledJobsLimit, setEnabledJobsLimit] = useState(
@@ -36,6 +49,9 @@ export function PlanEditor({ userId, initialPlan, initialOverrides }: Props) {
   const [dailyRunLimit, setDailyRunLimit] = useState(
     initialOverrides.dailyRunLimit == null ? "" : String(initialOverrides.dailyRunLimit),
   );
+  const [monthlyBudgetUsd, setMonthlyBudgetUsd] = useState(
+    initialOverrides.monthlyBudgetUsd == null ? "" : String(initialOverrides.monthlyBudgetUsd),
+  );
 
   const [saving, setSaving] = useState(false);
   const [error, setError] = useState<string | null>(null);
@@ -54,6 +70,12 @@ export function PlanEditor({ userId, initialPlan, initialOverrides }: Props) {
       setSaving(false);
       return;
     }
+    const budgetParsed = parseOptionalAmount(monthlyBudgetUsd);
+    if (Number.isNaN(budgetParsed)) {
+      setError("Monthly budget must be empty or a non-negative amount.");
+      setSaving(false);
+      return;
+    }
 
     try {
       const res = await fetch(`/api/admin/users/${userId}`, {
@@ -64,6 +86,7 @@ export function PlanEditor({ userId, initialPlan, initialOverrides }: Props) {
           overrideEnabledJobsLimit: enabledParsed,
           overrideTotalJobsLimit: totalParsed,
           overrideDailyRunLimit: dailyParsed,
+          overrideMonthlyBudgetUsd: budgetParsed,
         }),
       });
       if (!res.ok) {
@@ -131,6 +154,17 @@ export function PlanEditor({ userId, initialPlan, initialOverrides }: Props) {
             placeholder="(none)"
           />
         </label>
+
+        <label className="text-sm text-zinc-700">
+          Override monthly budget (USD)
+          <input
+            className="mt-1 block w-full rounded-lg border border-zinc-200 bg-white px-2 py-1 text-sm text-zinc-900"
+            inputMode="decimal"
+            value={monthlyBudgetUsd}
+            onChange={(e) => setMonthlyBudgetUsd(e.target.value)}
+            placeholder="(none)"
+          />
+        </label>
       </div>
 
       <div className="mt-3 flex items-center gap-3">
@@ -0,0 +1,34 @@
+import { NextRequest, NextResponse } from "next/server";
+import { z } from "zod";
+
+import { prisma } from "@/lib/prisma";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+
+const bodySchema = z.object({
+  // Opt in to having a small, scrubbed sample of delivered outputs reviewed by the operator (qa-mirror.ts).
+  qaSharing: z.boolean(),
+});
+
+export async function GET() {
+  try {
+    const userId = await requireUserId();
+    const user = await prisma.user.findUniqueOrThrow({ where: { id: userId }, select: { qaSharing: true } });
+    return NextResponse.json(user);
+  } catch (error) {
+    return errorResponse(error, 401);
+  }
+}
+
+export async function PATCH(request: NextRequest) {
+  try {
+    const userId = await requireUserId();
+    const parsed = bodySchema.parse(await request.json());
+    const user = await prisma.user.update({ where: { id: userId }, data: { qaSharing: parsed.qaSharing }, select: { qaSharing: true } });
+    await recordAudit({ userId, action: "account.update", entityType: "user", entityId: userId, data: { qaSharing: user.qaSharing } });
+    return NextResponse.json(user);
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
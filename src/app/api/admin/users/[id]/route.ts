@@ -26,6 +26,7 @@ export async function PATCH(request: NextRequest, { params }: Params) {
         overrideEnabledJobsLimit: true,
         overrideTotalJobsLimit: true,
         overrideDailyRunLimit: true,
+        overrideMonthlyBudgetUsd: true,
       },
     });
     if (!before) {
@@ -40,6 +41,7 @@ export async function PATCH(request: NextRequest, { params }: Params) {
         ...(parsed.overrideEnabledJobsLimit !== undefined ? { overrideEnabledJobsLimit: parsed.overrideEnabledJobsLimit } : {}),
         ...(parsed.overrideTotalJobsLimit !== undefined ? { overrideTotalJobsLimit: parsed.overrideTotalJobsLimit } : {}),
         ...(parsed.overrideDailyRunLimit !== undefined ? { overrideDailyRunLimit: parsed.overrideDailyRunLimit } : {}),
+        ...(parsed.overrideMonthlyBudgetUsd !== undefined ? { overrideMonthlyBudgetUsd: parsed.overrideMonthlyBudgetUsd } : {}),
       },
       select: {
         id: true,
@@ -49,6 +51,7 @@ export async function PATCH(request: NextRequest, { params }: Params) {
         overrideEnabledJobsLimit: true,
         overrideTotalJobsLimit: true,
         overrideDailyRunLimit: true,
+        overrideMonthlyBudgetUsd: true,
       },
     });
 
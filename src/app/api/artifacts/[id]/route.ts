@@ -0,0 +1,38 @@
+import { NextRequest, NextResponse } from "next/server";
+
+import { prisma } from "@/lib/prisma";
+import { verifyToken } from "@/lib/crypto";
+
+export const runtime = "nodejs";
+export const dynamic = "force-dynamic";
+
+type Params = { params: Promise<{ id: string }> };
+
+// Signed artifact download; the links are embedded in delivered messages, so no session is required.
+export async function GET(request: NextRequest, { params }: Params) {
+  const { id } = await params;
+  const token = request.nextUrl.searchParams.get("token") ?? "";
+  if (!token || !verifyToken("run-artifact", id, token)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+
+  const artifact = await prisma.runArtifact.findUnique({ where: { id } });
+  if (!artifact) {
+    return NextResponse.json({ error: "Not found" }, { status: 404 });
+  }
+  if (artifact.expiresAt.getTime() <= Date.now()) {
+    return NextResponse.json({ error: "Artifact expired" }, { status: 410 });
+  }
+  if (artifact.storage !== "db" || !artifact.content) {
+    return NextResponse.json({ error: `Unsupported artifact storage: ${artifact.storage}` }, { status: 501 });
+  }
+
+  return new Response(new Uint8Array(artifact.content), {
+    headers: {
+      "Content-Type": artifact.mediaType,
+      "Content-Length": String(artifact.sizeBytes),
+      "Content-Disposition": `inline; filename="${artifact.name}"`,
+      "Cache-Control": "private, max-age=3600",
+    },
+  });
+}
@@ -0,0 +1,58 @@
+import { NextRequest, NextResponse } from "next/server";
+import { Prisma } from "@prisma/client";
+
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+import { savedChannelUpdateSchema } from "@/lib/validation";
+import { deleteSavedChannel, toApiSavedChannel, updateSavedChannel } from "@/lib/saved-channels";
+
+type Params = { params: Promise<{ id: string }> };
+
+// Updating the config applies to every job that references the channel.
+export async function PUT(request: NextRequest, { params }: Params) {
+  try {
+    const userId = await requireUserId();
+    const { id } = await params;
+    const parsed = savedChannelUpdateSchema.parse(await request.json());
+
+    const result = await updateSavedChannel(userId, id, parsed);
+    if (!result) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    await recordAudit({
+      userId,
+      action: "channel.update",
+      entityType: "channel",
+      entityId: id,
+      data: { name: result.channel.name, configChanged: !!parsed.channel, jobsUpdated: result.jobsUpdated },
+    });
+
+    return NextResponse.json({ channel: toApiSavedChannel(result.channel), jobsUpdated: result.jobsUpdated });
+  } catch (error) {
+    if (error instanceof Prisma.PrismaClientKnownRequestError && error.code === "P2002") {
+      return NextResponse.json({ error: "A channel with this name already exists" }, { status: 409 });
+    }
+    return errorResponse(error);
+  }
+}
+
+// Jobs using the channel keep its last config and stop following it.
+export async function DELETE(_: NextRequest, { params }: Params) {
+  try {
+    const userId = await requireUserId();
+    const { id } = await params;
+
+    const result = await deleteSavedChannel(userId, id);
+    if (!result) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    await recordAudit({ userId, action: "channel.delete", entityType: "channel", entityId: id, data: result });
+
+    return NextResponse.json({ ok: true, jobsDetached: result.jobsDetached });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,42 @@
+import { NextRequest, NextResponse } from "next/server";
+import { Prisma } from "@prisma/client";
+
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+import { savedChannelCreateSchema } from "@/lib/validation";
+import { createSavedChannel, listSavedChannels, toApiSavedChannel } from "@/lib/saved-channels";
+
+// Lists saved channels with secrets masked.
+export async function GET() {
+  try {
+    const userId = await requireUserId();
+    const channels = await listSavedChannels(userId);
+    return NextResponse.json({ channels: channels.map(toApiSavedChannel) });
+  } catch (error) {
+    return errorResponse(error, 401);
+  }
+}
+
+export async function POST(request: NextRequest) {
+  try {
+    const userId = await requireUserId();
+    const parsed = savedChannelCreateSchema.parse(await request.json());
+    const channel = await createSavedChannel(userId, parsed);
+
+    await recordAudit({
+      userId,
+      action: "channel.create",
+      entityType: "channel",
+      entityId: channel.id,
+      data: { name: channel.name, channelType: channel.channelType, isDefault: channel.isDefault },
+    });
+
+    return NextResponse.json({ channel: toApiSavedChannel(channel) }, { status: 201 });
+  } catch (error) {
+    if (error instanceof Prisma.PrismaClientKnownRequestError && error.code === "P2002") {
+      return NextResponse.json({ error: "A channel with this name already exists" }, { status: 409 });
+    }
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,8 @@
+import { NextResponse } from "next/server";
+
+import { CHANNEL_CONFIG_VERSION, channelConfigJsonSchemas } from "@/lib/channel-config";
+
+// JSON Schema of each channel type's config, for API clients and config-as-code tooling.
+export async function GET() {
+  return NextResponse.json({ version: CHANNEL_CONFIG_VERSION, schemas: channelConfigJsonSchemas() });
+}
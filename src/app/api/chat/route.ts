@@ -2,16 +2,19 @@ import { streamText, convertToModelMessages, tool, stepCountIs, type UIMessage,
 import { formatRunTitle } from "@/lib/run-title";
 import { Prisma } from "@prisma/client";
 import { z } from "zod";
-import { openai } from "@ai-sdk/openai";
+import { openaiProvider } from "@/lib/openai-keys";
 import { prisma } from "@/lib/prisma";
 import { requireUserId } from "@/lib/authz";
 import { errorResponse } from "@/lib/http";
 import { AVAILABLE_OPENAI_MODELS, DEFAULT_LLM_MODEL, normalizeLlmModel, normalizeWebSearchMode } from "@/lib/llm-defaults";
+import { resolvePreviewModel } from "@/lib/model-router";
 import { computeNextRunAt } from "@/lib/schedule";
-import { toDbChannelConfig, toMaskedApiJob, toRunnableChannel } from "@/lib/jobs";
+import { toDbChannelConfig, toMaskedApiJob } from "@/lib/jobs";
+import { resolveChannelSecrets, runnableJobChannel } from "@/lib/saved-channels";
 import { recordAudit } from "@/lib/audit";
 import { enforceDailyRunLimit } from "@/lib/limits";
 import { runPrompt } from "@/lib/llm";
+import { normalizeLlmTools } from "@/lib/llm-tools";
 import { sendChannelMessage } from "@/lib/channel";
 import { getOrCreatePublishedPromptVersion } from "@/lib/prompt-version";
 import { compilePromptTemplate, coerceStringVars } from "@/lib/prompt-compile";
@@ -19,6 +22,9 @@ import { enhancePrompt } from "@/lib/prompt-writer";
 import { generatePromptDraftFromIntent, inferUseWebSearch, proposeSchedule } from "@/lib/job-intents";
 import { redactMessageForStorage } from "@/lib/chat-redact";
 import { buildPostPromptVariables, normalizePostPromptConfig } from "@/lib/post-prompt";
+import { logger } from "@/lib/logger";
+import { runUsageColumns } from "@/lib/usage-cost";
+import { generationParamsFromJob } from "@/lib/generation-params";
 
 export const maxDuration = 300;
 
@@ -524,6 +530,9 @@ export async function POST(req: Request) {
             scheduleTime: nextScheduleTime,
             scheduleDayOfWeek: nextScheduleDayOfWeek,
             scheduleCron: nextScheduleCron,
+            timezone: existing.timezone,
+            runAt: existing.runAt,
+            weekdaysOnly: nextScheduleType === "daily" && existing.weekdaysOnly,
           });
 
           const channel = input.channel ? toDbChannelConfig(input.channel) : null;
@@ -549,9 +558,10 @@ export async function POST(req: Request) {
               scheduleTime: nextScheduleTime,
               scheduleDayOfWeek: nextScheduleDayOfWeek,
               scheduleCron: nextScheduleCron,
+              weekdaysOnly: nextScheduleType === "daily" && existing.weekdaysOnly,
               enabled: nextEnabled,
               nextRunAt,
-              ...(channel ? { channelType: channel.channelType, channelConfig: channel.channelConfig } : {}),
+              ...(channel ? { channelId: null, channelType: channel.channelType, channelConfig: channel.channelConfig } : {}),
               ...(shouldCreatePromptVersion
                 ? {
                     promptVersions: {
@@ -626,13 +636,16 @@ export async function POST(req: Request) {
 
           const pv = job.publishedPromptVersion ?? (await getOrCreatePublishedPromptVersion(job.id));
           const vars = coerceStringVars(pv.variables);
-          const prompt = compilePromptTemplate(pv.template, vars);
+          const prompt = compilePromptTemplate(pv.template, vars, { timezone: job.timezone ?? "UTC", jobName: job.name });
 
-          const modelId = normalizeLlmModel(job.llmModel);
+          const modelId = resolvePreviewModel(normalizeLlmModel(job.llmModel), prompt, job.tags);
           const result = await runPrompt(prompt, {
             model: modelId,
             useWebSearch: job.allowWebSearch,
             webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+            tools: normalizeLlmTools(job.llmTools),
+            systemPrompt: job.systemPrompt,
+            generation: generationParamsFromJob(job),
           });
 
           let output = result.output;
@@ -654,7 +667,13 @@ export async function POST(req: Request) {
                 llmModel: result.llmModel ?? modelId,
               }),
             );
-            const post = await runPrompt(postPrompt, { model: modelId, useWebSearch: false, webSearchMode: normalizeWebSearchMode(job.webSearchMode) });
+            const post = await runPrompt(postPrompt, {
+              model: modelId,
+              useWebSearch: false,
+              webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+              systemPrompt: job.systemPrompt,
+              generation: generationParamsFromJob(job),
+            });
             output = post.output;
             postUsage = post.llmUsage ?? null;
             postToolCalls = post.llmToolCalls ?? null;
@@ -666,10 +685,10 @@ export async function POST(req: Request) {
             if (job.channelType === "in_app") {
               throw new Error("In-app delivery jobs cannot test-send.");
             }
-            await sendChannelMessage(toRunnableChannel(job), title, output, {
+            await sendChannelMessage(await runnableJobChannel(job), title, output, {
               citations: result.citations,
               usedWebSearch: result.usedWebSearch,
-              meta: { kind: "job-preview", jobId: job.id, promptVersionId: pv.id },
+              meta: { kind: "job-preview", jobId: job.id, jobName: job.name, promptVersionId: pv.id },
             });
           }
 
@@ -696,6 +715,10 @@ export async function POST(req: Request) {
                   : result.llmToolCalls == null
                     ? Prisma.DbNull
                     : (result.llmToolCalls as Prisma.InputJsonValue),
+              ...runUsageColumns(
+                result.llmModel ?? modelId,
+                postPromptApplied ? { primary: result.llmUsage ?? null, post: postUsage } : result.llmUsage,
+              ),
               usedWebSearch: result.usedWebSearch,
               citations: (result.citations as unknown as Prisma.InputJsonValue) ?? Prisma.DbNull,
               isPreview: true,
@@ -728,7 +751,7 @@ export async function POST(req: Request) {
             timezone: input.timezone,
           });
 
-          const modelId = normalizeLlmModel(input.llmModel);
+          const modelId = resolvePreviewModel(normalizeLlmModel(input.llmModel), prompt);
           const result = await runPrompt(prompt, {
             model: modelId,
             useWebSearch: input.useWebSearch,
@@ -773,13 +796,13 @@ export async function POST(req: Request) {
               );
             } else if (input.channel.type === "webhook") {
               await sendChannelMessage(
-                {
+                await resolveChannelSecrets(userId, {
                   type: "webhook",
                   url: input.channel.config.url,
                   method: input.channel.config.method,
                   headers: input.channel.config.headers,
                   payload: input.channel.config.payload,
-                },
+                }),
                 title,
                 output,
                 { citations: result.citations, usedWebSearch: result.usedWebSearch, meta: { kind: "preview" } },
@@ -964,7 +987,7 @@ export async function POST(req: Request) {
 
     const nowIso = new Date().toISOString();
     const result = streamText({
-      model: openai("gpt-5.2-chat-latest"),
+      model: openaiProvider()("gpt-5.2-chat-latest"),
       system: `${CHAT_SYSTEM_PROMPT}\n\nCurrent time (UTC): ${nowIso}`,
       messages: await convertToModelMessages(messages),
       tools,
@@ -980,11 +1003,11 @@ export async function POST(req: Request) {
           await ensureChat();
           await persistTranscript(finalMessages);
         } catch (e) {
-          console.error("[chat.persist] finish failed", e);
+          logger.error("chat persist failed", { error: e });
         }
       },
       onError: (e) => {
-        console.error("[chat.ui-stream] error", e);
+        logger.error("chat stream failed", { error: e });
         return "Streaming error";
       },
     });
@@ -992,7 +1015,7 @@ export async function POST(req: Request) {
     return createUIMessageStreamResponse({
       stream: uiStream,
       consumeSseStream: persist
-        ? ({ stream }) => consumeStream({ stream, onError: (e) => console.error("[chat.persist] consume failed", e) })
+        ? ({ stream }) => consumeStream({ stream, onError: (e) => logger.error("chat stream consume failed", { error: e }) })
         : undefined,
     });
   } catch (error) {
@@ -0,0 +1,20 @@
+import type { NextRequest } from "next/server";
+import { canaryConfig, canaryReport } from "@/lib/canary";
+import { isCronAuthorized } from "@/lib/cron-auth";
+
+export const runtime = "nodejs";
+
+const DEFAULT_DAYS = 7;
+const MAX_DAYS = 90;
+
+// Per-cohort comparison of the model canary for operators: success rate, fallbacks, cost, latency and quality.
+export async function GET(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  const requested = Number(request.nextUrl.searchParams.get("days") ?? DEFAULT_DAYS);
+  const days = Number.isFinite(requested) && requested > 0 ? Math.min(Math.floor(requested), MAX_DAYS) : DEFAULT_DAYS;
+  const environment = request.nextUrl.searchParams.get("environment")?.trim() || undefined;
+  const since = new Date(Date.now() - days * 24 * 60 * 60 * 1000);
+  return Response.json({ config: canaryConfig(), days, cohorts: await canaryReport({ since, environment }) });
+}
@@ -0,0 +1,14 @@
+import type { NextRequest } from "next/server";
+import { runDoctor } from "@/lib/doctor";
+import { isCronAuthorized } from "@/lib/cron-auth";
+
+export const runtime = "nodejs";
+export const maxDuration = 60;
+
+export async function GET(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  const report = await runDoctor();
+  return Response.json(report, { status: report.ok ? 200 : 503 });
+}
@@ -0,0 +1,46 @@
+import type { NextRequest } from "next/server";
+import { drainStatus, setDraining, waitForDrain } from "@/lib/worker-runner";
+import { logger } from "@/lib/logger";
+import { isCronAuthorized } from "@/lib/cron-auth";
+
+export const runtime = "nodejs";
+export const maxDuration = 300;
+
+const DEFAULT_DRAIN_TIMEOUT_MS = 120_000;
+
+// Pre-stop hook for deployments: stops this worker process from claiming new jobs and waits until the runs in
+// flight have finished, so a pod is never killed mid-LLM-call. Responds 200 when drained, 503 on timeout.
+export async function POST(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+
+  const requested = Number(request.nextUrl.searchParams.get("timeoutMs") ?? process.env.WORKER_DRAIN_TIMEOUT_MS ?? DEFAULT_DRAIN_TIMEOUT_MS);
+  const timeoutMs = Number.isFinite(requested) && requested >= 0 ? Math.min(Math.floor(requested), 280_000) : DEFAULT_DRAIN_TIMEOUT_MS;
+
+  setDraining(true);
+  logger.info("worker draining", drainStatus());
+  const drained = await waitForDrain(timeoutMs);
+  const status = drainStatus();
+  if (!drained) {
+    logger.warn("worker drain timed out", { timeout_ms: timeoutMs, ...status });
+  }
+  return Response.json({ ok: drained, ...status }, { status: drained ? 200 : 503 });
+}
+
+export async function GET(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  return Response.json({ ok: true, ...drainStatus() });
+}
+
+// Cancels a drain (e.g. an aborted rollout) so the process picks up jobs again.
+export async function DELETE(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  setDraining(false);
+  logger.info("worker drain cancelled");
+  return Response.json({ ok: true, ...drainStatus() });
+}
@@ -0,0 +1,23 @@
+import type { NextRequest } from "next/server";
+import { randomUUID } from "crypto";
+import { runJobNow } from "@/lib/worker-runner";
+import { isCronAuthorized } from "@/lib/cron-auth";
+
+export const runtime = "nodejs";
+export const maxDuration = 300;
+
+type Params = { params: Promise<{ id: string }> };
+
+// Operator endpoint behind `promptloop run --job-id`: runs the job now, waits for the result, and returns it.
+export async function POST(request: NextRequest, { params }: Params) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  const { id } = await params;
+  const runnerId = randomUUID();
+  const result = await runJobNow(id, { runnerId });
+  if (!result) {
+    return Response.json({ error: "Not found" }, { status: 404 });
+  }
+  return Response.json({ ok: result.status === "success", runnerId, ...result }, { status: result.status === "queued" ? 202 : 200 });
+}
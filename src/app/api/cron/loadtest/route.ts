@@ -0,0 +1,58 @@
+import { NextResponse, type NextRequest } from "next/server";
+import { z } from "zod";
+import { isCronAuthorized } from "@/lib/cron-auth";
+import { errorResponse } from "@/lib/http";
+import { cleanupLoadtest, LOADTEST_MAX_JOBS, loadtestEnabled, loadtestStatus, seedLoadtestJobs } from "@/lib/loadtest";
+import { workerEnvironment } from "@/lib/worker-runner";
+
+export const runtime = "nodejs";
+
+const seedSchema = z.object({
+  jobs: z.number().int().min(1).max(LOADTEST_MAX_JOBS),
+  webhookUrl: z.string().url(),
+});
+
+function guard(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  if (!loadtestEnabled()) {
+    return NextResponse.json({ error: "Load testing is disabled (set LOADTEST_ENABLED=1)" }, { status: 404 });
+  }
+  return null;
+}
+
+// Seeds a batch of synthetic jobs that are due now, for `promptloop loadtest`.
+export async function POST(request: NextRequest) {
+  const denied = guard(request);
+  if (denied) {
+    return denied;
+  }
+  try {
+    const parsed = seedSchema.parse(await request.json());
+    return NextResponse.json(await seedLoadtestJobs({ ...parsed, environment: workerEnvironment() }));
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
+
+export async function GET(request: NextRequest) {
+  const denied = guard(request);
+  if (denied) {
+    return denied;
+  }
+  const batch = request.nextUrl.searchParams.get("batch")?.trim();
+  if (!batch) {
+    return NextResponse.json({ error: "batch is required" }, { status: 400 });
+  }
+  return NextResponse.json(await loadtestStatus(batch));
+}
+
+export async function DELETE(request: NextRequest) {
+  const denied = guard(request);
+  if (denied) {
+    return denied;
+  }
+  const batch = request.nextUrl.searchParams.get("batch")?.trim() || undefined;
+  return NextResponse.json({ deleted: await cleanupLoadtest(batch) });
+}
@@ -0,0 +1,16 @@
+import type { NextRequest } from "next/server";
+import { isCronAuthorized } from "@/lib/cron-auth";
+import { formatPrometheus, metricSamples } from "@/lib/worker-metrics";
+import { workerEnvironment } from "@/lib/worker-runner";
+
+export const runtime = "nodejs";
+
+// Prometheus scrape target for the worker counters of this process (see METRICS_PUSH_URL for push mode).
+export async function GET(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  return new Response(formatPrometheus(metricSamples(), { environment: workerEnvironment() }), {
+    headers: { "content-type": "text/plain; version=0.0.4; charset=utf-8" },
+  });
+}
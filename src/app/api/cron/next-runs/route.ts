@@ -0,0 +1,17 @@
+import type { NextRequest } from "next/server";
+import { upcomingRuns } from "@/lib/deployment-checks";
+import { isCronAuthorized } from "@/lib/cron-auth";
+
+export const runtime = "nodejs";
+
+const DEFAULT_LIMIT = 20;
+
+export async function GET(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  const requested = Number(request.nextUrl.searchParams.get("limit") ?? DEFAULT_LIMIT);
+  const limit = Number.isFinite(requested) && requested > 0 ? Math.min(Math.floor(requested), 500) : DEFAULT_LIMIT;
+  const environment = request.nextUrl.searchParams.get("environment")?.trim() || undefined;
+  return Response.json({ runs: await upcomingRuns(limit, environment) });
+}
@@ -1,28 +1,30 @@
 import type { NextRequest } from "next/server";
 import { randomUUID } from "crypto";
-import { runDueJobs } from "@/lib/worker-runner";
+import { drainStatus, runWorker } from "@/lib/worker-runner";
+import { workerConfigFromEnv } from "@/lib/worker-config";
+import { logger } from "@/lib/logger";
+import { isCronAuthorized } from "@/lib/cron-auth";
 
 export const runtime = "nodejs";
 export const maxDuration = 300;
 
 export async function GET(request: NextRequest) {
-  const secret = process.env.CRON_SECRET;
-  if (secret) {
-    const authHeader = request.headers.get("authorization");
-    if (authHeader !== `Bearer ${secret}`) {
-      return new Response("Unauthorized", { status: 401 });
-    }
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
   }
 
-  const maxJobs = Number(process.env.WORKER_MAX_JOBS_PER_RUN ?? 25);
-  const budgetMs = Number(process.env.WORKER_TIME_BUDGET_MS ?? 250_000);
+  if (drainStatus().draining) {
+    return Response.json({ ok: false, draining: true }, { status: 503 });
+  }
+
+  const config = workerConfigFromEnv();
   const runnerId = randomUUID();
+  const startedAt = Date.now();
+
+  const result = await runWorker(config, { runnerId });
 
-  const result = await runDueJobs({
-    maxJobs: Number.isFinite(maxJobs) && maxJobs > 0 ? Math.floor(maxJobs) : 25,
-    timeBudgetMs: Number.isFinite(budgetMs) && budgetMs > 1000 ? Math.floor(budgetMs) : 250_000,
-    runnerId,
-  });
+  const environment = config.environment;
+  logger.info("worker run finished", { runner_id: runnerId, environment, duration_ms: Date.now() - startedAt, ...result });
 
-  return Response.json({ ok: true, runnerId, ...result, executedAt: new Date().toISOString() });
+  return Response.json({ ok: true, runnerId, environment, ...result, executedAt: new Date().toISOString() });
 }
@@ -0,0 +1,22 @@
+import { NextResponse, type NextRequest } from "next/server";
+import { isCronAuthorized } from "@/lib/cron-auth";
+import { errorResponse } from "@/lib/http";
+import { syncJobs } from "@/lib/job-sync";
+import { jobSyncSchema } from "@/lib/validation";
+
+export const runtime = "nodejs";
+export const maxDuration = 300;
+
+// Reconciles the owner's managed jobs with the posted spec files (`promptloop sync`). Answers 422 when any file
+// was rejected; the valid ones are still applied.
+export async function POST(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  try {
+    const result = await syncJobs(jobSyncSchema.parse(await request.json()));
+    return NextResponse.json(result, { status: result.errors.length ? 422 : 200 });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,14 @@
+import type { NextRequest } from "next/server";
+import { validateJobs } from "@/lib/deployment-checks";
+import { isCronAuthorized } from "@/lib/cron-auth";
+
+export const runtime = "nodejs";
+export const maxDuration = 300;
+
+export async function GET(request: NextRequest) {
+  if (!isCronAuthorized(request)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+  const result = await validateJobs();
+  return Response.json({ ok: result.failing.length === 0, ...result });
+}
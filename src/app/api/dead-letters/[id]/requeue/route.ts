@@ -0,0 +1,71 @@
+import { NextResponse } from "next/server";
+import { ChannelType, type Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+
+type Params = { params: Promise<{ id: string }> };
+
+const OUTPUT_PREVIEW_MAX = 1000;
+
+// Puts the stored output back into the deferred-delivery outbox as a new run (so its delivery
+// attempts start fresh); the next worker pass delivers the parts still missing through the job's current channel.
+export async function POST(_: Request, { params }: Params) {
+  try {
+    const userId = await requireUserId();
+    const { id } = await params;
+
+    const deadLetter = await prisma.deadLetter.findFirst({
+      where: { id, job: { userId } },
+      include: { job: true, runHistory: true },
+    });
+    if (!deadLetter) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+    if (!deadLetter.outputText) {
+      return NextResponse.json({ error: "Nothing to re-deliver: the run produced no output" }, { status: 409 });
+    }
+    if (deadLetter.job.channelType === ChannelType.in_app) {
+      return NextResponse.json({ error: "In-app jobs have no external channel to deliver to" }, { status: 409 });
+    }
+
+    const source = deadLetter.runHistory;
+    const run = await prisma.$transaction(async (tx) => {
+      const created = await tx.runHistory.create({
+        data: {
+          jobId: deadLetter.jobId,
+          promptVersionId: source?.promptVersionId ?? null,
+          status: "running",
+          outputText: deadLetter.outputText,
+          outputPreview: deadLetter.outputText!.slice(0, OUTPUT_PREVIEW_MAX),
+          llmModel: source?.llmModel ?? null,
+          usedWebSearch: source?.usedWebSearch ?? false,
+          citations: (source?.citations ?? undefined) as Prisma.InputJsonValue | undefined,
+          runnerId: "requeue",
+          deliverAt: new Date(),
+          // Parts the failed run already delivered are not sent again.
+          deliveredParts: source?.deliveredParts ?? 0,
+          tags: source?.tags ?? deadLetter.job.tags,
+        },
+      });
+      await tx.deadLetter.update({
+        where: { id: deadLetter.id },
+        data: { requeuedAt: new Date(), requeueRunHistoryId: created.id },
+      });
+      return created;
+    });
+
+    await recordAudit({
+      userId,
+      action: "dead_letter.requeue",
+      entityType: "dead_letter",
+      entityId: deadLetter.id,
+      data: { jobId: deadLetter.jobId, runHistoryId: run.id },
+    });
+
+    return NextResponse.json({ ok: true, runHistoryId: run.id });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,20 @@
+import { NextRequest, NextResponse } from "next/server";
+import { prisma } from "@/lib/prisma";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+
+export async function GET(request: NextRequest) {
+  try {
+    const userId = await requireUserId();
+    const jobId = request.nextUrl.searchParams.get("jobId");
+
+    const deadLetters = await prisma.deadLetter.findMany({
+      where: { job: { userId }, ...(jobId ? { jobId } : {}) },
+      orderBy: { createdAt: "desc" },
+      take: 50,
+    });
+    return NextResponse.json({ deadLetters });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
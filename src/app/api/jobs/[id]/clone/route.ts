@@ -0,0 +1,179 @@
+import { NextRequest, NextResponse } from "next/server";
+import type { Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { requireApiUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { jobCloneSchema } from "@/lib/validation";
+import { computeNextRunAt, offsetSchedule } from "@/lib/schedule";
+import { toMaskedApiJob } from "@/lib/jobs";
+import { toSavableChannelConfig } from "@/lib/saved-channels";
+import { recordAudit } from "@/lib/audit";
+import { getEntitlements, getJobUsage } from "@/lib/entitlements";
+import { LimitError } from "@/lib/limit-errors";
+
+type Params = { params: Promise<{ id: string }> };
+
+// Duplicates a job (settings, published prompt version, channel) with an optional schedule offset
+// and channel swap. Run history, replies, and eval suites stay with the source job.
+export async function POST(request: NextRequest, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+    const payload = await request.json().catch(() => ({}));
+    const parsed = jobCloneSchema.parse(payload);
+
+    const source = await prisma.job.findFirst({
+      where: { id, userId },
+      include: { publishedPromptVersion: true },
+    });
+    if (!source) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    const [entitlements, usage] = await Promise.all([getEntitlements(userId), getJobUsage(userId)]);
+    if (usage.totalJobs >= entitlements.limits.totalJobsLimit) {
+      throw new LimitError("Total job limit exceeded", "LIMIT_TOTAL_JOBS", {
+        limit: entitlements.limits.totalJobsLimit,
+        used: usage.totalJobs,
+      });
+    }
+    if (parsed.enabled && usage.enabledJobs >= entitlements.limits.enabledJobsLimit) {
+      throw new LimitError("Enabled job limit exceeded", "LIMIT_ENABLED_JOBS", {
+        limit: entitlements.limits.enabledJobsLimit,
+        used: usage.enabledJobs,
+      });
+    }
+
+    const schedule = offsetSchedule(
+      {
+        scheduleType: source.scheduleType,
+        scheduleTime: source.scheduleTime,
+        scheduleDayOfWeek: source.scheduleDayOfWeek,
+        scheduleCron: source.scheduleCron,
+        timezone: source.timezone,
+        runAt: source.runAt,
+        weekdaysOnly: source.weekdaysOnly,
+      },
+      parsed.scheduleOffsetMinutes,
+    );
+    const nextRunAt = computeNextRunAt({
+      scheduleType: source.scheduleType,
+      scheduleTime: schedule.scheduleTime,
+      scheduleDayOfWeek: schedule.scheduleDayOfWeek,
+      scheduleCron: source.scheduleCron,
+      timezone: source.timezone,
+      runAt: schedule.runAt,
+      weekdaysOnly: source.weekdaysOnly,
+    });
+
+    const { channelId, channelType, channelConfig } = parsed.channel
+      ? { channelId: null, ...(await toSavableChannelConfig(parsed.channel)) }
+      : { channelId: source.channelId, channelType: source.channelType, channelConfig: source.channelConfig as Prisma.InputJsonValue };
+
+    const version = source.publishedPromptVersion;
+    const job = await prisma.job.create({
+      data: {
+        userId,
+        name: parsed.name ?? `${source.name} (copy)`.slice(0, 100),
+        prompt: source.prompt,
+        postPrompt: source.postPrompt,
+        postPromptEnabled: source.postPromptEnabled,
+        allowWebSearch: source.allowWebSearch,
+        llmModel: source.llmModel,
+        fallbackModels: source.fallbackModels,
+        webSearchMode: source.webSearchMode,
+        llmTools: source.llmTools as Prisma.InputJsonValue,
+        pipelineSteps: source.pipelineSteps as Prisma.InputJsonValue,
+        inputs: source.inputs as Prisma.InputJsonValue,
+        systemPrompt: source.systemPrompt,
+        maxOutputTokens: source.maxOutputTokens,
+        temperature: source.temperature,
+        reasoningEffort: source.reasoningEffort,
+        verbosity: source.verbosity,
+        showHeader: source.showHeader,
+        headerTemplate: source.headerTemplate,
+        headerLocale: source.headerLocale,
+        scheduleType: source.scheduleType,
+        scheduleTime: schedule.scheduleTime,
+        scheduleDayOfWeek: schedule.scheduleDayOfWeek,
+        scheduleCron: source.scheduleCron,
+        timezone: source.timezone,
+        runAt: schedule.runAt,
+        weekdaysOnly: source.weekdaysOnly,
+        catchupPolicy: source.catchupPolicy,
+        quietHoursStart: source.quietHoursStart,
+        quietHoursEnd: source.quietHoursEnd,
+        blackoutDates: source.blackoutDates,
+        channelId,
+        channelType,
+        channelConfig,
+        enabled: parsed.enabled,
+        nextRunAt,
+        userAgent: source.userAgent,
+        deliveryDelayMinutes: source.deliveryDelayMinutes,
+        acceptReplies: source.acceptReplies,
+        outageNotice: source.outageNotice,
+        failureNotice: source.failureNotice,
+        includePreviousOutput: source.includePreviousOutput,
+        previousOutputCount: source.previousOutputCount,
+        deliverIf: source.deliverIf,
+        deliverIfPattern: source.deliverIfPattern,
+        deliveryDiff: source.deliveryDiff,
+        deliveryDiffOnly: source.deliveryDiffOnly,
+        fullOutputLink: source.fullOutputLink,
+        throttleLimit: source.throttleLimit,
+        throttleWindow: source.throttleWindow,
+        qualitySampleRate: source.qualitySampleRate,
+        qualityRubric: source.qualityRubric,
+        historyRetentionDays: source.historyRetentionDays,
+        responseCacheTtlMinutes: source.responseCacheTtlMinutes,
+        autoDisableThreshold: source.autoDisableThreshold,
+        autoDisablePolicy: source.autoDisablePolicy,
+        expectedRuntimeSeconds: source.expectedRuntimeSeconds,
+        slowRunNotice: source.slowRunNotice,
+        maxOutputChars: source.maxOutputChars,
+        ttsVoice: source.ttsVoice,
+        dependsOnJobId: source.dependsOnJobId,
+        includeUpstreamOutput: source.includeUpstreamOutput,
+        webhookRetrySchedule: source.webhookRetrySchedule,
+        tags: source.tags,
+        environment: source.environment,
+        priority: source.priority,
+        concurrencyGroup: source.concurrencyGroup,
+        promptVersions: {
+          create: {
+            template: version?.template ?? source.prompt,
+            postPrompt: version ? version.postPrompt : source.postPrompt,
+            postPromptEnabled: version ? version.postPromptEnabled : source.postPromptEnabled,
+            variables: (version?.variables ?? {}) as Prisma.InputJsonValue,
+          },
+        },
+      },
+      include: { promptVersions: { orderBy: { createdAt: "desc" }, take: 1 } },
+    });
+
+    const latest = job.promptVersions[0];
+    const updated = await prisma.job.update({
+      where: { id: job.id },
+      data: { publishedPromptVersionId: latest?.id ?? null },
+    });
+
+    await recordAudit({
+      userId,
+      action: "job.clone",
+      entityType: "job",
+      entityId: updated.id,
+      data: {
+        sourceJobId: source.id,
+        scheduleOffsetMinutes: parsed.scheduleOffsetMinutes,
+        channelType: updated.channelType,
+        channelSwapped: !!parsed.channel,
+        enabled: updated.enabled,
+      },
+    });
+
+    return NextResponse.json({ job: toMaskedApiJob(updated) });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,39 @@
+import { NextRequest, NextResponse } from "next/server";
+import { z } from "zod";
+
+import { prisma } from "@/lib/prisma";
+import { requireApiUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+import { DEBUG_RUNS_MAX } from "@/lib/debug-capture";
+
+type Params = { params: Promise<{ id: string }> };
+
+const bodySchema = z.object({ runs: z.number().int().min(0).max(DEBUG_RUNS_MAX) });
+
+// Turns on debug capture for the job's next `runs` scheduled runs (0 turns it off). Captured payloads are
+// returned with the run in GET /api/jobs/:id/histories as `debugCapture`.
+export async function PUT(request: NextRequest, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+    const parsed = bodySchema.parse(await request.json());
+
+    const updated = await prisma.job.updateMany({ where: { id, userId }, data: { debugRunsRemaining: parsed.runs } });
+    if (updated.count !== 1) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    await recordAudit({
+      userId,
+      action: "job.debug",
+      entityType: "job",
+      entityId: id,
+      data: { runs: parsed.runs },
+    });
+
+    return NextResponse.json({ debugRunsRemaining: parsed.runs });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
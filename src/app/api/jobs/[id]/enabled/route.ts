@@ -2,7 +2,7 @@ import { NextRequest, NextResponse } from "next/server";
 import { z } from "zod";
 
 import { prisma } from "@/lib/prisma";
-import { requireUserId } from "@/lib/authz";
+import { requireApiUserId } from "@/lib/authz";
 import { errorResponse } from "@/lib/http";
 import { computeNextRunAt } from "@/lib/schedule";
 import { toMaskedApiJob } from "@/lib/jobs";
@@ -16,7 +16,7 @@ const bodySchema = z.object({ enabled: z.boolean() });
 
 export async function PUT(request: NextRequest, { params }: Params) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     const { id } = await params;
     const payload = await request.json();
     const parsed = bodySchema.parse(payload);
@@ -30,6 +30,9 @@ export async function PUT(request: NextRequest, { params }: Params) {
         scheduleTime: true,
         scheduleDayOfWeek: true,
         scheduleCron: true,
+        timezone: true,
+        runAt: true,
+        weekdaysOnly: true,
       },
     });
 
@@ -54,6 +57,9 @@ export async function PUT(request: NextRequest, { params }: Params) {
           scheduleTime: existing.scheduleTime,
           scheduleDayOfWeek: existing.scheduleDayOfWeek,
           scheduleCron: existing.scheduleCron,
+          timezone: existing.timezone,
+          runAt: existing.runAt,
+          weekdaysOnly: existing.weekdaysOnly,
         })
       : undefined;
 
@@ -62,6 +68,8 @@ export async function PUT(request: NextRequest, { params }: Params) {
       data: {
         enabled: parsed.enabled,
         nextRunAt: nextRunAt ?? undefined,
+        syncHash: null,
+        ...(enabling ? { dormantReason: null, dormantAt: null, failCount: 0, retryAttempt: 0, autoPausedUntil: null, configError: null } : {}),
       },
     });
 
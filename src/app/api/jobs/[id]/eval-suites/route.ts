@@ -1,7 +1,7 @@
 import { NextRequest, NextResponse } from "next/server";
 import { z } from "zod";
 import { prisma } from "@/lib/prisma";
-import { requireUserId } from "@/lib/authz";
+import { requireApiUserId } from "@/lib/authz";
 import { errorResponse } from "@/lib/http";
 
 type Params = { params: Promise<{ id: string }> };
@@ -21,7 +21,7 @@ const createSuiteSchema = z.object({
 
 export async function GET(_: NextRequest, { params }: Params) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     const { id: jobId } = await params;
 
     const job = await prisma.job.findFirst({ where: { id: jobId, userId }, select: { id: true } });
@@ -45,7 +45,7 @@ export async function GET(_: NextRequest, { params }: Params) {
 
 export async function POST(request: NextRequest, { params }: Params) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     const { id: jobId } = await params;
     const payload = createSuiteSchema.parse(await request.json());
 
@@ -1,13 +1,17 @@
 import { NextResponse } from "next/server";
 import { prisma } from "@/lib/prisma";
-import { requireUserId } from "@/lib/authz";
+import { requireApiUserId } from "@/lib/authz";
 import { errorResponse } from "@/lib/http";
 
 type Params = { params: Promise<{ id: string }> };
 
-export async function GET(_: Request, { params }: Params) {
+const DEFAULT_LIMIT = 50;
+const MAX_LIMIT = 200;
+
+// Newest first. ?limit= (max 200) and ?before=<runAt ISO> page through older runs.
+export async function GET(request: Request, { params }: Params) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     const { id } = await params;
 
     const job = await prisma.job.findFirst({ where: { id, userId }, select: { id: true } });
@@ -15,10 +19,18 @@ export async function GET(_: Request, { params }: Params) {
       return NextResponse.json({ error: "Not found" }, { status: 404 });
     }
 
+    const searchParams = new URL(request.url).searchParams;
+    const requested = Number(searchParams.get("limit") ?? DEFAULT_LIMIT);
+    const limit = Number.isFinite(requested) && requested > 0 ? Math.min(Math.floor(requested), MAX_LIMIT) : DEFAULT_LIMIT;
+    const before = searchParams.get("before") ? new Date(searchParams.get("before") ?? "") : null;
+    if (before && Number.isNaN(before.getTime())) {
+      return NextResponse.json({ error: "before must be an ISO date" }, { status: 400 });
+    }
+
     const histories = await prisma.runHistory.findMany({
-      where: { jobId: id },
+      where: { jobId: id, ...(before ? { runAt: { lt: before } } : {}) },
       orderBy: { runAt: "desc" },
-      take: 50,
+      take: limit,
     });
     return NextResponse.json({ histories });
   } catch (error) {
@@ -1,19 +1,29 @@
 import { NextRequest, NextResponse } from "next/server";
-import { formatRunTitle } from "@/lib/run-title";
+import { renderRunHeader } from "@/lib/run-title";
 import { ChannelType, Prisma } from "@prisma/client";
 import { z } from "zod";
 
 import { prisma } from "@/lib/prisma";
-import { requireUserId } from "@/lib/authz";
+import { requireApiUserId } from "@/lib/authz";
 import { errorResponse } from "@/lib/http";
 import { runPrompt } from "@/lib/llm";
+import { normalizeLlmTools } from "@/lib/llm-tools";
 import { sendChannelMessage } from "@/lib/channel";
-import { toRunnableChannel } from "@/lib/jobs";
+import { runnableJobChannel } from "@/lib/saved-channels";
 import { enforceDailyRunLimit } from "@/lib/limits";
+import { getEntitlements } from "@/lib/entitlements";
 import { getOrCreatePublishedPromptVersion } from "@/lib/prompt-version";
 import { normalizeLlmModel, normalizeWebSearchMode } from "@/lib/llm-defaults";
+import { resolvePreviewModel } from "@/lib/model-router";
 import { compilePromptTemplate, coerceStringVars } from "@/lib/prompt-compile";
+import { formatPreviousOutputsForPrompt, loadPreviousOutputs } from "@/lib/previous-output";
 import { buildPostPromptVariables, normalizePostPromptConfig } from "@/lib/post-prompt";
+import { normalizePipelineSteps, runPipelineSteps } from "@/lib/pipeline";
+import { generationParamsFromJob } from "@/lib/generation-params";
+import { fetchPromptInputs, formatInputsForPrompt, normalizePromptInputs } from "@/lib/prompt-inputs";
+import { loadUserSecrets, redactSecrets, redactSecretsInJson, secretTemplateFunctions, usesSecrets } from "@/lib/secrets";
+import { runUsageColumns } from "@/lib/usage-cost";
+import { GENERATED_RUN_STATUSES } from "@/lib/run-status";
 
 export const maxDuration = 300;
 
@@ -28,7 +38,7 @@ const bodySchema = z.object({
 
 export async function POST(request: NextRequest, { params }: Params) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     await enforceDailyRunLimit(userId);
     const { id } = await params;
     const body = bodySchema.parse(await request.json());
@@ -44,8 +54,32 @@ export async function POST(request: NextRequest, { params }: Params) {
 
     const pv = job.publishedPromptVersion ?? (await getOrCreatePublishedPromptVersion(job.id));
     const vars = coerceStringVars(pv.variables);
-    const prompt = compilePromptTemplate(pv.template, vars);
-    const modelId = normalizeLlmModel(job.llmModel);
+    const pipelineSteps = normalizePipelineSteps(job.pipelineSteps);
+    const secrets = usesSecrets(pv.template, pv.postPrompt ?? job.postPrompt, ...pipelineSteps.map((step) => step.template))
+      ? await loadUserSecrets(userId)
+      : {};
+    const secretFunctions = secretTemplateFunctions(secrets);
+    const lastRun = await prisma.runHistory.findFirst({
+      where: { jobId: job.id, isPreview: false, status: { in: GENERATED_RUN_STATUSES } },
+      orderBy: { runAt: "desc" },
+      select: { runAt: true },
+    });
+    const compileContext = {
+      timezone: job.timezone ?? "UTC",
+      jobName: job.name,
+      lastRunAt: lastRun?.runAt ?? null,
+      functions: secretFunctions,
+    };
+    const compiledPrompt = compilePromptTemplate(pv.template, vars, compileContext);
+    const previousBlock = job.includePreviousOutput
+      ? formatPreviousOutputsForPrompt(await loadPreviousOutputs(job.id, job.previousOutputCount))
+      : "";
+    const inputs = normalizePromptInputs(job.inputs);
+    const inputsBlock = inputs.length
+      ? formatInputsForPrompt(await fetchPromptInputs(inputs, { plan: (await getEntitlements(userId)).plan, userAgent: job.userAgent }))
+      : "";
+    const prompt = [compiledPrompt, inputsBlock, previousBlock].filter(Boolean).join("\n\n");
+    const modelId = resolvePreviewModel(normalizeLlmModel(job.llmModel), prompt, job.tags);
     const now = new Date();
 
     let runHistoryId: string | null = null;
@@ -75,9 +109,37 @@ export async function POST(request: NextRequest, { params }: Params) {
         model: modelId,
         useWebSearch: job.allowWebSearch,
         webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+        tools: normalizeLlmTools(job.llmTools),
+        systemPrompt: job.systemPrompt,
+        generation: generationParamsFromJob(job),
       });
 
-      let output = result.output;
+      let output = redactSecrets(result.output, secrets);
+      let stepUsages: unknown[] = [];
+      let stepToolCalls: unknown[] = [];
+      if (pipelineSteps.length) {
+        const pipeline = await runPipelineSteps(pipelineSteps, {
+          output,
+          variables: vars,
+          citations: result.citations,
+          usedWebSearch: result.usedWebSearch,
+          llmModel: result.llmModel ?? modelId,
+          compileContext,
+          run: (stepPrompt) =>
+            runPrompt(stepPrompt, {
+              model: modelId,
+              useWebSearch: false,
+              webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+              systemPrompt: job.systemPrompt,
+          generation: generationParamsFromJob(job),
+              generation: generationParamsFromJob(job),
+            }),
+          clean: (stepOutput) => redactSecrets(stepOutput, secrets),
+        });
+        output = pipeline.output;
+        stepUsages = pipeline.usages;
+        stepToolCalls = pipeline.toolCalls;
+      }
       let postPromptApplied = false;
       let postUsage: unknown = null;
       let postToolCalls: unknown = null;
@@ -90,36 +152,50 @@ export async function POST(request: NextRequest, { params }: Params) {
           postPromptConfig.template,
           buildPostPromptVariables({
             baseVariables: vars,
-            output: result.output,
+            output,
             citations: result.citations,
             usedWebSearch: result.usedWebSearch,
             llmModel: result.llmModel ?? modelId,
           }),
+          compileContext,
         );
         const post = await runPrompt(postPrompt, {
           model: modelId,
           useWebSearch: false,
           webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+          systemPrompt: job.systemPrompt,
         });
-        output = post.output;
+        output = redactSecrets(post.output, secrets);
         postUsage = post.llmUsage ?? null;
         postToolCalls = post.llmToolCalls ?? null;
         postPromptApplied = true;
       }
 
+      const staged = postPromptApplied || stepUsages.length > 0;
+      const stagedUsage = {
+        primary: result.llmUsage ?? null,
+        ...(stepUsages.length ? { steps: stepUsages } : {}),
+        ...(postPromptApplied ? { post: postUsage } : {}),
+      };
+      const stagedToolCalls = {
+        primary: result.llmToolCalls ?? null,
+        ...(stepToolCalls.length ? { steps: stepToolCalls } : {}),
+        ...(postPromptApplied ? { post: postToolCalls } : {}),
+      };
       const llmUsageValue =
-        postPromptApplied
-          ? ({ primary: result.llmUsage ?? null, post: postUsage } as Prisma.InputJsonValue)
+        staged
+          ? (stagedUsage as Prisma.InputJsonValue)
           : result.llmUsage == null
             ? Prisma.DbNull
             : (result.llmUsage as Prisma.InputJsonValue);
       const llmToolCallsValue =
-        postPromptApplied
-          ? ({ primary: result.llmToolCalls ?? null, post: postToolCalls } as Prisma.InputJsonValue)
+        staged
+          ? (redactSecretsInJson(stagedToolCalls, secrets) as Prisma.InputJsonValue)
           : result.llmToolCalls == null
             ? Prisma.DbNull
-            : (result.llmToolCalls as Prisma.InputJsonValue);
+            : (redactSecretsInJson(result.llmToolCalls, secrets) as Prisma.InputJsonValue);
       const citationsValue = (result.citations as unknown as Prisma.InputJsonValue) ?? Prisma.DbNull;
+      const usageForCost = staged ? stagedUsage : result.llmUsage;
 
       if (runHistoryId) {
         await prisma.runHistory.update({
@@ -131,6 +207,7 @@ export async function POST(request: NextRequest, { params }: Params) {
             errorMessage: null,
             llmModel: result.llmModel ?? null,
             llmUsage: llmUsageValue,
+            ...runUsageColumns(result.llmModel ?? modelId, usageForCost),
             llmToolCalls: llmToolCallsValue,
             usedWebSearch: result.usedWebSearch,
             citations: citationsValue,
@@ -138,13 +215,14 @@ export async function POST(request: NextRequest, { params }: Params) {
         });
       }
 
-      const title = formatRunTitle(job.name, now);
+      const title = renderRunHeader(job, now, job.timezone ?? "UTC");
 
       if (body.testSend) {
-        await sendChannelMessage(toRunnableChannel(job), title, output, {
+        await sendChannelMessage(await runnableJobChannel(job), title, output, {
           citations: result.citations,
           usedWebSearch: result.usedWebSearch,
-          meta: { kind: "job-preview", jobId: job.id, promptVersionId: pv.id },
+          meta: { kind: "job-preview", jobId: job.id, jobName: job.name, promptVersionId: pv.id },
+          plan: (await getEntitlements(userId)).plan,
         });
 
         if (runHistoryId) {
@@ -173,7 +251,7 @@ export async function POST(request: NextRequest, { params }: Params) {
         postPromptWarning: postPromptConfig.warning,
       });
     } catch (err) {
-      const message = err instanceof Error ? err.message : String(err);
+      const message = redactSecrets(err instanceof Error ? err.message : String(err), secrets);
       if (runHistoryId) {
         await prisma.runHistory.update({
           where: { id: runHistoryId },
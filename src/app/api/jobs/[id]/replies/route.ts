@@ -0,0 +1,47 @@
+import { NextRequest, NextResponse } from "next/server";
+
+import { prisma } from "@/lib/prisma";
+import { verifyToken } from "@/lib/crypto";
+import { parseIncomingReply } from "@/lib/replies";
+import { logger } from "@/lib/logger";
+
+export const runtime = "nodejs";
+export const dynamic = "force-dynamic";
+
+type Params = { params: Promise<{ id: string }> };
+
+// Reply ingestion for delivered messages. Point a Telegram bot webhook (secret_token) or a Discord relay here;
+// the token is shown in the job's advanced settings once reply capture is enabled.
+export async function POST(request: NextRequest, { params }: Params) {
+  const { id } = await params;
+  const token =
+    request.nextUrl.searchParams.get("token") ?? request.headers.get("x-telegram-bot-api-secret-token") ?? "";
+  if (!token || !verifyToken("job-replies", id, token)) {
+    return new Response("Unauthorized", { status: 401 });
+  }
+
+  const job = await prisma.job.findUnique({ where: { id }, select: { id: true, acceptReplies: true } });
+  if (!job || !job.acceptReplies) {
+    return NextResponse.json({ error: "Not found" }, { status: 404 });
+  }
+
+  let body: unknown;
+  try {
+    body = await request.json();
+  } catch {
+    return NextResponse.json({ error: "Invalid JSON body" }, { status: 400 });
+  }
+
+  // Unrelated updates (e.g. non-reply Telegram messages) are acknowledged so the sender does not retry them.
+  const reply = parseIncomingReply(body);
+  if (!reply) {
+    return NextResponse.json({ ok: true, captured: false });
+  }
+
+  await prisma.jobReply.create({
+    data: { jobId: job.id, source: reply.source, author: reply.author, text: reply.text },
+  });
+  logger.info("job reply captured", { job_id: job.id, source: reply.source });
+
+  return NextResponse.json({ ok: true, captured: true });
+}
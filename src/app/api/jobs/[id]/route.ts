@@ -1,19 +1,35 @@
 import { NextRequest, NextResponse } from "next/server";
 import { prisma } from "@/lib/prisma";
-import { requireUserId } from "@/lib/authz";
+import { requireApiUserId } from "@/lib/authz";
 import { errorResponse } from "@/lib/http";
 import { jobUpsertSchema } from "@/lib/validation";
 import { computeNextRunAt } from "@/lib/schedule";
-import { toDbChannelConfig, toMaskedApiJob } from "@/lib/jobs";
+import { toDbJobSettings, toMaskedApiJob } from "@/lib/jobs";
+import { toDbJobChannel } from "@/lib/saved-channels";
+import { assertJobDependency } from "@/lib/job-dependencies";
 import { recordAudit } from "@/lib/audit";
 import { getEntitlements, getJobUsage } from "@/lib/entitlements";
 import { LimitError } from "@/lib/limit-errors";
 
 type Params = { params: Promise<{ id: string }> };
 
+export async function GET(_: NextRequest, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+    const job = await prisma.job.findFirst({ where: { id, userId } });
+    if (!job) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+    return NextResponse.json({ job: toMaskedApiJob(job) });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
+
 export async function PUT(request: NextRequest, { params }: Params) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     const { id } = await params;
     const payload = await request.json();
     const parsed = jobUpsertSchema.parse(payload);
@@ -36,12 +52,16 @@ export async function PUT(request: NextRequest, { params }: Params) {
       }
     }
 
-    const { channelType, channelConfig } = toDbChannelConfig(parsed.channel);
+    const { channelId, channelType, channelConfig } = await toDbJobChannel(userId, parsed);
+    await assertJobDependency(userId, id, parsed.dependsOnJobId);
     const nextRunAt = computeNextRunAt({
       scheduleType: parsed.scheduleType,
       scheduleTime: parsed.scheduleTime,
       scheduleDayOfWeek: parsed.scheduleDayOfWeek,
       scheduleCron: parsed.scheduleCron,
+      timezone: parsed.timezone,
+      runAt: parsed.runAt,
+      weekdaysOnly: parsed.weekdaysOnly,
     });
 
     const job = await prisma.job.update({
@@ -58,10 +78,22 @@ export async function PUT(request: NextRequest, { params }: Params) {
         scheduleTime: parsed.scheduleTime,
         scheduleDayOfWeek: parsed.scheduleDayOfWeek,
         scheduleCron: parsed.scheduleCron,
+        runAt: parsed.runAt,
+        weekdaysOnly: parsed.weekdaysOnly,
+        completedAt: null,
+        catchupPolicy: parsed.catchupPolicy,
+        channelId,
         channelType,
         channelConfig,
+        credentialCheckedAt: null,
+        credentialError: null,
+        configError: null,
         enabled: parsed.enabled,
+        // Edits to a synced job are drift; the next sync re-applies its spec.
+        syncHash: null,
         nextRunAt,
+        ...(enabling ? { dormantReason: null, dormantAt: null, failCount: 0, retryAttempt: 0, autoPausedUntil: null, configError: null } : {}),
+        ...toDbJobSettings(parsed),
         promptVersions: {
           create: {
             template: parsed.template,
@@ -116,7 +148,7 @@ export async function PUT(request: NextRequest, { params }: Params) {
 
 export async function DELETE(_: NextRequest, { params }: Params) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     const { id } = await params;
 
     await prisma.job.deleteMany({ where: { id, userId } });
@@ -0,0 +1,63 @@
+import { NextResponse } from "next/server";
+import { prisma } from "@/lib/prisma";
+import { requireApiUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { enforceDailyRunLimit } from "@/lib/limits";
+import { recordAudit } from "@/lib/audit";
+
+type Params = { params: Promise<{ id: string }> };
+
+const RECENT_REQUESTS = 10;
+
+// Queues a one-off run of the job outside its schedule; the next worker tick executes and delivers it and
+// records a normal run history row. next_run_at is not changed. A request already waiting is returned as is.
+export async function POST(_: Request, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+
+    const job = await prisma.job.findFirst({ where: { id, userId }, select: { id: true } });
+    if (!job) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+    await enforceDailyRunLimit(userId);
+
+    const pending = await prisma.runRequest.findFirst({
+      where: { jobId: job.id, status: { in: ["pending", "running"] } },
+      orderBy: { createdAt: "desc" },
+    });
+    if (pending) {
+      return NextResponse.json({ ok: true, request: pending }, { status: 202 });
+    }
+
+    const created = await prisma.runRequest.create({ data: { jobId: job.id, userId } });
+    await recordAudit({
+      userId,
+      action: "job.run_now",
+      entityType: "job",
+      entityId: job.id,
+      data: { runRequestId: created.id },
+    });
+    return NextResponse.json({ ok: true, request: created }, { status: 202 });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
+
+// Recent run-now requests for the job, newest first, with the resulting run once the worker has finished.
+export async function GET(_: Request, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+
+    const requests = await prisma.runRequest.findMany({
+      where: { jobId: id, job: { userId } },
+      orderBy: { createdAt: "desc" },
+      take: RECENT_REQUESTS,
+      include: { runHistory: { select: { id: true, status: true, runAt: true, outputPreview: true, errorMessage: true, deliveredAt: true } } },
+    });
+    return NextResponse.json({ requests });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,48 @@
+import { NextRequest, NextResponse } from "next/server";
+import { z } from "zod";
+
+import { prisma } from "@/lib/prisma";
+import { requireApiUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+
+type Params = { params: Promise<{ id: string }> };
+
+const SNOOZE_MAX_DAYS = 366;
+
+const bodySchema = z.object({ until: z.string().datetime({ offset: true }).nullable() });
+
+// Snoozes the job until `until` (null ends the snooze). The schedule is kept: slots that fall inside the snooze
+// are skipped and the job resumes at its first regular slot after it. Run-now requests still run.
+export async function PUT(request: NextRequest, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+    const parsed = bodySchema.parse(await request.json());
+
+    const until = parsed.until ? new Date(parsed.until) : null;
+    if (until && until.getTime() <= Date.now()) {
+      return NextResponse.json({ error: "until must be in the future" }, { status: 400 });
+    }
+    if (until && until.getTime() > Date.now() + SNOOZE_MAX_DAYS * 24 * 60 * 60 * 1000) {
+      return NextResponse.json({ error: `until must be within ${SNOOZE_MAX_DAYS} days` }, { status: 400 });
+    }
+
+    const updated = await prisma.job.updateMany({ where: { id, userId }, data: { pausedUntil: until } });
+    if (updated.count !== 1) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    await recordAudit({
+      userId,
+      action: "job.snooze",
+      entityType: "job",
+      entityId: id,
+      data: { pausedUntil: until },
+    });
+
+    return NextResponse.json({ pausedUntil: until });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,129 @@
+import { NextRequest, NextResponse } from "next/server";
+import { prisma } from "@/lib/prisma";
+import { requireApiUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { jobBulkSchema } from "@/lib/validation";
+import { computeNextRunAt, offsetSchedule, type ScheduleInput } from "@/lib/schedule";
+import { toMaskedApiJob } from "@/lib/jobs";
+import { toSavableChannelConfig } from "@/lib/saved-channels";
+import { recordAudit } from "@/lib/audit";
+import { getEntitlements, getJobUsage } from "@/lib/entitlements";
+import { LimitError } from "@/lib/limit-errors";
+
+// Applies one change set (enable/disable, reschedule, re-channel) to many jobs in a single
+// transaction: either every selected job is updated or none is.
+export async function POST(request: NextRequest) {
+  try {
+    const userId = await requireApiUserId();
+    const payload = await request.json();
+    const parsed = jobBulkSchema.parse(payload);
+    const { set } = parsed;
+
+    const jobs = await prisma.job.findMany({
+      where: {
+        userId,
+        ...(parsed.ids?.length ? { id: { in: parsed.ids } } : {}),
+        ...(parsed.tags?.length ? { tags: { hasEvery: parsed.tags } } : {}),
+      },
+      orderBy: { createdAt: "asc" },
+    });
+    if (jobs.length === 0) {
+      return NextResponse.json({ updated: 0, jobs: [] });
+    }
+
+    if (set.enabled) {
+      const enabling = jobs.filter((job) => !job.enabled).length;
+      if (enabling > 0) {
+        const [entitlements, usage] = await Promise.all([getEntitlements(userId), getJobUsage(userId)]);
+        if (usage.enabledJobs + enabling > entitlements.limits.enabledJobsLimit) {
+          throw new LimitError("Enabled job limit exceeded", "LIMIT_ENABLED_JOBS", {
+            limit: entitlements.limits.enabledJobsLimit,
+            used: usage.enabledJobs,
+          });
+        }
+      }
+    }
+
+    const channel = set.channel ? { channelId: null, ...(await toSavableChannelConfig(set.channel)) } : null;
+
+    // Compute every schedule before writing so one invalid job rejects the whole batch.
+    const updates = jobs.map((job) => {
+      const enabled = set.enabled ?? job.enabled;
+      let schedule: ScheduleInput = {
+        scheduleType: job.scheduleType,
+        scheduleTime: job.scheduleTime,
+        scheduleDayOfWeek: job.scheduleDayOfWeek,
+        scheduleCron: job.scheduleCron,
+        timezone: job.timezone,
+        runAt: job.runAt,
+        weekdaysOnly: job.weekdaysOnly,
+      };
+      const rescheduled = !!set.schedule || !!set.scheduleOffsetMinutes;
+      try {
+        if (set.schedule) {
+          schedule = {
+            ...schedule,
+            scheduleType: set.schedule.scheduleType,
+            scheduleTime: set.schedule.scheduleType === "cron" ? "00:00" : set.schedule.scheduleTime!,
+            scheduleDayOfWeek: set.schedule.scheduleType === "weekly" ? set.schedule.scheduleDayOfWeek! : null,
+            scheduleCron: set.schedule.scheduleType === "cron" ? set.schedule.scheduleCron! : null,
+            runAt: null,
+            weekdaysOnly: set.schedule.scheduleType === "daily" && !!set.schedule.weekdaysOnly,
+          };
+        } else if (set.scheduleOffsetMinutes) {
+          schedule = { ...schedule, ...offsetSchedule(schedule, set.scheduleOffsetMinutes) };
+        }
+        const needsNextRun = enabled && (rescheduled || !job.enabled);
+        return {
+          job,
+          data: {
+            enabled,
+            syncHash: null,
+            ...(rescheduled
+              ? {
+                  scheduleType: schedule.scheduleType,
+                  scheduleTime: schedule.scheduleTime,
+                  scheduleDayOfWeek: schedule.scheduleDayOfWeek ?? null,
+                  scheduleCron: schedule.scheduleCron ?? null,
+                  runAt: schedule.runAt ?? null,
+                  weekdaysOnly: !!schedule.weekdaysOnly,
+                  completedAt: null,
+                }
+              : {}),
+            ...(needsNextRun ? { nextRunAt: computeNextRunAt(schedule), retryAttempt: 0 } : {}),
+            ...(enabled && !job.enabled ? { dormantReason: null, dormantAt: null, failCount: 0, retryAttempt: 0, autoPausedUntil: null, configError: null } : {}),
+            ...(channel ?? {}),
+          },
+        };
+      } catch (error) {
+        throw new Error(`${job.name}: ${error instanceof Error ? error.message : String(error)}`);
+      }
+    });
+
+    const updated = await prisma.$transaction(async (tx) => {
+      const results = [];
+      for (const { job, data } of updates) {
+        results.push(await tx.job.update({ where: { id: job.id }, data }));
+      }
+      return results;
+    });
+
+    await recordAudit({
+      userId,
+      action: "job.bulk_update",
+      entityType: "job",
+      data: {
+        jobIds: updated.map((job) => job.id),
+        tags: parsed.tags ?? [],
+        enabled: set.enabled ?? null,
+        scheduleOffsetMinutes: set.scheduleOffsetMinutes ?? null,
+        scheduleType: set.schedule?.scheduleType ?? null,
+        channelType: channel?.channelType ?? null,
+      },
+    });
+
+    return NextResponse.json({ updated: updated.length, jobs: updated.map(toMaskedApiJob) });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -1,19 +1,22 @@
 import { NextRequest, NextResponse } from "next/server";
 import { prisma } from "@/lib/prisma";
-import { requireUserId } from "@/lib/authz";
+import { requireApiUserId } from "@/lib/authz";
 import { errorResponse } from "@/lib/http";
 import { jobUpsertSchema } from "@/lib/validation";
 import { computeNextRunAt } from "@/lib/schedule";
-import { toDbChannelConfig, toMaskedApiJob } from "@/lib/jobs";
+import { toDbJobSettings, toMaskedApiJob } from "@/lib/jobs";
+import { toDbJobChannel } from "@/lib/saved-channels";
+import { assertJobDependency } from "@/lib/job-dependencies";
 import { recordAudit } from "@/lib/audit";
 import { getEntitlements, getJobUsage } from "@/lib/entitlements";
 import { LimitError } from "@/lib/limit-errors";
 
-export async function GET() {
+export async function GET(request: NextRequest) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
+    const tags = request.nextUrl.searchParams.getAll("tag").filter(Boolean);
     const jobs = await prisma.job.findMany({
-      where: { userId },
+      where: { userId, ...(tags.length ? { tags: { hasEvery: tags } } : {}) },
       orderBy: { createdAt: "desc" },
     });
 
@@ -25,7 +28,7 @@ export async function GET() {
 
 export async function POST(request: NextRequest) {
   try {
-    const userId = await requireUserId();
+    const userId = await requireApiUserId();
     const payload = await request.json();
     const parsed = jobUpsertSchema.parse(payload);
 
@@ -45,12 +48,16 @@ export async function POST(request: NextRequest) {
 
     const variables = parsed.variables ? (JSON.parse(parsed.variables || "{}") as Record<string, string>) : {};
 
-    const { channelType, channelConfig } = toDbChannelConfig(parsed.channel);
+    const { channelId, channelType, channelConfig } = await toDbJobChannel(userId, parsed);
+    await assertJobDependency(userId, null, parsed.dependsOnJobId);
     const nextRunAt = computeNextRunAt({
       scheduleType: parsed.scheduleType,
       scheduleTime: parsed.scheduleTime,
       scheduleDayOfWeek: parsed.scheduleDayOfWeek,
       scheduleCron: parsed.scheduleCron,
+      timezone: parsed.timezone,
+      runAt: parsed.runAt,
+      weekdaysOnly: parsed.weekdaysOnly,
     });
 
     const job = await prisma.job.create({
@@ -67,10 +74,16 @@ export async function POST(request: NextRequest) {
         scheduleTime: parsed.scheduleTime,
         scheduleDayOfWeek: parsed.scheduleDayOfWeek,
         scheduleCron: parsed.scheduleCron,
+        runAt: parsed.runAt,
+        weekdaysOnly: parsed.weekdaysOnly,
+        completedAt: null,
+        catchupPolicy: parsed.catchupPolicy,
+        channelId,
         channelType,
         channelConfig,
         enabled: parsed.enabled,
         nextRunAt,
+        ...toDbJobSettings(parsed),
         promptVersions: {
           create: {
             template: parsed.template,
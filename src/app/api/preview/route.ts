@@ -5,11 +5,18 @@ import { errorResponse } from "@/lib/http";
 import { previewSchema } from "@/lib/validation";
 import { runPrompt } from "@/lib/llm";
 import { sendChannelMessage } from "@/lib/channel";
+import { toRunnableIncomingChannel } from "@/lib/jobs";
+import { resolveChannelSecrets } from "@/lib/saved-channels";
 import { prisma } from "@/lib/prisma";
 import { enforceDailyRunLimit } from "@/lib/limits";
+import { getEntitlements } from "@/lib/entitlements";
 import { normalizeLlmModel } from "@/lib/llm-defaults";
+import { resolvePreviewModel } from "@/lib/model-router";
 import { compilePromptTemplate, coerceStringVars } from "@/lib/prompt-compile";
 import { buildPostPromptVariables, normalizePostPromptConfig } from "@/lib/post-prompt";
+import { runPipelineSteps } from "@/lib/pipeline";
+import { fetchPromptInputs, formatInputsForPrompt } from "@/lib/prompt-inputs";
+import { loadUserSecrets, redactSecrets, secretTemplateFunctions, usesSecrets } from "@/lib/secrets";
 
 export const maxDuration = 300;
 
@@ -22,16 +29,56 @@ export async function POST(request: NextRequest) {
     const now = payload.nowIso ? new Date(payload.nowIso) : new Date();
     const rawVars = JSON.parse(payload.variables || "{}") as unknown;
     const vars = coerceStringVars(rawVars);
-    const prompt = compilePromptTemplate(payload.template, vars, { nowIso: payload.nowIso, timezone: payload.timezone });
+    const stepTemplates = payload.pipelineSteps.map((step) => step.template);
+    const secrets = usesSecrets(payload.template, payload.postPrompt, ...stepTemplates) ? await loadUserSecrets(userId) : {};
+    const secretFunctions = secretTemplateFunctions(secrets);
+    const prompt = compilePromptTemplate(payload.template, vars, {
+      nowIso: payload.nowIso,
+      timezone: payload.timezone,
+      functions: secretFunctions,
+    });
+
+    const inputsBlock = payload.inputs.length
+      ? formatInputsForPrompt(await fetchPromptInputs(payload.inputs, { plan: (await getEntitlements(userId)).plan }))
+      : "";
 
-    const modelId = normalizeLlmModel(payload.llmModel);
-    const result = await runPrompt(prompt, {
+    const modelId = resolvePreviewModel(normalizeLlmModel(payload.llmModel), prompt);
+    const generation = {
+      maxOutputTokens: payload.maxOutputTokens,
+      temperature: payload.temperature,
+      reasoningEffort: payload.reasoningEffort,
+      verbosity: payload.verbosity,
+    };
+    const result = await runPrompt(inputsBlock ? `${prompt}\n\n${inputsBlock}` : prompt, {
       model: modelId,
       useWebSearch: payload.useWebSearch,
       webSearchMode: payload.webSearchMode,
+      tools: payload.llmTools,
+      systemPrompt: payload.systemPrompt,
+      generation,
     });
 
-    let output = result.output;
+    let output = redactSecrets(result.output, secrets);
+    if (payload.pipelineSteps.length) {
+      const pipeline = await runPipelineSteps(payload.pipelineSteps, {
+        output,
+        variables: vars,
+        citations: result.citations,
+        usedWebSearch: result.usedWebSearch,
+        llmModel: result.llmModel ?? modelId,
+        compileContext: { nowIso: payload.nowIso, timezone: payload.timezone, functions: secretFunctions },
+        run: (stepPrompt) =>
+          runPrompt(stepPrompt, {
+            model: modelId,
+            useWebSearch: false,
+            webSearchMode: payload.webSearchMode,
+            systemPrompt: payload.systemPrompt,
+            generation,
+          }),
+        clean: (stepOutput) => redactSecrets(stepOutput, secrets),
+      });
+      output = pipeline.output;
+    }
     let postPromptApplied = false;
     const postPromptConfig = normalizePostPromptConfig({ enabled: payload.postPromptEnabled, template: payload.postPrompt });
     if (postPromptConfig.enabled) {
@@ -39,57 +86,33 @@ export async function POST(request: NextRequest) {
         postPromptConfig.template,
         buildPostPromptVariables({
           baseVariables: vars,
-          output: result.output,
+          output,
           citations: result.citations,
           usedWebSearch: result.usedWebSearch,
           llmModel: result.llmModel ?? modelId,
         }),
-        { nowIso: payload.nowIso, timezone: payload.timezone },
+        { nowIso: payload.nowIso, timezone: payload.timezone, functions: secretFunctions },
       );
 
       const post = await runPrompt(postPrompt, {
         model: modelId,
         useWebSearch: false,
         webSearchMode: payload.webSearchMode,
+        systemPrompt: payload.systemPrompt,
+        generation,
       });
-      output = post.output;
+      output = redactSecrets(post.output, secrets);
       postPromptApplied = true;
     }
     const title = formatRunTitle(payload.name, now, payload.timezone);
 
     if (payload.testSend && payload.channel) {
-      if (payload.channel.type === "discord") {
-        await sendChannelMessage(
-          { type: "discord", webhookUrl: payload.channel.config.webhookUrl },
-          title,
-          output,
-          { citations: result.citations, usedWebSearch: result.usedWebSearch, meta: { kind: "preview" } },
-        );
-      } else if (payload.channel.type === "telegram") {
-        await sendChannelMessage(
-          {
-            type: "telegram",
-            botToken: payload.channel.config.botToken,
-            chatId: payload.channel.config.chatId,
-          },
-          title,
-          output,
-          { citations: result.citations, usedWebSearch: result.usedWebSearch, meta: { kind: "preview" } },
-        );
-      } else {
-        await sendChannelMessage(
-          {
-            type: "webhook",
-            url: payload.channel.config.url,
-            method: payload.channel.config.method,
-            headers: payload.channel.config.headers,
-            payload: payload.channel.config.payload,
-          },
-          title,
-          output,
-          { citations: result.citations, usedWebSearch: result.usedWebSearch, meta: { kind: "preview" } },
-        );
-      }
+      await sendChannelMessage(await resolveChannelSecrets(userId, toRunnableIncomingChannel(payload.channel)), title, output, {
+        citations: result.citations,
+        usedWebSearch: result.usedWebSearch,
+        meta: { kind: "preview" },
+        plan: (await getEntitlements(userId)).plan,
+      });
     }
 
     await prisma.previewEvent.create({ data: { userId } });
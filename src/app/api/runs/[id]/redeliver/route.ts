@@ -0,0 +1,33 @@
+import { NextResponse } from "next/server";
+import { requireApiUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+import { queueRedelivery } from "@/lib/run-redelivery";
+
+type Params = { params: Promise<{ id: string }> };
+
+// Sends a past run's stored output to the job's current channel again without calling the model, e.g. after a
+// delivery failed for good or the message was deleted by accident (see queueRedelivery).
+export async function POST(_: Request, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+
+    const result = await queueRedelivery(userId, id);
+    if (!result.ok) {
+      return NextResponse.json({ error: result.error }, { status: result.status });
+    }
+
+    await recordAudit({
+      userId,
+      action: "run.redeliver",
+      entityType: "run_history",
+      entityId: id,
+      data: { runHistoryId: result.runHistoryId },
+    });
+
+    return NextResponse.json({ ok: true, runHistoryId: result.runHistoryId });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,25 @@
+import { NextResponse } from "next/server";
+import { prisma } from "@/lib/prisma";
+import { requireApiUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { loadRunOutput } from "@/lib/run-outputs";
+
+type Params = { params: Promise<{ id: string }> };
+
+// One run with its full output from run_outputs (output_text for runs recorded before it existed).
+export async function GET(_: Request, { params }: Params) {
+  try {
+    const userId = await requireApiUserId();
+    const { id } = await params;
+
+    const run = await prisma.runHistory.findFirst({ where: { id, job: { userId } } });
+    if (!run) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    const output = await loadRunOutput(run.id);
+    return NextResponse.json({ run, output: output?.text ?? null, outputTruncated: output?.truncated ?? false });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
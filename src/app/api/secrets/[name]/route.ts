@@ -0,0 +1,26 @@
+import { NextResponse } from "next/server";
+
+import { prisma } from "@/lib/prisma";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+
+type Params = { params: Promise<{ name: string }> };
+
+export async function DELETE(_: Request, { params }: Params) {
+  try {
+    const userId = await requireUserId();
+    const { name } = await params;
+
+    const deleted = await prisma.userSecret.deleteMany({ where: { userId, name } });
+    if (!deleted.count) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    await recordAudit({ userId, action: "secret.delete", entityType: "secret", entityId: name });
+
+    return NextResponse.json({ ok: true });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,50 @@
+import { NextRequest, NextResponse } from "next/server";
+import { z } from "zod";
+
+import { prisma } from "@/lib/prisma";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { encryptString } from "@/lib/crypto";
+import { recordAudit } from "@/lib/audit";
+import { SECRET_NAME_RE } from "@/lib/secrets";
+
+const bodySchema = z.object({
+  name: z.string().regex(SECRET_NAME_RE, "Secret names must be UPPER_SNAKE_CASE (max 64 chars)"),
+  value: z.string().min(1).max(4096),
+});
+
+// Lists secret names only; values are write-only.
+export async function GET() {
+  try {
+    const userId = await requireUserId();
+    const secrets = await prisma.userSecret.findMany({
+      where: { userId },
+      select: { name: true, createdAt: true, updatedAt: true },
+      orderBy: { name: "asc" },
+    });
+    return NextResponse.json({ secrets });
+  } catch (error) {
+    return errorResponse(error, 401);
+  }
+}
+
+export async function PUT(request: NextRequest) {
+  try {
+    const userId = await requireUserId();
+    const parsed = bodySchema.parse(await request.json());
+    const valueEnc = encryptString(parsed.value);
+
+    const secret = await prisma.userSecret.upsert({
+      where: { userId_name: { userId, name: parsed.name } },
+      create: { userId, name: parsed.name, valueEnc },
+      update: { valueEnc },
+      select: { name: true, createdAt: true, updatedAt: true },
+    });
+
+    await recordAudit({ userId, action: "secret.upsert", entityType: "secret", entityId: parsed.name });
+
+    return NextResponse.json({ secret });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,27 @@
+import { NextRequest, NextResponse } from "next/server";
+
+import { prisma } from "@/lib/prisma";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+
+type Params = { params: Promise<{ id: string }> };
+
+// Revokes a token; requests using it fail with 401 right away.
+export async function DELETE(_: NextRequest, { params }: Params) {
+  try {
+    const userId = await requireUserId();
+    const { id } = await params;
+
+    const result = await prisma.apiToken.deleteMany({ where: { id, userId } });
+    if (!result.count) {
+      return NextResponse.json({ error: "Not found" }, { status: 404 });
+    }
+
+    await recordAudit({ userId, action: "api_token.delete", entityType: "api_token", entityId: id });
+
+    return NextResponse.json({ ok: true });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
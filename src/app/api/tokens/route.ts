@@ -0,0 +1,39 @@
+import { NextRequest, NextResponse } from "next/server";
+
+import { prisma } from "@/lib/prisma";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { recordAudit } from "@/lib/audit";
+import { apiTokenCreateSchema } from "@/lib/validation";
+import { createApiToken, toApiToken } from "@/lib/api-tokens";
+
+// Token management needs a browser session, like every route outside the jobs and run history API.
+export async function GET() {
+  try {
+    const userId = await requireUserId();
+    const tokens = await prisma.apiToken.findMany({ where: { userId }, orderBy: { createdAt: "desc" } });
+    return NextResponse.json({ tokens: tokens.map(toApiToken) });
+  } catch (error) {
+    return errorResponse(error, 401);
+  }
+}
+
+export async function POST(request: NextRequest) {
+  try {
+    const userId = await requireUserId();
+    const parsed = apiTokenCreateSchema.parse(await request.json());
+    const { token, apiToken } = await createApiToken(userId, parsed);
+
+    await recordAudit({
+      userId,
+      action: "api_token.create",
+      entityType: "api_token",
+      entityId: apiToken.id,
+      data: { name: apiToken.name, tokenPrefix: apiToken.tokenPrefix, expiresAt: apiToken.expiresAt },
+    });
+
+    return NextResponse.json({ token, apiToken }, { status: 201 });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -0,0 +1,26 @@
+import { NextRequest, NextResponse } from "next/server";
+import { requireUserId } from "@/lib/authz";
+import { errorResponse } from "@/lib/http";
+import { getUsageRollup } from "@/lib/usage-cost";
+import { checkMonthlyBudget } from "@/lib/budgets";
+
+const DEFAULT_DAYS = 30;
+const MAX_DAYS = 366;
+
+// Token and estimated cost totals per job (and overall) for the signed-in user.
+export async function GET(request: NextRequest) {
+  try {
+    const userId = await requireUserId();
+    const daysParam = Number(request.nextUrl.searchParams.get("days") ?? DEFAULT_DAYS);
+    const days = Number.isFinite(daysParam) && daysParam > 0 ? Math.min(Math.floor(daysParam), MAX_DAYS) : DEFAULT_DAYS;
+    const jobId = request.nextUrl.searchParams.get("jobId") || undefined;
+
+    const [rollup, budget] = await Promise.all([
+      getUsageRollup(userId, { since: new Date(Date.now() - days * 24 * 60 * 60 * 1000), jobId }),
+      checkMonthlyBudget(userId),
+    ]);
+    return NextResponse.json({ days, ...rollup, budget });
+  } catch (error) {
+    return errorResponse(error);
+  }
+}
@@ -1,4 +1,5 @@
 import type { Metadata } from "next";
+import Link from "next/link";
 import { redirect } from "next/navigation";
 import { getServerSession } from "next-auth";
 import { authOptions } from "@/lib/auth-options";
@@ -10,6 +11,9 @@ import { JobCardActions } from "@/components/ui/job-card-actions";
 import { uiText } from "@/content/ui-text";
 import { PortalButton } from "@/components/billing/portal-button";
 import { JobEnabledToggle } from "@/components/ui/job-enabled-toggle";
+import { runStatusLabel, runStatusPillClass } from "@/lib/run-status";
+import { normalizeLlmModel } from "@/lib/llm-defaults";
+import { modelAliases, resolveModelAlias } from "@/lib/model-aliases";
 
 
 export const dynamic = "force-dynamic";
@@ -28,7 +32,11 @@ function SparkIcon() {
   );
 }
 
-export default async function DashboardPage() {
+type Props = {
+  searchParams: Promise<{ tag?: string }>;
+};
+
+export default async function DashboardPage({ searchParams }: Props) {
   const session = await getServerSession(authOptions);
   if (!session?.user?.id) {
     redirect("/signin?callbackUrl=/dashboard");
@@ -37,17 +45,20 @@ export default async function DashboardPage() {
   const user = await prisma.user.findUnique({ where: { id: session.user.id }, select: { plan: true } });
   const plan = user?.plan === "pro" ? "pro" : "free";
 
+  const { tag } = await searchParams;
   const jobs = await prisma.job.findMany({
-    where: { userId: session.user.id },
+    where: { userId: session.user.id, ...(tag ? { tags: { has: tag } } : {}) },
     include: {
       runHistories: {
         orderBy: { runAt: "desc" },
         take: 1,
       },
+      _count: { select: { deadLetters: { where: { requeuedAt: null } } } },
     },
     orderBy: { createdAt: "desc" },
   });
   const hasJobs = jobs.length > 0;
+  const aliases = modelAliases();
 
   return (
     <main className="page-shell">
@@ -60,6 +71,14 @@ export default async function DashboardPage() {
             <p className="mt-2 inline-flex items-center rounded-full border border-zinc-200 bg-zinc-50 px-2.5 py-1 text-xs text-zinc-600">
               {uiText.dashboard.totalJobs(jobs.length)}
             </p>
+            {tag ? (
+              <p className="ml-2 mt-2 inline-flex items-center gap-2 rounded-full border border-zinc-200 bg-white px-2.5 py-1 text-xs text-zinc-700">
+                {uiText.dashboard.status.filteredByTag(tag)}
+                <Link href="/dashboard" className="underline">
+                  {uiText.dashboard.status.clearTagFilter}
+                </Link>
+              </p>
+            ) : null}
             <p className="mt-2 inline-flex items-center rounded-full border border-zinc-200 bg-white px-2.5 py-1 text-xs text-zinc-700">
               plan: {plan}
             </p>
@@ -111,27 +130,74 @@ export default async function DashboardPage() {
             <ul className="space-y-3">
               {jobs.map((job) => {
                 const latest = job.runHistories[0];
-                const latestStatusClass = latest
-                  ? latest.status === "success"
-                    ? "status-pill status-pill-success"
-                    : latest.status === "fail"
-                      ? "status-pill status-pill-fail"
-                      : "status-pill status-pill-neutral"
-                  : "status-pill status-pill-neutral";
+                const model = normalizeLlmModel(job.llmModel);
+                const remappedTo = resolveModelAlias(model, aliases);
                 return (
                   <li key={job.id} className="relative rounded-xl border border-zinc-200 bg-white p-4 pr-10 sm:flex sm:items-center sm:justify-between sm:gap-4 sm:pr-4">
                     <div>
                       <h3 className="text-sm font-semibold text-zinc-900">{job.name}</h3>
                       <div className="mt-0.5 flex flex-wrap items-center gap-2 text-xs text-zinc-500">
-                        <span>
-                          {uiText.dashboard.status.nextRun} <LocalTime date={job.nextRunAt} />
-                        </span>
+                        {job.completedAt ? (
+                          <span>
+                            {uiText.dashboard.status.completedAt} <LocalTime date={job.completedAt} />
+                          </span>
+                        ) : (
+                          <span>
+                            {uiText.dashboard.status.nextRun} <LocalTime date={job.nextRunAt} />
+                          </span>
+                        )}
                         <span aria-hidden="true">·</span>
                         <JobEnabledToggle jobId={job.id} enabled={job.enabled} />
+                        {!job.enabled && job.dormantReason ? (
+                          <span className="status-pill status-pill-fail">
+                            {job.dormantReason === "owner_inactive"
+                              ? uiText.dashboard.status.dormantOwnerInactive
+                              : uiText.dashboard.status.dormantChannelFailing}
+                          </span>
+                        ) : null}
+                        {!job.enabled && job.configError ? (
+                          <span className="status-pill status-pill-fail" title={job.configError}>
+                            {uiText.dashboard.status.configError}
+                          </span>
+                        ) : null}
+                        {job.enabled && job.autoPausedUntil ? (
+                          <span className="status-pill status-pill-fail">
+                            {uiText.dashboard.status.autoPaused} <LocalTime date={job.autoPausedUntil} />
+                          </span>
+                        ) : null}
+                        {job.enabled && job.pausedUntil ? (
+                          <span className="status-pill status-pill-neutral">
+                            {uiText.dashboard.status.snoozed} <LocalTime date={job.pausedUntil} />
+                          </span>
+                        ) : null}
+                        {job.environment !== "production" ? (
+                          <span className="status-pill status-pill-neutral">{job.environment}</span>
+                        ) : null}
+                        {job._count.deadLetters ? (
+                          <span className="status-pill status-pill-fail">{uiText.dashboard.status.undelivered(job._count.deadLetters)}</span>
+                        ) : null}
+                        {job.enabled && job.credentialError ? (
+                          <span className="status-pill status-pill-fail" title={job.credentialError}>
+                            {uiText.dashboard.status.credentialsInvalid}
+                          </span>
+                        ) : null}
+                        {remappedTo !== model ? (
+                          <span className="status-pill status-pill-neutral" title={uiText.dashboard.status.modelRemappedTitle(model, remappedTo)}>
+                            {uiText.dashboard.status.modelRemapped}
+                          </span>
+                        ) : null}
+                        {job.qualityDegradedAt ? (
+                          <span className="status-pill status-pill-fail">{uiText.dashboard.status.qualityDegraded}</span>
+                        ) : null}
+                        {job.tags.map((jobTag) => (
+                          <Link key={jobTag} href={`/dashboard?tag=${encodeURIComponent(jobTag)}`} className="status-pill status-pill-neutral">
+                            {jobTag}
+                          </Link>
+                        ))}
                       </div>
                       {latest ? (
                         <div className="mt-2 flex flex-wrap items-center gap-2 text-xs text-zinc-500">
-                          <span className={latestStatusClass}>{latest.status}</span>
+                          <span className={runStatusPillClass(latest.status)}>{runStatusLabel(latest.status)}</span>
                           <p>{uiText.dashboard.status.lastRunAt} <LocalTime date={latest.runAt} /></p>
                         </div>
                       ) : null}
@@ -4,8 +4,20 @@ import { redirect } from "next/navigation";
 import { getServerSession } from "next-auth";
 import { authOptions } from "@/lib/auth-options";
 import { prisma } from "@/lib/prisma";
-import { decryptString } from "@/lib/crypto";
+import { toEditableChannel } from "@/lib/jobs";
+import { listSavedChannels, toSavedChannelOption } from "@/lib/saved-channels";
+import { signToken } from "@/lib/crypto";
+import { formatDateTimeLocalInTimeZone } from "@/lib/timezone";
+import { normalizeCatchupPolicy } from "@/lib/schedule";
+import { normalizeDeliverIf } from "@/lib/deliver-if";
+import { normalizeDeliveryDiff } from "@/lib/output-diff";
+import { normalizeThrottleWindow } from "@/lib/throttle";
 import { DEFAULT_LLM_MODEL, normalizeWebSearchMode } from "@/lib/llm-defaults";
+import { llmToolsToForm, normalizeLlmTools } from "@/lib/llm-tools";
+import { normalizePipelineSteps } from "@/lib/pipeline";
+import { normalizePromptInputs } from "@/lib/prompt-inputs";
+import { inputsToForm } from "@/types/job-form";
+import { priorityName } from "@/lib/job-priority";
 import { JobEditorPage } from "@/components/job-editor/job-editor-page";
 import { SiteNav } from "@/components/site-nav";
 
@@ -24,10 +36,14 @@ export default async function EditJobPage({ params }: Params) {
   if (!session?.user?.id) {
     redirect(`/signin?callbackUrl=${encodeURIComponent(`/jobs/${id}/edit`)}`);
   }
-  const job = await prisma.job.findFirst({
-    where: { id, userId: session.user.id },
-    include: { publishedPromptVersion: true },
-  });
+  const [job, savedChannels, upstreamJobs] = await Promise.all([
+    prisma.job.findFirst({
+      where: { id, userId: session.user.id },
+      include: { publishedPromptVersion: true },
+    }),
+    listSavedChannels(session.user.id),
+    prisma.job.findMany({ where: { userId: session.user.id, id: { not: id } }, select: { id: true, name: true }, orderBy: { name: "asc" } }),
+  ]);
   if (!job) {
     notFound();
   }
@@ -38,33 +54,7 @@ export default async function EditJobPage({ params }: Params) {
   const postPromptEnabledRaw = job.publishedPromptVersion?.postPromptEnabled ?? job.postPromptEnabled;
   const postPromptEnabled = postPromptEnabledRaw && postPromptValue.trim().length > 0;
 
-  const channel =
-    job.channelType === "in_app"
-      ? ({ type: "in_app" } as const)
-      : job.channelType === "discord"
-      ? {
-          type: "discord" as const,
-          config: {
-            webhookUrl: decryptString((job.channelConfig as { webhookUrlEnc: string }).webhookUrlEnc),
-          },
-        }
-      : job.channelType === "webhook"
-        ? {
-            type: "webhook" as const,
-            config: JSON.parse(decryptString((job.channelConfig as { configEnc: string }).configEnc)) as {
-              url: string;
-              method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
-              headers: string;
-              payload: string;
-            },
-          }
-        : {
-            type: "telegram" as const,
-            config: {
-              botToken: decryptString((job.channelConfig as { botTokenEnc: string }).botTokenEnc),
-              chatId: decryptString((job.channelConfig as { chatIdEnc: string }).chatIdEnc),
-            },
-          };
+  const channel = toEditableChannel(job);
 
   return (
     <main className="page-shell">
@@ -77,17 +67,72 @@ export default async function EditJobPage({ params }: Params) {
             prompt: template,
             postPrompt: postPromptValue,
             postPromptEnabled,
+            pipelineSteps: normalizePipelineSteps(job.pipelineSteps),
+            inputUrls: inputsToForm(normalizePromptInputs(job.inputs)),
+            systemPrompt: job.systemPrompt ?? "",
             variables,
             llmModel: DEFAULT_LLM_MODEL,
+            fallbackModels: job.fallbackModels.join(", "),
             useWebSearch: job.allowWebSearch,
             webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+            ...llmToolsToForm(normalizeLlmTools(job.llmTools)),
             scheduleType: job.scheduleType,
             time: job.scheduleTime,
-            timeIsUtc: true,
+            // Jobs saved before per-job time zones store UTC times; the editor converts them for display.
+            timeIsUtc: !job.timezone,
+            scheduleTimeZone: job.timezone ?? "",
             dayOfWeek: job.scheduleDayOfWeek ?? undefined,
+            weekdaysOnly: job.weekdaysOnly,
             cron: job.scheduleCron ?? "",
+            catchupPolicy: normalizeCatchupPolicy(job.catchupPolicy),
+            quietHoursStart: job.quietHoursStart ?? "",
+            quietHoursEnd: job.quietHoursEnd ?? "",
+            blackoutDates: job.blackoutDates.join(", "),
+            runAt: job.runAt ? formatDateTimeLocalInTimeZone(job.runAt, job.timezone ?? "UTC") : "",
             channel,
+            channelId: job.channelId ?? "",
+            savedChannels: savedChannels.map(toSavedChannelOption),
             enabled: job.enabled,
+            userAgent: job.userAgent ?? "",
+            deliveryDelayMinutes: job.deliveryDelayMinutes ? String(job.deliveryDelayMinutes) : "",
+            acceptReplies: job.acceptReplies,
+            outageNotice: job.outageNotice,
+            failureNotice: job.failureNotice,
+            includePreviousOutput: job.includePreviousOutput,
+            previousOutputCount: String(job.previousOutputCount),
+            deliverIf: normalizeDeliverIf(job.deliverIf),
+            deliverIfPattern: job.deliverIfPattern ?? "",
+            deliveryDiff: normalizeDeliveryDiff(job.deliveryDiff),
+            deliveryDiffOnly: job.deliveryDiffOnly,
+            fullOutputLink: job.fullOutputLink,
+            throttleLimit: job.throttleLimit == null ? "" : String(job.throttleLimit),
+            throttleWindow: normalizeThrottleWindow(job.throttleWindow),
+            qualitySampleRate: String(job.qualitySampleRate),
+            qualityRubric: job.qualityRubric ?? "",
+            historyRetentionDays: job.historyRetentionDays == null ? "" : String(job.historyRetentionDays),
+            responseCacheTtlMinutes: job.responseCacheTtlMinutes == null ? "" : String(job.responseCacheTtlMinutes),
+            autoDisableThreshold: job.autoDisableThreshold == null ? "" : String(job.autoDisableThreshold),
+            autoDisablePolicy: job.autoDisablePolicy === "disable" || job.autoDisablePolicy === "pause" ? job.autoDisablePolicy : "",
+            expectedRuntimeSeconds: job.expectedRuntimeSeconds == null ? "" : String(job.expectedRuntimeSeconds),
+            slowRunNotice: job.slowRunNotice,
+            maxOutputChars: job.maxOutputChars == null ? "" : String(job.maxOutputChars),
+            ttsVoice: job.ttsVoice ?? "",
+            maxOutputTokens: job.maxOutputTokens == null ? "" : String(job.maxOutputTokens),
+            temperature: job.temperature == null ? "" : String(job.temperature),
+            reasoningEffort: job.reasoningEffort ?? "",
+            verbosity: job.verbosity ?? "",
+            showHeader: job.showHeader,
+            headerTemplate: job.headerTemplate ?? "",
+            headerLocale: job.headerLocale ?? "",
+            dependsOnJobId: job.dependsOnJobId ?? "",
+            includeUpstreamOutput: job.includeUpstreamOutput,
+            upstreamJobs,
+            webhookRetrySchedule: job.webhookRetrySchedule ?? "",
+            tags: job.tags.join(", "),
+            environment: job.environment,
+            priority: priorityName(job.priority),
+            concurrencyGroup: job.concurrencyGroup ?? "",
+            replyEndpointPath: `/api/jobs/${job.id}/replies?token=${signToken("job-replies", job.id)}`,
           }}
         />
       </section>
@@ -9,6 +9,8 @@ import { SiteNav } from "@/components/site-nav";
 import { LocalTime } from "@/components/ui/local-time";
 import { LinkButton } from "@/components/ui/link-button";
 import { RunOnceButton } from "@/components/job-history/run-once-button";
+import { runStatusLabel, runStatusPillClass, runTimingSummary } from "@/lib/run-status";
+import { runOutputPath } from "@/lib/run-outputs";
 
 type Props = {
   params: Promise<{ id: string }>;
@@ -35,6 +37,16 @@ export default async function JobHistoryPage({ params, searchParams }: Props) {
       runHistories: {
         orderBy: { runAt: "desc" },
         take: 100,
+        // Full outputs are read one at a time from run_outputs.
+        omit: { outputText: true },
+        include: {
+          deliveryAttemptsLog: {
+            orderBy: { attempt: "desc" },
+            take: 1,
+            select: { attempt: true, status: true, renderedMessage: true },
+          },
+          runRequest: { select: { id: true } },
+        },
       },
     },
   });
@@ -49,7 +61,41 @@ export default async function JobHistoryPage({ params, searchParams }: Props) {
         ? "Discord"
         : job.channelType === "telegram"
           ? "Telegram"
-          : "Custom Webhook";
+          : job.channelType === "home_assistant"
+            ? "Home Assistant"
+            : job.channelType === "elasticsearch"
+              ? "Elasticsearch / OpenSearch"
+              : job.channelType === "clickhouse"
+                ? "ClickHouse"
+                : job.channelType === "bigquery"
+                  ? "BigQuery"
+                  : job.channelType === "redis"
+                    ? "Redis"
+                    : job.channelType === "pushover"
+                      ? "Pushover"
+                      : job.channelType === "twilio"
+                        ? "SMS (Twilio)"
+                        : job.channelType === "google_chat"
+                          ? "Google Chat"
+                          : job.channelType === "aws"
+                            ? "AWS SNS / SQS"
+                            : job.channelType === "kafka"
+                              ? "Kafka"
+                              : job.channelType === "nats"
+                                ? "NATS"
+                                : job.channelType === "mqtt"
+                                  ? "MQTT"
+                                  : job.channelType === "file"
+                                    ? "File / stdout"
+                                    : job.channelType === "notion"
+                                      ? "Notion"
+                                      : job.channelType === "google_sheets"
+                                        ? "Google Sheets"
+                                        : job.channelType === "jira"
+                                          ? "Jira"
+                                          : job.channelType === "plugin"
+                                            ? "Plugin"
+                                            : "Custom Webhook";
 
   const canRunOnce = job.channelType === "in_app";
 
@@ -113,25 +159,51 @@ export default async function JobHistoryPage({ params, searchParams }: Props) {
               const citations = Array.isArray(citationsUnknown)
                 ? (citationsUnknown as Array<{ url?: unknown; title?: unknown }>).filter((c) => typeof c?.url === "string")
                 : [];
+              const lastAttempt = history.deliveryAttemptsLog[0];
+              const timing = runTimingSummary(history);
+              const renderedParts = Array.isArray(lastAttempt?.renderedMessage)
+                ? lastAttempt.renderedMessage.filter((part): part is string => typeof part === "string")
+                : [];
 
               return (
                 <li key={history.id} className="surface-card p-3">
                   <div className="flex flex-wrap items-center gap-2 text-sm text-zinc-800">
-                    <span
-                      className={
-                        history.status === "success"
-                          ? "status-pill status-pill-success"
-                          : history.status === "fail"
-                            ? "status-pill status-pill-fail"
-                            : "status-pill status-pill-neutral"
-                      }
-                    >
-                      {history.status}
-                    </span>
+                    <span className={runStatusPillClass(history.status)}>{runStatusLabel(history.status)}</span>
                     {isManual ? <span className="status-pill status-pill-neutral">manual</span> : null}
+                    {history.runRequest ? <span className="status-pill status-pill-neutral">run now</span> : null}
+                    {history.deliverySkipReason ? (
+                      <span className="status-pill status-pill-neutral">not delivered: {history.deliverySkipReason.replace("_", " ")}</span>
+                    ) : null}
                     {usedWebSearch ? <span className="status-pill status-pill-neutral">web</span> : null}
+                    {history.qualityScore != null ? (
+                      <span className="status-pill status-pill-neutral" title={history.qualityReason ?? undefined}>
+                        quality {history.qualityScore}/10
+                      </span>
+                    ) : null}
                     <p><LocalTime date={history.runAt} /></p>
                   </div>
+                  {history.deliverAt && !history.deliveredAt ? (
+                    <p className="mt-1 text-xs text-zinc-500">
+                      Delivery scheduled <LocalTime date={history.deliverAt} />
+                    </p>
+                  ) : null}
+                  {history.throttledAt ? (
+                    <p className="mt-1 text-xs text-zinc-500">
+                      {history.deliveredAt ? "Delivered in a throttle digest" : "Held by the delivery limit for the next digest"}
+                    </p>
+                  ) : null}
+                  {history.deliveredParts > 0 && !history.deliveredAt ? (
+                    <p className="mt-1 text-xs text-zinc-500">
+                      {history.deliveredParts} message {history.deliveredParts === 1 ? "part" : "parts"} delivered; a retry sends only the rest.
+                    </p>
+                  ) : null}
+                  {history.promptTokens != null ? (
+                    <p className="mt-1 text-xs text-zinc-500">
+                      {history.promptTokens.toLocaleString()} in / {(history.completionTokens ?? 0).toLocaleString()} out tokens
+                      {history.costUsd != null ? ` · ~$${history.costUsd.toFixed(4)}` : ""}
+                    </p>
+                  ) : null}
+                  {timing ? <p className="mt-1 text-xs text-zinc-500">{timing}</p> : null}
                   {history.errorMessage ? <p className="mt-1 text-xs text-zinc-500">{history.errorMessage}</p> : null}
                   {history.outputPreview ? (
                     <p className="line-clamp-2 mt-1 text-xs text-zinc-500" title={history.outputPreview}>
@@ -139,11 +211,48 @@ export default async function JobHistoryPage({ params, searchParams }: Props) {
                     </p>
                   ) : null}
 
-                  {history.outputText ? (
+                  {history.outputPreview ? (
+                    <a
+                      className="mt-2 inline-block text-xs font-medium text-zinc-700 underline underline-offset-2"
+                      href={runOutputPath(history.id)}
+                      target="_blank"
+                      rel="noreferrer"
+                    >
+                      Open full output
+                    </a>
+                  ) : null}
+                  {history.archiveKey ? (
+                    <p className="mt-1 break-all text-xs text-zinc-500">Archived to {history.archiveKey}</p>
+                  ) : null}
+                  {renderedParts.length ? (
+                    <details className="mt-2">
+                      <summary className="cursor-pointer text-xs font-medium text-zinc-700">
+                        Show delivered message ({renderedParts.length} {renderedParts.length === 1 ? "request" : "requests"}, attempt{" "}
+                        {lastAttempt?.attempt} {lastAttempt?.status})
+                      </summary>
+                      {renderedParts.map((part, idx) => (
+                        <pre
+                          key={`${history.id}-r${idx}`}
+                          className="mt-2 max-h-96 overflow-auto whitespace-pre-wrap rounded-xl border border-zinc-200 bg-zinc-50 p-3 text-xs text-zinc-700"
+                        >
+                          {part}
+                        </pre>
+                      ))}
+                    </details>
+                  ) : null}
+                  {history.responseStatus != null ? (
+                    <details className="mt-2">
+                      <summary className="cursor-pointer text-xs font-medium text-zinc-700">Show webhook response ({history.responseStatus})</summary>
+                      <pre className="mt-2 max-h-96 overflow-auto whitespace-pre-wrap rounded-xl border border-zinc-200 bg-zinc-50 p-3 text-xs text-zinc-700">
+                        {history.responseBody || "(empty body)"}
+                      </pre>
+                    </details>
+                  ) : null}
+                  {history.debugCapture ? (
                     <details className="mt-2">
-                      <summary className="cursor-pointer text-xs font-medium text-zinc-700">Show full output</summary>
+                      <summary className="cursor-pointer text-xs font-medium text-zinc-700">Show provider payloads (debug)</summary>
                       <pre className="mt-2 max-h-96 overflow-auto whitespace-pre-wrap rounded-xl border border-zinc-200 bg-zinc-50 p-3 text-xs text-zinc-700">
-                        {history.outputText}
+                        {JSON.stringify(history.debugCapture, null, 2)}
                       </pre>
                     </details>
                   ) : null}
@@ -3,7 +3,8 @@ import { redirect } from "next/navigation";
 import { getServerSession } from "next-auth";
 import { authOptions } from "@/lib/auth-options";
 import { prisma } from "@/lib/prisma";
-import { decryptString } from "@/lib/crypto";
+import { toEditableChannel } from "@/lib/jobs";
+import { listSavedChannels, toSavedChannelOption } from "@/lib/saved-channels";
 import { JobEditorPage } from "@/components/job-editor/job-editor-page";
 import { SiteNav } from "@/components/site-nav";
 
@@ -18,45 +19,28 @@ export default async function NewJobPage() {
     redirect("/signin?callbackUrl=/jobs/new");
   }
   
-  const [jobCount, lastJob] = await Promise.all([
+  const [jobCount, lastJob, savedChannels, upstreamJobs] = await Promise.all([
     prisma.job.count({ where: { userId: session.user.id } }),
     prisma.job.findFirst({
       where: { userId: session.user.id },
       orderBy: { createdAt: "desc" },
       select: { channelType: true, channelConfig: true },
     }),
+    listSavedChannels(session.user.id),
+    prisma.job.findMany({ where: { userId: session.user.id }, select: { id: true, name: true }, orderBy: { name: "asc" } }),
   ]);
   const isFirstJob = jobCount === 0;
+  const defaultChannel = savedChannels.find((channel) => channel.isDefault);
 
-  const initialChannel =
-    isFirstJob
+  // The default saved channel wins over the last job's channel.
+  const initialChannel = defaultChannel
+    ? toEditableChannel(defaultChannel)
+    : isFirstJob
       ? ({ type: "in_app" } as const)
-      : lastJob?.channelType === "in_app"
-        ? ({ type: "in_app" } as const)
-        : lastJob?.channelType === "discord"
-      ? {
-          type: "discord" as const,
-          config: { webhookUrl: decryptString((lastJob.channelConfig as { webhookUrlEnc: string }).webhookUrlEnc) },
-        }
-      : lastJob?.channelType === "webhook"
-        ? {
-            type: "webhook" as const,
-            config: JSON.parse(decryptString((lastJob.channelConfig as { configEnc: string }).configEnc)) as {
-              url: string;
-              method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
-              headers: string;
-              payload: string;
-            },
-          }
-        : lastJob?.channelType === "telegram"
-          ? {
-              type: "telegram" as const,
-              config: {
-                botToken: decryptString((lastJob.channelConfig as { botTokenEnc: string }).botTokenEnc),
-                chatId: decryptString((lastJob.channelConfig as { chatIdEnc: string }).chatIdEnc),
-              },
-            }
-          : null;
+      : lastJob
+        ? toEditableChannel(lastJob)
+        : null;
+  const options = savedChannels.map(toSavedChannelOption);
 
   return (
     <main className="page-shell">
@@ -67,9 +51,12 @@ export default async function NewJobPage() {
             initialChannel
               ? {
                   channel: initialChannel,
-                  channelPrefillSource: isFirstJob ? null : "last_job",
+                  channelId: defaultChannel?.id ?? "",
+                  channelPrefillSource: defaultChannel ? "default_channel" : isFirstJob ? null : "last_job",
+                  savedChannels: options,
+                  upstreamJobs,
                 }
-              : undefined
+              : { savedChannels: options, upstreamJobs }
           }
         />
       </section>
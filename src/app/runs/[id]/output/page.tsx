@@ -0,0 +1,72 @@
+import type { Metadata } from "next";
+import { notFound } from "next/navigation";
+import ReactMarkdown from "react-markdown";
+import remarkGfm from "remark-gfm";
+import { prisma } from "@/lib/prisma";
+import { verifyToken } from "@/lib/crypto";
+import { loadRunOutput } from "@/lib/run-outputs";
+import { LocalTime } from "@/components/ui/local-time";
+
+type Props = {
+  params: Promise<{ id: string }>;
+  searchParams: Promise<{ token?: string }>;
+};
+
+export const dynamic = "force-dynamic";
+export const metadata: Metadata = {
+  title: "Run output",
+  robots: { index: false, follow: false },
+};
+
+// Full output behind Run History's "Open full output" and the "[Full output: ...]" link of a shortened chat message;
+// the signed token replaces a session.
+export default async function RunOutputPage({ params, searchParams }: Props) {
+  const { id } = await params;
+  const { token } = await searchParams;
+  if (!token || !verifyToken("run-output", id, token)) {
+    notFound();
+  }
+  const run = await prisma.runHistory.findUnique({
+    where: { id },
+    select: { runAt: true, outputDiff: true, job: { select: { name: true } } },
+  });
+  const output = run ? await loadRunOutput(id) : null;
+  if (!run || !output) {
+    notFound();
+  }
+
+  return (
+    <main className="page-shell">
+      <section className="content-shell max-w-3xl py-10">
+        <h1 className="text-2xl font-semibold text-zinc-900">{run.job.name}</h1>
+        <p className="mt-1 text-sm text-zinc-500">
+          <LocalTime date={run.runAt} />
+          {output.truncated ? " · output was longer than the stored limit and is cut off" : ""}
+        </p>
+        <article className="surface-card mt-5 p-5 text-sm leading-6 text-zinc-800">
+          <ReactMarkdown
+            remarkPlugins={[remarkGfm]}
+            components={{
+              p: ({ children }) => <p className="mb-3 last:mb-0">{children}</p>,
+              a: ({ children, href }) => (
+                <a className="underline underline-offset-2" href={href} target="_blank" rel="noreferrer">
+                  {children}
+                </a>
+              ),
+              pre: ({ children }) => (
+                <pre className="mb-3 overflow-auto rounded-lg border border-zinc-200 bg-zinc-50 p-3 text-xs leading-5">{children}</pre>
+              ),
+              ul: ({ children }) => <ul className="mb-3 list-disc pl-5 last:mb-0">{children}</ul>,
+              ol: ({ children }) => <ol className="mb-3 list-decimal pl-5 last:mb-0">{children}</ol>,
+              table: ({ children }) => <table className="mb-3 w-full border-collapse text-xs">{children}</table>,
+              th: ({ children }) => <th className="border border-zinc-200 px-2 py-1 text-left">{children}</th>,
+              td: ({ children }) => <td className="border border-zinc-200 px-2 py-1">{children}</td>,
+            }}
+          >
+            {run.outputDiff ? `${output.text}\n\n${run.outputDiff}` : output.text}
+          </ReactMarkdown>
+        </article>
+      </section>
+    </main>
+  );
+}
@@ -4,6 +4,7 @@ import { useState } from "react";
 import { useRouter } from "next/navigation";
 import { JobFormProvider, useJobForm } from "@/components/job-editor/job-form-provider";
 import {
+  JobAdvancedSection,
   JobChannelSection,
   JobHeaderSection,
   JobOptionsSection,
@@ -14,8 +15,9 @@ import {
 import { Button } from "@/components/ui/button";
 import { LinkButton } from "@/components/ui/link-button";
 import { uiText } from "@/content/ui-text";
-import type { JobFormState } from "@/types/job-form";
-import { convertZonedHHmmToUtcHHmm, convertZonedWeeklyToUtc, getBrowserTimeZone } from "@/lib/timezone";
+import { inputsFromForm, type JobFormState } from "@/types/job-form";
+import { getBrowserTimeZone, parseDateTimeLocalInTimeZone } from "@/lib/timezone";
+import { llmToolsFromForm } from "@/lib/llm-tools";
 
 function getSaveValidationMessage(state: JobFormState): string | null {
   if (!state.name.trim()) {
@@ -36,7 +38,7 @@ function getSaveValidationMessage(state: JobFormState): string | null {
     }
   }
 
-  if (state.scheduleType !== "cron" && !/^([01]\d|2[0-3]):([0-5]\d)$/.test(state.time)) {
+  if ((state.scheduleType === "daily" || state.scheduleType === "weekly") && !/^([01]\d|2[0-3]):([0-5]\d)$/.test(state.time)) {
     return "Schedule time must be in HH:mm format.";
   }
 
@@ -44,7 +46,15 @@ function getSaveValidationMessage(state: JobFormState): string | null {
     return "Cron expression is required.";
   }
 
-  if (state.channel.type === "in_app") {
+  if (state.scheduleType === "once" && !state.runAt) {
+    return "Run date and time are required.";
+  }
+
+  if (!state.quietHoursStart !== !state.quietHoursEnd) {
+    return "Set both quiet hours start and end, or leave both empty.";
+  }
+
+  if (state.channelId || state.channel.type === "in_app") {
     return null;
   }
 
@@ -62,6 +72,138 @@ function getSaveValidationMessage(state: JobFormState): string | null {
     return null;
   }
 
+  if (state.channel.type === "home_assistant") {
+    if (!state.channel.config.baseUrl.trim() || !state.channel.config.token.trim() || !state.channel.config.service.trim()) {
+      return "Home Assistant URL, access token, and notify service are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "elasticsearch") {
+    if (!state.channel.config.url.trim() || !state.channel.config.index.trim()) {
+      return "Elasticsearch URL and index are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "clickhouse") {
+    if (!state.channel.config.url.trim() || !state.channel.config.table.trim()) {
+      return "ClickHouse URL and table are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "redis") {
+    if (!state.channel.config.restUrl.trim() || !state.channel.config.token.trim() || !state.channel.config.key.trim()) {
+      return "Redis REST URL, token, and key are required.";
+    }
+    if (!/^\d*$/.test(state.channel.config.ttlSeconds.trim())) {
+      return "Redis TTL must be a whole number of seconds.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "pushover") {
+    if (!state.channel.config.appToken.trim() || !state.channel.config.userKey.trim()) {
+      return "Pushover application token and user key are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "twilio") {
+    const { accountSid, authToken, from, to } = state.channel.config;
+    if (!accountSid.trim() || !authToken.trim() || !from.trim() || !to.trim()) {
+      return "Twilio account SID, auth token, and from/to numbers are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "google_chat") {
+    if (!state.channel.config.webhookUrl.trim()) {
+      return "Google Chat webhook URL is required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "aws") {
+    const { service, target, accessKeyId, secretAccessKey } = state.channel.config;
+    if (!target.trim()) {
+      return service === "sns" ? "SNS topic ARN is required." : "SQS queue URL is required.";
+    }
+    if (!accessKeyId.trim() !== !secretAccessKey.trim()) {
+      return "Set both the AWS access key ID and secret, or leave both blank.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "kafka") {
+    const { restUrl, api, clusterId, topic } = state.channel.config;
+    if (!restUrl.trim() || !topic.trim() || (api === "v3" && !clusterId.trim())) {
+      return api === "v3" ? "Kafka REST URL, cluster ID, and topic are required." : "Kafka REST URL and topic are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "nats") {
+    if (!state.channel.config.url.trim() || !state.channel.config.subject.trim()) {
+      return "NATS server URL and subject are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "mqtt") {
+    if (!state.channel.config.brokerUrl.trim() || !state.channel.config.topic.trim()) {
+      return "MQTT broker URL and topic are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "file") {
+    if (state.channel.config.target === "file" && !state.channel.config.path.trim()) {
+      return "File path is required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "notion") {
+    if (!state.channel.config.token.trim() || !state.channel.config.parentId.trim()) {
+      return state.channel.config.target === "database"
+        ? "Notion token and database are required."
+        : "Notion token and page are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "google_sheets") {
+    if (!state.channel.config.spreadsheetId.trim() || !state.channel.config.serviceAccountJson.trim()) {
+      return "Spreadsheet and service account key are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "jira") {
+    const { siteUrl, apiToken, projectKey, issueType } = state.channel.config;
+    if (!siteUrl.trim() || !apiToken.trim() || !projectKey.trim() || !issueType.trim()) {
+      return "Jira site URL, API token, project key, and issue type are required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "plugin") {
+    if (!state.channel.config.plugin.trim()) {
+      return "Plugin name is required.";
+    }
+    return null;
+  }
+
+  if (state.channel.type === "bigquery") {
+    const { projectId, dataset, table, serviceAccountJson } = state.channel.config;
+    if (!projectId.trim() || !dataset.trim() || !table.trim() || !serviceAccountJson.trim()) {
+      return "BigQuery project, dataset, table, and service account key are required.";
+    }
+    return null;
+  }
+
   if (!state.channel.config.url.trim()) {
     return "Webhook URL is required.";
   }
@@ -74,7 +216,11 @@ function getSaveValidationMessage(state: JobFormState): string | null {
     }
   }
 
-  if (state.channel.config.payload.trim()) {
+  if (state.channel.config.bodyFormat === "xml") {
+    if (!state.channel.config.payload.trim()) {
+      return "XML body mode needs a payload template.";
+    }
+  } else if (state.channel.config.payload.trim()) {
     try {
       JSON.parse(state.channel.config.payload);
     } catch {
@@ -82,6 +228,10 @@ function getSaveValidationMessage(state: JobFormState): string | null {
     }
   }
 
+  if (state.channel.config.graphqlQuery?.trim() && state.channel.config.method !== "POST") {
+    return "GraphQL mode requires the POST method.";
+  }
+
   return null;
 }
 
@@ -100,33 +250,85 @@ function JobActionsSection({ jobId }: { jobId?: string }) {
     setError(null);
     setShowUpgrade(false);
 
+    // Times are saved as wall-clock values in the job's zone; the server keeps them fixed across DST.
     const timeZone = state.scheduleTimeZone.trim() ? state.scheduleTimeZone : getBrowserTimeZone();
-
-    let scheduleTimeUtc = "00:00";
-    let scheduleDayOfWeekUtc = state.dayOfWeek;
-    if (state.scheduleType === "daily") {
-      scheduleTimeUtc = convertZonedHHmmToUtcHHmm(state.time, timeZone);
-    }
-    if (state.scheduleType === "weekly") {
-      const converted = convertZonedWeeklyToUtc(state.dayOfWeek ?? 1, state.time, timeZone);
-      scheduleTimeUtc = converted.utcHHmm;
-      scheduleDayOfWeekUtc = converted.utcDayOfWeek;
-    }
+    const scheduleTime = state.scheduleType === "daily" || state.scheduleType === "weekly" ? state.time : "00:00";
+    const runAt = state.scheduleType === "once" ? parseDateTimeLocalInTimeZone(state.runAt, timeZone) : null;
     const body = {
       name: state.name,
       template: state.prompt,
       postPrompt: state.postPrompt,
       postPromptEnabled: state.postPromptEnabled && !!state.postPrompt.trim(),
+      pipelineSteps: state.pipelineSteps.filter((step) => step.template.trim()),
+      inputs: inputsFromForm(state.inputUrls),
+      systemPrompt: state.systemPrompt,
       variables: state.variables,
       useWebSearch: state.useWebSearch,
       llmModel: state.llmModel,
+      fallbackModels: state.fallbackModels
+        .split(",")
+        .map((model) => model.trim())
+        .filter(Boolean),
       webSearchMode: state.webSearchMode,
+      llmTools: llmToolsFromForm(state),
       scheduleType: state.scheduleType,
-      scheduleTime: scheduleTimeUtc,
-      scheduleDayOfWeek: scheduleDayOfWeekUtc,
+      scheduleTime,
+      scheduleDayOfWeek: state.dayOfWeek,
+      weekdaysOnly: state.scheduleType === "daily" && state.weekdaysOnly,
       scheduleCron: state.cron,
-      channel: state.channel,
+      runAt: runAt ? runAt.toISOString() : null,
+      catchupPolicy: state.catchupPolicy,
+      quietHoursStart: state.quietHoursStart || null,
+      quietHoursEnd: state.quietHoursEnd || null,
+      blackoutDates: state.blackoutDates
+        .split(",")
+        .map((date) => date.trim())
+        .filter(Boolean),
+      timezone: timeZone,
+      channel: state.channelId ? undefined : state.channel,
+      channelId: state.channelId || null,
       enabled: state.enabled,
+      userAgent: state.userAgent,
+      deliveryDelayMinutes: Number(state.deliveryDelayMinutes || 0),
+      acceptReplies: state.acceptReplies,
+      outageNotice: state.outageNotice,
+      failureNotice: state.failureNotice,
+      includePreviousOutput: state.includePreviousOutput,
+      previousOutputCount: Number(state.previousOutputCount || 1),
+      deliverIf: state.deliverIf,
+      deliverIfPattern: state.deliverIf === "regex" ? state.deliverIfPattern : "",
+      deliveryDiff: state.deliveryDiff,
+      deliveryDiffOnly: state.deliveryDiff !== "off" && state.deliveryDiffOnly,
+      fullOutputLink: state.fullOutputLink,
+      throttleLimit: state.throttleLimit.trim() === "" ? null : Number(state.throttleLimit),
+      throttleWindow: state.throttleWindow,
+      qualitySampleRate: Number(state.qualitySampleRate || 0),
+      qualityRubric: state.qualityRubric,
+      historyRetentionDays: state.historyRetentionDays.trim() === "" ? null : Number(state.historyRetentionDays),
+      responseCacheTtlMinutes: state.responseCacheTtlMinutes.trim() === "" ? null : Number(state.responseCacheTtlMinutes),
+      autoDisableThreshold: state.autoDisableThreshold.trim() === "" ? null : Number(state.autoDisableThreshold),
+      autoDisablePolicy: state.autoDisablePolicy || null,
+      expectedRuntimeSeconds: state.expectedRuntimeSeconds.trim() === "" ? null : Number(state.expectedRuntimeSeconds),
+      slowRunNotice: state.slowRunNotice,
+      maxOutputChars: state.maxOutputChars.trim() === "" ? null : Number(state.maxOutputChars),
+      ttsVoice: state.ttsVoice || null,
+      maxOutputTokens: state.maxOutputTokens.trim() === "" ? null : Number(state.maxOutputTokens),
+      temperature: state.temperature.trim() === "" ? null : Number(state.temperature),
+      reasoningEffort: state.reasoningEffort || null,
+      verbosity: state.verbosity || null,
+      showHeader: state.showHeader,
+      headerTemplate: state.headerTemplate,
+      headerLocale: state.headerLocale,
+      dependsOnJobId: state.dependsOnJobId || null,
+      includeUpstreamOutput: !!state.dependsOnJobId && state.includeUpstreamOutput,
+      webhookRetrySchedule: state.channel.type === "webhook" ? state.webhookRetrySchedule : "",
+      environment: state.environment.trim() || "production",
+      priority: state.priority,
+      concurrencyGroup: state.concurrencyGroup,
+      tags: state.tags
+        .split(",")
+        .map((tag) => tag.trim())
+        .filter(Boolean),
     };
 
     const endpoint = jobId ? `/api/jobs/${jobId}` : "/api/jobs";
@@ -258,6 +460,7 @@ function JobEditorBody({ jobId }: { jobId?: string }) {
       <JobPreviewSection />
       <JobScheduleSection />
       <JobChannelSection />
+      <JobAdvancedSection />
       <JobActionsSection jobId={jobId} />
     </div>
   );
@@ -13,6 +13,13 @@ import {
   formatUtcOffset,
   getBrowserTimeZone,
 } from "@/lib/timezone";
+import { WEBHOOK_PRESETS, findWebhookPreset } from "@/lib/webhook-presets";
+import { llmToolsFromForm, type LlmTool } from "@/lib/llm-tools";
+import { PIPELINE_STEPS_MAX } from "@/lib/pipeline";
+import { JOB_SYSTEM_PROMPT_MAX, TTS_VOICES } from "@/lib/llm-defaults";
+import { JOB_PRIORITIES } from "@/lib/job-priority";
+import { JOB_MAX_OUTPUT_TOKENS_LIMIT, REASONING_EFFORTS, TEXT_VERBOSITIES } from "@/lib/generation-params";
+import { inputsFromForm, type JobFormState } from "@/types/job-form";
 
 const sectionClass = "surface-card";
 const dayOptions = ["Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"];
@@ -175,6 +182,92 @@ export function JobPromptSection() {
               placeholder={uiText.jobEditor.prompt.placeholder}
             />
 
+            <div className="mt-6">
+              <label htmlFor="job-system-prompt" className="text-xs font-medium text-zinc-700">
+                {uiText.jobEditor.systemPrompt.label}
+              </label>
+              <p className="mt-1 text-[11px] text-zinc-500">{uiText.jobEditor.systemPrompt.help}</p>
+              <textarea
+                id="job-system-prompt"
+                value={state.systemPrompt}
+                onChange={(event) => setState((prev) => ({ ...prev, systemPrompt: event.target.value }))}
+                className="input-base mt-2 h-20 resize-y"
+                placeholder={uiText.jobEditor.systemPrompt.placeholder}
+                maxLength={JOB_SYSTEM_PROMPT_MAX}
+              />
+            </div>
+
+            <div className="mt-6">
+              <label htmlFor="job-inputs" className="text-xs font-medium text-zinc-700">
+                {uiText.jobEditor.inputs.label}
+              </label>
+              <p className="mt-1 text-[11px] text-zinc-500">{uiText.jobEditor.inputs.help}</p>
+              <textarea
+                id="job-inputs"
+                value={state.inputUrls}
+                onChange={(event) => setState((prev) => ({ ...prev, inputUrls: event.target.value }))}
+                className="input-base mt-2 h-20 resize-y font-mono text-xs"
+                placeholder={uiText.jobEditor.inputs.placeholder}
+              />
+            </div>
+
+            <div className="mt-6">
+              <div className="flex items-center justify-between">
+                <div>
+                  <p className="text-xs font-medium text-zinc-700">{uiText.jobEditor.pipeline.label}</p>
+                  <p className="mt-1 text-[11px] text-zinc-500">{uiText.jobEditor.pipeline.help}</p>
+                </div>
+                <Button
+                  type="button"
+                  variant="secondary"
+                  size="sm"
+                  disabled={state.pipelineSteps.length >= PIPELINE_STEPS_MAX}
+                  onClick={() => setState((prev) => ({ ...prev, pipelineSteps: [...prev.pipelineSteps, { name: "", template: "" }] }))}
+                >
+                  {uiText.jobEditor.pipeline.addStep}
+                </Button>
+              </div>
+              {state.pipelineSteps.map((step, index) => (
+                <div key={index} className="mt-3 rounded-md border border-zinc-200 p-3">
+                  <div className="flex items-center gap-2">
+                    <input
+                      value={step.name}
+                      onChange={(event) =>
+                        setState((prev) => ({
+                          ...prev,
+                          pipelineSteps: prev.pipelineSteps.map((item, i) => (i === index ? { ...item, name: event.target.value } : item)),
+                        }))
+                      }
+                      className="input-base"
+                      placeholder={uiText.jobEditor.pipeline.namePlaceholder(index + 2)}
+                      maxLength={60}
+                    />
+                    <Button
+                      type="button"
+                      variant="secondary"
+                      size="sm"
+                      onClick={() =>
+                        setState((prev) => ({ ...prev, pipelineSteps: prev.pipelineSteps.filter((_, i) => i !== index) }))
+                      }
+                    >
+                      {uiText.jobEditor.pipeline.removeStep}
+                    </Button>
+                  </div>
+                  <textarea
+                    value={step.template}
+                    onChange={(event) =>
+                      setState((prev) => ({
+                        ...prev,
+                        pipelineSteps: prev.pipelineSteps.map((item, i) => (i === index ? { ...item, template: event.target.value } : item)),
+                      }))
+                    }
+                    className="input-base mt-2 h-28 resize-y"
+                    placeholder={uiText.jobEditor.pipeline.templatePlaceholder}
+                  />
+                </div>
+              ))}
+            </div>
+
             <div className="mt-6">
               <div className="flex items-center justify-between">
                 <div>
@@ -293,6 +386,17 @@ export function JobOptionsSection() {
           />
         )}
         <p className="text-xs text-zinc-500">{uiText.jobEditor.options.modelHelp}</p>
+        <label className="text-xs text-zinc-600" htmlFor="job-fallback-models">
+          {uiText.jobEditor.options.fallbackModelsLabel}
+        </label>
+        <input
+          id="job-fallback-models"
+          value={state.fallbackModels}
+          onChange={(event) => setState((prev) => ({ ...prev, fallbackModels: event.target.value }))}
+          className="input-base h-10"
+          placeholder={uiText.jobEditor.options.fallbackModelsPlaceholder}
+        />
+        <p className="text-xs text-zinc-500">{uiText.jobEditor.options.fallbackModelsHelp}</p>
         <label className="inline-flex items-center gap-2 text-sm text-zinc-900">
           <input
             type="checkbox"
@@ -301,6 +405,25 @@ export function JobOptionsSection() {
           />
           {uiText.jobEditor.options.useWebSearch}
         </label>
+        <label className="inline-flex items-center gap-2 text-sm text-zinc-900">
+          <input
+            type="checkbox"
+            checked={state.codeInterpreter}
+            onChange={(event) => setState((prev) => ({ ...prev, codeInterpreter: event.target.checked }))}
+          />
+          {uiText.jobEditor.options.codeInterpreter}
+        </label>
+        <label className="text-xs text-zinc-600" htmlFor="job-file-search">
+          {uiText.jobEditor.options.fileSearchLabel}
+        </label>
+        <input
+          id="job-file-search"
+          value={state.fileSearchVectorStores}
+          onChange={(event) => setState((prev) => ({ ...prev, fileSearchVectorStores: event.target.value }))}
+          className="input-base"
+          placeholder={uiText.jobEditor.options.fileSearchPlaceholder}
+        />
+        <p className="text-xs text-zinc-500">{uiText.jobEditor.options.fileSearchHelp}</p>
         <div className="flex items-center justify-between gap-4 rounded-xl border border-zinc-200 bg-zinc-50 px-4 py-3">
           <label className="text-sm font-medium text-zinc-900" htmlFor="job-enabled-toggle">
             {uiText.jobEditor.options.keepEnabled}
@@ -331,8 +454,16 @@ export function JobScheduleSection() {
 
   const timeZone = state.scheduleTimeZone.trim() ? state.scheduleTimeZone : getBrowserTimeZone();
   const timeZoneOffset = formatUtcOffset(timeZone);
+  const timeZoneOptions = (() => {
+    try {
+      const zones = Intl.supportedValuesOf("timeZone");
+      return zones.includes(timeZone) ? zones : [timeZone, ...zones];
+    } catch {
+      return [timeZone];
+    }
+  })();
   const storedUtcTime = (() => {
-    if (state.scheduleType === "cron") return null;
+    if (state.scheduleType === "cron" || state.scheduleType === "once") return null;
     if (!state.time.trim()) return null;
     if (state.scheduleType === "weekly") {
       const { utcDayOfWeek, utcHHmm } = convertZonedWeeklyToUtc(state.dayOfWeek ?? 1, state.time, timeZone);
@@ -347,7 +478,7 @@ export function JobScheduleSection() {
   }, [setState, state.scheduleTimeZone]);
 
   useEffect(() => {
-    if (state.scheduleType === "cron") return;
+    if (state.scheduleType === "cron" || state.scheduleType === "once") return;
     if (!state.timeIsUtc) return;
 
     try {
@@ -374,27 +505,51 @@ export function JobScheduleSection() {
           {timeZone} · {timeZoneOffset}
         </span>
       </div>
+      <select
+        aria-label="Schedule time zone"
+        value={timeZone}
+        onChange={(event) => setState((prev) => ({ ...prev, scheduleTimeZone: event.target.value }))}
+        className="input-base mt-3 h-10"
+      >
+        {timeZoneOptions.map((zone) => (
+          <option key={zone} value={zone}>
+            {zone}
+          </option>
+        ))}
+      </select>
       <p className="mt-2 text-[11px] text-zinc-500">
         {state.scheduleType === "cron"
-          ? uiText.jobEditor.schedule.timezone.cronNote
-          : storedUtcTime
-            ? uiText.jobEditor.schedule.timezone.storedNote(storedUtcTime)
-            : uiText.jobEditor.schedule.timezone.defaultNote}
+          ? uiText.jobEditor.schedule.timezone.cronNote(timeZone)
+          : state.scheduleType === "once"
+            ? uiText.jobEditor.schedule.timezone.onceNote(timeZone)
+            : storedUtcTime
+              ? uiText.jobEditor.schedule.timezone.storedNote(storedUtcTime, timeZone)
+              : uiText.jobEditor.schedule.timezone.defaultNote}
       </p>
       <div className="mt-3 grid gap-3 sm:grid-cols-3">
         <select
           aria-label="Schedule type"
           value={state.scheduleType}
           onChange={(event) =>
-            setState((prev) => ({ ...prev, scheduleType: event.target.value as "daily" | "weekly" | "cron" }))
+            setState((prev) => ({ ...prev, scheduleType: event.target.value as JobFormState["scheduleType"] }))
           }
           className="input-base h-10"
         >
           <option value="daily">{uiText.jobEditor.schedule.types.daily}</option>
           <option value="weekly">{uiText.jobEditor.schedule.types.weekly}</option>
           <option value="cron">{uiText.jobEditor.schedule.types.cron}</option>
+          <option value="once">{uiText.jobEditor.schedule.types.once}</option>
         </select>
-        {state.scheduleType !== "cron" ? (
+        {state.scheduleType === "once" ? (
+          <input
+            type="datetime-local"
+            aria-label="Run date and time"
+            value={state.runAt}
+            onChange={(event) => setState((prev) => ({ ...prev, runAt: event.target.value }))}
+            className="input-base sm:col-span-2"
+          />
+        ) : null}
+        {state.scheduleType === "daily" || state.scheduleType === "weekly" ? (
           <input
             type="time"
             aria-label="Schedule time"
@@ -430,17 +585,234 @@ export function JobScheduleSection() {
           </div>
         ) : null}
       </div>
+      {state.scheduleType === "daily" ? (
+        <label className="mt-3 flex items-center gap-2 text-xs text-zinc-600">
+          <input
+            type="checkbox"
+            checked={state.weekdaysOnly}
+            onChange={(event) => setState((prev) => ({ ...prev, weekdaysOnly: event.target.checked }))}
+          />
+          {uiText.jobEditor.schedule.weekdaysOnly}
+        </label>
+      ) : null}
+      {state.scheduleType !== "once" ? (
+        <div className="mt-3 grid gap-1">
+          <label className="text-xs text-zinc-600" htmlFor="job-catchup-policy">
+            {uiText.jobEditor.schedule.catchup.label}
+          </label>
+          <select
+            id="job-catchup-policy"
+            value={state.catchupPolicy}
+            onChange={(event) =>
+              setState((prev) => ({ ...prev, catchupPolicy: event.target.value as JobFormState["catchupPolicy"] }))
+            }
+            className="input-base h-10"
+          >
+            <option value="run_once">{uiText.jobEditor.schedule.catchup.runOnce}</option>
+            <option value="run_all">{uiText.jobEditor.schedule.catchup.runAll}</option>
+            <option value="skip">{uiText.jobEditor.schedule.catchup.skip}</option>
+          </select>
+        </div>
+      ) : null}
+      <div className="mt-3 grid gap-1">
+        <span className="text-xs text-zinc-600">{uiText.jobEditor.schedule.quietHours.label}</span>
+        <div className="grid grid-cols-2 gap-3">
+          <input
+            type="time"
+            aria-label="Quiet hours start"
+            value={state.quietHoursStart}
+            onChange={(event) => setState((prev) => ({ ...prev, quietHoursStart: event.target.value }))}
+            className="input-base"
+          />
+          <input
+            type="time"
+            aria-label="Quiet hours end"
+            value={state.quietHoursEnd}
+            onChange={(event) => setState((prev) => ({ ...prev, quietHoursEnd: event.target.value }))}
+            className="input-base"
+          />
+        </div>
+        <input
+          aria-label="Blackout dates"
+          value={state.blackoutDates}
+          onChange={(event) => setState((prev) => ({ ...prev, blackoutDates: event.target.value }))}
+          className="input-base"
+          placeholder={uiText.jobEditor.schedule.quietHours.datesPlaceholder}
+        />
+        <p className="text-[11px] text-zinc-500">{uiText.jobEditor.schedule.quietHours.help(timeZone)}</p>
+      </div>
     </section>
   );
 }
 
+function ChannelConfigInputs<T extends Record<string, string>>({
+  fields,
+  config,
+  onChange,
+}: {
+  fields: Array<{
+    key: keyof T & string;
+    label: string;
+    placeholder: string;
+    secret?: boolean;
+    options?: Array<{ value: string; label: string }>;
+  }>;
+  config: T;
+  onChange: (next: T) => void;
+}) {
+  return (
+    <div className="mt-3 grid gap-2">
+      {fields.map((field) =>
+        field.options ? (
+          <select
+            key={field.key}
+            aria-label={field.label}
+            value={config[field.key]}
+            onChange={(event) => onChange({ ...config, [field.key]: event.target.value })}
+            className="input-base h-10"
+          >
+            {field.options.map((option) => (
+              <option key={option.value} value={option.value}>
+                {option.label}
+              </option>
+            ))}
+          </select>
+        ) : (
+          <input
+            key={field.key}
+            aria-label={field.label}
+            type={field.secret ? "password" : "text"}
+            autoComplete="off"
+            value={config[field.key]}
+            onChange={(event) => onChange({ ...config, [field.key]: event.target.value })}
+            className="input-base"
+            placeholder={field.placeholder}
+          />
+        ),
+      )}
+    </div>
+  );
+}
+
+// Empty config for a newly picked channel type.
+function blankChannel(type: string): JobFormState["channel"] {
+  if (type === "in_app") {
+    return { type: "in_app" };
+  }
+  if (type === "discord") {
+    return { type: "discord", config: { webhookUrl: "" } };
+  }
+  if (type === "webhook") {
+    return { type: "webhook", config: { url: "", method: "POST", headers: "", payload: "" } };
+  }
+  if (type === "home_assistant") {
+    return { type: "home_assistant", config: { baseUrl: "", token: "", service: "" } };
+  }
+  if (type === "elasticsearch") {
+    return { type: "elasticsearch", config: { url: "", index: "", apiKey: "", username: "", password: "" } };
+  }
+  if (type === "clickhouse") {
+    return { type: "clickhouse", config: { url: "", table: "", username: "", password: "" } };
+  }
+  if (type === "redis") {
+    return { type: "redis", config: { restUrl: "", token: "", mode: "set", key: "", ttlSeconds: "" } };
+  }
+  if (type === "bigquery") {
+    return { type: "bigquery", config: { projectId: "", dataset: "", table: "", serviceAccountJson: "" } };
+  }
+  if (type === "pushover") {
+    return { type: "pushover", config: { appToken: "", userKey: "", priority: "", sound: "" } };
+  }
+  if (type === "twilio") {
+    return { type: "twilio", config: { accountSid: "", authToken: "", from: "", to: "", mode: "summary" } };
+  }
+  if (type === "google_chat") {
+    return { type: "google_chat", config: { webhookUrl: "", format: "card", threadKey: "" } };
+  }
+  if (type === "aws") {
+    return { type: "aws", config: { service: "sns", target: "", accessKeyId: "", secretAccessKey: "" } };
+  }
+  if (type === "kafka") {
+    return { type: "kafka", config: { restUrl: "", api: "v3", clusterId: "", topic: "", username: "", password: "" } };
+  }
+  if (type === "nats") {
+    return { type: "nats", config: { url: "", subject: "", mode: "core", username: "", password: "", token: "" } };
+  }
+  if (type === "mqtt") {
+    return {
+      type: "mqtt",
+      config: { brokerUrl: "", topic: "", qos: "1", retain: "off", format: "json", username: "", password: "" },
+    };
+  }
+  if (type === "file") {
+    return { type: "file", config: { target: "file", path: "" } };
+  }
+  if (type === "notion") {
+    return { type: "notion", config: { token: "", target: "page", parentId: "", titleProperty: "" } };
+  }
+  if (type === "google_sheets") {
+    return { type: "google_sheets", config: { spreadsheetId: "", sheet: "", columns: "", serviceAccountJson: "" } };
+  }
+  if (type === "jira") {
+    return {
+      type: "jira",
+      config: { siteUrl: "", email: "", apiToken: "", projectKey: "", issueType: "Task", summaryTemplate: "", labels: "" },
+    };
+  }
+  if (type === "plugin") {
+    return { type: "plugin", config: { plugin: "", settings: "{}" } };
+  }
+  return { type: "telegram", config: { botToken: "", chatId: "" } };
+}
+
 export function JobChannelSection() {
   const { state, setState } = useJobForm();
+  const [presetHint, setPresetHint] = useState<string | null>(null);
 
   function setChannel(next: typeof state.channel) {
     setState((prev) => ({ ...prev, channel: next, channelPrefillSource: null }));
   }
 
+  const savedChannel = state.savedChannels.find((channel) => channel.id === state.channelId);
+  // The inline config is kept (or reset to the saved channel's type) so type-specific settings still apply.
+  const savedChannelPicker = state.savedChannels.length ? (
+    <select
+      aria-label="Saved channel"
+      value={savedChannel?.id ?? ""}
+      onChange={(event) => {
+        const picked = state.savedChannels.find((channel) => channel.id === event.target.value);
+        setState((prev) => ({
+          ...prev,
+          channelId: picked?.id ?? "",
+          channel: picked && picked.type !== prev.channel.type ? blankChannel(picked.type) : prev.channel,
+          channelPrefillSource: null,
+        }));
+      }}
+      className="input-base mt-2 h-10"
+    >
+      <option value="">{uiText.jobEditor.channel.savedChannelNone}</option>
+      {state.savedChannels.map((channel) => (
+        <option key={channel.id} value={channel.id}>
+          {channel.name} ({uiText.jobEditor.channel.types[channel.type]})
+        </option>
+      ))}
+    </select>
+  ) : null;
+
+  if (savedChannel) {
+    return (
+      <section className={sectionClass}>
+        <h3 className="text-sm font-medium text-zinc-900">{uiText.jobEditor.channel.title}</h3>
+        <p className="field-help">{uiText.jobEditor.channel.description}</p>
+        {state.channelPrefillSource === "default_channel" ? (
+          <p className="mt-2 text-xs text-zinc-500">{uiText.jobEditor.channel.prefilledFromDefault}</p>
+        ) : null}
+        {savedChannelPicker}
+        <p className="mt-2 text-xs text-zinc-500">{uiText.jobEditor.channel.savedChannelHelp}</p>
+      </section>
+    );
+  }
+
   return (
     <section className={sectionClass}>
       <h3 className="text-sm font-medium text-zinc-900">{uiText.jobEditor.channel.title}</h3>
@@ -448,30 +820,34 @@ export function JobChannelSection() {
       {state.channelPrefillSource === "last_job" ? (
         <p className="mt-2 text-xs text-zinc-500">{uiText.jobEditor.channel.prefilledFromLastJob}</p>
       ) : null}
+      {savedChannelPicker}
       <select
         aria-label="Delivery channel"
         value={state.channel.type}
-        onChange={(event) => {
-          if (event.target.value === "in_app") {
-            setChannel({ type: "in_app" });
-            return;
-          }
-          if (event.target.value === "discord") {
-            setChannel({ type: "discord", config: { webhookUrl: "" } });
-            return;
-          }
-          if (event.target.value === "webhook") {
-            setChannel({ type: "webhook", config: { url: "", method: "POST", headers: "", payload: "" } });
-            return;
-          }
-          setChannel({ type: "telegram", config: { botToken: "", chatId: "" } });
-        }}
+        onChange={(event) => setChannel(blankChannel(event.target.value))}
         className="input-base mt-2 h-10"
       >
         <option value="in_app">{uiText.jobEditor.channel.types.in_app}</option>
         <option value="discord">{uiText.jobEditor.channel.types.discord}</option>
         <option value="telegram">{uiText.jobEditor.channel.types.telegram}</option>
         <option value="webhook">{uiText.jobEditor.channel.types.webhook}</option>
+        <option value="home_assistant">{uiText.jobEditor.channel.types.home_assistant}</option>
+        <option value="elasticsearch">{uiText.jobEditor.channel.types.elasticsearch}</option>
+        <option value="clickhouse">{uiText.jobEditor.channel.types.clickhouse}</option>
+        <option value="bigquery">{uiText.jobEditor.channel.types.bigquery}</option>
+        <option value="redis">{uiText.jobEditor.channel.types.redis}</option>
+        <option value="pushover">{uiText.jobEditor.channel.types.pushover}</option>
+        <option value="twilio">{uiText.jobEditor.channel.types.twilio}</option>
+        <option value="google_chat">{uiText.jobEditor.channel.types.google_chat}</option>
+        <option value="aws">{uiText.jobEditor.channel.types.aws}</option>
+        <option value="kafka">{uiText.jobEditor.channel.types.kafka}</option>
+        <option value="nats">{uiText.jobEditor.channel.types.nats}</option>
+        <option value="mqtt">{uiText.jobEditor.channel.types.mqtt}</option>
+        <option value="file">{uiText.jobEditor.channel.types.file}</option>
+        <option value="notion">{uiText.jobEditor.channel.types.notion}</option>
+        <option value="google_sheets">{uiText.jobEditor.channel.types.google_sheets}</option>
+        <option value="jira">{uiText.jobEditor.channel.types.jira}</option>
+        <option value="plugin">{uiText.jobEditor.channel.types.plugin}</option>
       </select>
       
       {state.channel.type === "in_app" ? (
@@ -482,15 +858,44 @@ export function JobChannelSection() {
       ) : null}
 
       {state.channel.type === "discord" ? (
-        <input
-          aria-label="Discord webhook URL"
-          value={state.channel.config.webhookUrl}
-          onChange={(event) =>
-            setChannel({ type: "discord", config: { webhookUrl: event.target.value } })
-          }
-          className="input-base mt-3"
-          placeholder={uiText.jobEditor.channel.discordPlaceholder}
-        />
+        <div className="mt-3 grid gap-2">
+          <input
+            aria-label="Discord webhook URL"
+            value={state.channel.config.webhookUrl}
+            onChange={(event) =>
+              setChannel({
+                type: "discord",
+                config: { ...(state.channel.type === "discord" ? state.channel.config : {}), webhookUrl: event.target.value },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.discordPlaceholder}
+          />
+          <input
+            aria-label="Discord thread ID"
+            value={state.channel.config.threadId ?? ""}
+            onChange={(event) =>
+              setChannel({
+                type: "discord",
+                config: { ...(state.channel.type === "discord" ? state.channel.config : { webhookUrl: "" }), threadId: event.target.value },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.discordThreadIdPlaceholder}
+          />
+          <input
+            aria-label="Discord new thread name"
+            value={state.channel.config.threadName ?? ""}
+            onChange={(event) =>
+              setChannel({
+                type: "discord",
+                config: { ...(state.channel.type === "discord" ? state.channel.config : { webhookUrl: "" }), threadName: event.target.value },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.discordThreadNamePlaceholder}
+          />
+        </div>
       ) : state.channel.type === "telegram" ? (
         <div className="mt-3 grid gap-2">
           <input
@@ -499,7 +904,7 @@ export function JobChannelSection() {
             onChange={(event) =>
               setChannel({
                 type: "telegram",
-                config: { botToken: event.target.value, chatId: state.channel.type === "telegram" ? state.channel.config.chatId : "" },
+                config: { ...(state.channel.type === "telegram" ? state.channel.config : { chatId: "" }), botToken: event.target.value },
               })
             }
             className="input-base"
@@ -511,15 +916,55 @@ export function JobChannelSection() {
             onChange={(event) =>
               setChannel({
                 type: "telegram",
-                config: { botToken: state.channel.type === "telegram" ? state.channel.config.botToken : "", chatId: event.target.value },
+                config: { ...(state.channel.type === "telegram" ? state.channel.config : { botToken: "" }), chatId: event.target.value },
               })
             }
             className="input-base"
             placeholder={uiText.jobEditor.channel.telegramChatPlaceholder}
           />
+          <input
+            aria-label="Telegram topic ID"
+            value={state.channel.config.messageThreadId ?? ""}
+            onChange={(event) =>
+              setChannel({
+                type: "telegram",
+                config: { ...(state.channel.type === "telegram" ? state.channel.config : { botToken: "", chatId: "" }), messageThreadId: event.target.value },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.telegramTopicPlaceholder}
+          />
         </div>
       ) : state.channel.type === "webhook" ? (
         <div className="mt-3 grid gap-2">
+          <select
+            aria-label="Webhook preset"
+            value=""
+            onChange={(event) => {
+              const preset = findWebhookPreset(event.target.value);
+              if (!preset || state.channel.type !== "webhook") return;
+              setChannel({
+                type: "webhook",
+                config: {
+                  url: state.channel.config.url,
+                  method: preset.method,
+                  headers: preset.headers,
+                  payload: preset.payload,
+                  bodyFormat: preset.bodyFormat ?? "json",
+                },
+              });
+              setPresetHint(`${preset.description} URL: ${preset.urlHint}`);
+            }}
+            className="input-base h-10"
+          >
+            <option value="">{uiText.jobEditor.channel.presetPlaceholder}</option>
+            {WEBHOOK_PRESETS.map((preset) => (
+              <option key={preset.key} value={preset.key}>
+                {preset.name}
+              </option>
+            ))}
+          </select>
+          {presetHint ? <p className="text-[11px] text-zinc-500">{presetHint}</p> : null}
           <input
             aria-label="Webhook URL"
             value={state.channel.config.url}
@@ -555,6 +1000,119 @@ export function JobChannelSection() {
             <option value="PATCH">{uiText.jobEditor.channel.methods.patch}</option>
             <option value="DELETE">{uiText.jobEditor.channel.methods.delete}</option>
           </select>
+          <select
+            aria-label="Webhook body format"
+            value={state.channel.config.bodyFormat ?? "json"}
+            onChange={(event) =>
+              setChannel({
+                type: "webhook",
+                config: {
+                  ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                  bodyFormat: event.target.value as "json" | "xml",
+                },
+              })
+            }
+            className="input-base h-10"
+          >
+            <option value="json">{uiText.jobEditor.channel.bodyFormats.json}</option>
+            <option value="xml">{uiText.jobEditor.channel.bodyFormats.xml}</option>
+          </select>
+          <label className="flex items-center gap-2 text-xs text-zinc-700">
+            <input
+              type="checkbox"
+              checked={state.channel.config.gzip ?? false}
+              onChange={(event) =>
+                setChannel({
+                  type: "webhook",
+                  config: {
+                    ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                    gzip: event.target.checked,
+                  },
+                })
+              }
+            />
+            <span>{uiText.jobEditor.channel.gzipLabel}</span>
+          </label>
+          <label className="flex items-center gap-2 text-xs text-zinc-700">
+            <input
+              type="checkbox"
+              checked={state.channel.config.captureResponse ?? false}
+              onChange={(event) =>
+                setChannel({
+                  type: "webhook",
+                  config: {
+                    ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                    captureResponse: event.target.checked,
+                  },
+                })
+              }
+            />
+            <span>{uiText.jobEditor.channel.captureResponseLabel}</span>
+          </label>
+          <input
+            aria-label="Webhook proxy URL"
+            autoComplete="off"
+            value={state.channel.config.proxyUrl ?? ""}
+            onChange={(event) =>
+              setChannel({
+                type: "webhook",
+                config: {
+                  ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                  proxyUrl: event.target.value,
+                },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.proxyUrlPlaceholder}
+          />
+          <textarea
+            aria-label="Webhook CA bundle"
+            value={state.channel.config.caBundle ?? ""}
+            onChange={(event) =>
+              setChannel({
+                type: "webhook",
+                config: {
+                  ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                  caBundle: event.target.value,
+                },
+              })
+            }
+            className="input-base h-20 font-mono text-xs"
+            placeholder={uiText.jobEditor.channel.caBundlePlaceholder}
+          />
+          <label className="flex items-center gap-2 text-xs text-zinc-700">
+            <input
+              type="checkbox"
+              checked={state.channel.config.insecureSkipVerify ?? false}
+              onChange={(event) =>
+                setChannel({
+                  type: "webhook",
+                  config: {
+                    ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                    insecureSkipVerify: event.target.checked,
+                  },
+                })
+              }
+            />
+            <span>{uiText.jobEditor.channel.insecureSkipVerifyLabel}</span>
+          </label>
+          <input
+            type="password"
+            aria-label="Webhook signing secret"
+            autoComplete="off"
+            value={state.channel.config.signingSecret ?? ""}
+            onChange={(event) =>
+              setChannel({
+                type: "webhook",
+                config: {
+                  ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                  signingSecret: event.target.value,
+                },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.signingSecretPlaceholder}
+          />
           <textarea
             aria-label="Webhook headers JSON"
             value={state.channel.config.headers}
@@ -570,6 +1128,21 @@ export function JobChannelSection() {
             className="input-base h-24"
             placeholder={uiText.jobEditor.channel.headersPlaceholder}
           />
+          <textarea
+            aria-label="GraphQL mutation"
+            value={state.channel.config.graphqlQuery ?? ""}
+            onChange={(event) =>
+              setChannel({
+                type: "webhook",
+                config: {
+                  ...(state.channel.type === "webhook" ? state.channel.config : { url: "", method: "POST", headers: "", payload: "" }),
+                  graphqlQuery: event.target.value,
+                },
+              })
+            }
+            className="input-base h-24 font-mono text-xs"
+            placeholder={uiText.jobEditor.channel.graphqlQueryPlaceholder}
+          />
           <textarea
             aria-label="Webhook payload JSON"
             value={state.channel.config.payload}
@@ -583,14 +1156,881 @@ export function JobChannelSection() {
               })
             }
             className="input-base h-28"
-            placeholder={uiText.jobEditor.channel.payloadPlaceholder}
+            placeholder={
+              state.channel.config.bodyFormat === "xml"
+                ? uiText.jobEditor.channel.xmlPayloadPlaceholder
+                : state.channel.config.graphqlQuery?.trim()
+                  ? uiText.jobEditor.channel.graphqlVariablesPlaceholder
+                  : uiText.jobEditor.channel.payloadPlaceholder
+            }
+          />
+        </div>
+      ) : state.channel.type === "home_assistant" ? (
+        <div className="mt-3 grid gap-2">
+          <input
+            aria-label="Home Assistant URL"
+            value={state.channel.config.baseUrl}
+            onChange={(event) =>
+              setChannel({
+                type: "home_assistant",
+                config: {
+                  ...(state.channel.type === "home_assistant" ? state.channel.config : { token: "", service: "" }),
+                  baseUrl: event.target.value,
+                },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.homeAssistantUrlPlaceholder}
+          />
+          <input
+            aria-label="Home Assistant access token"
+            value={state.channel.config.token}
+            onChange={(event) =>
+              setChannel({
+                type: "home_assistant",
+                config: {
+                  ...(state.channel.type === "home_assistant" ? state.channel.config : { baseUrl: "", service: "" }),
+                  token: event.target.value,
+                },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.homeAssistantTokenPlaceholder}
+          />
+          <input
+            aria-label="Home Assistant notify service"
+            value={state.channel.config.service}
+            onChange={(event) =>
+              setChannel({
+                type: "home_assistant",
+                config: {
+                  ...(state.channel.type === "home_assistant" ? state.channel.config : { baseUrl: "", token: "" }),
+                  service: event.target.value,
+                },
+              })
+            }
+            className="input-base"
+            placeholder={uiText.jobEditor.channel.homeAssistantServicePlaceholder}
           />
         </div>
+      ) : state.channel.type === "elasticsearch" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "url", label: "Elasticsearch URL", placeholder: uiText.jobEditor.channel.elasticsearch.url },
+            { key: "index", label: "Elasticsearch index", placeholder: uiText.jobEditor.channel.elasticsearch.index },
+            { key: "apiKey", label: "Elasticsearch API key", placeholder: uiText.jobEditor.channel.elasticsearch.apiKey, secret: true },
+            { key: "username", label: "Elasticsearch username", placeholder: uiText.jobEditor.channel.elasticsearch.username },
+            { key: "password", label: "Elasticsearch password", placeholder: uiText.jobEditor.channel.elasticsearch.password, secret: true },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "elasticsearch", config })}
+        />
+      ) : state.channel.type === "clickhouse" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "url", label: "ClickHouse URL", placeholder: uiText.jobEditor.channel.clickhouse.url },
+            { key: "table", label: "ClickHouse table", placeholder: uiText.jobEditor.channel.clickhouse.table },
+            { key: "username", label: "ClickHouse username", placeholder: uiText.jobEditor.channel.clickhouse.username },
+            { key: "password", label: "ClickHouse password", placeholder: uiText.jobEditor.channel.clickhouse.password, secret: true },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "clickhouse", config })}
+        />
+      ) : state.channel.type === "bigquery" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "projectId", label: "BigQuery project ID", placeholder: uiText.jobEditor.channel.bigquery.projectId },
+            { key: "dataset", label: "BigQuery dataset", placeholder: uiText.jobEditor.channel.bigquery.dataset },
+            { key: "table", label: "BigQuery table", placeholder: uiText.jobEditor.channel.bigquery.table },
+            {
+              key: "serviceAccountJson",
+              label: "BigQuery service account key",
+              placeholder: uiText.jobEditor.channel.bigquery.serviceAccountJson,
+              secret: true,
+            },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "bigquery", config })}
+        />
+      ) : state.channel.type === "redis" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "restUrl", label: "Redis REST URL", placeholder: uiText.jobEditor.channel.redis.restUrl },
+            { key: "token", label: "Redis REST token", placeholder: uiText.jobEditor.channel.redis.token, secret: true },
+            {
+              key: "mode",
+              label: "Redis command",
+              placeholder: "",
+              options: [
+                { value: "set", label: uiText.jobEditor.channel.redis.modes.set },
+                { value: "xadd", label: uiText.jobEditor.channel.redis.modes.xadd },
+              ],
+            },
+            { key: "key", label: "Redis key", placeholder: uiText.jobEditor.channel.redis.key },
+            ...(state.channel.config.mode === "set"
+              ? [{ key: "ttlSeconds" as const, label: "Redis TTL seconds", placeholder: uiText.jobEditor.channel.redis.ttlSeconds }]
+              : []),
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "redis", config })}
+        />
+      ) : state.channel.type === "pushover" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "appToken", label: "Pushover application token", placeholder: uiText.jobEditor.channel.pushover.appToken, secret: true },
+            { key: "userKey", label: "Pushover user key", placeholder: uiText.jobEditor.channel.pushover.userKey, secret: true },
+            {
+              key: "priority",
+              label: "Pushover priority",
+              placeholder: "",
+              options: [
+                { value: "", label: uiText.jobEditor.channel.pushover.priorities.default },
+                { value: "-2", label: uiText.jobEditor.channel.pushover.priorities.lowest },
+                { value: "-1", label: uiText.jobEditor.channel.pushover.priorities.low },
+                { value: "0", label: uiText.jobEditor.channel.pushover.priorities.normal },
+                { value: "1", label: uiText.jobEditor.channel.pushover.priorities.high },
+              ],
+            },
+            { key: "sound", label: "Pushover sound", placeholder: uiText.jobEditor.channel.pushover.sound },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "pushover", config })}
+        />
+      ) : state.channel.type === "twilio" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "accountSid", label: "Twilio account SID", placeholder: uiText.jobEditor.channel.twilio.accountSid },
+            { key: "authToken", label: "Twilio auth token", placeholder: uiText.jobEditor.channel.twilio.authToken, secret: true },
+            { key: "from", label: "Twilio from number", placeholder: uiText.jobEditor.channel.twilio.from },
+            { key: "to", label: "Twilio to number", placeholder: uiText.jobEditor.channel.twilio.to },
+            {
+              key: "mode",
+              label: "SMS length",
+              placeholder: "",
+              options: [
+                { value: "summary", label: uiText.jobEditor.channel.twilio.modes.summary },
+                { value: "full", label: uiText.jobEditor.channel.twilio.modes.full },
+              ],
+            },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "twilio", config })}
+        />
+      ) : state.channel.type === "google_chat" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "webhookUrl", label: "Google Chat webhook URL", placeholder: uiText.jobEditor.channel.googleChat.webhookUrl, secret: true },
+            {
+              key: "format",
+              label: "Google Chat message format",
+              placeholder: "",
+              options: [
+                { value: "card", label: uiText.jobEditor.channel.googleChat.formats.card },
+                { value: "text", label: uiText.jobEditor.channel.googleChat.formats.text },
+              ],
+            },
+            { key: "threadKey", label: "Google Chat thread key", placeholder: uiText.jobEditor.channel.googleChat.threadKey },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "google_chat", config })}
+        />
+      ) : state.channel.type === "aws" ? (
+        <ChannelConfigInputs
+          fields={[
+            {
+              key: "service",
+              label: "AWS service",
+              placeholder: "",
+              options: [
+                { value: "sns", label: uiText.jobEditor.channel.aws.services.sns },
+                { value: "sqs", label: uiText.jobEditor.channel.aws.services.sqs },
+              ],
+            },
+            {
+              key: "target",
+              label: state.channel.config.service === "sns" ? "SNS topic ARN" : "SQS queue URL",
+              placeholder: state.channel.config.service === "sns" ? uiText.jobEditor.channel.aws.topicArn : uiText.jobEditor.channel.aws.queueUrl,
+            },
+            { key: "accessKeyId", label: "AWS access key ID", placeholder: uiText.jobEditor.channel.aws.accessKeyId },
+            { key: "secretAccessKey", label: "AWS secret access key", placeholder: uiText.jobEditor.channel.aws.secretAccessKey, secret: true },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "aws", config })}
+        />
+      ) : state.channel.type === "kafka" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "restUrl", label: "Kafka REST URL", placeholder: uiText.jobEditor.channel.kafka.restUrl },
+            {
+              key: "api",
+              label: "Kafka REST API",
+              placeholder: "",
+              options: [
+                { value: "v3", label: uiText.jobEditor.channel.kafka.apis.v3 },
+                { value: "v2", label: uiText.jobEditor.channel.kafka.apis.v2 },
+              ],
+            },
+            ...(state.channel.config.api === "v3"
+              ? [{ key: "clusterId" as const, label: "Kafka cluster ID", placeholder: uiText.jobEditor.channel.kafka.clusterId }]
+              : []),
+            { key: "topic", label: "Kafka topic", placeholder: uiText.jobEditor.channel.kafka.topic },
+            { key: "username", label: "Kafka username", placeholder: uiText.jobEditor.channel.kafka.username },
+            { key: "password", label: "Kafka password", placeholder: uiText.jobEditor.channel.kafka.password, secret: true },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "kafka", config })}
+        />
+      ) : state.channel.type === "nats" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "url", label: "NATS server URL", placeholder: uiText.jobEditor.channel.nats.url },
+            { key: "subject", label: "NATS subject", placeholder: uiText.jobEditor.channel.nats.subject },
+            {
+              key: "mode",
+              label: "NATS publish mode",
+              placeholder: "",
+              options: [
+                { value: "core", label: uiText.jobEditor.channel.nats.modes.core },
+                { value: "jetstream", label: uiText.jobEditor.channel.nats.modes.jetstream },
+              ],
+            },
+            { key: "username", label: "NATS username", placeholder: uiText.jobEditor.channel.nats.username },
+            { key: "password", label: "NATS password", placeholder: uiText.jobEditor.channel.nats.password, secret: true },
+            { key: "token", label: "NATS token", placeholder: uiText.jobEditor.channel.nats.token, secret: true },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "nats", config })}
+        />
+      ) : state.channel.type === "mqtt" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "brokerUrl", label: "MQTT broker URL", placeholder: uiText.jobEditor.channel.mqtt.brokerUrl },
+            { key: "topic", label: "MQTT topic", placeholder: uiText.jobEditor.channel.mqtt.topic },
+            {
+              key: "qos",
+              label: "MQTT QoS",
+              placeholder: "",
+              options: [
+                { value: "0", label: uiText.jobEditor.channel.mqtt.qos.atMostOnce },
+                { value: "1", label: uiText.jobEditor.channel.mqtt.qos.atLeastOnce },
+                { value: "2", label: uiText.jobEditor.channel.mqtt.qos.exactlyOnce },
+              ],
+            },
+            {
+              key: "retain",
+              label: "MQTT retain",
+              placeholder: "",
+              options: [
+                { value: "off", label: uiText.jobEditor.channel.mqtt.retain.off },
+                { value: "on", label: uiText.jobEditor.channel.mqtt.retain.on },
+              ],
+            },
+            {
+              key: "format",
+              label: "MQTT payload format",
+              placeholder: "",
+              options: [
+                { value: "json", label: uiText.jobEditor.channel.mqtt.formats.json },
+                { value: "text", label: uiText.jobEditor.channel.mqtt.formats.text },
+              ],
+            },
+            { key: "username", label: "MQTT username", placeholder: uiText.jobEditor.channel.mqtt.username },
+            { key: "password", label: "MQTT password", placeholder: uiText.jobEditor.channel.mqtt.password, secret: true },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "mqtt", config })}
+        />
+      ) : state.channel.type === "file" ? (
+        <ChannelConfigInputs
+          fields={[
+            {
+              key: "target",
+              label: "Output target",
+              placeholder: "",
+              options: [
+                { value: "file", label: uiText.jobEditor.channel.file.targets.file },
+                { value: "stdout", label: uiText.jobEditor.channel.file.targets.stdout },
+              ],
+            },
+            ...(state.channel.config.target === "file"
+              ? [{ key: "path" as const, label: "File path", placeholder: uiText.jobEditor.channel.file.path }]
+              : []),
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "file", config })}
+        />
+      ) : state.channel.type === "notion" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "token", label: "Notion integration token", placeholder: uiText.jobEditor.channel.notion.token, secret: true },
+            {
+              key: "target",
+              label: "Notion target",
+              placeholder: "",
+              options: [
+                { value: "page", label: uiText.jobEditor.channel.notion.targets.page },
+                { value: "database", label: uiText.jobEditor.channel.notion.targets.database },
+              ],
+            },
+            {
+              key: "parentId",
+              label: state.channel.config.target === "database" ? "Notion database" : "Notion page",
+              placeholder:
+                state.channel.config.target === "database"
+                  ? uiText.jobEditor.channel.notion.databaseId
+                  : uiText.jobEditor.channel.notion.pageId,
+            },
+            ...(state.channel.config.target === "database"
+              ? [{ key: "titleProperty" as const, label: "Title property", placeholder: uiText.jobEditor.channel.notion.titleProperty }]
+              : []),
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "notion", config })}
+        />
+      ) : state.channel.type === "google_sheets" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "spreadsheetId", label: "Spreadsheet", placeholder: uiText.jobEditor.channel.googleSheets.spreadsheetId },
+            { key: "sheet", label: "Sheet tab", placeholder: uiText.jobEditor.channel.googleSheets.sheet },
+            { key: "columns", label: "Columns", placeholder: uiText.jobEditor.channel.googleSheets.columns },
+            {
+              key: "serviceAccountJson",
+              label: "Google Sheets service account key",
+              placeholder: uiText.jobEditor.channel.googleSheets.serviceAccountJson,
+              secret: true,
+            },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "google_sheets", config })}
+        />
+      ) : state.channel.type === "jira" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "siteUrl", label: "Jira site URL", placeholder: uiText.jobEditor.channel.jira.siteUrl },
+            { key: "email", label: "Jira account email", placeholder: uiText.jobEditor.channel.jira.email },
+            { key: "apiToken", label: "Jira API token", placeholder: uiText.jobEditor.channel.jira.apiToken, secret: true },
+            { key: "projectKey", label: "Jira project key", placeholder: uiText.jobEditor.channel.jira.projectKey },
+            { key: "issueType", label: "Jira issue type", placeholder: uiText.jobEditor.channel.jira.issueType },
+            { key: "summaryTemplate", label: "Jira summary template", placeholder: uiText.jobEditor.channel.jira.summaryTemplate },
+            { key: "labels", label: "Jira labels", placeholder: uiText.jobEditor.channel.jira.labels },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "jira", config })}
+        />
+      ) : state.channel.type === "plugin" ? (
+        <ChannelConfigInputs
+          fields={[
+            { key: "plugin", label: "Plugin name", placeholder: uiText.jobEditor.channel.plugin.plugin },
+            { key: "settings", label: "Plugin settings", placeholder: uiText.jobEditor.channel.plugin.settings, secret: true },
+          ]}
+          config={state.channel.config}
+          onChange={(config) => setChannel({ type: "plugin", config })}
+        />
       ) : null}
     </section>
   );
 }
 
+export function JobAdvancedSection() {
+  const { state, setState } = useJobForm();
+
+  return (
+    <section className={sectionClass}>
+      <details>
+        <summary className="cursor-pointer text-sm font-medium text-zinc-900">{uiText.jobEditor.advanced.title}</summary>
+        <p className="field-help">{uiText.jobEditor.advanced.description}</p>
+        <div className="mt-3 grid gap-2">
+          <label className="text-xs text-zinc-600" htmlFor="job-tags">
+            {uiText.jobEditor.advanced.tagsLabel}
+          </label>
+          <input
+            id="job-tags"
+            value={state.tags}
+            onChange={(event) => setState((prev) => ({ ...prev, tags: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.tagsPlaceholder}
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.tagsHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-environment">
+            {uiText.jobEditor.advanced.environmentLabel}
+          </label>
+          <input
+            id="job-environment"
+            value={state.environment}
+            onChange={(event) => setState((prev) => ({ ...prev, environment: event.target.value }))}
+            className="input-base"
+            placeholder="production"
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.environmentHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-priority">
+            {uiText.jobEditor.advanced.priorityLabel}
+          </label>
+          <select
+            id="job-priority"
+            value={state.priority}
+            onChange={(event) => setState((prev) => ({ ...prev, priority: event.target.value as typeof prev.priority }))}
+            className="input-base h-10"
+          >
+            {JOB_PRIORITIES.map((priority) => (
+              <option key={priority} value={priority}>
+                {uiText.jobEditor.advanced.priorityOptions[priority]}
+              </option>
+            ))}
+          </select>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.priorityHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-concurrency-group">
+            {uiText.jobEditor.advanced.concurrencyGroupLabel}
+          </label>
+          <input
+            id="job-concurrency-group"
+            value={state.concurrencyGroup}
+            onChange={(event) => setState((prev) => ({ ...prev, concurrencyGroup: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.concurrencyGroupPlaceholder}
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.concurrencyGroupHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-user-agent">
+            {uiText.jobEditor.advanced.userAgentLabel}
+          </label>
+          <input
+            id="job-user-agent"
+            value={state.userAgent}
+            onChange={(event) => setState((prev) => ({ ...prev, userAgent: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.userAgentPlaceholder}
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.userAgentHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-delivery-delay">
+            {uiText.jobEditor.advanced.deliveryDelayLabel}
+          </label>
+          <input
+            id="job-delivery-delay"
+            type="number"
+            min={0}
+            max={10080}
+            value={state.deliveryDelayMinutes}
+            onChange={(event) => setState((prev) => ({ ...prev, deliveryDelayMinutes: event.target.value }))}
+            className="input-base"
+            placeholder="0"
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.deliveryDelayHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-deliver-if">
+            {uiText.jobEditor.advanced.deliverIfLabel}
+          </label>
+          <select
+            id="job-deliver-if"
+            value={state.deliverIf}
+            onChange={(event) => setState((prev) => ({ ...prev, deliverIf: event.target.value as typeof prev.deliverIf }))}
+            className="input-base h-10"
+          >
+            {(["always", "changed", "nonempty", "regex"] as const).map((mode) => (
+              <option key={mode} value={mode}>
+                {uiText.jobEditor.advanced.deliverIfOptions[mode]}
+              </option>
+            ))}
+          </select>
+          {state.deliverIf === "regex" ? (
+            <input
+              value={state.deliverIfPattern}
+              onChange={(event) => setState((prev) => ({ ...prev, deliverIfPattern: event.target.value }))}
+              className="input-base"
+              placeholder={uiText.jobEditor.advanced.deliverIfPatternPlaceholder}
+            />
+          ) : null}
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.deliverIfHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-delivery-diff">
+            {uiText.jobEditor.advanced.deliveryDiffLabel}
+          </label>
+          <select
+            id="job-delivery-diff"
+            value={state.deliveryDiff}
+            onChange={(event) => setState((prev) => ({ ...prev, deliveryDiff: event.target.value as typeof prev.deliveryDiff }))}
+            className="input-base h-10"
+          >
+            {(["off", "unified", "summary"] as const).map((mode) => (
+              <option key={mode} value={mode}>
+                {uiText.jobEditor.advanced.deliveryDiffOptions[mode]}
+              </option>
+            ))}
+          </select>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.deliveryDiffHelp}</p>
+          {state.deliveryDiff !== "off" ? (
+            <label className="flex items-center gap-2 text-xs text-zinc-600">
+              <input
+                type="checkbox"
+                checked={state.deliveryDiffOnly}
+                onChange={(event) => setState((prev) => ({ ...prev, deliveryDiffOnly: event.target.checked }))}
+              />
+              {uiText.jobEditor.advanced.deliveryDiffOnlyLabel}
+            </label>
+          ) : null}
+          {state.channel.type === "discord" || state.channel.type === "telegram" ? (
+            <>
+              <label className="mt-2 flex items-center gap-2 text-xs text-zinc-600">
+                <input
+                  type="checkbox"
+                  checked={state.fullOutputLink}
+                  onChange={(event) => setState((prev) => ({ ...prev, fullOutputLink: event.target.checked }))}
+                />
+                {uiText.jobEditor.advanced.fullOutputLinkLabel}
+              </label>
+              <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.fullOutputLinkHelp}</p>
+            </>
+          ) : null}
+          {state.channel.type !== "in_app" ? (
+            <>
+              <label className="mt-2 text-xs text-zinc-600" htmlFor="job-throttle-limit">
+                {uiText.jobEditor.advanced.throttleLabel}
+              </label>
+              <div className="flex gap-2">
+                <input
+                  id="job-throttle-limit"
+                  type="number"
+                  min={1}
+                  max={1000}
+                  value={state.throttleLimit}
+                  onChange={(event) => setState((prev) => ({ ...prev, throttleLimit: event.target.value }))}
+                  className="input-base"
+                  placeholder={uiText.jobEditor.advanced.throttlePlaceholder}
+                />
+                <select
+                  aria-label="Throttle window"
+                  value={state.throttleWindow}
+                  onChange={(event) => setState((prev) => ({ ...prev, throttleWindow: event.target.value as typeof prev.throttleWindow }))}
+                  className="input-base h-10"
+                >
+                  {(["hour", "day"] as const).map((window) => (
+                    <option key={window} value={window}>
+                      {uiText.jobEditor.advanced.throttleWindowOptions[window]}
+                    </option>
+                  ))}
+                </select>
+              </div>
+              <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.throttleHelp}</p>
+            </>
+          ) : null}
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-quality-sample-rate">
+            {uiText.jobEditor.advanced.qualitySampleRateLabel}
+          </label>
+          <input
+            id="job-quality-sample-rate"
+            type="number"
+            min={0}
+            max={100}
+            value={state.qualitySampleRate}
+            onChange={(event) => setState((prev) => ({ ...prev, qualitySampleRate: event.target.value }))}
+            className="input-base"
+          />
+          {Number(state.qualitySampleRate) > 0 ? (
+            <textarea
+              aria-label="Quality rubric"
+              value={state.qualityRubric}
+              onChange={(event) => setState((prev) => ({ ...prev, qualityRubric: event.target.value }))}
+              className="input-base h-24"
+              placeholder={uiText.jobEditor.advanced.qualityRubricPlaceholder}
+            />
+          ) : null}
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.qualitySampleRateHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-history-retention">
+            {uiText.jobEditor.advanced.historyRetentionLabel}
+          </label>
+          <input
+            id="job-history-retention"
+            type="number"
+            min={0}
+            max={3650}
+            value={state.historyRetentionDays}
+            onChange={(event) => setState((prev) => ({ ...prev, historyRetentionDays: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.historyRetentionPlaceholder}
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.historyRetentionHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-response-cache">
+            {uiText.jobEditor.advanced.responseCacheLabel}
+          </label>
+          <input
+            id="job-response-cache"
+            type="number"
+            min={1}
+            max={10080}
+            value={state.responseCacheTtlMinutes}
+            onChange={(event) => setState((prev) => ({ ...prev, responseCacheTtlMinutes: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.responseCachePlaceholder}
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.responseCacheHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-auto-disable-threshold">
+            {uiText.jobEditor.advanced.autoDisableLabel}
+          </label>
+          <div className="flex gap-2">
+            <input
+              id="job-auto-disable-threshold"
+              type="number"
+              min={0}
+              max={1000}
+              value={state.autoDisableThreshold}
+              onChange={(event) => setState((prev) => ({ ...prev, autoDisableThreshold: event.target.value }))}
+              className="input-base"
+              placeholder={uiText.jobEditor.advanced.autoDisablePlaceholder}
+            />
+            <select
+              aria-label="After repeated failures"
+              value={state.autoDisablePolicy}
+              onChange={(event) => setState((prev) => ({ ...prev, autoDisablePolicy: event.target.value as typeof prev.autoDisablePolicy }))}
+              className="input-base h-10"
+            >
+              {(["", "disable", "pause"] as const).map((policy) => (
+                <option key={policy} value={policy}>
+                  {uiText.jobEditor.advanced.autoDisablePolicyOptions[policy || "default"]}
+                </option>
+              ))}
+            </select>
+          </div>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.autoDisableHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-expected-runtime">
+            {uiText.jobEditor.advanced.expectedRuntimeLabel}
+          </label>
+          <input
+            id="job-expected-runtime"
+            type="number"
+            min={1}
+            max={3600}
+            value={state.expectedRuntimeSeconds}
+            onChange={(event) => setState((prev) => ({ ...prev, expectedRuntimeSeconds: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.expectedRuntimePlaceholder}
+          />
+          <label className="flex items-center gap-2 text-xs text-zinc-600">
+            <input
+              type="checkbox"
+              checked={state.slowRunNotice}
+              disabled={!state.expectedRuntimeSeconds.trim()}
+              onChange={(event) => setState((prev) => ({ ...prev, slowRunNotice: event.target.checked }))}
+            />
+            {uiText.jobEditor.advanced.slowRunNoticeLabel}
+          </label>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.expectedRuntimeHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-max-output-chars">
+            {uiText.jobEditor.advanced.maxOutputCharsLabel}
+          </label>
+          <input
+            id="job-max-output-chars"
+            type="number"
+            min={50}
+            max={100000}
+            value={state.maxOutputChars}
+            onChange={(event) => setState((prev) => ({ ...prev, maxOutputChars: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.maxOutputCharsPlaceholder}
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.maxOutputCharsHelp}</p>
+          {state.upstreamJobs.length ? (
+            <>
+              <label className="mt-2 text-xs text-zinc-600" htmlFor="job-depends-on">
+                {uiText.jobEditor.advanced.dependsOnLabel}
+              </label>
+              <select
+                id="job-depends-on"
+                value={state.dependsOnJobId}
+                onChange={(event) => setState((prev) => ({ ...prev, dependsOnJobId: event.target.value }))}
+                className="input-base h-10"
+              >
+                <option value="">{uiText.jobEditor.advanced.dependsOnNone}</option>
+                {state.upstreamJobs.map((upstream) => (
+                  <option key={upstream.id} value={upstream.id}>
+                    {upstream.name}
+                  </option>
+                ))}
+              </select>
+              <label className="flex items-center gap-2 text-xs text-zinc-600">
+                <input
+                  type="checkbox"
+                  checked={state.includeUpstreamOutput}
+                  disabled={!state.dependsOnJobId}
+                  onChange={(event) => setState((prev) => ({ ...prev, includeUpstreamOutput: event.target.checked }))}
+                />
+                {uiText.jobEditor.advanced.includeUpstreamOutputLabel}
+              </label>
+              <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.dependsOnHelp}</p>
+            </>
+          ) : null}
+          <label className="mt-2 flex items-center gap-2 text-xs text-zinc-600">
+            <input
+              type="checkbox"
+              checked={state.showHeader}
+              onChange={(event) => setState((prev) => ({ ...prev, showHeader: event.target.checked }))}
+            />
+            {uiText.jobEditor.advanced.showHeaderLabel}
+          </label>
+          <input
+            id="job-header-template"
+            value={state.headerTemplate}
+            disabled={!state.showHeader}
+            onChange={(event) => setState((prev) => ({ ...prev, headerTemplate: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.headerTemplatePlaceholder}
+            maxLength={300}
+          />
+          <input
+            id="job-header-locale"
+            value={state.headerLocale}
+            disabled={!state.showHeader || !state.headerTemplate.trim()}
+            onChange={(event) => setState((prev) => ({ ...prev, headerLocale: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.headerLocalePlaceholder}
+            maxLength={35}
+          />
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.headerHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-max-output-tokens">
+            {uiText.jobEditor.advanced.maxOutputTokensLabel}
+          </label>
+          <input
+            id="job-max-output-tokens"
+            type="number"
+            min={16}
+            max={JOB_MAX_OUTPUT_TOKENS_LIMIT}
+            value={state.maxOutputTokens}
+            onChange={(event) => setState((prev) => ({ ...prev, maxOutputTokens: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.providerDefault}
+          />
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-temperature">
+            {uiText.jobEditor.advanced.temperatureLabel}
+          </label>
+          <input
+            id="job-temperature"
+            type="number"
+            min={0}
+            max={2}
+            step={0.1}
+            value={state.temperature}
+            onChange={(event) => setState((prev) => ({ ...prev, temperature: event.target.value }))}
+            className="input-base"
+            placeholder={uiText.jobEditor.advanced.providerDefault}
+          />
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-reasoning-effort">
+            {uiText.jobEditor.advanced.reasoningEffortLabel}
+          </label>
+          <select
+            id="job-reasoning-effort"
+            value={state.reasoningEffort}
+            onChange={(event) => setState((prev) => ({ ...prev, reasoningEffort: event.target.value }))}
+            className="input-base h-10"
+          >
+            <option value="">{uiText.jobEditor.advanced.providerDefault}</option>
+            {REASONING_EFFORTS.map((effort) => (
+              <option key={effort} value={effort}>
+                {effort}
+              </option>
+            ))}
+          </select>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-verbosity">
+            {uiText.jobEditor.advanced.verbosityLabel}
+          </label>
+          <select
+            id="job-verbosity"
+            value={state.verbosity}
+            onChange={(event) => setState((prev) => ({ ...prev, verbosity: event.target.value }))}
+            className="input-base h-10"
+          >
+            <option value="">{uiText.jobEditor.advanced.providerDefault}</option>
+            {TEXT_VERBOSITIES.map((verbosity) => (
+              <option key={verbosity} value={verbosity}>
+                {verbosity}
+              </option>
+            ))}
+          </select>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.generationHelp}</p>
+          <label className="mt-2 text-xs text-zinc-600" htmlFor="job-tts-voice">
+            {uiText.jobEditor.advanced.ttsVoiceLabel}
+          </label>
+          <select
+            id="job-tts-voice"
+            value={state.ttsVoice}
+            onChange={(event) => setState((prev) => ({ ...prev, ttsVoice: event.target.value }))}
+            className="input-base h-10"
+          >
+            <option value="">{uiText.jobEditor.advanced.ttsVoiceOff}</option>
+            {TTS_VOICES.map((voice) => (
+              <option key={voice} value={voice}>
+                {voice}
+              </option>
+            ))}
+          </select>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.ttsVoiceHelp}</p>
+          {state.channel.type === "webhook" ? (
+            <>
+              <label className="mt-2 text-xs text-zinc-600" htmlFor="job-webhook-retry-schedule">
+                {uiText.jobEditor.advanced.webhookRetryScheduleLabel}
+              </label>
+              <input
+                id="job-webhook-retry-schedule"
+                value={state.webhookRetrySchedule}
+                onChange={(event) => setState((prev) => ({ ...prev, webhookRetrySchedule: event.target.value }))}
+                className="input-base"
+                placeholder={uiText.jobEditor.advanced.webhookRetrySchedulePlaceholder}
+              />
+              <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.webhookRetryScheduleHelp}</p>
+            </>
+          ) : null}
+          <label className="mt-2 flex items-center gap-2 text-xs text-zinc-600">
+            <input
+              type="checkbox"
+              checked={state.acceptReplies}
+              onChange={(event) => setState((prev) => ({ ...prev, acceptReplies: event.target.checked }))}
+            />
+            {uiText.jobEditor.advanced.acceptRepliesLabel}
+          </label>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.acceptRepliesHelp}</p>
+          {state.acceptReplies && state.replyEndpointPath ? (
+            <code className="break-all rounded-lg bg-zinc-50 px-2 py-1 text-[11px] text-zinc-700">{state.replyEndpointPath}</code>
+          ) : null}
+          <label className="mt-2 flex items-center gap-2 text-xs text-zinc-600">
+            <input
+              type="checkbox"
+              checked={state.outageNotice}
+              onChange={(event) => setState((prev) => ({ ...prev, outageNotice: event.target.checked }))}
+            />
+            {uiText.jobEditor.advanced.outageNoticeLabel}
+          </label>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.outageNoticeHelp}</p>
+          <label className="mt-2 flex items-center gap-2 text-xs text-zinc-600">
+            <input
+              type="checkbox"
+              checked={state.failureNotice}
+              onChange={(event) => setState((prev) => ({ ...prev, failureNotice: event.target.checked }))}
+            />
+            {uiText.jobEditor.advanced.failureNoticeLabel}
+          </label>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.failureNoticeHelp}</p>
+          <label className="mt-2 flex items-center gap-2 text-xs text-zinc-600">
+            <input
+              type="checkbox"
+              checked={state.includePreviousOutput}
+              onChange={(event) => setState((prev) => ({ ...prev, includePreviousOutput: event.target.checked }))}
+            />
+            {uiText.jobEditor.advanced.previousOutputLabel}
+          </label>
+          <p className="text-[11px] text-zinc-500">{uiText.jobEditor.advanced.previousOutputHelp}</p>
+          {state.includePreviousOutput ? (
+            <label className="flex items-center gap-2 text-xs text-zinc-600">
+              {uiText.jobEditor.advanced.previousOutputCountLabel}
+              <input
+                type="number"
+                min={1}
+                max={5}
+                value={state.previousOutputCount}
+                onChange={(event) => setState((prev) => ({ ...prev, previousOutputCount: event.target.value }))}
+                className="input-base h-8 w-16"
+              />
+            </label>
+          ) : null}
+        </div>
+      </details>
+    </section>
+  );
+}
+
 export function JobPreviewSection() {
   const { state, setState } = useJobForm();
   const [testSend, setTestSend] = useState(false);
@@ -629,10 +2069,18 @@ export function JobPreviewSection() {
         template: string;
         postPrompt: string;
         postPromptEnabled: boolean;
+        pipelineSteps: Array<{ name: string; template: string }>;
+        inputs: Array<{ url: string; label?: string }>;
+        systemPrompt: string;
+        maxOutputTokens: number | null;
+        temperature: number | null;
+        reasoningEffort: string | null;
+        verbosity: string | null;
         variables: string;
         useWebSearch: boolean;
         llmModel: string;
         webSearchMode: typeof state.webSearchMode;
+        llmTools: LlmTool[];
         testSend: boolean;
         channel?: typeof state.channel;
       } = {
@@ -640,10 +2088,18 @@ export function JobPreviewSection() {
         template: state.prompt,
         postPrompt: state.postPrompt,
         postPromptEnabled: state.postPromptEnabled && !!state.postPrompt.trim(),
+        pipelineSteps: state.pipelineSteps.filter((step) => step.template.trim()),
+        inputs: inputsFromForm(state.inputUrls),
+        systemPrompt: state.systemPrompt,
+        maxOutputTokens: state.maxOutputTokens.trim() === "" ? null : Number(state.maxOutputTokens),
+        temperature: state.temperature.trim() === "" ? null : Number(state.temperature),
+        reasoningEffort: state.reasoningEffort || null,
+        verbosity: state.verbosity || null,
         variables: state.variables,
         useWebSearch: state.useWebSearch,
         llmModel: state.llmModel,
         webSearchMode: state.webSearchMode,
+        llmTools: llmToolsFromForm(state),
         testSend,
       };
 
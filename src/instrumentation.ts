@@ -0,0 +1,17 @@
+export async function register() {
+  if (process.env.NEXT_RUNTIME === "nodejs") {
+    // The config file only fills env vars that are unset, so it has to run before anything reads them.
+    const { applyConfigFile } = await import("@/lib/config-file");
+    const applied = applyConfigFile();
+    if (applied.length) {
+      const { logger } = await import("@/lib/logger");
+      logger.info("config file loaded", { path: process.env.PROMPTLOOP_CONFIG, settings: applied.length });
+    }
+    const { registerOtlpTracing } = await import("@/lib/otlp-traces");
+    registerOtlpTracing();
+    const { initSecretBackend } = await import("@/lib/secret-backend");
+    await initSecretBackend();
+    const { startDebugServer } = await import("@/lib/debug-server");
+    startDebugServer();
+  }
+}
@@ -0,0 +1,44 @@
+import { createServer, type Server } from "node:http";
+import type { AddressInfo } from "node:net";
+
+export type FakeDelivery = { at: number; status: number; jobId: string | null; body: string };
+
+// Local webhook endpoint for integration tests. Answers each request with the next queued status (204 once the
+// queue is empty) and records what it received.
+export async function startFakeChannel() {
+  const deliveries: FakeDelivery[] = [];
+  const statuses: number[] = [];
+  const server: Server = createServer((req, res) => {
+    let body = "";
+    req.on("data", (chunk) => (body += chunk));
+    req.on("end", () => {
+      let jobId: string | null = null;
+      try {
+        jobId = (JSON.parse(body) as { meta?: { jobId?: string } }).meta?.jobId ?? null;
+      } catch {
+        // Recorded without a job id.
+      }
+      const status = statuses.shift() ?? 204;
+      deliveries.push({ at: Date.now(), status, jobId, body });
+      res.writeHead(status).end();
+    });
+  });
+  await new Promise<void>((resolve) => server.listen(0, "127.0.0.1", resolve));
+  const { port } = server.address() as AddressInfo;
+
+  return {
+    url: `http://127.0.0.1:${port}/hook`,
+    deliveries,
+    // Statuses for the next requests, in order.
+    respondWith(...next: number[]) {
+      statuses.push(...next);
+    },
+    reset() {
+      deliveries.length = 0;
+      statuses.length = 0;
+    },
+    close() {
+      return new Promise<void>((resolve) => server.close(() => resolve()));
+    },
+  };
+}
@@ -0,0 +1,185 @@
+import { randomUUID } from "node:crypto";
+import { afterAll, beforeAll, beforeEach, describe, expect, inject, it, vi } from "vitest";
+import { startFakeChannel } from "./fake-channel";
+
+// The real worker tick against Postgres: claims and locks, delivery retries, failure backoff, schedule
+// advancement and run history. The model is the load test mock, so no provider is called.
+vi.stubEnv("PRISMA_DATABASE_URL", inject("databaseUrl"));
+vi.stubEnv("CHANNEL_SECRET_KEY", "integration-secret");
+vi.stubEnv("LOADTEST_ENABLED", "1");
+vi.stubEnv("LOADTEST_LLM_LATENCY_MS", "20");
+vi.stubEnv("WORKER_DELIVERY_MAX_RETRIES", "2");
+vi.stubEnv("WORKER_FAILURE_RETRIES", "3");
+vi.stubEnv("WORKER_FAILURE_BACKOFF_SECONDS", "60");
+
+const { prisma } = await import("@/lib/prisma");
+const { releaseActiveLocks, runDueJobs } = await import("@/lib/worker-runner");
+const { toDbChannelConfig } = await import("@/lib/jobs");
+const { LOADTEST_MODEL } = await import("@/lib/loadtest");
+
+const channel = await startFakeChannel();
+let userId = "";
+
+function tick() {
+  return runDueJobs({ maxJobs: 25, timeBudgetMs: 30_000, concurrency: 4, runnerId: randomUUID() });
+}
+
+async function createDueJob(name: string, scheduleTime = "09:00") {
+  const { channelType, channelConfig } = toDbChannelConfig({
+    type: "webhook",
+    config: { url: channel.url, method: "POST", headers: "", payload: "", bodyFormat: "json" },
+  });
+  return prisma.job.create({
+    data: {
+      userId,
+      name,
+      prompt: `Integration job ${name}`,
+      llmModel: LOADTEST_MODEL,
+      scheduleType: "daily",
+      scheduleTime,
+      timezone: "UTC",
+      nextRunAt: new Date(Date.now() - 60_000),
+      channelType,
+      channelConfig,
+    },
+  });
+}
+
+describe("worker loop (integration)", () => {
+  beforeAll(async () => {
+    await prisma.$executeRawUnsafe(`TRUNCATE "public"."users" CASCADE`);
+  });
+
+  beforeEach(async () => {
+    channel.reset();
+    await prisma.job.deleteMany();
+    const user = await prisma.user.upsert({
+      where: { provider_providerUserId: { provider: "github", providerUserId: "integration" } },
+      create: { provider: "github", providerUserId: "integration", name: "Integration", overrideDailyRunLimit: 10_000 },
+      update: {},
+    });
+    userId = user.id;
+  });
+
+  afterAll(async () => {
+    await channel.close();
+    await prisma.$disconnect();
+  });
+
+  it("runs a due job, writes its history and advances the schedule", async () => {
+    const job = await createDueJob("daily");
+    const result = await tick();
+    expect(result).toMatchObject({ processed: 1, success: 1, fail: 0 });
+
+    const runs = await prisma.runHistory.findMany({ where: { jobId: job.id } });
+    expect(runs).toHaveLength(1);
+    expect(runs[0]).toMatchObject({ status: "success", llmModel: LOADTEST_MODEL, isPreview: false, deliveryAttempts: 1 });
+    expect(runs[0].outputText).toContain("Load test output");
+    expect(runs[0].deliveredAt).not.toBeNull();
+
+    const after = await prisma.job.findUniqueOrThrow({ where: { id: job.id } });
+    expect(after.lockedAt).toBeNull();
+    expect(after.nextRunAt.getTime()).toBeGreaterThan(Date.now());
+    expect(after.nextRunAt.toISOString()).toMatch(/T09:00:00\.000Z$/);
+    expect(channel.deliveries.map((delivery) => delivery.jobId)).toEqual([job.id]);
+
+    expect(await tick()).toMatchObject({ processed: 0 });
+  });
+
+  it("never runs a job twice when workers tick concurrently", async () => {
+    const jobs = await Promise.all(Array.from({ length: 10 }, (_, index) => createDueJob(`concurrent ${index}`)));
+    const results = await Promise.all([tick(), tick(), tick()]);
+    expect(results.reduce((sum, result) => sum + result.success, 0)).toBe(10);
+
+    const counts = await prisma.runHistory.groupBy({ by: ["jobId"], _count: { _all: true } });
+    expect(counts).toHaveLength(10);
+    expect(counts.every((row) => row._count._all === 1)).toBe(true);
+    expect(new Set(channel.deliveries.map((delivery) => delivery.jobId))).toEqual(new Set(jobs.map((job) => job.id)));
+    expect(channel.deliveries).toHaveLength(10);
+  });
+
+  it("holds a job while another job of its concurrency group is running", async () => {
+    const running = await createDueJob("group running");
+    const waiting = await createDueJob("group waiting");
+    await prisma.job.update({ where: { id: running.id }, data: { concurrencyGroup: "crm-api", lockedAt: new Date(), nextRunAt: new Date(Date.now() + 3_600_000) } });
+    await prisma.job.update({ where: { id: waiting.id }, data: { concurrencyGroup: "crm-api" } });
+    expect(await tick()).toMatchObject({ processed: 0 });
+
+    await prisma.job.update({ where: { id: running.id }, data: { lockedAt: null } });
+    expect(await tick()).toMatchObject({ processed: 1, success: 1 });
+    expect(channel.deliveries.map((delivery) => delivery.jobId)).toEqual([waiting.id]);
+  });
+
+  it("finishes a run interrupted by a crash instead of delivering it twice", async () => {
+    const delivered = await createDueJob("crashed after send");
+    const pending = await createDueJob("crashed before send");
+    for (const job of [delivered, pending]) {
+      const run = await prisma.runHistory.create({
+        data: { jobId: job.id, scheduledFor: job.nextRunAt, status: "running", isPreview: false, outputText: "Saved output" },
+      });
+      if (job.id === delivered.id) {
+        await prisma.deliveryAttempt.create({ data: { runHistoryId: run.id, attempt: 1, status: "success", partsDelivered: 1 } });
+      }
+    }
+
+    await tick();
+    await tick();
+    const runs = await prisma.runHistory.findMany({ where: { jobId: { in: [delivered.id, pending.id] } } });
+    expect(runs).toHaveLength(2);
+    expect(runs.every((run) => run.status === "success" && run.deliveredAt)).toBe(true);
+    expect(channel.deliveries.map((delivery) => delivery.jobId)).toEqual([pending.id]);
+    const jobs = await prisma.job.findMany({ where: { id: { in: [delivered.id, pending.id] } } });
+    expect(jobs.every((job) => job.nextRunAt.getTime() > Date.now())).toBe(true);
+  });
+
+  it("runs a slot again after a shutdown released it mid-run", async () => {
+    const job = await createDueJob("released");
+    vi.stubEnv("LOADTEST_LLM_LATENCY_MS", "2000");
+    try {
+      const interrupted = tick().catch(() => null);
+      await vi.waitFor(async () => expect(await prisma.runHistory.count({ where: { jobId: job.id, status: "running" } })).toBe(1), {
+        timeout: 5000,
+      });
+      await releaseActiveLocks();
+      expect(await prisma.runHistory.count({ where: { jobId: job.id } })).toBe(0);
+      await interrupted;
+    } finally {
+      vi.stubEnv("LOADTEST_LLM_LATENCY_MS", "20");
+    }
+
+    const result = await tick();
+    expect(result).toMatchObject({ processed: 1, success: 1 });
+    const runs = await prisma.runHistory.findMany({ where: { jobId: job.id } });
+    expect(runs).toHaveLength(1);
+    expect(runs[0]).toMatchObject({ status: "success", scheduledFor: job.nextRunAt });
+    expect(channel.deliveries.map((delivery) => delivery.jobId)).toEqual([job.id]);
+  });
+
+  it("retries a delivery the channel rejected with a server error", async () => {
+    const job = await createDueJob("flaky channel");
+    channel.respondWith(503);
+    await tick();
+
+    const run = await prisma.runHistory.findFirstOrThrow({ where: { jobId: job.id } });
+    expect(run.status).toBe("success");
+    expect(channel.deliveries.map((delivery) => delivery.status)).toEqual([503, 204]);
+  });
+
+  it("backs off and retries the slot when delivery keeps failing", async () => {
+    // Failure retries never go past the next regular slot; keep it far away.
+    const job = await createDueJob("broken channel", `${String((new Date().getUTCHours() + 12) % 24).padStart(2, "0")}:00`);
+    channel.respondWith(500, 500, 500, 500, 500, 500, 500, 500, 500);
+    const startedAt = Date.now();
+    expect(await tick()).toMatchObject({ processed: 1, fail: 1 });
+
+    const run = await prisma.runHistory.findFirstOrThrow({ where: { jobId: job.id } });
+    expect(run.status).toBe("fail");
+    expect(run.deliveredAt).toBeNull();
+
+    const after = await prisma.job.findUniqueOrThrow({ where: { id: job.id } });
+    expect(after.lockedAt).toBeNull();
+    expect(after.retryAttempt).toBe(1);
+    expect(after.nextRunAt.getTime()).toBeGreaterThanOrEqual(startedAt + 55_000);
+    expect(after.nextRunAt.getTime()).toBeLessThanOrEqual(Date.now() + 65_000);
+  });
+});
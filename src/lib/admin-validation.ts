@@ -6,4 +6,5 @@ export const adminUserUpdateSchema = z.object({
   overrideEnabledJobsLimit: z.number().int().min(0).optional().nullable(),
   overrideTotalJobsLimit: z.number().int().min(0).optional().nullable(),
   overrideDailyRunLimit: z.number().int().min(0).optional().nullable(),
+  overrideMonthlyBudgetUsd: z.number().min(0).optional().nullable(),
 });
@@ -14,3 +14,18 @@ export function extractToolResults(result: unknown): unknown {
   if (!isRecord(result)) return undefined;
   return "toolResults" in result ? (result as { toolResults?: unknown }).toolResults : undefined;
 }
+
+export type GeneratedRunFile = { mediaType: string; data: Uint8Array };
+
+// Files the model generated (e.g. images); AI SDK results expose them as `files`.
+export function extractFiles(result: unknown): GeneratedRunFile[] {
+  if (!isRecord(result) || !Array.isArray((result as { files?: unknown }).files)) return [];
+  const out: GeneratedRunFile[] = [];
+  for (const file of (result as { files: unknown[] }).files) {
+    if (!isRecord(file)) continue;
+    const mediaType = typeof file.mediaType === "string" ? file.mediaType : "application/octet-stream";
+    const data = file.uint8Array instanceof Uint8Array ? file.uint8Array : null;
+    if (data) out.push({ mediaType, data });
+  }
+  return out;
+}
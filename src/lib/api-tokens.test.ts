@@ -0,0 +1,52 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { findUnique, update } = vi.hoisted(() => ({ findUnique: vi.fn(), update: vi.fn() }));
+vi.mock("@/lib/prisma", () => ({ prisma: { apiToken: { findUnique, update } } }));
+
+import { bearerApiToken, generateApiToken, hashApiToken, userIdForApiToken } from "./api-tokens";
+import { setClock, systemClock } from "./clock";
+
+const now = new Date("2026-04-13T12:00:00Z");
+
+afterEach(() => {
+  vi.clearAllMocks();
+});
+
+describe("api tokens", () => {
+  it("generates prefixed tokens and stores only their hash", () => {
+    const { token, tokenHash, tokenPrefix } = generateApiToken();
+    expect(token).toMatch(/^pl_[A-Za-z0-9_-]{43}$/);
+    expect(tokenHash).toBe(hashApiToken(token));
+    expect(tokenHash).not.toContain(token.slice(3));
+    expect(token.startsWith(tokenPrefix)).toBe(true);
+  });
+
+  it("only takes promptloop tokens from the Authorization header", () => {
+    expect(bearerApiToken("Bearer pl_abc")).toBe("pl_abc");
+    expect(bearerApiToken("bearer  pl_abc ")).toBe("pl_abc");
+    expect(bearerApiToken("Bearer cron-secret")).toBeNull();
+    expect(bearerApiToken("Basic pl_abc")).toBeNull();
+    expect(bearerApiToken(null)).toBeNull();
+  });
+
+  it("resolves valid tokens and throttles last-used updates", async () => {
+    const restore = setClock({ ...systemClock, now: () => now });
+    try {
+      findUnique.mockResolvedValueOnce({ id: "t1", userId: "u1", expiresAt: null, lastUsedAt: null });
+      expect(await userIdForApiToken("pl_x")).toBe("u1");
+      expect(findUnique).toHaveBeenCalledWith(expect.objectContaining({ where: { tokenHash: hashApiToken("pl_x") } }));
+      expect(update).toHaveBeenCalledWith({ where: { id: "t1" }, data: { lastUsedAt: now } });
+
+      findUnique.mockResolvedValueOnce({ id: "t1", userId: "u1", expiresAt: null, lastUsedAt: new Date(now.getTime() - 5000) });
+      expect(await userIdForApiToken("pl_x")).toBe("u1");
+      expect(update).toHaveBeenCalledTimes(1);
+
+      findUnique.mockResolvedValueOnce({ id: "t1", userId: "u1", expiresAt: now, lastUsedAt: null });
+      expect(await userIdForApiToken("pl_x")).toBeNull();
+      findUnique.mockResolvedValueOnce(null);
+      expect(await userIdForApiToken("pl_y")).toBeNull();
+    } finally {
+      restore();
+    }
+  });
+});
@@ -0,0 +1,64 @@
+import { createHash, randomBytes } from "crypto";
+import { prisma } from "@/lib/prisma";
+import { clock } from "@/lib/clock";
+
+// Personal access tokens: "pl_" plus 32 random bytes (base64url). Requests send them as "Authorization: Bearer pl_...".
+export const API_TOKEN_PREFIX = "pl_";
+export const API_TOKENS_PER_USER_MAX = 20;
+const DISPLAY_PREFIX_CHARS = 10;
+// last_used_at is refreshed at most this often, so busy scripts do not write on every request.
+const LAST_USED_REFRESH_MS = 60_000;
+
+export function hashApiToken(token: string) {
+  return createHash("sha256").update(token).digest("hex");
+}
+
+export function generateApiToken() {
+  const token = `${API_TOKEN_PREFIX}${randomBytes(32).toString("base64url")}`;
+  return { token, tokenHash: hashApiToken(token), tokenPrefix: token.slice(0, DISPLAY_PREFIX_CHARS) };
+}
+
+// The token from an Authorization header, or null when the header carries no promptloop token.
+export function bearerApiToken(header: string | null | undefined) {
+  const match = /^Bearer\s+(\S+)$/i.exec(header?.trim() ?? "");
+  return match && match[1].startsWith(API_TOKEN_PREFIX) ? match[1] : null;
+}
+
+export function toApiToken(token: { id: string; name: string; tokenPrefix: string; lastUsedAt: Date | null; expiresAt: Date | null; createdAt: Date }) {
+  return {
+    id: token.id,
+    name: token.name,
+    tokenPrefix: token.tokenPrefix,
+    lastUsedAt: token.lastUsedAt,
+    expiresAt: token.expiresAt,
+    createdAt: token.createdAt,
+  };
+}
+
+// Owner of a valid, unexpired token; null otherwise.
+export async function userIdForApiToken(token: string) {
+  const row = await prisma.apiToken.findUnique({
+    where: { tokenHash: hashApiToken(token) },
+    select: { id: true, userId: true, expiresAt: true, lastUsedAt: true },
+  });
+  const now = clock().now();
+  if (!row || (row.expiresAt && row.expiresAt <= now)) {
+    return null;
+  }
+  if (!row.lastUsedAt || now.getTime() - row.lastUsedAt.getTime() >= LAST_USED_REFRESH_MS) {
+    await prisma.apiToken.update({ where: { id: row.id }, data: { lastUsedAt: now } });
+  }
+  return row.userId;
+}
+
+export async function createApiToken(userId: string, input: { name: string; expiresInDays: number | null }) {
+  const count = await prisma.apiToken.count({ where: { userId } });
+  if (count >= API_TOKENS_PER_USER_MAX) {
+    throw new Error(`At most ${API_TOKENS_PER_USER_MAX} API tokens per account`);
+  }
+  const { token, tokenHash, tokenPrefix } = generateApiToken();
+  const expiresAt = input.expiresInDays ? new Date(clock().now().getTime() + input.expiresInDays * 24 * 60 * 60 * 1000) : null;
+  const row = await prisma.apiToken.create({ data: { userId, name: input.name, tokenHash, tokenPrefix, expiresAt } });
+  // The plaintext token is only ever returned here.
+  return { token, apiToken: toApiToken(row) };
+}
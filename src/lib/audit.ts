@@ -1,4 +1,5 @@
 import { prisma } from "@/lib/prisma";
+import { logger } from "@/lib/logger";
 
 export async function recordAudit(input: {
   userId: string;
@@ -20,6 +21,6 @@ export async function recordAudit(input: {
       },
     });
   } catch (err) {
-    console.error("audit_log_failed", err);
+    logger.error("audit log write failed", { action: input.action, entity_type: input.entityType, error: err });
   }
 }
@@ -3,6 +3,8 @@ import GoogleProvider from "next-auth/providers/google";
 import { prisma } from "@/lib/prisma";
 import { isAdminEmail } from "@/lib/admin-allowlist";
 
+const SEEN_REFRESH_MS = 24 * 60 * 60 * 1000;
+
 export const authOptions: NextAuthOptions = {
   session: { strategy: "jwt" },
   pages: { signIn: "/signin" },
@@ -63,6 +65,12 @@ export const authOptions: NextAuthOptions = {
           token.userId = dbUser.id;
         }
       }
+      // Sessions roll without a new sign-in, so activity is tracked here (at most once a day per token).
+      const seenAt = typeof token.seenAt === "number" ? token.seenAt : 0;
+      if (typeof token.userId === "string" && Date.now() - seenAt > SEEN_REFRESH_MS) {
+        await prisma.user.update({ where: { id: token.userId }, data: { lastSeenAt: new Date() } }).catch(() => undefined);
+        token.seenAt = Date.now();
+      }
       return token;
     },
     async session({ session, token }) {
@@ -0,0 +1,46 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { getServerSession, headers, userIdForApiToken } = vi.hoisted(() => ({
+  getServerSession: vi.fn(),
+  headers: vi.fn(),
+  userIdForApiToken: vi.fn(),
+}));
+vi.mock("next-auth", () => ({ getServerSession }));
+vi.mock("next/headers", () => ({ headers }));
+vi.mock("@/lib/auth-options", () => ({ authOptions: {} }));
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+vi.mock("@/lib/api-tokens", async (importOriginal) => ({ ...(await importOriginal<typeof import("./api-tokens")>()), userIdForApiToken }));
+
+import { requireApiUserId, requireUserId } from "./authz";
+
+function withAuthorization(value: string | null) {
+  headers.mockResolvedValue(new Headers(value ? { authorization: value } : {}));
+}
+
+afterEach(() => {
+  vi.clearAllMocks();
+});
+
+describe("authz", () => {
+  it("accepts API tokens on the jobs and run history API", async () => {
+    getServerSession.mockResolvedValue(null);
+    userIdForApiToken.mockResolvedValue("user_1");
+    withAuthorization("Bearer pl_valid");
+    await expect(requireApiUserId()).resolves.toBe("user_1");
+
+    userIdForApiToken.mockResolvedValue(null);
+    getServerSession.mockResolvedValue({ user: { id: "user_1" } });
+    await expect(requireApiUserId()).rejects.toThrow("Unauthorized");
+  });
+
+  it("rejects API tokens on session-only routes", async () => {
+    getServerSession.mockResolvedValue(null);
+    userIdForApiToken.mockResolvedValue("user_1");
+    withAuthorization("Bearer pl_valid");
+    await expect(requireUserId()).rejects.toThrow("Unauthorized");
+    expect(userIdForApiToken).not.toHaveBeenCalled();
+
+    getServerSession.mockResolvedValue({ user: { id: "user_2" } });
+    await expect(requireUserId()).resolves.toBe("user_2");
+  });
+});
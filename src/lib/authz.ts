@@ -1,5 +1,7 @@
 import { getServerSession } from "next-auth";
+import { headers } from "next/headers";
 import { authOptions } from "@/lib/auth-options";
+import { bearerApiToken, userIdForApiToken } from "@/lib/api-tokens";
 
 function getUserIdFromSession(session: unknown): string | null {
   if (typeof session !== "object" || session === null || Array.isArray(session)) {
@@ -13,6 +15,8 @@ function getUserIdFromSession(session: unknown): string | null {
   return typeof id === "string" && id.length ? id : null;
 }
 
+// The signed-in session user. API tokens are not accepted: account, billing, secrets, chat and token management
+// stay behind the browser session even if a token leaks.
 export async function requireUserId() {
   let session: unknown = null;
   try {
@@ -28,3 +32,25 @@ export async function requireUserId() {
 
   return userId;
 }
+
+// For the jobs and run history API only: the session user, or the owner of the API token in
+// "Authorization: Bearer pl_...". A request that presents a token is authenticated by the token alone, so an
+// invalid one is rejected even alongside a session cookie.
+export async function requireApiUserId() {
+  let authorization: string | null = null;
+  try {
+    authorization = (await headers()).get("authorization");
+  } catch {
+    authorization = null;
+  }
+
+  const token = bearerApiToken(authorization);
+  if (token) {
+    const userId = await userIdForApiToken(token);
+    if (!userId) {
+      throw new Error("Unauthorized");
+    }
+    return userId;
+  }
+  return requireUserId();
+}
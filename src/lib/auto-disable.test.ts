@@ -0,0 +1,36 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { autoDisableSettings, pausedUntil, repeatedFailureAction } from "./auto-disable";
+
+describe("auto-disable policy", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("uses the deployment defaults unless the job overrides them", () => {
+    expect(autoDisableSettings({ autoDisableThreshold: null, autoDisablePolicy: null })).toEqual({
+      threshold: 10,
+      policy: "disable",
+      cooldownMinutes: 1440,
+    });
+    vi.stubEnv("WORKER_AUTO_DISABLE_THRESHOLD", "3");
+    vi.stubEnv("WORKER_AUTO_DISABLE_POLICY", "pause");
+    vi.stubEnv("WORKER_AUTO_PAUSE_COOLDOWN_MINUTES", "60");
+    expect(autoDisableSettings({ autoDisableThreshold: null, autoDisablePolicy: null })).toEqual({ threshold: 3, policy: "pause", cooldownMinutes: 60 });
+    expect(autoDisableSettings({ autoDisableThreshold: 5, autoDisablePolicy: "disable" })).toMatchObject({ threshold: 5, policy: "disable" });
+    vi.stubEnv("WORKER_AUTO_DISABLE_THRESHOLD", "-1");
+    expect(autoDisableSettings({ autoDisableThreshold: null, autoDisablePolicy: null }).threshold).toBe(10);
+  });
+
+  it("acts once the threshold is reached, never with a threshold of 0", () => {
+    const settings = { threshold: 3, policy: "pause" as const, cooldownMinutes: 60 };
+    expect(repeatedFailureAction(2, settings)).toBeNull();
+    expect(repeatedFailureAction(3, settings)).toBe("pause");
+    expect(repeatedFailureAction(100, { ...settings, threshold: 0 })).toBeNull();
+  });
+
+  it("resumes at the first regular slot after the cooldown", () => {
+    const schedule = { scheduleType: "daily" as const, scheduleTime: "09:00", timezone: "UTC" };
+    const settings = { threshold: 3, policy: "pause" as const, cooldownMinutes: 1440 };
+    expect(pausedUntil(schedule, settings, new Date("2026-03-02T10:00:00Z"))).toEqual(new Date("2026-03-04T09:00:00Z"));
+  });
+});
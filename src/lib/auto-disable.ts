@@ -0,0 +1,42 @@
+import { computeNextRunAt, type ScheduleInput } from "@/lib/schedule";
+
+// What happens to a job whose scheduled slots keep failing. After `threshold` failed slots in a row (retries
+// included) the job is either disabled until someone re-enables it, or paused: it stays enabled, its failure
+// count starts over and it next runs at its first regular slot after the cooldown. A job's own threshold and
+// policy override WORKER_AUTO_DISABLE_THRESHOLD / WORKER_AUTO_DISABLE_POLICY; a threshold of 0 never stops it.
+
+export const AUTO_DISABLE_POLICIES = ["disable", "pause"] as const;
+export type AutoDisablePolicy = (typeof AUTO_DISABLE_POLICIES)[number];
+
+const DEFAULT_THRESHOLD = 10;
+const DEFAULT_COOLDOWN_MINUTES = 24 * 60;
+
+export type AutoDisableSettings = { threshold: number; policy: AutoDisablePolicy; cooldownMinutes: number };
+
+export function normalizeAutoDisablePolicy(value: unknown): AutoDisablePolicy {
+  return value === "pause" ? "pause" : "disable";
+}
+
+function envCount(name: string, fallback: number, min: number) {
+  const raw = process.env[name]?.trim();
+  const value = raw ? Number(raw) : fallback;
+  return Number.isInteger(value) && value >= min ? value : fallback;
+}
+
+export function autoDisableSettings(job: { autoDisableThreshold: number | null; autoDisablePolicy: string | null }): AutoDisableSettings {
+  return {
+    threshold: job.autoDisableThreshold ?? envCount("WORKER_AUTO_DISABLE_THRESHOLD", DEFAULT_THRESHOLD, 0),
+    policy: normalizeAutoDisablePolicy(job.autoDisablePolicy ?? process.env.WORKER_AUTO_DISABLE_POLICY?.trim()),
+    cooldownMinutes: envCount("WORKER_AUTO_PAUSE_COOLDOWN_MINUTES", DEFAULT_COOLDOWN_MINUTES, 1),
+  };
+}
+
+// Action for a slot that failed for good, given the job's failure count including that slot.
+export function repeatedFailureAction(failCount: number, settings: AutoDisableSettings): AutoDisablePolicy | null {
+  return settings.threshold > 0 && failCount >= settings.threshold ? settings.policy : null;
+}
+
+// A paused job resumes at its first regular slot after the cooldown, not at the end of the cooldown itself.
+export function pausedUntil(schedule: ScheduleInput, settings: AutoDisableSettings, now: Date) {
+  return computeNextRunAt(schedule, new Date(now.getTime() + settings.cooldownMinutes * 60_000));
+}
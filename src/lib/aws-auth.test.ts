@@ -0,0 +1,55 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { resolveAwsCredentials, signAwsRequest } from "./aws-auth";
+
+const credentials = { accessKeyId: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY" };
+const now = new Date("2015-08-30T12:36:00Z");
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("aws signing", () => {
+  // Expected signatures from the AWS SigV4 test suite (get-vanilla, get-vanilla-query-order-key-case).
+  it("matches the SigV4 test suite", () => {
+    const vanilla = signAwsRequest({ method: "GET", url: "https://example.amazonaws.com/", region: "us-east-1", service: "service", credentials, now });
+    expect(vanilla).toEqual({
+      "x-amz-date": "20150830T123600Z",
+      Authorization:
+        "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+    });
+    const query = signAwsRequest({
+      method: "GET",
+      url: "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+      region: "us-east-1",
+      service: "service",
+      credentials,
+      now,
+    });
+    expect(query.Authorization).toContain("Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500");
+  });
+
+  it("signs the session token and body headers", () => {
+    const headers = signAwsRequest({
+      method: "POST",
+      url: "https://sns.us-east-1.amazonaws.com/",
+      region: "us-east-1",
+      service: "sns",
+      headers: { "content-type": "application/x-www-form-urlencoded" },
+      body: "Action=Publish",
+      credentials: { ...credentials, sessionToken: "session" },
+      now,
+    });
+    expect(headers["x-amz-security-token"]).toBe("session");
+    expect(headers.Authorization).toContain("SignedHeaders=content-type;host;x-amz-date;x-amz-security-token");
+  });
+
+  it("uses web identity only when the deployment enables it", async () => {
+    await expect(resolveAwsCredentials({ accessKeyId: " AKID ", secretAccessKey: "s" }, "us-east-1")).resolves.toEqual({
+      accessKeyId: "AKID",
+      secretAccessKey: "s",
+    });
+    await expect(resolveAwsCredentials({ accessKeyId: "", secretAccessKey: "" }, "us-east-1")).rejects.toThrow(/not enabled/);
+    vi.stubEnv("CHANNEL_AWS_WEB_IDENTITY", "1");
+    await expect(resolveAwsCredentials({ accessKeyId: "", secretAccessKey: "" }, "us-east-1")).rejects.toThrow(/AWS_ROLE_ARN/);
+  });
+});
@@ -0,0 +1,136 @@
+import { createHash, createHmac } from "node:crypto";
+import { readFile } from "node:fs/promises";
+
+// AWS Signature Version 4 for the SNS and SQS channels, without the SDK. Credentials are the channel's access
+// key, or (when the operator enables CHANNEL_AWS_WEB_IDENTITY) the worker's web identity role, e.g. IRSA on EKS.
+
+export type AwsCredentials = { accessKeyId: string; secretAccessKey: string; sessionToken?: string };
+
+// Refresh a little early so credentials never expire mid-request.
+const CREDENTIALS_REFRESH_MARGIN_MS = 5 * 60_000;
+
+let webIdentityCache: { credentials: AwsCredentials; expiresAt: number } | null = null;
+
+function sha256Hex(value: string) {
+  return createHash("sha256").update(value, "utf8").digest("hex");
+}
+
+function hmac(key: string | Buffer, value: string) {
+  return createHmac("sha256", key).update(value, "utf8").digest();
+}
+
+// RFC 3986 encoding, as SigV4 expects for query strings.
+function encodeRfc3986(value: string) {
+  return encodeURIComponent(value).replace(/[!'()*]/g, (c) => `%${c.charCodeAt(0).toString(16).toUpperCase()}`);
+}
+
+function amzDate(now: Date) {
+  return now.toISOString().replace(/[-:]/g, "").replace(/\.\d{3}/, "");
+}
+
+// Returns the headers to send: the given ones plus x-amz-date, the session token and Authorization. The host header
+// is signed too; fetch sets it from the URL.
+export function signAwsRequest(input: {
+  method: string;
+  url: string;
+  region: string;
+  service: string;
+  headers?: Record<string, string>;
+  body?: string;
+  credentials: AwsCredentials;
+  now?: Date;
+}): Record<string, string> {
+  const url = new URL(input.url);
+  const date = amzDate(input.now ?? new Date());
+  const headers: Record<string, string> = { ...(input.headers ?? {}), "x-amz-date": date };
+  if (input.credentials.sessionToken) {
+    headers["x-amz-security-token"] = input.credentials.sessionToken;
+  }
+
+  const canonical = Object.entries({ ...headers, host: url.host })
+    .map(([name, value]) => [name.toLowerCase(), value.trim().replace(/\s+/g, " ")] as const)
+    .sort(([a], [b]) => (a < b ? -1 : a > b ? 1 : 0));
+  const signedHeaders = canonical.map(([name]) => name).join(";");
+  const query = [...url.searchParams.entries()]
+    .map(([key, value]) => [encodeRfc3986(key), encodeRfc3986(value)])
+    .sort(([a, av], [b, bv]) => (a === b ? (av < bv ? -1 : 1) : a < b ? -1 : 1))
+    .map(([key, value]) => `${key}=${value}`)
+    .join("&");
+  const canonicalRequest = [
+    input.method.toUpperCase(),
+    url.pathname || "/",
+    query,
+    canonical.map(([name, value]) => `${name}:${value}\n`).join(""),
+    signedHeaders,
+    sha256Hex(input.body ?? ""),
+  ].join("\n");
+
+  const day = date.slice(0, 8);
+  const scope = `${day}/${input.region}/${input.service}/aws4_request`;
+  const stringToSign = ["AWS4-HMAC-SHA256", date, scope, sha256Hex(canonicalRequest)].join("\n");
+  const key = hmac(hmac(hmac(hmac(`AWS4${input.credentials.secretAccessKey}`, day), input.region), input.service), "aws4_request");
+  const signature = createHmac("sha256", key).update(stringToSign, "utf8").digest("hex");
+
+  return {
+    ...headers,
+    Authorization: `AWS4-HMAC-SHA256 Credential=${input.credentials.accessKeyId}/${scope}, SignedHeaders=${signedHeaders}, Signature=${signature}`,
+  };
+}
+
+export function awsWebIdentityEnabled() {
+  return ["1", "true", "yes"].includes((process.env.CHANNEL_AWS_WEB_IDENTITY ?? "").trim().toLowerCase());
+}
+
+function xmlValue(xml: string, tag: string) {
+  return new RegExp(`<${tag}>([^<]*)</${tag}>`).exec(xml)?.[1] ?? null;
+}
+
+// AssumeRoleWithWebIdentity with AWS_ROLE_ARN and the token in AWS_WEB_IDENTITY_TOKEN_FILE (cached until shortly
+// before the credentials expire). The call itself is unsigned.
+async function webIdentityCredentials(region: string): Promise<AwsCredentials> {
+  if (webIdentityCache && webIdentityCache.expiresAt - CREDENTIALS_REFRESH_MARGIN_MS > Date.now()) {
+    return webIdentityCache.credentials;
+  }
+  const roleArn = process.env.AWS_ROLE_ARN?.trim();
+  const tokenFile = process.env.AWS_WEB_IDENTITY_TOKEN_FILE?.trim();
+  if (!roleArn || !tokenFile) {
+    throw new Error("AWS web identity needs AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE");
+  }
+  const token = (await readFile(tokenFile, "utf8")).trim();
+  const res = await fetch(`https://sts.${region}.amazonaws.com/`, {
+    method: "POST",
+    headers: { "Content-Type": "application/x-www-form-urlencoded" },
+    body: new URLSearchParams({
+      Action: "AssumeRoleWithWebIdentity",
+      Version: "2011-06-15",
+      RoleArn: roleArn,
+      RoleSessionName: "promptloop",
+      WebIdentityToken: token,
+    }),
+  });
+  if (!res.ok) {
+    throw new Error(`AWS AssumeRoleWithWebIdentity failed: ${res.status}`);
+  }
+  const xml = await res.text();
+  const accessKeyId = xmlValue(xml, "AccessKeyId");
+  const secretAccessKey = xmlValue(xml, "SecretAccessKey");
+  const sessionToken = xmlValue(xml, "SessionToken");
+  if (!accessKeyId || !secretAccessKey || !sessionToken) {
+    throw new Error("AWS AssumeRoleWithWebIdentity returned no credentials");
+  }
+  const expiration = Date.parse(xmlValue(xml, "Expiration") ?? "");
+  const credentials = { accessKeyId, secretAccessKey, sessionToken };
+  webIdentityCache = { credentials, expiresAt: Number.isFinite(expiration) ? expiration : Date.now() + 15 * 60_000 };
+  return credentials;
+}
+
+// The channel's own access key when set, otherwise the worker's web identity role if the operator allows it.
+export async function resolveAwsCredentials(channel: { accessKeyId: string; secretAccessKey: string }, region: string) {
+  if (channel.accessKeyId.trim()) {
+    return { accessKeyId: channel.accessKeyId.trim(), secretAccessKey: channel.secretAccessKey };
+  }
+  if (!awsWebIdentityEnabled()) {
+    throw new Error("AWS access key is required (web identity credentials are not enabled on this deployment)");
+  }
+  return webIdentityCredentials(region);
+}
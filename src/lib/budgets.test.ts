@@ -0,0 +1,26 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { exceedsBudget, formatBudgetNotice, monthKey, startOfMonthUtc } from "./budgets";
+
+describe("monthly budgets", () => {
+  it("uses UTC calendar months", () => {
+    const at = new Date("2026-03-31T23:30:00-05:00");
+    expect(monthKey(at)).toBe("2026-04");
+    expect(startOfMonthUtc(at).toISOString()).toBe("2026-04-01T00:00:00.000Z");
+  });
+
+  it("treats a null limit as no cap", () => {
+    expect(exceedsBudget(1000, null)).toBe(false);
+    expect(exceedsBudget(4.99, 5)).toBe(false);
+    expect(exceedsBudget(5, 5)).toBe(true);
+    expect(exceedsBudget(0, 0)).toBe(true);
+  });
+
+  it("formats the one-time notice", () => {
+    expect(formatBudgetNotice({ month: "2026-04", limitUsd: 5, spentUsd: 5.1234, exceeded: true })).toContain(
+      "($5.12) reached the monthly budget of $5.00",
+    );
+  });
+});
@@ -0,0 +1,55 @@
+import { prisma } from "@/lib/prisma";
+import { getEntitlements } from "@/lib/entitlements";
+import { clock } from "@/lib/clock";
+
+// Budgets follow UTC calendar months so every worker agrees on when a month rolls over.
+export function monthKey(at: Date) {
+  return at.toISOString().slice(0, 7);
+}
+
+export function startOfMonthUtc(at: Date) {
+  return new Date(Date.UTC(at.getUTCFullYear(), at.getUTCMonth(), 1));
+}
+
+export function exceedsBudget(spentUsd: number, limitUsd: number | null) {
+  return limitUsd != null && spentUsd >= limitUsd;
+}
+
+// Estimated spend (run_histories.cost_usd, previews included) for the user's jobs since the start of the month.
+export async function getMonthlySpendUsd(userId: string, at = clock().now()) {
+  const total = await prisma.runHistory.aggregate({
+    _sum: { costUsd: true },
+    where: { runAt: { gte: startOfMonthUtc(at) }, job: { userId } },
+  });
+  return total._sum.costUsd ?? 0;
+}
+
+export type BudgetStatus = {
+  month: string;
+  limitUsd: number | null;
+  spentUsd: number;
+  exceeded: boolean;
+};
+
+export async function checkMonthlyBudget(userId: string, at = clock().now()): Promise<BudgetStatus> {
+  const entitlements = await getEntitlements(userId);
+  const limitUsd = entitlements.limits.monthlyBudgetUsd;
+  if (limitUsd == null) {
+    return { month: monthKey(at), limitUsd, spentUsd: 0, exceeded: false };
+  }
+  const spentUsd = await getMonthlySpendUsd(userId, at);
+  return { month: monthKey(at), limitUsd, spentUsd, exceeded: exceedsBudget(spentUsd, limitUsd) };
+}
+
+// Marks the user as notified for the month; true only for the first caller, so the notice goes out once.
+export async function claimBudgetNotice(userId: string, month: string) {
+  const claimed = await prisma.user.updateMany({
+    where: { id: userId, OR: [{ budgetNotifiedMonth: null }, { budgetNotifiedMonth: { not: month } }] },
+    data: { budgetNotifiedMonth: month },
+  });
+  return claimed.count > 0;
+}
+
+export function formatBudgetNotice(status: BudgetStatus) {
+  return `Scheduled runs are paused until next month: estimated LLM spend for ${status.month} ($${status.spentUsd.toFixed(2)}) reached the monthly budget of $${(status.limitUsd ?? 0).toFixed(2)}.`;
+}
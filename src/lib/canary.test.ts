@@ -0,0 +1,49 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { assignCanary, canaryConfig } from "./canary";
+
+const config = { baseModel: "gpt-5-mini", canaryModel: "gpt-5.2", canaryPercent: 20 };
+
+describe("model canary", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("needs every setting to be enabled", () => {
+    expect(canaryConfig()).toBeNull();
+    vi.stubEnv("LLM_CANARY_MODEL", "gpt-5.2");
+    vi.stubEnv("LLM_CANARY_BASE_MODEL", "gpt-5-mini");
+    expect(canaryConfig()).toBeNull();
+    vi.stubEnv("LLM_CANARY_PERCENT", "250");
+    expect(canaryConfig()).toEqual({ baseModel: "gpt-5-mini", canaryModel: "gpt-5.2", canaryPercent: 100 });
+    vi.stubEnv("LLM_CANARY_BASE_MODEL", "gpt-5.2");
+    expect(canaryConfig()).toBeNull();
+  });
+
+  it("splits only runs on the base model, close to the configured share", () => {
+    expect(assignCanary("run-1", ["gpt-5"], config)).toBeNull();
+    expect(assignCanary("run-1", ["gpt-5-mini"], null)).toBeNull();
+    const cohorts = Array.from({ length: 1000 }, (_, i) => assignCanary(`run-${i}`, ["gpt-5-mini"], config)?.cohort);
+    const canaries = cohorts.filter((cohort) => cohort === "canary").length;
+    expect(canaries).toBeGreaterThan(140);
+    expect(canaries).toBeLessThan(260);
+    expect(cohorts.filter((cohort) => cohort === "control").length).toBe(1000 - canaries);
+  });
+
+  it("tries the canary first and falls back to the routed models", () => {
+    const runId = Array.from({ length: 100 }, (_, i) => `run-${i}`).find((id) => assignCanary(id, ["gpt-5-mini"], config)?.cohort === "canary");
+    expect(assignCanary(runId!, ["gpt-5-mini", "gpt-5"], config)).toEqual({
+      cohort: "canary",
+      model: "gpt-5.2",
+      candidates: ["gpt-5.2", "gpt-5-mini", "gpt-5"],
+    });
+    const controlId = Array.from({ length: 100 }, (_, i) => `run-${i}`).find((id) => assignCanary(id, ["gpt-5-mini"], config)?.cohort === "control");
+    expect(assignCanary(controlId!, ["gpt-5-mini", "gpt-5"], config)).toEqual({
+      cohort: "control",
+      model: "gpt-5-mini",
+      candidates: ["gpt-5-mini", "gpt-5"],
+    });
+  });
+});
@@ -0,0 +1,111 @@
+import { createHash } from "node:crypto";
+import { Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { GENERATED_RUN_STATUSES } from "@/lib/run-status";
+
+export type CanaryCohort = "canary" | "control";
+
+export type CanaryConfig = {
+  // Runs whose model is baseModel are split; canaryPercent of them use canaryModel instead.
+  baseModel: string;
+  canaryModel: string;
+  canaryPercent: number;
+};
+
+// LLM_CANARY_MODEL, LLM_CANARY_BASE_MODEL and LLM_CANARY_PERCENT (1-100) are read per deployment, so a canary
+// can run on one worker environment before the others. Any of them missing turns the canary off.
+export function canaryConfig(): CanaryConfig | null {
+  const canaryModel = process.env.LLM_CANARY_MODEL?.trim();
+  const baseModel = process.env.LLM_CANARY_BASE_MODEL?.trim();
+  const percent = Number(process.env.LLM_CANARY_PERCENT ?? 0);
+  if (!canaryModel || !baseModel || canaryModel === baseModel || !Number.isFinite(percent) || percent <= 0) {
+    return null;
+  }
+  return { baseModel, canaryModel, canaryPercent: Math.min(percent, 100) };
+}
+
+// Salted so the canary split is independent of quality sampling, which buckets the same run ids.
+function canaryBucket(runId: string) {
+  return createHash("sha256").update(`canary:${runId}`).digest().readUInt16BE(0) % 100;
+}
+
+// Assigns a run to a cohort when its first model is the canary's base model. Canary runs fall back to the base
+// model when the new one cannot produce a result, so a bad canary costs an extra call rather than a failed run.
+export function assignCanary(runId: string, candidates: string[], config = canaryConfig()) {
+  if (!config || candidates[0] !== config.baseModel) {
+    return null;
+  }
+  if (canaryBucket(runId) >= config.canaryPercent) {
+    return { cohort: "control" as const, model: config.baseModel, candidates };
+  }
+  return {
+    cohort: "canary" as const,
+    model: config.canaryModel,
+    candidates: [config.canaryModel, ...candidates.filter((model) => model !== config.canaryModel)],
+  };
+}
+
+export type CanaryReportRow = {
+  cohort: CanaryCohort;
+  model: string;
+  runs: number;
+  succeeded: number;
+  successRate: number;
+  // Runs that produced output on another model (canary fallbacks, auto upgrades).
+  fellBack: number;
+  avgCostUsd: number | null;
+  avgLlmDurationMs: number | null;
+  avgQualityScore: number | null;
+};
+
+// Success, cost, latency and quality per cohort and assigned model for scheduled runs since a point in time.
+export async function canaryReport(opts: { since: Date; environment?: string }): Promise<CanaryReportRow[]> {
+  const rows = await prisma.$queryRaw<
+    Array<{
+      cohort: CanaryCohort;
+      model: string;
+      runs: bigint;
+      succeeded: bigint;
+      fell_back: bigint;
+      avg_cost_usd: number | null;
+      avg_llm_duration_ms: number | null;
+      avg_quality_score: number | null;
+    }>
+  >`
+    SELECT
+      r.canary_cohort AS cohort,
+      r.cohort_model AS model,
+      COUNT(*) AS runs,
+      COUNT(*) FILTER (WHERE r.status::text IN (${Prisma.join(GENERATED_RUN_STATUSES)})) AS succeeded,
+      COUNT(*) FILTER (WHERE r.llm_model IS NOT NULL AND r.llm_model <> r.cohort_model) AS fell_back,
+      AVG(r.cost_usd) AS avg_cost_usd,
+      AVG(r.llm_duration_ms)::float8 AS avg_llm_duration_ms,
+      AVG(r.quality_score) AS avg_quality_score
+    FROM run_histories r
+    JOIN jobs j ON j.id = r.job_id
+    WHERE r.canary_cohort IS NOT NULL
+      AND r.is_preview = false
+      AND r.status <> 'running'
+      AND r.run_at >= ${opts.since}
+      ${opts.environment ? Prisma.sql`AND j.environment = ${opts.environment}` : Prisma.empty}
+    GROUP BY r.canary_cohort, r.cohort_model
+    ORDER BY r.canary_cohort, r.cohort_model
+  `;
+
+  const round = (value: number | null, digits: number) => (value == null ? null : Math.round(Number(value) * 10 ** digits) / 10 ** digits);
+  return rows.map((row) => {
+    const runs = Number(row.runs);
+    const succeeded = Number(row.succeeded);
+    return {
+      cohort: row.cohort,
+      model: row.model,
+      runs,
+      succeeded,
+      successRate: runs ? Math.round((succeeded / runs) * 1000) / 1000 : 0,
+      fellBack: Number(row.fell_back),
+      avgCostUsd: round(row.avg_cost_usd, 6),
+      avgLlmDurationMs: round(row.avg_llm_duration_ms, 0),
+      avgQualityScore: round(row.avg_quality_score, 1),
+    };
+  });
+}
@@ -0,0 +1,87 @@
+import { afterEach, beforeEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { ChannelType } from "@prisma/client";
+import { decryptString, encryptString } from "./crypto";
+import {
+  CHANNEL_CONFIG_VERSION,
+  ChannelConfigError,
+  channelConfigJsonSchemas,
+  decodeChannelConfig,
+  encodeChannelConfig,
+  upgradeChannelConfig,
+} from "./channel-config";
+import { toMaskedChannel, toRunnableChannel } from "./jobs";
+
+beforeEach(() => {
+  vi.stubEnv("CHANNEL_SECRET_KEY", "test-secret");
+});
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("channel config versions", () => {
+  it("stores every external channel as one encrypted document at the current version", () => {
+    const { channelType, channelConfig } = encodeChannelConfig({ type: "telegram", config: { botToken: "123456:abcdef", chatId: "42" } });
+    expect(channelType).toBe(ChannelType.telegram);
+    expect(channelConfig.v).toBe(CHANNEL_CONFIG_VERSION);
+    expect(JSON.parse(decryptString((channelConfig as { configEnc: string }).configEnc))).toEqual({ botToken: "123456:abcdef", chatId: "42" });
+    expect(encodeChannelConfig({ type: "in_app" }).channelConfig).toEqual({ v: CHANNEL_CONFIG_VERSION, kind: "in_app" });
+  });
+
+  it("reads unversioned configs and fills fields added later", () => {
+    expect(
+      decodeChannelConfig({ channelType: ChannelType.discord, channelConfig: { webhookUrlEnc: encryptString("https://discord.com/api/webhooks/1/a") } }),
+    ).toEqual({ type: "discord", config: { webhookUrl: "https://discord.com/api/webhooks/1/a" } });
+
+    const oldWebhook = { configEnc: encryptString(JSON.stringify({ url: "https://example.com/hook", method: "PUT", headers: "{}", payload: "" })) };
+    expect(decodeChannelConfig({ channelType: ChannelType.webhook, channelConfig: oldWebhook })).toEqual({
+      type: "webhook",
+      config: {
+        url: "https://example.com/hook",
+        method: "PUT",
+        headers: "{}",
+        payload: "",
+        graphqlQuery: "",
+        bodyFormat: "json",
+        gzip: false,
+        signingSecret: "",
+      },
+    });
+  });
+
+  it("raises typed errors for unreadable and invalid configs", () => {
+    const unreadable = { channelType: ChannelType.telegram, channelConfig: { botTokenEnc: "bad", chatIdEnc: "bad" } };
+    expect(() => decodeChannelConfig(unreadable)).toThrow(ChannelConfigError);
+    expect(() => decodeChannelConfig({ channelType: ChannelType.redis, channelConfig: { v: 9, configEnc: "x" } })).toThrow(/newer than this release/);
+
+    const invalid = { channelType: ChannelType.telegram, channelConfig: { v: 2, configEnc: encryptString(JSON.stringify({ botToken: "short", chatId: "1" })) } };
+    expect(() => toRunnableChannel(invalid)).toThrow("Invalid telegram channel config: botToken:");
+    // The editor can still open it.
+    expect(toMaskedChannel(invalid)).toMatchObject({ type: "telegram" });
+    expect(() => encodeChannelConfig({ type: "discord", config: { webhookUrl: "not a url" } })).toThrow(ChannelConfigError);
+  });
+
+  it("upgrades legacy rows once", () => {
+    const legacy = { channelType: ChannelType.discord, channelConfig: { webhookUrlEnc: encryptString("https://discord.com/api/webhooks/1/a") } };
+    const upgraded = upgradeChannelConfig(legacy);
+    expect(upgraded).toMatchObject({ v: CHANNEL_CONFIG_VERSION });
+    expect(decodeChannelConfig({ channelType: ChannelType.discord, channelConfig: upgraded as { v: number; configEnc: string } })).toEqual(
+      decodeChannelConfig(legacy),
+    );
+    expect(upgradeChannelConfig({ channelType: ChannelType.discord, channelConfig: upgraded as { v: number } })).toBeNull();
+    expect(upgradeChannelConfig({ channelType: ChannelType.telegram, channelConfig: { botTokenEnc: "bad", chatIdEnc: "bad" } })).toEqual({
+      botTokenEnc: "bad",
+      chatIdEnc: "bad",
+      v: 1,
+    });
+  });
+
+  it("publishes a JSON Schema per channel type", () => {
+    const schemas = channelConfigJsonSchemas();
+    expect(Object.keys(schemas)).toContain("home_assistant");
+    expect(schemas.discord).toMatchObject({ type: "object", required: ["webhookUrl"] });
+  });
+});
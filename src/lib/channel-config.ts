@@ -0,0 +1,196 @@
+import { ChannelType, Prisma } from "@prisma/client";
+import { z } from "zod";
+import { prisma } from "@/lib/prisma";
+import { decryptString, encryptString } from "@/lib/crypto";
+import { channelConfigSchemas, jobChannelSchema } from "@/lib/validation";
+import { isRecord } from "@/lib/type-guards";
+import { logger } from "@/lib/logger";
+import type { IncomingChannel, StoredChannel } from "@/lib/jobs";
+
+// Stored channel_config shapes. Version 1 (unversioned, or stamped v: 1 when an upgrade failed) is what jobs
+// were saved with before versioning: Discord and Telegram kept one encrypted value per field, the other types an
+// encrypted JSON document whose optional fields depended on when it was saved. Version 2 stores every external
+// channel as { v: 2, configEnc } holding the complete config, defaults included.
+export const CHANNEL_CONFIG_VERSION = 2;
+
+export type ChannelConfigDb = { v: number; configEnc: string } | { v: number; kind: "in_app" };
+
+// decrypt: the stored value cannot be read with the configured keys (or is not a channel config at all).
+// invalid: it decrypts but no longer passes the channel's schema.
+export class ChannelConfigError extends Error {
+  kind: "decrypt" | "invalid";
+  channelType: ChannelType;
+  issues: string[];
+
+  constructor(kind: "decrypt" | "invalid", channelType: ChannelType, issues: string[]) {
+    super(
+      kind === "decrypt"
+        ? `${channelType} channel config cannot be decrypted: ${issues.join("; ")}`
+        : `Invalid ${channelType} channel config: ${issues.join("; ")}`,
+    );
+    this.name = "ChannelConfigError";
+    this.kind = kind;
+    this.channelType = channelType;
+    this.issues = issues;
+  }
+}
+
+// Fields added to channel configs after their type was introduced, with the value older configs behave as.
+const ADDED_FIELD_DEFAULTS: Partial<Record<ChannelType, Record<string, unknown>>> = {
+  webhook: {
+    method: "POST",
+    headers: "{}",
+    payload: "",
+    graphqlQuery: "",
+    bodyFormat: "json",
+    gzip: false,
+    signingSecret: "",
+    captureResponse: false,
+    proxyUrl: "",
+    caBundle: "",
+    insecureSkipVerify: false,
+  },
+  elasticsearch: { apiKey: "", username: "", password: "" },
+  clickhouse: { username: "", password: "" },
+  redis: { mode: "set", ttlSeconds: "" },
+};
+
+export function channelConfigVersion(config: unknown) {
+  return isRecord(config) && typeof config.v === "number" ? config.v : 1;
+}
+
+function decryptJson(value: unknown) {
+  if (typeof value !== "string") {
+    throw new Error("configEnc is missing");
+  }
+  const parsed = JSON.parse(decryptString(value)) as unknown;
+  if (!isRecord(parsed)) {
+    throw new Error("config is not an object");
+  }
+  return parsed;
+}
+
+// The plaintext config of a version 1 value.
+function readV1(channelType: ChannelType, config: Record<string, unknown>): Record<string, unknown> {
+  if (channelType === ChannelType.discord) {
+    if (typeof config.webhookUrlEnc !== "string") {
+      throw new Error("webhookUrlEnc is missing");
+    }
+    return { webhookUrl: decryptString(config.webhookUrlEnc) };
+  }
+  if (channelType === ChannelType.telegram) {
+    if (typeof config.botTokenEnc !== "string" || typeof config.chatIdEnc !== "string") {
+      throw new Error("botTokenEnc or chatIdEnc is missing");
+    }
+    return { botToken: decryptString(config.botTokenEnc), chatId: decryptString(config.chatIdEnc) };
+  }
+  return { ...ADDED_FIELD_DEFAULTS[channelType], ...decryptJson(config.configEnc) };
+}
+
+// Reads a stored channel at any version into the current editor/API shape. No schema validation: configs that a
+// later release tightened can still be opened and fixed in the editor.
+export function decodeChannelConfig(stored: StoredChannel): IncomingChannel {
+  if (stored.channelType === ChannelType.in_app) {
+    return { type: "in_app" };
+  }
+  const raw = stored.channelConfig;
+  let config: Record<string, unknown>;
+  try {
+    if (!isRecord(raw)) {
+      throw new Error("config is not an object");
+    }
+    const version = channelConfigVersion(raw);
+    if (version > CHANNEL_CONFIG_VERSION) {
+      throw new Error(`config version ${version} is newer than this release supports (${CHANNEL_CONFIG_VERSION})`);
+    }
+    config = version === 1 ? readV1(stored.channelType, raw) : decryptJson(raw.configEnc);
+  } catch (err) {
+    throw new ChannelConfigError("decrypt", stored.channelType, [err instanceof Error ? err.message : String(err)]);
+  }
+  return { type: stored.channelType, config } as IncomingChannel;
+}
+
+// Parses a channel against its type's schema, filling defaults. Throws ChannelConfigError with one entry per issue.
+export function validateChannel(channel: IncomingChannel): IncomingChannel {
+  const parsed = jobChannelSchema.safeParse(channel);
+  if (!parsed.success) {
+    throw new ChannelConfigError(
+      "invalid",
+      channel.type as ChannelType,
+      parsed.error.issues.map((issue) => `${issue.path.filter((part) => part !== "config").join(".") || "config"}: ${issue.message}`),
+    );
+  }
+  return parsed.data as IncomingChannel;
+}
+
+// Validates and encrypts a channel at the current version (every save goes through here).
+export function encodeChannelConfig(channel: IncomingChannel): { channelType: ChannelType; channelConfig: ChannelConfigDb } {
+  const valid = validateChannel(channel);
+  if (valid.type === "in_app") {
+    return { channelType: ChannelType.in_app, channelConfig: { v: CHANNEL_CONFIG_VERSION, kind: "in_app" } };
+  }
+  return {
+    channelType: valid.type as ChannelType,
+    channelConfig: { v: CHANNEL_CONFIG_VERSION, configEnc: encryptString(JSON.stringify(valid.config)) },
+  };
+}
+
+// The stored value rewritten at the current version, or null when it already is. Values that cannot be decoded or
+// no longer validate are stamped { v: 1 } so the upgrade does not pick them up again; they keep working as before.
+export function upgradeChannelConfig(stored: StoredChannel): Prisma.InputJsonValue | null {
+  if (isRecord(stored.channelConfig) && typeof stored.channelConfig.v === "number") {
+    return null;
+  }
+  try {
+    return encodeChannelConfig(decodeChannelConfig(stored)).channelConfig;
+  } catch (err) {
+    logger.warn("channel config upgrade failed", { channel_type: stored.channelType, error: err });
+    return { ...(isRecord(stored.channelConfig) ? stored.channelConfig : {}), v: 1 } as Prisma.InputJsonValue;
+  }
+}
+
+// Worker tick step: upgrades a batch of unversioned job and saved channel configs. Each update only applies if the
+// row was not edited meanwhile.
+export async function upgradeChannelConfigs(limit: number) {
+  let upgraded = 0;
+  const jobs = await prisma.$queryRaw<Array<{ id: string; channel_type: ChannelType; channel_config: unknown }>>`
+    SELECT id, channel_type, channel_config FROM jobs
+    WHERE jsonb_typeof(channel_config) = 'object' AND channel_config->'v' IS NULL
+    LIMIT ${limit}
+  `;
+  for (const job of jobs) {
+    const next = upgradeChannelConfig({ channelType: job.channel_type, channelConfig: job.channel_config as Prisma.JsonValue });
+    if (next) {
+      const res = await prisma.job.updateMany({
+        where: { id: job.id, channelConfig: { equals: job.channel_config as Prisma.InputJsonValue } },
+        data: { channelConfig: next },
+      });
+      upgraded += res.count;
+    }
+  }
+
+  const channels = await prisma.$queryRaw<Array<{ id: string; channel_type: ChannelType; channel_config: unknown }>>`
+    SELECT id, channel_type, channel_config FROM saved_channels
+    WHERE jsonb_typeof(channel_config) = 'object' AND channel_config->'v' IS NULL
+    LIMIT ${limit}
+  `;
+  for (const channel of channels) {
+    const next = upgradeChannelConfig({ channelType: channel.channel_type, channelConfig: channel.channel_config as Prisma.JsonValue });
+    if (next) {
+      const res = await prisma.savedChannel.updateMany({
+        where: { id: channel.id, channelConfig: { equals: channel.channel_config as Prisma.InputJsonValue } },
+        data: { channelConfig: next },
+      });
+      upgraded += res.count;
+    }
+  }
+  return upgraded;
+}
+
+// JSON Schema (draft 2020-12) of each external channel type's config, as accepted by the jobs and channels APIs.
+// Cross-field rules (e.g. GraphQL mode requires POST) are enforced by the API but not expressible here.
+export function channelConfigJsonSchemas() {
+  return Object.fromEntries(
+    Object.entries(channelConfigSchemas).map(([type, schema]) => [type, z.toJSONSchema(schema, { io: "input" })]),
+  );
+}
@@ -0,0 +1,69 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+vi.mock("@/lib/destination-policy", () => ({ checkDestination: vi.fn(async () => null) }));
+
+import { checkChannelCredentials, credentialCheckHours } from "./channel-health";
+
+function stubResponses(...responses: Array<{ status: number; body?: unknown }>) {
+  const fetchMock = vi.fn();
+  for (const { status, body } of responses) {
+    fetchMock.mockResolvedValueOnce(new Response(JSON.stringify(body ?? {}), { status }));
+  }
+  vi.stubGlobal("fetch", fetchMock);
+  return fetchMock;
+}
+
+afterEach(() => {
+  vi.unstubAllGlobals();
+  vi.unstubAllEnvs();
+});
+
+describe("channel credential checks", () => {
+  it("reads the check interval", () => {
+    expect(credentialCheckHours()).toBe(24);
+    vi.stubEnv("CHANNEL_CREDENTIAL_CHECK_HOURS", "0");
+    expect(credentialCheckHours()).toBe(0);
+  });
+
+  it("flags deleted Discord webhooks and ignores server errors", async () => {
+    const channel = { type: "discord" as const, webhookUrl: "https://discord.com/api/webhooks/1/abc" };
+    const fetchMock = stubResponses({ status: 200 }, { status: 404 }, { status: 503 });
+    await expect(checkChannelCredentials(channel)).resolves.toEqual({ status: "ok" });
+    await expect(checkChannelCredentials(channel)).resolves.toMatchObject({ status: "invalid" });
+    await expect(checkChannelCredentials(channel)).resolves.toMatchObject({ status: "unknown" });
+    expect(fetchMock.mock.calls[0][1]).toMatchObject({ method: "GET" });
+  });
+
+  it("checks the Telegram bot token and chat", async () => {
+    const channel = { type: "telegram" as const, botToken: "123:abc", chatId: "-100" };
+    const fetchMock = stubResponses(
+      { status: 200, body: { ok: true } },
+      { status: 200, body: { ok: true } },
+      { status: 401, body: { ok: false, description: "Unauthorized" } },
+      { status: 200, body: { ok: true } },
+      { status: 403, body: { ok: false, description: "Forbidden: bot was kicked from the supergroup chat" } },
+    );
+    await expect(checkChannelCredentials(channel)).resolves.toEqual({ status: "ok" });
+    expect(fetchMock.mock.calls[1][0]).toBe("https://api.telegram.org/bot123:abc/getChat?chat_id=-100");
+    await expect(checkChannelCredentials(channel)).resolves.toEqual({
+      status: "invalid",
+      reason: "Telegram bot token was revoked: Unauthorized",
+    });
+    await expect(checkChannelCredentials(channel)).resolves.toMatchObject({
+      status: "invalid",
+      reason: expect.stringContaining("bot was kicked"),
+    });
+  });
+
+  it("treats network errors as inconclusive and skips unchecked channel types", async () => {
+    vi.stubGlobal("fetch", vi.fn().mockRejectedValue(new Error("ECONNRESET")));
+    await expect(checkChannelCredentials({ type: "discord", webhookUrl: "https://discord.com/api/webhooks/1/abc" })).resolves.toEqual({
+      status: "unknown",
+      reason: "ECONNRESET",
+    });
+    await expect(
+      checkChannelCredentials({ type: "redis", restUrl: "https://redis.test", token: "t", mode: "set", key: "k", ttlSeconds: "" }),
+    ).resolves.toBeNull();
+  });
+});
@@ -0,0 +1,157 @@
+import { ChannelType } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { DEFAULT_USER_AGENT, type SendChannelInput } from "@/lib/channel";
+import { checkDestination } from "@/lib/destination-policy";
+import { runnableJobChannel } from "@/lib/saved-channels";
+import { recordAudit } from "@/lib/audit";
+import { logger } from "@/lib/logger";
+import { clock } from "@/lib/clock";
+import type { UserPlan } from "@/lib/entitlements";
+
+const HOUR_MS = 60 * 60 * 1000;
+const CHECK_TIMEOUT_MS = 10_000;
+
+// Channels with a cheap read-only endpoint that proves the stored credentials still work.
+export const CHECKED_CHANNEL_TYPES = [ChannelType.discord, ChannelType.telegram, ChannelType.home_assistant];
+
+// invalid: the provider rejected the credentials. unknown: the check itself failed (network, 5xx, rate limit),
+// which says nothing about the credentials.
+export type CredentialCheck = { status: "ok" } | { status: "invalid"; reason: string } | { status: "unknown"; reason: string };
+
+// CHANNEL_CREDENTIAL_CHECK_HOURS: how often each job's channel credentials are validated (default 24; 0 turns
+// the checks off).
+export function credentialCheckHours() {
+  const raw = Number(process.env.CHANNEL_CREDENTIAL_CHECK_HOURS ?? 24);
+  return Number.isFinite(raw) && raw > 0 ? raw : 0;
+}
+
+async function telegramDescription(res: Response) {
+  const body = (await res.json().catch(() => null)) as { description?: unknown } | null;
+  return typeof body?.description === "string" ? body.description : `HTTP ${res.status}`;
+}
+
+export async function checkChannelCredentials(
+  channel: SendChannelInput,
+  opts?: { userAgent?: string | null; plan?: UserPlan | null },
+): Promise<CredentialCheck | null> {
+  const headers = { "User-Agent": opts?.userAgent?.trim() || DEFAULT_USER_AGENT };
+  const get = (url: string, extra?: Record<string, string>) =>
+    fetch(url, { method: "GET", headers: { ...headers, ...extra }, signal: AbortSignal.timeout(CHECK_TIMEOUT_MS) });
+
+  try {
+    if (channel.type === "discord") {
+      const blocked = await checkDestination(channel.webhookUrl, opts?.plan);
+      if (blocked) {
+        return { status: "unknown", reason: `destination policy: ${blocked}` };
+      }
+      // GET on a webhook URL returns the webhook without posting anything; 401/404 means it was deleted.
+      const res = await get(channel.webhookUrl);
+      if (res.ok) {
+        return { status: "ok" };
+      }
+      if (res.status === 401 || res.status === 403 || res.status === 404) {
+        return { status: "invalid", reason: `Discord webhook was deleted or its token is invalid (HTTP ${res.status})` };
+      }
+      return { status: "unknown", reason: `Discord returned HTTP ${res.status}` };
+    }
+
+    if (channel.type === "telegram") {
+      const base = `https://api.telegram.org/bot${channel.botToken}`;
+      const me = await get(`${base}/getMe`);
+      if (me.status === 401 || me.status === 404) {
+        return { status: "invalid", reason: `Telegram bot token was revoked: ${await telegramDescription(me)}` };
+      }
+      if (!me.ok) {
+        return { status: "unknown", reason: `Telegram getMe returned HTTP ${me.status}` };
+      }
+      // getChat fails once the bot was removed from the chat or the chat no longer exists.
+      const chat = await get(`${base}/getChat?chat_id=${encodeURIComponent(channel.chatId)}`);
+      if (chat.status === 400 || chat.status === 403) {
+        return { status: "invalid", reason: `Telegram chat is not reachable: ${await telegramDescription(chat)}` };
+      }
+      return chat.ok ? { status: "ok" } : { status: "unknown", reason: `Telegram getChat returned HTTP ${chat.status}` };
+    }
+
+    if (channel.type === "home_assistant") {
+      const blocked = await checkDestination(channel.baseUrl, opts?.plan);
+      if (blocked) {
+        return { status: "unknown", reason: `destination policy: ${blocked}` };
+      }
+      const res = await get(`${channel.baseUrl.replace(/\/+$/, "")}/api/`, { Authorization: `Bearer ${channel.token}` });
+      if (res.ok) {
+        return { status: "ok" };
+      }
+      if (res.status === 401 || res.status === 403) {
+        return { status: "invalid", reason: `Home Assistant rejected the access token (HTTP ${res.status})` };
+      }
+      return { status: "unknown", reason: `Home Assistant returned HTTP ${res.status}` };
+    }
+  } catch (err) {
+    return { status: "unknown", reason: err instanceof Error ? err.message : String(err) };
+  }
+  return null;
+}
+
+// Validates the channel credentials of a batch of enabled jobs not checked within the interval. Each job is
+// claimed by moving credential_checked_at first, so parallel workers never check the same job twice. A failed
+// check flags the job (credential_error) until a later check passes or the channel is edited.
+export async function runCredentialChecks(limit: number, now = clock().now()) {
+  const hours = credentialCheckHours();
+  if (!hours) {
+    return 0;
+  }
+  const cutoff = new Date(now.getTime() - hours * HOUR_MS);
+  const jobs = await prisma.job.findMany({
+    where: {
+      enabled: true,
+      channelType: { in: CHECKED_CHANNEL_TYPES },
+      OR: [{ credentialCheckedAt: null }, { credentialCheckedAt: { lt: cutoff } }],
+    },
+    include: { user: { select: { plan: true } } },
+    orderBy: { credentialCheckedAt: { sort: "asc", nulls: "first" } },
+    take: limit,
+  });
+
+  let checked = 0;
+  for (const job of jobs) {
+    const claimed = await prisma.job.updateMany({
+      where: { id: job.id, credentialCheckedAt: job.credentialCheckedAt },
+      data: { credentialCheckedAt: now },
+    });
+    if (!claimed.count) {
+      continue;
+    }
+    checked++;
+    const log = logger.with({ job_id: job.id, job_name: job.name, channel_type: job.channelType });
+    let result: CredentialCheck | null;
+    try {
+      result = await checkChannelCredentials(await runnableJobChannel(job), { userAgent: job.userAgent, plan: job.user.plan });
+    } catch (err) {
+      result = { status: "unknown", reason: err instanceof Error ? err.message : String(err) };
+    }
+    if (!result || result.status === "unknown") {
+      if (result) {
+        log.info("channel credential check inconclusive", { reason: result.reason });
+      }
+      continue;
+    }
+    const credentialError = result.status === "invalid" ? result.reason : null;
+    if (credentialError === job.credentialError) {
+      continue;
+    }
+    await prisma.job.update({ where: { id: job.id }, data: { credentialError } });
+    if (credentialError) {
+      log.warn("channel credentials invalid", { reason: credentialError });
+      await recordAudit({
+        userId: job.userId,
+        action: "job.credentials_invalid",
+        entityType: "job",
+        entityId: job.id,
+        data: { channelType: job.channelType, reason: credentialError },
+      });
+    } else {
+      log.info("channel credentials valid again");
+    }
+  }
+  return checked;
+}
@@ -0,0 +1,8 @@
+import type { ChannelPlugin } from "@/lib/channel-plugins";
+
+// Deployment-specific channel plugins, selectable as the "plugin" channel type by name. Keep plugin code in its
+// own modules and list it here, e.g.:
+//
+//   import { pagerDutyChannel } from "@/lib/acme-pagerduty";
+//   export const deploymentChannelPlugins: ChannelPlugin[] = [pagerDutyChannel];
+export const deploymentChannelPlugins: ChannelPlugin[] = [];
@@ -0,0 +1,93 @@
+import { chmodSync, mkdtempSync, readFileSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+import {
+  channelPlugin,
+  channelPluginNames,
+  clearChannelPlugins,
+  pluginConfigError,
+  pluginSettingsError,
+  registerChannelPlugin,
+  type ChannelPlugin,
+} from "./channel-plugins";
+import { sendChannelMessage } from "./channel";
+
+afterEach(() => {
+  clearChannelPlugins();
+  vi.unstubAllEnvs();
+});
+
+describe("channel plugins", () => {
+  it("validates settings through the registered plugin on save only", async () => {
+    const validate = vi.fn((settings: Record<string, unknown>) => (typeof settings.routingKey === "string" ? null : "routingKey is required"));
+    registerChannelPlugin({ name: "pager", validate, send: async () => undefined });
+    expect(channelPluginNames()).toContain("pager");
+    await expect(pluginSettingsError("pager", '{"routingKey": "abc"}')).resolves.toBeNull();
+    await expect(pluginSettingsError("pager", "{}")).resolves.toBe("routingKey is required");
+    await expect(pluginSettingsError("pager", "[1]")).resolves.toBe("Settings must be a JSON object");
+    await expect(pluginSettingsError("missing", "{}")).resolves.toBe('Channel plugin "missing" is not installed on this deployment');
+
+    // The schema check (also run by the worker) never calls the plugin.
+    validate.mockClear();
+    expect(pluginConfigError("pager", "{}")).toBeNull();
+    expect(pluginConfigError("missing", "{}")).toBe('Channel plugin "missing" is not installed on this deployment');
+    expect(validate).not.toHaveBeenCalled();
+  });
+
+  it("sends through the plugin and reports its failures as channel errors", async () => {
+    const send = vi.fn<ChannelPlugin["send"]>(async () => undefined);
+    registerChannelPlugin({ name: "pager", send });
+    await sendChannelMessage({ type: "plugin", plugin: "pager", settings: '{"routingKey": "abc"}' }, "[Daily]", "out", {
+      citations: [{ url: "https://example.com" }],
+    });
+    expect(send).toHaveBeenCalledWith(
+      { routingKey: "abc" },
+      expect.objectContaining({ title: "[Daily]", body: "out", text: "[Daily]\n\nout\n\nSources:\n- https://example.com", usedWebSearch: false }),
+    );
+
+    registerChannelPlugin({
+      name: "broken",
+      send: async () => {
+        throw new Error("queue full");
+      },
+    });
+    await expect(sendChannelMessage({ type: "plugin", plugin: "broken", settings: "{}" }, "t", "out")).rejects.toMatchObject({
+      status: 502,
+      message: 'Channel plugin "broken" failed: queue full',
+    });
+    await expect(sendChannelMessage({ type: "plugin", plugin: "gone", settings: "{}" }, "t", "out")).rejects.toMatchObject({ status: 400 });
+  });
+
+  it("runs executables from CHANNEL_EXEC_PLUGINS with JSON on stdin", async () => {
+    const dir = mkdtempSync(join(tmpdir(), "promptloop-plugin-"));
+    const script = join(dir, "plugin.sh");
+    writeFileSync(
+      script,
+      [
+        "#!/bin/sh",
+        "input=$(cat)",
+        'case "$input" in',
+        '  *\'"action":"validate"\'*\'"routingKey"\'*) exit 0 ;;',
+        '  *\'"action":"validate"\'*) echo "routingKey is required" >&2; exit 1 ;;',
+        "esac",
+        `printf '%s' "$input" > ${join(dir, "sent.json")}`,
+      ].join("\n"),
+    );
+    chmodSync(script, 0o755);
+    vi.stubEnv("CHANNEL_EXEC_PLUGINS", JSON.stringify({ tickets: script, "Bad Name": script }));
+
+    expect(channelPluginNames()).toEqual(["tickets"]);
+    await expect(pluginSettingsError("tickets", '{"routingKey": "abc"}')).resolves.toBeNull();
+    await expect(pluginSettingsError("tickets", "{}")).resolves.toBe("routingKey is required");
+
+    await channelPlugin("tickets")?.send({ routingKey: "abc" }, { title: "t", body: "out", text: "t\n\nout", citations: [], attachments: [], usedWebSearch: false });
+    expect(JSON.parse(readFileSync(join(dir, "sent.json"), "utf8"))).toMatchObject({
+      action: "send",
+      plugin: "tickets",
+      settings: { routingKey: "abc" },
+      message: { body: "out" },
+    });
+  });
+});
@@ -0,0 +1,155 @@
+import { spawn } from "node:child_process";
+import { deploymentChannelPlugins } from "@/lib/channel-plugin-registry";
+import { isRecord } from "@/lib/type-guards";
+import type { ChannelAttachment, ChannelCitation } from "@/lib/channel";
+
+// Delivery targets that live outside this codebase. A "plugin" channel stores a plugin name and its settings
+// (a JSON object); the plugin checks the settings when a job or saved channel is saved and sends every message. In-process plugins are listed in channel-plugin-registry.ts or registered at
+// startup; CHANNEL_EXEC_PLUGINS maps further names to executables, e.g. {"pagerduty": "/opt/promptloop/pagerduty"}.
+//
+// An executable gets one JSON document on stdin per call, {"action": "validate" | "send", "plugin", "settings",
+// "message"}, and reports success with exit code 0. On failure the first line of stderr is the error; for
+// validate it is shown to the user as the settings problem.
+
+export const CHANNEL_PLUGIN_NAME_RE = /^[a-z0-9][a-z0-9_-]{0,63}$/;
+
+export type ChannelPluginMessage = {
+  // The job's run title ("" when the header is off), the output, and the text chat channels send (title, output,
+  // sources and attachment links).
+  title: string;
+  body: string;
+  text: string;
+  citations: ChannelCitation[];
+  attachments: Array<Omit<ChannelAttachment, "content">>;
+  usedWebSearch: boolean;
+  meta?: Record<string, unknown>;
+};
+
+export interface ChannelPlugin {
+  name: string;
+  // Returns what is wrong with the settings, or null when they are usable. Called when a channel is saved, not
+  // before runs.
+  validate?(settings: Record<string, unknown>): string | null | Promise<string | null>;
+  // Throws on failure; a ChannelRequestError keeps its status, anything else is reported as a 502.
+  send(settings: Record<string, unknown>, message: ChannelPluginMessage): Promise<void>;
+}
+
+const registered = new Map<string, ChannelPlugin>();
+
+// Registers a plugin in addition to the deployment registry (useful for tests and plugins loaded at startup).
+export function registerChannelPlugin(plugin: ChannelPlugin) {
+  registered.set(plugin.name, plugin);
+}
+
+export function clearChannelPlugins() {
+  registered.clear();
+}
+
+function execPluginCommands(): Record<string, string> {
+  try {
+    const parsed = JSON.parse(process.env.CHANNEL_EXEC_PLUGINS?.trim() || "{}") as unknown;
+    if (!isRecord(parsed)) {
+      return {};
+    }
+    return Object.fromEntries(
+      Object.entries(parsed).filter(
+        (entry): entry is [string, string] => CHANNEL_PLUGIN_NAME_RE.test(entry[0]) && typeof entry[1] === "string" && entry[1].trim() !== "",
+      ),
+    );
+  } catch {
+    return {};
+  }
+}
+
+function execTimeoutMs() {
+  const value = Number(process.env.CHANNEL_EXEC_PLUGIN_TIMEOUT_MS ?? 30_000);
+  return Number.isFinite(value) && value >= 1000 ? Math.floor(value) : 30_000;
+}
+
+function stderrReason(stderr: string, code: number | null) {
+  return stderr.trim().split("\n")[0]?.trim() || (code == null ? "was killed (timeout)" : `exited with code ${code}`);
+}
+
+// Rejects when the executable cannot be started; otherwise resolves with its exit code (null when killed).
+function runExecPlugin(command: string, input: unknown, timeoutMs: number) {
+  return new Promise<{ code: number | null; stderr: string }>((resolve, reject) => {
+    const child = spawn(command, [], { stdio: ["pipe", "ignore", "pipe"], timeout: timeoutMs, killSignal: "SIGKILL" });
+    let stderr = "";
+    child.stderr.setEncoding("utf8");
+    child.stderr.on("data", (chunk: string) => {
+      stderr = (stderr + chunk).slice(0, 4000);
+    });
+    child.on("error", reject);
+    child.on("close", (code) => resolve({ code, stderr }));
+    child.stdin.on("error", () => undefined);
+    child.stdin.end(JSON.stringify(input));
+  });
+}
+
+// Runs `command` once per call; no shell is involved, so the command is a path to an executable.
+export function execChannelPlugin(name: string, command: string): ChannelPlugin {
+  return {
+    name,
+    async validate(settings) {
+      try {
+        const res = await runExecPlugin(command, { action: "validate", plugin: name, settings }, Math.min(execTimeoutMs(), 10_000));
+        return res.code === 0 ? null : stderrReason(res.stderr, res.code);
+      } catch (err) {
+        return `Channel plugin "${name}" could not be started: ${err instanceof Error ? err.message : String(err)}`;
+      }
+    },
+    async send(settings, message) {
+      const res = await runExecPlugin(command, { action: "send", plugin: name, settings, message }, execTimeoutMs());
+      if (res.code !== 0) {
+        throw new Error(stderrReason(res.stderr, res.code));
+      }
+    },
+  };
+}
+
+export function channelPlugin(name: string): ChannelPlugin | null {
+  const inProcess = registered.get(name) ?? deploymentChannelPlugins.find((plugin) => plugin.name === name);
+  if (inProcess) {
+    return inProcess;
+  }
+  const command = execPluginCommands()[name];
+  return command ? execChannelPlugin(name, command) : null;
+}
+
+export function channelPluginNames() {
+  const names = [...deploymentChannelPlugins.map((plugin) => plugin.name), ...registered.keys(), ...Object.keys(execPluginCommands())];
+  return Array.from(new Set(names)).sort();
+}
+
+export function parsePluginSettings(settings: string): Record<string, unknown> | null {
+  try {
+    const parsed = JSON.parse(settings.trim() || "{}") as unknown;
+    return isRecord(parsed) ? parsed : null;
+  } catch {
+    return null;
+  }
+}
+
+// Problem with a plugin channel's config that can be seen without asking the plugin (settings that are not a JSON
+// object, a plugin that is not installed), or null. The channel schema uses it, so it runs on every parse,
+// including when the worker picks up a job, and must stay cheap and synchronous.
+export function pluginConfigError(plugin: string, settings: string) {
+  const parsed = parsePluginSettings(settings);
+  if (!parsed) {
+    return "Settings must be a JSON object";
+  }
+  if (!channelPlugin(plugin)) {
+    return `Channel plugin "${plugin}" is not installed on this deployment`;
+  }
+  return null;
+}
+
+// pluginConfigError plus the plugin's own validate(); awaited by the save paths (saved-channels.ts), never at
+// run time, so a slow executable delays only the request that saves it.
+export async function pluginSettingsError(plugin: string, settings: string) {
+  const error = pluginConfigError(plugin, settings);
+  if (error) {
+    return error;
+  }
+  return (await channelPlugin(plugin)?.validate?.(parsePluginSettings(settings) ?? {})) ?? null;
+}
@@ -1,6 +1,9 @@
+import { mkdtempSync, readFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
 import { afterEach, describe, expect, it, vi } from "vitest";
 
-import { __private__, sendChannelMessage } from "./channel";
+import { __private__, ChannelRequestError, parseRetryAfterMs, sendChannelMessage, webhookSignature } from "./channel";
 
 function mockOkFetch() {
   return vi.fn(async (_input: RequestInfo | URL, _init?: RequestInit) => {
@@ -15,25 +18,61 @@ afterEach(() => {
 });
 
 describe("channel chunking", () => {
-  it("chunkPlainText prefers splitting on newlines", () => {
+  it("chunkFencedText prefers splitting on newlines", () => {
     const max = 8;
-    const chunks = __private__.chunkPlainText("12345\n67890\nabc", max);
+    const chunks = __private__.chunkFencedText("12345\n67890\nabc", max);
     expect(chunks).toEqual(["12345", "67890", "abc"]);
     for (const c of chunks) {
       expect(c.length).toBeLessThanOrEqual(max);
     }
   });
 
-  it("chunkDiscordContent produces fence-balanced chunks", () => {
+  it("chunkFencedText produces fence-balanced chunks", () => {
     const max = 60;
     const text = `Intro\n\n\`\`\`ts\n${"x".repeat(200)}\n\`\`\`\nTail`;
-    const chunks = __private__.chunkDiscordContent(text, max);
+    const chunks = __private__.chunkFencedText(text, max);
     expect(chunks.length).toBeGreaterThan(1);
     for (const c of chunks) {
       expect(c.length).toBeLessThanOrEqual(max);
       expect(__private__.updateCodeFenceState(null, c)).toBeNull();
     }
   });
+
+  it("splits on paragraph, sentence, and word boundaries before cutting", () => {
+    expect(__private__.chunkFencedText("First paragraph here.\n\nSecond one.", 30)).toEqual(["First paragraph here.", "Second one."]);
+    expect(__private__.chunkFencedText("One sentence here. Another sentence follows", 30)).toEqual([
+      "One sentence here.",
+      "Another sentence follows",
+    ]);
+    expect(__private__.chunkFencedText("alpha beta gamma delta epsilon", 20)).toEqual(["alpha beta gamma", "delta epsilon"]);
+    expect(__private__.findSplitIndex("xxxxxxxxxxxxhttps://example.com/a/very/long/path", 30)).toBe(12);
+  });
+
+  it("numbers parts when CHANNEL_CHUNK_NUMBERING is on", () => {
+    vi.stubEnv("CHANNEL_CHUNK_NUMBERING", "true");
+    const chunks = __private__.buildDiscordChunks(Array.from({ length: 400 }, (_, i) => `word${i}`).join(" "));
+    expect(chunks.length).toBeGreaterThan(1);
+    chunks.forEach((chunk, index) => {
+      expect(chunk.endsWith(` (${index + 1}/${chunks.length})`)).toBe(true);
+      expect(chunk.length).toBeLessThanOrEqual(1900);
+    });
+    vi.unstubAllEnvs();
+  });
+
+  it("cuts long chat messages to the first part plus the full output link", () => {
+    const url = "https://app.example.com/runs/r1/output?token=t";
+    const long = `Intro\n\n\`\`\`ts\n${"x\n".repeat(1500)}\`\`\``;
+    const [discord, ...rest] = __private__.buildDiscordChunks(long, url);
+    expect(rest).toEqual([]);
+    expect(discord.length).toBeLessThanOrEqual(1900);
+    expect(discord.endsWith(`[Full output: ${url}]`)).toBe(true);
+    expect(__private__.updateCodeFenceState(null, discord)).toBeNull();
+
+    const telegram = __private__.buildTelegramChunks(long, url);
+    expect(telegram).toHaveLength(1);
+    expect(telegram[0].length).toBeLessThanOrEqual(4000);
+    expect(__private__.buildDiscordChunks("short", url)).toEqual(["short"]);
+  });
 });
 
 describe("sendChannelMessage", () => {
@@ -43,7 +82,7 @@ describe("sendChannelMessage", () => {
 
     const title = "[t]";
     const body = "a".repeat(4000);
-    const expectedChunks = __private__.chunkDiscordContent(`${title}\n\n${body}`, 1900);
+    const expectedChunks = __private__.chunkFencedText(`${title}\n\n${body}`, 1900);
 
     await sendChannelMessage({ type: "discord", webhookUrl: "https://discord.com/api/webhooks/1/x" }, title, body);
 
@@ -56,12 +95,60 @@ describe("sendChannelMessage", () => {
     }
   });
 
+  it("starts a Discord forum post per run and sends later parts into it", async () => {
+    const fetchMock = vi.fn(async () => new Response(JSON.stringify({ id: "m1", channel_id: "555" }), { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+
+    const webhookUrl = "https://discord.com/api/webhooks/1/x";
+    await sendChannelMessage({ type: "discord", webhookUrl, threadId: "", threadName: "Digest {{jobName}}" }, "[t]", "e".repeat(3000), {
+      meta: { jobName: "Prices" },
+    });
+    const calls = fetchMock.mock.calls as unknown as Array<[string, RequestInit]>;
+    expect(calls.length).toBe(2);
+    expect(calls[0][0]).toBe(`${webhookUrl}?wait=true`);
+    expect(JSON.parse(String(calls[0][1].body)).thread_name).toBe("Digest Prices");
+    expect(calls[1][0]).toBe(`${webhookUrl}?thread_id=555`);
+    expect(JSON.parse(String(calls[1][1].body))).not.toHaveProperty("thread_name");
+
+    fetchMock.mockClear();
+    await sendChannelMessage({ type: "discord", webhookUrl, threadId: "777", threadName: "" }, "[t]", "short");
+    expect((fetchMock.mock.calls as unknown as Array<[string]>)[0][0]).toBe(`${webhookUrl}?thread_id=777`);
+  });
+
+  it("posts Telegram messages into a forum topic", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage({ type: "telegram", botToken: "t", chatId: "-100", messageThreadId: "42" }, "[t]", "hello");
+    const req = fetchMock.mock.calls[0][1] as RequestInit;
+    expect(JSON.parse(String(req.body))).toMatchObject({ chat_id: "-100", message_thread_id: 42 });
+  });
+
+  it("does not follow a webhook redirect to a private address", async () => {
+    const fetchMock = vi.fn(async (_input: RequestInfo | URL, _init?: RequestInit) => {
+      void _input;
+      void _init;
+      return new Response(null, { status: 307, headers: { location: "http://169.254.169.254/latest/meta-data/?token=x" } });
+    });
+    vi.stubGlobal("fetch", fetchMock);
+
+    const send = sendChannelMessage(
+      { type: "webhook", url: "https://hooks.example.com/in", method: "POST", headers: "{}", payload: "" },
+      "t",
+      "hello",
+    );
+    await expect(send).rejects.toThrow("Delivery redirected to http://169.254.169.254; redirects are not followed");
+    await expect(send).rejects.toBeInstanceOf(ChannelRequestError);
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+    expect(fetchMock.mock.calls[0][1]).toMatchObject({ redirect: "manual" });
+  });
+
   it("splits Discord webhook payloads even via generic webhook channel", async () => {
     const fetchMock = mockOkFetch();
     vi.stubGlobal("fetch", fetchMock);
 
     const long = "b".repeat(4000);
-    const expectedChunks = __private__.chunkDiscordContent(long, 1900);
+    const expectedChunks = __private__.chunkFencedText(long, 1900);
 
     await sendChannelMessage(
       {
@@ -84,6 +171,30 @@ describe("sendChannelMessage", () => {
     }
   });
 
+  it("sends long outputs as a file when CHANNEL_FILE_FALLBACK_CHARS is set", async () => {
+    vi.stubEnv("CHANNEL_FILE_FALLBACK_CHARS", "1000");
+    try {
+      const fetchMock = mockOkFetch();
+      vi.stubGlobal("fetch", fetchMock);
+
+      const body = `Short intro.\n\n${"d".repeat(5000)}`;
+      await sendChannelMessage({ type: "discord", webhookUrl: "https://discord.com/api/webhooks/1/x" }, "[t]", body);
+      expect(fetchMock).toHaveBeenCalledTimes(1);
+      const discordForm = fetchMock.mock.calls[0][1]?.body as FormData;
+      expect(JSON.parse(discordForm.get("payload_json") as string).content).toContain("Short intro.");
+      expect((discordForm.get("files[0]") as File).name).toBe("output.md");
+
+      fetchMock.mockClear();
+      await sendChannelMessage({ type: "telegram", botToken: "t", chatId: "1" }, "[t]", body);
+      expect(fetchMock).toHaveBeenCalledTimes(1);
+      expect(String(fetchMock.mock.calls[0][0])).toContain("/sendDocument");
+      const telegramForm = fetchMock.mock.calls[0][1]?.body as FormData;
+      expect(telegramForm.get("caption")).toContain("[Full output attached as output.md (5,014 characters).]");
+    } finally {
+      vi.unstubAllEnvs();
+    }
+  });
+
   it("caps Discord parts to avoid runaway sends", async () => {
     const prev = process.env.CHANNEL_DISCORD_MAX_PARTS;
     process.env.CHANNEL_DISCORD_MAX_PARTS = "3";
@@ -144,4 +255,780 @@ describe("sendChannelMessage", () => {
       else process.env.CHANNEL_DISCORD_429_MAX_RETRIES = prev;
     }
   });
+
+  it("parses Retry-After as seconds or an HTTP date", () => {
+    const now = new Date("2026-04-01T00:00:00Z");
+    expect(parseRetryAfterMs("30", now)).toBe(30_000);
+    expect(parseRetryAfterMs("1.5", now)).toBe(1500);
+    expect(parseRetryAfterMs("Wed, 01 Apr 2026 00:00:45 GMT", now)).toBe(45_000);
+    expect(parseRetryAfterMs("-1", now)).toBeNull();
+    expect(parseRetryAfterMs("soon", now)).toBeNull();
+  });
+
+  it("reports Telegram's retry_after on 429", async () => {
+    vi.stubGlobal(
+      "fetch",
+      vi.fn(async () => Response.json({ ok: false, error_code: 429, parameters: { retry_after: 35 } }, { status: 429 })),
+    );
+    const err = await sendChannelMessage({ type: "telegram", botToken: "t", chatId: "1" }, "t", "hello").catch((e) => e);
+    expect(err).toBeInstanceOf(ChannelRequestError);
+    expect(err.status).toBe(429);
+    expect(err.retryAfterMs).toBe(35_000);
+  });
+
+  it("gives up on a Discord 429 whose Retry-After exceeds the cap", async () => {
+    const prev = process.env.CHANNEL_RETRY_AFTER_MAX_SECONDS;
+    process.env.CHANNEL_RETRY_AFTER_MAX_SECONDS = "10";
+    try {
+      const fetchMock = vi.fn(async () => new Response(null, { status: 429, headers: { "retry-after": "30" } }));
+      vi.stubGlobal("fetch", fetchMock);
+      const err = await sendChannelMessage({ type: "discord", webhookUrl: "https://discord.com/api/webhooks/1/x" }, "t", "hi").catch((e) => e);
+      expect(fetchMock).toHaveBeenCalledTimes(1);
+      expect(err.retryAfterMs).toBe(30_000);
+    } finally {
+      if (prev == null) delete process.env.CHANNEL_RETRY_AFTER_MAX_SECONDS;
+      else process.env.CHANNEL_RETRY_AFTER_MAX_SECONDS = prev;
+    }
+  });
+
+  it("posts Home Assistant notifications to the notify service with a bearer token", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "home_assistant", baseUrl: "https://ha.example:8123/", token: "llat-secret-token", service: "notify.mobile_app_pixel" },
+      "t",
+      "hello",
+    );
+
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe("https://ha.example:8123/api/services/notify/mobile_app_pixel");
+    expect((init.headers as Record<string, string>).Authorization).toBe("Bearer llat-secret-token");
+    expect(JSON.parse(init.body as string)).toEqual({ title: "t", message: "hello" });
+  });
+
+  it("lists attachment links and sends them to Telegram as photo/document", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage({ type: "telegram", botToken: "bot-token", chatId: "42" }, "t", "hello", {
+      attachments: [
+        { name: "artifact-1.png", url: "https://app.example/api/artifacts/a1?token=x", mediaType: "image/png" },
+        { name: "artifact-2.pdf", url: "https://app.example/api/artifacts/a2?token=y", mediaType: "application/pdf" },
+      ],
+    });
+
+    const calls = fetchMock.mock.calls as Array<[string, RequestInit]>;
+    expect(calls.map(([url]) => url.split("/").pop())).toEqual(["sendMessage", "sendPhoto", "sendDocument"]);
+    expect(JSON.parse(calls[0][1].body as string).text).toContain("Attachments:\n- artifact-1.png: https://app.example/api/artifacts/a1?token=x");
+    expect(JSON.parse(calls[1][1].body as string)).toMatchObject({ chat_id: "42", photo: "https://app.example/api/artifacts/a1?token=x" });
+    expect(JSON.parse(calls[2][1].body as string)).toMatchObject({ document: "https://app.example/api/artifacts/a2?token=y" });
+  });
+
+  it("reports every rendered request body, one per Discord chunk", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+    const rendered: string[] = [];
+
+    await sendChannelMessage({ type: "discord", webhookUrl: "https://discord.com/api/webhooks/1/x" }, "[t]", "a".repeat(4000), {
+      onRendered: (body) => rendered.push(body),
+    });
+
+    const calls = fetchMock.mock.calls as Array<[string, RequestInit]>;
+    expect(rendered).toEqual(calls.map(([, init]) => init.body));
+    expect(rendered.length).toBeGreaterThan(1);
+  });
+});
+
+describe("webhook payload templates", () => {
+  it("substitutes title/body/meta placeholders inside custom payload strings", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      {
+        type: "webhook",
+        url: "https://maker.ifttt.com/trigger/e/with/key/k",
+        method: "POST",
+        headers: "{}",
+        payload: JSON.stringify({ value1: "{{title}}", value2: "{{ body }}", value3: "{{jobId}}", keep: "{{unknown}}" }),
+      },
+      "Daily \"brief\"",
+      "line1\nline2",
+      { meta: { jobId: "job-1" } },
+    );
+
+    const req = fetchMock.mock.calls[0][1] as RequestInit;
+    expect(JSON.parse(req.body as string)).toEqual({
+      value1: "Daily \"brief\"",
+      value2: "line1\nline2",
+      value3: "job-1",
+      keep: "{{unknown}}",
+    });
+  });
+});
+
+describe("elasticsearch channel", () => {
+  it("indexes the run with the run id as document id", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "elasticsearch", url: "https://es.example:9200/", index: "runs", apiKey: "abc", username: "", password: "" },
+      "t",
+      "out",
+      { meta: { runHistoryId: "run-1", jobId: "job-1" } },
+    );
+
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe("https://es.example:9200/runs/_doc/run-1");
+    expect(init.method).toBe("PUT");
+    expect((init.headers as Record<string, string>).Authorization).toBe("ApiKey abc");
+    const doc = JSON.parse(init.body as string) as Record<string, unknown>;
+    expect(doc.output).toBe("out");
+    expect(doc.jobId).toBe("job-1");
+  });
+});
+
+describe("clickhouse channel", () => {
+  it("inserts one JSONEachRow row and flattens structured output", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "clickhouse", url: "https://ch.example:8443/", table: "analytics.runs", username: "u", password: "p" },
+      "t",
+      '```json\n{"sentiment":"positive","run_id":"spoofed"}\n```',
+      { meta: { runHistoryId: "run-1", jobId: "job-1" } },
+    );
+
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    const parsed = new URL(url);
+    expect(parsed.origin).toBe("https://ch.example:8443");
+    expect(parsed.searchParams.get("query")).toBe("INSERT INTO analytics.runs FORMAT JSONEachRow");
+    expect((init.headers as Record<string, string>)["X-ClickHouse-User"]).toBe("u");
+    const row = JSON.parse(init.body as string) as Record<string, unknown>;
+    expect(row.sentiment).toBe("positive");
+    expect(row.run_id).toBe("run-1");
+    expect(row.job_id).toBe("job-1");
+  });
+
+  it("keeps plain text output in the output column only", () => {
+    const row = __private__.warehouseRow("t", "just text", false);
+    expect(row.output).toBe("just text");
+    expect(__private__.parseStructuredOutput("[1,2]")).toBeNull();
+  });
+
+  it("carries job tags into warehouse rows and webhook template variables", () => {
+    const meta = { jobId: "job-1", tags: ["team:data", "prod"] };
+    expect(__private__.warehouseRow("t", "x", false, meta).tags).toEqual(["team:data", "prod"]);
+    expect(__private__.warehouseRow("t", "x", false).tags).toEqual([]);
+    expect(__private__.payloadTemplateVars("t", "b", "c", meta).tags).toBe("team:data,prod");
+  });
+});
+
+describe("redis channel", () => {
+  it("sends SET with EX when a TTL is configured", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "redis", restUrl: "https://db.upstash.io/", token: "tok", mode: "set", key: "summary:market", ttlSeconds: "3600" },
+      "t",
+      "latest",
+    );
+
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe("https://db.upstash.io");
+    expect((init.headers as Record<string, string>).Authorization).toBe("Bearer tok");
+    expect(JSON.parse(init.body as string)).toEqual(["SET", "summary:market", "latest", "EX", "3600"]);
+  });
+
+  it("builds XADD with run fields", () => {
+    const cmd = __private__.redisCommand({ mode: "xadd", key: "runs", ttlSeconds: "" }, "t", "out", { runHistoryId: "run-1" });
+    expect(cmd).toEqual(["XADD", "runs", "*", "title", "t", "output", "out", "runHistoryId", "run-1"]);
+  });
+});
+
+describe("pushover channel", () => {
+  const channel = { type: "pushover" as const, appToken: "a".repeat(30), userKey: "u".repeat(30), priority: "1" as const, sound: "cosmic" };
+
+  it("splits long outputs at the message limit and sends the header as the title", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+    const rendered: string[] = [];
+
+    await sendChannelMessage(channel, "[Daily] 2026-01-01", "word ".repeat(500), { onRendered: (body) => rendered.push(body) });
+
+    expect(fetchMock).toHaveBeenCalledTimes(3);
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe("https://api.pushover.net/1/messages.json");
+    const form = init.body as URLSearchParams;
+    expect(form.get("token")).toBe("a".repeat(30));
+    expect(form.get("user")).toBe("u".repeat(30));
+    expect(form.get("title")).toBe("[Daily] 2026-01-01");
+    expect(form.get("priority")).toBe("1");
+    expect(form.get("sound")).toBe("cosmic");
+    for (const [, call] of fetchMock.mock.calls as Array<[string, RequestInit]>) {
+      expect((call.body as URLSearchParams).get("message")!.length).toBeLessThanOrEqual(1024);
+    }
+    // Recorded bodies never include the credentials.
+    expect(rendered).toHaveLength(3);
+    expect(rendered.join("")).not.toContain("a".repeat(30));
+  });
+
+  it("omits optional fields that are not set", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage({ ...channel, priority: "", sound: "" }, "", "short");
+
+    const form = (fetchMock.mock.calls[0] as [string, RequestInit])[1].body as URLSearchParams;
+    expect([...form.keys()]).toEqual(["token", "user", "message"]);
+  });
+});
+
+describe("twilio channel", () => {
+  const channel = {
+    type: "twilio" as const,
+    accountSid: `AC${"0".repeat(32)}`,
+    authToken: "t".repeat(32),
+    from: "+15551234567",
+    to: "+15557654321",
+    mode: "summary" as const,
+  };
+
+  it("sends a short summary of long outputs", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(channel, "[Daily]", `${"word ".repeat(200)}\n\nMore`, { fullOutputUrl: "https://app.test/runs/1/output" });
+
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe(`https://api.twilio.com/2010-04-01/Accounts/AC${"0".repeat(32)}/Messages.json`);
+    expect((init.headers as Record<string, string>).Authorization).toBe(
+      `Basic ${Buffer.from(`AC${"0".repeat(32)}:${"t".repeat(32)}`).toString("base64")}`,
+    );
+    const form = init.body as URLSearchParams;
+    expect(form.get("From")).toBe("+15551234567");
+    expect(form.get("To")).toBe("+15557654321");
+    const message = form.get("Body")!;
+    expect(message.length).toBeLessThanOrEqual(320);
+    expect(message.startsWith("[Daily]\nword")).toBe(true);
+    expect(message.endsWith("...\nhttps://app.test/runs/1/output")).toBe(true);
+  });
+
+  it("splits full outputs and uses a Messaging Service SID", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage({ ...channel, mode: "full", from: `MG${"1".repeat(32)}` }, "", "word ".repeat(500));
+
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+    const form = (fetchMock.mock.calls[0] as [string, RequestInit])[1].body as URLSearchParams;
+    expect(form.get("MessagingServiceSid")).toBe(`MG${"1".repeat(32)}`);
+    expect(form.has("From")).toBe(false);
+  });
+});
+
+describe("google chat channel", () => {
+  const webhookUrl = "https://chat.googleapis.com/v1/spaces/AAA/messages?key=k&token=t";
+
+  it("posts cards in the run's thread with the header on the first part", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage({ type: "google_chat", webhookUrl, format: "card", threadKey: "" }, "[Daily]", "a < b\n" + "word ".repeat(1000), {
+      meta: { runHistoryId: "run-1" },
+    });
+
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(new URL(url).searchParams.get("messageReplyOption")).toBe("REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD");
+    expect(new URL(url).searchParams.get("token")).toBe("t");
+    const first = JSON.parse(init.body as string);
+    expect(first.thread).toEqual({ threadKey: "run-1" });
+    expect(first.cardsV2[0].card.header).toEqual({ title: "[Daily]" });
+    expect(first.cardsV2[0].card.sections[0].widgets[0].textParagraph.text.startsWith("a &lt; b<br>word")).toBe(true);
+    const second = JSON.parse((fetchMock.mock.calls[1] as [string, RequestInit])[1].body as string);
+    expect(second.cardsV2[0].card.header).toBeUndefined();
+  });
+
+  it("sends plain text to a fixed thread", () => {
+    expect(__private__.googleChatMessage("text", "[Daily]", "hello", 0, "digest")).toEqual({
+      text: "*[Daily]*\n\nhello",
+      thread: { threadKey: "digest" },
+    });
+  });
+});
+
+describe("aws channel", () => {
+  it("publishes a signed JSON message to an SNS FIFO topic", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "aws", service: "sns", target: "arn:aws:sns:eu-west-1:123456789012:runs.fifo", accessKeyId: "AKID", secretAccessKey: "secret" },
+      "t",
+      "out",
+      { meta: { jobId: "job-1", runHistoryId: "run-1" } },
+    );
+
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe("https://sns.eu-west-1.amazonaws.com/");
+    expect((init.headers as Record<string, string>).Authorization).toMatch(/^AWS4-HMAC-SHA256 Credential=AKID\/\d{8}\/eu-west-1\/sns\/aws4_request/);
+    const form = new URLSearchParams(new TextDecoder().decode(init.body as Uint8Array));
+    expect(form.get("Action")).toBe("Publish");
+    expect(form.get("MessageGroupId")).toBe("job-1");
+    expect(form.get("MessageDeduplicationId")).toBe("run-1");
+    expect(JSON.parse(form.get("Message")!)).toMatchObject({ title: "t", body: "out" });
+  });
+
+  it("sends to the SQS queue URL in its region", () => {
+    const target = "https://sqs.us-west-2.amazonaws.com/123456789012/runs";
+    expect(__private__.awsEndpoint({ service: "sqs", target })).toEqual({ region: "us-west-2", url: target });
+    expect(__private__.awsMessageParams({ service: "sqs", target }, "m")).toEqual({
+      Action: "SendMessage",
+      Version: "2012-11-05",
+      MessageBody: "m",
+    });
+  });
+
+  it("refuses blank keys unless web identity is enabled", async () => {
+    vi.stubGlobal("fetch", mockOkFetch());
+    await expect(
+      sendChannelMessage({ type: "aws", service: "sqs", target: "https://sqs.us-west-2.amazonaws.com/123456789012/runs", accessKeyId: "", secretAccessKey: "" }, "t", "out"),
+    ).rejects.toMatchObject({ status: 401 });
+  });
+});
+
+describe("kafka channel", () => {
+  it("produces the run envelope through the v3 REST API, keyed by job", async () => {
+    const fetchMock = vi.fn(async (_url: string, _init: RequestInit) => new Response(JSON.stringify({ error_code: 200, offset: 7 }), { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "kafka", restUrl: "https://kafka.test/", api: "v3", clusterId: "lkc-1", topic: "llm-runs", username: "key", password: "secret" },
+      "[Daily]",
+      "out",
+      { meta: { jobId: "job-1", jobName: "Daily", runHistoryId: "run-1", scheduledFor: "2026-01-01T09:00:00.000Z" } },
+    );
+
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe("https://kafka.test/kafka/v3/clusters/lkc-1/topics/llm-runs/records");
+    expect((init.headers as Record<string, string>).Authorization).toBe(`Basic ${Buffer.from("key:secret").toString("base64")}`);
+    expect(JSON.parse(init.body as string)).toEqual({
+      key: { type: "STRING", data: "job-1" },
+      value: {
+        type: "JSON",
+        data: { job_id: "job-1", run_id: "run-1", name: "Daily", run_at: "2026-01-01T09:00:00.000Z", output: "out" },
+      },
+    });
+  });
+
+  it("builds v2 requests and reports per-record errors", () => {
+    const v2 = __private__.kafkaProduceRequest({ restUrl: "https://proxy.test", api: "v2", clusterId: "", topic: "runs" }, "job-1", { output: "x" });
+    expect(v2).toEqual({
+      url: "https://proxy.test/topics/runs",
+      contentType: "application/vnd.kafka.json.v2+json",
+      body: { records: [{ key: "job-1", value: { output: "x" } }] },
+    });
+    expect(__private__.kafkaRecordError({ offsets: [{ partition: 0, offset: 1, error: null }] })).toBeNull();
+    expect(__private__.kafkaRecordError({ offsets: [{ error_code: 40403, error: "Topic not found" }] })).toBe("Topic not found");
+    expect(__private__.kafkaRecordError({ error_code: 40301, message: "Not authorized" })).toBe("Not authorized");
+  });
+});
+
+describe("file channel", () => {
+  it("appends timestamped entries inside CHANNEL_FILE_DIR", async () => {
+    const dir = mkdtempSync(join(tmpdir(), "promptloop-file-"));
+    vi.stubEnv("CHANNEL_FILE_DIR", dir);
+    const channel = { type: "file" as const, target: "file" as const, path: "daily/out.md" };
+
+    await sendChannelMessage(channel, "[Daily]", "first", { meta: { jobName: "Daily" } });
+    await sendChannelMessage(channel, "", "second");
+
+    const content = readFileSync(join(dir, "daily/out.md"), "utf8");
+    expect(content).toMatch(/^===== \d{4}-\d{2}-\d{2}T[\d:.]+Z · Daily =====\n\[Daily\]\n\nfirst\n\n===== [\d:.TZ-]+ =====\nsecond\n\n$/);
+    vi.unstubAllEnvs();
+  });
+
+  it("is disabled without CHANNEL_FILE_DIR and keeps paths inside it", async () => {
+    vi.stubEnv("CHANNEL_FILE_DIR", "");
+    await expect(sendChannelMessage({ type: "file", target: "stdout", path: "" }, "t", "out")).rejects.toMatchObject({ status: 403 });
+    expect(__private__.fileChannelPath("/srv/out", "a/b.md")).toBe("/srv/out/a/b.md");
+    expect(__private__.fileChannelPath("/srv/out", "../etc/passwd")).toBeNull();
+    expect(__private__.fileChannelPath("/srv/out", "/etc/passwd")).toBeNull();
+    vi.unstubAllEnvs();
+  });
+});
+
+describe("notion channel", () => {
+  const pageId = "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d";
+
+  it("appends a heading and the output blocks to a page", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "notion", token: "ntn_x", target: "page", parentId: `https://www.notion.so/Research-${pageId.replace(/-/g, "")}`, titleProperty: "" },
+      "[Research] 2026-05-05",
+      "- finding",
+    );
+
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe(`https://api.notion.com/v1/blocks/${pageId}/children`);
+    expect(init?.method).toBe("PATCH");
+    expect((init?.headers as Record<string, string>)["Notion-Version"]).toBe("2022-06-28");
+    const { children } = JSON.parse(init?.body as string) as { children: Array<{ type: string }> };
+    expect(children.map((block) => block.type)).toEqual(["heading_2", "bulleted_list_item"]);
+  });
+
+  it("creates one database row per run and appends blocks past 100", async () => {
+    const fetchMock = vi.fn(async (_url: string, _init: RequestInit) => new Response(JSON.stringify({ id: "row-1" }), { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+
+    const body = Array.from({ length: 150 }, (_, i) => `- item ${i}`).join("\n");
+    await sendChannelMessage({ type: "notion", token: "ntn_x", target: "database", parentId: pageId, titleProperty: "" }, "t", body, {
+      meta: { jobName: "Prices" },
+    });
+
+    const created = JSON.parse(fetchMock.mock.calls[0][1].body as string);
+    expect(fetchMock.mock.calls[0][0]).toBe("https://api.notion.com/v1/pages");
+    expect(created.parent).toEqual({ database_id: pageId });
+    expect(created.properties.title.title[0].text.content).toMatch(/^Prices · \d{4}-\d{2}-\d{2}$/);
+    expect(created.children).toHaveLength(100);
+    expect(fetchMock.mock.calls[1][0]).toBe("https://api.notion.com/v1/blocks/row-1/children");
+    expect(JSON.parse(fetchMock.mock.calls[1][1].body as string).children).toHaveLength(50);
+  });
+});
+
+describe("google sheets channel", () => {
+  it("appends to the first tab or a quoted tab of the spreadsheet", () => {
+    const id = "1AbCdEfGhIjKlMnOpQrStUvWxYz0123456789";
+    expect(__private__.sheetsAppendUrl({ spreadsheetId: `https://docs.google.com/spreadsheets/d/${id}/edit#gid=0`, sheet: "" })).toBe(
+      `https://sheets.googleapis.com/v4/spreadsheets/${id}/values/A1:append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS`,
+    );
+    expect(__private__.sheetsAppendUrl({ spreadsheetId: id, sheet: "Bob's prices" })).toContain(
+      `/values/${encodeURIComponent("'Bob''s prices'!A1")}:append`,
+    );
+  });
+
+  it("maps run values and structured-output fields to columns", () => {
+    const now = new Date("2026-05-06T09:30:00.000Z");
+    const meta = { jobName: "Prices", runHistoryId: "run-1" };
+    expect(__private__.sheetsRow("", "[Prices]", "=1+1", now, meta)).toEqual(["2026-05-06 09:30:00", "Prices", "'=1+1"]);
+
+    const body = '```json\n{"price": 101.5, "change": "-0.4%", "tickers": ["A"]}\n```';
+    expect(__private__.sheetsRow("timestamp, price, change, tickers, missing, run_id", "t", body, now, meta)).toEqual([
+      "2026-05-06 09:30:00",
+      101.5,
+      "-0.4%",
+      '["A"]',
+      "",
+      "run-1",
+    ]);
+  });
+});
+
+describe("jira channel", () => {
+  const jira = {
+    type: "jira" as const,
+    siteUrl: "https://acme.atlassian.net/",
+    email: "bot@acme.test",
+    apiToken: "tok",
+    projectKey: "OPS",
+    issueType: "Bug",
+    summaryTemplate: "Triage: {{jobName}}",
+    labels: "promptloop, triage",
+  };
+
+  it("creates an issue with a rendered summary and a wiki description", async () => {
+    const fetchMock = vi.fn(async (_url: string, _init: RequestInit) => new Response(JSON.stringify({ key: "OPS-1" }), { status: 201 }));
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(jira, "[Triage]", "## Alerts\n- **disk** full", { meta: { jobName: "Nightly" } });
+
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe("https://acme.atlassian.net/rest/api/2/issue");
+    expect((init.headers as Record<string, string>).Authorization).toBe(`Basic ${Buffer.from("bot@acme.test:tok").toString("base64")}`);
+    expect(JSON.parse(init.body as string)).toEqual({
+      fields: {
+        project: { key: "OPS" },
+        issuetype: { name: "Bug" },
+        summary: "Triage: Nightly",
+        description: "h2. Alerts\n* *disk* full",
+        labels: ["promptloop", "triage"],
+      },
+    });
+  });
+
+  it("falls back to the title for the summary and reports field errors", async () => {
+    const payload = __private__.jiraIssuePayload({ ...jira, summaryTemplate: "", labels: "" }, "[Triage] 2026-05-07", "x", {});
+    expect(payload.fields.summary).toBe("[Triage] 2026-05-07");
+    expect(payload.fields).not.toHaveProperty("labels");
+
+    const rejected = { errorMessages: [], errors: { issuetype: "Specify a valid issue type" } };
+    vi.stubGlobal("fetch", vi.fn(async () => new Response(JSON.stringify(rejected), { status: 400 })));
+    await expect(sendChannelMessage({ ...jira, email: "" }, "t", "x")).rejects.toThrow(
+      "Jira issue create failed: 400 (issuetype: Specify a valid issue type)",
+    );
+  });
+});
+
+describe("webhook secret references", () => {
+  it("refuses to send headers with an unresolved secret reference", async () => {
+    const fetchMock = vi.fn(async () => new Response("ok", { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+
+    await expect(
+      sendChannelMessage(
+        { type: "webhook", url: "https://hooks.example/in", method: "POST", headers: '{"Authorization":"Bearer {{secret:GONE}}"}', payload: "" },
+        "t",
+        "out",
+      ),
+    ).rejects.toMatchObject({ status: 400, message: 'Webhook header references unknown secret "GONE"' });
+    expect(fetchMock).not.toHaveBeenCalled();
+  });
+});
+
+describe("webhook graphql mode", () => {
+  it("posts the mutation with rendered variables", async () => {
+    const fetchMock = vi.fn(async () => new Response(JSON.stringify({ data: { ok: true } }), { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      {
+        type: "webhook",
+        url: "https://api.example/graphql",
+        method: "POST",
+        headers: "",
+        payload: '{"body":"{{body}}","job":"{{jobId}}"}',
+        graphqlQuery: "mutation Post($body: String!) { post(body: $body) { id } }",
+      },
+      "t",
+      "out",
+      { meta: { jobId: "job-1" } },
+    );
+
+    const [, init] = fetchMock.mock.calls[0] as unknown as [string, RequestInit];
+    const sent = JSON.parse(init.body as string) as { query: string; variables: Record<string, string> };
+    expect(sent.query).toContain("mutation Post");
+    expect(sent.variables).toEqual({ body: "out", job: "job-1" });
+  });
+
+  it("fails when the response carries GraphQL errors", async () => {
+    vi.stubGlobal("fetch", vi.fn(async () => new Response(JSON.stringify({ errors: [{ message: "denied" }] }), { status: 200 })));
+
+    await expect(
+      sendChannelMessage(
+        { type: "webhook", url: "https://api.example/graphql", method: "POST", headers: "", payload: "", graphqlQuery: "mutation { x }" },
+        "t",
+        "out",
+      ),
+    ).rejects.toThrow("GraphQL webhook failed: denied");
+  });
+});
+
+describe("webhook xml mode", () => {
+  it("renders the XML template with escaping and a default text/xml content type", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      {
+        type: "webhook",
+        url: "https://legacy.example/service.asmx",
+        method: "POST",
+        headers: '{"SOAPAction":"urn:PostReport"}',
+        payload: "<Body>{{body}}</Body>",
+        bodyFormat: "xml",
+      },
+      "t",
+      "a & b",
+    );
+
+    const [, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(init.body).toBe("<Body>a &amp; b</Body>");
+    expect((init.headers as Record<string, string>)["Content-Type"]).toBe("text/xml; charset=utf-8");
+    expect((init.headers as Record<string, string>).SOAPAction).toBe("urn:PostReport");
+  });
+});
+
+describe("chat formatting", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("sends Telegram parts as HTML and falls back to plain text when Telegram rejects the markup", async () => {
+    const bodies: Array<Record<string, unknown>> = [];
+    vi.stubGlobal(
+      "fetch",
+      vi.fn(async (_url: string, init: RequestInit) => {
+        bodies.push(JSON.parse(String(init.body)));
+        return ({ ok: bodies.length > 1, status: bodies.length > 1 ? 200 : 400 }) as unknown as Response;
+      }),
+    );
+    await sendChannelMessage({ type: "telegram", botToken: "t", chatId: "1" }, "t", "| a | b |\n|---|---|\n| 1 | 2 |");
+    expect(bodies[0]).toMatchObject({ parse_mode: "HTML" });
+    expect(bodies[0].text).toContain("<pre>a   | b\n----+----\n1   | 2</pre>");
+    expect(bodies[1].parse_mode).toBeUndefined();
+    expect(bodies[1].text).toContain("```\na   | b");
+
+    vi.stubEnv("CHANNEL_TELEGRAM_FORMAT", "plain");
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+    await sendChannelMessage({ type: "telegram", botToken: "t", chatId: "1" }, "t", "`x` <y>");
+    expect(JSON.parse(String(fetchMock.mock.calls[0][1]?.body))).toEqual({ chat_id: "1", text: "t\n\n`x` <y>" });
+  });
+
+  it("keeps Discord tables readable as fixed-width code blocks", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+    await sendChannelMessage({ type: "discord", webhookUrl: "https://discord.com/api/webhooks/1/x" }, "t", "| a | b |\n|---|--:|\n| 1 | 22 |");
+    expect(JSON.parse(String(fetchMock.mock.calls[0][1]?.body)).content).toBe("t\n\n```\na   |   b\n----+----\n1   |  22\n```");
+  });
+});
+
+describe("identification headers", () => {
+  it("adds User-Agent and job/run ids to every channel request", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage({ type: "telegram", botToken: "123456:abcdef", chatId: "42" }, "t", "out", {
+      meta: { jobId: "job-1", runHistoryId: "run-1" },
+    });
+
+    const [, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    const headers = init.headers as Record<string, string>;
+    expect(headers["User-Agent"]).toMatch(/^promptloop\//);
+    expect(headers["X-Promptloop-Job-Id"]).toBe("job-1");
+    expect(headers["X-Promptloop-Run-Id"]).toBe("run-1");
+  });
+
+  it("lets the per-job override and explicit webhook headers win", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "webhook", url: "https://hooks.example/x", method: "POST", headers: '{"X-Promptloop-Job-Id":"custom"}', payload: "" },
+      "t",
+      "out",
+      { meta: { jobId: "job-1" }, userAgent: "acme-reports/2" },
+    );
+
+    const [, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    const headers = init.headers as Record<string, string>;
+    expect(headers["User-Agent"]).toBe("acme-reports/2");
+    expect(headers["X-Promptloop-Job-Id"]).toBe("custom");
+  });
+});
+
+describe("webhook gzip", () => {
+  it("compresses large bodies and falls back to plain on 415", async () => {
+    const { gunzipSync } = await import("node:zlib");
+    const statuses = [415, 204];
+    const fetchMock = vi.fn(async (_input: RequestInfo | URL, _init?: RequestInit) => {
+      void _input;
+      void _init;
+      return ({ ok: statuses[0] < 300, status: statuses.shift() }) as unknown as Response;
+    });
+    vi.stubGlobal("fetch", fetchMock);
+
+    const body = "x".repeat(4096);
+    await sendChannelMessage({ type: "webhook", url: "https://hooks.example/x", method: "POST", headers: "", payload: "", gzip: true }, "t", body);
+
+    const [, first] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect((first.headers as Record<string, string>)["Content-Encoding"]).toBe("gzip");
+    const decoded = JSON.parse(gunzipSync(first.body as Uint8Array).toString("utf8")) as { body: string };
+    expect(decoded.body).toBe(body);
+
+    const [, second] = fetchMock.mock.calls[1] as [string, RequestInit];
+    expect((second.headers as Record<string, string>)["Content-Encoding"]).toBeUndefined();
+    expect(typeof second.body).toBe("string");
+  });
+});
+
+describe("webhook signing", () => {
+  it("signs the body with the timestamp when a signing secret is set", async () => {
+    const fetchMock = mockOkFetch();
+    vi.stubGlobal("fetch", fetchMock);
+
+    await sendChannelMessage(
+      { type: "webhook", url: "https://hooks.example/x", method: "POST", headers: "", payload: "", signingSecret: "s3cret" },
+      "t",
+      "hello",
+    );
+
+    const init = fetchMock.mock.calls[0][1] as RequestInit;
+    const headers = init.headers as Record<string, string>;
+    const timestamp = Number(headers["X-Promptloop-Timestamp"]);
+    expect(Number.isInteger(timestamp)).toBe(true);
+    expect(headers["X-Promptloop-Signature"]).toBe(webhookSignature("s3cret", timestamp, init.body as string));
+    expect(webhookSignature("key", 1, "{}")).toMatch(/^sha256=[0-9a-f]{64}$/);
+  });
+});
+
+describe("webhook response capture", () => {
+  it("reports the response only when capture is on, failed responses included", async () => {
+    vi.stubGlobal(
+      "fetch",
+      vi.fn(async () => new Response('{"error":"conflict","id":"t-42"}', { status: 409 })),
+    );
+    const responses: Array<{ status: number; body: string }> = [];
+    const channel = { type: "webhook" as const, url: "https://hooks.example/x", method: "POST" as const, headers: "", payload: "" };
+
+    await expect(
+      sendChannelMessage({ ...channel, captureResponse: true }, "t", "hello", { onResponse: (res) => responses.push(res) }),
+    ).rejects.toMatchObject({ status: 409 });
+    await expect(sendChannelMessage(channel, "t", "hello", { onResponse: (res) => responses.push(res) })).rejects.toMatchObject({
+      status: 409,
+    });
+    expect(responses).toEqual([{ status: 409, body: '{"error":"conflict","id":"t-42"}' }]);
+  });
+});
+
+describe("partial delivery", () => {
+  it("reports how many parts reached the channel before a failure", async () => {
+    let calls = 0;
+    vi.stubGlobal(
+      "fetch",
+      vi.fn(async () => {
+        calls++;
+        return ({ ok: calls === 1, status: calls === 1 ? 200 : 502 }) as unknown as Response;
+      }),
+    );
+
+    const error = await sendChannelMessage({ type: "telegram", botToken: "t", chatId: "1" }, "[t]", "e".repeat(9000)).catch(
+      (err: unknown) => err,
+    );
+    expect(error).toBeInstanceOf(ChannelRequestError);
+    expect((error as ChannelRequestError).partsDelivered).toBe(1);
+    expect((error as ChannelRequestError).status).toBe(502);
+  });
+
+  it("resumes after the parts an earlier attempt delivered", async () => {
+    const sent: string[] = [];
+    vi.stubGlobal(
+      "fetch",
+      vi.fn(async (_url: string, init: RequestInit) => {
+        sent.push(JSON.parse(String(init.body)).text);
+        return ({ ok: sent.length === 1, status: sent.length === 1 ? 200 : 502 }) as unknown as Response;
+      }),
+    );
+    const progress: number[] = [];
+    const error = await sendChannelMessage({ type: "telegram", botToken: "t", chatId: "1" }, "[t]", "e".repeat(9000), {
+      resumeFromPart: 1,
+      onPartDelivered: (parts) => {
+        progress.push(parts);
+      },
+    }).catch((err: unknown) => err);
+
+    expect(sent).toHaveLength(2);
+    expect(sent[0].startsWith("[t]")).toBe(false);
+    expect(progress).toEqual([2]);
+    expect((error as ChannelRequestError).partsDelivered).toBe(2);
+  });
 });
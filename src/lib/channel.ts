@@ -1,39 +1,177 @@
-type SendChannelInput =
-  | { type: "discord"; webhookUrl: string }
-  | { type: "telegram"; botToken: string; chatId: string }
+import { createHmac } from "node:crypto";
+import { appendFile, mkdir } from "node:fs/promises";
+import { dirname, resolve as resolvePath, sep } from "node:path";
+import { gzipSync } from "node:zlib";
+import { renderWebhookPayload, renderXmlTemplate } from "@/lib/webhook-presets";
+import { getGoogleAccessToken } from "@/lib/google-auth";
+import { awsWebIdentityEnabled, resolveAwsCredentials, signAwsRequest } from "@/lib/aws-auth";
+import { checkDestination } from "@/lib/destination-policy";
+import { MqttError, mqttPublish, type MqttQos } from "@/lib/mqtt";
+import { NatsError, natsPublish } from "@/lib/nats";
+import { clock } from "@/lib/clock";
+import { isRecord } from "@/lib/type-guards";
+import { chaosChannelResponse } from "@/lib/chaos";
+import { tablesToCodeBlocks, toJiraWiki, toNotionBlocks, toTelegramHtml, type NotionBlock } from "@/lib/message-format";
+import { renderTemplate } from "@/lib/template-functions";
+import { channelPlugin, parsePluginSettings } from "@/lib/channel-plugins";
+import { insecureTlsAllowed, transportFetch, usesCustomTransport } from "@/lib/webhook-transport";
+import type { UserPlan } from "@/lib/entitlements";
+import packageJson from "../../package.json";
+
+export type SendChannelInput =
+  | { type: "discord"; webhookUrl: string; threadId?: string; threadName?: string }
+  | { type: "telegram"; botToken: string; chatId: string; messageThreadId?: string }
   | {
       type: "webhook";
       url: string;
       method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
       headers: string;
       payload: string;
-    };
+      graphqlQuery?: string;
+      bodyFormat?: "json" | "xml";
+      gzip?: boolean;
+      signingSecret?: string;
+      captureResponse?: boolean;
+      proxyUrl?: string;
+      caBundle?: string;
+      insecureSkipVerify?: boolean;
+    }
+  | { type: "home_assistant"; baseUrl: string; token: string; service: string }
+  | { type: "elasticsearch"; url: string; index: string; apiKey: string; username: string; password: string }
+  | { type: "clickhouse"; url: string; table: string; username: string; password: string }
+  | { type: "bigquery"; projectId: string; dataset: string; table: string; serviceAccountJson: string }
+  | { type: "redis"; restUrl: string; token: string; mode: "set" | "xadd"; key: string; ttlSeconds: string }
+  | { type: "pushover"; appToken: string; userKey: string; priority: "" | "-2" | "-1" | "0" | "1"; sound: string }
+  | { type: "twilio"; accountSid: string; authToken: string; from: string; to: string; mode: "summary" | "full" }
+  | { type: "google_chat"; webhookUrl: string; format: "card" | "text"; threadKey: string }
+  | { type: "aws"; service: "sns" | "sqs"; target: string; accessKeyId: string; secretAccessKey: string }
+  | { type: "kafka"; restUrl: string; api: "v3" | "v2"; clusterId: string; topic: string; username: string; password: string }
+  | { type: "nats"; url: string; subject: string; mode: "core" | "jetstream"; username: string; password: string; token: string }
+  | {
+      type: "mqtt";
+      brokerUrl: string;
+      topic: string;
+      qos: "0" | "1" | "2";
+      retain: "on" | "off";
+      format: "json" | "text";
+      username: string;
+      password: string;
+    }
+  | { type: "file"; target: "file" | "stdout"; path: string }
+  | { type: "notion"; token: string; target: "page" | "database"; parentId: string; titleProperty: string }
+  | { type: "google_sheets"; spreadsheetId: string; sheet: string; columns: string; serviceAccountJson: string }
+  | {
+      type: "jira";
+      siteUrl: string;
+      email: string;
+      apiToken: string;
+      projectKey: string;
+      issueType: string;
+      summaryTemplate: string;
+      labels: string;
+    }
+  | { type: "plugin"; plugin: string; settings: string };
 
 export type ChannelCitation = { url: string; title?: string };
 
+// A run artifact exposed by URL; text channels list the links, Telegram and Discord also attach them.
+// Audio carries its bytes so it can be uploaded (Telegram voice message, Discord audio file).
+export type ChannelAttachment = { name: string; url: string; mediaType: string; sizeBytes?: number; content?: Uint8Array };
+
 type SendChannelOptions = {
   citations?: ChannelCitation[];
+  attachments?: ChannelAttachment[];
   usedWebSearch?: boolean;
   meta?: Record<string, unknown>;
+  // Per-job override for the default promptloop/<version> User-Agent.
+  userAgent?: string | null;
+  // Called with each request body as sent (after templating and chunking, before compression).
+  onRendered?: (body: string) => void;
+  // Webhook channels with captureResponse: called with the status and body of the final response.
+  onResponse?: (response: { status: number; body: string }) => void;
+  // Multi-part sends (Discord/Telegram/Pushover/Google Chat): skip parts a previous attempt already delivered, and report progress.
+  resumeFromPart?: number;
+  onPartDelivered?: (partsDelivered: number) => Promise<void> | void;
+  // Owner's plan, for the plan-specific rules of DELIVERY_DESTINATION_POLICY.
+  plan?: UserPlan | null;
+  // Chat channels (Discord/Telegram/Pushover/Google Chat): a message that needs more than one part is cut to its first part, ending
+  // in this link to the full output.
+  fullOutputUrl?: string | null;
 };
 
+export const DEFAULT_USER_AGENT = `promptloop/${packageJson.version}`;
+
 export class ChannelRequestError extends Error {
   status: number;
+  // Message parts (counted from the start of the message, including resumed ones) that reached the channel
+  // before this failure.
+  partsDelivered: number;
+  // Wait the channel asked for (Retry-After header or retry_after body field) on a 429, if any.
+  retryAfterMs: number | null;
 
-  constructor(message: string, status: number) {
+  constructor(message: string, status: number, partsDelivered = 0, retryAfterMs: number | null = null) {
     super(message);
     this.name = "ChannelRequestError";
     this.status = status;
+    this.partsDelivered = partsDelivered;
+    this.retryAfterMs = retryAfterMs;
   }
 }
 
+// Sends the ordered parts of one message (file, text chunks, attachments). Parts before resumeFromPart were
+// delivered by an earlier attempt and are skipped; a failure after the first part is rethrown with partsDelivered
+// set, so callers can record the progress and resume instead of sending duplicate chunks.
+function partSender(opts?: Pick<SendChannelOptions, "resumeFromPart" | "onPartDelivered">) {
+  const skip = Math.max(0, Math.floor(opts?.resumeFromPart ?? 0));
+  let next = 0;
+  return async (send: () => Promise<unknown>) => {
+    const index = next++;
+    if (index < skip) {
+      return;
+    }
+    try {
+      await send();
+    } catch (err) {
+      if (index === 0) {
+        throw err;
+      }
+      const status = err instanceof ChannelRequestError ? err.status : 0;
+      const retryAfterMs = err instanceof ChannelRequestError ? err.retryAfterMs : null;
+      throw new ChannelRequestError(err instanceof Error ? err.message : String(err), status, index, retryAfterMs);
+    }
+    await opts?.onPartDelivered?.(index + 1);
+  };
+}
+
 const DISCORD_MAX = 1900;
 const TELEGRAM_MAX = 4000;
+// Pushover's message limit; the title (up to 250 characters) is sent separately.
+const PUSHOVER_MAX = 1024;
+const PUSHOVER_TITLE_MAX = 250;
+const PUSHOVER_MESSAGES_URL = "https://api.pushover.net/1/messages.json";
+// Twilio accepts up to 1600 characters per message; a summary fits in two SMS segments.
+const TWILIO_MAX = 1600;
+const SMS_SUMMARY_MAX = 320;
+// Google Chat allows 4096 characters of message text.
+const GOOGLE_CHAT_MAX = 4000;
+// SNS and SQS reject messages over 256 KiB.
+const AWS_MESSAGE_MAX_BYTES = 256 * 1024;
+const NOTION_API_URL = "https://api.notion.com/v1";
+const NOTION_VERSION = "2022-06-28";
+// Notion accepts at most 100 children per create or append request.
+const NOTION_BLOCKS_PER_REQUEST = 100;
+// Google Sheets cells hold at most 50,000 characters.
+const SHEETS_CELL_MAX = 50_000;
+const SHEETS_DEFAULT_COLUMNS = ["timestamp", "job", "output"];
+// Jira summaries are limited to 255 characters; descriptions are cut well below the 32,767 field limit.
+const JIRA_SUMMARY_MAX = 255;
+const JIRA_DESCRIPTION_MAX = 30_000;
 
+const DISCORD_THREAD_NAME_MAX = 100;
 const DISCORD_WEBHOOK_URL_RE = /^https:\/\/(?:discord\.com|discordapp\.com)\/api\/webhooks\/(\d+)\/[A-Za-z0-9_-]+/;
 
 function sleep(ms: number) {
-  return new Promise((r) => setTimeout(r, ms));
+  return clock().sleep(ms);
 }
 
 function envInt(name: string, fallback: number, min: number, max: number): number {
@@ -47,20 +185,24 @@ function envInt(name: string, fallback: number, min: number, max: number): numbe
   return v;
 }
 
-function parseRetryAfterMs(value: string | null): number | null {
-  if (!value) return null;
+// Retry-After is delay-seconds (Discord sends fractions, e.g. "1.5") or an HTTP date.
+export function parseRetryAfterMs(value: string | null, now = clock().now()): number | null {
+  if (!value?.trim()) return null;
   const n = Number(value);
-  if (!Number.isFinite(n) || n < 0) return null;
-  if (n < 10) return Math.round(n * 1000);
-  return Math.round(n);
+  if (Number.isFinite(n)) return n >= 0 ? Math.round(n * 1000) : null;
+  const at = Date.parse(value);
+  if (!Number.isFinite(at)) return null;
+  return Math.max(0, at - now.getTime());
 }
 
-async function retryAfterMsFromResponse(res: Response): Promise<number | null> {
+// Header first, then the JSON body: Discord's retry_after or Telegram's parameters.retry_after, both in seconds.
+export async function retryAfterMsFromResponse(res: Response): Promise<number | null> {
   const header = parseRetryAfterMs(res.headers.get("retry-after"));
   if (header != null) return header;
   try {
     const data = (await res.json()) as unknown;
-    const retry = data && typeof data === "object" ? (data as { retry_after?: unknown }).retry_after : undefined;
+    const body = isRecord(data) ? data : {};
+    const retry = body.retry_after ?? (isRecord(body.parameters) ? body.parameters.retry_after : undefined);
     if (typeof retry === "number" && Number.isFinite(retry) && retry >= 0) {
       return Math.round(retry * 1000);
     }
@@ -70,43 +212,401 @@ async function retryAfterMsFromResponse(res: Response): Promise<number | null> {
   return null;
 }
 
-function buildDiscordChunks(text: string): string[] {
-  const chunks = chunkDiscordContent(text, DISCORD_MAX);
+// CHANNEL_RETRY_AFTER_MAX_SECONDS (default 60): the longest rate-limit wait honoured within one delivery. A longer
+// Retry-After ends the attempt instead of sleeping through it.
+export function retryAfterMaxMs() {
+  return envInt("CHANNEL_RETRY_AFTER_MAX_SECONDS", 60, 0, 3600) * 1000;
+}
+
+// The error for a failed response; on 429 it carries the wait the channel asked for.
+async function responseError(message: string, res: Response) {
+  return new ChannelRequestError(message, res.status, 0, res.status === 429 ? await retryAfterMsFromResponse(res) : null);
+}
+
+// Webhook request signature: hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the channel's signing secret.
+// Receivers recompute it from the X-Promptloop-Timestamp header and the (uncompressed) body.
+export function webhookSignature(secret: string, timestamp: number, body: string) {
+  return `sha256=${createHmac("sha256", secret).update(`${timestamp}.${body}`).digest("hex")}`;
+}
+
+// Room kept at the end of each chunk for the " (12/50)" part suffix.
+const PART_SUFFIX_RESERVE = 10;
+
+function numberParts() {
+  return ["1", "true", "yes"].includes((process.env.CHANNEL_CHUNK_NUMBERING ?? "").trim().toLowerCase());
+}
+
+// Appends "(1/3)"-style suffixes when CHANNEL_CHUNK_NUMBERING is on and the message needed more than one part.
+function withPartSuffixes(chunks: string[], enabled: boolean) {
+  if (!enabled || chunks.length < 2) return chunks;
+  return chunks.map((chunk, index) => `${chunk} (${index + 1}/${chunks.length})`);
+}
+
+// The first part of a long message with a link to the rest; fences cut by the shortened part are closed.
+function firstPartWithLink(text: string, max: number, url: string) {
+  const note = `\n\n[Full output: ${url}]`;
+  return `${chunkFencedText(text, Math.max(1, max - note.length))[0]}${note}`;
+}
+
+function buildDiscordChunks(text: string, fullOutputUrl?: string | null): string[] {
+  const numbered = numberParts();
+  const max = numbered ? DISCORD_MAX - PART_SUFFIX_RESERVE : DISCORD_MAX;
+  const chunks = chunkFencedText(text, max);
+  if (fullOutputUrl && chunks.length > 1) return [firstPartWithLink(text, DISCORD_MAX, fullOutputUrl)];
   const maxParts = envInt("CHANNEL_DISCORD_MAX_PARTS", 10, 1, 50);
-  if (chunks.length <= maxParts) return chunks;
+  if (chunks.length <= maxParts) return withPartSuffixes(chunks, numbered);
 
   const note = `\n\n[Truncated: sent first ${maxParts} of ${chunks.length} parts. Full output is available in Run History.]`;
-  const maxTotal = maxParts * DISCORD_MAX;
+  const maxTotal = maxParts * max;
   const baseBudget = Math.max(0, maxTotal - note.length);
   const base = text.slice(0, baseBudget);
   const openFence = updateCodeFenceState(null, base);
   const closeFence = openFence != null ? "\n```" : "";
   const truncatedText = `${base}${closeFence}${note}`;
-  return chunkDiscordContent(truncatedText, DISCORD_MAX).slice(0, maxParts);
+  return withPartSuffixes(chunkFencedText(truncatedText, max).slice(0, maxParts), numbered);
+}
+
+const OUTPUT_FILE_NAME = "output.md";
+const FILE_SUMMARY_PREVIEW = 300;
+const TELEGRAM_CAPTION_MAX = 1024;
+
+// CHANNEL_FILE_FALLBACK_CHARS: Discord and Telegram messages longer than this are sent as an output.md file with
+// a short summary message instead of many chunks. 0 (default) always chunks.
+function fileFallbackChars() {
+  return envInt("CHANNEL_FILE_FALLBACK_CHARS", 0, 0, 10_000_000);
+}
+
+function fileFallbackSummary(title: string, body: string) {
+  const firstParagraph = body.trim().split(/\n\s*\n/)[0] ?? "";
+  const preview =
+    firstParagraph.length > FILE_SUMMARY_PREVIEW ? `${firstParagraph.slice(0, FILE_SUMMARY_PREVIEW).trimEnd()}...` : firstParagraph;
+  return `${title ? `${title}\n\n` : ""}${preview}\n\n[Full output attached as ${OUTPUT_FILE_NAME} (${body.length.toLocaleString("en-US")} characters).]`;
+}
+
+// CHANNEL_TELEGRAM_FORMAT: "html" (default) sends parts in Telegram's HTML parse mode so code blocks and tables
+// render monospaced; "plain" sends the raw Markdown text.
+function telegramHtmlEnabled() {
+  return (process.env.CHANNEL_TELEGRAM_FORMAT ?? "").trim().toLowerCase() !== "plain";
+}
+
+function buildMessageChunks(text: string, max: number, fullOutputUrl?: string | null): string[] {
+  const numbered = numberParts();
+  const chunks = chunkFencedText(text, numbered ? max - PART_SUFFIX_RESERVE : max);
+  if (fullOutputUrl && chunks.length > 1) return [firstPartWithLink(text, max, fullOutputUrl)];
+  return withPartSuffixes(chunks, numbered);
+}
+
+function buildTelegramChunks(text: string, fullOutputUrl?: string | null): string[] {
+  return buildMessageChunks(text, TELEGRAM_MAX, fullOutputUrl);
+}
+
+function buildPushoverChunks(text: string, fullOutputUrl?: string | null): string[] {
+  return buildMessageChunks(text, PUSHOVER_MAX, fullOutputUrl);
+}
+
+// SMS summary mode: an output that does not fit SMS_SUMMARY_MAX is cut to the header plus the start of its first
+// paragraph (at a word boundary), ending in the full output link when the job has one.
+function smsSummary(title: string, body: string, fullOutputUrl?: string | null) {
+  const head = title ? `${title}\n` : "";
+  const whole = `${head}${body.trim()}`;
+  if (whole.length <= SMS_SUMMARY_MAX) return whole;
+  const link = fullOutputUrl ? `\n${fullOutputUrl}` : "";
+  const budget = Math.max(40, SMS_SUMMARY_MAX - head.length - link.length - 3);
+  const paragraph = (body.trim().split(/\n\s*\n/)[0] ?? "").replace(/\s+/g, " ");
+  if (paragraph.length <= budget) return `${head}${paragraph}${link}`;
+  const cut = paragraph.slice(0, budget);
+  const space = cut.lastIndexOf(" ");
+  return `${head}${(space > budget / 2 ? cut.slice(0, space) : cut).trimEnd()}...${link}`;
+}
+
+function escapeHtml(text: string) {
+  return text.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;");
+}
+
+// Discord webhook URL posting into a thread (thread_id), or waiting for the created message (wait=true) so the id of
+// a new forum post can be read from its channel_id.
+function discordWebhookUrl(webhookUrl: string, threadId: string, wait: boolean) {
+  const url = new URL(webhookUrl);
+  if (threadId) {
+    url.searchParams.set("thread_id", threadId);
+  }
+  if (wait) {
+    url.searchParams.set("wait", "true");
+  }
+  return url.toString();
+}
+
+// Name of the forum post a run starts: the rendered template, else the run title, cut to Discord's 100 characters.
+function discordThreadName(template: string, vars: Record<string, string>, title: string) {
+  const rendered = template.trim() ? renderTemplate(template, vars).trim() : "";
+  return (rendered || title.trim() || "Run").slice(0, DISCORD_THREAD_NAME_MAX);
+}
+
+// Google Chat webhook URL with the thread to post into; replies fall back to a new thread if the key is unknown.
+function googleChatUrl(webhookUrl: string, threadKey: string) {
+  const url = new URL(webhookUrl);
+  if (threadKey) {
+    url.searchParams.set("messageReplyOption", "REPLY_MESSAGE_FALLBACK_TO_NEW_THREAD");
+  }
+  return url.toString();
+}
+
+// One part as a Google Chat message: a cards v2 card (the header as card title on the first part, the text as a
+// paragraph, which renders basic HTML) or a plain text message.
+function googleChatMessage(format: "card" | "text", title: string, part: string, index: number, threadKey: string) {
+  const thread = threadKey ? { thread: { threadKey } } : {};
+  if (format === "text") {
+    return { text: index === 0 && title ? `*${title}*\n\n${part}` : part, ...thread };
+  }
+  return {
+    cardsV2: [
+      {
+        cardId: `part-${index + 1}`,
+        card: {
+          ...(index === 0 && title ? { header: { title } } : {}),
+          sections: [{ widgets: [{ textParagraph: { text: escapeHtml(part).replace(/\n/g, "<br>") } }] }],
+        },
+      },
+    ],
+    ...thread,
+  };
+}
+
+// Region and API endpoint of an SNS topic ARN (arn:aws:sns:<region>:<account>:<name>) or SQS queue URL.
+function awsEndpoint(channel: { service: "sns" | "sqs"; target: string }) {
+  if (channel.service === "sqs") {
+    const url = new URL(channel.target);
+    return { region: url.hostname.split(".")[1] ?? "", url: channel.target };
+  }
+  const [, partition, , region] = channel.target.split(":");
+  return { region, url: `https://sns.${region}.amazonaws.com${partition === "aws-cn" ? ".cn" : ""}/` };
+}
+
+// Publish (SNS) or SendMessage (SQS) query API parameters. FIFO topics and queues group messages by job and
+// de-duplicate by run, so a retried delivery of the same run is not published twice.
+function awsMessageParams(channel: { service: "sns" | "sqs"; target: string }, message: string, meta?: Record<string, unknown>) {
+  const params: Record<string, string> =
+    channel.service === "sns"
+      ? { Action: "Publish", Version: "2010-03-31", TopicArn: channel.target, Message: message }
+      : { Action: "SendMessage", Version: "2012-11-05", MessageBody: message };
+  if (channel.target.endsWith(".fifo")) {
+    params.MessageGroupId = typeof meta?.jobId === "string" ? meta.jobId : "promptloop";
+    if (typeof meta?.runHistoryId === "string") {
+      params.MessageDeduplicationId = meta.runHistoryId;
+    }
+  }
+  return params;
+}
+
+// The event published to Kafka; keyed by job id so one job's runs stay ordered on one partition.
+function kafkaEnvelope(title: string, body: string, meta?: Record<string, unknown>) {
+  const str = (v: unknown) => (typeof v === "string" ? v : null);
+  return {
+    job_id: str(meta?.jobId),
+    run_id: str(meta?.runHistoryId),
+    name: str(meta?.jobName) ?? title,
+    run_at: str(meta?.scheduledFor) ?? new Date().toISOString(),
+    output: body,
+  };
+}
+
+function kafkaProduceRequest(
+  channel: { restUrl: string; api: "v3" | "v2"; clusterId: string; topic: string },
+  key: string | null,
+  value: unknown,
+) {
+  const base = channel.restUrl.trim().replace(/\/+$/, "");
+  if (channel.api === "v2") {
+    return {
+      url: `${base}/topics/${encodeURIComponent(channel.topic)}`,
+      contentType: "application/vnd.kafka.json.v2+json",
+      body: { records: [{ ...(key ? { key } : {}), value }] },
+    };
+  }
+  return {
+    url: `${base}/kafka/v3/clusters/${encodeURIComponent(channel.clusterId)}/topics/${encodeURIComponent(channel.topic)}/records`,
+    contentType: "application/json",
+    body: { ...(key ? { key: { type: "STRING", data: key } } : {}), value: { type: "JSON", data: value } },
+  };
+}
+
+// A produce call can answer 200 with a per-record error (v3 error_code, v2 offsets[].error).
+function kafkaRecordError(data: unknown): string | null {
+  if (!isRecord(data)) return null;
+  if (typeof data.error_code === "number" && data.error_code !== 200) {
+    return typeof data.message === "string" ? data.message : `error ${data.error_code}`;
+  }
+  const offset = Array.isArray(data.offsets) && isRecord(data.offsets[0]) ? data.offsets[0] : null;
+  return offset && typeof offset.error === "string" && offset.error ? offset.error : null;
+}
+
+// CHANNEL_FILE_DIR enables the file channel (local development); file targets must stay inside this directory.
+function fileChannelDir() {
+  return (process.env.CHANNEL_FILE_DIR ?? "").trim();
+}
+
+function fileChannelPath(dir: string, path: string) {
+  const root = resolvePath(dir);
+  const target = resolvePath(root, path.trim());
+  return target.startsWith(`${root}${sep}`) ? target : null;
+}
+
+function fileEntry(text: string, now: Date, meta?: Record<string, unknown>) {
+  const name = typeof meta?.jobName === "string" && meta.jobName ? ` · ${meta.jobName}` : "";
+  return `===== ${now.toISOString()}${name} =====\n${text}\n\n`;
+}
+
+// Page and database ids are 32 hex digits, pasted bare, dashed, or as the tail of a Notion URL (after the title slug).
+function notionId(value: string) {
+  const match = /.*([0-9a-f]{8})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{12})/i.exec(value.split(/[?#]/)[0] ?? "");
+  return match ? match.slice(1).join("-").toLowerCase() : null;
+}
+
+function notionBatches(blocks: NotionBlock[]) {
+  const batches: NotionBlock[][] = [];
+  for (let i = 0; i < blocks.length; i += NOTION_BLOCKS_PER_REQUEST) {
+    batches.push(blocks.slice(i, i + NOTION_BLOCKS_PER_REQUEST));
+  }
+  return batches.length ? batches : [[]];
+}
+
+// A database row is titled with the job name (or the rendered header) and the date; the property defaults to the
+// title column, whose property id is always "title".
+function notionRowProperties(titleProperty: string, name: string) {
+  return { [titleProperty.trim() || "title"]: { title: [{ type: "text", text: { content: name.slice(0, 2000) } }] } };
+}
+
+function spreadsheetId(value: string) {
+  return /\/spreadsheets\/d\/([A-Za-z0-9_-]+)/.exec(value)?.[1] ?? value.trim();
+}
+
+function sheetsAppendUrl(channel: { spreadsheetId: string; sheet: string }) {
+  // A quoted tab name ('My tab'!A1, quotes doubled) or the first tab.
+  const range = channel.sheet.trim() ? `'${channel.sheet.trim().replace(/'/g, "''")}'!A1` : "A1";
+  const id = encodeURIComponent(spreadsheetId(channel.spreadsheetId));
+  const params = new URLSearchParams({ valueInputOption: "USER_ENTERED", insertDataOption: "INSERT_ROWS" });
+  return `https://sheets.googleapis.com/v4/spreadsheets/${id}/values/${encodeURIComponent(range)}:append?${params.toString()}`;
+}
+
+// One row per run. Run columns (timestamp in UTC, job, title, output, run_id) come first; any other name is looked up
+// in the structured output. Text starting with "=" is quoted so model output never becomes a formula.
+function sheetsRow(columns: string, title: string, body: string, now: Date, meta?: Record<string, unknown>) {
+  const names = columns
+    .split(",")
+    .map((name) => name.trim())
+    .filter(Boolean);
+  const fields = parseStructuredOutput(body) ?? {};
+  const run: Record<string, unknown> = {
+    timestamp: now.toISOString().slice(0, 19).replace("T", " "),
+    job: typeof meta?.jobName === "string" ? meta.jobName : title,
+    title,
+    output: body,
+    run_id: typeof meta?.runHistoryId === "string" ? meta.runHistoryId : "",
+  };
+  return (names.length ? names : SHEETS_DEFAULT_COLUMNS).map((name) => {
+    const value = name in run ? run[name] : fields[name];
+    if (typeof value === "number" || typeof value === "boolean") {
+      return value;
+    }
+    const text = value == null ? "" : typeof value === "string" ? value : JSON.stringify(value);
+    return (text.startsWith("=") ? `'${text}` : text).slice(0, SHEETS_CELL_MAX);
+  });
+}
+
+// The create-issue body (REST API v2, so the description is wiki markup). The summary is the rendered template's
+// first line, falling back to the run title and then the job name.
+function jiraIssuePayload(
+  channel: { projectKey: string; issueType: string; summaryTemplate: string; labels: string },
+  title: string,
+  description: string,
+  vars: Record<string, string>,
+) {
+  const rendered = channel.summaryTemplate.trim() ? renderTemplate(channel.summaryTemplate, vars) : "";
+  const summary = [rendered, title, vars.jobName ?? "", "Promptloop run"].map((s) => s.split("\n")[0].trim()).find(Boolean) ?? "";
+  const wiki = toJiraWiki(description);
+  const labels = channel.labels
+    .split(",")
+    .map((label) => label.trim())
+    .filter(Boolean);
+  return {
+    fields: {
+      project: { key: channel.projectKey },
+      issuetype: { name: channel.issueType.trim() || "Task" },
+      summary: summary.slice(0, JIRA_SUMMARY_MAX),
+      description: wiki.length > JIRA_DESCRIPTION_MAX ? `${wiki.slice(0, JIRA_DESCRIPTION_MAX)}\n\n[output truncated]` : wiki,
+      ...(labels.length ? { labels } : {}),
+    },
+  };
+}
+
+// Jira explains a rejected issue in errorMessages and per-field errors, e.g. {"issuetype": "Specify a valid issue type"}.
+function jiraErrorDetail(data: unknown) {
+  if (!isRecord(data)) return "";
+  const messages = Array.isArray(data.errorMessages) ? data.errorMessages.filter((m): m is string => typeof m === "string") : [];
+  const fields = isRecord(data.errors) ? Object.entries(data.errors).map(([field, message]) => `${field}: ${String(message)}`) : [];
+  return [...messages, ...fields].join("; ");
+}
+
+// References left after resolveChannelSecrets name a secret the owner does not have (or was deleted).
+function unresolvedSecretRef(headers: Record<string, string>) {
+  for (const value of Object.values(headers)) {
+    const match = typeof value === "string" ? /{{\s*secret:\s*([A-Za-z0-9_-]+)\s*}}/.exec(value) : null;
+    if (match) {
+      return match[1];
+    }
+  }
+  return null;
+}
+
+// Deliveries do not follow redirects: the destination policy only checked the configured URL, and a redirect could
+// send the message (and its headers) to a private or denied address. A 3xx fails the delivery without a retry.
+async function deliveryFetch(url: string, init: RequestInit) {
+  const res = await fetch(url, { ...init, redirect: "manual" });
+  if (res.status >= 300 && res.status < 400) {
+    // Only the origin goes into the error: the rest of the target may carry a token.
+    const location = res.headers.get("location");
+    let target: string | null = null;
+    try {
+      target = location ? new URL(location, url).origin : null;
+    } catch {
+      target = null;
+    }
+    await res.body?.cancel().catch(() => undefined);
+    throw new ChannelRequestError(`Delivery redirected${target ? ` to ${target}` : ""}; redirects are not followed, use the final URL`, res.status);
+  }
+  return res;
 }
 
-async function postJsonWithRetry(url: string, headers: Record<string, string>, payload: unknown): Promise<void> {
+async function postJsonWithRetry(url: string, headers: Record<string, string>, payload: unknown): Promise<Response> {
   const maxRetries = envInt("CHANNEL_DISCORD_429_MAX_RETRIES", 3, 0, 10);
   let attempt = 0;
   while (true) {
-    const res = await fetch(url, {
-      method: "POST",
-      headers: { "Content-Type": "application/json", ...headers },
-      body: JSON.stringify(payload),
-    });
-    if (res.ok) return;
+    const res =
+      (await chaosChannelResponse()) ??
+      (await deliveryFetch(url, {
+        method: "POST",
+        headers: { "Content-Type": "application/json", ...headers },
+        body: JSON.stringify(payload),
+      }));
+    if (res.ok) return res;
 
-    if (res.status === 429 && attempt < maxRetries) {
-      attempt++;
-      const retryMs = (await retryAfterMsFromResponse(res)) ?? 1000;
-      await sleep(Math.min(Math.max(retryMs, 250), 10_000));
-      continue;
+    if (res.status === 429) {
+      const retryMs = Math.max((await retryAfterMsFromResponse(res)) ?? 1000, 250);
+      if (attempt < maxRetries && retryMs <= retryAfterMaxMs()) {
+        attempt++;
+        await sleep(retryMs);
+        continue;
+      }
+      throw new ChannelRequestError(`Webhook failed: ${res.status}`, res.status, 0, retryMs);
     }
 
     throw new ChannelRequestError(`Webhook failed: ${res.status}`, res.status);
   }
 }
 
+// Picks where to cut text that is longer than max, preferring (within the second half of the window) a paragraph
+// break, then a line break, then the end of a sentence, then a space. A hard cut never lands inside a URL
+// when the URL can move to the next chunk whole.
 function findSplitIndex(text: string, max: number): number {
   if (text.length <= max) {
     return text.length;
@@ -114,40 +614,35 @@ function findSplitIndex(text: string, max: number): number {
 
   const within = text.slice(0, max);
   const min = Math.max(1, Math.floor(max * 0.5));
+  const paragraph = within.lastIndexOf("\n\n");
+  if (paragraph >= min) {
+    return paragraph;
+  }
+
   const newline = within.lastIndexOf("\n");
   if (newline >= min) {
     return newline;
   }
 
+  let sentence = -1;
+  for (const match of within.matchAll(/[.!?]["')\]]*(?= )/g)) {
+    sentence = (match.index ?? 0) + match[0].length;
+  }
+  if (sentence >= min) {
+    return sentence;
+  }
+
   const space = within.lastIndexOf(" ");
   if (space >= min) {
     return space;
   }
 
-  return max;
-}
-
-function chunkPlainText(text: string, max: number) {
-  const chunks: string[] = [];
-  let value = text;
-
-  while (value.length > max) {
-    const split = findSplitIndex(value, max);
-    const part = value.slice(0, split).trimEnd();
-    if (part.length) {
-      chunks.push(part);
-    }
-    value = value.slice(split);
-    if (value.startsWith("\n")) {
-      value = value.slice(1);
-    }
+  const url = /https?:\/\/\S*$/.exec(within);
+  if (url && url.index > 0 && !/\s/.test(text[max] ?? " ")) {
+    return url.index;
   }
 
-  const tail = value.trim();
-  if (tail.length) {
-    chunks.push(tail);
-  }
-  return chunks;
+  return max;
 }
 
 function updateCodeFenceState(openFenceLang: string | null, text: string): string | null {
@@ -167,7 +662,9 @@ function updateCodeFenceState(openFenceLang: string | null, text: string): strin
   return state;
 }
 
-function chunkDiscordContent(text: string, max: number) {
+// Splits text into chunks of at most max characters at natural boundaries (see findSplitIndex). A code fence
+// that spans a cut is closed at the end of one chunk and reopened, with its language, at the start of the next.
+function chunkFencedText(text: string, max: number) {
   const chunks: string[] = [];
   let remaining = text;
   let openFenceLang: string | null = null;
@@ -186,7 +683,7 @@ function chunkDiscordContent(text: string, max: number) {
     const initialSplit = findSplitIndex(remaining, available);
     let body = remaining.slice(0, initialSplit);
     let nextRemaining = remaining.slice(initialSplit);
-    if (nextRemaining.startsWith("\n")) {
+    if (nextRemaining.startsWith("\n") || nextRemaining.startsWith(" ")) {
       nextRemaining = nextRemaining.slice(1);
     }
 
@@ -215,13 +712,200 @@ function chunkDiscordContent(text: string, max: number) {
   return chunks.length ? chunks : [text.slice(0, max)];
 }
 
+function payloadTemplateVars(title: string, body: string, content: string, meta?: Record<string, unknown>) {
+  const vars: Record<string, string> = {};
+  for (const [k, v] of Object.entries(meta ?? {})) {
+    if (typeof v === "string" || typeof v === "number" || typeof v === "boolean") {
+      vars[k] = String(v);
+    } else if (Array.isArray(v) && v.every((item) => typeof item === "string")) {
+      vars[k] = v.join(",");
+    }
+  }
+  return { ...vars, title, body, content };
+}
+
+function homeAssistantServiceUrl(baseUrl: string, service: string) {
+  const base = baseUrl.trim().replace(/\/+$/, "");
+  const name = service.trim().replace(/^notify\./, "");
+  return `${base}/api/services/notify/${encodeURIComponent(name)}`;
+}
+
+function elasticsearchDocUrl(baseUrl: string, index: string, docId?: string) {
+  const base = baseUrl.trim().replace(/\/+$/, "");
+  const path = `${base}/${encodeURIComponent(index.trim())}/_doc`;
+  return docId ? `${path}/${encodeURIComponent(docId)}` : path;
+}
+
+function elasticsearchAuthHeader(channel: { apiKey: string; username: string; password: string }): Record<string, string> {
+  if (channel.apiKey.trim()) {
+    return { Authorization: `ApiKey ${channel.apiKey.trim()}` };
+  }
+  if (channel.username.trim()) {
+    const basic = Buffer.from(`${channel.username}:${channel.password}`, "utf8").toString("base64");
+    return { Authorization: `Basic ${basic}` };
+  }
+  return {};
+}
+
+function parseStructuredOutput(body: string): Record<string, unknown> | null {
+  const trimmed = body.trim();
+  const fenced = /^```(?:json)?\s*\n([\s\S]*?)\n```$/.exec(trimmed);
+  try {
+    const parsed = JSON.parse(fenced ? fenced[1] : trimmed) as unknown;
+    return parsed && typeof parsed === "object" && !Array.isArray(parsed) ? (parsed as Record<string, unknown>) : null;
+  } catch {
+    return null;
+  }
+}
+
+// One warehouse row per run. When the output is a JSON object (structured output), its top-level
+// fields become columns; the run columns always win so they cannot be overwritten by the model.
+function warehouseRow(title: string, body: string, usedWebSearch: boolean, meta?: Record<string, unknown>) {
+  const str = (v: unknown) => (typeof v === "string" ? v : null);
+  return {
+    ...(parseStructuredOutput(body) ?? {}),
+    run_id: str(meta?.runHistoryId),
+    job_id: str(meta?.jobId),
+    scheduled_for: str(meta?.scheduledFor),
+    inserted_at: new Date().toISOString(),
+    title,
+    output: body,
+    used_web_search: usedWebSearch,
+    llm_model: str(meta?.llmModel),
+    tags: Array.isArray(meta?.tags) ? meta.tags : [],
+  };
+}
+
+function clickhouseInsertUrl(baseUrl: string, table: string) {
+  const base = baseUrl.trim().replace(/\/+$/, "");
+  const params = new URLSearchParams({
+    query: `INSERT INTO ${table.trim()} FORMAT JSONEachRow`,
+    input_format_skip_unknown_fields: "1",
+    date_time_input_format: "best_effort",
+  });
+  return `${base}/?${params.toString()}`;
+}
+
+function bigqueryInsertAllUrl(projectId: string, dataset: string, table: string) {
+  return `https://bigquery.googleapis.com/bigquery/v2/projects/${encodeURIComponent(projectId)}/datasets/${encodeURIComponent(
+    dataset,
+  )}/tables/${encodeURIComponent(table)}/insertAll`;
+}
+
+function redisCommand(
+  channel: { mode: "set" | "xadd"; key: string; ttlSeconds: string },
+  title: string,
+  body: string,
+  meta?: Record<string, unknown>,
+): string[] {
+  if (channel.mode === "xadd") {
+    const fields: string[] = ["title", title, "output", body];
+    for (const name of ["runHistoryId", "jobId", "scheduledFor"] as const) {
+      const value = meta?.[name];
+      if (typeof value === "string") fields.push(name, value);
+    }
+    return ["XADD", channel.key, "*", ...fields];
+  }
+  const ttl = Number(channel.ttlSeconds);
+  return Number.isInteger(ttl) && ttl > 0 ? ["SET", channel.key, body, "EX", String(ttl)] : ["SET", channel.key, body];
+}
+
+// Sent on every outgoing channel request so receivers can attribute and filter traffic.
+// Explicit request headers (e.g. custom webhook headers) take precedence.
+function identificationHeaders(userAgent: string | null | undefined, meta?: Record<string, unknown>): Record<string, string> {
+  const headers: Record<string, string> = { "User-Agent": userAgent?.trim() || DEFAULT_USER_AGENT };
+  if (typeof meta?.jobId === "string") {
+    headers["X-Promptloop-Job-Id"] = meta.jobId;
+  }
+  if (typeof meta?.runHistoryId === "string") {
+    headers["X-Promptloop-Run-Id"] = meta.runHistoryId;
+  }
+  return headers;
+}
+
 export const __private__ = {
-  chunkPlainText,
-  chunkDiscordContent,
+  chunkFencedText,
+  findSplitIndex,
+  buildDiscordChunks,
+  buildTelegramChunks,
+  buildPushoverChunks,
+  smsSummary,
+  googleChatUrl,
+  googleChatMessage,
+  awsEndpoint,
+  awsMessageParams,
+  kafkaEnvelope,
+  kafkaProduceRequest,
+  kafkaRecordError,
+  fileChannelPath,
+  fileEntry,
+  notionId,
+  notionBatches,
+  notionRowProperties,
+  sheetsAppendUrl,
+  sheetsRow,
+  jiraIssuePayload,
+  jiraErrorDetail,
   updateCodeFenceState,
+  homeAssistantServiceUrl,
+  elasticsearchDocUrl,
+  discordWebhookUrl,
+  discordThreadName,
+  parseStructuredOutput,
+  warehouseRow,
+  clickhouseInsertUrl,
+  redisCommand,
+  identificationHeaders,
+  payloadTemplateVars,
+  destinationUrl,
 };
 
+// The user-supplied URL a channel sends to; Telegram, BigQuery, Google Sheets, Pushover, Twilio, AWS and Notion only talk to their fixed provider APIs.
+function destinationUrl(channel: SendChannelInput) {
+  switch (channel.type) {
+    case "discord":
+    case "google_chat":
+      return channel.webhookUrl;
+    case "webhook":
+    case "elasticsearch":
+    case "clickhouse":
+    case "nats":
+      return channel.url;
+    case "home_assistant":
+      return channel.baseUrl;
+    case "jira":
+      return channel.siteUrl;
+    case "redis":
+    case "kafka":
+      return channel.restUrl;
+    case "mqtt":
+      return channel.brokerUrl;
+    default:
+      return null;
+  }
+}
+
+// Every endpoint a channel talks to, including the fixed provider APIs (for reachability checks).
+export function channelEndpointUrl(channel: SendChannelInput) {
+  if (channel.type === "telegram") return "https://api.telegram.org";
+  if (channel.type === "bigquery") return "https://bigquery.googleapis.com";
+  if (channel.type === "google_sheets") return "https://sheets.googleapis.com";
+  if (channel.type === "pushover") return "https://api.pushover.net";
+  if (channel.type === "twilio") return "https://api.twilio.com";
+  if (channel.type === "aws") return awsEndpoint(channel).url;
+  if (channel.type === "notion") return "https://api.notion.com";
+  return destinationUrl(channel);
+}
+
 export async function sendChannelMessage(channel: SendChannelInput, title: string, body: string, opts?: SendChannelOptions) {
+  const destination = destinationUrl(channel);
+  if (destination) {
+    const blocked = await checkDestination(destination, opts?.plan);
+    if (blocked) {
+      throw new ChannelRequestError(`Delivery blocked by destination policy: ${blocked}`, 403);
+    }
+  }
+
   const citations = (opts?.citations ?? []).filter((c) => c && typeof c.url === "string" && c.url.length > 0);
   const meta = opts?.meta && typeof opts.meta === "object" && opts.meta !== null && !Array.isArray(opts.meta) ? opts.meta : undefined;
   const sources = citations.length
@@ -230,64 +914,697 @@ export async function sendChannelMessage(channel: SendChannelInput, title: strin
         .map((c) => (c.title ? `- ${c.title}: ${c.url}` : `- ${c.url}`))
         .join("\n")}`
     : "";
+  const attachments = opts?.attachments ?? [];
+  const attachmentList = attachments.length
+    ? `\n\nAttachments:\n${attachments.map((a) => `- ${a.name}: ${a.url}`).join("\n")}`
+    : "";
+
+  // An empty title means the job turned its header off.
+  const text = `${title ? `${title}\n\n` : ""}${body}${sources}${attachmentList}`;
+  const identity = identificationHeaders(opts?.userAgent, meta);
+  const record = (rendered: string) => opts?.onRendered?.(rendered);
+  const request = async (url: string, init: RequestInit) => {
+    if (typeof init.body === "string") {
+      record(init.body);
+    }
+    return (
+      (await chaosChannelResponse()) ??
+      deliveryFetch(url, { ...init, headers: { ...identity, ...((init.headers as Record<string, string> | undefined) ?? {}) } })
+    );
+  };
+  const postJson = (url: string, headers: Record<string, string>, payload: unknown) => {
+    record(JSON.stringify(payload));
+    return postJsonWithRetry(url, headers, payload);
+  };
 
-  const text = `${title}\n\n${body}${sources}`;
+  const fallbackChars = fileFallbackChars();
+  // A per-job full-output link takes precedence over the deployment-wide file fallback.
+  const sendAsFile =
+    (channel.type === "discord" || channel.type === "telegram") && !opts?.fullOutputUrl && fallbackChars > 0 && text.length > fallbackChars;
+  const summary = sendAsFile ? fileFallbackSummary(title, body) : "";
+  const outputFile = () => new Blob([text], { type: "text/markdown" });
 
   if (channel.type === "discord") {
-    for (const chunk of buildDiscordChunks(text)) {
-      try {
-        await postJsonWithRetry(channel.webhookUrl, {}, { content: chunk });
-      } catch (err) {
-        if (err instanceof ChannelRequestError) {
-          throw new ChannelRequestError(`Discord webhook failed: ${err.status}`, err.status);
-        }
-        throw err;
+    const sendPart = partSender(opts);
+    // With a thread name, the first message sent starts a forum post and the rest of the run goes into it (a resumed
+    // delivery starts its own post). Text channels reject thread_name; use threadId there.
+    let threadId = channel.threadId?.trim() ?? "";
+    let threadName = channel.threadName?.trim()
+      ? discordThreadName(channel.threadName, payloadTemplateVars(title, body, text, meta), title)
+      : "";
+    const target = () => discordWebhookUrl(channel.webhookUrl, threadId, Boolean(threadName));
+    const threadFields = () => (threadName ? { thread_name: threadName } : {});
+    const opened = async (res: Response) => {
+      if (!threadName) {
+        return;
       }
+      const created = (await res.clone().json().catch(() => null)) as { channel_id?: unknown } | null;
+      if (typeof created?.channel_id === "string") {
+        threadId = created.channel_id;
+      }
+      threadName = "";
+    };
+    if (sendAsFile) {
+      await sendPart(async () => {
+        const form = new FormData();
+        form.append("payload_json", JSON.stringify({ content: summary, ...threadFields() }));
+        form.append("files[0]", outputFile(), OUTPUT_FILE_NAME);
+        record(JSON.stringify({ content: summary, ...threadFields(), file: { name: OUTPUT_FILE_NAME, chars: text.length } }));
+        const res = await request(target(), { method: "POST", body: form });
+        if (!res.ok) {
+          throw await responseError(`Discord webhook failed: ${res.status}`, res);
+        }
+        await opened(res);
+      });
+    }
+    for (const chunk of sendAsFile ? [] : buildDiscordChunks(tablesToCodeBlocks(text), opts?.fullOutputUrl)) {
+      await sendPart(async () => {
+        try {
+          await opened(await postJson(target(), identity, { content: chunk, ...threadFields() }));
+        } catch (err) {
+          if (err instanceof ChannelRequestError) {
+            throw new ChannelRequestError(`Discord webhook failed: ${err.status}`, err.status, 0, err.retryAfterMs);
+          }
+          throw err;
+        }
+      });
+    }
+    const images = attachments.filter((a) => a.mediaType.startsWith("image/")).slice(0, 10);
+    if (images.length) {
+      await sendPart(async () =>
+        opened(
+          await postJson(target(), identity, {
+            embeds: images.map((a) => ({ title: a.name, url: a.url, image: { url: a.url } })),
+            ...threadFields(),
+          }),
+        ),
+      );
+    }
+    for (const audio of attachments.filter((a) => a.mediaType.startsWith("audio/") && a.content)) {
+      await sendPart(async () => {
+        const form = new FormData();
+        form.append("payload_json", JSON.stringify({ content: "", ...threadFields() }));
+        form.append("files[0]", new Blob([new Uint8Array(audio.content as Uint8Array)], { type: audio.mediaType }), audio.name);
+        record(JSON.stringify({ file: { name: audio.name, size_bytes: audio.content?.byteLength } }));
+        const res = await request(target(), { method: "POST", body: form });
+        if (!res.ok) {
+          throw await responseError(`Discord webhook failed: ${res.status}`, res);
+        }
+        await opened(res);
+      });
     }
     return;
   }
 
   if (channel.type === "webhook") {
+    const templateMeta = attachments.length ? { ...meta, attachments: attachments.map((a) => a.url) } : meta;
+    const templateVars = payloadTemplateVars(title, body, text, templateMeta);
     const headers = channel.headers.trim() ? JSON.parse(channel.headers) : {};
+    const unresolved = unresolvedSecretRef(headers as Record<string, string>);
+    if (unresolved) {
+      throw new ChannelRequestError(`Webhook header references unknown secret "${unresolved}"`, 400);
+    }
+    if (channel.insecureSkipVerify && !insecureTlsAllowed()) {
+      throw new ChannelRequestError("Skipping TLS verification is not allowed on this deployment (WEBHOOK_ALLOW_INSECURE_TLS)", 400);
+    }
+    if (channel.proxyUrl?.trim()) {
+      const blocked = await checkDestination(channel.proxyUrl.trim(), opts?.plan);
+      if (blocked) {
+        throw new ChannelRequestError(`Proxy blocked by destination policy: ${blocked}`, 403);
+      }
+    }
+    // Proxy and TLS options need the node:http transport; everything else goes through fetch.
+    const send = async (init: RequestInit) => {
+      if (!usesCustomTransport(channel)) {
+        return request(channel.url, init);
+      }
+      if (typeof init.body === "string") {
+        record(init.body);
+      }
+      return (
+        (await chaosChannelResponse()) ??
+        transportFetch(
+          channel.url,
+          {
+            method: init.method ?? "POST",
+            headers: { ...identity, ...((init.headers as Record<string, string> | undefined) ?? {}) },
+            body: init.body as string | Uint8Array | undefined,
+          },
+          channel,
+        )
+      );
+    };
+    const gzipMinBytes = envInt("CHANNEL_WEBHOOK_GZIP_MIN_BYTES", 1024, 0, 10 * 1024 * 1024);
+    const sendWebhook = async (method: string, contentType: string, payloadText?: string) => {
+      const baseHeaders: Record<string, string> = { "Content-Type": contentType, ...(headers as Record<string, string>) };
+      if (channel.signingSecret) {
+        const timestamp = Math.floor(Date.now() / 1000);
+        baseHeaders["X-Promptloop-Timestamp"] = String(timestamp);
+        baseHeaders["X-Promptloop-Signature"] = webhookSignature(channel.signingSecret, timestamp, payloadText ?? "");
+      }
+      if (payloadText != null && channel.gzip && Buffer.byteLength(payloadText, "utf8") >= gzipMinBytes) {
+        record(payloadText);
+        const res = await send({
+          method,
+          headers: { ...baseHeaders, "Content-Encoding": "gzip" },
+          body: new Uint8Array(gzipSync(payloadText)),
+        });
+        // Receivers that cannot decode gzip answer 415; resend that one uncompressed.
+        if (res.status !== 415) {
+          return res;
+        }
+      }
+      return send({ method, headers: baseHeaders, body: payloadText });
+    };
+    const capture = async (res: Response) => {
+      if (channel.captureResponse && opts?.onResponse) {
+        opts.onResponse({ status: res.status, body: await res.clone().text().catch(() => "") });
+      }
+    };
+
+    if (channel.graphqlQuery?.trim()) {
+      // GraphQL mode: the payload template becomes the operation variables.
+      const variables = channel.payload.trim()
+        ? renderWebhookPayload(JSON.parse(channel.payload), templateVars)
+        : { title, body, content: text };
+      const res = await sendWebhook("POST", "application/json", JSON.stringify({ query: channel.graphqlQuery, variables }));
+      await capture(res);
+      if (!res.ok) {
+        throw new ChannelRequestError(`GraphQL webhook failed: ${res.status}`, res.status);
+      }
+      const data = (await res.json().catch(() => null)) as { errors?: Array<{ message?: unknown }> } | null;
+      if (data?.errors?.length) {
+        const first = typeof data.errors[0]?.message === "string" ? data.errors[0].message : "unknown error";
+        throw new ChannelRequestError(`GraphQL webhook failed: ${first}`, 400);
+      }
+      return;
+    }
+
+    if (channel.bodyFormat === "xml") {
+      const res = await sendWebhook(
+        channel.method,
+        "text/xml; charset=utf-8",
+        channel.method === "GET" ? undefined : renderXmlTemplate(channel.payload, templateVars),
+      );
+      await capture(res);
+      if (!res.ok) {
+        throw new ChannelRequestError(`Webhook failed: ${res.status}`, res.status);
+      }
+      return;
+    }
+
     const payload = channel.payload.trim()
-      ? JSON.parse(channel.payload)
-      : { title, body, content: text, usedWebSearch: opts?.usedWebSearch ?? false, citations, meta };
+      ? renderWebhookPayload(JSON.parse(channel.payload), templateVars)
+      : { title, body, content: text, usedWebSearch: opts?.usedWebSearch ?? false, citations, attachments, meta };
 
     if (channel.method === "POST" && DISCORD_WEBHOOK_URL_RE.test(channel.url)) {
       const obj = payload && typeof payload === "object" ? (payload as Record<string, unknown>) : null;
       const content = obj && typeof obj.content === "string" ? obj.content : null;
       if (content) {
-        const extraHeaders = headers as Record<string, string>;
-        for (const chunk of buildDiscordChunks(content)) {
-          await postJsonWithRetry(channel.url, extraHeaders, { ...obj, content: chunk });
+        const extraHeaders = { ...identity, ...(headers as Record<string, string>) };
+        const sendPart = partSender(opts);
+        for (const chunk of buildDiscordChunks(tablesToCodeBlocks(content), opts?.fullOutputUrl)) {
+          await sendPart(() => postJson(channel.url, extraHeaders, { ...obj, content: chunk }));
         }
         return;
       }
     }
 
-    const res = await fetch(channel.url, {
-      method: channel.method,
+    const res = await sendWebhook(channel.method, "application/json", channel.method === "GET" ? undefined : JSON.stringify(payload));
+    await capture(res);
+    if (!res.ok) {
+      throw new ChannelRequestError(`Webhook failed: ${res.status}`, res.status);
+    }
+    return;
+  }
+
+  if (channel.type === "home_assistant") {
+    const res = await request(homeAssistantServiceUrl(channel.baseUrl, channel.service), {
+      method: "POST",
       headers: {
         "Content-Type": "application/json",
-        ...(headers as Record<string, string>),
+        Authorization: `Bearer ${channel.token}`,
       },
-      body: channel.method === "GET" ? undefined : JSON.stringify(payload),
+      body: JSON.stringify({ title, message: `${body}${sources}${attachmentList}` }),
     });
     if (!res.ok) {
-      throw new ChannelRequestError(`Webhook failed: ${res.status}`, res.status);
+      throw new ChannelRequestError(`Home Assistant notify failed: ${res.status}`, res.status);
     }
     return;
   }
 
-  const url = `https://api.telegram.org/bot${channel.botToken}/sendMessage`;
-  for (const chunk of chunkPlainText(text, TELEGRAM_MAX)) {
-    const res = await fetch(url, {
+  if (channel.type === "elasticsearch") {
+    // Using the run id as document id keeps delivery retries idempotent.
+    const docId = typeof meta?.runHistoryId === "string" ? meta.runHistoryId : undefined;
+    const res = await request(elasticsearchDocUrl(channel.url, channel.index, docId), {
+      method: docId ? "PUT" : "POST",
+      headers: { "Content-Type": "application/json", ...elasticsearchAuthHeader(channel) },
+      body: JSON.stringify({
+        "@timestamp": new Date().toISOString(),
+        title,
+        output: body,
+        usedWebSearch: opts?.usedWebSearch ?? false,
+        citations,
+        attachments,
+        ...(meta ?? {}),
+      }),
+    });
+    if (!res.ok) {
+      throw new ChannelRequestError(`Elasticsearch index failed: ${res.status}`, res.status);
+    }
+    return;
+  }
+
+  if (channel.type === "clickhouse") {
+    const res = await request(clickhouseInsertUrl(channel.url, channel.table), {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/x-ndjson",
+        ...(channel.username.trim() ? { "X-ClickHouse-User": channel.username, "X-ClickHouse-Key": channel.password } : {}),
+      },
+      body: `${JSON.stringify(warehouseRow(title, body, opts?.usedWebSearch ?? false, meta))}\n`,
+    });
+    if (!res.ok) {
+      throw new ChannelRequestError(`ClickHouse insert failed: ${res.status}`, res.status);
+    }
+    return;
+  }
+
+  if (channel.type === "bigquery") {
+    const accessToken = await getGoogleAccessToken(channel.serviceAccountJson, "https://www.googleapis.com/auth/bigquery.insertdata");
+    const row = warehouseRow(title, body, opts?.usedWebSearch ?? false, meta);
+    const res = await request(bigqueryInsertAllUrl(channel.projectId, channel.dataset, channel.table), {
+      method: "POST",
+      headers: { "Content-Type": "application/json", Authorization: `Bearer ${accessToken}` },
+      // insertId lets BigQuery de-duplicate retried deliveries of the same run.
+      body: JSON.stringify({ ignoreUnknownValues: true, rows: [{ insertId: row.run_id ?? undefined, json: row }] }),
+    });
+    if (!res.ok) {
+      throw new ChannelRequestError(`BigQuery insert failed: ${res.status}`, res.status);
+    }
+    const data = (await res.json().catch(() => null)) as { insertErrors?: unknown[] } | null;
+    if (data?.insertErrors?.length) {
+      throw new ChannelRequestError("BigQuery insert failed: row rejected", 400);
+    }
+    return;
+  }
+
+  if (channel.type === "google_sheets") {
+    const accessToken = await getGoogleAccessToken(channel.serviceAccountJson, "https://www.googleapis.com/auth/spreadsheets");
+    const res = await request(sheetsAppendUrl(channel), {
       method: "POST",
-      headers: { "Content-Type": "application/json" },
-      body: JSON.stringify({ chat_id: channel.chatId, text: chunk }),
+      headers: { "Content-Type": "application/json", Authorization: `Bearer ${accessToken}` },
+      body: JSON.stringify({ values: [sheetsRow(channel.columns, title, body, clock().now(), meta)] }),
     });
     if (!res.ok) {
-      throw new ChannelRequestError(`Telegram sendMessage failed: ${res.status}`, res.status);
+      throw await responseError(`Google Sheets append failed: ${res.status}`, res);
     }
+    return;
+  }
+
+  if (channel.type === "redis") {
+    // Redis REST endpoints (Upstash-compatible) accept a command as a JSON array.
+    const res = await request(channel.restUrl.trim().replace(/\/+$/, ""), {
+      method: "POST",
+      headers: { "Content-Type": "application/json", Authorization: `Bearer ${channel.token}` },
+      body: JSON.stringify(redisCommand(channel, title, body, meta)),
+    });
+    if (!res.ok) {
+      throw new ChannelRequestError(`Redis ${channel.mode.toUpperCase()} failed: ${res.status}`, res.status);
+    }
+    return;
+  }
+
+  if (channel.type === "pushover") {
+    // The header goes in Pushover's title field; the message is split at its 1024-character limit. The form body
+    // carries the credentials, so only the message fields are recorded.
+    const sendPart = partSender(opts);
+    for (const chunk of buildPushoverChunks(`${body}${sources}${attachmentList}`, opts?.fullOutputUrl)) {
+      await sendPart(async () => {
+        const fields: Record<string, string> = {
+          message: chunk,
+          ...(title ? { title: title.slice(0, PUSHOVER_TITLE_MAX) } : {}),
+          ...(channel.priority ? { priority: channel.priority } : {}),
+          ...(channel.sound ? { sound: channel.sound } : {}),
+        };
+        record(JSON.stringify(fields));
+        const res = await request(PUSHOVER_MESSAGES_URL, {
+          method: "POST",
+          body: new URLSearchParams({ token: channel.appToken, user: channel.userKey, ...fields }),
+        });
+        if (!res.ok) {
+          throw await responseError(`Pushover send failed: ${res.status}`, res);
+        }
+      });
+    }
+    return;
+  }
+
+  if (channel.type === "google_chat") {
+    // Parts of one run share a thread (the configured key, else the run id) so they stay together.
+    const threadKey = channel.threadKey.trim() || (typeof meta?.runHistoryId === "string" ? meta.runHistoryId : "");
+    const url = googleChatUrl(channel.webhookUrl, threadKey);
+    const sendPart = partSender(opts);
+    const parts = buildMessageChunks(`${body}${sources}${attachmentList}`, GOOGLE_CHAT_MAX, opts?.fullOutputUrl);
+    for (const [index, part] of parts.entries()) {
+      await sendPart(async () => {
+        const res = await request(url, {
+          method: "POST",
+          headers: { "Content-Type": "application/json; charset=UTF-8" },
+          body: JSON.stringify(googleChatMessage(channel.format, title, part, index, threadKey)),
+        });
+        if (!res.ok) {
+          throw await responseError(`Google Chat webhook failed: ${res.status}`, res);
+        }
+      });
+    }
+    return;
+  }
+
+  if (channel.type === "aws") {
+    // The message is the webhook channel's default JSON payload.
+    const message = JSON.stringify({ title, body, content: text, usedWebSearch: opts?.usedWebSearch ?? false, citations, attachments, meta });
+    if (Buffer.byteLength(message, "utf8") > AWS_MESSAGE_MAX_BYTES) {
+      throw new ChannelRequestError("AWS message exceeds 256 KB", 413);
+    }
+    if (!channel.accessKeyId.trim() && !awsWebIdentityEnabled()) {
+      throw new ChannelRequestError("AWS access key is required (web identity credentials are not enabled on this deployment)", 401);
+    }
+    const endpoint = awsEndpoint(channel);
+    const credentials = await resolveAwsCredentials(channel, endpoint.region);
+    const form = new URLSearchParams(awsMessageParams(channel, message, meta)).toString();
+    const headers = signAwsRequest({
+      method: "POST",
+      url: endpoint.url,
+      region: endpoint.region,
+      service: channel.service,
+      headers: { "content-type": "application/x-www-form-urlencoded; charset=utf-8" },
+      body: form,
+      credentials,
+    });
+    record(message);
+    // The body is sent as bytes so it is exactly what was signed (and the form is not recorded a second time).
+    const res = await request(endpoint.url, { method: "POST", headers, body: new TextEncoder().encode(form) });
+    if (!res.ok) {
+      throw await responseError(`AWS ${channel.service === "sns" ? "SNS publish" : "SQS send"} failed: ${res.status}`, res);
+    }
+    return;
+  }
+
+  if (channel.type === "kafka") {
+    const envelope = kafkaEnvelope(title, body, meta);
+    const produce = kafkaProduceRequest(channel, envelope.job_id, envelope);
+    const res = await request(produce.url, {
+      method: "POST",
+      headers: {
+        "Content-Type": produce.contentType,
+        ...(channel.username.trim()
+          ? { Authorization: `Basic ${Buffer.from(`${channel.username}:${channel.password}`, "utf8").toString("base64")}` }
+          : {}),
+      },
+      body: JSON.stringify(produce.body),
+    });
+    if (!res.ok) {
+      throw await responseError(`Kafka produce failed: ${res.status}`, res);
+    }
+    const recordError = kafkaRecordError(await res.json().catch(() => null));
+    if (recordError) {
+      throw new ChannelRequestError(`Kafka produce failed: ${recordError}`, 502);
+    }
+    return;
+  }
+
+  if (channel.type === "nats") {
+    // Same JSON payload as the webhook channel's default; a failed publish with a status is not worth retrying.
+    const payload = JSON.stringify({ title, body, content: text, usedWebSearch: opts?.usedWebSearch ?? false, citations, attachments, meta });
+    record(payload);
+    try {
+      await natsPublish({
+        url: channel.url,
+        subject: channel.subject,
+        payload,
+        username: channel.username,
+        password: channel.password,
+        token: channel.token,
+        jetstream: channel.mode === "jetstream",
+      });
+    } catch (err) {
+      if (err instanceof NatsError && err.status) {
+        throw new ChannelRequestError(`NATS publish failed: ${err.message}`, err.status);
+      }
+      throw err;
+    }
+    return;
+  }
+
+  if (channel.type === "mqtt") {
+    const payload =
+      channel.format === "text"
+        ? text
+        : JSON.stringify({ title, body, content: text, usedWebSearch: opts?.usedWebSearch ?? false, citations, attachments, meta });
+    record(payload);
+    try {
+      await mqttPublish({
+        url: channel.brokerUrl,
+        topic: channel.topic,
+        payload,
+        qos: Number(channel.qos) as MqttQos,
+        retain: channel.retain === "on",
+        username: channel.username,
+        password: channel.password,
+      });
+    } catch (err) {
+      if (err instanceof MqttError && err.status) {
+        throw new ChannelRequestError(`MQTT publish failed: ${err.message}`, err.status);
+      }
+      throw err;
+    }
+    return;
+  }
+
+  if (channel.type === "file") {
+    const dir = fileChannelDir();
+    if (!dir) {
+      throw new ChannelRequestError("File channel is disabled on this deployment (set CHANNEL_FILE_DIR)", 403);
+    }
+    const entry = fileEntry(text, clock().now(), meta);
+    record(entry);
+    if (channel.target === "stdout") {
+      process.stdout.write(entry);
+      return;
+    }
+    const path = fileChannelPath(dir, channel.path);
+    if (!path) {
+      throw new ChannelRequestError("File path must stay inside CHANNEL_FILE_DIR", 400);
+    }
+    await mkdir(dirname(path), { recursive: true });
+    await appendFile(path, entry, "utf8");
+    return;
+  }
+
+  if (channel.type === "notion") {
+    const parentId = notionId(channel.parentId);
+    if (!parentId) {
+      throw new ChannelRequestError("Notion page or database id is invalid", 400);
+    }
+    const headers = { Authorization: `Bearer ${channel.token}`, "Notion-Version": NOTION_VERSION, "Content-Type": "application/json" };
+    const day = clock().now().toISOString().slice(0, 10);
+    const blocks = toNotionBlocks(`${body}${sources}${attachmentList}`);
+
+    if (channel.target === "database") {
+      // One row per run; blocks past the first request are appended to the new row.
+      const name = typeof meta?.jobName === "string" && meta.jobName ? `${meta.jobName} · ${day}` : title || day;
+      const [first, ...rest] = notionBatches(blocks);
+      const res = await request(`${NOTION_API_URL}/pages`, {
+        method: "POST",
+        headers,
+        body: JSON.stringify({
+          parent: { database_id: parentId },
+          properties: notionRowProperties(channel.titleProperty, name),
+          children: first,
+        }),
+      });
+      if (!res.ok) {
+        throw await responseError(`Notion request failed: ${res.status}`, res);
+      }
+      const page = (await res.json().catch(() => null)) as { id?: unknown } | null;
+      const rowId = typeof page?.id === "string" ? page.id : null;
+      if (rest.length && !rowId) {
+        throw new ChannelRequestError("Notion did not return the new row's id", 502);
+      }
+      for (const children of rest) {
+        const append = await request(`${NOTION_API_URL}/blocks/${rowId}/children`, {
+          method: "PATCH",
+          headers,
+          body: JSON.stringify({ children }),
+        });
+        if (!append.ok) {
+          throw await responseError(`Notion request failed: ${append.status}`, append);
+        }
+      }
+      return;
+    }
+
+    // Pages get a heading per run, then the output; each request of up to 100 blocks is one resumable part.
+    const heading: NotionBlock = {
+      object: "block",
+      type: "heading_2",
+      heading_2: { rich_text: [{ type: "text", text: { content: (title || day).slice(0, 2000) } }] },
+    };
+    const sendPart = partSender(opts);
+    for (const children of notionBatches([heading, ...blocks])) {
+      await sendPart(async () => {
+        const res = await request(`${NOTION_API_URL}/blocks/${parentId}/children`, {
+          method: "PATCH",
+          headers,
+          body: JSON.stringify({ children }),
+        });
+        if (!res.ok) {
+          throw await responseError(`Notion request failed: ${res.status}`, res);
+        }
+      });
+    }
+    return;
+  }
+
+  if (channel.type === "plugin") {
+    const plugin = channelPlugin(channel.plugin);
+    if (!plugin) {
+      throw new ChannelRequestError(`Channel plugin "${channel.plugin}" is not installed on this deployment`, 400);
+    }
+    const message = {
+      title,
+      body,
+      text,
+      citations,
+      attachments: attachments.map((a) => ({ name: a.name, url: a.url, mediaType: a.mediaType, sizeBytes: a.sizeBytes })),
+      usedWebSearch: !!opts?.usedWebSearch,
+      ...(meta ? { meta } : {}),
+    };
+    record(JSON.stringify(message));
+    try {
+      await plugin.send(parsePluginSettings(channel.settings) ?? {}, message);
+    } catch (err) {
+      if (err instanceof ChannelRequestError) {
+        throw err;
+      }
+      throw new ChannelRequestError(`Channel plugin "${channel.plugin}" failed: ${err instanceof Error ? err.message : String(err)}`, 502);
+    }
+    return;
+  }
+
+  if (channel.type === "jira") {
+    const auth = channel.email.trim()
+      ? `Basic ${Buffer.from(`${channel.email.trim()}:${channel.apiToken}`, "utf8").toString("base64")}`
+      : `Bearer ${channel.apiToken}`;
+    const vars = payloadTemplateVars(title, body, text, meta);
+    const res = await request(`${channel.siteUrl.trim().replace(/\/+$/, "")}/rest/api/2/issue`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json", Accept: "application/json", Authorization: auth },
+      body: JSON.stringify(jiraIssuePayload(channel, title, `${body}${sources}${attachmentList}`, vars)),
+    });
+    if (!res.ok) {
+      const detail = res.status === 400 ? jiraErrorDetail(await res.json().catch(() => null)) : "";
+      throw await responseError(`Jira issue create failed: ${res.status}${detail ? ` (${detail})` : ""}`, res);
+    }
+    return;
+  }
+
+  if (channel.type === "twilio") {
+    // Only the message body is recorded; the numbers and credentials stay out of the delivery log.
+    const messages =
+      channel.mode === "full"
+        ? buildMessageChunks(`${title ? `${title}\n\n` : ""}${body}${sources}`, TWILIO_MAX, opts?.fullOutputUrl)
+        : [smsSummary(title, body, opts?.fullOutputUrl)];
+    const url = `https://api.twilio.com/2010-04-01/Accounts/${encodeURIComponent(channel.accountSid)}/Messages.json`;
+    const auth = Buffer.from(`${channel.accountSid}:${channel.authToken}`, "utf8").toString("base64");
+    const sendPart = partSender(opts);
+    for (const message of messages) {
+      await sendPart(async () => {
+        record(JSON.stringify({ Body: message }));
+        const form = new URLSearchParams({ To: channel.to, Body: message });
+        form.set(channel.from.startsWith("MG") ? "MessagingServiceSid" : "From", channel.from);
+        const res = await request(url, { method: "POST", headers: { Authorization: `Basic ${auth}` }, body: form });
+        if (!res.ok) {
+          throw await responseError(`Twilio send failed: ${res.status}`, res);
+        }
+      });
+    }
+    return;
+  }
+
+  const sendPart = partSender(opts);
+  // Forum topic of a supergroup; without it messages go to the chat's General topic.
+  const topic = channel.messageThreadId?.trim() ? { message_thread_id: Number(channel.messageThreadId.trim()) } : {};
+  const telegramForm = () => {
+    const form = new FormData();
+    form.append("chat_id", channel.chatId);
+    if (channel.messageThreadId?.trim()) {
+      form.append("message_thread_id", channel.messageThreadId.trim());
+    }
+    return form;
+  };
+  if (sendAsFile) {
+    await sendPart(async () => {
+      const caption = summary.slice(0, TELEGRAM_CAPTION_MAX);
+      const form = telegramForm();
+      form.append("caption", caption);
+      form.append("document", outputFile(), OUTPUT_FILE_NAME);
+      record(JSON.stringify({ chat_id: channel.chatId, caption, document: { name: OUTPUT_FILE_NAME, chars: text.length } }));
+      const res = await request(`https://api.telegram.org/bot${channel.botToken}/sendDocument`, { method: "POST", body: form });
+      if (!res.ok) {
+        throw await responseError(`Telegram sendDocument failed: ${res.status}`, res);
+      }
+    });
+  }
+  const url = `https://api.telegram.org/bot${channel.botToken}/sendMessage`;
+  const html = telegramHtmlEnabled();
+  for (const chunk of sendAsFile ? [] : buildTelegramChunks(tablesToCodeBlocks(text), opts?.fullOutputUrl)) {
+    await sendPart(async () => {
+      const sendText = (payload: Record<string, unknown>) =>
+        request(url, {
+          method: "POST",
+          headers: { "Content-Type": "application/json" },
+          body: JSON.stringify({ chat_id: channel.chatId, ...topic, ...payload }),
+        });
+      let res = html ? await sendText({ text: toTelegramHtml(chunk), parse_mode: "HTML" }) : await sendText({ text: chunk });
+      // Telegram rejects markup it cannot parse with 400; the part is resent as plain text rather than failing.
+      if (html && res.status === 400) {
+        res = await sendText({ text: chunk });
+      }
+      if (!res.ok) {
+        throw await responseError(`Telegram sendMessage failed: ${res.status}`, res);
+      }
+    });
+  }
+  // Telegram fetches the file from the URL itself, except audio: Ogg/Opus is uploaded as a voice message.
+  for (const attachment of attachments) {
+    if (attachment.mediaType.startsWith("audio/") && attachment.content) {
+      const audio = attachment.content;
+      const voice = attachment.mediaType === "audio/ogg";
+      const method = voice ? "sendVoice" : "sendAudio";
+      await sendPart(async () => {
+        const form = telegramForm();
+        form.append(voice ? "voice" : "audio", new Blob([new Uint8Array(audio)], { type: attachment.mediaType }), attachment.name);
+        record(JSON.stringify({ chat_id: channel.chatId, [voice ? "voice" : "audio"]: { name: attachment.name, size_bytes: audio.byteLength } }));
+        const res = await request(`https://api.telegram.org/bot${channel.botToken}/${method}`, { method: "POST", body: form });
+        if (!res.ok) {
+          throw await responseError(`Telegram ${method} failed: ${res.status}`, res);
+        }
+      });
+      continue;
+    }
+    await sendPart(async () => {
+      const photo = attachment.mediaType.startsWith("image/");
+      const method = photo ? "sendPhoto" : "sendDocument";
+      const res = await request(`https://api.telegram.org/bot${channel.botToken}/${method}`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ chat_id: channel.chatId, ...topic, [photo ? "photo" : "document"]: attachment.url, caption: attachment.name }),
+      });
+      if (!res.ok) {
+        throw await responseError(`Telegram ${method} failed: ${res.status}`, res);
+      }
+    });
   }
 }
@@ -0,0 +1,37 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { chaosBeforeLlm, chaosChannelResponse, chaosConfig, chaosDbFault, parseChaosFaults } from "./chaos";
+
+const config = { rates: { llm_429: 0.5, channel_500: 0.5, slow: 0, db_error: 1 }, slowMs: 0 };
+
+describe("chaos", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("parses fault rates and ignores unknown or invalid entries", () => {
+    expect(parseChaosFaults("llm_429=0.2, slow=0.05,db_error=2,disk=0.5,channel_500")).toEqual({
+      llm_429: 0.2,
+      channel_500: 0,
+      slow: 0.05,
+      db_error: 0,
+    });
+  });
+
+  it("is off unless enabled outside the production environment", () => {
+    vi.stubEnv("CHAOS_FAULTS", "llm_429=1");
+    expect(chaosConfig()).toBeNull();
+    vi.stubEnv("CHAOS_ENABLED", "1");
+    expect(chaosConfig()).toBeNull();
+    vi.stubEnv("WORKER_ENV", "staging");
+    expect(chaosConfig()?.rates.llm_429).toBe(1);
+  });
+
+  it("injects faults at the configured rates", async () => {
+    await expect(chaosBeforeLlm(config, () => 0.4)).rejects.toMatchObject({ status: 429 });
+    await expect(chaosBeforeLlm(config, () => 0.6)).resolves.toBeUndefined();
+    expect((await chaosChannelResponse(config, () => 0.1))?.status).toBe(500);
+    expect(await chaosChannelResponse(config, () => 0.9)).toBeNull();
+    expect(chaosDbFault(config, () => 0.99)).toMatchObject({ status: 503 });
+    expect(chaosDbFault(null)).toBeNull();
+  });
+});
@@ -0,0 +1,79 @@
+import { logger } from "@/lib/logger";
+
+// Fault injection for resilience testing: with CHAOS_ENABLED=1, LLM calls, channel requests and database queries
+// fail or slow down at the rates in CHAOS_FAULTS (e.g. "llm_429=0.2,channel_500=0.1,slow=0.05,db_error=0.01"), so
+// retries, the delivery outbox and outage detection can be exercised without a misbehaving provider. It refuses
+// to run in the production worker environment (WORKER_ENV unset or "production").
+export const CHAOS_FAULTS = ["llm_429", "channel_500", "slow", "db_error"] as const;
+export type ChaosFault = (typeof CHAOS_FAULTS)[number];
+
+export type ChaosConfig = { rates: Record<ChaosFault, number>; slowMs: number };
+
+const DEFAULT_SLOW_MS = 5000;
+
+export class ChaosFaultError extends Error {
+  status: number;
+
+  constructor(message: string, status: number) {
+    super(message);
+    this.name = "ChaosFaultError";
+    this.status = status;
+  }
+}
+
+// Unknown names and rates outside 0-1 are ignored.
+export function parseChaosFaults(value: string | undefined): Record<ChaosFault, number> {
+  const rates = Object.fromEntries(CHAOS_FAULTS.map((fault) => [fault, 0])) as Record<ChaosFault, number>;
+  for (const entry of (value ?? "").split(",")) {
+    const [name, raw] = entry.split("=", 2).map((part) => part.trim());
+    const rate = Number(raw);
+    if (CHAOS_FAULTS.includes(name as ChaosFault) && raw && Number.isFinite(rate) && rate >= 0 && rate <= 1) {
+      rates[name as ChaosFault] = rate;
+    }
+  }
+  return rates;
+}
+
+export function chaosConfig(): ChaosConfig | null {
+  if (process.env.CHAOS_ENABLED !== "1") {
+    return null;
+  }
+  const environment = process.env.WORKER_ENV?.trim() || "production";
+  if (environment === "production") {
+    return null;
+  }
+  const slowMs = Number(process.env.CHAOS_SLOW_MS ?? DEFAULT_SLOW_MS);
+  return { rates: parseChaosFaults(process.env.CHAOS_FAULTS), slowMs: Number.isFinite(slowMs) && slowMs >= 0 ? slowMs : DEFAULT_SLOW_MS };
+}
+
+export function rollFault(fault: ChaosFault, config = chaosConfig(), random = Math.random) {
+  if (!config || config.rates[fault] <= 0 || random() >= config.rates[fault]) {
+    return false;
+  }
+  logger.warn("chaos fault injected", { fault });
+  return true;
+}
+
+async function maybeSlow(config: ChaosConfig | null, random: () => number) {
+  if (config && rollFault("slow", config, random)) {
+    await new Promise((resolve) => setTimeout(resolve, config.slowMs));
+  }
+}
+
+// Called before each LLM call; the error carries status 429 like a provider rate limit.
+export async function chaosBeforeLlm(config = chaosConfig(), random = Math.random) {
+  await maybeSlow(config, random);
+  if (rollFault("llm_429", config, random)) {
+    throw new ChaosFaultError("Injected fault: LLM rate limited (429)", 429);
+  }
+}
+
+// Called before each channel request; a non-null response replaces the real one.
+export async function chaosChannelResponse(config = chaosConfig(), random = Math.random): Promise<Response | null> {
+  await maybeSlow(config, random);
+  return rollFault("channel_500", config, random) ? new Response("Injected fault", { status: 500 }) : null;
+}
+
+export function chaosDbFault(config = chaosConfig(), random = Math.random) {
+  return rollFault("db_error", config, random) ? new ChaosFaultError("Injected fault: database unavailable", 503) : null;
+}
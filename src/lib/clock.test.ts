@@ -0,0 +1,41 @@
+import { afterEach, describe, expect, it } from "vitest";
+import { clock, fakeClock, nowMs, setClock, systemClock } from "./clock";
+import { computeFailureRetryAt, computeNextRunAt } from "./schedule";
+import { nextDeliveryRetryAt } from "./delivery-retry";
+
+describe("clock", () => {
+  let restore = () => {};
+  afterEach(() => {
+    restore();
+  });
+
+  it("fires sleeps and intervals in order as fake time advances", async () => {
+    const fake = fakeClock("2026-07-01T00:00:00Z");
+    const events: string[] = [];
+    void fake.sleep(1500).then(() => events.push(`sleep@${fake.now().toISOString()}`));
+    const timer = fake.setInterval(() => events.push(`tick@${fake.now().getTime() % 10_000}`), 1000);
+
+    await fake.advance(999);
+    expect(events).toEqual([]);
+    await fake.advance(2001);
+    expect(events).toEqual(["tick@1000", "sleep@2026-07-01T00:00:01.500Z", "tick@2000", "tick@3000"]);
+
+    timer.clear();
+    expect(fake.pending()).toBe(0);
+    expect(fake.now().toISOString()).toBe("2026-07-01T00:00:03.000Z");
+  });
+
+  it("drives schedule and retry defaults from the installed clock", () => {
+    restore = setClock(fakeClock("2026-07-01T08:30:00Z"));
+    expect(nowMs()).toBe(Date.parse("2026-07-01T08:30:00Z"));
+    expect(computeNextRunAt({ scheduleType: "daily", scheduleTime: "09:00" }).toISOString()).toBe("2026-07-01T09:00:00.000Z");
+    expect(computeFailureRetryAt(1, { maxRetries: 3, baseSeconds: 60, maxSeconds: 3600 }, null)?.toISOString()).toBe(
+      "2026-07-01T08:32:00.000Z",
+    );
+    expect(nextDeliveryRetryAt("1m,10m", 1)?.toISOString()).toBe("2026-07-01T08:40:00.000Z");
+
+    restore();
+    restore = () => {};
+    expect(clock()).toBe(systemClock);
+  });
+});
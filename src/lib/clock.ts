@@ -0,0 +1,105 @@
+// Time source for scheduling, lock heartbeats, retries and backoff sleeps. Code reads the time through clock()
+// instead of Date.now()/setTimeout so tests can install a fake clock and step through schedules and retries
+// deterministically. Workers correct the system clock by its offset from Postgres now() (db-clock.ts), so next-run
+// times, catch-up windows and the lock expiry checked in the claim queries all follow the database's clock.
+
+export type ClockTimer = { clear(): void };
+
+export type Clock = {
+  now(): Date;
+  sleep(ms: number): Promise<void>;
+  setInterval(fn: () => void, ms: number): ClockTimer;
+};
+
+let offsetMs = 0;
+
+// Milliseconds added to the machine's time by systemClock; set from the database clock.
+export function setClockOffset(ms: number) {
+  offsetMs = Number.isFinite(ms) ? Math.round(ms) : 0;
+}
+
+export function clockOffsetMs() {
+  return offsetMs;
+}
+
+export const systemClock: Clock = {
+  now: () => new Date(Date.now() + offsetMs),
+  sleep: (ms) => new Promise((resolve) => setTimeout(resolve, ms)),
+  setInterval: (fn, ms) => {
+    const timer = setInterval(fn, ms);
+    timer.unref?.();
+    return { clear: () => clearInterval(timer) };
+  },
+};
+
+let current: Clock = systemClock;
+
+export function clock() {
+  return current;
+}
+
+export function nowMs() {
+  return current.now().getTime();
+}
+
+// Installs a clock (tests); the returned function restores the previous one.
+export function setClock(next: Clock) {
+  const previous = current;
+  current = next;
+  return () => {
+    current = previous;
+  };
+}
+
+export type FakeClock = Clock & {
+  // Moves time forward, firing sleeps and intervals that come due in order; awaits pending callbacks in between.
+  advance(ms: number): Promise<void>;
+  // Timers (sleeps and intervals) waiting to fire.
+  pending(): number;
+};
+
+export function fakeClock(start: Date | string = "2026-01-01T00:00:00Z"): FakeClock {
+  let time = new Date(start).getTime();
+  let nextId = 0;
+  const timers = new Map<number, { at: number; fn: () => void; every?: number }>();
+
+  const flush = async () => {
+    for (let i = 0; i < 10; i++) {
+      await Promise.resolve();
+    }
+  };
+
+  return {
+    now: () => new Date(time),
+    sleep: (ms) =>
+      new Promise((resolve) => {
+        timers.set(nextId++, { at: time + Math.max(0, ms), fn: () => resolve() });
+      }),
+    setInterval: (fn, ms) => {
+      const id = nextId++;
+      timers.set(id, { at: time + ms, fn, every: Math.max(1, ms) });
+      return { clear: () => timers.delete(id) };
+    },
+    advance: async (ms) => {
+      const target = time + ms;
+      await flush();
+      while (true) {
+        const due = [...timers.entries()].filter(([, timer]) => timer.at <= target).sort((a, b) => a[1].at - b[1].at || a[0] - b[0])[0];
+        if (!due) {
+          break;
+        }
+        const [id, timer] = due;
+        time = timer.at;
+        if (timer.every) {
+          timer.at += timer.every;
+        } else {
+          timers.delete(id);
+        }
+        timer.fn();
+        await flush();
+      }
+      time = target;
+    },
+    pending: () => timers.size,
+  };
+}
@@ -0,0 +1,141 @@
+import { mkdtempSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { describe, expect, it } from "vitest";
+import { applyConfigFile, configEnv, parseConfigText, parseToml, parseYaml } from "./config-file";
+
+const TOML = `
+# Deployment settings
+[database]
+url = "postgres://app@db/promptloop#main" # comment after a value
+
+[worker]
+environment = "staging"
+concurrency = 4
+
+[retry]
+failureRetries = 5
+
+[providers]
+routingModels = [
+  "gpt-5-nano",
+  "gpt-5-mini",
+]
+pricing.gpt-5-mini = { input = 0.25, output = 2 }
+
+[providers.pricing."gpt-4.1"]
+input = 2
+output = 8
+
+[providers.modelAliases]
+"gpt-4.1" = 'gpt-5\\mini'
+`;
+
+const YAML = `
+worker:
+  environment: staging
+  concurrency: 4
+providers:
+  routingModels:
+  - gpt-5-nano
+  - gpt-5-mini
+  canary:
+    model: gpt-5.2
+    baseModel: gpt-5-mini
+    percent: 10
+channels:
+  chunkNumbering: true
+  destinationPolicy:
+    blockPrivateNetworks: true
+    deny: ["*.internal"]
+    plans:
+      free:
+        allow:
+          - discord.com
+metrics:
+  otlpEndpoint: http://collector:4318
+`;
+
+describe("config file", () => {
+  it("parses TOML with inline tables and quoted dotted keys", () => {
+    expect(parseToml(TOML)).toEqual({
+      database: { url: "postgres://app@db/promptloop#main" },
+      worker: { environment: "staging", concurrency: 4 },
+      retry: { failureRetries: 5 },
+      providers: {
+        routingModels: ["gpt-5-nano", "gpt-5-mini"],
+        pricing: { "gpt-5-mini": { input: 0.25, output: 2 }, "gpt-4.1": { input: 2, output: 8 } },
+        modelAliases: { "gpt-4.1": "gpt-5\\mini" },
+      },
+    });
+    expect(JSON.parse(configEnv(parseConfigText(TOML, "promptloop.toml")).LLM_PRICING_JSON)).toEqual({
+      "gpt-5-mini": { input: 0.25, output: 2 },
+      "gpt-4.1": { input: 2, output: 8 },
+    });
+    expect(parseToml("note = '''it''s'''")).toEqual({ note: "it''s" });
+    expect(() => parseToml("[worker]\nconcurrency = 1\n[worker]")).toThrow("Invalid TOML config");
+  });
+
+  it("parses nested YAML mappings and lists", () => {
+    const parsed = parseYaml(YAML);
+    expect(parsed.providers).toEqual({
+      routingModels: ["gpt-5-nano", "gpt-5-mini"],
+      canary: { model: "gpt-5.2", baseModel: "gpt-5-mini", percent: 10 },
+    });
+    expect(parsed.channels).toEqual({
+      chunkNumbering: true,
+      destinationPolicy: { blockPrivateNetworks: true, deny: ["*.internal"], plans: { free: { allow: ["discord.com"] } } },
+    });
+    expect(() => parseYaml("worker:\n  concurrency: 4\n   environment: x")).toThrow("Invalid YAML config");
+    expect(parseYaml("items:\n  - name: a\n    weight: 2\n  - name: b")).toEqual({ items: [{ name: "a", weight: 2 }, { name: "b" }] });
+    expect(parseYaml('"gpt-4.1": gpt-5')).toEqual({ "gpt-4.1": "gpt-5" });
+  });
+
+  it("reads literal block strings", () => {
+    const parsed = parseYaml("template: |\n  line one\n\n    indented # kept\nnext: |-\n  a\n  b\n\nlast: 1");
+    expect(parsed).toEqual({ template: "line one\n\n  indented # kept\n", next: "a\nb", last: 1 });
+  });
+
+  it("maps the typed config onto env vars", () => {
+    const env = configEnv(parseConfigText(YAML, "promptloop.yaml"));
+    expect(env).toMatchObject({
+      WORKER_ENV: "staging",
+      WORKER_CONCURRENCY: "4",
+      LLM_ROUTING_MODELS: "gpt-5-nano,gpt-5-mini",
+      LLM_CANARY_PERCENT: "10",
+      CHANNEL_CHUNK_NUMBERING: "1",
+      OTEL_EXPORTER_OTLP_ENDPOINT: "http://collector:4318",
+    });
+    expect(JSON.parse(env.DELIVERY_DESTINATION_POLICY)).toEqual({
+      blockPrivateNetworks: true,
+      deny: ["*.internal"],
+      plans: { free: { allow: ["discord.com"] } },
+    });
+  });
+
+  it("rejects unknown settings and unsupported formats", () => {
+    expect(() => parseConfigText("worker:\n  concurency: 4", "promptloop.yml")).toThrow("Invalid config promptloop.yml");
+    expect(() => parseConfigText("{}", "promptloop.ini")).toThrow('Unsupported config file type ".ini"');
+    expect(parseConfigText('{"worker": {"concurrency": 2}}', "promptloop.json")).toEqual({ worker: { concurrency: 2 } });
+    expect(
+      configEnv(parseConfigText('[providers.openrouterProviderPreferences]\norder = ["anthropic"]\nallow_fallbacks = false', "promptloop.toml")),
+    ).toEqual({ OPENROUTER_PROVIDER_PREFERENCES: '{"order":["anthropic"],"allow_fallbacks":false}' });
+    expect(configEnv(parseConfigText("providers:\n  fallbackModels: [gpt-4.1-mini, gpt-5-nano]", "promptloop.yaml"))).toEqual({
+      LLM_FALLBACK_MODELS: "gpt-4.1-mini,gpt-5-nano",
+    });
+    expect(configEnv(parseConfigText("[worker]\nshutdownTimeoutMs = 5000", "promptloop.toml"))).toEqual({ WORKER_SHUTDOWN_TIMEOUT_MS: "5000" });
+  });
+
+  it("lets env vars override the file", () => {
+    const dir = mkdtempSync(join(tmpdir(), "promptloop-config-"));
+    const path = join(dir, "promptloop.yaml");
+    writeFileSync(path, YAML);
+    const env: NodeJS.ProcessEnv = { PROMPTLOOP_CONFIG: path, WORKER_ENV: "production", WORKER_CONCURRENCY: "" };
+    const applied = applyConfigFile(env);
+    expect(env.WORKER_ENV).toBe("production");
+    expect(env.WORKER_CONCURRENCY).toBe("4");
+    expect(applied).toContain("LLM_CANARY_MODEL");
+    expect(applied).not.toContain("WORKER_ENV");
+    expect(applyConfigFile({})).toEqual([]);
+  });
+});
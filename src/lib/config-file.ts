@@ -0,0 +1,291 @@
+import { readFileSync } from "node:fs";
+import { extname } from "node:path";
+import { CORE_SCHEMA, load as loadYaml } from "js-yaml";
+import { parse as parseTomlText } from "smol-toml";
+import { z } from "zod";
+
+// Deployment settings can live in one file (PROMPTLOOP_CONFIG=/etc/promptloop.toml) instead of dozens of env vars.
+// The file is mapped onto the env vars the rest of the code already reads; a variable set in the environment
+// always wins over the file, so secrets and one-off overrides can stay in the environment.
+
+const str = z.string().min(1);
+const int = z.number().int();
+const stringList = z.array(str);
+
+const destinationEntry = z.string().min(1);
+const destinationRules = z
+  .object({ allow: z.array(destinationEntry), deny: z.array(destinationEntry), blockPrivateNetworks: z.boolean() })
+  .partial()
+  .strict();
+
+export const configSchema = z
+  .object({
+    database: z.object({ url: str, directUrl: str }).partial().strict(),
+    providers: z
+      .object({
+        openaiApiKey: str,
+        openaiApiKeys: str,
+        openaiKeyQuarantineMinutes: int.min(1),
+        anthropicApiKey: str,
+        googleApiKey: str,
+        openrouterApiKey: str,
+        openrouterProviderPreferences: z.record(z.string(), z.unknown()),
+        timeoutMs: int.min(1),
+        systemPromptFile: str,
+        systemPromptAddendumFile: str,
+        pricing: z.record(z.string(), z.object({ input: z.number().min(0), output: z.number().min(0) }).strict()),
+        routingModels: stringList,
+        fallbackModels: stringList,
+        routingTagModels: z.record(z.string(), str),
+        modelAliases: z.record(z.string(), str),
+        canary: z.object({ model: str, baseModel: str, percent: z.number().min(0).max(100) }).strict(),
+      })
+      .partial()
+      .strict(),
+    worker: z
+      .object({
+        environment: str,
+        concurrency: int.min(1),
+        claimBatch: int.min(1),
+        claimRetries: int.min(0),
+        maxJobsPerRun: int.min(1),
+        timeBudgetMs: int.min(1),
+        lockStaleMinutes: int.min(1),
+        lockHeartbeatSeconds: int.min(1),
+        catchupGraceMinutes: int.min(0),
+        smoothingWindowSeconds: int.min(0),
+        userRunsPerTick: int.min(0),
+        userRunsPerHour: int.min(0),
+        drainTimeoutMs: int.min(0),
+        shutdownTimeoutMs: int.min(0),
+        dormantWeeks: int.min(0),
+        maintenanceTimeoutSeconds: int.min(10),
+        clockSource: z.enum(["db", "local"]),
+        debugListenAddr: str,
+      })
+      .partial()
+      .strict(),
+    retry: z
+      .object({
+        llmMaxRetries: int.min(1),
+        deliveryMaxRetries: int.min(0),
+        failureRetries: int.min(0),
+        failureBackoffSeconds: int.min(1),
+        failureBackoffMaxSeconds: int.min(1),
+        autoDisableThreshold: int.min(0),
+        autoDisablePolicy: z.enum(["disable", "pause"]),
+        autoPauseCooldownMinutes: int.min(1),
+      })
+      .partial()
+      .strict(),
+    limits: z
+      .object({
+        dailyRunLimit: int.min(0),
+        monthlyBudgetUsd: z.number().min(0),
+        runOutputMaxBytes: int.min(1),
+        runHistoryRetentionDays: int.min(0),
+      })
+      .partial()
+      .strict(),
+    metrics: z
+      .object({ otlpEndpoint: str, serviceName: str, pushUrl: str, pushFormat: z.enum(["prometheus", "otlp"]), pushInstance: str })
+      .partial()
+      .strict(),
+    logging: z.object({ level: z.enum(["debug", "info", "warn", "error"]), format: z.enum(["json", "text"]) }).partial().strict(),
+    errorReporting: z.object({ sentryDsn: str, sentryEnvironment: str, webhookUrl: str, dedupeSeconds: int.min(0) }).partial().strict(),
+    channels: z
+      .object({
+        destinationPolicy: destinationRules.extend({ plans: z.record(z.string(), destinationRules) }).partial().strict(),
+        discordMaxParts: int.min(1),
+        fileDir: str,
+        fileFallbackChars: int.min(0),
+        chunkNumbering: z.boolean(),
+        telegramFormat: z.enum(["html", "plain"]),
+        execPlugins: z.record(z.string(), str),
+        execPluginTimeoutMs: int.min(1000),
+        webhookGzipMinBytes: int.min(0),
+        webhookAllowInsecureTls: z.boolean(),
+      })
+      .partial()
+      .strict(),
+  })
+  .partial()
+  .strict();
+
+export type Config = z.infer<typeof configSchema>;
+
+type EnvValue = string | number | boolean | string[] | Record<string, unknown>;
+
+// Config path -> env var. Lists are joined with commas, tables are passed as JSON.
+const ENV_MAP: Array<[string, string]> = [
+  ["database.url", "PRISMA_DATABASE_URL"],
+  ["database.directUrl", "DATABASE_URL"],
+  ["providers.openaiApiKey", "OPENAI_API_KEY"],
+  ["providers.openaiApiKeys", "OPENAI_API_KEYS"],
+  ["providers.openaiKeyQuarantineMinutes", "OPENAI_KEY_QUARANTINE_MINUTES"],
+  ["providers.anthropicApiKey", "ANTHROPIC_API_KEY"],
+  ["providers.googleApiKey", "GOOGLE_GENERATIVE_AI_API_KEY"],
+  ["providers.openrouterApiKey", "OPENROUTER_API_KEY"],
+  ["providers.openrouterProviderPreferences", "OPENROUTER_PROVIDER_PREFERENCES"],
+  ["providers.timeoutMs", "LLM_TIMEOUT_MS"],
+  ["providers.systemPromptFile", "LLM_SYSTEM_PROMPT_FILE"],
+  ["providers.systemPromptAddendumFile", "LLM_SYSTEM_PROMPT_ADDENDUM_FILE"],
+  ["providers.pricing", "LLM_PRICING_JSON"],
+  ["providers.routingModels", "LLM_ROUTING_MODELS"],
+  ["providers.fallbackModels", "LLM_FALLBACK_MODELS"],
+  ["providers.routingTagModels", "LLM_ROUTING_TAG_MODELS"],
+  ["providers.modelAliases", "LLM_MODEL_ALIASES"],
+  ["providers.canary.model", "LLM_CANARY_MODEL"],
+  ["providers.canary.baseModel", "LLM_CANARY_BASE_MODEL"],
+  ["providers.canary.percent", "LLM_CANARY_PERCENT"],
+  ["worker.environment", "WORKER_ENV"],
+  ["worker.concurrency", "WORKER_CONCURRENCY"],
+  ["worker.claimBatch", "WORKER_CLAIM_BATCH"],
+  ["worker.claimRetries", "WORKER_CLAIM_RETRIES"],
+  ["worker.maxJobsPerRun", "WORKER_MAX_JOBS_PER_RUN"],
+  ["worker.timeBudgetMs", "WORKER_TIME_BUDGET_MS"],
+  ["worker.lockStaleMinutes", "WORKER_LOCK_STALE_MINUTES"],
+  ["worker.lockHeartbeatSeconds", "WORKER_LOCK_HEARTBEAT_SECONDS"],
+  ["worker.catchupGraceMinutes", "WORKER_CATCHUP_GRACE_MINUTES"],
+  ["worker.smoothingWindowSeconds", "WORKER_SMOOTHING_WINDOW_SECONDS"],
+  ["worker.userRunsPerTick", "WORKER_USER_RUNS_PER_TICK"],
+  ["worker.userRunsPerHour", "WORKER_USER_RUNS_PER_HOUR"],
+  ["worker.drainTimeoutMs", "WORKER_DRAIN_TIMEOUT_MS"],
+  ["worker.shutdownTimeoutMs", "WORKER_SHUTDOWN_TIMEOUT_MS"],
+  ["worker.dormantWeeks", "WORKER_DORMANT_WEEKS"],
+  ["worker.maintenanceTimeoutSeconds", "WORKER_MAINTENANCE_TIMEOUT_SECONDS"],
+  ["worker.clockSource", "WORKER_CLOCK_SOURCE"],
+  ["worker.debugListenAddr", "DEBUG_LISTEN_ADDR"],
+  ["retry.llmMaxRetries", "WORKER_LLM_MAX_RETRIES"],
+  ["retry.deliveryMaxRetries", "WORKER_DELIVERY_MAX_RETRIES"],
+  ["retry.failureRetries", "WORKER_FAILURE_RETRIES"],
+  ["retry.failureBackoffSeconds", "WORKER_FAILURE_BACKOFF_SECONDS"],
+  ["retry.failureBackoffMaxSeconds", "WORKER_FAILURE_BACKOFF_MAX_SECONDS"],
+  ["retry.autoDisableThreshold", "WORKER_AUTO_DISABLE_THRESHOLD"],
+  ["retry.autoDisablePolicy", "WORKER_AUTO_DISABLE_POLICY"],
+  ["retry.autoPauseCooldownMinutes", "WORKER_AUTO_PAUSE_COOLDOWN_MINUTES"],
+  ["limits.dailyRunLimit", "DAILY_RUN_LIMIT"],
+  ["limits.monthlyBudgetUsd", "MONTHLY_BUDGET_USD"],
+  ["limits.runOutputMaxBytes", "RUN_OUTPUT_MAX_BYTES"],
+  ["limits.runHistoryRetentionDays", "RUN_HISTORY_RETENTION_DAYS"],
+  ["metrics.otlpEndpoint", "OTEL_EXPORTER_OTLP_ENDPOINT"],
+  ["metrics.serviceName", "OTEL_SERVICE_NAME"],
+  ["metrics.pushUrl", "METRICS_PUSH_URL"],
+  ["metrics.pushFormat", "METRICS_PUSH_FORMAT"],
+  ["metrics.pushInstance", "METRICS_PUSH_INSTANCE"],
+  ["logging.level", "LOG_LEVEL"],
+  ["logging.format", "LOG_FORMAT"],
+  ["errorReporting.sentryDsn", "SENTRY_DSN"],
+  ["errorReporting.sentryEnvironment", "SENTRY_ENVIRONMENT"],
+  ["errorReporting.webhookUrl", "ERROR_WEBHOOK_URL"],
+  ["errorReporting.dedupeSeconds", "ERROR_REPORT_DEDUPE_SECONDS"],
+  ["channels.destinationPolicy", "DELIVERY_DESTINATION_POLICY"],
+  ["channels.discordMaxParts", "CHANNEL_DISCORD_MAX_PARTS"],
+  ["channels.fileDir", "CHANNEL_FILE_DIR"],
+  ["channels.fileFallbackChars", "CHANNEL_FILE_FALLBACK_CHARS"],
+  ["channels.chunkNumbering", "CHANNEL_CHUNK_NUMBERING"],
+  ["channels.telegramFormat", "CHANNEL_TELEGRAM_FORMAT"],
+  ["channels.execPlugins", "CHANNEL_EXEC_PLUGINS"],
+  ["channels.execPluginTimeoutMs", "CHANNEL_EXEC_PLUGIN_TIMEOUT_MS"],
+  ["channels.webhookGzipMinBytes", "CHANNEL_WEBHOOK_GZIP_MIN_BYTES"],
+  ["channels.webhookAllowInsecureTls", "WEBHOOK_ALLOW_INSECURE_TLS"],
+];
+
+function valueAt(config: Config, path: string): EnvValue | undefined {
+  let current: unknown = config;
+  for (const key of path.split(".")) {
+    if (!current || typeof current !== "object") {
+      return undefined;
+    }
+    current = (current as Record<string, unknown>)[key];
+  }
+  return current as EnvValue | undefined;
+}
+
+function envString(value: EnvValue) {
+  if (Array.isArray(value)) {
+    return value.join(",");
+  }
+  if (typeof value === "object") {
+    return JSON.stringify(value);
+  }
+  if (typeof value === "boolean") {
+    return value ? "1" : "0";
+  }
+  return String(value);
+}
+
+// The env vars a config file sets, before overrides.
+export function configEnv(config: Config): Record<string, string> {
+  const env: Record<string, string> = {};
+  for (const [path, name] of ENV_MAP) {
+    const value = valueAt(config, path);
+    if (value !== undefined) {
+      env[name] = envString(value);
+    }
+  }
+  return env;
+}
+
+// --- Parsers: smol-toml for TOML 1.0 and js-yaml with the core schema, so YAML yields only JSON-like values
+// (no timestamps or custom tags). Shape and types are checked by configSchema afterwards.
+
+function parseError(format: string, err: unknown) {
+  return new Error(`Invalid ${format} config: ${err instanceof Error ? err.message : String(err)}`);
+}
+
+export function parseToml(text: string): Record<string, unknown> {
+  try {
+    return parseTomlText(text);
+  } catch (err) {
+    throw parseError("TOML", err);
+  }
+}
+
+export function parseYaml(text: string): Record<string, unknown> {
+  let doc: unknown;
+  try {
+    doc = loadYaml(text, { schema: CORE_SCHEMA });
+  } catch (err) {
+    throw parseError("YAML", err);
+  }
+  if (doc === undefined || doc === null) {
+    return {};
+  }
+  if (typeof doc !== "object" || Array.isArray(doc)) {
+    throw parseError("YAML", "the top level must be a mapping");
+  }
+  return doc as Record<string, unknown>;
+}
+
+export function parseConfigText(text: string, path: string): Config {
+  const ext = extname(path).toLowerCase();
+  const raw = ext === ".toml" ? parseToml(text) : ext === ".yaml" || ext === ".yml" ? parseYaml(text) : ext === ".json" ? JSON.parse(text) : null;
+  if (raw === null) {
+    throw new Error(`Unsupported config file type "${ext}" (use .toml, .yaml, .yml or .json)`);
+  }
+  const parsed = configSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issue = parsed.error.issues[0];
+    throw new Error(`Invalid config ${path}: ${issue.path.join(".") || "(root)"}: ${issue.message}`);
+  }
+  return parsed.data;
+}
+
+// Fills unset env vars from the file at PROMPTLOOP_CONFIG and returns the names it set. Runs once at startup;
+// a broken file stops the server rather than running with half the settings.
+export function applyConfigFile(env: NodeJS.ProcessEnv = process.env) {
+  const path = env.PROMPTLOOP_CONFIG?.trim();
+  if (!path) {
+    return [];
+  }
+  const config = parseConfigText(readFileSync(path, "utf8"), path);
+  const applied: string[] = [];
+  for (const [name, value] of Object.entries(configEnv(config))) {
+    if (env[name] === undefined || env[name] === "") {
+      env[name] = value;
+      applied.push(name);
+    }
+  }
+  return applied;
+}
@@ -0,0 +1,5 @@
+// Worker and operator endpoints under /api/cron require `Authorization: Bearer $CRON_SECRET` when it is set.
+export function isCronAuthorized(request: Request) {
+  const secret = process.env.CRON_SECRET;
+  return !secret || request.headers.get("authorization") === `Bearer ${secret}`;
+}
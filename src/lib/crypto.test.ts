@@ -0,0 +1,58 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { currentKeyId, decryptString, encryptString, hasRetiredKeys, keyIdOf, reencryptString, signToken, verifyToken } from "./crypto";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+function useKeys(keys: string, legacy = "") {
+  vi.stubEnv("SECRET_BACKEND", "env");
+  vi.stubEnv("CHANNEL_SECRET_KEYS", keys);
+  vi.stubEnv("CHANNEL_SECRET_KEY", legacy);
+  vi.stubEnv("NEXTAUTH_SECRET", "");
+}
+
+describe("channel secret keys", () => {
+  it("keeps the unversioned format when only the legacy key is set", () => {
+    useKeys("", "legacy-secret");
+    const value = encryptString("hello");
+    expect(value.split(":")).toHaveLength(3);
+    expect(keyIdOf(value)).toBeNull();
+    expect(currentKeyId()).toBeNull();
+    expect(decryptString(value)).toBe("hello");
+  });
+
+  it("writes with the newest key and still reads older ciphertexts", () => {
+    useKeys("", "legacy-secret");
+    const legacy = encryptString("old");
+    useKeys("v1:first-secret");
+    const v1 = encryptString("one");
+
+    useKeys("v2:second-secret,v1:first-secret", "legacy-secret");
+    const v2 = encryptString("two");
+    expect(keyIdOf(v2)).toBe("v2");
+    expect(hasRetiredKeys()).toBe(true);
+    expect(decryptString(v1)).toBe("one");
+    expect(decryptString(legacy)).toBe("old");
+    expect(keyIdOf(reencryptString(v1))).toBe("v2");
+  });
+
+  it("finds legacy ciphertexts under a renamed key and rejects unknown ids", () => {
+    useKeys("", "legacy-secret");
+    const legacy = encryptString("old");
+    useKeys("v2:second-secret,v1:legacy-secret");
+    expect(decryptString(legacy)).toBe("old");
+    expect(() => decryptString(`v9:${legacy}`)).toThrow("Unknown encryption key id: v9");
+    useKeys("bad id:x");
+    expect(() => encryptString("x")).toThrow("<id>:<secret>");
+  });
+
+  it("accepts tokens signed with a retired key", () => {
+    useKeys("v1:first-secret");
+    const token = signToken("job-replies", "job-1");
+    useKeys("v2:second-secret,v1:first-secret");
+    expect(signToken("job-replies", "job-1")).not.toBe(token);
+    expect(verifyToken("job-replies", "job-1", token)).toBe(true);
+    expect(verifyToken("job-replies", "job-2", token)).toBe(false);
+  });
+});
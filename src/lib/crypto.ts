@@ -1,11 +1,21 @@
-import { createCipheriv, createDecipheriv, createHash, randomBytes } from "crypto";
+import { createCipheriv, createDecipheriv, createHash, createHmac, randomBytes, timingSafeEqual } from "crypto";
 
-function keyFromEnv() {
-  const raw = process.env.CHANNEL_SECRET_KEY ?? process.env.NEXTAUTH_SECRET;
-  if (!raw) {
-    throw new Error("CHANNEL_SECRET_KEY or NEXTAUTH_SECRET is required");
+// Data key unwrapped by a KMS/Vault secret backend (see secret-backend.ts); takes precedence over the env keys.
+let dataKey: SecretKey | null = null;
+
+type SecretKey = { id: string | null; key: Buffer };
+
+export const DATA_KEY_ID = "kms";
+const KEY_ID_RE = /^[A-Za-z0-9_-]{1,32}$/;
+
+export function setDataKey(key: Buffer | null, id = DATA_KEY_ID) {
+  if (!KEY_ID_RE.test(id)) {
+    throw new Error(`Invalid encryption key id: ${id}`);
   }
+  dataKey = key ? { id, key } : null;
+}
 
+function deriveKey(raw: string) {
   if (raw.length === 64 && /^[a-f0-9]+$/i.test(raw)) {
     return Buffer.from(raw, "hex");
   }
@@ -13,31 +23,136 @@ function keyFromEnv() {
   return createHash("sha256").update(raw).digest();
 }
 
+// CHANNEL_SECRET_KEYS="v2:<secret>,v1:<secret>" lists versioned keys, newest first. The first key (or the KMS data
+// key) encrypts; every listed key, plus the unversioned CHANNEL_SECRET_KEY/NEXTAUTH_SECRET, still decrypts, so old
+// ciphertexts keep working until they are re-encrypted.
+function secretKeys(): SecretKey[] {
+  const keys: SecretKey[] = dataKey ? [dataKey] : [];
+  for (const entry of (process.env.CHANNEL_SECRET_KEYS ?? "").split(",")) {
+    if (!entry.trim()) {
+      continue;
+    }
+    const sep = entry.indexOf(":");
+    const id = entry.slice(0, Math.max(sep, 0)).trim();
+    const raw = entry.slice(sep + 1).trim();
+    if (sep < 0 || !KEY_ID_RE.test(id) || !raw) {
+      throw new Error("CHANNEL_SECRET_KEYS entries must look like <id>:<secret>");
+    }
+    keys.push({ id, key: deriveKey(raw) });
+  }
+
+  const legacy = process.env.CHANNEL_SECRET_KEY ?? process.env.NEXTAUTH_SECRET;
+  if (legacy) {
+    keys.push({ id: null, key: deriveKey(legacy) });
+  }
+  return keys;
+}
+
+function primaryKey() {
+  if (dataKey) {
+    return dataKey;
+  }
+  const backend = (process.env.SECRET_BACKEND ?? "env").trim().toLowerCase();
+  if (backend !== "env") {
+    throw new Error(`SECRET_BACKEND=${backend} is not initialized`);
+  }
+
+  const [key] = secretKeys();
+  if (!key) {
+    throw new Error("CHANNEL_SECRET_KEYS, CHANNEL_SECRET_KEY or NEXTAUTH_SECRET is required");
+  }
+  return key;
+}
+
+// Id of the key new ciphertexts are written with; null for the unversioned legacy key.
+export function currentKeyId() {
+  return primaryKey().id;
+}
+
+// True when more than one key can decrypt, i.e. stored values may still need re-encrypting with the current key.
+export function hasRetiredKeys() {
+  return secretKeys().length > 1;
+}
+
+// Versioned payloads are "<keyId>:iv:tag:ciphertext"; legacy ones are "iv:tag:ciphertext".
+export function keyIdOf(value: string) {
+  const parts = value.split(":");
+  return parts.length === 4 ? parts[0] : null;
+}
+
 export function encryptString(value: string) {
   const iv = randomBytes(12);
-  const key = keyFromEnv();
+  const { id, key } = primaryKey();
   const cipher = createCipheriv("aes-256-gcm", key, iv);
   const encrypted = Buffer.concat([cipher.update(value, "utf8"), cipher.final()]);
   const tag = cipher.getAuthTag();
-  return `${iv.toString("base64")}:${tag.toString("base64")}:${encrypted.toString("base64")}`;
+  const payload = `${iv.toString("base64")}:${tag.toString("base64")}:${encrypted.toString("base64")}`;
+  return id ? `${id}:${payload}` : payload;
 }
 
-export function decryptString(value: string) {
-  const [ivB64, tagB64, encryptedB64] = value.split(":");
-  if (!ivB64 || !tagB64 || !encryptedB64) {
-    throw new Error("Invalid encrypted payload");
-  }
-
+function decryptWith(key: Buffer, ivB64: string, tagB64: string, encryptedB64: string) {
   const iv = Buffer.from(ivB64, "base64");
   const tag = Buffer.from(tagB64, "base64");
   const encrypted = Buffer.from(encryptedB64, "base64");
-  const key = keyFromEnv();
   const decipher = createDecipheriv("aes-256-gcm", key, iv);
   decipher.setAuthTag(tag);
   const decrypted = Buffer.concat([decipher.update(encrypted), decipher.final()]);
   return decrypted.toString("utf8");
 }
 
+export function decryptString(value: string) {
+  const parts = value.split(":");
+  const [ivB64, tagB64, encryptedB64] = parts.length === 4 ? parts.slice(1) : parts;
+  if (parts.length > 4 || !ivB64 || !tagB64 || !encryptedB64) {
+    throw new Error("Invalid encrypted payload");
+  }
+
+  if (parts.length === 4) {
+    const match = secretKeys().find((candidate) => candidate.id === parts[0]);
+    if (!match) {
+      throw new Error(`Unknown encryption key id: ${parts[0]}`);
+    }
+    return decryptWith(match.key, ivB64, tagB64, encryptedB64);
+  }
+
+  // Unversioned payloads predate key ids: the legacy key is tried first, then the rest (GCM rejects a wrong key).
+  primaryKey(); // throws when no key is configured or the secret backend is not initialized
+  const candidates = secretKeys().sort((a, b) => Number(a.id !== null) - Number(b.id !== null));
+  let lastError: unknown = new Error("Invalid encrypted payload");
+  for (const candidate of candidates) {
+    try {
+      return decryptWith(candidate.key, ivB64, tagB64, encryptedB64);
+    } catch (err) {
+      lastError = err;
+    }
+  }
+  throw lastError;
+}
+
+// Decrypts with whichever key wrote the value and encrypts again with the current key.
+export function reencryptString(value: string) {
+  return encryptString(decryptString(value));
+}
+
+function tokenWith(key: Buffer, purpose: string, value: string) {
+  return createHmac("sha256", key).update(`${purpose}:${value}`).digest("hex");
+}
+
+// Deterministic token bound to a purpose and value (e.g. a job id), so it can be re-derived instead of stored.
+export function signToken(purpose: string, value: string) {
+  return tokenWith(primaryKey().key, purpose, value);
+}
+
+// Tokens signed with a retired key stay valid, so rotating keys does not break reply links already handed out.
+export function verifyToken(purpose: string, value: string, token: string) {
+  const actual = Buffer.from(token);
+  const keys = [primaryKey(), ...secretKeys()];
+  return keys.some(({ key }) => {
+    const expected = Buffer.from(tokenWith(key, purpose, value));
+    return expected.length === actual.length && timingSafeEqual(expected, actual);
+  });
+}
+
 export function maskSecret(value: string) {
   if (!value) {
     return "";
@@ -0,0 +1,36 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { queryRaw } = vi.hoisted(() => ({ queryRaw: vi.fn() }));
+
+vi.mock("@/lib/prisma", () => ({ prisma: { $queryRaw: queryRaw } }));
+
+import { clockOffsetMs, setClockOffset, systemClock } from "./clock";
+import { syncClockWithDatabase } from "./db-clock";
+
+describe("database clock", () => {
+  afterEach(() => {
+    setClockOffset(0);
+    queryRaw.mockReset();
+    vi.unstubAllEnvs();
+  });
+
+  it("corrects the system clock by its offset from the database", async () => {
+    queryRaw.mockResolvedValue([{ now: new Date(Date.now() + 60_000) }]);
+    const offset = await syncClockWithDatabase(true);
+    expect(offset).toBeGreaterThan(59_000);
+    expect(offset).toBeLessThan(61_000);
+    expect(Math.abs(systemClock.now().getTime() - (Date.now() + 60_000))).toBeLessThan(1000);
+
+    // Within the resync interval the measured offset is reused.
+    await syncClockWithDatabase();
+    expect(queryRaw).toHaveBeenCalledTimes(1);
+  });
+
+  it("keeps the machine clock with WORKER_CLOCK_SOURCE=local", async () => {
+    setClockOffset(5000);
+    vi.stubEnv("WORKER_CLOCK_SOURCE", "local");
+    await expect(syncClockWithDatabase(true)).resolves.toBe(0);
+    expect(clockOffsetMs()).toBe(0);
+    expect(queryRaw).not.toHaveBeenCalled();
+  });
+});
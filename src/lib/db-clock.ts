@@ -0,0 +1,37 @@
+import { prisma } from "@/lib/prisma";
+import { clockOffsetMs, setClockOffset } from "@/lib/clock";
+import { logger } from "@/lib/logger";
+
+// Scheduling decisions mix the worker's time (next runs, catch-up grace, backoff) with Postgres now() (due and
+// stale-lock checks in the claim queries). A worker whose clock is skewed would claim a slot it then treats as
+// still in the future, or compute the next run from the wrong time. Each worker measures its clock's offset from
+// the database and applies it to systemClock, so every timing decision uses the database's clock.
+const RESYNC_MS = 5 * 60 * 1000;
+const SKEW_WARN_MS = 2000;
+
+let syncedAt = 0;
+
+// The offset is the database time minus the midpoint of the query's round trip. Re-measured at most every five
+// minutes unless forced. WORKER_CLOCK_SOURCE=local keeps the machine's clock as is.
+export async function syncClockWithDatabase(force = false) {
+  if (process.env.WORKER_CLOCK_SOURCE?.trim().toLowerCase() === "local") {
+    setClockOffset(0);
+    return 0;
+  }
+  if (!force && syncedAt && Date.now() - syncedAt < RESYNC_MS) {
+    return clockOffsetMs();
+  }
+  const before = Date.now();
+  const [row] = await prisma.$queryRaw<Array<{ now: Date }>>`SELECT now() AS now`;
+  const after = Date.now();
+  if (!row?.now) {
+    return clockOffsetMs();
+  }
+  const offset = row.now.getTime() - (before + after) / 2;
+  setClockOffset(offset);
+  syncedAt = after;
+  if (Math.abs(offset) >= SKEW_WARN_MS) {
+    logger.warn("worker clock differs from database clock", { offset_ms: Math.round(offset), round_trip_ms: after - before });
+  }
+  return clockOffsetMs();
+}
@@ -0,0 +1,33 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { buildErrorChain } from "./dead-letters";
+
+describe("dead letters", () => {
+  const at = new Date("2026-07-01T00:00:00.000Z");
+
+  it("orders delivery attempts and appends the final run error", () => {
+    const chain = buildErrorChain(
+      [
+        { attempt: 2, statusCode: 503, errorMessage: "Service Unavailable", createdAt: new Date("2026-07-01T00:00:02.000Z") },
+        { attempt: 1, statusCode: null, errorMessage: "fetch failed", createdAt: new Date("2026-07-01T00:00:01.000Z") },
+      ],
+      "Schedule calculation error: bad cron",
+      at,
+    );
+    expect(chain.map((entry) => entry.attempt ?? entry.stage)).toEqual([1, 2, "run"]);
+    expect(chain[0]).not.toHaveProperty("statusCode");
+    expect(chain[1].statusCode).toBe(503);
+  });
+
+  it("does not repeat the last delivery error as the run error", () => {
+    const chain = buildErrorChain(
+      [{ attempt: 1, statusCode: 404, errorMessage: "Not Found", createdAt: at }],
+      "Not Found",
+      at,
+    );
+    expect(chain).toHaveLength(1);
+    expect(buildErrorChain([], "LLM timeout", at)).toEqual([{ stage: "run", message: "LLM timeout", at: at.toISOString() }]);
+  });
+});
@@ -0,0 +1,73 @@
+import type { Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { logger } from "@/lib/logger";
+import { recordAudit } from "@/lib/audit";
+import { clock } from "@/lib/clock";
+
+export type DeadLetterReason = "auto_disabled" | "auto_paused" | "delivery_failed";
+
+export type DeadLetterError = {
+  stage: "run" | "delivery";
+  attempt?: number;
+  statusCode?: number;
+  message: string;
+  at: string;
+};
+
+type FailedAttempt = { attempt: number; statusCode: number | null; errorMessage: string | null; createdAt: Date };
+
+// Delivery attempts in order, followed by the error that ended the run (when it is not just the last attempt repeated).
+export function buildErrorChain(attempts: FailedAttempt[], finalError: string | null, at = clock().now()): DeadLetterError[] {
+  const chain: DeadLetterError[] = [...attempts]
+    .sort((a, b) => a.attempt - b.attempt)
+    .map((attempt) => ({
+      stage: "delivery" as const,
+      attempt: attempt.attempt,
+      ...(attempt.statusCode != null ? { statusCode: attempt.statusCode } : {}),
+      message: attempt.errorMessage ?? "Delivery failed",
+      at: attempt.createdAt.toISOString(),
+    }));
+  if (finalError && chain[chain.length - 1]?.message !== finalError) {
+    chain.push({ stage: "run", message: finalError, at: at.toISOString() });
+  }
+  return chain;
+}
+
+// Best effort, like audit logging: a failed write is logged and never fails the run. With userId set the owner
+// is also notified through the audit log (job.dead_letter) and the dashboard's undelivered-output badge.
+export async function recordDeadLetter(input: {
+  jobId: string;
+  userId?: string;
+  runHistoryId: string;
+  reason: DeadLetterReason;
+  outputText: string | null;
+  errorMessage: string | null;
+}) {
+  try {
+    const attempts = await prisma.deliveryAttempt.findMany({
+      where: { runHistoryId: input.runHistoryId, status: "fail" },
+      select: { attempt: true, statusCode: true, errorMessage: true, createdAt: true },
+    });
+    const deadLetter = await prisma.deadLetter.create({
+      data: {
+        jobId: input.jobId,
+        runHistoryId: input.runHistoryId,
+        reason: input.reason,
+        outputText: input.outputText || null,
+        errorChain: buildErrorChain(attempts, input.errorMessage) as unknown as Prisma.InputJsonValue,
+      },
+      select: { id: true },
+    });
+    if (input.userId) {
+      await recordAudit({
+        userId: input.userId,
+        action: "job.dead_letter",
+        entityType: "job",
+        entityId: input.jobId,
+        data: { deadLetterId: deadLetter.id, runHistoryId: input.runHistoryId, reason: input.reason, error: input.errorMessage },
+      });
+    }
+  } catch (err) {
+    logger.error("dead letter write failed", { job_id: input.jobId, run_id: input.runHistoryId, error: err });
+  }
+}
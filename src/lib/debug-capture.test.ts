@@ -0,0 +1,41 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { debugCaptureEntry, debugPayloadFromError, debugPayloadFromResult, scrubDebugPayload } from "./debug-capture";
+
+describe("debug capture", () => {
+  it("reads request/response bodies from results and API errors", () => {
+    expect(debugPayloadFromResult({ request: { body: '{"model":"gpt-5-mini"}' }, response: { body: { id: "resp_1" } } })).toEqual({
+      request: '{"model":"gpt-5-mini"}',
+      response: { id: "resp_1" },
+    });
+    expect(debugPayloadFromResult({ text: "hi" })).toEqual({ request: null, response: null });
+    expect(debugPayloadFromError({ requestBodyValues: { input: "x" }, responseBody: "bad" })).toEqual({
+      request: { input: "x" },
+      response: "bad",
+    });
+  });
+
+  it("scrubs secrets and credential fields", () => {
+    const secrets = { NEWSAPI_KEY: "newsapi-secret-value" };
+    expect(scrubDebugPayload('{"input":"key=newsapi-secret-value","headers":{"Authorization":"Bearer x"}}', secrets)).toEqual({
+      input: "key=[secret:NEWSAPI_KEY]",
+      headers: { Authorization: "[redacted]" },
+    });
+  });
+
+  it("truncates oversized payloads", () => {
+    const scrubbed = scrubDebugPayload({ input: "x".repeat(300_000) }, {});
+    expect(typeof scrubbed).toBe("string");
+    expect((scrubbed as string).endsWith("...[truncated]")).toBe(true);
+  });
+
+  it("builds entries with a redacted error", () => {
+    const entry = debugCaptureEntry(
+      { step: "primary", model: "gpt-5-mini", payload: { request: null, response: null }, error: "failed with tok-123456789" },
+      { TOKEN: "tok-123456789" },
+    );
+    expect(entry).toMatchObject({ step: "primary", model: "gpt-5-mini", error: "failed with [secret:TOKEN]" });
+  });
+});
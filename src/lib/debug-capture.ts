@@ -0,0 +1,74 @@
+import { redactSecrets } from "@/lib/secrets";
+import { isRecord } from "@/lib/type-guards";
+
+export const DEBUG_RUNS_MAX = 20;
+const DEBUG_PAYLOAD_MAX_CHARS = 200_000;
+const SENSITIVE_KEY_RE = /authorization|api[-_]?key|token|secret|password|cookie/i;
+
+export type DebugPayload = { request: unknown; response: unknown };
+
+export type DebugCaptureEntry = DebugPayload & {
+  step: "primary" | "pipeline" | "post" | "diff";
+  model: string;
+  at: string;
+  error?: string;
+};
+
+// Raw provider payloads from an AI SDK result (request/response bodies as sent and received).
+export function debugPayloadFromResult(result: unknown): DebugPayload {
+  const request = isRecord(result) && isRecord(result.request) ? result.request.body : undefined;
+  const response = isRecord(result) && isRecord(result.response) ? result.response.body : undefined;
+  return { request: request ?? null, response: response ?? null };
+}
+
+// AI SDK API errors carry the request body and the raw response text.
+export function debugPayloadFromError(err: unknown): DebugPayload {
+  if (!isRecord(err)) {
+    return { request: null, response: null };
+  }
+  return { request: err.requestBodyValues ?? null, response: err.responseBody ?? null };
+}
+
+function scrubValue(value: unknown, secrets: Record<string, string>, depth: number): unknown {
+  if (typeof value === "string") {
+    return redactSecrets(value, secrets);
+  }
+  if (depth > 20 || value == null || typeof value !== "object") {
+    return value;
+  }
+  if (Array.isArray(value)) {
+    return value.map((item) => scrubValue(item, secrets, depth + 1));
+  }
+  return Object.fromEntries(
+    Object.entries(value).map(([key, item]) => [key, SENSITIVE_KEY_RE.test(key) ? "[redacted]" : scrubValue(item, secrets, depth + 1)]),
+  );
+}
+
+// Redacts user secrets and credential-looking fields; oversized payloads are kept as a truncated JSON string.
+export function scrubDebugPayload(value: unknown, secrets: Record<string, string>): unknown {
+  let parsed = value;
+  if (typeof value === "string") {
+    try {
+      parsed = JSON.parse(value) as unknown;
+    } catch {
+      parsed = value;
+    }
+  }
+  const scrubbed = scrubValue(parsed, secrets, 0);
+  const json = JSON.stringify(scrubbed) ?? "null";
+  return json.length > DEBUG_PAYLOAD_MAX_CHARS ? `${json.slice(0, DEBUG_PAYLOAD_MAX_CHARS)}...[truncated]` : scrubbed;
+}
+
+export function debugCaptureEntry(
+  input: { step: DebugCaptureEntry["step"]; model: string; payload: DebugPayload; error?: string },
+  secrets: Record<string, string>,
+): DebugCaptureEntry {
+  return {
+    step: input.step,
+    model: input.model,
+    at: new Date().toISOString(),
+    request: scrubDebugPayload(input.payload.request, secrets),
+    response: scrubDebugPayload(input.payload.response, secrets),
+    ...(input.error ? { error: redactSecrets(input.error, secrets) } : {}),
+  };
+}
@@ -0,0 +1,22 @@
+import { describe, expect, it } from "vitest";
+import { debugVars, parseListenAddr } from "./debug-server";
+import { recordClaim } from "./worker-metrics";
+
+describe("debug listener", () => {
+  it("parses listen addresses", () => {
+    expect(parseListenAddr("6060")).toEqual({ host: "127.0.0.1", port: 6060 });
+    expect(parseListenAddr("0.0.0.0:6060")).toEqual({ host: "0.0.0.0", port: 6060 });
+    expect(parseListenAddr("[::1]:6060")).toEqual({ host: "::1", port: 6060 });
+    expect(parseListenAddr(":6060")).toEqual({ host: "127.0.0.1", port: 6060 });
+    expect(parseListenAddr("localhost:http")).toBeNull();
+    expect(parseListenAddr(" ")).toBeNull();
+  });
+
+  it("reports process and worker counters", () => {
+    recordClaim();
+    const vars = debugVars();
+    expect(vars.memory.heapUsed).toBeGreaterThan(0);
+    expect(vars.worker.claims).toBe(1);
+    expect(vars.eventLoopDelayMs).toBeNull();
+  });
+});
@@ -0,0 +1,136 @@
+import { createServer, type IncomingMessage, type Server, type ServerResponse } from "http";
+import { monitorEventLoopDelay, type IntervalHistogram } from "perf_hooks";
+import { Session } from "inspector";
+import { getHeapSnapshot, getHeapStatistics } from "v8";
+import { logger } from "@/lib/logger";
+import { workerCounters } from "@/lib/worker-metrics";
+
+// Optional diagnostics listener for long-running workers (DEBUG_LISTEN_ADDR, e.g. "127.0.0.1:6060"). It runs on
+// its own port, outside Next.js routing, so it stays reachable when the app is busy and can be kept off the public
+// network. Requests need the CRON_SECRET bearer token when one is set.
+const DEFAULT_HOST = "127.0.0.1";
+const PROFILE_DEFAULT_SECONDS = 30;
+const PROFILE_MAX_SECONDS = 300;
+
+let server: Server | null = null;
+let loopDelay: IntervalHistogram | null = null;
+let profiling = false;
+
+export function parseListenAddr(value: string | undefined): { host: string; port: number } | null {
+  const raw = value?.trim();
+  if (!raw) {
+    return null;
+  }
+  const index = raw.lastIndexOf(":");
+  const host = index >= 0 ? raw.slice(0, index).replace(/^\[|\]$/g, "") || DEFAULT_HOST : DEFAULT_HOST;
+  const port = Number(index >= 0 ? raw.slice(index + 1) : raw);
+  return Number.isInteger(port) && port > 0 && port < 65536 ? { host, port } : null;
+}
+
+// The expvar equivalent: process, heap, event loop and worker counters as one JSON document.
+export function debugVars() {
+  const memory = process.memoryUsage();
+  const resources = process.getActiveResourcesInfo().reduce<Record<string, number>>((counts, type) => {
+    counts[type] = (counts[type] ?? 0) + 1;
+    return counts;
+  }, {});
+  return {
+    pid: process.pid,
+    uptimeSeconds: Math.round(process.uptime()),
+    memory,
+    heap: getHeapStatistics(),
+    // Handles and requests keeping the event loop alive; a steadily growing count points at a leak.
+    activeResources: resources,
+    eventLoopDelayMs: loopDelay
+      ? { mean: loopDelay.mean / 1e6, p99: loopDelay.percentile(99) / 1e6, max: loopDelay.max / 1e6 }
+      : null,
+    worker: workerCounters(),
+  };
+}
+
+function authorized(request: IncomingMessage) {
+  const secret = process.env.CRON_SECRET;
+  return !secret || request.headers.authorization === `Bearer ${secret}`;
+}
+
+async function cpuProfile(seconds: number) {
+  const session = new Session();
+  session.connect();
+  const post = (method: string) =>
+    new Promise<unknown>((resolve, reject) => session.post(method, (err, result) => (err ? reject(err) : resolve(result))));
+  try {
+    await post("Profiler.enable");
+    await post("Profiler.start");
+    await new Promise((resolve) => setTimeout(resolve, seconds * 1000));
+    const { profile } = (await post("Profiler.stop")) as { profile: unknown };
+    return profile;
+  } finally {
+    session.disconnect();
+  }
+}
+
+async function handle(request: IncomingMessage, response: ServerResponse) {
+  if (!authorized(request)) {
+    response.writeHead(401).end("Unauthorized");
+    return;
+  }
+  const url = new URL(request.url ?? "/", "http://debug");
+
+  if (url.pathname === "/debug/vars") {
+    response.writeHead(200, { "content-type": "application/json" }).end(JSON.stringify(debugVars()));
+    return;
+  }
+  if (url.pathname === "/debug/heap") {
+    // Blocks the event loop while V8 walks the heap; open the file in Chrome DevTools (Memory tab).
+    response.writeHead(200, {
+      "content-type": "application/octet-stream",
+      "content-disposition": `attachment; filename="promptloop-${process.pid}-${Date.now()}.heapsnapshot"`,
+    });
+    getHeapSnapshot().pipe(response);
+    return;
+  }
+  if (url.pathname === "/debug/profile") {
+    if (profiling) {
+      response.writeHead(409).end("A profile is already running");
+      return;
+    }
+    const requested = Number(url.searchParams.get("seconds") ?? PROFILE_DEFAULT_SECONDS);
+    const seconds = Number.isFinite(requested) && requested > 0 ? Math.min(requested, PROFILE_MAX_SECONDS) : PROFILE_DEFAULT_SECONDS;
+    profiling = true;
+    try {
+      const profile = await cpuProfile(seconds);
+      response
+        .writeHead(200, {
+          "content-type": "application/json",
+          "content-disposition": `attachment; filename="promptloop-${process.pid}-${Date.now()}.cpuprofile"`,
+        })
+        .end(JSON.stringify(profile));
+    } finally {
+      profiling = false;
+    }
+    return;
+  }
+  response.writeHead(404).end("Not found");
+}
+
+export function startDebugServer() {
+  const addr = parseListenAddr(process.env.DEBUG_LISTEN_ADDR);
+  if (!addr || server) {
+    return;
+  }
+  loopDelay = monitorEventLoopDelay({ resolution: 20 });
+  loopDelay.enable();
+  server = createServer((request, response) => {
+    handle(request, response).catch((err) => {
+      logger.warn("debug request failed", { path: request.url, error: err });
+      if (!response.headersSent) {
+        response.writeHead(500);
+      }
+      response.end();
+    });
+  });
+  server.on("error", (err) => logger.warn("debug listener failed", { error: err }));
+  // The listener must not keep a finished process alive.
+  server.unref();
+  server.listen(addr.port, addr.host, () => logger.info("debug listener started", { host: addr.host, port: addr.port }));
+}
@@ -0,0 +1,24 @@
+import { describe, expect, it } from "vitest";
+import { deliverySkipReason, normalizeDeliverIf, outputHash } from "./deliver-if";
+
+describe("deliver_if", () => {
+  it("defaults unknown modes to always", () => {
+    expect(normalizeDeliverIf("changed")).toBe("changed");
+    expect(normalizeDeliverIf("sometimes")).toBe("always");
+    expect(deliverySkipReason({ mode: "always", output: "" })).toBeNull();
+  });
+
+  it("holds unchanged output, ignoring whitespace", () => {
+    const previousHash = outputHash("Price: 10 USD\n");
+    expect(deliverySkipReason({ mode: "changed", output: "Price:  10 USD", previousHash })).toBe("unchanged");
+    expect(deliverySkipReason({ mode: "changed", output: "Price: 11 USD", previousHash })).toBeNull();
+    expect(deliverySkipReason({ mode: "changed", output: "first run", previousHash: null })).toBeNull();
+  });
+
+  it("supports nonempty and case-insensitive regex modes", () => {
+    expect(deliverySkipReason({ mode: "nonempty", output: "  \n" })).toBe("empty");
+    expect(deliverySkipReason({ mode: "regex", pattern: "^ALERT", output: "alert: disk full" })).toBeNull();
+    expect(deliverySkipReason({ mode: "regex", pattern: "^ALERT", output: "all good" })).toBe("no_match");
+    expect(deliverySkipReason({ mode: "regex", pattern: "(", output: "all good" })).toBeNull();
+  });
+});
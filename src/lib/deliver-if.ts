@@ -0,0 +1,45 @@
+import { createHash } from "node:crypto";
+
+export const DELIVER_IF_MODES = ["always", "changed", "nonempty", "regex"] as const;
+export type DeliverIfMode = (typeof DELIVER_IF_MODES)[number];
+
+export function normalizeDeliverIf(value: unknown): DeliverIfMode {
+  return DELIVER_IF_MODES.includes(value as DeliverIfMode) ? (value as DeliverIfMode) : "always";
+}
+
+// Whitespace-insensitive, so re-wrapped but identical outputs count as unchanged.
+export function outputHash(output: string) {
+  return createHash("sha256").update(output.trim().replace(/\s+/g, " ")).digest("hex");
+}
+
+export function isValidDeliverIfPattern(pattern: string) {
+  try {
+    new RegExp(pattern, "i");
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+// Returns null when the output should be delivered, otherwise the reason it is held back.
+export function deliverySkipReason(input: {
+  mode: DeliverIfMode;
+  pattern?: string | null;
+  output: string;
+  previousHash?: string | null;
+}): string | null {
+  switch (input.mode) {
+    case "changed":
+      return input.previousHash && input.previousHash === outputHash(input.output) ? "unchanged" : null;
+    case "nonempty":
+      return input.output.trim() ? null : "empty";
+    case "regex": {
+      if (!input.pattern || !isValidDeliverIfPattern(input.pattern)) {
+        return null;
+      }
+      return new RegExp(input.pattern, "i").test(input.output) ? null : "no_match";
+    }
+    default:
+      return null;
+  }
+}
@@ -0,0 +1,25 @@
+import { describe, expect, it } from "vitest";
+import { isValidRetrySchedule, nextDeliveryRetryAt, parseRetrySchedule } from "./delivery-retry";
+
+describe("delivery retry schedule", () => {
+  it("parses comma-separated durations", () => {
+    expect(parseRetrySchedule("1m, 10m,1h,6h")).toEqual([60_000, 600_000, 3_600_000, 21_600_000]);
+    expect(parseRetrySchedule("")).toEqual([]);
+    expect(parseRetrySchedule(null)).toEqual([]);
+  });
+
+  it("rejects malformed or oversized steps", () => {
+    expect(isValidRetrySchedule("1m,soon")).toBe(false);
+    expect(isValidRetrySchedule("0m")).toBe(false);
+    expect(isValidRetrySchedule("8d")).toBe(false);
+    expect(isValidRetrySchedule(Array.from({ length: 11 }, () => "1m").join(","))).toBe(false);
+  });
+
+  it("walks the schedule and stops when it is spent", () => {
+    const now = new Date("2026-03-01T00:00:00Z");
+    expect(nextDeliveryRetryAt("1m,1h", 0, now)?.toISOString()).toBe("2026-03-01T00:01:00.000Z");
+    expect(nextDeliveryRetryAt("1m,1h", 1, now)?.toISOString()).toBe("2026-03-01T01:00:00.000Z");
+    expect(nextDeliveryRetryAt("1m,1h", 2, now)).toBeNull();
+    expect(nextDeliveryRetryAt(null, 0, now)).toBeNull();
+  });
+});
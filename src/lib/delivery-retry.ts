@@ -0,0 +1,40 @@
+import { clock } from "@/lib/clock";
+
+// Durable delivery retries for webhook channels and partially delivered messages: after the in-process retries
+// give up, the output stays in the outbox and is retried on a schedule such as "1m,10m,1h,6h".
+
+export const RETRY_SCHEDULE_MAX_STEPS = 10;
+const STEP_MAX_MS = 7 * 24 * 60 * 60 * 1000;
+const UNIT_MS: Record<string, number> = { s: 1000, m: 60 * 1000, h: 60 * 60 * 1000, d: 24 * 60 * 60 * 1000 };
+
+// Delays in ms, [] for an empty schedule, or null when any step is malformed or out of range.
+export function parseRetrySchedule(value: string | null | undefined): number[] | null {
+  const steps = (value ?? "")
+    .split(",")
+    .map((step) => step.trim().toLowerCase())
+    .filter(Boolean);
+  if (steps.length > RETRY_SCHEDULE_MAX_STEPS) {
+    return null;
+  }
+  const delays: number[] = [];
+  for (const step of steps) {
+    const match = /^(\d+)([smhd])$/.exec(step);
+    const ms = match ? Number(match[1]) * UNIT_MS[match[2]] : NaN;
+    if (!Number.isFinite(ms) || ms <= 0 || ms > STEP_MAX_MS) {
+      return null;
+    }
+    delays.push(ms);
+  }
+  return delays;
+}
+
+export function isValidRetrySchedule(value: string) {
+  return parseRetrySchedule(value) != null;
+}
+
+// When to try again after retriesScheduled durable retries have already been used; null once the schedule is spent.
+export function nextDeliveryRetryAt(schedule: string | null | undefined, retriesScheduled: number, now = clock().now()) {
+  const delays = parseRetrySchedule(schedule) ?? [];
+  const delay = delays[retriesScheduled];
+  return delay == null ? null : new Date(now.getTime() + delay);
+}
@@ -0,0 +1,52 @@
+import { afterEach, beforeEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { ChannelType } from "@prisma/client";
+import { encryptString } from "./crypto";
+import { jobProblems } from "./deployment-checks";
+
+beforeEach(() => {
+  vi.stubEnv("CHANNEL_SECRET_KEY", "test-secret");
+});
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+const baseJob = {
+  id: "job-1",
+  name: "Daily",
+  enabled: true,
+  scheduleType: "daily" as const,
+  scheduleTime: "09:00",
+  scheduleDayOfWeek: null,
+  scheduleCron: null,
+  timezone: "UTC",
+  runAt: null,
+  weekdaysOnly: false,
+};
+
+describe("deployment checks", () => {
+  it("accepts a job with a valid schedule and channel", () => {
+    const job = {
+      ...baseJob,
+      channelType: ChannelType.discord,
+      channelConfig: { webhookUrlEnc: encryptString("https://discord.com/api/webhooks/1/abc") },
+    };
+    expect(jobProblems(job)).toEqual([]);
+  });
+
+  it("reports bad cron expressions and configs that no longer decrypt", () => {
+    const job = {
+      ...baseJob,
+      scheduleType: "cron" as const,
+      scheduleCron: "not a cron",
+      channelType: ChannelType.telegram,
+      channelConfig: { botTokenEnc: "bad", chatIdEnc: "bad" },
+    };
+    const problems = jobProblems(job);
+    expect(problems[0]).toMatch(/^schedule: /);
+    expect(problems[1]).toMatch(/^channel: cannot decrypt/);
+  });
+});
@@ -0,0 +1,109 @@
+import type { Job } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { toEditableChannel } from "@/lib/jobs";
+import { computeNextRunAt } from "@/lib/schedule";
+import { ChannelConfigError, validateChannel } from "@/lib/channel-config";
+
+const VALIDATE_BATCH = 200;
+
+type CheckedJob = Pick<
+  Job,
+  "id" | "name" | "enabled" | "channelType" | "channelConfig" | "scheduleType" | "scheduleTime" | "scheduleDayOfWeek" | "scheduleCron" | "timezone" | "runAt" | "weekdaysOnly"
+>;
+
+// Problems that would make the worker fail this job before or after the model call: a schedule that cannot be
+// computed, or a channel config that does not decrypt with the current keys or no longer passes validation.
+export function jobProblems(job: CheckedJob, now = new Date()) {
+  const problems: string[] = [];
+  if (job.scheduleType !== "once") {
+    try {
+      computeNextRunAt(
+        {
+          scheduleType: job.scheduleType,
+          scheduleTime: job.scheduleTime,
+          scheduleDayOfWeek: job.scheduleDayOfWeek,
+          scheduleCron: job.scheduleCron,
+          timezone: job.timezone,
+          weekdaysOnly: job.weekdaysOnly,
+        },
+        now,
+      );
+    } catch (err) {
+      problems.push(`schedule: ${err instanceof Error ? err.message : String(err)}`);
+    }
+  }
+
+  try {
+    validateChannel(toEditableChannel(job));
+  } catch (err) {
+    if (!(err instanceof ChannelConfigError)) {
+      throw err;
+    }
+    if (err.kind === "decrypt") {
+      problems.push(`channel: cannot decrypt (${err.issues.join("; ")})`);
+    } else {
+      problems.push(...err.issues.map((issue) => `channel: ${issue}`));
+    }
+  }
+  return problems;
+}
+
+// Checks every job (enabled or not) in batches; only jobs with problems are returned.
+export async function validateJobs() {
+  const failing: Array<{ id: string; name: string; enabled: boolean; problems: string[] }> = [];
+  let checked = 0;
+  let cursor: string | undefined;
+  while (true) {
+    const jobs = await prisma.job.findMany({
+      orderBy: { id: "asc" },
+      take: VALIDATE_BATCH,
+      ...(cursor ? { cursor: { id: cursor }, skip: 1 } : {}),
+      select: {
+        id: true,
+        name: true,
+        enabled: true,
+        channelType: true,
+        channelConfig: true,
+        scheduleType: true,
+        scheduleTime: true,
+        scheduleDayOfWeek: true,
+        scheduleCron: true,
+        timezone: true,
+        runAt: true,
+        weekdaysOnly: true,
+      },
+    });
+    for (const job of jobs) {
+      const problems = jobProblems(job);
+      if (problems.length) {
+        failing.push({ id: job.id, name: job.name, enabled: job.enabled, problems });
+      }
+    }
+    checked += jobs.length;
+    if (jobs.length < VALIDATE_BATCH) {
+      break;
+    }
+    cursor = jobs[jobs.length - 1].id;
+  }
+  return { checked, failing };
+}
+
+// Enabled jobs in due order, as the worker would claim them (environment filter optional).
+export async function upcomingRuns(limit: number, environment?: string) {
+  return prisma.job.findMany({
+    where: { enabled: true, ...(environment ? { environment } : {}) },
+    orderBy: { nextRunAt: "asc" },
+    take: limit,
+    select: {
+      id: true,
+      name: true,
+      nextRunAt: true,
+      scheduleType: true,
+      scheduleTime: true,
+      scheduleCron: true,
+      timezone: true,
+      environment: true,
+      lockedAt: true,
+    },
+  });
+}
@@ -0,0 +1,45 @@
+import { describe, expect, it } from "vitest";
+import { checkDestination, isPrivateAddress, matchesEntry, parseDestinationPolicy } from "./destination-policy";
+
+const noDns = async () => ["93.184.216.34"];
+
+describe("destination policy", () => {
+  it("matches domains, subdomains and regex entries", () => {
+    const url = new URL("https://hooks.evil.example/path?x=1");
+    expect(matchesEntry("evil.example", url)).toBe(true);
+    expect(matchesEntry("*.evil.example", url)).toBe(true);
+    expect(matchesEntry("vil.example", url)).toBe(false);
+    expect(matchesEntry("/path\\?x=/", url)).toBe(true);
+  });
+
+  it("detects private and loopback addresses", () => {
+    expect(["10.1.2.3", "172.20.0.1", "192.168.1.1", "127.0.0.1", "169.254.169.254", "::1", "fd00::1", "::ffff:10.0.0.1"].every(isPrivateAddress)).toBe(
+      true,
+    );
+    expect(["8.8.8.8", "172.32.0.1", "2606:4700::1111"].some(isPrivateAddress)).toBe(false);
+  });
+
+  it("applies global deny rules and layered plan allow lists", async () => {
+    const policy = parseDestinationPolicy(
+      JSON.stringify({ deny: ["spam.example"], plans: { free: { allow: ["discord.com"] } } }),
+    );
+    expect(await checkDestination("https://spam.example/hook", "pro", policy, noDns)).toContain("deny entry spam.example");
+    expect(await checkDestination("https://hooks.example/x", "pro", policy, noDns)).toBeNull();
+    expect(await checkDestination("https://hooks.example/x", "free", policy, noDns)).toContain("free plan allow list");
+    expect(await checkDestination("https://discord.com/api/webhooks/1/a", "free", policy, noDns)).toBeNull();
+  });
+
+  it("blocks private networks, including names that resolve to them", async () => {
+    const policy = parseDestinationPolicy(JSON.stringify({ blockPrivateNetworks: true }));
+    expect(await checkDestination("http://169.254.169.254/latest", null, policy, noDns)).toContain("private network");
+    expect(await checkDestination("http://localhost:8123", null, policy, noDns)).toContain("private network");
+    expect(await checkDestination("http://internal.corp", null, policy, async () => ["10.0.0.5"])).toContain("private network");
+    expect(await checkDestination("https://example.com", null, policy, noDns)).toBeNull();
+  });
+
+  it("fails closed on an invalid policy", async () => {
+    expect(parseDestinationPolicy("{nope")).toBeNull();
+    expect(await checkDestination("https://example.com", null, null, noDns)).toContain("not valid JSON");
+    expect(await checkDestination("https://example.com", null, parseDestinationPolicy(undefined), noDns)).toBeNull();
+  });
+});
@@ -0,0 +1,129 @@
+import { lookup } from "node:dns/promises";
+import { isIP } from "node:net";
+import type { UserPlan } from "@/lib/entitlements";
+import { isRecord } from "@/lib/type-guards";
+
+// Operator-managed rules for where deliveries may go, from DELIVERY_DESTINATION_POLICY (JSON), e.g.
+//   {"deny": ["evil.example", "/\\.onion\\//"], "blockPrivateNetworks": true,
+//    "plans": {"free": {"allow": ["discord.com", "hooks.slack.com"]}}}
+// Entries are domains (matching subdomains too) or /regex/ tested against the full URL. A plan's rules are added
+// to the global ones: both deny lists apply, and a destination must pass every non-empty allow list.
+export type DestinationRules = { allow: string[]; deny: string[]; blockPrivateNetworks: boolean };
+export type DestinationPolicy = DestinationRules & { plans: Partial<Record<UserPlan, DestinationRules>> };
+
+type Lookup = (hostname: string) => Promise<string[]>;
+
+const EMPTY_RULES: DestinationRules = { allow: [], deny: [], blockPrivateNetworks: false };
+
+function parseRules(value: unknown): DestinationRules {
+  if (!isRecord(value)) {
+    return EMPTY_RULES;
+  }
+  const list = (entries: unknown) =>
+    Array.isArray(entries) ? entries.filter((entry): entry is string => typeof entry === "string" && entry.trim() !== "").map((entry) => entry.trim()) : [];
+  return { allow: list(value.allow), deny: list(value.deny), blockPrivateNetworks: value.blockPrivateNetworks === true };
+}
+
+// An unparseable policy fails closed: every external delivery is blocked until it is fixed.
+export function parseDestinationPolicy(raw: string | undefined): DestinationPolicy | null {
+  if (!raw?.trim()) {
+    return { ...EMPTY_RULES, plans: {} };
+  }
+  try {
+    const parsed = JSON.parse(raw) as unknown;
+    if (!isRecord(parsed)) {
+      return null;
+    }
+    const plans = isRecord(parsed.plans)
+      ? Object.fromEntries(Object.entries(parsed.plans).map(([plan, rules]) => [plan, parseRules(rules)]))
+      : {};
+    return { ...parseRules(parsed), plans };
+  } catch {
+    return null;
+  }
+}
+
+export function matchesEntry(entry: string, url: URL) {
+  if (entry.length > 2 && entry.startsWith("/") && entry.endsWith("/")) {
+    try {
+      return new RegExp(entry.slice(1, -1), "i").test(url.href);
+    } catch {
+      return false;
+    }
+  }
+  const domain = entry.toLowerCase().replace(/^\*\./, "").replace(/\.$/, "");
+  const host = url.hostname.toLowerCase().replace(/^\[|\]$/g, "");
+  return host === domain || host.endsWith(`.${domain}`);
+}
+
+export function isPrivateAddress(address: string) {
+  const ip = address.toLowerCase().replace(/^::ffff:(?=\d+\.)/, "");
+  if (isIP(ip) === 4) {
+    const [a, b] = ip.split(".").map(Number);
+    return (
+      a === 0 ||
+      a === 10 ||
+      a === 127 ||
+      (a === 100 && b >= 64 && b <= 127) ||
+      (a === 169 && b === 254) ||
+      (a === 172 && b >= 16 && b <= 31) ||
+      (a === 192 && b === 168)
+    );
+  }
+  if (isIP(ip) === 6) {
+    return ip === "::" || ip === "::1" || /^f[cd]/.test(ip) || /^fe[89ab]/.test(ip);
+  }
+  return false;
+}
+
+async function resolveAll(hostname: string) {
+  const records = await lookup(hostname, { all: true });
+  return records.map((record) => record.address);
+}
+
+// Reason the destination is blocked, or null when it may be used.
+export async function checkDestination(
+  rawUrl: string,
+  plan?: UserPlan | null,
+  policy = parseDestinationPolicy(process.env.DELIVERY_DESTINATION_POLICY),
+  resolve: Lookup = resolveAll,
+) {
+  if (!policy) {
+    return "DELIVERY_DESTINATION_POLICY is not valid JSON";
+  }
+  let url: URL;
+  try {
+    url = new URL(rawUrl);
+  } catch {
+    return "invalid URL";
+  }
+
+  const planRules = plan ? policy.plans[plan] : undefined;
+  const layers = planRules ? [policy, planRules] : [policy];
+  for (const rules of layers) {
+    const denied = rules.deny.find((entry) => matchesEntry(entry, url));
+    if (denied) {
+      return `${url.hostname} matches deny entry ${denied}`;
+    }
+    if (rules.allow.length && !rules.allow.some((entry) => matchesEntry(entry, url))) {
+      return `${url.hostname} is not on the ${rules === policy ? "" : `${plan} plan `}allow list`;
+    }
+  }
+
+  if (layers.some((rules) => rules.blockPrivateNetworks)) {
+    const host = url.hostname.replace(/^\[|\]$/g, "");
+    if (host.toLowerCase() === "localhost" || host.toLowerCase().endsWith(".localhost")) {
+      return `${host} is a private network address`;
+    }
+    let addresses: string[];
+    try {
+      addresses = isIP(host) ? [host] : await resolve(host);
+    } catch {
+      return `${host} does not resolve`;
+    }
+    if (addresses.some(isPrivateAddress)) {
+      return `${host} is a private network address`;
+    }
+  }
+  return null;
+}
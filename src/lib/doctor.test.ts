@@ -0,0 +1,48 @@
+import { afterEach, beforeEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { ChannelType } from "@prisma/client";
+import { encryptString } from "./crypto";
+import { channelOrigins, encryptionCheck, schemaCheck } from "./doctor";
+
+beforeEach(() => {
+  vi.stubEnv("CHANNEL_SECRET_KEY", "test-secret");
+});
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+const discord = (url: string) => ({ channelType: ChannelType.discord, channelConfig: { webhookUrlEnc: encryptString(url) } });
+
+describe("doctor", () => {
+  it("compares applied and bundled migrations", () => {
+    expect(schemaCheck(["0401_a", "0402_b"], ["0401_a", "0402_b"])).toMatchObject({ status: "ok", detail: "up to date at 0402_b" });
+    expect(schemaCheck(["0401_a"], ["0401_a", "0402_b", "0403_c"])).toMatchObject({ status: "fail", detail: expect.stringContaining("2 pending") });
+    expect(schemaCheck(["0401_a"], [])).toMatchObject({ status: "warn" });
+  });
+
+  it("reports channel configs that do not decrypt", () => {
+    expect(encryptionCheck([discord("https://discord.com/api/webhooks/1/a")])).toMatchObject({ status: "ok" });
+    const broken = { channelType: ChannelType.telegram, channelConfig: { botTokenEnc: "bad", chatIdEnc: "bad" } };
+    expect(encryptionCheck([discord("https://discord.com/api/webhooks/1/a"), broken])).toMatchObject({
+      status: "fail",
+      detail: "1 of 2 channel config(s) do not decrypt",
+    });
+
+    vi.stubEnv("CHANNEL_SECRET_KEY", "");
+    vi.stubEnv("NEXTAUTH_SECRET", "");
+    expect(encryptionCheck([])).toMatchObject({ status: "fail" });
+  });
+
+  it("collects distinct channel origins", () => {
+    const telegram = {
+      channelType: ChannelType.telegram,
+      channelConfig: { botTokenEnc: encryptString("123:abc"), chatIdEnc: encryptString("42") },
+    };
+    expect(
+      channelOrigins([discord("https://discord.com/api/webhooks/1/a"), discord("https://discord.com/api/webhooks/2/b"), telegram]),
+    ).toEqual(["https://api.telegram.org", "https://discord.com"]);
+  });
+});
@@ -0,0 +1,211 @@
+import { existsSync, readdirSync } from "fs";
+import { join } from "path";
+import { ChannelType } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { channelEndpointUrl, DEFAULT_USER_AGENT } from "@/lib/channel";
+import { currentKeyId, decryptString, encryptString } from "@/lib/crypto";
+import { checkDestination } from "@/lib/destination-policy";
+import { toRunnableChannel, type StoredChannel } from "@/lib/jobs";
+import { fakeLlmConfig } from "@/lib/fake-llm";
+import { openaiApiKeys } from "@/lib/openai-keys";
+
+// Preflight checks behind `promptloop doctor`: everything a deployment needs before the worker can run jobs.
+export type DoctorStatus = "ok" | "warn" | "fail";
+export type DoctorCheck = { name: string; status: DoctorStatus; detail: string };
+
+const CHECK_TIMEOUT_MS = 5000;
+const HOSTS_MAX = 50;
+
+function message(err: unknown) {
+  return err instanceof Error ? err.message : String(err);
+}
+
+// Migration directories shipped with this build, oldest first; empty when the build has no prisma/ folder.
+function bundledMigrations(dir = join(process.cwd(), "prisma", "migrations")) {
+  if (!existsSync(dir)) {
+    return [];
+  }
+  return readdirSync(dir, { withFileTypes: true })
+    .filter((entry) => entry.isDirectory())
+    .map((entry) => entry.name)
+    .sort();
+}
+
+export function schemaCheck(applied: string[], bundled: string[]): DoctorCheck {
+  const latest = applied[applied.length - 1] ?? "none";
+  if (!bundled.length) {
+    return { name: "schema", status: "warn", detail: `latest applied migration ${latest}; no prisma/migrations in this build to compare` };
+  }
+  const pending = bundled.filter((name) => !applied.includes(name));
+  if (pending.length) {
+    return { name: "schema", status: "fail", detail: `${pending.length} pending migration(s), starting with ${pending[0]}; run prisma migrate deploy` };
+  }
+  return { name: "schema", status: "ok", detail: `up to date at ${latest}` };
+}
+
+async function databaseChecks(): Promise<DoctorCheck[]> {
+  const startedAt = Date.now();
+  try {
+    await prisma.$queryRaw`SELECT 1`;
+  } catch (err) {
+    return [{ name: "database", status: "fail", detail: message(err) }];
+  }
+  const database: DoctorCheck = { name: "database", status: "ok", detail: `connected in ${Date.now() - startedAt}ms` };
+  try {
+    const rows = await prisma.$queryRaw<Array<{ migration_name: string }>>`
+      SELECT migration_name FROM "_prisma_migrations"
+      WHERE finished_at IS NOT NULL AND rolled_back_at IS NULL
+      ORDER BY migration_name
+    `;
+    return [database, schemaCheck(rows.map((row) => row.migration_name), bundledMigrations())];
+  } catch (err) {
+    return [database, { name: "schema", status: "fail", detail: `cannot read _prisma_migrations: ${message(err)}` }];
+  }
+}
+
+// The current key must round-trip, and every stored channel config must still decrypt with the configured keys.
+export function encryptionCheck(channels: StoredChannel[]): DoctorCheck {
+  try {
+    const keyId = currentKeyId();
+    if (decryptString(encryptString("doctor")) !== "doctor") {
+      return { name: "encryption key", status: "fail", detail: "round trip returned a different value" };
+    }
+    const failing = channels.filter((channel) => {
+      try {
+        toRunnableChannel(channel);
+        return false;
+      } catch {
+        return true;
+      }
+    });
+    if (failing.length) {
+      return { name: "encryption key", status: "fail", detail: `${failing.length} of ${channels.length} channel config(s) do not decrypt` };
+    }
+    return { name: "encryption key", status: "ok", detail: `key ${keyId ?? "(unversioned)"}; ${channels.length} channel config(s) decrypt` };
+  } catch (err) {
+    return { name: "encryption key", status: "fail", detail: message(err) };
+  }
+}
+
+type ProviderProbe = {
+  name: string;
+  env: string;
+  required: boolean;
+  request: (key: string) => [string, Record<string, string>];
+  // Several keys to check one by one (the OpenAI key pool); defaults to the env var's value.
+  keys?: () => string[];
+};
+
+// Model list calls: free, and they fail with 401/403 on a bad key.
+const PROVIDERS: ProviderProbe[] = [
+  {
+    name: "openai",
+    env: "OPENAI_API_KEY",
+    required: true,
+    request: (key) => ["https://api.openai.com/v1/models", { authorization: `Bearer ${key}` }],
+    keys: openaiApiKeys,
+  },
+  {
+    name: "anthropic",
+    env: "ANTHROPIC_API_KEY",
+    required: false,
+    request: (key) => ["https://api.anthropic.com/v1/models", { "x-api-key": key, "anthropic-version": "2023-06-01" }],
+  },
+  {
+    name: "google",
+    env: "GOOGLE_GENERATIVE_AI_API_KEY",
+    required: false,
+    request: (key) => ["https://generativelanguage.googleapis.com/v1beta/models", { "x-goog-api-key": key }],
+  },
+  // OpenRouter's model list is public; the key endpoint is what rejects a bad key.
+  { name: "openrouter", env: "OPENROUTER_API_KEY", required: false, request: (key) => ["https://openrouter.ai/api/v1/key", { authorization: `Bearer ${key}` }] },
+];
+
+async function providerChecks(): Promise<DoctorCheck[]> {
+  const fake = fakeLlmConfig();
+  if (fake) {
+    return [{ name: "provider fake", status: "warn", detail: `FAKE_LLM=${fake.mode}: job runs never call a provider` }];
+  }
+  return Promise.all(
+    PROVIDERS.flatMap((provider): Array<Promise<DoctorCheck>> => {
+      const keys = provider.keys ? provider.keys() : [process.env[provider.env]?.trim() ?? ""].filter(Boolean);
+      if (!keys.length) {
+        const name = `provider ${provider.name}`;
+        return provider.required ? [Promise.resolve({ name, status: "fail", detail: `${provider.env} is not set` })] : [];
+      }
+      return keys.map((key, index) => {
+        const name = keys.length > 1 ? `provider ${provider.name} key ${index + 1}` : `provider ${provider.name}`;
+        const [url, headers] = provider.request(key);
+        return fetch(url, { headers, signal: AbortSignal.timeout(CHECK_TIMEOUT_MS) })
+          .then((res): DoctorCheck => {
+            if (res.ok) {
+              return { name, status: "ok", detail: "key accepted" };
+            }
+            if (res.status === 401 || res.status === 403) {
+              return { name, status: "fail", detail: `key rejected (HTTP ${res.status})` };
+            }
+            return { name, status: "warn", detail: `models list returned HTTP ${res.status}` };
+          })
+          .catch((err): DoctorCheck => ({ name, status: "fail", detail: `unreachable: ${message(err)}` }));
+      });
+    }),
+  );
+}
+
+// Origins (scheme://host:port) of the channels that enabled jobs deliver to.
+export function channelOrigins(channels: StoredChannel[]) {
+  const origins = new Set<string>();
+  for (const channel of channels) {
+    try {
+      const url = channelEndpointUrl(toRunnableChannel(channel));
+      // The reachability probe is an HTTP request; NATS servers and MQTT brokers are only checked when a delivery connects.
+      if (url && /^https?:\/\//i.test(url)) {
+        origins.add(new URL(url).origin);
+      }
+    } catch {
+      // Undecryptable or invalid configs are reported by the encryption check.
+    }
+  }
+  return [...origins].sort();
+}
+
+// Any HTTP answer proves the host is reachable; only network errors and timeouts fail.
+async function reachabilityCheck(origin: string): Promise<DoctorCheck> {
+  const name = `reach ${new URL(origin).host}`;
+  const blocked = await checkDestination(origin);
+  if (blocked) {
+    return { name, status: "warn", detail: `blocked by destination policy: ${blocked}` };
+  }
+  const startedAt = Date.now();
+  try {
+    const res = await fetch(origin, {
+      method: "HEAD",
+      redirect: "manual",
+      headers: { "User-Agent": DEFAULT_USER_AGENT },
+      signal: AbortSignal.timeout(CHECK_TIMEOUT_MS),
+    });
+    return { name, status: "ok", detail: `HTTP ${res.status} in ${Date.now() - startedAt}ms` };
+  } catch (err) {
+    return { name, status: "fail", detail: message(err) };
+  }
+}
+
+export async function runDoctor(): Promise<{ ok: boolean; checks: DoctorCheck[] }> {
+  const checks = await databaseChecks();
+  let channels: StoredChannel[] = [];
+  if (checks[0].status !== "fail") {
+    channels = await prisma.job.findMany({
+      where: { enabled: true, channelType: { not: ChannelType.in_app } },
+      select: { channelType: true, channelConfig: true },
+    });
+  }
+  checks.push(encryptionCheck(channels));
+  checks.push(...(await providerChecks()));
+
+  const origins = channelOrigins(channels);
+  checks.push(...(await Promise.all(origins.slice(0, HOSTS_MAX).map(reachabilityCheck))));
+  if (origins.length > HOSTS_MAX) {
+    checks.push({ name: "reach", status: "warn", detail: `${origins.length - HOSTS_MAX} more channel host(s) not checked` });
+  }
+  return { ok: checks.every((check) => check.status !== "fail"), checks };
+}
@@ -0,0 +1,28 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { dormantCutoff, dormantWeeks, formatDormantNotice } from "./dormant-jobs";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("dormant jobs", () => {
+  it("is off unless WORKER_DORMANT_WEEKS is positive", () => {
+    expect(dormantWeeks()).toBe(0);
+    vi.stubEnv("WORKER_DORMANT_WEEKS", "-2");
+    expect(dormantWeeks()).toBe(0);
+    vi.stubEnv("WORKER_DORMANT_WEEKS", "8");
+    expect(dormantWeeks()).toBe(8);
+  });
+
+  it("computes the cutoff in whole weeks", () => {
+    expect(dormantCutoff(2, new Date("2026-03-15T00:00:00Z")).toISOString()).toBe("2026-03-01T00:00:00.000Z");
+  });
+
+  it("explains why the job was paused", () => {
+    expect(formatDormantNotice("owner_inactive", 1)).toContain("for 1 week.");
+    expect(formatDormantNotice("channel_failing", 6)).toContain("every delivery for the last 6 weeks has failed");
+  });
+});
@@ -0,0 +1,100 @@
+import { ChannelType } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { sendChannelMessage } from "@/lib/channel";
+import { runnableJobChannel } from "@/lib/saved-channels";
+import { formatRunTitle } from "@/lib/run-title";
+import { recordAudit } from "@/lib/audit";
+import { logger } from "@/lib/logger";
+import { clock } from "@/lib/clock";
+
+export type DormantReason = "owner_inactive" | "channel_failing";
+
+const WEEK_MS = 7 * 24 * 60 * 60 * 1000;
+
+// WORKER_DORMANT_WEEKS: pause jobs whose owner has not been seen, or whose channel has only failed, for this
+// many weeks. 0 (default) turns the policy off.
+export function dormantWeeks() {
+  const raw = Number(process.env.WORKER_DORMANT_WEEKS ?? 0);
+  return Number.isFinite(raw) && raw > 0 ? Math.floor(raw) : 0;
+}
+
+export function dormantCutoff(weeks: number, now = clock().now()) {
+  return new Date(now.getTime() - weeks * WEEK_MS);
+}
+
+export function formatDormantNotice(reason: DormantReason, weeks: number) {
+  const why =
+    reason === "owner_inactive"
+      ? `nobody has signed in to this account for ${weeks} week${weeks === 1 ? "" : "s"}`
+      : `every delivery for the last ${weeks} week${weeks === 1 ? "" : "s"} has failed`;
+  return `This job was paused because ${why}. Turn it back on from the dashboard to resume.`;
+}
+
+// Finds enabled jobs that are dormant under the policy, disables them, and tells the owner: through the job's
+// channel when the owner went quiet (the channel still works), and through the dashboard and audit log either way.
+export async function pauseDormantJobs(limit: number, now = clock().now()) {
+  const weeks = dormantWeeks();
+  if (!weeks) {
+    return 0;
+  }
+  const cutoff = dormantCutoff(weeks, now);
+
+  // Channel failing: older than the window, nothing delivered within it, and at least one delivery that failed
+  // for good (a delivery_failed dead letter) within it. In-app jobs have no channel to fail.
+  const rows = await prisma.$queryRaw<Array<{ id: string; reason: DormantReason }>>`
+    SELECT j.id,
+      CASE WHEN COALESCE(u.last_seen_at, u.created_at) < ${cutoff} THEN 'owner_inactive' ELSE 'channel_failing' END AS reason
+    FROM jobs j
+    JOIN users u ON u.id = j.user_id
+    WHERE j.enabled = true
+      AND j.created_at < ${cutoff}
+      AND (
+        COALESCE(u.last_seen_at, u.created_at) < ${cutoff}
+        OR (
+          j.channel_type <> 'in_app'
+          AND NOT EXISTS (
+            SELECT 1 FROM run_histories r WHERE r.job_id = j.id AND r.delivered_at >= ${cutoff}
+          )
+          AND EXISTS (
+            SELECT 1 FROM dead_letters d WHERE d.job_id = j.id AND d.reason = 'delivery_failed' AND d.created_at >= ${cutoff}
+          )
+        )
+      )
+    ORDER BY j.created_at
+    LIMIT ${limit}
+  `;
+
+  let paused = 0;
+  for (const row of rows) {
+    const claimed = await prisma.job.updateMany({
+      where: { id: row.id, enabled: true },
+      data: { enabled: false, dormantReason: row.reason, dormantAt: now },
+    });
+    if (!claimed.count) {
+      continue;
+    }
+    paused++;
+    const job = await prisma.job.findUnique({ where: { id: row.id } });
+    if (!job) {
+      continue;
+    }
+    const log = logger.with({ job_id: job.id, job_name: job.name, channel_type: job.channelType });
+    log.warn("dormant job paused", { reason: row.reason, weeks });
+    await recordAudit({
+      userId: job.userId,
+      action: "job.dormant_pause",
+      entityType: "job",
+      entityId: job.id,
+      data: { reason: row.reason, weeks },
+    });
+    if (row.reason === "owner_inactive" && job.channelType !== ChannelType.in_app) {
+      await sendChannelMessage(
+        await runnableJobChannel(job),
+        formatRunTitle(job.name, now, job.timezone ?? "UTC"),
+        formatDormantNotice(row.reason, weeks),
+        { userAgent: job.userAgent, meta: { kind: "dormant_notice", jobId: job.id, tags: job.tags } },
+      ).catch((err) => log.warn("dormant notice failed", { error: err }));
+    }
+  }
+  return paused;
+}
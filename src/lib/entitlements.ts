@@ -8,6 +8,8 @@ export type Entitlements = {
     enabledJobsLimit: number;
     totalJobsLimit: number;
     dailyRunLimit: number;
+    // Monthly LLM spend cap in USD; null means no cap.
+    monthlyBudgetUsd: number | null;
   };
 };
 
@@ -37,6 +39,15 @@ function resolveDailyRunLimit(overrideDailyRunLimit: number | null | undefined,
   return PLAN_DEFAULTS[plan].dailyRunLimit;
 }
 
+function resolveMonthlyBudgetUsd(overrideMonthlyBudgetUsd: number | null | undefined) {
+  if (overrideMonthlyBudgetUsd != null) {
+    return overrideMonthlyBudgetUsd;
+  }
+  const raw = process.env.MONTHLY_BUDGET_USD?.trim();
+  const envBudget = raw ? Number(raw) : NaN;
+  return Number.isFinite(envBudget) && envBudget >= 0 ? envBudget : null;
+}
+
 export async function getEntitlements(userId: string): Promise<Entitlements> {
   const user = await prisma.user.findUnique({
     where: { id: userId },
@@ -45,6 +56,7 @@ export async function getEntitlements(userId: string): Promise<Entitlements> {
       overrideEnabledJobsLimit: true,
       overrideTotalJobsLimit: true,
       overrideDailyRunLimit: true,
+      overrideMonthlyBudgetUsd: true,
     },
   });
 
@@ -54,6 +66,7 @@ export async function getEntitlements(userId: string): Promise<Entitlements> {
   const enabledJobsLimit = user?.overrideEnabledJobsLimit ?? defaults.enabledJobsLimit;
   const totalJobsLimit = user?.overrideTotalJobsLimit ?? defaults.totalJobsLimit;
   const dailyRunLimit = resolveDailyRunLimit(user?.overrideDailyRunLimit, plan);
+  const monthlyBudgetUsd = resolveMonthlyBudgetUsd(user?.overrideMonthlyBudgetUsd);
 
   return {
     plan,
@@ -61,6 +74,7 @@ export async function getEntitlements(userId: string): Promise<Entitlements> {
       enabledJobsLimit,
       totalJobsLimit,
       dailyRunLimit,
+      monthlyBudgetUsd,
     },
   };
 }
@@ -0,0 +1,82 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+import { __private__, parseSentryDsn, reportError } from "./error-reporting";
+
+afterEach(() => {
+  __private__.resetDedupe();
+  vi.unstubAllEnvs();
+  vi.unstubAllGlobals();
+});
+
+describe("error reporting", () => {
+  it("parses Sentry DSNs into their envelope endpoint", () => {
+    expect(parseSentryDsn("https://abc123@o1.ingest.sentry.io/42")).toMatchObject({
+      url: "https://o1.ingest.sentry.io/api/42/envelope/",
+      key: "abc123",
+    });
+    expect(parseSentryDsn("https://key@sentry.example.com/relay/7")?.url).toBe("https://sentry.example.com/relay/api/7/envelope/");
+    expect(parseSentryDsn("https://sentry.example.com/7")).toBeNull();
+    expect(parseSentryDsn("not a dsn")).toBeNull();
+    expect(parseSentryDsn(undefined)).toBeNull();
+  });
+
+  it("sends the error with its job context to Sentry and the error webhook", async () => {
+    const fetchMock = vi.fn().mockResolvedValue(new Response(null, { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+    vi.stubEnv("SENTRY_DSN", "https://abc123@sentry.example.com/42");
+    vi.stubEnv("ERROR_WEBHOOK_URL", "https://hooks.example.com/errors");
+    vi.stubEnv("WORKER_ENV", "staging");
+
+    await reportError(new Error("connection terminated"), { stage: "job", job_id: "job_1", job_name: undefined });
+
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+    const [sentryUrl, sentryInit] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(sentryUrl).toBe("https://sentry.example.com/api/42/envelope/");
+    expect(new Headers(sentryInit.headers).get("X-Sentry-Auth")).toContain("sentry_key=abc123");
+    const event = JSON.parse(String(sentryInit.body).trim().split("\n")[2]);
+    expect(event).toMatchObject({
+      level: "error",
+      environment: "staging",
+      exception: { values: [{ type: "Error", value: "connection terminated" }] },
+      tags: { stage: "job", job_id: "job_1" },
+    });
+    expect(event.tags).not.toHaveProperty("job_name");
+
+    const [webhookUrl, webhookInit] = fetchMock.mock.calls[1] as [string, RequestInit];
+    expect(webhookUrl).toBe("https://hooks.example.com/errors");
+    expect(JSON.parse(String(webhookInit.body))).toMatchObject({
+      event: "worker_error",
+      error: "connection terminated",
+      environment: "staging",
+      job_id: "job_1",
+    });
+  });
+
+  it("reports an error once and repeats of a message once per window", async () => {
+    const fetchMock = vi.fn().mockResolvedValue(new Response(null, { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+    vi.stubEnv("ERROR_WEBHOOK_URL", "https://hooks.example.com/errors");
+
+    const err = new Error("decrypt failed");
+    await reportError(err, { stage: "job" });
+    await reportError(err, { stage: "tick" });
+    await reportError(new Error("decrypt failed"), { stage: "tick" });
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+
+    vi.stubEnv("ERROR_REPORT_DEDUPE_SECONDS", "0");
+    await reportError(new Error("decrypt failed"), { stage: "tick" });
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+  });
+
+  it("does nothing without a sink and never throws when sending fails", async () => {
+    const fetchMock = vi.fn().mockRejectedValue(new Error("ECONNREFUSED"));
+    vi.stubGlobal("fetch", fetchMock);
+
+    await reportError(new Error("ignored"));
+    expect(fetchMock).not.toHaveBeenCalled();
+
+    vi.stubEnv("ERROR_WEBHOOK_URL", "https://hooks.example.com/errors");
+    await expect(reportError(new Error("unreachable"))).resolves.toBeUndefined();
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+  });
+});
@@ -0,0 +1,159 @@
+import { randomUUID } from "node:crypto";
+import { hostname } from "node:os";
+import packageJson from "../../package.json";
+import { logger } from "@/lib/logger";
+
+// Worker-level errors (a failed tick, a job that crashed outside the normal failure path, housekeeping steps
+// hitting the database, channel configs that can no longer be decrypted) are sent to Sentry when SENTRY_DSN is set
+// and/or POSTed as JSON to ERROR_WEBHOOK_URL, so operators hear about them without tailing logs. No SDK is
+// involved: events go straight to the DSN's envelope endpoint. Reports are best effort and never throw; the same
+// message is sent at most once per ERROR_REPORT_DEDUPE_SECONDS (default: 300) per process.
+
+export type ErrorContext = Record<string, string | number | boolean | null | undefined>;
+
+type SentryDsn = { url: string; key: string; dsn: string };
+
+const REPORT_TIMEOUT_MS = 5000;
+const RELEASE = `promptloop@${packageJson.version}`;
+
+const reported = new WeakSet<object>();
+const lastSent = new Map<string, number>();
+
+export function parseSentryDsn(raw: string | undefined): SentryDsn | null {
+  if (!raw?.trim()) {
+    return null;
+  }
+  try {
+    const url = new URL(raw.trim());
+    const segments = url.pathname.split("/").filter(Boolean);
+    const project = segments.pop();
+    if (!url.username || !project || !/^\d+$/.test(project)) {
+      return null;
+    }
+    const prefix = segments.length ? `/${segments.join("/")}` : "";
+    return { url: `${url.protocol}//${url.host}${prefix}/api/${project}/envelope/`, key: url.username, dsn: raw.trim() };
+  } catch {
+    return null;
+  }
+}
+
+function dedupeWindowMs() {
+  const seconds = Number(process.env.ERROR_REPORT_DEDUPE_SECONDS ?? 300);
+  return (Number.isFinite(seconds) && seconds >= 0 ? seconds : 300) * 1000;
+}
+
+function reportEnvironment() {
+  return process.env.SENTRY_ENVIRONMENT?.trim() || process.env.WORKER_ENV?.trim() || "production";
+}
+
+function describe(err: unknown) {
+  if (err instanceof Error) {
+    return { type: err.name || "Error", message: err.message, stack: err.stack };
+  }
+  return { type: "Error", message: typeof err === "string" ? err : JSON.stringify(err) ?? String(err), stack: undefined };
+}
+
+function cleanContext(context: ErrorContext) {
+  return Object.fromEntries(Object.entries(context).filter((entry): entry is [string, string | number | boolean] => entry[1] != null));
+}
+
+export function sentryEnvelope(dsn: SentryDsn, err: unknown, context: ErrorContext, now = new Date()) {
+  const eventId = randomUUID().replace(/-/g, "");
+  const { type, message, stack } = describe(err);
+  const tags = Object.fromEntries(Object.entries(cleanContext(context)).map(([key, value]) => [key, String(value)]));
+  const event = {
+    event_id: eventId,
+    timestamp: now.getTime() / 1000,
+    platform: "node",
+    level: "error",
+    logger: "promptloop.worker",
+    server_name: hostname(),
+    environment: reportEnvironment(),
+    release: RELEASE,
+    exception: { values: [{ type, value: message }] },
+    tags,
+    extra: stack ? { stack } : {},
+  };
+  return [JSON.stringify({ event_id: eventId, sent_at: now.toISOString(), dsn: dsn.dsn }), JSON.stringify({ type: "event" }), JSON.stringify(event)].join("\n") + "\n";
+}
+
+async function post(target: string, url: string, init: RequestInit) {
+  try {
+    const res = await fetch(url, { ...init, signal: AbortSignal.timeout(REPORT_TIMEOUT_MS) });
+    if (!res.ok) {
+      logger.warn("error report failed", { target, status_code: res.status });
+    }
+  } catch (err) {
+    logger.warn("error report failed", { target, error: err });
+  }
+}
+
+// Sends `err` with its context (job_id, job_name, channel_type, step, ...) to the configured sinks. An error object
+// is reported once even when it is rethrown through several layers that each report it.
+export async function reportError(err: unknown, context: ErrorContext = {}) {
+  const dsn = parseSentryDsn(process.env.SENTRY_DSN);
+  const webhookUrl = process.env.ERROR_WEBHOOK_URL?.trim();
+  if (!dsn && !webhookUrl) {
+    return;
+  }
+  if (typeof err === "object" && err !== null) {
+    if (reported.has(err)) {
+      return;
+    }
+    reported.add(err);
+  }
+  const { type, message } = describe(err);
+  const fingerprint = `${type}:${message}`;
+  const now = Date.now();
+  const last = lastSent.get(fingerprint);
+  if (last !== undefined && now - last < dedupeWindowMs()) {
+    return;
+  }
+  lastSent.set(fingerprint, now);
+
+  const sends: Array<Promise<void>> = [];
+  if (dsn) {
+    sends.push(
+      post("sentry", dsn.url, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/x-sentry-envelope",
+          "X-Sentry-Auth": `Sentry sentry_version=7, sentry_key=${dsn.key}, sentry_client=${RELEASE}`,
+        },
+        body: sentryEnvelope(dsn, err, context),
+      }),
+    );
+  }
+  if (webhookUrl) {
+    const fields = cleanContext(context);
+    sends.push(
+      post("webhook", webhookUrl, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          text: `promptloop worker error (${reportEnvironment()}): ${message}`,
+          event: "worker_error",
+          error_type: type,
+          error: message,
+          environment: reportEnvironment(),
+          release: RELEASE,
+          ...fields,
+        }),
+      }),
+    );
+  }
+  await Promise.all(sends);
+}
+
+// Reports crashes that escape every handler. The process is already going down, so the report may not get out.
+let processHookInstalled = false;
+
+export function installErrorReportingHook() {
+  if (processHookInstalled) return;
+  processHookInstalled = true;
+  process.on("uncaughtExceptionMonitor", (err, origin) => {
+    void reportError(err, { stage: origin });
+  });
+}
+
+export const __private__ = { resetDedupe: () => lastSent.clear() };
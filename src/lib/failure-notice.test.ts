@@ -0,0 +1,34 @@
+import { describe, expect, it } from "vitest";
+import { failureReason, formatFailureNotice } from "./failure-notice";
+
+describe("failure notice", () => {
+  it("shortens errors to a reason", () => {
+    expect(failureReason("OpenAI API error 429: Too Many Requests")).toBe("rate limited");
+    expect(failureReason("Request timed out after 60000ms")).toBe("timed out");
+    expect(failureReason("Daily run limit exceeded (50/day)")).toBe("daily run limit reached");
+    expect(failureReason("Empty output\nstack")).toBe("Empty output");
+    expect(failureReason("x".repeat(300))).toHaveLength(160);
+  });
+
+  it("mentions the retry time in the job timezone", () => {
+    const notice = formatFailureNotice({
+      errorMessage: "429 rate limit",
+      retryAt: new Date("2026-03-02T01:30:00Z"),
+      nextRunAt: new Date("2026-03-03T00:00:00Z"),
+      disabled: false,
+      timeZone: "Asia/Seoul",
+    });
+    expect(notice).toBe("Today's run failed: rate limited, will retry at 10:30.");
+  });
+
+  it("falls back to the next slot or the disabled state", () => {
+    const base = { errorMessage: "Empty output", retryAt: null, timeZone: "UTC" };
+    expect(formatFailureNotice({ ...base, nextRunAt: new Date("2026-03-03T09:05:00Z"), disabled: false })).toBe(
+      "Today's run failed: Empty output. The next run is at 09:05.",
+    );
+    expect(formatFailureNotice({ ...base, nextRunAt: null, disabled: true })).toContain("paused after repeated failures");
+    expect(formatFailureNotice({ ...base, nextRunAt: null, disabled: false, pausedUntil: new Date("2026-03-04T09:00:00Z") })).toBe(
+      "Today's run failed: Empty output. After repeated failures the job is paused until 2026-03-04 09:00.",
+    );
+  });
+});
@@ -0,0 +1,55 @@
+import { getPartsInTimeZone } from "@/lib/timezone";
+
+const REASON_MAX = 160;
+
+// Turns a stored run error into a short, channel-safe reason ("rate limited", "timed out", or the first line).
+export function failureReason(errorMessage: string) {
+  const message = errorMessage.trim();
+  if (/\b429\b|rate.?limit|too many requests/i.test(message)) {
+    return "rate limited";
+  }
+  if (/timed? ?out|timeout|ETIMEDOUT/i.test(message)) {
+    return "timed out";
+  }
+  if (message.startsWith("Daily run limit exceeded")) {
+    return "daily run limit reached";
+  }
+  const firstLine = message.split("\n")[0]?.trim() || "unknown error";
+  return firstLine.length > REASON_MAX ? `${firstLine.slice(0, REASON_MAX - 3)}...` : firstLine;
+}
+
+function formatClock(at: Date, timeZone: string) {
+  const parts = getPartsInTimeZone(at, timeZone);
+  return `${String(parts.hour % 24).padStart(2, "0")}:${String(parts.minute).padStart(2, "0")}`;
+}
+
+function formatDate(at: Date, timeZone: string) {
+  const parts = getPartsInTimeZone(at, timeZone);
+  return `${parts.year}-${String(parts.month).padStart(2, "0")}-${String(parts.day).padStart(2, "0")}`;
+}
+
+// One-line notice sent in place of the missing output, e.g.
+// "Today's run failed: rate limited, will retry at 10:30."
+export function formatFailureNotice(input: {
+  errorMessage: string;
+  retryAt: Date | null;
+  nextRunAt: Date | null;
+  disabled: boolean;
+  pausedUntil?: Date | null;
+  timeZone: string;
+}) {
+  const reason = failureReason(input.errorMessage);
+  if (input.disabled) {
+    return `Today's run failed: ${reason}. The job has been paused after repeated failures.`;
+  }
+  if (input.pausedUntil) {
+    return `Today's run failed: ${reason}. After repeated failures the job is paused until ${formatDate(input.pausedUntil, input.timeZone)} ${formatClock(input.pausedUntil, input.timeZone)}.`;
+  }
+  if (input.retryAt) {
+    return `Today's run failed: ${reason}, will retry at ${formatClock(input.retryAt, input.timeZone)}.`;
+  }
+  if (input.nextRunAt) {
+    return `Today's run failed: ${reason}. The next run is at ${formatClock(input.nextRunAt, input.timeZone)}.`;
+  }
+  return `Today's run failed: ${reason}.`;
+}
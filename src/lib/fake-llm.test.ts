@@ -0,0 +1,37 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { fakeLlmConfig, fakeLlmOutput, runFakePrompt, type FakeLlmConfig } from "./fake-llm";
+
+const canned: FakeLlmConfig = { mode: "canned", output: "", latencyMs: 0, errorRate: 0, errorStatus: 500 };
+
+describe("fake llm", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("is off unless FAKE_LLM names a mode", () => {
+    expect(fakeLlmConfig()).toBeNull();
+    vi.stubEnv("FAKE_LLM", "1");
+    expect(fakeLlmConfig()).toBeNull();
+    vi.stubEnv("FAKE_LLM", "Echo");
+    vi.stubEnv("FAKE_LLM_ERROR_RATE", "2");
+    vi.stubEnv("FAKE_LLM_ERROR_STATUS", "429");
+    expect(fakeLlmConfig()).toEqual({ mode: "echo", output: "", latencyMs: 0, errorRate: 0, errorStatus: 429 });
+  });
+
+  it("answers deterministically", () => {
+    const first = fakeLlmOutput(canned, "Summarize the news", "gpt-5-mini");
+    expect(first).toMatch(/^Fake output from gpt-5-mini for prompt [0-9a-f]{12}\.$/);
+    expect(fakeLlmOutput(canned, "Summarize the news", "gpt-5-mini")).toBe(first);
+    expect(fakeLlmOutput(canned, "Other prompt", "gpt-5-mini")).not.toBe(first);
+    expect(fakeLlmOutput({ ...canned, output: "Fixed" }, "x", "gpt-5-mini")).toBe("Fixed");
+    expect(fakeLlmOutput({ ...canned, mode: "echo" }, " hello \n", "gpt-5-mini")).toBe("hello");
+  });
+
+  it("injects errors at the configured rate and fakes web search", async () => {
+    const failing = { ...canned, errorRate: 0.5, errorStatus: 429 };
+    await expect(runFakePrompt("p", { model: "gpt-5-mini", useWebSearch: false }, failing, () => 0.1)).rejects.toMatchObject({ status: 429 });
+    const result = await runFakePrompt("p", { model: "gpt-5-mini", useWebSearch: true }, failing, () => 0.9);
+    expect(result).toMatchObject({ usedWebSearch: true, llmModel: "gpt-5-mini", servedModel: "fake/gpt-5-mini" });
+    expect(result.citations).toHaveLength(1);
+  });
+});
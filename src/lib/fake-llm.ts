@@ -0,0 +1,77 @@
+import { createHash } from "node:crypto";
+import type { RunPromptOptions, RunPromptResult } from "@/lib/llm";
+
+// Mock provider for end-to-end tests, staging and demos: with FAKE_LLM set, every model call is answered locally
+// and never reaches a provider, so no API key or spend is needed. FAKE_LLM=echo returns the prompt as the output;
+// FAKE_LLM=canned returns FAKE_LLM_OUTPUT, or a fixed text naming the model and a hash of the prompt, so the same
+// prompt always gives the same output. FAKE_LLM_LATENCY_MS delays each answer and FAKE_LLM_ERROR_RATE (0-1) fails
+// that share of calls with FAKE_LLM_ERROR_STATUS (default: 500), like a provider error.
+
+export const FAKE_LLM_MODES = ["echo", "canned"] as const;
+export type FakeLlmMode = (typeof FAKE_LLM_MODES)[number];
+
+export type FakeLlmConfig = { mode: FakeLlmMode; output: string; latencyMs: number; errorRate: number; errorStatus: number };
+
+export class FakeLlmError extends Error {
+  status: number;
+
+  constructor(message: string, status: number) {
+    super(message);
+    this.name = "FakeLlmError";
+    this.status = status;
+  }
+}
+
+function envNumber(name: string, fallback: number, min: number, max: number) {
+  const raw = process.env[name]?.trim();
+  const value = raw ? Number(raw) : fallback;
+  return Number.isFinite(value) && value >= min && value <= max ? value : fallback;
+}
+
+export function fakeLlmConfig(): FakeLlmConfig | null {
+  const mode = process.env.FAKE_LLM?.trim().toLowerCase();
+  if (!FAKE_LLM_MODES.includes(mode as FakeLlmMode)) {
+    return null;
+  }
+  return {
+    mode: mode as FakeLlmMode,
+    output: process.env.FAKE_LLM_OUTPUT ?? "",
+    latencyMs: Math.floor(envNumber("FAKE_LLM_LATENCY_MS", 0, 0, 600_000)),
+    errorRate: envNumber("FAKE_LLM_ERROR_RATE", 0, 0, 1),
+    errorStatus: Math.floor(envNumber("FAKE_LLM_ERROR_STATUS", 500, 400, 599)),
+  };
+}
+
+export function fakeLlmOutput(config: FakeLlmConfig, prompt: string, model: string) {
+  if (config.mode === "echo") {
+    return prompt.trim() || "(empty prompt)";
+  }
+  if (config.output.trim()) {
+    return config.output.trim();
+  }
+  const digest = createHash("sha256").update(prompt).digest("hex").slice(0, 12);
+  return `Fake output from ${model} for prompt ${digest}.`;
+}
+
+// Stands in for runPrompt. Web search calls get one placeholder citation so they pass the "no search results" check.
+export async function runFakePrompt(
+  prompt: string,
+  opts: Pick<RunPromptOptions, "model" | "useWebSearch">,
+  config: FakeLlmConfig,
+  random = Math.random,
+): Promise<RunPromptResult> {
+  if (config.latencyMs > 0) {
+    await new Promise((resolve) => setTimeout(resolve, config.latencyMs));
+  }
+  if (config.errorRate > 0 && random() < config.errorRate) {
+    throw new FakeLlmError(`Fake LLM error ${config.errorStatus}`, config.errorStatus);
+  }
+  return {
+    output: fakeLlmOutput(config, prompt, opts.model),
+    usedWebSearch: opts.useWebSearch,
+    citations: opts.useWebSearch ? [{ url: "https://example.com/fake-search", title: "Fake search result" }] : [],
+    llmModel: opts.model,
+    servedModel: `fake/${opts.model}`,
+    ttftMs: config.latencyMs,
+  };
+}
@@ -0,0 +1,50 @@
+// Per-job generation settings passed through to the provider. Unset fields keep the provider defaults.
+export const REASONING_EFFORTS = ["minimal", "low", "medium", "high"] as const;
+export type ReasoningEffort = (typeof REASONING_EFFORTS)[number];
+export const TEXT_VERBOSITIES = ["low", "medium", "high"] as const;
+export type TextVerbosity = (typeof TEXT_VERBOSITIES)[number];
+
+export const JOB_MAX_OUTPUT_TOKENS_LIMIT = 128_000;
+
+export type GenerationParams = {
+  maxOutputTokens?: number | null;
+  temperature?: number | null;
+  reasoningEffort?: ReasoningEffort | null;
+  verbosity?: TextVerbosity | null;
+};
+
+export function generationParamsFromJob(job: {
+  maxOutputTokens: number | null;
+  temperature: number | null;
+  reasoningEffort: string | null;
+  verbosity: string | null;
+}): GenerationParams {
+  return {
+    maxOutputTokens: job.maxOutputTokens,
+    temperature: job.temperature,
+    reasoningEffort: REASONING_EFFORTS.includes(job.reasoningEffort as ReasoningEffort) ? (job.reasoningEffort as ReasoningEffort) : null,
+    verbosity: TEXT_VERBOSITIES.includes(job.verbosity as TextVerbosity) ? (job.verbosity as TextVerbosity) : null,
+  };
+}
+
+// A job's max output tokens never exceeds the deployment cap (LLM_MAX_OUTPUT_TOKENS), whichever is lower wins.
+export function capMaxOutputTokens(jobMax: number | null | undefined, deploymentCap: number | null | undefined) {
+  const values = [jobMax, deploymentCap].filter((value): value is number => typeof value === "number" && value > 0);
+  return values.length ? Math.min(...values) : undefined;
+}
+
+// Settings for the AI SDK call: temperature is clamped to the provider range, reasoning effort and verbosity go
+// through the OpenAI provider options.
+export function generationCallSettings(params: GenerationParams | undefined) {
+  const openai: Record<string, string> = {};
+  if (params?.reasoningEffort) {
+    openai.reasoningEffort = params.reasoningEffort;
+  }
+  if (params?.verbosity) {
+    openai.textVerbosity = params.verbosity;
+  }
+  return {
+    ...(typeof params?.temperature === "number" ? { temperature: Math.min(Math.max(params.temperature, 0), 2) } : {}),
+    ...(Object.keys(openai).length ? { providerOptions: { openai } } : {}),
+  };
+}
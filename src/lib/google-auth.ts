@@ -0,0 +1,73 @@
+import { createSign } from "node:crypto";
+
+const TOKEN_URL = "https://oauth2.googleapis.com/token";
+const TOKEN_TTL_SECONDS = 3600;
+// Refresh a little early so a token never expires mid-request.
+const TOKEN_REFRESH_MARGIN_MS = 60_000;
+
+export type GoogleServiceAccount = {
+  client_email: string;
+  private_key: string;
+};
+
+const tokenCache = new Map<string, { accessToken: string; expiresAt: number }>();
+
+export function parseServiceAccount(json: string): GoogleServiceAccount {
+  const parsed = JSON.parse(json) as Partial<GoogleServiceAccount>;
+  if (typeof parsed.client_email !== "string" || typeof parsed.private_key !== "string") {
+    throw new Error("Service account JSON must include client_email and private_key");
+  }
+  return { client_email: parsed.client_email, private_key: parsed.private_key };
+}
+
+function base64Url(value: string | Buffer) {
+  return Buffer.from(value).toString("base64url");
+}
+
+function signJwt(account: GoogleServiceAccount, scope: string, nowSeconds: number) {
+  const header = base64Url(JSON.stringify({ alg: "RS256", typ: "JWT" }));
+  const claims = base64Url(
+    JSON.stringify({
+      iss: account.client_email,
+      scope,
+      aud: TOKEN_URL,
+      iat: nowSeconds,
+      exp: nowSeconds + TOKEN_TTL_SECONDS,
+    }),
+  );
+  const signer = createSign("RSA-SHA256");
+  signer.update(`${header}.${claims}`);
+  return `${header}.${claims}.${base64Url(signer.sign(account.private_key))}`;
+}
+
+// Exchanges a service-account JWT for an OAuth access token (cached per account and scope).
+export async function getGoogleAccessToken(serviceAccountJson: string, scope: string): Promise<string> {
+  const account = parseServiceAccount(serviceAccountJson);
+  const cacheKey = `${account.client_email}|${scope}`;
+  const cached = tokenCache.get(cacheKey);
+  if (cached && cached.expiresAt - TOKEN_REFRESH_MARGIN_MS > Date.now()) {
+    return cached.accessToken;
+  }
+
+  const res = await fetch(TOKEN_URL, {
+    method: "POST",
+    headers: { "Content-Type": "application/x-www-form-urlencoded" },
+    body: new URLSearchParams({
+      grant_type: "urn:ietf:params:oauth:grant-type:jwt-bearer",
+      assertion: signJwt(account, scope, Math.floor(Date.now() / 1000)),
+    }),
+  });
+  if (!res.ok) {
+    const err = new Error(`Google token exchange failed: ${res.status}`) as Error & { status: number };
+    err.status = res.status;
+    throw err;
+  }
+
+  const data = (await res.json()) as { access_token?: string; expires_in?: number };
+  if (!data.access_token) {
+    throw new Error("Google token exchange returned no access_token");
+  }
+  const expiresIn = typeof data.expires_in === "number" ? data.expires_in : TOKEN_TTL_SECONDS;
+  tokenCache.set(cacheKey, { accessToken: data.access_token, expiresAt: Date.now() + expiresIn * 1000 });
+  return data.access_token;
+}
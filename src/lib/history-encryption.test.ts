@@ -0,0 +1,67 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { DEAD_LETTER_ENCRYPTED_FIELDS, openHistoryBytes, openHistoryField, sealHistoryBytes, sealHistoryData, sealHistoryField } from "./history-encryption";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+function useKeys(keys: string) {
+  vi.stubEnv("SECRET_BACKEND", "env");
+  vi.stubEnv("CHANNEL_SECRET_KEYS", keys);
+  vi.stubEnv("CHANNEL_SECRET_KEY", "");
+  vi.stubEnv("NEXTAUTH_SECRET", "");
+}
+
+describe("history encryption", () => {
+  it("stores plaintext unless enabled and reads both forms", () => {
+    useKeys("v1:first");
+    expect(sealHistoryField("quarterly numbers")).toBe("quarterly numbers");
+
+    vi.stubEnv("HISTORY_ENCRYPTION", "on");
+    const sealed = sealHistoryField("quarterly numbers")!;
+    expect(sealed).toMatch(/^enc:v1:/);
+    expect(sealHistoryField(sealed)).toBe(sealed);
+    expect(openHistoryField(sealed)).toBe("quarterly numbers");
+    expect(openHistoryField("written before")).toBe("written before");
+    expect(openHistoryField(null)).toBeNull();
+
+    useKeys("v2:second");
+    expect(openHistoryField(sealed)).toBe("[encrypted with a key that is no longer configured]");
+  });
+
+  it("seals only the history fields of a write", () => {
+    useKeys("v1:first");
+    vi.stubEnv("HISTORY_ENCRYPTION", "true");
+    const data = sealHistoryData({ status: "fail", errorMessage: "boom", outputPreview: { set: "hi" }, outputText: null, outputDiff: "+ new" });
+    expect(data.status).toBe("fail");
+    expect(data.outputText).toBeNull();
+    expect(openHistoryField(data.errorMessage)).toBe("boom");
+    expect(openHistoryField(data.outputPreview.set)).toBe("hi");
+    expect(data.outputDiff).toMatch(/^enc:/);
+    expect(openHistoryField(data.outputDiff)).toBe("+ new");
+
+    const resumable = sealHistoryData({ deliveredParts: 1, deliveryTitle: "Daily digest", deliveryBody: "part one\npart two" });
+    expect(resumable.deliveredParts).toBe(1);
+    expect(resumable.deliveryTitle).toMatch(/^enc:/);
+    expect(openHistoryField(resumable.deliveryBody)).toBe("part one\npart two");
+
+    const letter = sealHistoryData({ reason: "delivery_failed", outputText: "full output" }, DEAD_LETTER_ENCRYPTED_FIELDS);
+    expect(letter.reason).toBe("delivery_failed");
+    expect(openHistoryField(letter.outputText)).toBe("full output");
+  });
+
+  it("seals binary values and reads unsealed ones as they are", () => {
+    useKeys("v1:first");
+    const plain = Buffer.from([0x1f, 0x8b, 1, 2, 3]);
+    expect(sealHistoryBytes(plain)).toBe(plain);
+    expect(openHistoryBytes(plain)).toEqual(plain);
+
+    vi.stubEnv("HISTORY_ENCRYPTION", "on");
+    const sealed = sealHistoryBytes(plain);
+    expect(sealed.toString("utf8")).toMatch(/^enc:v1:/);
+    expect(openHistoryBytes(sealed)).toEqual(plain);
+
+    useKeys("v2:second");
+    expect(openHistoryBytes(sealed)).toBeNull();
+  });
+});
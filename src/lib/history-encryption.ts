@@ -0,0 +1,119 @@
+import { Prisma } from "@prisma/client";
+import { decryptString, encryptString } from "@/lib/crypto";
+
+// Optional at-rest encryption of run history text (HISTORY_ENCRYPTION=on): outputs, output previews, diffs, error
+// messages and the message kept for a resumed delivery can carry whatever the prompt and the model put in them.
+// Values are encrypted with the channel secret keys (CHANNEL_SECRET_KEYS / KMS data key) when a run is written and
+// decrypted when it is read, through the Prisma client extension below, so the rest of the code keeps reading plain
+// strings. The compressed full output (run_outputs.content) is sealed by run-outputs.ts with sealHistoryBytes.
+// Stored values are tagged, so plaintext rows written before (or with the option off) stay readable.
+export const HISTORY_ENCRYPTED_FIELDS = ["outputText", "outputPreview", "outputDiff", "errorMessage", "deliveryTitle", "deliveryBody"] as const;
+// Dead letters keep a copy of the failed run's output for requeueing.
+export const DEAD_LETTER_ENCRYPTED_FIELDS = ["outputText"] as const;
+
+const PREFIX = "enc:";
+export const HISTORY_UNREADABLE = "[encrypted with a key that is no longer configured]";
+
+export function historyEncryptionEnabled() {
+  return ["1", "true", "on"].includes((process.env.HISTORY_ENCRYPTION ?? "").trim().toLowerCase());
+}
+
+export function sealHistoryField(value: string | null | undefined) {
+  if (value == null || !historyEncryptionEnabled() || value.startsWith(PREFIX)) {
+    return value;
+  }
+  return `${PREFIX}${encryptString(value)}`;
+}
+
+export function openHistoryField(value: string | null) {
+  if (!value?.startsWith(PREFIX)) {
+    return value;
+  }
+  try {
+    return decryptString(value.slice(PREFIX.length));
+  } catch {
+    return HISTORY_UNREADABLE;
+  }
+}
+
+// Binary values (gzip) are sealed as the tagged ciphertext of their base64 form.
+export function sealHistoryBytes(content: Buffer) {
+  return historyEncryptionEnabled() ? Buffer.from(`${PREFIX}${encryptString(content.toString("base64"))}`, "utf8") : content;
+}
+
+// null when the value was sealed with a key that is no longer configured.
+export function openHistoryBytes(content: Uint8Array) {
+  const bytes = Buffer.from(content);
+  if (!bytes.subarray(0, PREFIX.length).equals(Buffer.from(PREFIX, "utf8"))) {
+    return bytes;
+  }
+  try {
+    return Buffer.from(decryptString(bytes.subarray(PREFIX.length).toString("utf8")), "base64");
+  } catch {
+    return null;
+  }
+}
+
+// Encrypts the history fields of a create/update payload; accepts plain values and { set: value }.
+export function sealHistoryData<T>(data: T, fields: readonly string[] = HISTORY_ENCRYPTED_FIELDS): T {
+  if (!historyEncryptionEnabled() || !data || typeof data !== "object") {
+    return data;
+  }
+  const sealed: Record<string, unknown> = { ...(data as Record<string, unknown>) };
+  for (const field of fields) {
+    const value = sealed[field];
+    if (typeof value === "string") {
+      sealed[field] = sealHistoryField(value);
+    } else if (value && typeof value === "object" && typeof (value as { set?: unknown }).set === "string") {
+      sealed[field] = { set: sealHistoryField((value as { set: string }).set) };
+    }
+  }
+  return sealed as T;
+}
+
+// Reads decrypt whenever a value is tagged, so switching the option off later does not hide stored history.
+// Raw SQL bypasses the extension; run history text is only written through the client.
+export const historyEncryption = Prisma.defineExtension({
+  name: "history-encryption",
+  query: {
+    runHistory: {
+      async create({ args, query }) {
+        return query({ ...args, data: sealHistoryData(args.data) });
+      },
+      async createMany({ args, query }) {
+        const data = Array.isArray(args.data) ? args.data.map((row) => sealHistoryData(row)) : sealHistoryData(args.data);
+        return query({ ...args, data });
+      },
+      async update({ args, query }) {
+        return query({ ...args, data: sealHistoryData(args.data) });
+      },
+      async updateMany({ args, query }) {
+        return query({ ...args, data: sealHistoryData(args.data) });
+      },
+      async upsert({ args, query }) {
+        return query({ ...args, create: sealHistoryData(args.create), update: sealHistoryData(args.update) });
+      },
+    },
+    deadLetter: {
+      async create({ args, query }) {
+        return query({ ...args, data: sealHistoryData(args.data, DEAD_LETTER_ENCRYPTED_FIELDS) });
+      },
+      async update({ args, query }) {
+        return query({ ...args, data: sealHistoryData(args.data, DEAD_LETTER_ENCRYPTED_FIELDS) });
+      },
+    },
+  },
+  result: {
+    runHistory: {
+      outputText: { needs: { outputText: true }, compute: (run) => openHistoryField(run.outputText) },
+      outputPreview: { needs: { outputPreview: true }, compute: (run) => openHistoryField(run.outputPreview) },
+      outputDiff: { needs: { outputDiff: true }, compute: (run) => openHistoryField(run.outputDiff) },
+      errorMessage: { needs: { errorMessage: true }, compute: (run) => openHistoryField(run.errorMessage) },
+      deliveryTitle: { needs: { deliveryTitle: true }, compute: (run) => openHistoryField(run.deliveryTitle) },
+      deliveryBody: { needs: { deliveryBody: true }, compute: (run) => openHistoryField(run.deliveryBody) },
+    },
+    deadLetter: {
+      outputText: { needs: { outputText: true }, compute: (letter) => openHistoryField(letter.outputText) },
+    },
+  },
+});
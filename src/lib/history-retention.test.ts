@@ -0,0 +1,25 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { defaultRetentionDays, effectiveRetentionDays } from "./history-retention";
+
+describe("run history retention", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("keeps history forever unless a default is configured", () => {
+    expect(defaultRetentionDays()).toBe(0);
+    vi.stubEnv("RUN_HISTORY_RETENTION_DAYS", "90");
+    expect(defaultRetentionDays()).toBe(90);
+    vi.stubEnv("RUN_HISTORY_RETENTION_DAYS", "-1");
+    expect(defaultRetentionDays()).toBe(0);
+  });
+
+  it("prefers the job setting, including 0 to keep everything", () => {
+    expect(effectiveRetentionDays(null, 90)).toBe(90);
+    expect(effectiveRetentionDays(30, 90)).toBe(30);
+    expect(effectiveRetentionDays(0, 90)).toBe(0);
+  });
+});
@@ -0,0 +1,58 @@
+import { prisma } from "@/lib/prisma";
+import { clock } from "@/lib/clock";
+
+const DEFAULT_BATCH = 500;
+// Only one worker prunes at a time; the others skip the step for that tick.
+const PRUNE_LOCK_KEY = "promptloop:history-retention";
+
+// RUN_HISTORY_RETENTION_DAYS: deployment default for jobs without their own history_retention_days. Unset or 0
+// keeps run history forever.
+export function defaultRetentionDays() {
+  const raw = Number(process.env.RUN_HISTORY_RETENTION_DAYS ?? 0);
+  return Number.isFinite(raw) && raw > 0 ? Math.floor(raw) : 0;
+}
+
+function pruneBatch() {
+  const raw = Number(process.env.RUN_HISTORY_PRUNE_BATCH ?? DEFAULT_BATCH);
+  return Number.isFinite(raw) && raw > 0 ? Math.floor(raw) : DEFAULT_BATCH;
+}
+
+// A job's own setting wins (0 keeps its history forever); null falls back to the deployment default.
+export function effectiveRetentionDays(jobDays: number | null | undefined, fallback = defaultRetentionDays()) {
+  return jobDays ?? fallback;
+}
+
+// Deletes up to RUN_HISTORY_PRUNE_BATCH runs older than their job's retention period, oldest first, so a large
+// backlog is worked off over several ticks instead of in one long statement. Each job's latest run is kept as
+// the baseline for diffs and deliver_if = changed; runs still in flight or waiting in the outbox are kept too.
+// Artifacts, stored outputs and delivery attempts cascade; dead letters keep their copy of the output.
+export async function pruneRunHistories(now = clock().now()) {
+  const fallback = defaultRetentionDays();
+  const batch = pruneBatch();
+  return prisma.$transaction(async (tx) => {
+    const [lock] = await tx.$queryRaw<Array<{ locked: boolean }>>`SELECT pg_try_advisory_xact_lock(hashtext(${PRUNE_LOCK_KEY})) AS locked`;
+    if (!lock?.locked) {
+      return 0;
+    }
+    return tx.$executeRaw`
+      WITH doomed AS (
+        SELECT r.id
+        FROM run_histories r
+        JOIN jobs j ON j.id = r.job_id
+        WHERE COALESCE(j.history_retention_days, ${fallback}::int) > 0
+          AND r.run_at < ${now}::timestamptz - make_interval(days => COALESCE(j.history_retention_days, ${fallback}::int))
+          AND r.status <> 'running'
+          AND r.deliver_at IS NULL
+          AND r.id <> (
+            SELECT latest.id FROM run_histories latest
+            WHERE latest.job_id = r.job_id AND latest.is_preview = false
+            ORDER BY latest.run_at DESC
+            LIMIT 1
+          )
+        ORDER BY r.run_at
+        LIMIT ${batch}
+      )
+      DELETE FROM run_histories WHERE id IN (SELECT id FROM doomed)
+    `;
+  });
+}
@@ -0,0 +1,48 @@
+import { describe, expect, it, vi } from "vitest";
+
+const { findFirst } = vi.hoisted(() => ({ findFirst: vi.fn() }));
+vi.mock("@/lib/prisma", () => ({ prisma: { job: { findFirst } } }));
+
+import {
+  UPSTREAM_OUTPUT_TEXT_MAX,
+  assertJobDependency,
+  dependencyRecheckAt,
+  dependencyWindowStart,
+  formatUpstreamOutputForPrompt,
+} from "./job-dependencies";
+
+describe("job dependencies", () => {
+  it("waits since the last run, or one day for a first run", () => {
+    const scheduledFor = new Date("2026-04-21T08:05:00Z");
+    const lastRunAt = new Date("2026-04-20T08:05:10Z");
+    expect(dependencyWindowStart(lastRunAt, scheduledFor)).toBe(lastRunAt);
+    expect(dependencyWindowStart(null, scheduledFor).toISOString()).toBe("2026-04-20T08:05:00.000Z");
+  });
+
+  it("rechecks in five minutes but never past the next regular slot", () => {
+    const now = new Date("2026-04-21T08:05:00Z");
+    expect(dependencyRecheckAt(now, new Date("2026-04-22T08:05:00Z")).toISOString()).toBe("2026-04-21T08:10:00.000Z");
+    expect(dependencyRecheckAt(now, new Date("2026-04-21T08:07:00Z")).toISOString()).toBe("2026-04-21T08:07:00.000Z");
+  });
+
+  it("formats the upstream output block", () => {
+    const run = { jobName: "Collect data", runAt: new Date("2026-04-21T08:00:30Z"), outputText: "rows: 12" };
+    expect(formatUpstreamOutputForPrompt(run)).toBe('Output of the upstream job "Collect data" (run at 2026-04-21T08:00:30.000Z):\nrows: 12');
+    expect(formatUpstreamOutputForPrompt({ ...run, outputText: "x".repeat(UPSTREAM_OUTPUT_TEXT_MAX + 1) })).toContain("[truncated]");
+    expect(formatUpstreamOutputForPrompt({ ...run, outputText: null })).toBe("");
+  });
+
+  it("rejects self-references, unknown jobs and cycles", async () => {
+    await expect(assertJobDependency("u1", "a", "a")).rejects.toThrow("cannot depend on itself");
+
+    findFirst.mockResolvedValueOnce(null);
+    await expect(assertJobDependency("u1", "a", "b")).rejects.toThrow("Upstream job not found");
+
+    // a -> b -> c -> a
+    findFirst.mockResolvedValueOnce({ dependsOnJobId: "c" }).mockResolvedValueOnce({ dependsOnJobId: "a" });
+    await expect(assertJobDependency("u1", "a", "b")).rejects.toThrow("cycle");
+
+    findFirst.mockResolvedValueOnce({ dependsOnJobId: null });
+    await expect(assertJobDependency("u1", null, "b")).resolves.toBeUndefined();
+  });
+});
@@ -0,0 +1,72 @@
+import { prisma } from "@/lib/prisma";
+import { GENERATED_RUN_STATUSES } from "@/lib/run-status";
+
+// A dependent job (jobs.depends_on_job_id) runs only once its upstream job has produced an output since the
+// dependent job last did, e.g. "collect data" then "summarize collected data". Until then the slot is held and
+// rechecked every few minutes, up to the job's next regular slot.
+export const DEPENDENCY_RECHECK_MS = 5 * 60 * 1000;
+export const UPSTREAM_OUTPUT_TEXT_MAX = 8000;
+const FIRST_RUN_WINDOW_MS = 24 * 60 * 60 * 1000;
+const MAX_CHAIN_DEPTH = 20;
+
+export type UpstreamRun = { jobName: string; runAt: Date; outputText: string | null };
+
+// Rejects unknown or foreign upstream jobs, self-dependencies and cycles (jobId is null for a new job).
+export async function assertJobDependency(userId: string, jobId: string | null, dependsOnJobId: string | null) {
+  if (!dependsOnJobId) {
+    return;
+  }
+  if (dependsOnJobId === jobId) {
+    throw new Error("A job cannot depend on itself");
+  }
+  let current: string | null = dependsOnJobId;
+  for (let depth = 0; current && depth < MAX_CHAIN_DEPTH; depth++) {
+    const upstream: { dependsOnJobId: string | null } | null = await prisma.job.findFirst({
+      where: { id: current, userId },
+      select: { dependsOnJobId: true },
+    });
+    if (!upstream) {
+      if (depth === 0) {
+        throw new Error("Upstream job not found");
+      }
+      return;
+    }
+    if (jobId && upstream.dependsOnJobId === jobId) {
+      throw new Error("Job dependencies cannot form a cycle");
+    }
+    current = upstream.dependsOnJobId;
+  }
+}
+
+// The period a dependent run waits for: since its own last generated run, or the last day for a first run.
+export function dependencyWindowStart(lastRunAt: Date | null, scheduledFor: Date) {
+  return lastRunAt ?? new Date(scheduledFor.getTime() - FIRST_RUN_WINDOW_MS);
+}
+
+export function dependencyRecheckAt(now: Date, regularNextRunAt: Date) {
+  return new Date(Math.min(now.getTime() + DEPENDENCY_RECHECK_MS, regularNextRunAt.getTime()));
+}
+
+// Latest scheduled upstream run that generated an output after `since` (any time when null).
+export async function findUpstreamRun(upstreamJobId: string, since: Date | null): Promise<UpstreamRun | null> {
+  const run = await prisma.runHistory.findFirst({
+    where: {
+      jobId: upstreamJobId,
+      isPreview: false,
+      status: { in: GENERATED_RUN_STATUSES },
+      ...(since ? { runAt: { gt: since } } : {}),
+    },
+    orderBy: { runAt: "desc" },
+    select: { runAt: true, outputText: true, job: { select: { name: true } } },
+  });
+  return run ? { jobName: run.job.name, runAt: run.runAt, outputText: run.outputText } : null;
+}
+
+export function formatUpstreamOutputForPrompt(run: UpstreamRun) {
+  const text = run.outputText?.trim();
+  if (!text) {
+    return "";
+  }
+  const capped = text.length > UPSTREAM_OUTPUT_TEXT_MAX ? `${text.slice(0, UPSTREAM_OUTPUT_TEXT_MAX)}\n[truncated]` : text;
+  return `Output of the upstream job "${run.jobName}" (run at ${run.runAt.toISOString()}):\n${capped}`;
+}
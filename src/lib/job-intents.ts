@@ -1,5 +1,5 @@
 import { generateText } from "ai";
-import { openai } from "@ai-sdk/openai";
+import { openaiProvider } from "@/lib/openai-keys";
 import { z } from "zod";
 import { DEFAULT_LLM_MODEL, DEFAULT_WEB_SEARCH_MODE } from "@/lib/llm-defaults";
 import { CronExpressionParser } from "cron-parser";
@@ -265,7 +265,7 @@ export async function generatePromptDraftFromIntent(intentText: string): Promise
   ].join("\n");
 
   const result = await generateText({
-    model: openai(DEFAULT_LLM_MODEL),
+    model: openaiProvider()(DEFAULT_LLM_MODEL),
     system,
     prompt: intentText,
     timeout: 60_000,
@@ -0,0 +1,17 @@
+import { describe, expect, it } from "vitest";
+import { JOB_PRIORITIES, priorityName, priorityValue } from "./job-priority";
+
+describe("job priority", () => {
+  it("round-trips the named levels in claim order", () => {
+    const values = JOB_PRIORITIES.map(priorityValue);
+    expect(values).toEqual([...values].sort((a, b) => a - b));
+    for (const priority of JOB_PRIORITIES) {
+      expect(priorityName(priorityValue(priority))).toBe(priority);
+    }
+  });
+
+  it("clamps out-of-range stored values", () => {
+    expect(priorityName(7)).toBe("high");
+    expect(priorityName(-3)).toBe("low");
+  });
+});
@@ -0,0 +1,17 @@
+// Claim order when the queue is backed up: due jobs are picked by priority, then by how long they have been due.
+// Stored as a number (jobs.priority) so the claim query can sort on it; the API and editor use the names.
+export const JOB_PRIORITIES = ["low", "normal", "high"] as const;
+export type JobPriority = (typeof JOB_PRIORITIES)[number];
+
+const PRIORITY_VALUES: Record<JobPriority, number> = { low: 0, normal: 1, high: 2 };
+
+export function priorityValue(priority: JobPriority) {
+  return PRIORITY_VALUES[priority];
+}
+
+export function priorityName(value: number): JobPriority {
+  if (value >= PRIORITY_VALUES.high) {
+    return "high";
+  }
+  return value <= PRIORITY_VALUES.low ? "low" : "normal";
+}
@@ -0,0 +1,62 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { parseJobSpec, planSync, syncKeyFromPath } from "./job-sync";
+
+const CHANNEL_ID = "6f1c2a4e-0b7d-4c2a-9a59-2b1f0c3d4e5f";
+
+function spec(name: string, extra = "") {
+  return `name: ${name}\ntemplate: |\n  Summarize {{topic}}.\nvariables:\n  topic: AI\nscheduleType: daily\nscheduleTime: "09:00"\nchannelId: ${CHANNEL_ID}\n${extra}`;
+}
+
+describe("declarative job sync", () => {
+  it("parses YAML specs in the jobs API format", () => {
+    const parsed = parseJobSpec({ path: "team/daily-digest.yaml", content: spec("Digest", "tags: [team:data]") });
+    expect(parsed.key).toBe("daily-digest");
+    expect(parsed.input).toMatchObject({ name: "Digest", template: "Summarize {{topic}}.\n", variables: '{"topic":"AI"}', tags: ["team:data"] });
+    expect(parseJobSpec({ path: "x.yaml", content: spec("Digest", "id: custom") }).key).toBe("custom");
+    expect(() => parseJobSpec({ path: "x.yaml", content: "name: Digest\nscheduleType: daily" })).toThrow();
+    expect(syncKeyFromPath("a/b/c.yml")).toBe("c");
+  });
+
+  it("hashes specs independently of key order", () => {
+    const a = parseJobSpec({ path: "a.yaml", content: spec("Digest") });
+    const b = parseJobSpec({ path: "a.json", content: JSON.stringify({ channelId: CHANNEL_ID, scheduleTime: "09:00", scheduleType: "daily", variables: { topic: "AI" }, template: "Summarize {{topic}}.\n", name: "Digest" }) });
+    expect(b.hash).toBe(a.hash);
+  });
+
+  it("plans creates, updates and disables", () => {
+    const current = parseJobSpec({ path: "same.yaml", content: spec("Same") });
+    const managed = [
+      { id: "1", name: "Same", syncKey: "same", syncHash: current.hash, enabled: true },
+      { id: "2", name: "Changed", syncKey: "changed", syncHash: "old", enabled: true },
+      { id: "3", name: "Gone", syncKey: "gone", syncHash: "x", enabled: true },
+      { id: "4", name: "Already off", syncKey: "off", syncHash: null, enabled: false },
+    ];
+    const files = [
+      { path: "same.yaml", content: spec("Same") },
+      { path: "changed.yaml", content: spec("Changed") },
+      { path: "new.yaml", content: spec("New") },
+    ];
+    const plan = planSync(files, managed);
+    expect(plan.create.map((s) => s.key)).toEqual(["new"]);
+    expect(plan.update.map((s) => [s.key, s.jobId])).toEqual([["changed", "2"]]);
+    expect(plan.disable).toEqual([{ jobId: "3", key: "gone", name: "Gone" }]);
+    expect(plan.unchanged).toEqual(["same"]);
+  });
+
+  it("does not disable anything when a spec is invalid or duplicated", () => {
+    const managed = [{ id: "3", name: "Gone", syncKey: "gone", syncHash: "x", enabled: true }];
+    const plan = planSync(
+      [
+        { path: "a.yaml", content: spec("A", "id: dup") },
+        { path: "b.yaml", content: spec("B", "id: dup") },
+      ],
+      managed,
+    );
+    expect(plan.errors).toEqual([{ path: "b.yaml", error: 'id "dup" is also used by a.yaml' }]);
+    expect(plan.disable).toEqual([]);
+    expect(planSync([{ path: "bad.yaml", content: "name: x\nscheduleType: hourly" }], managed).errors[0].error).toContain("template");
+  });
+});
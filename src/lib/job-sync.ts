@@ -0,0 +1,221 @@
+import { createHash } from "node:crypto";
+import { prisma } from "@/lib/prisma";
+import { parseYaml } from "@/lib/config-file";
+import { jobUpsertSchema, type JobUpsertInput } from "@/lib/validation";
+import { computeNextRunAt } from "@/lib/schedule";
+import { toDbJobSettings } from "@/lib/jobs";
+import { toDbJobChannel } from "@/lib/saved-channels";
+import { assertJobDependency } from "@/lib/job-dependencies";
+import { recordAudit } from "@/lib/audit";
+import { isRecord } from "@/lib/type-guards";
+
+// Declarative job management (`promptloop sync`): a directory or Git repo of job spec files is the source of
+// truth for one user's managed jobs. Each file holds one job in the jobs API format plus an optional `id` (the
+// sync key, default: the file name). Sync creates jobs for new keys, updates jobs whose spec changed or that
+// were edited elsewhere, and disables managed jobs whose file was removed. Jobs created in the UI are untouched.
+
+const SYNC_KEY_RE = /^[A-Za-z0-9_.-]{1,100}$/;
+
+export type SyncFile = { path: string; content: string };
+export type SyncSpec = { key: string; path: string; input: JobUpsertInput; hash: string };
+export type ManagedJob = { id: string; name: string; syncKey: string | null; syncHash: string | null; enabled: boolean };
+
+export type SyncPlan = {
+  create: SyncSpec[];
+  update: Array<SyncSpec & { jobId: string }>;
+  disable: Array<{ jobId: string; key: string; name: string }>;
+  unchanged: string[];
+  errors: Array<{ path: string; error: string }>;
+};
+
+export function syncKeyFromPath(path: string) {
+  const file = path.split(/[\\/]/).pop() ?? path;
+  return file.replace(/\.(ya?ml|json)$/i, "");
+}
+
+function stableStringify(value: unknown): string {
+  if (Array.isArray(value)) {
+    return `[${value.map(stableStringify).join(",")}]`;
+  }
+  if (isRecord(value)) {
+    const keys = Object.keys(value)
+      .filter((key) => value[key] !== undefined)
+      .sort();
+    return `{${keys.map((key) => `${JSON.stringify(key)}:${stableStringify(value[key])}`).join(",")}}`;
+  }
+  return JSON.stringify(value ?? null);
+}
+
+export function specHash(input: JobUpsertInput) {
+  return createHash("sha256").update(stableStringify(input)).digest("hex").slice(0, 32);
+}
+
+export function parseJobSpec(file: SyncFile): SyncSpec {
+  const raw = /\.json$/i.test(file.path) ? (JSON.parse(file.content) as unknown) : parseYaml(file.content);
+  if (!isRecord(raw)) {
+    throw new Error("spec must be a mapping");
+  }
+  const { id, variables, ...rest } = raw;
+  const key = typeof id === "string" && id.trim() ? id.trim() : syncKeyFromPath(file.path);
+  if (!SYNC_KEY_RE.test(key)) {
+    throw new Error(`invalid id "${key}" (letters, digits, _ . - up to 100 characters)`);
+  }
+  // Specs write variables as a mapping; the jobs API takes them as a JSON string.
+  const input = jobUpsertSchema.parse({ ...rest, variables: isRecord(variables) ? JSON.stringify(variables) : variables });
+  return { key, path: file.path, input, hash: specHash(input) };
+}
+
+export function planSync(files: SyncFile[], managed: ManagedJob[]): SyncPlan {
+  const plan: SyncPlan = { create: [], update: [], disable: [], unchanged: [], errors: [] };
+  const specs = new Map<string, SyncSpec>();
+  for (const file of files) {
+    try {
+      const spec = parseJobSpec(file);
+      const clash = specs.get(spec.key);
+      if (clash) {
+        throw new Error(`id "${spec.key}" is also used by ${clash.path}`);
+      }
+      specs.set(spec.key, spec);
+    } catch (err) {
+      const message = err instanceof Error && "issues" in err ? formatIssues(err) : err instanceof Error ? err.message : String(err);
+      plan.errors.push({ path: file.path, error: message });
+    }
+  }
+
+  const byKey = new Map(managed.filter((job) => job.syncKey).map((job) => [job.syncKey as string, job]));
+  for (const spec of specs.values()) {
+    const job = byKey.get(spec.key);
+    if (!job) {
+      plan.create.push(spec);
+    } else if (job.syncHash !== spec.hash) {
+      plan.update.push({ ...spec, jobId: job.id });
+    } else {
+      plan.unchanged.push(spec.key);
+    }
+  }
+  // A file that failed to parse may be the one defining a job; never disable anything on a broken checkout.
+  if (!plan.errors.length) {
+    for (const [key, job] of byKey) {
+      if (!specs.has(key) && job.enabled) {
+        plan.disable.push({ jobId: job.id, key, name: job.name });
+      }
+    }
+  }
+  return plan;
+}
+
+function formatIssues(err: Error) {
+  const issues = (err as Error & { issues: Array<{ path: PropertyKey[]; message: string }> }).issues;
+  return issues.map((issue) => (issue.path.length ? `${issue.path.join(".")}: ${issue.message}` : issue.message)).join("; ");
+}
+
+async function applySpec(userId: string, spec: SyncSpec, jobId: string | null) {
+  const parsed = spec.input;
+  const variables = JSON.parse(parsed.variables || "{}") as Record<string, string>;
+  const postPrompt = parsed.postPrompt.trim() ? parsed.postPrompt : null;
+  const { channelId, channelType, channelConfig } = await toDbJobChannel(userId, parsed);
+  await assertJobDependency(userId, jobId, parsed.dependsOnJobId);
+  const data = {
+    name: parsed.name,
+    prompt: parsed.template,
+    postPrompt,
+    postPromptEnabled: parsed.postPromptEnabled && !!postPrompt,
+    allowWebSearch: parsed.useWebSearch,
+    llmModel: parsed.llmModel || null,
+    webSearchMode: parsed.webSearchMode || null,
+    scheduleType: parsed.scheduleType,
+    scheduleTime: parsed.scheduleTime,
+    scheduleDayOfWeek: parsed.scheduleDayOfWeek,
+    scheduleCron: parsed.scheduleCron,
+    runAt: parsed.runAt,
+    weekdaysOnly: parsed.weekdaysOnly,
+    completedAt: null,
+    catchupPolicy: parsed.catchupPolicy,
+    channelId,
+    channelType,
+    channelConfig,
+    enabled: parsed.enabled,
+    nextRunAt: computeNextRunAt(parsed),
+    syncKey: spec.key,
+    syncHash: spec.hash,
+    ...toDbJobSettings(parsed),
+    promptVersions: { create: { template: parsed.template, postPrompt, postPromptEnabled: parsed.postPromptEnabled && !!postPrompt, variables } },
+  };
+
+  const job = jobId
+    ? await prisma.job.update({
+        where: { id: jobId },
+        data: { ...data, credentialCheckedAt: null, credentialError: null, dormantReason: null, dormantAt: null },
+        include: { promptVersions: { orderBy: { createdAt: "desc" }, take: 1 } },
+      })
+    : await prisma.job.create({ data: { ...data, userId }, include: { promptVersions: { orderBy: { createdAt: "desc" }, take: 1 } } });
+  await prisma.job.update({ where: { id: job.id }, data: { publishedPromptVersionId: job.promptVersions[0]?.id ?? null } });
+  await recordAudit({
+    userId,
+    action: jobId ? "job.update" : "job.create",
+    entityType: "job",
+    entityId: job.id,
+    data: { source: "sync", syncKey: spec.key, path: spec.path, enabled: job.enabled },
+  });
+  return job.id;
+}
+
+export type SyncResult = {
+  dryRun: boolean;
+  created: string[];
+  updated: string[];
+  disabled: string[];
+  unchanged: number;
+  errors: Array<{ path: string; error: string }>;
+};
+
+export async function syncJobs(input: { owner: string; files: SyncFile[]; dryRun: boolean }): Promise<SyncResult> {
+  const user = await prisma.user.findFirst({ where: { email: input.owner }, select: { id: true } });
+  if (!user) {
+    throw new Error(`No user with email ${input.owner}`);
+  }
+  const managed = await prisma.job.findMany({
+    where: { userId: user.id, syncKey: { not: null } },
+    select: { id: true, name: true, syncKey: true, syncHash: true, enabled: true },
+  });
+  const plan = planSync(input.files, managed);
+  const result: SyncResult = {
+    dryRun: input.dryRun,
+    created: plan.create.map((spec) => spec.key),
+    updated: plan.update.map((spec) => spec.key),
+    disabled: plan.disable.map((job) => job.key),
+    unchanged: plan.unchanged.length,
+    errors: plan.errors,
+  };
+  if (input.dryRun) {
+    return result;
+  }
+
+  // A spec the database rejects (e.g. an unknown saved channel) is reported and does not stop the others.
+  const applied = async (spec: SyncSpec, jobId: string | null) => {
+    try {
+      await applySpec(user.id, spec, jobId);
+      return true;
+    } catch (err) {
+      result.errors.push({ path: spec.path, error: err instanceof Error ? err.message : String(err) });
+      return false;
+    }
+  };
+  result.created = [];
+  for (const spec of plan.create) {
+    if (await applied(spec, null)) {
+      result.created.push(spec.key);
+    }
+  }
+  result.updated = [];
+  for (const spec of plan.update) {
+    if (await applied(spec, spec.jobId)) {
+      result.updated.push(spec.key);
+    }
+  }
+  for (const job of plan.disable) {
+    await prisma.job.update({ where: { id: job.jobId }, data: { enabled: false, syncHash: null } });
+    await recordAudit({ userId: user.id, action: "job.disable", entityType: "job", entityId: job.jobId, data: { source: "sync", syncKey: job.key } });
+  }
+  return result;
+}
@@ -1,149 +1,431 @@
-import { ChannelType, type Job } from "@prisma/client";
-import { decryptString, encryptString, maskSecret } from "@/lib/crypto";
+import type { Job } from "@prisma/client";
+import { maskSecret } from "@/lib/crypto";
+import { decodeChannelConfig, encodeChannelConfig, validateChannel } from "@/lib/channel-config";
+import type { SendChannelInput } from "@/lib/channel";
+import type { JobUpsertInput } from "@/lib/validation";
+import { priorityValue } from "@/lib/job-priority";
 
-type IncomingChannel =
-  | { type: "discord"; config: { webhookUrl: string } }
-  | { type: "telegram"; config: { botToken: string; chatId: string } }
-  | { type: "in_app" }
-  | {
-      type: "webhook";
-      config: {
-        url: string;
-        method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
-        headers: string;
-        payload: string;
-      };
-    };
+type WebhookConfig = {
+  url: string;
+  method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
+  headers: string;
+  payload: string;
+  // Absent on webhooks saved before GraphQL mode existed.
+  graphqlQuery?: string;
+  bodyFormat?: "json" | "xml";
+  gzip?: boolean;
+  // HMAC key for X-Promptloop-Signature; stored encrypted with the rest of the config.
+  signingSecret?: string;
+  // Store the response status and body on the run (run_histories.response_status / response_body).
+  captureResponse?: boolean;
+  // Outbound proxy (may carry credentials), private CA bundle (PEM) and skip-verify (WEBHOOK_ALLOW_INSECURE_TLS).
+  proxyUrl?: string;
+  caBundle?: string;
+  insecureSkipVerify?: boolean;
+};
 
-type ChannelConfigDb =
-  | { webhookUrlEnc: string }
-  | { botTokenEnc: string; chatIdEnc: string }
-  | { kind: "in_app" }
-  | { configEnc: string };
+type HomeAssistantConfig = {
+  baseUrl: string;
+  token: string;
+  service: string;
+};
 
-export function toDbChannelConfig(channel: IncomingChannel): { channelType: ChannelType; channelConfig: ChannelConfigDb } {
-  if (channel.type === "discord") {
-    return {
-      channelType: ChannelType.discord,
-      channelConfig: { webhookUrlEnc: encryptString(channel.config.webhookUrl) },
-    };
-  }
+type ElasticsearchConfig = {
+  url: string;
+  index: string;
+  apiKey: string;
+  username: string;
+  password: string;
+};
 
-  if (channel.type === "webhook") {
-    return {
-      channelType: ChannelType.webhook,
-      channelConfig: { configEnc: encryptString(JSON.stringify(channel.config)) },
-    };
-  }
+type ClickHouseConfig = {
+  url: string;
+  table: string;
+  username: string;
+  password: string;
+};
 
-  if (channel.type === "in_app") {
-    return {
-      channelType: ChannelType.in_app,
-      channelConfig: { kind: "in_app" },
-    };
-  }
+type BigQueryConfig = {
+  projectId: string;
+  dataset: string;
+  table: string;
+  serviceAccountJson: string;
+};
+
+type RedisConfig = {
+  restUrl: string;
+  token: string;
+  mode: "set" | "xadd";
+  key: string;
+  ttlSeconds: string;
+};
+
+type PushoverConfig = {
+  appToken: string;
+  userKey: string;
+  priority: "" | "-2" | "-1" | "0" | "1";
+  sound: string;
+};
+
+type TwilioConfig = {
+  accountSid: string;
+  authToken: string;
+  from: string;
+  to: string;
+  mode: "summary" | "full";
+};
+
+type GoogleChatConfig = {
+  webhookUrl: string;
+  format: "card" | "text";
+  threadKey: string;
+};
+
+type AwsConfig = {
+  service: "sns" | "sqs";
+  // Topic ARN (SNS) or queue URL (SQS).
+  target: string;
+  accessKeyId: string;
+  secretAccessKey: string;
+};
+
+type KafkaConfig = {
+  restUrl: string;
+  api: "v3" | "v2";
+  clusterId: string;
+  topic: string;
+  username: string;
+  password: string;
+};
+
+type NatsConfig = {
+  url: string;
+  subject: string;
+  mode: "core" | "jetstream";
+  username: string;
+  password: string;
+  token: string;
+};
+
+type MqttConfig = {
+  brokerUrl: string;
+  topic: string;
+  qos: "0" | "1" | "2";
+  retain: "on" | "off";
+  format: "json" | "text";
+  username: string;
+  password: string;
+};
+
+type FileConfig = {
+  target: "file" | "stdout";
+  path: string;
+};
 
+type NotionConfig = {
+  token: string;
+  target: "page" | "database";
+  parentId: string;
+  titleProperty: string;
+};
+
+type GoogleSheetsConfig = {
+  spreadsheetId: string;
+  sheet: string;
+  columns: string;
+  serviceAccountJson: string;
+};
+
+type JiraConfig = {
+  siteUrl: string;
+  email: string;
+  apiToken: string;
+  projectKey: string;
+  issueType: string;
+  summaryTemplate: string;
+  labels: string;
+};
+
+type PluginConfig = {
+  plugin: string;
+  settings: string;
+};
+
+export type IncomingChannel =
+  // Thread fields are absent on channels saved before thread targeting existed.
+  | { type: "discord"; config: { webhookUrl: string; threadId?: string; threadName?: string } }
+  | { type: "telegram"; config: { botToken: string; chatId: string; messageThreadId?: string } }
+  | { type: "in_app" }
+  | { type: "webhook"; config: WebhookConfig }
+  | { type: "home_assistant"; config: HomeAssistantConfig }
+  | { type: "elasticsearch"; config: ElasticsearchConfig }
+  | { type: "clickhouse"; config: ClickHouseConfig }
+  | { type: "bigquery"; config: BigQueryConfig }
+  | { type: "redis"; config: RedisConfig }
+  | { type: "pushover"; config: PushoverConfig }
+  | { type: "twilio"; config: TwilioConfig }
+  | { type: "google_chat"; config: GoogleChatConfig }
+  | { type: "aws"; config: AwsConfig }
+  | { type: "kafka"; config: KafkaConfig }
+  | { type: "nats"; config: NatsConfig }
+  | { type: "mqtt"; config: MqttConfig }
+  | { type: "file"; config: FileConfig }
+  | { type: "notion"; config: NotionConfig }
+  | { type: "google_sheets"; config: GoogleSheetsConfig }
+  | { type: "jira"; config: JiraConfig }
+  | { type: "plugin"; config: PluginConfig };
+
+export type StoredChannel = Pick<Job, "channelType" | "channelConfig">;
+
+export function toDbChannelConfig(channel: IncomingChannel) {
+  return encodeChannelConfig(channel);
+}
+
+// Per-job delivery/run settings shared by job create (POST) and update (PUT).
+export function toDbJobSettings(parsed: JobUpsertInput) {
   return {
-    channelType: ChannelType.telegram,
-    channelConfig: {
-      botTokenEnc: encryptString(channel.config.botToken),
-      chatIdEnc: encryptString(channel.config.chatId),
-    },
+    userAgent: parsed.userAgent.trim() || null,
+    llmTools: parsed.llmTools,
+    pipelineSteps: parsed.pipelineSteps,
+    inputs: parsed.inputs,
+    systemPrompt: parsed.systemPrompt.trim() || null,
+    fallbackModels: Array.from(new Set(parsed.fallbackModels.filter((model) => model !== parsed.llmModel))),
+    maxOutputTokens: parsed.maxOutputTokens,
+    temperature: parsed.temperature,
+    reasoningEffort: parsed.reasoningEffort,
+    verbosity: parsed.verbosity,
+    showHeader: parsed.showHeader,
+    headerTemplate: parsed.headerTemplate.trim() || null,
+    headerLocale: parsed.headerLocale || null,
+    timezone: parsed.timezone?.trim() || null,
+    deliveryDelayMinutes: parsed.deliveryDelayMinutes || null,
+    acceptReplies: parsed.acceptReplies,
+    outageNotice: parsed.outageNotice,
+    failureNotice: parsed.failureNotice,
+    includePreviousOutput: parsed.includePreviousOutput,
+    previousOutputCount: parsed.previousOutputCount,
+    deliverIf: parsed.deliverIf,
+    deliverIfPattern: parsed.deliverIf === "regex" ? parsed.deliverIfPattern.trim() || null : null,
+    deliveryDiff: parsed.deliveryDiff,
+    deliveryDiffOnly: parsed.deliveryDiff !== "off" && parsed.deliveryDiffOnly,
+    fullOutputLink: parsed.fullOutputLink,
+    throttleLimit: parsed.throttleLimit,
+    throttleWindow: parsed.throttleWindow,
+    qualitySampleRate: parsed.qualitySampleRate,
+    qualityRubric: parsed.qualityRubric.trim() || null,
+    historyRetentionDays: parsed.historyRetentionDays,
+    responseCacheTtlMinutes: parsed.responseCacheTtlMinutes,
+    autoDisableThreshold: parsed.autoDisableThreshold,
+    autoDisablePolicy: parsed.autoDisablePolicy,
+    expectedRuntimeSeconds: parsed.expectedRuntimeSeconds,
+    slowRunNotice: parsed.slowRunNotice,
+    maxOutputChars: parsed.maxOutputChars,
+    ttsVoice: parsed.ttsVoice,
+    dependsOnJobId: parsed.dependsOnJobId,
+    includeUpstreamOutput: parsed.includeUpstreamOutput,
+    webhookRetrySchedule: parsed.webhookRetrySchedule.trim() || null,
+    tags: Array.from(new Set(parsed.tags)),
+    environment: parsed.environment,
+    priority: priorityValue(parsed.priority),
+    concurrencyGroup: parsed.concurrencyGroup || null,
+    quietHoursStart: parsed.quietHoursStart || null,
+    quietHoursEnd: parsed.quietHoursEnd || null,
+    blackoutDates: Array.from(new Set(parsed.blackoutDates)).sort(),
   };
 }
 
-export function toMaskedApiJob(job: Job) {
-  const { allowWebSearch, ...jobRest } = job;
-  if (job.channelType === ChannelType.in_app) {
-    return {
-      ...jobRest,
-      useWebSearch: allowWebSearch,
-      channel: { type: "in_app" as const },
-    };
-  }
-  if (job.channelType === ChannelType.discord) {
-    const raw = job.channelConfig as { webhookUrlEnc: string };
-    const webhook = decryptString(raw.webhookUrlEnc);
-    return {
-      ...jobRest,
-      useWebSearch: allowWebSearch,
-      channel: {
-        type: "discord" as const,
-        config: { webhookUrl: maskSecret(webhook) },
-      },
-    };
-  }
+// Decrypts the stored channel (any config version) back into the shape accepted by the job editor and upsert schema.
+export function toEditableChannel(job: StoredChannel): IncomingChannel {
+  return decodeChannelConfig(job);
+}
 
-  if (job.channelType === ChannelType.webhook) {
-    const raw = job.channelConfig as { configEnc: string };
-    const parsed = JSON.parse(decryptString(raw.configEnc)) as {
-      url: string;
-      method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
-      headers: string;
-      payload: string;
-    };
-    return {
-      ...jobRest,
-      useWebSearch: allowWebSearch,
-      channel: {
-        type: "webhook" as const,
+// Channel config with secrets masked, for API responses.
+export function toMaskedChannel(job: StoredChannel) {
+  const channel = toEditableChannel(job);
+  switch (channel.type) {
+    case "in_app":
+      return channel;
+    case "discord":
+      return { type: channel.type, config: { ...channel.config, webhookUrl: maskSecret(channel.config.webhookUrl) } };
+    case "telegram":
+      return {
+        type: channel.type,
+        config: { ...channel.config, botToken: maskSecret(channel.config.botToken), chatId: maskSecret(channel.config.chatId) },
+      };
+    case "webhook":
+      return {
+        type: channel.type,
         config: {
-          url: maskSecret(parsed.url),
-          method: parsed.method,
-          headers: parsed.headers,
-          payload: parsed.payload,
+          ...channel.config,
+          url: maskSecret(channel.config.url),
+          signingSecret: channel.config.signingSecret ? maskSecret(channel.config.signingSecret) : "",
+          proxyUrl: channel.config.proxyUrl ? maskSecret(channel.config.proxyUrl) : "",
         },
-      },
-    };
+      };
+    case "home_assistant":
+      return { type: channel.type, config: { ...channel.config, token: maskSecret(channel.config.token) } };
+    case "elasticsearch":
+      return {
+        type: channel.type,
+        config: { ...channel.config, apiKey: maskSecret(channel.config.apiKey), password: maskSecret(channel.config.password) },
+      };
+    case "clickhouse":
+      return { type: channel.type, config: { ...channel.config, password: maskSecret(channel.config.password) } };
+    case "bigquery":
+      return { type: channel.type, config: { ...channel.config, serviceAccountJson: maskSecret(channel.config.serviceAccountJson) } };
+    case "redis":
+      return { type: channel.type, config: { ...channel.config, token: maskSecret(channel.config.token) } };
+    case "pushover":
+      return {
+        type: channel.type,
+        config: { ...channel.config, appToken: maskSecret(channel.config.appToken), userKey: maskSecret(channel.config.userKey) },
+      };
+    case "twilio":
+      return {
+        type: channel.type,
+        config: {
+          ...channel.config,
+          accountSid: maskSecret(channel.config.accountSid),
+          authToken: maskSecret(channel.config.authToken),
+          from: maskSecret(channel.config.from),
+          to: maskSecret(channel.config.to),
+        },
+      };
+    case "google_chat":
+      return { type: channel.type, config: { ...channel.config, webhookUrl: maskSecret(channel.config.webhookUrl) } };
+    case "aws":
+      return {
+        type: channel.type,
+        config: {
+          ...channel.config,
+          accessKeyId: channel.config.accessKeyId ? maskSecret(channel.config.accessKeyId) : "",
+          secretAccessKey: channel.config.secretAccessKey ? maskSecret(channel.config.secretAccessKey) : "",
+        },
+      };
+    case "kafka":
+      return { type: channel.type, config: { ...channel.config, password: maskSecret(channel.config.password) } };
+    case "nats":
+      return {
+        type: channel.type,
+        config: {
+          ...channel.config,
+          password: channel.config.password ? maskSecret(channel.config.password) : "",
+          token: channel.config.token ? maskSecret(channel.config.token) : "",
+        },
+      };
+    case "mqtt":
+      return {
+        type: channel.type,
+        config: { ...channel.config, password: channel.config.password ? maskSecret(channel.config.password) : "" },
+      };
+    case "file":
+      return { type: channel.type, config: channel.config };
+    case "notion":
+      return { type: channel.type, config: { ...channel.config, token: maskSecret(channel.config.token) } };
+    case "google_sheets":
+      return { type: channel.type, config: { ...channel.config, serviceAccountJson: maskSecret(channel.config.serviceAccountJson) } };
+    case "jira":
+      return { type: channel.type, config: { ...channel.config, apiToken: maskSecret(channel.config.apiToken) } };
+    case "plugin":
+      return { type: channel.type, config: { ...channel.config, settings: maskSecret(channel.config.settings) } };
   }
+}
 
-  const raw = job.channelConfig as { botTokenEnc: string; chatIdEnc: string };
+export function toMaskedApiJob(job: Job) {
+  const { allowWebSearch, ...jobRest } = job;
   return {
     ...jobRest,
     useWebSearch: allowWebSearch,
-    channel: {
-      type: "telegram" as const,
-      config: {
-        botToken: maskSecret(decryptString(raw.botTokenEnc)),
-        chatId: maskSecret(decryptString(raw.chatIdEnc)),
-      },
-    },
+    channel: toMaskedChannel(job),
   };
 }
 
-export function toRunnableChannel(job: Job) {
-  if (job.channelType === ChannelType.in_app) {
-    throw new Error("In-app delivery jobs do not have a runnable external channel");
+// Converts an editor/API channel into the input accepted by sendChannelMessage.
+export function toRunnableIncomingChannel(channel: Exclude<IncomingChannel, { type: "in_app" }>): SendChannelInput {
+  if (channel.type === "discord") {
+    return { type: "discord", webhookUrl: channel.config.webhookUrl, threadId: channel.config.threadId, threadName: channel.config.threadName };
   }
-  if (job.channelType === ChannelType.discord) {
-    const raw = job.channelConfig as { webhookUrlEnc: string };
-    return {
-      type: "discord" as const,
-      webhookUrl: decryptString(raw.webhookUrlEnc),
-    };
+  if (channel.type === "telegram") {
+    return { type: "telegram", botToken: channel.config.botToken, chatId: channel.config.chatId, messageThreadId: channel.config.messageThreadId };
   }
-
-  if (job.channelType === ChannelType.webhook) {
-    const raw = job.channelConfig as { configEnc: string };
-    const parsed = JSON.parse(decryptString(raw.configEnc)) as {
-      url: string;
-      method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
-      headers: string;
-      payload: string;
-    };
+  if (channel.type === "home_assistant") {
     return {
-      type: "webhook" as const,
-      url: parsed.url,
-      method: parsed.method,
-      headers: parsed.headers,
-      payload: parsed.payload,
+      type: "home_assistant",
+      baseUrl: channel.config.baseUrl,
+      token: channel.config.token,
+      service: channel.config.service,
     };
   }
-
-  const raw = job.channelConfig as { botTokenEnc: string; chatIdEnc: string };
+  if (channel.type === "elasticsearch") {
+    return { type: "elasticsearch", ...channel.config };
+  }
+  if (channel.type === "clickhouse") {
+    return { type: "clickhouse", ...channel.config };
+  }
+  if (channel.type === "bigquery") {
+    return { type: "bigquery", ...channel.config };
+  }
+  if (channel.type === "redis") {
+    return { type: "redis", ...channel.config };
+  }
+  if (channel.type === "pushover") {
+    return { type: "pushover", ...channel.config };
+  }
+  if (channel.type === "twilio") {
+    return { type: "twilio", ...channel.config };
+  }
+  if (channel.type === "google_chat") {
+    return { type: "google_chat", ...channel.config };
+  }
+  if (channel.type === "aws") {
+    return { type: "aws", ...channel.config };
+  }
+  if (channel.type === "kafka") {
+    return { type: "kafka", ...channel.config };
+  }
+  if (channel.type === "nats") {
+    return { type: "nats", ...channel.config };
+  }
+  if (channel.type === "mqtt") {
+    return { type: "mqtt", ...channel.config };
+  }
+  if (channel.type === "file") {
+    return { type: "file", ...channel.config };
+  }
+  if (channel.type === "notion") {
+    return { type: "notion", ...channel.config };
+  }
+  if (channel.type === "google_sheets") {
+    return { type: "google_sheets", ...channel.config };
+  }
+  if (channel.type === "jira") {
+    return { type: "jira", ...channel.config };
+  }
+  if (channel.type === "plugin") {
+    return { type: "plugin", ...channel.config };
+  }
   return {
-    type: "telegram" as const,
-    botToken: decryptString(raw.botTokenEnc),
-    chatId: decryptString(raw.chatIdEnc),
+    type: "webhook",
+    url: channel.config.url,
+    method: channel.config.method,
+    headers: channel.config.headers,
+    payload: channel.config.payload,
+    graphqlQuery: channel.config.graphqlQuery ?? "",
+    bodyFormat: channel.config.bodyFormat ?? "json",
+    gzip: channel.config.gzip ?? false,
+    signingSecret: channel.config.signingSecret ?? "",
+    captureResponse: channel.config.captureResponse ?? false,
+    proxyUrl: channel.config.proxyUrl ?? "",
+    caBundle: channel.config.caBundle ?? "",
+    insecureSkipVerify: channel.config.insecureSkipVerify ?? false,
   };
 }
+
+// Lock time: the stored config must still pass its schema, otherwise the run fails with a ChannelConfigError.
+export function toRunnableChannel(job: StoredChannel): SendChannelInput {
+  const channel = validateChannel(toEditableChannel(job));
+  if (channel.type === "in_app") {
+    throw new Error("In-app delivery jobs do not have a runnable external channel");
+  }
+  return toRunnableIncomingChannel(channel);
+}
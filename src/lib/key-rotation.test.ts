@@ -0,0 +1,25 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { decryptString, encryptString, keyIdOf } from "./crypto";
+import { reencryptChannelConfig } from "./key-rotation";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("key rotation", () => {
+  it("re-encrypts only the encrypted fields written with an older key", () => {
+    vi.stubEnv("CHANNEL_SECRET_KEYS", "v1:first-secret");
+    const config = { botTokenEnc: encryptString("token"), chatIdEnc: encryptString("42"), note: "plain" };
+
+    vi.stubEnv("CHANNEL_SECRET_KEYS", "v2:second-secret,v1:first-secret");
+    const next = reencryptChannelConfig(config, "v2");
+    expect(next?.note).toBe("plain");
+    expect(keyIdOf(String(next?.botTokenEnc))).toBe("v2");
+    expect(decryptString(String(next?.chatIdEnc))).toBe("42");
+    expect(reencryptChannelConfig(next, "v2")).toBeNull();
+    expect(reencryptChannelConfig(null, "v2")).toBeNull();
+  });
+});
@@ -0,0 +1,111 @@
+import { Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { currentKeyId, hasRetiredKeys, keyIdOf, reencryptString } from "@/lib/crypto";
+import { isRecord } from "@/lib/type-guards";
+import { logger } from "@/lib/logger";
+
+// Re-encrypts the *Enc values of a stored channel config with the current key; null when nothing changes.
+export function reencryptChannelConfig(config: unknown, keyId: string) {
+  if (!isRecord(config)) {
+    return null;
+  }
+  let changed = false;
+  const next: Record<string, unknown> = { ...config };
+  for (const [field, value] of Object.entries(config)) {
+    if (field.endsWith("Enc") && typeof value === "string" && keyIdOf(value) !== keyId) {
+      next[field] = reencryptString(value);
+      changed = true;
+    }
+  }
+  return changed ? next : null;
+}
+
+// Rotation runs a batch per worker tick instead of one big migration: values written with a retired key are
+// decrypted and written again with the current one. Each update only applies if the row was not edited meanwhile.
+export async function reencryptStaleSecrets(limit: number) {
+  if (!hasRetiredKeys()) {
+    return 0;
+  }
+  const keyId = currentKeyId();
+  if (!keyId) {
+    // The unversioned legacy key is current: there is nothing newer to rotate to.
+    return 0;
+  }
+  const prefix = `${keyId}:`;
+
+  const jobs = await prisma.$queryRaw<Array<{ id: string; channel_config: unknown }>>`
+    SELECT j.id, j.channel_config
+    FROM jobs j
+    WHERE jsonb_typeof(j.channel_config) = 'object'
+      AND EXISTS (
+        SELECT 1 FROM jsonb_each_text(j.channel_config) e
+        WHERE e.key LIKE '%Enc' AND left(e.value, ${prefix.length}) <> ${prefix}
+      )
+    LIMIT ${limit}
+  `;
+
+  let updated = 0;
+  for (const job of jobs) {
+    try {
+      const next = reencryptChannelConfig(job.channel_config, keyId);
+      if (!next) {
+        continue;
+      }
+      const res = await prisma.job.updateMany({
+        where: { id: job.id, channelConfig: { equals: job.channel_config as Prisma.InputJsonValue } },
+        data: { channelConfig: next as Prisma.InputJsonValue },
+      });
+      updated += res.count;
+    } catch (err) {
+      logger.warn("channel config re-encryption failed", { job_id: job.id, error: err });
+    }
+  }
+
+  const savedChannels = await prisma.$queryRaw<Array<{ id: string; channel_config: unknown }>>`
+    SELECT c.id, c.channel_config
+    FROM saved_channels c
+    WHERE jsonb_typeof(c.channel_config) = 'object'
+      AND EXISTS (
+        SELECT 1 FROM jsonb_each_text(c.channel_config) e
+        WHERE e.key LIKE '%Enc' AND left(e.value, ${prefix.length}) <> ${prefix}
+      )
+    LIMIT ${limit}
+  `;
+  for (const channel of savedChannels) {
+    try {
+      const next = reencryptChannelConfig(channel.channel_config, keyId);
+      if (!next) {
+        continue;
+      }
+      const res = await prisma.savedChannel.updateMany({
+        where: { id: channel.id, channelConfig: { equals: channel.channel_config as Prisma.InputJsonValue } },
+        data: { channelConfig: next as Prisma.InputJsonValue },
+      });
+      updated += res.count;
+    } catch (err) {
+      logger.warn("saved channel re-encryption failed", { channel_id: channel.id, error: err });
+    }
+  }
+
+  const secrets = await prisma.userSecret.findMany({
+    where: { NOT: { valueEnc: { startsWith: prefix } } },
+    select: { id: true, valueEnc: true },
+    take: limit,
+  });
+  for (const secret of secrets) {
+    try {
+      const res = await prisma.userSecret.updateMany({
+        where: { id: secret.id, valueEnc: secret.valueEnc },
+        data: { valueEnc: reencryptString(secret.valueEnc) },
+      });
+      updated += res.count;
+    } catch (err) {
+      logger.warn("user secret re-encryption failed", { secret_id: secret.id, error: err });
+    }
+  }
+
+  if (updated) {
+    logger.info("secrets re-encrypted", { key_id: keyId, count: updated });
+  }
+  return updated;
+}
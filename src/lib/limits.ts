@@ -2,11 +2,12 @@ import { startOfDay } from "date-fns";
 import { prisma } from "@/lib/prisma";
 import { getEntitlements } from "@/lib/entitlements";
 import { LimitError } from "@/lib/limit-errors";
+import { clock } from "@/lib/clock";
 
 export async function enforceDailyRunLimit(userId: string) {
   const entitlements = await getEntitlements(userId);
   const limit = entitlements.limits.dailyRunLimit;
-  const dayStart = startOfDay(new Date());
+  const dayStart = startOfDay(clock().now());
 
   const [runCount, previewCount] = await Promise.all([
     prisma.runHistory.count({
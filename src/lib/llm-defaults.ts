@@ -1,6 +1,17 @@
 export const DEFAULT_LLM_MODEL = "gpt-5-mini" as const;
 export const DEFAULT_WEB_SEARCH_MODE = "native" as const;
 
+// Per-job system prompts (persona, tone, format) are capped at this many characters.
+export const JOB_SYSTEM_PROMPT_MAX = 4000;
+
+// Voices of the provider's speech API, for jobs that also deliver the output as audio.
+export const TTS_VOICES = ["alloy", "ash", "ballad", "coral", "echo", "fable", "nova", "onyx", "sage", "shimmer", "verse"] as const;
+export type TtsVoice = (typeof TTS_VOICES)[number];
+
+export function normalizeTtsVoice(voice: unknown): TtsVoice | null {
+  return TTS_VOICES.includes(voice as TtsVoice) ? (voice as TtsVoice) : null;
+}
+
 export type WebSearchMode = typeof DEFAULT_WEB_SEARCH_MODE | "parallel";
 
 export function normalizeWebSearchMode(mode: unknown): WebSearchMode {
@@ -14,6 +25,10 @@ export function normalizeLlmModel(model: unknown): string {
   const trimmed = model.trim();
   if (!trimmed) return DEFAULT_LLM_MODEL;
 
+  if (trimmed.toLowerCase().startsWith("openrouter/")) {
+    return trimmed.length > "openrouter/".length ? trimmed : DEFAULT_LLM_MODEL;
+  }
+
   if (trimmed.includes("/")) {
     const [provider, rest] = trimmed.split("/", 2);
     if (provider === "openai" && rest && rest.trim()) {
@@ -26,6 +41,7 @@ export function normalizeLlmModel(model: unknown): string {
 }
 
 export const AVAILABLE_OPENAI_MODELS: Array<{ id: string; name: string }> = [
+  { id: "auto", name: "auto (cheapest suitable model)" },
   { id: "gpt-5-mini", name: "gpt-5-mini" },
   { id: "gpt-5.2", name: "gpt-5.2" },
   { id: "gpt-5.1", name: "gpt-5.1" },
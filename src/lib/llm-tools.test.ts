@@ -0,0 +1,25 @@
+import { describe, expect, it } from "vitest";
+import { llmToolsFromForm, llmToolsSchema, llmToolsToForm, normalizeLlmTools } from "./llm-tools";
+
+describe("llm tools", () => {
+  it("validates tool lists", () => {
+    expect(llmToolsSchema.safeParse([{ type: "code_interpreter" }, { type: "file_search", vectorStoreIds: ["vs_abc"] }]).success).toBe(true);
+    expect(llmToolsSchema.safeParse([{ type: "code_interpreter" }, { type: "code_interpreter" }]).success).toBe(false);
+    expect(llmToolsSchema.safeParse([{ type: "file_search", vectorStoreIds: [] }]).success).toBe(false);
+    expect(llmToolsSchema.safeParse([{ type: "file_search", vectorStoreIds: ["abc"] }]).success).toBe(false);
+    expect(llmToolsSchema.safeParse([{ type: "shell" }]).success).toBe(false);
+  });
+
+  it("ignores stored values that do not parse", () => {
+    expect(normalizeLlmTools(null)).toEqual([]);
+    expect(normalizeLlmTools([{ type: "shell" }])).toEqual([]);
+    expect(normalizeLlmTools([{ type: "code_interpreter" }])).toEqual([{ type: "code_interpreter" }]);
+  });
+
+  it("maps the editor fields", () => {
+    const tools = llmToolsFromForm({ codeInterpreter: true, fileSearchVectorStores: " vs_a, vs_b ," });
+    expect(tools).toEqual([{ type: "file_search", vectorStoreIds: ["vs_a", "vs_b"] }, { type: "code_interpreter" }]);
+    expect(llmToolsToForm(tools)).toEqual({ codeInterpreter: true, fileSearchVectorStores: "vs_a, vs_b" });
+    expect(llmToolsFromForm({ codeInterpreter: false, fileSearchVectorStores: "" })).toEqual([]);
+  });
+});
@@ -0,0 +1,58 @@
+import { z } from "zod";
+
+// Extra OpenAI (Responses API) tools a job can enable besides web search. Both run on the provider side, so a
+// single model call returns their results.
+// - file_search: retrieval over uploaded vector stores (ids start with "vs_").
+// - code_interpreter: a sandboxed Python container for data-crunching prompts.
+export const LLM_TOOL_TYPES = ["file_search", "code_interpreter"] as const;
+export type LlmToolType = (typeof LLM_TOOL_TYPES)[number];
+
+export const llmToolSchema = z.discriminatedUnion("type", [
+  z
+    .object({
+      type: z.literal("file_search"),
+      vectorStoreIds: z
+        .array(z.string().trim().regex(/^vs_[A-Za-z0-9_-]{1,128}$/, "Vector store ids look like vs_abc123"))
+        .min(1)
+        .max(2),
+      maxNumResults: z.number().int().min(1).max(50).optional(),
+    })
+    .strict(),
+  z.object({ type: z.literal("code_interpreter") }).strict(),
+]);
+
+export type LlmTool = z.infer<typeof llmToolSchema>;
+
+export const llmToolsSchema = z
+  .array(llmToolSchema)
+  .max(LLM_TOOL_TYPES.length)
+  .refine((tools) => new Set(tools.map((tool) => tool.type)).size === tools.length, "Each tool can be listed once");
+
+// jobs.llm_tools is JSON; anything that does not parse (hand-edited rows) runs without extra tools.
+export function normalizeLlmTools(value: unknown): LlmTool[] {
+  const parsed = llmToolsSchema.safeParse(value ?? []);
+  return parsed.success ? parsed.data : [];
+}
+
+export function fileSearchTool(tools: LlmTool[]) {
+  return tools.find((tool): tool is Extract<LlmTool, { type: "file_search" }> => tool.type === "file_search") ?? null;
+}
+
+// Editor fields <-> stored list.
+export function llmToolsFromForm(form: { codeInterpreter: boolean; fileSearchVectorStores: string }): LlmTool[] {
+  const vectorStoreIds = form.fileSearchVectorStores
+    .split(",")
+    .map((id) => id.trim())
+    .filter(Boolean);
+  return [
+    ...(vectorStoreIds.length ? [{ type: "file_search" as const, vectorStoreIds }] : []),
+    ...(form.codeInterpreter ? [{ type: "code_interpreter" as const }] : []),
+  ];
+}
+
+export function llmToolsToForm(tools: LlmTool[]) {
+  return {
+    codeInterpreter: tools.some((tool) => tool.type === "code_interpreter"),
+    fileSearchVectorStores: fileSearchTool(tools)?.vectorStoreIds.join(", ") ?? "",
+  };
+}
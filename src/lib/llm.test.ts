@@ -0,0 +1,112 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { streamText } = vi.hoisted(() => ({ streamText: vi.fn() }));
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+vi.mock("ai", () => ({ streamText }));
+vi.mock("@ai-sdk/openai", () => ({
+  openai: Object.assign((model: string) => ({ model }), { tools: { webSearch: () => ({}) } }),
+  createOpenAI: () => ({ chat: (model: string) => ({ model, api: "openrouter" }) }),
+}));
+
+import { runPrompt, streamLimits } from "./llm";
+
+type Part = { type: string; text?: string; error?: unknown };
+
+function fakeStream(parts: Part[], opts: { hangAfter?: boolean; modelId?: string } = {}) {
+  streamText.mockImplementation(({ abortSignal }: { abortSignal: AbortSignal }) => {
+    async function* fullStream() {
+      for (const part of parts) {
+        yield part;
+      }
+      if (opts.hangAfter) {
+        await new Promise((_, reject) => abortSignal.addEventListener("abort", () => reject(new Error("aborted"))));
+      }
+    }
+    const text = parts.map((part) => part.text ?? "").join("");
+    return {
+      fullStream: fullStream(),
+      text: Promise.resolve(text),
+      finishReason: Promise.resolve("stop"),
+      usage: Promise.resolve({ inputTokens: 1, outputTokens: 2 }),
+      sources: Promise.resolve([]),
+      files: Promise.resolve([]),
+      toolCalls: Promise.resolve([]),
+      toolResults: Promise.resolve([]),
+      request: Promise.resolve({}),
+      response: Promise.resolve(opts.modelId ? { modelId: opts.modelId } : {}),
+    };
+  });
+}
+
+const opts = { model: "gpt-4o-mini", useWebSearch: false, webSearchMode: "native" as const };
+
+describe("streamed prompt runs", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+    vi.useRealTimers();
+    streamText.mockReset();
+  });
+
+  it("reads the stream limits from env", () => {
+    vi.stubEnv("LLM_FIRST_TOKEN_TIMEOUT_MS", "500000");
+    vi.stubEnv("LLM_MAX_OUTPUT_TOKENS", "800");
+    expect(streamLimits(60_000)).toEqual({ timeoutMs: 60_000, firstTokenMs: 60_000, stallMs: 30_000, maxOutputTokens: 800 });
+  });
+
+  it("collects the streamed output and passes the token cap", async () => {
+    vi.stubEnv("LLM_MAX_OUTPUT_TOKENS", "800");
+    fakeStream([{ type: "text-delta", text: "Hello " }, { type: "text-delta", text: "world" }, { type: "finish" }]);
+    const result = await runPrompt("hi", opts);
+    expect(result.output).toBe("Hello world");
+    expect(typeof result.ttftMs).toBe("number");
+    expect(streamText.mock.calls[0][0]).toMatchObject({ maxOutputTokens: 800 });
+  });
+
+  it("passes job generation settings and keeps the deployment token cap", async () => {
+    vi.stubEnv("LLM_MAX_OUTPUT_TOKENS", "800");
+    fakeStream([{ type: "text-delta", text: "ok" }]);
+    await runPrompt("hi", { ...opts, generation: { maxOutputTokens: 2000, temperature: 3, reasoningEffort: "low", verbosity: "high" } });
+    expect(streamText.mock.calls[0][0]).toMatchObject({
+      maxOutputTokens: 800,
+      temperature: 2,
+      providerOptions: { openai: { reasoningEffort: "low", textVerbosity: "high" } },
+    });
+
+    vi.stubEnv("LLM_MAX_OUTPUT_TOKENS", "");
+    expect(streamLimits(60_000, 300).maxOutputTokens).toBe(300);
+    expect(streamLimits(60_000).maxOutputTokens).toBeUndefined();
+  });
+
+  it("sends openrouter models through the OpenRouter chat API and records the served model", async () => {
+    fakeStream([{ type: "text-delta", text: "ok" }], { modelId: "anthropic/claude-3.5-haiku-20241022" });
+    const result = await runPrompt("hi", { ...opts, model: "openrouter/anthropic/claude-3.5-haiku" });
+    expect(streamText.mock.calls[0][0]).toMatchObject({ model: { model: "anthropic/claude-3.5-haiku", api: "openrouter" } });
+    expect(result).toMatchObject({ llmModel: "openrouter/anthropic/claude-3.5-haiku", servedModel: "anthropic/claude-3.5-haiku-20241022" });
+
+    await expect(runPrompt("hi", { ...opts, model: "openrouter/auto", useWebSearch: true })).rejects.toThrow("need an OpenAI model");
+  });
+
+  it("rethrows stream errors", async () => {
+    fakeStream([{ type: "error", error: new Error("rate limited") }]);
+    await expect(runPrompt("hi", opts)).rejects.toThrow("rate limited");
+  });
+
+  it("aborts a stream that stalls after output started", async () => {
+    vi.useFakeTimers();
+    vi.stubEnv("LLM_STALL_TIMEOUT_MS", "5000");
+    fakeStream([{ type: "text-delta", text: "partial" }], { hangAfter: true });
+    const run = expect(runPrompt("hi", opts)).rejects.toThrow("timed out: the stream stalled for 5s");
+    await vi.advanceTimersByTimeAsync(5000);
+    await run;
+  });
+
+  it("aborts when no first token arrives in time", async () => {
+    vi.useFakeTimers();
+    vi.stubEnv("LLM_FIRST_TOKEN_TIMEOUT_MS", "10000");
+    fakeStream([], { hangAfter: true });
+    const run = expect(runPrompt("hi", opts)).rejects.toThrow("timed out waiting 10s for the first token");
+    await vi.advanceTimersByTimeAsync(10_000);
+    await run;
+  });
+});
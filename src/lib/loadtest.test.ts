@@ -0,0 +1,26 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { batchTag, isLoadtestModel, LOADTEST_MODEL, runLoadtestPrompt } from "./loadtest";
+
+describe("load test harness", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("only serves the mock model when load testing is enabled", () => {
+    expect(isLoadtestModel(LOADTEST_MODEL)).toBe(false);
+    vi.stubEnv("LOADTEST_ENABLED", "1");
+    expect(isLoadtestModel(LOADTEST_MODEL)).toBe(true);
+    expect(isLoadtestModel("gpt-5-mini")).toBe(false);
+  });
+
+  it("returns a deterministic output after the configured latency", async () => {
+    vi.stubEnv("LOADTEST_LLM_LATENCY_MS", "0");
+    const output = await runLoadtestPrompt("hello");
+    expect(output).toBe(await runLoadtestPrompt("hello"));
+    expect(output).toContain("Prompt: 5 characters.");
+    expect(batchTag("ab12cd34")).toBe("loadtest:ab12cd34");
+  });
+});
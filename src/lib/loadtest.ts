@@ -0,0 +1,114 @@
+import { randomUUID } from "crypto";
+import { ChannelType, ScheduleType } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { toDbChannelConfig } from "@/lib/jobs";
+
+// Synthetic load for capacity planning (`promptloop loadtest`). Seeded jobs belong to one dedicated user, run once
+// on a mock model that never calls a provider, and deliver to a webhook served by the CLI. Everything is off
+// unless LOADTEST_ENABLED=1, so production deployments cannot be seeded by accident.
+export const LOADTEST_MODEL = "loadtest-mock";
+export const LOADTEST_TAG = "loadtest";
+export const LOADTEST_MAX_JOBS = 5000;
+
+const LOADTEST_PROVIDER_USER_ID = "promptloop-loadtest";
+const DEFAULT_LATENCY_MS = 200;
+const DAILY_RUN_LIMIT = 1_000_000;
+
+export function loadtestEnabled() {
+  return process.env.LOADTEST_ENABLED === "1";
+}
+
+export function isLoadtestModel(model: string) {
+  return loadtestEnabled() && model === LOADTEST_MODEL;
+}
+
+function mockLatencyMs() {
+  const value = Number(process.env.LOADTEST_LLM_LATENCY_MS ?? DEFAULT_LATENCY_MS);
+  return Number.isFinite(value) && value >= 0 ? Math.floor(value) : DEFAULT_LATENCY_MS;
+}
+
+// Stands in for the provider call: waits LOADTEST_LLM_LATENCY_MS and returns a fixed-size output.
+export async function runLoadtestPrompt(prompt: string) {
+  await new Promise((resolve) => setTimeout(resolve, mockLatencyMs()));
+  return `Load test output\n\n${"lorem ipsum ".repeat(80).trim()}\n\nPrompt: ${prompt.length} characters.`;
+}
+
+export function batchTag(batch: string) {
+  return `${LOADTEST_TAG}:${batch}`;
+}
+
+export async function seedLoadtestJobs(input: { jobs: number; webhookUrl: string; environment: string; now?: Date }) {
+  const now = input.now ?? new Date();
+  const user = await prisma.user.upsert({
+    where: { provider_providerUserId: { provider: "github", providerUserId: LOADTEST_PROVIDER_USER_ID } },
+    create: { provider: "github", providerUserId: LOADTEST_PROVIDER_USER_ID, name: "Load test", overrideDailyRunLimit: DAILY_RUN_LIMIT },
+    update: { overrideDailyRunLimit: DAILY_RUN_LIMIT },
+    select: { id: true },
+  });
+  const channel = toDbChannelConfig({
+    type: "webhook",
+    config: { url: input.webhookUrl, method: "POST", headers: "", payload: "", bodyFormat: "json" },
+  });
+  const batch = randomUUID().slice(0, 8);
+  await prisma.job.createMany({
+    data: Array.from({ length: input.jobs }, (_, index) => ({
+      userId: user.id,
+      name: `loadtest ${batch} #${index + 1}`,
+      prompt: `Load test job ${index + 1}`,
+      llmModel: LOADTEST_MODEL,
+      scheduleType: ScheduleType.once,
+      scheduleTime: "00:00",
+      runAt: now,
+      nextRunAt: now,
+      timezone: "UTC",
+      environment: input.environment,
+      tags: [LOADTEST_TAG, batchTag(batch)],
+      channelType: ChannelType.webhook,
+      channelConfig: channel.channelConfig,
+    })),
+  });
+  return { batch, jobs: input.jobs, seededAt: now.toISOString() };
+}
+
+// Progress of one batch. lockWaits samples sessions blocked on a row or table lock right now, the direct measure
+// of claim contention (claims themselves use SKIP LOCKED and never wait).
+export async function loadtestStatus(batch: string) {
+  const where = { tags: { has: batchTag(batch) } };
+  const [jobs, pending, locked, statuses, timing, waits] = await Promise.all([
+    prisma.job.count({ where }),
+    prisma.job.count({ where: { ...where, enabled: true } }),
+    prisma.job.count({ where: { ...where, lockedAt: { not: null } } }),
+    prisma.runHistory.groupBy({ by: ["status"], where: { job: where }, _count: { _all: true } }),
+    prisma.runHistory.aggregate({
+      where: { job: where },
+      _min: { runAt: true },
+      _max: { deliveredAt: true },
+      _avg: { llmDurationMs: true },
+    }),
+    prisma.$queryRaw<Array<{ waiting: bigint }>>`
+      SELECT count(*) AS waiting FROM pg_stat_activity WHERE datname = current_database() AND wait_event_type = 'Lock'
+    `,
+  ]);
+  return {
+    batch,
+    jobs,
+    pending,
+    locked,
+    runs: Object.fromEntries(statuses.map((row) => [row.status, row._count._all])),
+    firstRunAt: timing._min.runAt,
+    lastDeliveredAt: timing._max.deliveredAt,
+    avgLlmDurationMs: timing._avg.llmDurationMs,
+    lockWaits: Number(waits[0]?.waiting ?? 0),
+  };
+}
+
+// Deletes the batch's jobs (runs cascade), or every load test job when no batch is given.
+export async function cleanupLoadtest(batch?: string) {
+  const deleted = await prisma.job.deleteMany({
+    where: {
+      tags: { has: batch ? batchTag(batch) : LOADTEST_TAG },
+      user: { provider: "github", providerUserId: LOADTEST_PROVIDER_USER_ID },
+    },
+  });
+  return deleted.count;
+}
@@ -0,0 +1,32 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { __private__, logger } from "./logger";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+  vi.restoreAllMocks();
+});
+
+describe("logger", () => {
+  it("formats JSON lines with fields and serialized errors", () => {
+    const line = __private__.formatLine("warn", "delivery attempt failed", { job_id: "j1", attempt: 2, error: new Error("boom"), skip: undefined }, "json");
+    const parsed = JSON.parse(line) as Record<string, unknown>;
+    expect(parsed).toMatchObject({ level: "warn", msg: "delivery attempt failed", job_id: "j1", attempt: 2, error: "boom" });
+    expect("skip" in parsed).toBe(false);
+  });
+
+  it("formats text lines as key=value pairs", () => {
+    const line = __private__.formatLine("info", "job run succeeded", { job_id: "j1", duration_ms: 12 }, "text");
+    expect(line).toMatch(/ INFO job run succeeded job_id="j1" duration_ms=12$/);
+  });
+
+  it("drops entries below LOG_LEVEL and keeps bound fields", () => {
+    vi.stubEnv("LOG_LEVEL", "warn");
+    const spy = vi.spyOn(console, "log").mockImplementation(() => {});
+    const warnSpy = vi.spyOn(console, "warn").mockImplementation(() => {});
+    const log = logger.with({ job_id: "j1" });
+    log.info("hidden");
+    log.warn("shown", { attempt: 1 });
+    expect(spy).not.toHaveBeenCalled();
+    expect(JSON.parse(warnSpy.mock.calls[0][0] as string)).toMatchObject({ msg: "shown", job_id: "j1", attempt: 1 });
+  });
+});
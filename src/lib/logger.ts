@@ -0,0 +1,68 @@
+type LogLevel = "debug" | "info" | "warn" | "error";
+type LogFields = Record<string, unknown>;
+
+const LEVELS: Record<LogLevel, number> = { debug: 10, info: 20, warn: 30, error: 40 };
+
+function configuredLevel(): LogLevel {
+  const raw = (process.env.LOG_LEVEL ?? "").trim().toLowerCase();
+  return raw in LEVELS ? (raw as LogLevel) : "info";
+}
+
+function configuredFormat(): "json" | "text" {
+  return (process.env.LOG_FORMAT ?? "").trim().toLowerCase() === "text" ? "text" : "json";
+}
+
+function serializeValue(value: unknown): unknown {
+  if (value instanceof Error) {
+    return value.message;
+  }
+  if (value instanceof Date) {
+    return value.toISOString();
+  }
+  return value;
+}
+
+function formatLine(level: LogLevel, msg: string, fields: LogFields, format: "json" | "text") {
+  const entries = Object.entries(fields)
+    .filter(([, v]) => v !== undefined)
+    .map(([k, v]) => [k, serializeValue(v)] as const);
+  const time = new Date().toISOString();
+  if (format === "json") {
+    return JSON.stringify({ time, level, msg, ...Object.fromEntries(entries) });
+  }
+  const rest = entries.map(([k, v]) => `${k}=${JSON.stringify(v) ?? "null"}`);
+  return [time, level.toUpperCase(), msg, ...rest].join(" ");
+}
+
+export type Logger = {
+  debug: (msg: string, fields?: LogFields) => void;
+  info: (msg: string, fields?: LogFields) => void;
+  warn: (msg: string, fields?: LogFields) => void;
+  error: (msg: string, fields?: LogFields) => void;
+  with: (fields: LogFields) => Logger;
+};
+
+// Leveled logger that writes one line per event (JSON by default) so logs can be queried by field.
+// Common field names: job_id, job_name, channel_type, run_id, attempt, duration_ms, error.
+function createLogger(base: LogFields): Logger {
+  const log = (level: LogLevel, msg: string, fields?: LogFields) => {
+    if (LEVELS[level] < LEVELS[configuredLevel()]) {
+      return;
+    }
+    const line = formatLine(level, msg, { ...base, ...fields }, configuredFormat());
+    if (level === "error") console.error(line);
+    else if (level === "warn") console.warn(line);
+    else console.log(line);
+  };
+  return {
+    debug: (msg, fields) => log("debug", msg, fields),
+    info: (msg, fields) => log("info", msg, fields),
+    warn: (msg, fields) => log("warn", msg, fields),
+    error: (msg, fields) => log("error", msg, fields),
+    with: (fields) => createLogger({ ...base, ...fields }),
+  };
+}
+
+export const logger = createLogger({});
+
+export const __private__ = { formatLine };
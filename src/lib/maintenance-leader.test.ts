@@ -0,0 +1,35 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { queryRaw } = vi.hoisted(() => ({ queryRaw: vi.fn() }));
+
+vi.mock("@/lib/prisma", () => ({
+  prisma: {
+    $transaction: async (fn: (tx: unknown) => Promise<unknown>) => fn({ $queryRaw: queryRaw }),
+  },
+}));
+
+import { asMaintenanceLeader, maintenanceTimeoutMs } from "./maintenance-leader";
+
+describe("maintenance leader", () => {
+  afterEach(() => {
+    queryRaw.mockReset();
+    vi.unstubAllEnvs();
+  });
+
+  it("runs the work only on the worker holding the lock", async () => {
+    const work = vi.fn(async () => 3);
+    queryRaw.mockResolvedValueOnce([{ locked: true }]);
+    await expect(asMaintenanceLeader(work)).resolves.toBe(3);
+    queryRaw.mockResolvedValueOnce([{ locked: false }]);
+    await expect(asMaintenanceLeader(work)).resolves.toBeNull();
+    expect(work).toHaveBeenCalledTimes(1);
+  });
+
+  it("reads the lock timeout", () => {
+    expect(maintenanceTimeoutMs()).toBe(300_000);
+    vi.stubEnv("WORKER_MAINTENANCE_TIMEOUT_SECONDS", "60");
+    expect(maintenanceTimeoutMs()).toBe(60_000);
+    vi.stubEnv("WORKER_MAINTENANCE_TIMEOUT_SECONDS", "1");
+    expect(maintenanceTimeoutMs()).toBe(300_000);
+  });
+});
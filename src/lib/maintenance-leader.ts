@@ -0,0 +1,31 @@
+import { prisma } from "@/lib/prisma";
+
+// With several worker replicas, housekeeping (lock reaping, artifact/cache/history pruning, dormant-job pausing,
+// secret re-encryption, channel config upgrades, credential checks) runs on one of them per tick: whichever takes
+// the maintenance lock first. The others skip straight to due jobs.
+const LEADER_LOCK_KEY = "promptloop:maintenance-leader";
+const DEFAULT_TIMEOUT_SECONDS = 300;
+
+// WORKER_MAINTENANCE_TIMEOUT_SECONDS (default 300): how long the leader may hold the lock. Past it the lock is
+// dropped and another worker may start its own maintenance pass.
+export function maintenanceTimeoutMs() {
+  const value = Number(process.env.WORKER_MAINTENANCE_TIMEOUT_SECONDS ?? DEFAULT_TIMEOUT_SECONDS);
+  return (Number.isFinite(value) && value >= 10 ? Math.floor(value) : DEFAULT_TIMEOUT_SECONDS) * 1000;
+}
+
+// Runs `work` only if this worker wins the maintenance lock and returns its result, or null when another worker
+// holds it. The lock is a transaction-level advisory lock kept by an otherwise idle transaction for as long as
+// `work` runs (its queries use their own connections), so it is released when the work ends and also when the
+// leader crashes or loses its connection; there is no lease to expire.
+export async function asMaintenanceLeader<T>(work: () => Promise<T>): Promise<T | null> {
+  return prisma.$transaction(
+    async (tx) => {
+      const [lock] = await tx.$queryRaw<Array<{ locked: boolean }>>`SELECT pg_try_advisory_xact_lock(hashtext(${LEADER_LOCK_KEY})) AS locked`;
+      if (!lock?.locked) {
+        return null;
+      }
+      return work();
+    },
+    { maxWait: 10_000, timeout: maintenanceTimeoutMs() },
+  );
+}
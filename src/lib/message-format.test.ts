@@ -0,0 +1,51 @@
+import { describe, expect, it } from "vitest";
+import { tableToFixedWidth, tablesToCodeBlocks, toJiraWiki, toNotionBlocks, toTelegramHtml } from "./message-format";
+
+describe("message format", () => {
+  it("renders tables as aligned fixed-width rows", () => {
+    expect(tableToFixedWidth(["| Name | Qty | Note |", "|:-----|----:|:----:|", "| apple | 3 | ok |", "| **kiwi** | 12 |"])).toEqual([
+      "Name  | Qty | Note",
+      "------+-----+-----",
+      "apple |   3 |  ok",
+      "kiwi  |  12 |",
+    ]);
+  });
+
+  it("converts tables outside code fences only", () => {
+    const text = ["Intro", "a | b", "--|--", "1 | 2", "", "```", "| x | y |", "|---|---|", "```"].join("\n");
+    expect(tablesToCodeBlocks(text)).toBe(
+      ["Intro", "```", "a   | b", "----+----", "1   | 2", "```", "", "```", "| x | y |", "|---|---|", "```"].join("\n"),
+    );
+    expect(tablesToCodeBlocks("a | b\nnot a separator")).toBe("a | b\nnot a separator");
+  });
+
+  it("maps fences, inline code and bold to Telegram HTML and escapes the rest", () => {
+    expect(toTelegramHtml("**Total** <5> & `x<y`\n```ts\nif (a < b) {}\n```")).toBe(
+      '<b>Total</b> &lt;5&gt; &amp; <code>x&lt;y</code>\n<pre><code class="language-ts">if (a &lt; b) {}</code></pre>',
+    );
+    expect(toTelegramHtml("```\nunclosed")).toBe("<pre>unclosed</pre>");
+  });
+
+  it("converts Markdown to Notion blocks", () => {
+    const blocks = toNotionBlocks("## Prices\nToday **up** [src](https://x.test/a)\n\n- one\n1. two\n---\n```py\nprint(1)\n```");
+    expect(blocks.map((block) => block.type)).toEqual(["heading_2", "paragraph", "bulleted_list_item", "numbered_list_item", "divider", "code"]);
+    expect(blocks[1].paragraph).toEqual({
+      rich_text: [
+        { type: "text", text: { content: "Today " } },
+        { type: "text", text: { content: "up" }, annotations: { bold: true } },
+        { type: "text", text: { content: " " } },
+        { type: "text", text: { content: "src", link: { url: "https://x.test/a" } } },
+      ],
+    });
+    expect(blocks[5].code).toEqual({ rich_text: [{ type: "text", text: { content: "print(1)" } }], language: "python" });
+
+    const long = toNotionBlocks("x".repeat(4500))[0].paragraph as { rich_text: Array<{ text: { content: string } }> };
+    expect(long.rich_text.map((part) => part.text.content.length)).toEqual([2000, 2000, 500]);
+  });
+
+  it("converts Markdown to Jira wiki markup", () => {
+    expect(toJiraWiki("# Report\n- **P1** `api` [log](https://x.test/l)\n  - nested\n1. first\n```sh\nls **x**\n```")).toBe(
+      ["h1. Report", "* *P1* {{api}} [log|https://x.test/l]", "** nested", "# first", "{code:sh}", "ls **x**", "{code}"].join("\n"),
+    );
+  });
+});
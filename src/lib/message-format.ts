@@ -0,0 +1,294 @@
+// Channel-aware formatting for chat deliveries. Neither Discord nor Telegram renders Markdown tables, so tables
+// become fixed-width code blocks (which the chunker already keeps intact); Telegram additionally gets its HTML
+// subset so code blocks and inline code render instead of showing raw backticks.
+
+const FENCE_RE = /^\s*```(\S*)\s*$/;
+const TABLE_SEPARATOR_RE = /^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$/;
+
+type Align = "left" | "right" | "center";
+
+function splitRow(line: string) {
+  const trimmed = line.trim().replace(/^\|/, "").replace(/(?<!\\)\|$/, "");
+  return trimmed.split(/(?<!\\)\|/).map((cell) =>
+    cell
+      .trim()
+      .replace(/\\\|/g, "|")
+      .replace(/\*\*|__|`/g, ""),
+  );
+}
+
+function columnAlign(cell: string): Align {
+  const value = cell.trim();
+  if (value.startsWith(":") && value.endsWith(":")) {
+    return "center";
+  }
+  return value.endsWith(":") ? "right" : "left";
+}
+
+function pad(value: string, width: number, align: Align) {
+  const gap = width - value.length;
+  if (gap <= 0) {
+    return value;
+  }
+  if (align === "right") {
+    return `${" ".repeat(gap)}${value}`;
+  }
+  if (align === "center") {
+    const left = Math.floor(gap / 2);
+    return `${" ".repeat(left)}${value}${" ".repeat(gap - left)}`;
+  }
+  return `${value}${" ".repeat(gap)}`;
+}
+
+// Renders a GFM table (header, separator, rows) as aligned plain-text lines.
+export function tableToFixedWidth(lines: string[]) {
+  const header = splitRow(lines[0]);
+  const aligns = splitRow(lines[1]).map(columnAlign);
+  const rows = lines.slice(2).map(splitRow);
+  const columns = Math.max(header.length, ...rows.map((row) => row.length));
+  const widths = Array.from({ length: columns }, (_, index) =>
+    Math.max(3, ...[header, ...rows].map((row) => (row[index] ?? "").length)),
+  );
+  const render = (row: string[]) =>
+    widths
+      .map((width, index) => pad(row[index] ?? "", width, aligns[index] ?? "left"))
+      .join(" | ")
+      .trimEnd();
+  return [render(header), widths.map((width) => "-".repeat(width)).join("-+-"), ...rows.map(render)];
+}
+
+// Replaces Markdown tables outside code fences with fenced fixed-width blocks.
+export function tablesToCodeBlocks(text: string) {
+  const lines = text.split("\n");
+  const out: string[] = [];
+  let inFence = false;
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i];
+    if (FENCE_RE.test(line)) {
+      inFence = !inFence;
+      out.push(line);
+      continue;
+    }
+    if (!inFence && line.includes("|") && TABLE_SEPARATOR_RE.test(lines[i + 1] ?? "") && (lines[i + 1] ?? "").includes("-")) {
+      let end = i + 2;
+      while (end < lines.length && lines[end].includes("|") && lines[end].trim() && !FENCE_RE.test(lines[end])) {
+        end++;
+      }
+      out.push("```", ...tableToFixedWidth(lines.slice(i, end)), "```");
+      i = end - 1;
+      continue;
+    }
+    out.push(line);
+  }
+  return out.join("\n");
+}
+
+function escapeHtml(value: string) {
+  return value.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;").replace(/"/g, "&quot;");
+}
+
+function inlineHtml(line: string) {
+  return escapeHtml(line)
+    .replace(/`([^`\n]+)`/g, "<code>$1</code>")
+    .replace(/\*\*([^*\n]+)\*\*/g, "<b>$1</b>");
+}
+
+// Converts one message part (fences balanced by the chunker) to Telegram's HTML parse mode: fenced blocks become
+// <pre>, inline code <code>, **bold** <b>; everything else is escaped text.
+export function toTelegramHtml(text: string) {
+  const out: string[] = [];
+  let block: string[] | null = null;
+  let lang = "";
+  for (const line of text.split("\n")) {
+    const fence = FENCE_RE.exec(line);
+    if (fence && block == null) {
+      block = [];
+      lang = fence[1] ?? "";
+      continue;
+    }
+    if (fence && block != null) {
+      out.push(preBlock(block, lang));
+      block = null;
+      continue;
+    }
+    if (block != null) {
+      block.push(line);
+    } else {
+      out.push(inlineHtml(line));
+    }
+  }
+  if (block != null) {
+    out.push(preBlock(block, lang));
+  }
+  return out.join("\n");
+}
+
+function preBlock(lines: string[], lang: string) {
+  const body = escapeHtml(lines.join("\n"));
+  return lang ? `<pre><code class="language-${escapeHtml(lang)}">${body}</code></pre>` : `<pre>${body}</pre>`;
+}
+
+// Notion API limits: 2000 characters per rich text object, 100 rich text objects per block.
+const NOTION_TEXT_MAX = 2000;
+const NOTION_RICH_TEXT_MAX = 100;
+// Fence languages Notion accepts as is; anything else is shown as "plain text".
+const NOTION_CODE_LANGUAGES = new Set(
+  "bash c c++ c# css diff go graphql html java javascript json kotlin markdown php python ruby rust shell sql swift typescript xml yaml".split(" "),
+);
+const NOTION_LANGUAGE_ALIASES: Record<string, string> = { js: "javascript", ts: "typescript", py: "python", sh: "shell", yml: "yaml", md: "markdown" };
+const INLINE_RE = /(\*\*[^*\n]+\*\*|`[^`\n]+`|\[[^\]\n]+\]\(https?:\/\/[^)\s]+\))/;
+
+export type NotionRichText = {
+  type: "text";
+  text: { content: string; link?: { url: string } };
+  annotations?: { bold?: boolean; code?: boolean };
+};
+export type NotionBlock = { object: "block"; type: string } & Record<string, unknown>;
+
+function notionText(content: string, url?: string, annotations?: NotionRichText["annotations"]) {
+  const out: NotionRichText[] = [];
+  for (let i = 0; i < content.length; i += NOTION_TEXT_MAX) {
+    out.push({
+      type: "text",
+      text: { content: content.slice(i, i + NOTION_TEXT_MAX), ...(url ? { link: { url } } : {}) },
+      ...(annotations ? { annotations } : {}),
+    });
+  }
+  return out;
+}
+
+// **bold**, `code` and [links](https://...) become annotated rich text; everything else is plain.
+function inlineRichText(text: string) {
+  const out: NotionRichText[] = [];
+  for (const token of text.split(INLINE_RE)) {
+    if (!token) continue;
+    const link = /^\[([^\]]+)\]\((\S+)\)$/.exec(token);
+    if (link) {
+      out.push(...notionText(link[1], link[2]));
+    } else if (/^\*\*[^*]+\*\*$/.test(token)) {
+      out.push(...notionText(token.slice(2, -2), undefined, { bold: true }));
+    } else if (/^`[^`]+`$/.test(token)) {
+      out.push(...notionText(token.slice(1, -1), undefined, { code: true }));
+    } else {
+      out.push(...notionText(token));
+    }
+  }
+  return out.slice(0, NOTION_RICH_TEXT_MAX);
+}
+
+function notionBlock(type: string, content: Record<string, unknown>): NotionBlock {
+  return { object: "block", type, [type]: content };
+}
+
+// Converts a Markdown output to Notion blocks: headings, bullet and numbered list items, quotes, dividers, fenced
+// code and paragraphs. Tables are kept as fixed-width code blocks since Notion table blocks need a fixed width.
+export function toNotionBlocks(markdown: string): NotionBlock[] {
+  const blocks: NotionBlock[] = [];
+  let paragraph: string[] = [];
+  let code: string[] | null = null;
+  let lang = "";
+  const flush = () => {
+    if (paragraph.length) {
+      blocks.push(notionBlock("paragraph", { rich_text: inlineRichText(paragraph.join("\n")) }));
+      paragraph = [];
+    }
+  };
+
+  for (const line of tablesToCodeBlocks(markdown).split("\n")) {
+    const fence = FENCE_RE.exec(line);
+    if (code != null) {
+      if (fence) {
+        const language = NOTION_LANGUAGE_ALIASES[lang] ?? lang;
+        blocks.push(
+          notionBlock("code", {
+            rich_text: notionText(code.join("\n")).slice(0, NOTION_RICH_TEXT_MAX),
+            language: NOTION_CODE_LANGUAGES.has(language) ? language : "plain text",
+          }),
+        );
+        code = null;
+      } else {
+        code.push(line);
+      }
+      continue;
+    }
+    if (fence) {
+      flush();
+      code = [];
+      lang = (fence[1] ?? "").toLowerCase();
+      continue;
+    }
+
+    const heading = /^(#{1,6})\s+(.*)$/.exec(line);
+    const bullet = /^\s*[-*+]\s+(.*)$/.exec(line);
+    const numbered = /^\s*\d+[.)]\s+(.*)$/.exec(line);
+    const quote = /^>\s?(.*)$/.exec(line);
+    if (!line.trim()) {
+      flush();
+    } else if (/^\s*(-{3,}|\*{3,}|_{3,})\s*$/.test(line)) {
+      flush();
+      blocks.push(notionBlock("divider", {}));
+    } else if (heading) {
+      flush();
+      const level = Math.min(heading[1].length, 3);
+      blocks.push(notionBlock(`heading_${level}`, { rich_text: inlineRichText(heading[2]) }));
+    } else if (bullet) {
+      flush();
+      blocks.push(notionBlock("bulleted_list_item", { rich_text: inlineRichText(bullet[1]) }));
+    } else if (numbered) {
+      flush();
+      blocks.push(notionBlock("numbered_list_item", { rich_text: inlineRichText(numbered[1]) }));
+    } else if (quote) {
+      flush();
+      blocks.push(notionBlock("quote", { rich_text: inlineRichText(quote[1]) }));
+    } else {
+      paragraph.push(line);
+    }
+  }
+  flush();
+  if (code != null) {
+    blocks.push(notionBlock("code", { rich_text: notionText(code.join("\n")).slice(0, NOTION_RICH_TEXT_MAX), language: "plain text" }));
+  }
+  return blocks;
+}
+
+function inlineJiraWiki(line: string) {
+  return line
+    .replace(/\[([^\]\n]+)\]\((https?:\/\/[^)\s]+)\)/g, "[$1|$2]")
+    .replace(/`([^`\n]+)`/g, "{{$1}}")
+    .replace(/\*\*([^*\n]+)\*\*/g, "*$1*");
+}
+
+// Converts a Markdown output to Jira wiki markup (REST API v2 descriptions): headings, bullet and numbered lists,
+// bold, inline code, links and fenced code. Tables become code blocks like in chat channels.
+export function toJiraWiki(markdown: string) {
+  const out: string[] = [];
+  let fenced = false;
+  for (const line of tablesToCodeBlocks(markdown).split("\n")) {
+    const fence = FENCE_RE.exec(line);
+    if (fence) {
+      out.push(fenced ? "{code}" : fence[1] ? `{code:${fence[1]}}` : "{code}");
+      fenced = !fenced;
+      continue;
+    }
+    if (fenced) {
+      out.push(line);
+      continue;
+    }
+    const heading = /^(#{1,6})\s+(.*)$/.exec(line);
+    const bullet = /^(\s*)[-*+]\s+(.*)$/.exec(line);
+    const numbered = /^(\s*)\d+[.)]\s+(.*)$/.exec(line);
+    if (heading) {
+      out.push(`h${heading[1].length}. ${inlineJiraWiki(heading[2])}`);
+    } else if (bullet) {
+      out.push(`${"*".repeat(Math.floor(bullet[1].length / 2) + 1)} ${inlineJiraWiki(bullet[2])}`);
+    } else if (numbered) {
+      out.push(`${"#".repeat(Math.floor(numbered[1].length / 2) + 1)} ${inlineJiraWiki(numbered[2])}`);
+    } else {
+      out.push(inlineJiraWiki(line));
+    }
+  }
+  if (fenced) {
+    out.push("{code}");
+  }
+  return out.join("\n");
+}
@@ -0,0 +1,22 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { modelAliases, resolveModelAlias } from "./model-aliases";
+
+describe("model aliases", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("reads the alias map and ignores invalid entries", () => {
+    vi.stubEnv("LLM_MODEL_ALIASES", '{" gpt-5-mini ": "gpt-5.1-mini", "gpt-4o": 4, "old": ""}');
+    expect(modelAliases()).toEqual({ "gpt-5-mini": "gpt-5.1-mini" });
+    vi.stubEnv("LLM_MODEL_ALIASES", "not json");
+    expect(modelAliases()).toEqual({});
+  });
+
+  it("follows alias chains and stops at cycles", () => {
+    const aliases = { a: "b", b: "c", x: "y", y: "x" };
+    expect(resolveModelAlias("a", aliases)).toBe("c");
+    expect(resolveModelAlias("x", aliases)).toBe("y");
+    expect(resolveModelAlias("gpt-5", aliases)).toBe("gpt-5");
+  });
+});
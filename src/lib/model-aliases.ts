@@ -0,0 +1,39 @@
+import { isRecord } from "@/lib/type-guards";
+
+// When a provider retires a model, operators remap it once (LLM_MODEL_ALIASES='{"gpt-5-mini": "gpt-5.1-mini"}')
+// instead of every job on it failing with a 404. Jobs keep their configured model, so removing the alias
+// restores it; runs record the model that actually answered.
+
+const MAX_ALIAS_HOPS = 10;
+
+export function modelAliases(): Record<string, string> {
+  try {
+    const parsed = JSON.parse(process.env.LLM_MODEL_ALIASES?.trim() || "{}") as unknown;
+    if (!isRecord(parsed)) {
+      return {};
+    }
+    return Object.fromEntries(
+      Object.entries(parsed)
+        .filter((entry): entry is [string, string] => typeof entry[1] === "string" && entry[1].trim() !== "")
+        .map(([from, to]) => [from.trim(), to.trim()]),
+    );
+  } catch {
+    return {};
+  }
+}
+
+// Follows chains (a -> b -> c) so a model retired twice needs no edit to the first alias; a cycle stops at the
+// last model before it repeats.
+export function resolveModelAlias(model: string, aliases = modelAliases()) {
+  let resolved = model.trim();
+  const seen = new Set([resolved]);
+  for (let hop = 0; hop < MAX_ALIAS_HOPS; hop++) {
+    const next = aliases[resolved];
+    if (!next || seen.has(next)) {
+      break;
+    }
+    seen.add(next);
+    resolved = next;
+  }
+  return resolved;
+}
@@ -0,0 +1,31 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { deploymentFallbackModels, nextModelIndex, withFallbackModels } from "./model-fallback";
+
+describe("model fallback", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("appends the job's fallbacks, or the deployment default, after the candidates", () => {
+    expect(withFallbackModels(["gpt-5-mini"], ["gpt-4.1-mini", "gpt-5-mini", "gpt-4.1-mini"], ["gpt-4o-mini"])).toEqual({
+      models: ["gpt-5-mini", "gpt-4.1-mini"],
+      fallbackStart: 1,
+    });
+    expect(withFallbackModels(["gpt-5-nano", "gpt-5-mini"], [], ["gpt-4o-mini"])).toEqual({
+      models: ["gpt-5-nano", "gpt-5-mini", "gpt-4o-mini"],
+      fallbackStart: 2,
+    });
+
+    vi.stubEnv("LLM_FALLBACK_MODELS", " gpt-4.1-mini, ,openrouter/anthropic/claude-3.5-haiku,a,b");
+    expect(deploymentFallbackModels()).toEqual(["gpt-4.1-mini", "openrouter/anthropic/claude-3.5-haiku", "a"]);
+  });
+
+  it("skips routed upgrades on provider failures but not on other errors", () => {
+    // candidates: nano, mini (routed upgrade), haiku (fallback)
+    expect(nextModelIndex(0, 2, 3, false)).toBe(1);
+    expect(nextModelIndex(0, 2, 3, true)).toBe(2);
+    expect(nextModelIndex(2, 2, 3, false)).toBeNull();
+    // Without fallbacks a provider failure ends the run, as before.
+    expect(nextModelIndex(0, 2, 2, true)).toBeNull();
+  });
+});
@@ -0,0 +1,29 @@
+// Fallback models are tried in order after a job's own model (and its routed upgrades) when it errors or returns
+// empty output, e.g. gpt-5-mini -> gpt-4.1-mini -> openrouter/anthropic/claude-3.5-haiku. A job's list replaces the
+// deployment default (LLM_FALLBACK_MODELS).
+
+export const MODEL_FALLBACKS_MAX = 3;
+
+export function deploymentFallbackModels() {
+  return (process.env.LLM_FALLBACK_MODELS ?? "")
+    .split(",")
+    .map((model) => model.trim())
+    .filter(Boolean)
+    .slice(0, MODEL_FALLBACKS_MAX);
+}
+
+// The models to try and the index of the first fallback (models.length when there are none). Fallbacks already
+// among the candidates are not tried twice.
+export function withFallbackModels(candidates: string[], jobFallbacks: string[], defaults = deploymentFallbackModels()) {
+  const fallbacks = (jobFallbacks.length ? jobFallbacks : defaults).filter(
+    (model, index, list) => !candidates.includes(model) && list.indexOf(model) === index,
+  );
+  return { models: [...candidates, ...fallbacks], fallbackStart: candidates.length };
+}
+
+// Routed upgrades help with prompts a cheap model cannot handle, not with outages, so a provider failure (429,
+// 5xx, timeout) skips straight to the fallbacks, which may be served by another provider. null: no model left.
+export function nextModelIndex(index: number, fallbackStart: number, modelCount: number, providerFailure: boolean) {
+  const next = providerFailure ? Math.max(index + 1, fallbackStart) : index + 1;
+  return next < modelCount ? next : null;
+}
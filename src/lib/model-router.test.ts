@@ -0,0 +1,33 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { isAutoModel, resolvePreviewModel, routeModels } from "./model-router";
+
+const config = { ladder: ["gpt-5-nano", "gpt-5-mini", "gpt-5"], longPromptChars: 1000, tagFloors: { research: "gpt-5" } };
+
+describe("model router", () => {
+  it("starts at the cheapest model and keeps the rest as upgrades", () => {
+    expect(routeModels({ promptChars: 200, tags: [] }, config)).toEqual(["gpt-5-nano", "gpt-5-mini", "gpt-5"]);
+  });
+
+  it("skips the cheapest model for long prompts and honours tag floors", () => {
+    expect(routeModels({ promptChars: 5000, tags: [] }, config)).toEqual(["gpt-5-mini", "gpt-5"]);
+    expect(routeModels({ promptChars: 200, tags: ["digest", "research"] }, config)).toEqual(["gpt-5"]);
+  });
+
+  it("skips models that recently needed an upgrade for this job", () => {
+    const failing = Array.from({ length: 3 }, () => ({ routedModel: "gpt-5-nano", succeeded: false }));
+    expect(routeModels({ promptChars: 200, tags: [], history: failing }, config)).toEqual(["gpt-5-mini", "gpt-5"]);
+
+    const mixed = [...failing, ...Array.from({ length: 3 }, () => ({ routedModel: "gpt-5-nano", succeeded: true }))];
+    expect(routeModels({ promptChars: 200, tags: [], history: mixed }, config)[0]).toBe("gpt-5-nano");
+    expect(routeModels({ promptChars: 200, tags: [], history: failing.slice(0, 2) }, config)[0]).toBe("gpt-5-nano");
+  });
+
+  it("only routes the auto model", () => {
+    expect(isAutoModel(" Auto ")).toBe(true);
+    expect(resolvePreviewModel("gpt-5", "hi")).toBe("gpt-5");
+    expect(resolvePreviewModel("auto", "hi")).toBe("gpt-5-nano");
+  });
+});
@@ -0,0 +1,102 @@
+import { prisma } from "@/lib/prisma";
+import { isRecord } from "@/lib/type-guards";
+
+// Jobs whose model is "auto" are routed to the cheapest model that is expected to handle them.
+export const AUTO_LLM_MODEL = "auto";
+
+const DEFAULT_LADDER = ["gpt-5-nano", "gpt-5-mini", "gpt-5"];
+const DEFAULT_LONG_PROMPT_CHARS = 8000;
+const HISTORY_RUNS = 10;
+const HISTORY_MIN_SAMPLES = 3;
+const HISTORY_MIN_SUCCESS_RATE = 0.5;
+
+export type RoutingConfig = {
+  // Allowed models, cheapest first.
+  ladder: string[];
+  longPromptChars: number;
+  // Minimum model per job tag, e.g. {"research": "gpt-5"}.
+  tagFloors: Record<string, string>;
+};
+
+export type RoutingHistory = Array<{ routedModel: string; succeeded: boolean }>;
+
+export function isAutoModel(model: string | null | undefined) {
+  return (model ?? "").trim().toLowerCase() === AUTO_LLM_MODEL;
+}
+
+export function routingConfig(): RoutingConfig {
+  const ladder = (process.env.LLM_ROUTING_MODELS ?? "")
+    .split(",")
+    .map((model) => model.trim())
+    .filter(Boolean);
+  const longPromptChars = Number(process.env.LLM_ROUTING_LONG_PROMPT_CHARS ?? DEFAULT_LONG_PROMPT_CHARS);
+
+  let tagFloors: Record<string, string> = {};
+  try {
+    const parsed = JSON.parse(process.env.LLM_ROUTING_TAG_MODELS?.trim() || "{}") as unknown;
+    if (isRecord(parsed)) {
+      tagFloors = Object.fromEntries(
+        Object.entries(parsed).filter((entry): entry is [string, string] => typeof entry[1] === "string"),
+      );
+    }
+  } catch {
+    tagFloors = {};
+  }
+
+  return {
+    ladder: ladder.length ? ladder : DEFAULT_LADDER,
+    longPromptChars: Number.isFinite(longPromptChars) && longPromptChars > 0 ? longPromptChars : DEFAULT_LONG_PROMPT_CHARS,
+    tagFloors,
+  };
+}
+
+// Returns the models to try in order: the chosen starting model, then every more capable one as an upgrade path.
+// Long prompts skip the cheapest model, a tag floor skips everything below it, and a model whose recent runs
+// for this job mostly needed an upgrade is skipped too.
+export function routeModels(input: { promptChars: number; tags: string[]; history?: RoutingHistory }, config = routingConfig()) {
+  const { ladder } = config;
+  let start = 0;
+
+  if (input.promptChars > config.longPromptChars) {
+    start = Math.min(1, ladder.length - 1);
+  }
+  for (const tag of input.tags) {
+    const floor = config.tagFloors[tag];
+    const index = floor ? ladder.indexOf(floor) : -1;
+    if (index > start) {
+      start = index;
+    }
+  }
+
+  const history = input.history ?? [];
+  while (start < ladder.length - 1) {
+    const samples = history.filter((run) => run.routedModel === ladder[start]);
+    const successes = samples.filter((run) => run.succeeded).length;
+    if (samples.length < HISTORY_MIN_SAMPLES || successes / samples.length >= HISTORY_MIN_SUCCESS_RATE) {
+      break;
+    }
+    start++;
+  }
+
+  return ladder.slice(start);
+}
+
+// A routed run succeeded when its first model produced the output without an upgrade.
+async function loadRoutingHistory(jobId: string): Promise<RoutingHistory> {
+  const runs = await prisma.runHistory.findMany({
+    where: { jobId, isPreview: false, routedModel: { not: null } },
+    orderBy: { runAt: "desc" },
+    take: HISTORY_RUNS,
+    select: { routedModel: true, modelUpgraded: true, llmModel: true },
+  });
+  return runs.map((run) => ({ routedModel: run.routedModel ?? "", succeeded: !run.modelUpgraded && run.llmModel != null }));
+}
+
+export async function routeJobModels(job: { id: string; tags: string[] }, prompt: string) {
+  return routeModels({ promptChars: prompt.length, tags: job.tags, history: await loadRoutingHistory(job.id) });
+}
+
+// Previews have no upgrade loop: "auto" resolves to the starting model a scheduled run would use (without history).
+export function resolvePreviewModel(model: string, prompt: string, tags: string[] = []) {
+  return isAutoModel(model) ? routeModels({ promptChars: prompt.length, tags })[0] : model;
+}
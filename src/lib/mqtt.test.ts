@@ -0,0 +1,91 @@
+import { createServer, type AddressInfo, type Server } from "node:net";
+import { afterEach, describe, expect, it } from "vitest";
+import { __private__, mqttPublish } from "./mqtt";
+
+let server: Server | null = null;
+
+afterEach(() => {
+  server?.close();
+  server = null;
+});
+
+type Seen = { type: number; qos?: number; retain?: boolean; topic?: string; payload?: string };
+
+// Fake broker: answers CONNECT with the given return code and acknowledges publishes for their QoS.
+function fakeBroker(returnCode = 0) {
+  const seen: Seen[] = [];
+  const disconnected = new Promise<void>((resolve) => {
+    server = createServer((socket) => {
+      let buffer = Buffer.alloc(0);
+      socket.on("data", (chunk: Buffer) => {
+        buffer = Buffer.concat([buffer, chunk]);
+        while (buffer.length >= 2) {
+          let length = 0;
+          let index = 1;
+          for (let multiplier = 1; ; index++, multiplier *= 128) {
+            length += (buffer[index] & 0x7f) * multiplier;
+            if (!(buffer[index] & 0x80)) break;
+          }
+          if (buffer.length < index + 1 + length) return;
+          const header = buffer[0];
+          const body = buffer.subarray(index + 1, index + 1 + length);
+          buffer = buffer.subarray(index + 1 + length);
+          const type = header >> 4;
+          if (type === 1) {
+            socket.write(Buffer.from([0x20, 2, 0, returnCode]));
+            continue;
+          } else if (type === 3) {
+            const qos = (header >> 1) & 3;
+            const topicLength = body.readUInt16BE(0);
+            const topic = body.subarray(2, 2 + topicLength).toString();
+            const payload = body.subarray(2 + topicLength + (qos ? 2 : 0)).toString();
+            seen.push({ type, qos, retain: !!(header & 1), topic, payload });
+            if (qos) socket.write(Buffer.from([qos === 1 ? 0x40 : 0x50, 2, 0, 1]));
+            continue;
+          } else if (type === 6) {
+            socket.write(Buffer.from([0x70, 2, 0, 1]));
+          } else if (type === 14) {
+            socket.end();
+            resolve();
+          }
+          seen.push({ type });
+        }
+      });
+    });
+  });
+  return new Promise<{ url: string; seen: Seen[]; disconnected: Promise<void> }>((resolve) =>
+    server!.listen(0, "127.0.0.1", () =>
+      resolve({ url: `mqtt://127.0.0.1:${(server!.address() as AddressInfo).port}`, seen, disconnected }),
+    ),
+  );
+}
+
+describe("mqtt publish", () => {
+  it("encodes remaining lengths and credentials in CONNECT", () => {
+    expect(__private__.remainingLength(127).toString("hex")).toBe("7f");
+    expect(__private__.remainingLength(321).toString("hex")).toBe("c102");
+    const connect = __private__.connectPacket("client", "user", "pass");
+    // Protocol name, level 4, flags: user name, password, clean session.
+    expect(connect.subarray(2, 10).toString("hex")).toBe("00044d51545404c2");
+    expect(connect.subarray(-6).toString()).toBe("\u0000\u0004pass");
+  });
+
+  it("publishes at QoS 0, 1 and 2 and disconnects cleanly", async () => {
+    for (const qos of [0, 1, 2] as const) {
+      const broker = await fakeBroker();
+      await mqttPublish({ url: broker.url, topic: "home/briefing", payload: "héllo", qos, retain: qos === 1 });
+      await broker.disconnected;
+      expect(broker.seen[0]).toEqual({ type: 3, qos, retain: qos === 1, topic: "home/briefing", payload: "héllo" });
+      expect(broker.seen.map((packet) => packet.type)).toEqual(qos === 2 ? [3, 6, 14] : [3, 14]);
+      server?.close();
+    }
+  });
+
+  it("maps refused connections to statuses", async () => {
+    const broker = await fakeBroker(4);
+    await expect(mqttPublish({ url: broker.url, topic: "t", payload: "x", qos: 1, username: "u", password: "bad" })).rejects.toMatchObject({
+      message: "MQTT connection refused: bad user name or password",
+      status: 401,
+    });
+  });
+});
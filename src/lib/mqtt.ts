@@ -0,0 +1,229 @@
+import { connect as netConnect, type Socket } from "node:net";
+import { connect as tlsConnect } from "node:tls";
+import { randomUUID } from "node:crypto";
+
+// Minimal MQTT 3.1.1 publisher for the mqtt channel: one connection per delivery (CONNECT, PUBLISH, DISCONNECT).
+// QoS 1 waits for PUBACK and QoS 2 completes the PUBREC/PUBREL/PUBCOMP exchange, so the delivery only succeeds once
+// the broker has taken the message.
+
+const MQTT_PORT = 1883;
+const MQTTS_PORT = 8883;
+const MQTT_TIMEOUT_MS = 10_000;
+const KEEPALIVE_SECONDS = 30;
+const PACKET_ID = 1;
+
+const CONNECT = 0x10;
+const CONNACK = 0x20;
+const PUBLISH = 0x30;
+const PUBACK = 0x40;
+const PUBREC = 0x50;
+const PUBREL = 0x62;
+const PUBCOMP = 0x70;
+const DISCONNECT = 0xe0;
+
+export type MqttQos = 0 | 1 | 2;
+
+export type MqttPublishInput = {
+  // mqtt://host:port, or mqtts://host:port for TLS.
+  url: string;
+  topic: string;
+  payload: string;
+  qos: MqttQos;
+  retain?: boolean;
+  username?: string;
+  password?: string;
+  timeoutMs?: number;
+};
+
+// status: 401 for refused credentials, 400 for a refused client; errors without a status (network, timeout,
+// broker unavailable) are worth retrying.
+export class MqttError extends Error {
+  status?: number;
+
+  constructor(message: string, status?: number) {
+    super(message);
+    this.name = "MqttError";
+    this.status = status;
+  }
+}
+
+const CONNACK_ERRORS: Record<number, [string, number | undefined]> = {
+  1: ["unacceptable protocol version", 400],
+  2: ["client identifier rejected", 400],
+  3: ["server unavailable", undefined],
+  4: ["bad user name or password", 401],
+  5: ["not authorized", 401],
+};
+
+function mqttString(value: string) {
+  const bytes = Buffer.from(value, "utf8");
+  const length = Buffer.alloc(2);
+  length.writeUInt16BE(bytes.length);
+  return Buffer.concat([length, bytes]);
+}
+
+function remainingLength(length: number) {
+  const bytes: number[] = [];
+  do {
+    let byte = length % 128;
+    length = Math.floor(length / 128);
+    if (length > 0) byte |= 0x80;
+    bytes.push(byte);
+  } while (length > 0);
+  return Buffer.from(bytes);
+}
+
+function packet(header: number, ...parts: Buffer[]) {
+  const body = Buffer.concat(parts);
+  return Buffer.concat([Buffer.from([header]), remainingLength(body.length), body]);
+}
+
+function packetId(id: number) {
+  const buf = Buffer.alloc(2);
+  buf.writeUInt16BE(id);
+  return buf;
+}
+
+function connectPacket(clientId: string, username: string, password: string) {
+  let flags = 0x02; // clean session
+  if (username) flags |= 0x80;
+  if (username && password) flags |= 0x40;
+  const keepalive = Buffer.alloc(2);
+  keepalive.writeUInt16BE(KEEPALIVE_SECONDS);
+  return packet(
+    CONNECT,
+    mqttString("MQTT"),
+    Buffer.from([4, flags]),
+    keepalive,
+    mqttString(clientId),
+    ...(username ? [mqttString(username)] : []),
+    ...(username && password ? [mqttString(password)] : []),
+  );
+}
+
+function publishPacket(topic: string, payload: Buffer, qos: MqttQos, retain: boolean) {
+  return packet(PUBLISH | (qos << 1) | (retain ? 1 : 0), mqttString(topic), ...(qos > 0 ? [packetId(PACKET_ID)] : []), payload);
+}
+
+// Splits the incoming stream into control packets.
+class PacketReader {
+  private buffer = Buffer.alloc(0);
+  private waiters: Array<() => void> = [];
+  private failure: Error | null = null;
+
+  push(chunk: Buffer) {
+    this.buffer = Buffer.concat([this.buffer, chunk]);
+    this.wake();
+  }
+
+  fail(err: Error) {
+    this.failure ??= err;
+    this.wake();
+  }
+
+  private wake() {
+    const waiters = this.waiters;
+    this.waiters = [];
+    waiters.forEach((resolve) => resolve());
+  }
+
+  private take(): { type: number; body: Buffer } | null {
+    let length = 0;
+    let multiplier = 1;
+    for (let i = 1; i <= 4 && i < this.buffer.length; i++) {
+      const byte = this.buffer[i];
+      length += (byte & 0x7f) * multiplier;
+      multiplier *= 128;
+      if (!(byte & 0x80)) {
+        if (this.buffer.length < i + 1 + length) return null;
+        const body = this.buffer.subarray(i + 1, i + 1 + length);
+        const type = this.buffer[0] & 0xf0;
+        this.buffer = this.buffer.subarray(i + 1 + length);
+        return { type, body };
+      }
+    }
+    return null;
+  }
+
+  async next() {
+    while (true) {
+      const value = this.take();
+      if (value) return value;
+      if (this.failure) throw this.failure;
+      await new Promise<void>((resolve) => this.waiters.push(resolve));
+    }
+  }
+}
+
+function openSocket(url: URL, timeoutMs: number) {
+  const host = url.hostname.replace(/^\[|\]$/g, "");
+  const secure = url.protocol === "mqtts:";
+  const port = Number(url.port) || (secure ? MQTTS_PORT : MQTT_PORT);
+  return new Promise<Socket>((resolve, reject) => {
+    const socket = secure ? tlsConnect({ host, port, servername: host }) : netConnect({ host, port });
+    const timer = setTimeout(() => {
+      socket.destroy();
+      reject(new MqttError(`MQTT connect timed out after ${timeoutMs}ms`));
+    }, timeoutMs);
+    socket.once(secure ? "secureConnect" : "connect", () => {
+      clearTimeout(timer);
+      resolve(socket);
+    });
+    socket.once("error", (err) => {
+      clearTimeout(timer);
+      reject(err);
+    });
+  });
+}
+
+export async function mqttPublish(input: MqttPublishInput): Promise<void> {
+  const url = new URL(input.url);
+  const timeoutMs = input.timeoutMs ?? MQTT_TIMEOUT_MS;
+  const reader = new PacketReader();
+  const conn: { socket: Socket | null } = { socket: null };
+  const timer = setTimeout(() => {
+    reader.fail(new MqttError(`MQTT publish timed out after ${timeoutMs}ms`));
+    conn.socket?.destroy();
+  }, timeoutMs);
+
+  const awaitPacket = async (type: number, name: string) => {
+    const received = await reader.next();
+    if (received.type !== type) {
+      throw new MqttError(`MQTT broker sent packet type ${received.type >> 4} instead of ${name}`);
+    }
+    return received.body;
+  };
+
+  try {
+    const socket = await openSocket(url, timeoutMs);
+    conn.socket = socket;
+    socket.on("data", (chunk: Buffer) => reader.push(chunk));
+    socket.on("error", (err) => reader.fail(err));
+    socket.on("close", () => reader.fail(new MqttError("MQTT connection closed")));
+
+    const clientId = `promptloop-${randomUUID().replace(/-/g, "").slice(0, 12)}`;
+    socket.write(connectPacket(clientId, input.username ?? "", input.password ?? ""));
+    const connack = await awaitPacket(CONNACK, "CONNACK");
+    const code = connack[1] ?? 0;
+    if (code !== 0) {
+      const [reason, status] = CONNACK_ERRORS[code] ?? [`return code ${code}`, undefined];
+      throw new MqttError(`MQTT connection refused: ${reason}`, status);
+    }
+
+    socket.write(publishPacket(input.topic, Buffer.from(input.payload, "utf8"), input.qos, !!input.retain));
+    if (input.qos === 1) {
+      await awaitPacket(PUBACK, "PUBACK");
+    } else if (input.qos === 2) {
+      await awaitPacket(PUBREC, "PUBREC");
+      socket.write(packet(PUBREL, packetId(PACKET_ID)));
+      await awaitPacket(PUBCOMP, "PUBCOMP");
+    }
+    // QoS 0 has no acknowledgement; a clean DISCONNECT after the flushed write is as far as it goes.
+    await new Promise<void>((resolve) => socket.end(packet(DISCONNECT), () => resolve()));
+  } finally {
+    clearTimeout(timer);
+    conn.socket?.destroy();
+  }
+}
+
+export const __private__ = { connectPacket, publishPacket, remainingLength };
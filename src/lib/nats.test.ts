@@ -0,0 +1,77 @@
+import { createServer, type AddressInfo, type Server } from "node:net";
+import { afterEach, describe, expect, it } from "vitest";
+import { natsPublish } from "./nats";
+
+let server: Server | null = null;
+
+afterEach(() => {
+  server?.close();
+  server = null;
+});
+
+// Fake NATS server: sends INFO, records what the client writes and answers its PING (plus a JetStream reply if asked).
+function fakeNats(options: { headers?: boolean; reply?: "ack" | "no-stream"; error?: string } = {}) {
+  const received: string[] = [];
+  server = createServer((socket) => {
+    socket.write(`INFO ${JSON.stringify({ server_id: "test", max_payload: 1024, headers: !!options.headers })}\r\n`);
+    let buffer = "";
+    socket.on("data", (chunk) => {
+      buffer += chunk.toString();
+      received.push(chunk.toString());
+      if (!buffer.includes("PING\r\n")) return;
+      if (options.error) {
+        socket.write(`-ERR '${options.error}'\r\n`);
+        return;
+      }
+      const inbox = /PUB \S+ (\S+) \d+\r\n/.exec(buffer)?.[1];
+      if (inbox && options.reply === "ack") {
+        const ack = '{"stream":"RUNS","seq":1}';
+        socket.write(`MSG ${inbox} 1 ${ack.length}\r\n${ack}\r\n`);
+      }
+      if (inbox && options.reply === "no-stream") {
+        const headers = "NATS/1.0 503\r\n\r\n";
+        socket.write(`HMSG ${inbox} 1 ${headers.length} ${headers.length}\r\n${headers}\r\n`);
+      }
+      socket.write("PONG\r\n");
+      buffer = "";
+    });
+  });
+  return new Promise<{ url: string; received: string[] }>((resolve) =>
+    server!.listen(0, "127.0.0.1", () => resolve({ url: `nats://127.0.0.1:${(server!.address() as AddressInfo).port}`, received })),
+  );
+}
+
+describe("nats publish", () => {
+  it("connects, publishes and flushes with PING", async () => {
+    const nats = await fakeNats();
+    await natsPublish({ url: nats.url, subject: "reports.daily", payload: "héllo", username: "u", password: "p" });
+
+    const sent = nats.received.join("");
+    expect(sent).toMatch(/^CONNECT \{.*"user":"u","pass":"p"\}\r\n/);
+    expect(sent).toContain("PUB reports.daily 6\r\nhéllo\r\nPING\r\n");
+  });
+
+  it("waits for the JetStream ack and reports a missing stream", async () => {
+    const acked = await fakeNats({ reply: "ack" });
+    await natsPublish({ url: acked.url, subject: "reports.daily", payload: "x", jetstream: true });
+    expect(acked.received.join("")).toMatch(/SUB (_INBOX\.\w+) 1\r\nPUB reports\.daily \1 1\r\n/);
+    server?.close();
+
+    const missing = await fakeNats({ headers: true, reply: "no-stream" });
+    await expect(natsPublish({ url: missing.url, subject: "reports.daily", payload: "x", jetstream: true })).rejects.toMatchObject({
+      status: 404,
+    });
+  });
+
+  it("maps server errors and oversized payloads to statuses", async () => {
+    const denied = await fakeNats({ error: "Authorization Violation" });
+    await expect(natsPublish({ url: denied.url, subject: "a", payload: "x" })).rejects.toMatchObject({
+      message: "NATS error: Authorization Violation",
+      status: 401,
+    });
+    server?.close();
+
+    const small = await fakeNats();
+    await expect(natsPublish({ url: small.url, subject: "a", payload: "x".repeat(2000) })).rejects.toMatchObject({ status: 413 });
+  });
+});
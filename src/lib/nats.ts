@@ -0,0 +1,213 @@
+import { connect as netConnect, type Socket } from "node:net";
+import { connect as tlsConnect } from "node:tls";
+import { randomUUID } from "node:crypto";
+import packageJson from "../../package.json";
+
+// Minimal NATS publisher for the nats channel: one connection per delivery, speaking the text protocol directly
+// (INFO/CONNECT/PUB/PING). With JetStream the message is published with a reply inbox and the stream's ack is
+// awaited, so a delivery only succeeds once the message is persisted.
+
+const NATS_DEFAULT_PORT = 4222;
+const NATS_TIMEOUT_MS = 10_000;
+const DEFAULT_MAX_PAYLOAD = 1024 * 1024;
+
+export type NatsPublishInput = {
+  // nats://host:port, or tls://host:port to require TLS.
+  url: string;
+  subject: string;
+  payload: string;
+  username?: string;
+  password?: string;
+  token?: string;
+  jetstream?: boolean;
+  timeoutMs?: number;
+};
+
+// status: 401 auth rejected, 413 payload over the server's max_payload, 404 no JetStream stream for the subject.
+// Errors without a status (network, timeout, protocol) are worth retrying.
+export class NatsError extends Error {
+  status?: number;
+
+  constructor(message: string, status?: number) {
+    super(message);
+    this.name = "NatsError";
+    this.status = status;
+  }
+}
+
+// Reads CRLF-terminated control lines and the payload bytes that follow MSG/HMSG lines.
+class LineReader {
+  private buffer = Buffer.alloc(0);
+  private waiters: Array<() => void> = [];
+  private failure: Error | null = null;
+
+  push(chunk: Buffer) {
+    this.buffer = Buffer.concat([this.buffer, chunk]);
+    this.wake();
+  }
+
+  fail(err: Error) {
+    this.failure ??= err;
+    this.wake();
+  }
+
+  private wake() {
+    const waiters = this.waiters;
+    this.waiters = [];
+    waiters.forEach((resolve) => resolve());
+  }
+
+  private async until<T>(take: () => T | null): Promise<T> {
+    while (true) {
+      const value = take();
+      if (value != null) return value;
+      if (this.failure) throw this.failure;
+      await new Promise<void>((resolve) => this.waiters.push(resolve));
+    }
+  }
+
+  line() {
+    return this.until(() => {
+      const end = this.buffer.indexOf("\r\n");
+      if (end < 0) return null;
+      const line = this.buffer.subarray(0, end).toString("utf8");
+      this.buffer = this.buffer.subarray(end + 2);
+      return line;
+    });
+  }
+
+  bytes(length: number) {
+    return this.until(() => {
+      if (this.buffer.length < length + 2) return null;
+      const data = this.buffer.subarray(0, length);
+      this.buffer = this.buffer.subarray(length + 2);
+      return data;
+    });
+  }
+}
+
+function openSocket(host: string, port: number, timeoutMs: number) {
+  return new Promise<Socket>((resolve, reject) => {
+    const socket = netConnect({ host, port });
+    const timer = setTimeout(() => {
+      socket.destroy();
+      reject(new NatsError(`NATS connect timed out after ${timeoutMs}ms`));
+    }, timeoutMs);
+    socket.once("connect", () => {
+      clearTimeout(timer);
+      resolve(socket);
+    });
+    socket.once("error", (err) => {
+      clearTimeout(timer);
+      reject(err);
+    });
+  });
+}
+
+function upgradeTls(socket: Socket, host: string) {
+  return new Promise<Socket>((resolve, reject) => {
+    const secure = tlsConnect({ socket, servername: host });
+    secure.once("secureConnect", () => resolve(secure));
+    secure.once("error", reject);
+  });
+}
+
+function serverError(line: string) {
+  const message = line.replace(/^-ERR\s*/, "").replace(/^'|'$/g, "");
+  return new NatsError(`NATS error: ${message}`, /authoriz|authentic/i.test(message) ? 401 : /payload/i.test(message) ? 413 : undefined);
+}
+
+export async function natsPublish(input: NatsPublishInput): Promise<void> {
+  const url = new URL(input.url);
+  const host = url.hostname.replace(/^\[|\]$/g, "");
+  const port = Number(url.port) || NATS_DEFAULT_PORT;
+  const timeoutMs = input.timeoutMs ?? NATS_TIMEOUT_MS;
+  const reader = new LineReader();
+  const conn: { socket: Socket | null } = { socket: null };
+  const timer = setTimeout(() => {
+    reader.fail(new NatsError(`NATS publish timed out after ${timeoutMs}ms`));
+    conn.socket?.destroy();
+  }, timeoutMs);
+
+  const attach = (socket: Socket) => {
+    conn.socket = socket;
+    socket.on("data", (chunk: Buffer) => reader.push(chunk));
+    socket.on("error", (err) => reader.fail(err));
+    socket.on("close", () => reader.fail(new NatsError("NATS connection closed")));
+  };
+
+  try {
+    const plain = await openSocket(host, port, timeoutMs);
+    attach(plain);
+    let socket = plain;
+    const infoLine = await reader.line();
+    if (!infoLine.startsWith("INFO ")) {
+      throw new NatsError("NATS server did not send INFO");
+    }
+    const info = JSON.parse(infoLine.slice(5)) as { tls_required?: boolean; max_payload?: number; headers?: boolean };
+    if (url.protocol === "tls:" || info.tls_required) {
+      plain.removeAllListeners("data");
+      plain.removeAllListeners("close");
+      socket = await upgradeTls(plain, host);
+      attach(socket);
+    }
+
+    const payload = Buffer.from(input.payload, "utf8");
+    if (payload.length > (info.max_payload ?? DEFAULT_MAX_PAYLOAD)) {
+      throw new NatsError(`NATS message of ${payload.length} bytes exceeds the server's max_payload`, 413);
+    }
+
+    const connectOptions = {
+      verbose: false,
+      pedantic: false,
+      tls_required: url.protocol === "tls:" || !!info.tls_required,
+      name: "promptloop",
+      lang: "node",
+      version: packageJson.version,
+      protocol: 1,
+      // Headers let the server answer a JetStream publish without a stream with a 503 status instead of silence.
+      headers: !!info.headers,
+      no_responders: !!info.headers,
+      ...(input.token ? { auth_token: input.token } : {}),
+      ...(input.username ? { user: input.username, pass: input.password ?? "" } : {}),
+    };
+    const inbox = `_INBOX.${randomUUID().replace(/-/g, "")}`;
+    const writes = [`CONNECT ${JSON.stringify(connectOptions)}\r\n`];
+    if (input.jetstream) {
+      writes.push(`SUB ${inbox} 1\r\n`, `PUB ${input.subject} ${inbox} ${payload.length}\r\n`);
+    } else {
+      writes.push(`PUB ${input.subject} ${payload.length}\r\n`);
+    }
+    socket.write(Buffer.concat([Buffer.from(writes.join(""), "utf8"), payload, Buffer.from("\r\nPING\r\n", "utf8")]));
+
+    let flushed = false;
+    let acked = !input.jetstream;
+    while (!flushed || !acked) {
+      const line = await reader.line();
+      if (line.startsWith("-ERR")) {
+        throw serverError(line);
+      }
+      if (line === "PING") {
+        socket.write("PONG\r\n");
+      } else if (line === "PONG") {
+        flushed = true;
+      } else if (line.startsWith("MSG ") || line.startsWith("HMSG ")) {
+        const parts = line.split(" ");
+        const headerBytes = line.startsWith("HMSG ") ? Number(parts[parts.length - 2]) : 0;
+        const data = await reader.bytes(Number(parts[parts.length - 1]));
+        const headers = data.subarray(0, headerBytes).toString("utf8");
+        if (/^NATS\/1\.0 503/.test(headers)) {
+          throw new NatsError(`No JetStream stream captures subject ${input.subject}`, 404);
+        }
+        const ack = JSON.parse(data.subarray(headerBytes).toString("utf8") || "{}") as { error?: { description?: string } };
+        if (ack.error) {
+          throw new NatsError(`JetStream publish failed: ${ack.error.description ?? "unknown error"}`);
+        }
+        acked = true;
+      }
+    }
+  } finally {
+    clearTimeout(timer);
+    conn.socket?.destroy();
+  }
+}
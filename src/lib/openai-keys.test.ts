@@ -0,0 +1,57 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+import { keyOrder, openaiApiKeys, pooledFetch, type KeyPoolState } from "./openai-keys";
+
+function pool(): KeyPoolState {
+  return { next: 0, quarantinedUntil: new Map() };
+}
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("openai key pool", () => {
+  it("reads the pool from OPENAI_API_KEYS and OPENAI_API_KEY", () => {
+    vi.stubEnv("OPENAI_API_KEYS", " sk-a, sk-b ,,sk-a");
+    vi.stubEnv("OPENAI_API_KEY", "sk-c");
+    expect(openaiApiKeys()).toEqual(["sk-a", "sk-b", "sk-c"]);
+  });
+
+  it("rotates keys and puts quarantined ones last", () => {
+    const state = pool();
+    expect(keyOrder(["a", "b", "c"], state)).toEqual(["a", "b", "c"]);
+    expect(keyOrder(["a", "b", "c"], state)).toEqual(["b", "c", "a"]);
+
+    const now = Date.now();
+    state.quarantinedUntil.set("c", now + 60_000);
+    state.quarantinedUntil.set("a", now + 30_000);
+    expect(keyOrder(["a", "b", "c"], state, now)).toEqual(["b", "a", "c"]);
+  });
+
+  it("quarantines revoked and out-of-quota keys and retries with the next one", async () => {
+    const seen: string[] = [];
+    const baseFetch = vi.fn(async (_input: RequestInfo | URL, init?: RequestInit) => {
+      const key = new Headers(init?.headers).get("Authorization") ?? "";
+      seen.push(key);
+      if (key === "Bearer sk-revoked") {
+        return new Response("{}", { status: 401 });
+      }
+      if (key === "Bearer sk-empty") {
+        return new Response(JSON.stringify({ error: { code: "insufficient_quota" } }), { status: 429 });
+      }
+      return new Response("ok", { status: 200 });
+    });
+    const state = pool();
+    const send = pooledFetch(["sk-revoked", "sk-empty", "sk-good"], state, baseFetch as typeof fetch);
+
+    const res = await send("https://api.openai.com/v1/responses", { method: "POST", body: "{}" });
+    expect(res.status).toBe(200);
+    expect(seen).toEqual(["Bearer sk-revoked", "Bearer sk-empty", "Bearer sk-good"]);
+    expect([...state.quarantinedUntil.keys()]).toEqual(["sk-revoked", "sk-empty"]);
+
+    // Plain rate limits are passed through without quarantining the key.
+    baseFetch.mockResolvedValueOnce(new Response(JSON.stringify({ error: { code: "rate_limit_exceeded" } }), { status: 429 }));
+    expect((await send("https://api.openai.com/v1/responses", { method: "POST", body: "{}" })).status).toBe(429);
+    expect(state.quarantinedUntil.has("sk-good")).toBe(false);
+  });
+});
@@ -0,0 +1,87 @@
+import { createOpenAI, openai } from "@ai-sdk/openai";
+import { logger } from "@/lib/logger";
+
+// OpenAI key pool for high-volume deployments: OPENAI_API_KEYS holds several comma-separated keys (OPENAI_API_KEY,
+// when set, joins the pool). Requests take the keys in turn. A key answered with 401 (revoked or invalid) or a
+// 429 insufficient_quota error is quarantined for OPENAI_KEY_QUARANTINE_MINUTES (default: 60) and the request is
+// sent again with the next key, so one bad key does not fail runs. Ordinary rate limits (429 without the quota
+// code) are returned as usual. The rotation and quarantine are per process. With a single key the stock provider
+// is used unchanged.
+
+export type KeyPoolState = { next: number; quarantinedUntil: Map<string, number> };
+
+const state: KeyPoolState = { next: 0, quarantinedUntil: new Map() };
+
+export function openaiApiKeys() {
+  const raw = [process.env.OPENAI_API_KEYS ?? "", process.env.OPENAI_API_KEY ?? ""].join(",");
+  return Array.from(new Set(raw.split(",").map((key) => key.trim()).filter(Boolean)));
+}
+
+function quarantineMs() {
+  const minutes = Number(process.env.OPENAI_KEY_QUARANTINE_MINUTES ?? 60);
+  return (Number.isFinite(minutes) && minutes >= 1 ? minutes : 60) * 60_000;
+}
+
+// Keys to try for one request, starting at the round-robin position: usable keys first, then quarantined ones
+// soonest released first (better a likely failure than no attempt when the whole pool is quarantined).
+export function keyOrder(keys: string[], pool: KeyPoolState = state, now = Date.now()) {
+  const start = pool.next % keys.length;
+  pool.next = (start + 1) % keys.length;
+  const rotated = [...keys.slice(start), ...keys.slice(0, start)];
+  const usable = rotated.filter((key) => (pool.quarantinedUntil.get(key) ?? 0) <= now);
+  const quarantined = rotated
+    .filter((key) => (pool.quarantinedUntil.get(key) ?? 0) > now)
+    .sort((a, b) => (pool.quarantinedUntil.get(a) ?? 0) - (pool.quarantinedUntil.get(b) ?? 0));
+  return [...usable, ...quarantined];
+}
+
+// Last characters only, so logs can tell keys apart without exposing them.
+function keyLabel(key: string) {
+  return `...${key.slice(-4)}`;
+}
+
+async function quarantineReason(res: Response) {
+  if (res.status === 401) {
+    return "unauthorized";
+  }
+  if (res.status === 429) {
+    const body = await res.clone().text().catch(() => "");
+    return body.includes("insufficient_quota") ? "insufficient_quota" : null;
+  }
+  return null;
+}
+
+export function pooledFetch(keys: string[], pool: KeyPoolState = state, baseFetch: typeof fetch = fetch): typeof fetch {
+  return async (input, init) => {
+    const order = keyOrder(keys, pool);
+    let res: Response | null = null;
+    for (const key of order) {
+      const headers = new Headers(init?.headers);
+      headers.set("Authorization", `Bearer ${key}`);
+      res = await baseFetch(input, { ...init, headers });
+      const reason = await quarantineReason(res);
+      if (!reason) {
+        pool.quarantinedUntil.delete(key);
+        return res;
+      }
+      pool.quarantinedUntil.set(key, Date.now() + quarantineMs());
+      logger.warn("openai key quarantined", { key: keyLabel(key), reason, status_code: res.status, pool_size: keys.length });
+    }
+    return res as Response;
+  };
+}
+
+let pooled: { keys: string; provider: ReturnType<typeof createOpenAI> } | null = null;
+
+// The provider job runs and the assistant use: the stock one for a single OPENAI_API_KEY, a rotating one otherwise.
+export function openaiProvider() {
+  const keys = openaiApiKeys();
+  if (!keys.length || (keys.length === 1 && process.env.OPENAI_API_KEY?.trim() === keys[0])) {
+    return openai;
+  }
+  const id = keys.join(",");
+  if (pooled?.keys !== id) {
+    pooled = { keys: id, provider: createOpenAI({ apiKey: keys[0], fetch: pooledFetch(keys) }) };
+  }
+  return pooled.provider;
+}
@@ -0,0 +1,28 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { isOpenRouterModel, openRouterModelId, openRouterProviderPreferences, withProviderPreferences } from "./openrouter";
+
+describe("openrouter", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("recognizes prefixed model ids", () => {
+    expect(isOpenRouterModel("openrouter/anthropic/claude-3.5-haiku")).toBe(true);
+    expect(isOpenRouterModel("gpt-5-mini")).toBe(false);
+    expect(openRouterModelId(" openrouter/auto ")).toBe("auto");
+  });
+
+  it("passes provider preferences through in the request body", () => {
+    vi.stubEnv("OPENROUTER_PROVIDER_PREFERENCES", '{"order":["anthropic"],"allow_fallbacks":false}');
+    const preferences = openRouterProviderPreferences();
+    expect(JSON.parse(withProviderPreferences('{"model":"auto","messages":[]}', preferences) as string)).toEqual({
+      model: "auto",
+      messages: [],
+      provider: { order: ["anthropic"], allow_fallbacks: false },
+    });
+    expect(withProviderPreferences('{"model":"auto"}', null)).toBe('{"model":"auto"}');
+
+    vi.stubEnv("OPENROUTER_PROVIDER_PREFERENCES", "not json");
+    expect(openRouterProviderPreferences()).toBeNull();
+  });
+});
@@ -0,0 +1,54 @@
+import { createOpenAI } from "@ai-sdk/openai";
+import { isRecord } from "@/lib/type-guards";
+
+// OpenRouter gives one key access to many vendors' models. Jobs select it with an "openrouter/" prefix, e.g.
+// "openrouter/anthropic/claude-3.5-haiku" or "openrouter/auto"; requests go through its OpenAI-compatible chat API.
+// OPENROUTER_PROVIDER_PREFERENCES is passed through as the request's `provider` object (order, allow_fallbacks,
+// data_collection, ...), see https://openrouter.ai/docs/features/provider-routing.
+
+export const OPENROUTER_PREFIX = "openrouter/";
+const OPENROUTER_BASE_URL = "https://openrouter.ai/api/v1";
+
+export function isOpenRouterModel(model: string) {
+  return model.trim().toLowerCase().startsWith(OPENROUTER_PREFIX);
+}
+
+export function openRouterModelId(model: string) {
+  return model.trim().slice(OPENROUTER_PREFIX.length);
+}
+
+export function openRouterProviderPreferences(): Record<string, unknown> | null {
+  try {
+    const parsed = JSON.parse(process.env.OPENROUTER_PROVIDER_PREFERENCES?.trim() || "null") as unknown;
+    return isRecord(parsed) && Object.keys(parsed).length ? parsed : null;
+  } catch {
+    return null;
+  }
+}
+
+// Adds the provider preferences to a chat completions request body; other bodies pass through unchanged.
+export function withProviderPreferences(body: unknown, preferences: Record<string, unknown> | null) {
+  if (!preferences || typeof body !== "string") {
+    return body;
+  }
+  try {
+    const parsed = JSON.parse(body) as unknown;
+    return isRecord(parsed) ? JSON.stringify({ ...parsed, provider: preferences }) : body;
+  } catch {
+    return body;
+  }
+}
+
+export function openRouterModel(model: string) {
+  const preferences = openRouterProviderPreferences();
+  const appUrl = process.env.APP_URL ?? process.env.NEXTAUTH_URL;
+  const provider = createOpenAI({
+    name: "openrouter",
+    baseURL: process.env.OPENROUTER_BASE_URL?.trim() || OPENROUTER_BASE_URL,
+    apiKey: process.env.OPENROUTER_API_KEY?.trim() ?? "",
+    // App attribution on openrouter.ai; optional.
+    headers: { "X-Title": "promptloop", ...(appUrl ? { "HTTP-Referer": appUrl } : {}) },
+    fetch: (input, init) => fetch(input, init?.body ? { ...init, body: withProviderPreferences(init.body, preferences) as BodyInit } : init),
+  });
+  return provider.chat(openRouterModelId(model));
+}
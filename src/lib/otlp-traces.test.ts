@@ -0,0 +1,104 @@
+import { ROOT_CONTEXT, TraceFlags, trace } from "@opentelemetry/api";
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+import { OtlpTraceExporter, otlpTracesUrl, registerOtlpTracing, samplerFromEnv, type OtlpSpan } from "./otlp-traces";
+import { withSpan } from "./tracing";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+  vi.unstubAllGlobals();
+});
+
+describe("otlp traces", () => {
+  it("reads the endpoint from the standard env vars", () => {
+    expect(otlpTracesUrl()).toBeNull();
+    vi.stubEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/");
+    expect(otlpTracesUrl()).toBe("http://collector:4318/v1/traces");
+    vi.stubEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "https://otlp.example.com/custom/traces");
+    expect(otlpTracesUrl()).toBe("https://otlp.example.com/custom/traces");
+    vi.stubEnv("OTEL_TRACES_EXPORTER", "none");
+    expect(otlpTracesUrl()).toBeNull();
+  });
+
+  it("exports nested spans with their parent, attributes and errors", async () => {
+    const fetchMock = vi.fn().mockResolvedValue(new Response(null, { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+    vi.stubEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318");
+    vi.stubEnv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=secret");
+    vi.stubEnv("OTEL_SERVICE_NAME", "promptloop-test");
+    const exporter = registerOtlpTracing();
+    expect(exporter).not.toBeNull();
+
+    await withSpan("promptloop.job.run", { "promptloop.job.id": "job_1" }, async () => {
+      await withSpan("promptloop.llm.run", { "promptloop.llm.attempt": 1 }, async () => "ok");
+      await expect(
+        withSpan("promptloop.channel.deliver", { "promptloop.channel.type": "discord" }, async () => {
+          throw new Error("HTTP 500");
+        }),
+      ).rejects.toThrow("HTTP 500");
+    });
+    await exporter?.flush();
+
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+    const [url, init] = fetchMock.mock.calls[0] as [string, RequestInit];
+    expect(url).toBe("http://collector:4318/v1/traces");
+    expect(new Headers(init.headers).get("x-api-key")).toBe("secret");
+    const body = JSON.parse(String(init.body));
+    expect(body.resourceSpans[0].resource.attributes).toContainEqual({ key: "service.name", value: { stringValue: "promptloop-test" } });
+    const spans = body.resourceSpans[0].scopeSpans[0].spans as OtlpSpan[];
+    const byName = Object.fromEntries(spans.map((span) => [span.name, span]));
+    const root = byName["promptloop.job.run"];
+    expect(root.parentSpanId).toBeUndefined();
+    expect(root.attributes).toContainEqual({ key: "promptloop.job.id", value: { stringValue: "job_1" } });
+    expect(byName["promptloop.llm.run"]).toMatchObject({ traceId: root.traceId, parentSpanId: root.spanId, status: { code: 0 } });
+    expect(byName["promptloop.llm.run"].attributes).toContainEqual({ key: "promptloop.llm.attempt", value: { intValue: "1" } });
+    expect(byName["promptloop.channel.deliver"]).toMatchObject({ parentSpanId: root.spanId, status: { code: 2, message: "HTTP 500" } });
+    expect(byName["promptloop.channel.deliver"].events[0].name).toBe("exception");
+  });
+
+  it("honours OTEL_TRACES_SAMPLER and its ratio", () => {
+    const sampled = { traceId: "a".repeat(32), spanId: "b".repeat(16), traceFlags: TraceFlags.SAMPLED };
+    const unsampled = { ...sampled, traceFlags: TraceFlags.NONE };
+    const lowTraceId = `${"f".repeat(19)}0000000000001`;
+    const highTraceId = `${"0".repeat(19)}fffffffffffff`;
+
+    const defaults = samplerFromEnv({});
+    expect(defaults(undefined, highTraceId)).toBe(true);
+    expect(defaults(unsampled, highTraceId)).toBe(false);
+
+    expect(samplerFromEnv({ OTEL_TRACES_SAMPLER: "always_off" })(sampled, lowTraceId)).toBe(false);
+    const ratio = samplerFromEnv({ OTEL_TRACES_SAMPLER: "traceidratio", OTEL_TRACES_SAMPLER_ARG: "0.25" });
+    expect(ratio(undefined, lowTraceId)).toBe(true);
+    expect(ratio(undefined, highTraceId)).toBe(false);
+    expect(ratio(unsampled, lowTraceId)).toBe(true);
+    const parentRatio = samplerFromEnv({ OTEL_TRACES_SAMPLER: "parentbased_traceidratio", OTEL_TRACES_SAMPLER_ARG: "0.25" });
+    expect(parentRatio(sampled, highTraceId)).toBe(true);
+    expect(parentRatio(undefined, highTraceId)).toBe(false);
+    expect(samplerFromEnv({ OTEL_TRACES_SAMPLER: "sometimes" })(undefined, highTraceId)).toBe(true);
+  });
+
+  it("records links and skips spans the sampler drops", async () => {
+    const fetchMock = vi.fn().mockResolvedValue(new Response(null, { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+    const exporter = new OtlpTraceExporter("http://collector:4318/v1/traces", {}, {}, samplerFromEnv({}));
+    const tracer = exporter.getTracer();
+    const linked = { traceId: "c".repeat(32), spanId: "d".repeat(16), traceFlags: TraceFlags.SAMPLED };
+
+    const span = tracer.startSpan("promptloop.run_request", { links: [{ context: linked, attributes: { "promptloop.reason": "retry" } }] });
+    span.addLink({ context: { ...linked, spanId: "e".repeat(16) } });
+    span.end();
+    const dropped = tracer.startSpan("promptloop.job.run", {}, trace.setSpanContext(ROOT_CONTEXT, { ...linked, traceFlags: TraceFlags.NONE }));
+    expect(dropped.isRecording()).toBe(false);
+    expect(dropped.spanContext()).toMatchObject({ traceId: linked.traceId, traceFlags: TraceFlags.NONE });
+    dropped.end();
+    await exporter.flush();
+
+    const body = JSON.parse(String((fetchMock.mock.calls[0] as [string, RequestInit])[1].body));
+    const spans = body.resourceSpans[0].scopeSpans[0].spans as OtlpSpan[];
+    expect(spans.map((item) => item.name)).toEqual(["promptloop.run_request"]);
+    expect(spans[0].links).toEqual([
+      { traceId: linked.traceId, spanId: linked.spanId, attributes: [{ key: "promptloop.reason", value: { stringValue: "retry" } }] },
+      { traceId: linked.traceId, spanId: "e".repeat(16), attributes: [] },
+    ]);
+  });
+});
@@ -0,0 +1,398 @@
+import { AsyncLocalStorage } from "node:async_hooks";
+import { randomBytes } from "node:crypto";
+import { hostname } from "node:os";
+import {
+  ROOT_CONTEXT,
+  SpanStatusCode,
+  TraceFlags,
+  context as otelContext,
+  trace,
+  type Attributes,
+  type AttributeValue,
+  type Context,
+  type ContextManager,
+  type Exception,
+  type Link,
+  type Span,
+  type SpanContext,
+  type SpanOptions,
+  type SpanStatus,
+  type TimeInput,
+  type Tracer,
+  type TracerProvider,
+} from "@opentelemetry/api";
+import { logger } from "@/lib/logger";
+import { parseHeaderList } from "@/lib/worker-metrics";
+
+// Minimal OTLP/HTTP JSON trace exporter behind the global OpenTelemetry API, so the spans in tracing.ts reach a
+// collector without an SDK. Enabled by the standard env vars: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT (full URL) or
+// OTEL_EXPORTER_OTLP_ENDPOINT (+ /v1/traces), OTEL_EXPORTER_OTLP_HEADERS, OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES; OTEL_TRACES_EXPORTER=none turns it off. OTEL_TRACES_SAMPLER/OTEL_TRACES_SAMPLER_ARG
+// pick the sampler (parentbased_always_on by default). Finished spans are batched and sent every few seconds, and
+// on SIGTERM; a failed export is logged and dropped.
+
+const EXPORT_INTERVAL_MS = 5000;
+const EXPORT_BATCH_MAX = 512;
+const QUEUE_MAX = 4096;
+const EXPORT_TIMEOUT_MS = 10_000;
+
+type OtlpValue = { stringValue: string } | { boolValue: boolean } | { intValue: string } | { doubleValue: number } | { arrayValue: { values: OtlpValue[] } };
+type OtlpAttribute = { key: string; value: OtlpValue };
+
+export type OtlpSpan = {
+  traceId: string;
+  spanId: string;
+  parentSpanId?: string;
+  name: string;
+  kind: number;
+  startTimeUnixNano: string;
+  endTimeUnixNano: string;
+  attributes: OtlpAttribute[];
+  events: Array<{ name: string; timeUnixNano: string; attributes: OtlpAttribute[] }>;
+  links: Array<{ traceId: string; spanId: string; attributes: OtlpAttribute[] }>;
+  status: { code: number; message?: string };
+};
+
+export function otlpTracesUrl() {
+  if (process.env.OTEL_TRACES_EXPORTER?.trim().toLowerCase() === "none") {
+    return null;
+  }
+  const traces = process.env.OTEL_EXPORTER_OTLP_TRACES_ENDPOINT?.trim();
+  if (traces) {
+    return traces;
+  }
+  const base = process.env.OTEL_EXPORTER_OTLP_ENDPOINT?.trim().replace(/\/+$/, "");
+  return base ? `${base}/v1/traces` : null;
+}
+
+// Decides whether a new span is recorded, from its parent (if any) and trace id.
+export type Sampler = (parent: SpanContext | undefined, traceId: string) => boolean;
+
+const SAMPLERS = ["always_on", "always_off", "traceidratio"];
+
+// The spec's samplers: always_on, always_off, traceidratio (OTEL_TRACES_SAMPLER_ARG, 0..1, default 1) and their
+// parentbased_ variants, which follow the parent's sampled flag and only decide for root spans. The ratio is taken
+// from the low 52 bits of the trace id, so every span of a trace gets the same answer.
+export function samplerFromEnv(env: NodeJS.ProcessEnv = process.env): Sampler {
+  const name = env.OTEL_TRACES_SAMPLER?.trim().toLowerCase() || "parentbased_always_on";
+  const parentBased = name.startsWith("parentbased_");
+  const root = parentBased ? name.slice("parentbased_".length) : name;
+  if (!SAMPLERS.includes(root)) {
+    logger.warn("unknown OTEL_TRACES_SAMPLER, using parentbased_always_on", { sampler: name });
+    return samplerFromEnv({ ...env, OTEL_TRACES_SAMPLER: "parentbased_always_on" });
+  }
+  const arg = Number(env.OTEL_TRACES_SAMPLER_ARG?.trim() || 1);
+  const ratio = root === "always_on" ? 1 : root === "always_off" ? 0 : Number.isFinite(arg) ? Math.min(Math.max(arg, 0), 1) : 1;
+  return (parent, traceId) => {
+    if (parentBased && parent) {
+      return (parent.traceFlags & TraceFlags.SAMPLED) === TraceFlags.SAMPLED;
+    }
+    return ratio >= 1 || parseInt(traceId.slice(-13), 16) / 2 ** 52 < ratio;
+  };
+}
+
+function toNanos(time: TimeInput | undefined) {
+  if (Array.isArray(time)) {
+    return `${BigInt(time[0]) * 1_000_000_000n + BigInt(time[1])}`;
+  }
+  const ms = time instanceof Date ? time.getTime() : typeof time === "number" ? time : Date.now();
+  return `${BigInt(Math.round(ms * 1000)) * 1000n}`;
+}
+
+function otlpValue(value: AttributeValue): OtlpValue {
+  if (Array.isArray(value)) {
+    return { arrayValue: { values: value.filter((item) => item != null).map((item) => otlpValue(item as AttributeValue)) } };
+  }
+  if (typeof value === "boolean") {
+    return { boolValue: value };
+  }
+  if (typeof value === "number") {
+    return Number.isInteger(value) ? { intValue: String(value) } : { doubleValue: value };
+  }
+  return { stringValue: String(value) };
+}
+
+function otlpAttributes(attributes: Attributes | undefined): OtlpAttribute[] {
+  return Object.entries(attributes ?? {})
+    .filter((entry): entry is [string, AttributeValue] => entry[1] != null)
+    .map(([key, value]) => ({ key, value: otlpValue(value) }));
+}
+
+class RecordingSpan implements Span {
+  private readonly context: SpanContext;
+  private readonly attributes: Attributes = {};
+  private readonly events: OtlpSpan["events"] = [];
+  private readonly links: OtlpSpan["links"] = [];
+  private readonly startTime: string;
+  private status: SpanStatus = { code: SpanStatusCode.UNSET };
+  private ended = false;
+
+  constructor(
+    private name: string,
+    private readonly kind: number,
+    private readonly parentSpanId: string | undefined,
+    traceId: string,
+    options: SpanOptions,
+    private readonly onEnd: (span: OtlpSpan) => void,
+  ) {
+    this.context = { traceId, spanId: randomBytes(8).toString("hex"), traceFlags: TraceFlags.SAMPLED };
+    this.startTime = toNanos(options.startTime);
+    this.setAttributes(options.attributes ?? {});
+    this.addLinks(options.links ?? []);
+  }
+
+  spanContext() {
+    return this.context;
+  }
+
+  setAttribute(key: string, value: AttributeValue) {
+    if (!this.ended) {
+      this.attributes[key] = value;
+    }
+    return this;
+  }
+
+  setAttributes(attributes: Attributes) {
+    for (const [key, value] of Object.entries(attributes)) {
+      if (value != null) {
+        this.setAttribute(key, value);
+      }
+    }
+    return this;
+  }
+
+  addEvent(name: string, attributesOrTime?: Attributes | TimeInput, time?: TimeInput) {
+    const isTime = Array.isArray(attributesOrTime) || typeof attributesOrTime === "number" || attributesOrTime instanceof Date;
+    if (!this.ended) {
+      this.events.push({
+        name,
+        timeUnixNano: toNanos(isTime ? (attributesOrTime as TimeInput) : time),
+        attributes: otlpAttributes(isTime ? undefined : (attributesOrTime as Attributes | undefined)),
+      });
+    }
+    return this;
+  }
+
+  addLink(link: Link) {
+    if (!this.ended) {
+      this.links.push({ traceId: link.context.traceId, spanId: link.context.spanId, attributes: otlpAttributes(link.attributes) });
+    }
+    return this;
+  }
+
+  addLinks(links: Link[]) {
+    for (const link of links) {
+      this.addLink(link);
+    }
+    return this;
+  }
+
+  setStatus(status: SpanStatus) {
+    this.status = status;
+    return this;
+  }
+
+  updateName(name: string) {
+    this.name = name;
+    return this;
+  }
+
+  end(endTime?: TimeInput) {
+    if (this.ended) {
+      return;
+    }
+    this.ended = true;
+    this.onEnd({
+      traceId: this.context.traceId,
+      spanId: this.context.spanId,
+      ...(this.parentSpanId ? { parentSpanId: this.parentSpanId } : {}),
+      name: this.name,
+      kind: this.kind + 1,
+      startTimeUnixNano: this.startTime,
+      endTimeUnixNano: toNanos(endTime),
+      attributes: otlpAttributes(this.attributes),
+      events: this.events,
+      links: this.links,
+      status: { code: this.status.code, ...(this.status.message ? { message: this.status.message } : {}) },
+    });
+  }
+
+  isRecording() {
+    return !this.ended;
+  }
+
+  recordException(exception: Exception, time?: TimeInput) {
+    const attributes: Attributes =
+      typeof exception === "string"
+        ? { "exception.message": exception }
+        : {
+            "exception.type": exception.name ?? ("code" in exception && exception.code != null ? String(exception.code) : "Error"),
+            "exception.message": exception.message ?? "",
+            ...(exception.stack ? { "exception.stacktrace": exception.stack } : {}),
+          };
+    this.addEvent("exception", attributes, time);
+  }
+}
+
+class OtlpTracer implements Tracer {
+  constructor(
+    private readonly onEnd: (span: OtlpSpan) => void,
+    private readonly sample: Sampler,
+  ) {}
+
+  // A span the sampler drops still carries its ids (unsampled), so its children and outgoing requests see the
+  // decision; it records nothing.
+  startSpan(name: string, options: SpanOptions = {}, ctx: Context = otelContext.active()): Span {
+    const parent = options.root ? undefined : trace.getSpanContext(ctx);
+    const traceId = parent?.traceId ?? randomBytes(16).toString("hex");
+    if (!this.sample(parent, traceId)) {
+      return trace.wrapSpanContext({ traceId, spanId: randomBytes(8).toString("hex"), traceFlags: TraceFlags.NONE });
+    }
+    return new RecordingSpan(name, options.kind ?? 0, parent?.spanId, traceId, options, this.onEnd);
+  }
+
+  startActiveSpan<F extends (span: Span) => unknown>(name: string, fn: F): ReturnType<F>;
+  startActiveSpan<F extends (span: Span) => unknown>(name: string, options: SpanOptions, fn: F): ReturnType<F>;
+  startActiveSpan<F extends (span: Span) => unknown>(name: string, options: SpanOptions, ctx: Context, fn: F): ReturnType<F>;
+  startActiveSpan<F extends (span: Span) => unknown>(name: string, ...args: unknown[]): ReturnType<F> {
+    const fn = args.pop() as F;
+    const [options, ctx] = args as [SpanOptions | undefined, Context | undefined];
+    const parentContext = ctx ?? otelContext.active();
+    const span = this.startSpan(name, options, parentContext);
+    return otelContext.with(trace.setSpan(parentContext, span), () => fn(span)) as ReturnType<F>;
+  }
+}
+
+// AsyncLocalStorage-backed context, so spans started inside withSpan() callbacks get the right parent.
+class AsyncContextManager implements ContextManager {
+  private readonly storage = new AsyncLocalStorage<Context>();
+
+  active() {
+    return this.storage.getStore() ?? ROOT_CONTEXT;
+  }
+
+  with<A extends unknown[], F extends (...args: A) => ReturnType<F>>(ctx: Context, fn: F, thisArg?: ThisParameterType<F>, ...args: A): ReturnType<F> {
+    return this.storage.run(ctx, () => fn.apply(thisArg, args) as ReturnType<F>);
+  }
+
+  bind<T>(ctx: Context, target: T): T {
+    if (typeof target !== "function") {
+      return target;
+    }
+    const run = (...args: unknown[]) => this.with(ctx, () => (target as (...a: unknown[]) => unknown)(...args));
+    return run as T;
+  }
+
+  enable() {
+    return this;
+  }
+
+  disable() {
+    this.storage.disable();
+    return this;
+  }
+}
+
+export class OtlpTraceExporter implements TracerProvider {
+  private queue: OtlpSpan[] = [];
+  private timer: NodeJS.Timeout | null = null;
+  private readonly tracer: OtlpTracer;
+
+  constructor(
+    private readonly url: string,
+    private readonly headers: Record<string, string> = {},
+    private readonly resource: Record<string, string> = {},
+    sampler: Sampler = samplerFromEnv(),
+  ) {
+    this.tracer = new OtlpTracer((span) => this.enqueue(span), sampler);
+  }
+
+  getTracer() {
+    return this.tracer;
+  }
+
+  private enqueue(span: OtlpSpan) {
+    if (this.queue.length >= QUEUE_MAX) {
+      this.queue.shift();
+    }
+    this.queue.push(span);
+    if (this.queue.length >= EXPORT_BATCH_MAX) {
+      void this.flush();
+    } else if (!this.timer) {
+      this.timer = setTimeout(() => void this.flush(), EXPORT_INTERVAL_MS);
+      this.timer.unref?.();
+    }
+  }
+
+  // OTLP/HTTP JSON body (ExportTraceServiceRequest).
+  body(spans: OtlpSpan[]) {
+    return {
+      resourceSpans: [
+        {
+          resource: { attributes: Object.entries(this.resource).map(([key, value]) => ({ key, value: { stringValue: value } })) },
+          scopeSpans: [{ scope: { name: "promptloop" }, spans }],
+        },
+      ],
+    };
+  }
+
+  async flush() {
+    if (this.timer) {
+      clearTimeout(this.timer);
+      this.timer = null;
+    }
+    while (this.queue.length) {
+      const spans = this.queue.splice(0, EXPORT_BATCH_MAX);
+      try {
+        const res = await fetch(this.url, {
+          method: "POST",
+          headers: { "content-type": "application/json", ...this.headers },
+          body: JSON.stringify(this.body(spans)),
+          signal: AbortSignal.timeout(EXPORT_TIMEOUT_MS),
+        });
+        if (!res.ok) {
+          throw new Error(`HTTP ${res.status}`);
+        }
+      } catch (err) {
+        logger.warn("trace export failed", { spans: spans.length, error: err });
+      }
+    }
+  }
+}
+
+let installed: OtlpTraceExporter | null = null;
+
+// Sends the spans still queued; a no-op when tracing is off. The worker's SIGTERM hook awaits it before exiting.
+export function flushTraces() {
+  return installed?.flush() ?? Promise.resolve();
+}
+
+// Registers the exporter as the global tracer provider when an OTLP endpoint is configured; called from
+// instrumentation.ts. Returns the exporter, or null when tracing stays a no-op.
+export function registerOtlpTracing() {
+  const url = otlpTracesUrl();
+  if (!url || installed) {
+    return installed;
+  }
+  const resource = {
+    ...parseHeaderList(process.env.OTEL_RESOURCE_ATTRIBUTES),
+    "service.name": process.env.OTEL_SERVICE_NAME?.trim() || "promptloop",
+    "service.instance.id": hostname(),
+  };
+  const headers = parseHeaderList(process.env.OTEL_EXPORTER_OTLP_TRACES_HEADERS ?? process.env.OTEL_EXPORTER_OTLP_HEADERS);
+  installed = new OtlpTraceExporter(url, headers, resource);
+  otelContext.setGlobalContextManager(new AsyncContextManager());
+  trace.setGlobalTracerProvider(installed);
+  process.once("beforeExit", () => void flushTraces());
+  // beforeExit does not fire on a signal. Export what has ended so far right away; when nothing else handles
+  // SIGTERM, re-raise it afterwards so the process still terminates.
+  process.once("SIGTERM", () => {
+    const handled = process.listenerCount("SIGTERM") > 0;
+    void flushTraces().finally(() => {
+      if (!handled) {
+        process.kill(process.pid, "SIGTERM");
+      }
+    });
+  });
+  return installed;
+}
@@ -0,0 +1,84 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+import { __private__, sendChannelMessage } from "./channel";
+import { outboxMessage } from "./outbox-message";
+
+const webhookUrl = "https://discord.com/api/webhooks/1/x";
+
+const job = {
+  name: "Digest",
+  showHeader: true,
+  headerTemplate: "{{job_name}} {{date}}",
+  headerLocale: "en-US",
+  timezone: "UTC",
+  deliveryDiffOnly: false,
+  maxOutputChars: null,
+};
+
+const run = {
+  deliveredParts: 0,
+  deliveryTitle: null,
+  deliveryBody: null,
+  scheduledFor: new Date("2026-05-31T23:59:00Z"),
+  runAt: new Date("2026-06-01T00:00:10Z"),
+  outputText: Array.from({ length: 120 }, (_, i) => `Item ${i} changed overnight.`).join(" "),
+  outputDiff: "Changes since the last run:\n+ Item 119",
+};
+
+// Discord webhook stub that records each part's content and fails the listed calls (1-based).
+function discordFetch(failCalls: number[]) {
+  const contents: string[] = [];
+  let call = 0;
+  const fetchMock = vi.fn(async (_input: RequestInfo | URL, init?: RequestInit) => {
+    call++;
+    if (failCalls.includes(call)) {
+      return new Response(null, { status: 400 });
+    }
+    contents.push((JSON.parse(String(init?.body)) as { content: string }).content);
+    return new Response(null, { status: 204 });
+  });
+  return { fetchMock, contents };
+}
+
+afterEach(() => {
+  vi.unstubAllGlobals();
+});
+
+describe("outbox message", () => {
+  it("renders the header for the run's time, not the retry's", () => {
+    expect(outboxMessage(run, job)).toEqual({
+      title: "Digest May 31, 2026",
+      body: `${run.outputText}\n\n${run.outputDiff}`,
+      stored: false,
+    });
+  });
+
+  it("resumes a partial delivery on the same text even after the header and settings change", async () => {
+    const first = outboxMessage(run, job);
+    const parts = __private__.chunkFencedText(`${first.title}\n\n${first.body}`, 1900);
+    expect(parts.length).toBeGreaterThan(1);
+
+    const failing = discordFetch([2]);
+    vi.stubGlobal("fetch", failing.fetchMock);
+    let delivered = 0;
+    await expect(
+      sendChannelMessage({ type: "discord", webhookUrl }, first.title, first.body, {
+        onPartDelivered: (count) => {
+          delivered = count;
+        },
+      }),
+    ).rejects.toMatchObject({ partsDelivered: 1 });
+    expect(delivered).toBe(1);
+
+    // What onPartDelivered stores with the first part; the retry comes later, with the job edited meanwhile.
+    const stored = { ...run, deliveredParts: delivered, deliveryTitle: first.title, deliveryBody: first.body };
+    const edited = { ...job, headerTemplate: "{{job_name}} ({{weekday}})", deliveryDiffOnly: true, maxOutputChars: 500 };
+    const retry = outboxMessage(stored, edited);
+    expect(retry).toEqual({ title: first.title, body: first.body, stored: true });
+
+    const resumed = discordFetch([]);
+    vi.stubGlobal("fetch", resumed.fetchMock);
+    await sendChannelMessage({ type: "discord", webhookUrl }, retry.title, retry.body, { resumeFromPart: delivered });
+    expect([...failing.contents, ...resumed.contents]).toEqual(parts);
+  });
+});
@@ -0,0 +1,24 @@
+import type { Job, RunHistory } from "@prisma/client";
+import { diffDeliveryBody } from "@/lib/output-diff";
+import { truncateOutput } from "@/lib/output-length";
+import { renderRunHeader } from "@/lib/run-title";
+
+// The message an outbox delivery (deferred, retried or resumed) sends. Once a delivery has confirmed its first
+// part, the exact header and body it sent are stored on the run (delivery_title / delivery_body), and a resumed
+// delivery sends those again: delivered_parts counts parts of that text, so re-rendering it (a header whose time
+// grew a digit, a changed diff-only or length setting) would shift the part boundaries and duplicate or skip
+// chunks. Otherwise the message is rendered from the run, with the header for the run's own time rather than the
+// retry's. `stored` tells the caller that pre-delivery hooks already ran on it.
+export function outboxMessage(
+  run: Pick<RunHistory, "deliveredParts" | "deliveryTitle" | "deliveryBody" | "scheduledFor" | "runAt" | "outputText" | "outputDiff">,
+  job: Pick<Job, "name" | "showHeader" | "headerTemplate" | "headerLocale" | "timezone" | "deliveryDiffOnly" | "maxOutputChars">,
+) {
+  if (run.deliveredParts > 0 && run.deliveryBody != null) {
+    return { title: run.deliveryTitle ?? "", body: run.deliveryBody, stored: true };
+  }
+  return {
+    title: renderRunHeader(job, run.scheduledFor ?? run.runAt, job.timezone ?? "UTC"),
+    body: truncateOutput(diffDeliveryBody(run.outputText ?? "", run.outputDiff, job.deliveryDiffOnly), job.maxOutputChars),
+    stored: false,
+  };
+}
@@ -0,0 +1,57 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { update } = vi.hoisted(() => ({ update: vi.fn(async (_args: unknown) => ({})) }));
+vi.mock("@/lib/prisma", () => ({ prisma: { runHistory: { update } } }));
+
+import { archiveKey, archiveRunOutput, archiveTarget } from "./output-archive";
+
+describe("output archive", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+    vi.unstubAllGlobals();
+    update.mockClear();
+  });
+
+  it("parses archive URLs into bucket and prefix", () => {
+    expect(archiveTarget("")).toBeNull();
+    expect(archiveTarget("s3://reports/promptloop/")).toEqual({ scheme: "s3", bucket: "reports", prefix: "promptloop" });
+    expect(archiveTarget("gs://reports")).toEqual({ scheme: "gs", bucket: "reports", prefix: "" });
+    expect(() => archiveTarget("https://reports.example")).toThrow("OUTPUT_ARCHIVE_URL");
+  });
+
+  it("keys objects by job id and run time", () => {
+    const target = { scheme: "s3" as const, bucket: "b", prefix: "p" };
+    expect(archiveKey(target, "job-1", new Date("2026-07-01T08:00:00.123Z"))).toBe("p/job-1/20260701T080000.123Z.txt");
+    expect(archiveKey({ ...target, prefix: "" }, "job-1", new Date("2026-07-01T08:00:00Z"))).toBe("job-1/20260701T080000.000Z.txt");
+  });
+
+  it("uploads to S3 with SigV4 and records the object URI", async () => {
+    const fetchMock = vi.fn(async (_url: string, _init?: RequestInit) => new Response(null, { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+    vi.stubEnv("OUTPUT_ARCHIVE_URL", "s3://reports/runs");
+    vi.stubEnv("AWS_REGION", "eu-west-1");
+    vi.stubEnv("AWS_ACCESS_KEY_ID", "AKID");
+    vi.stubEnv("AWS_SECRET_ACCESS_KEY", "secret");
+
+    const uri = await archiveRunOutput({ id: "run-1", jobId: "job-1", runAt: new Date("2026-07-01T08:00:00Z") }, "hello");
+    expect(uri).toBe("s3://reports/runs/job-1/20260701T080000.000Z.txt");
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe("https://reports.s3.eu-west-1.amazonaws.com/runs/job-1/20260701T080000.000Z.txt");
+    expect(init?.method).toBe("PUT");
+    expect((init?.headers as Record<string, string>).authorization).toContain("/eu-west-1/s3/aws4_request");
+    expect(update).toHaveBeenCalledWith({ where: { id: "run-1" }, data: { archiveKey: uri } });
+  });
+
+  it("uses path-style URLs for S3-compatible endpoints", async () => {
+    const fetchMock = vi.fn(async (_url: string, _init?: RequestInit) => new Response(null, { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+    vi.stubEnv("OUTPUT_ARCHIVE_URL", "s3://reports");
+    vi.stubEnv("OUTPUT_ARCHIVE_S3_ENDPOINT", "http://minio:9000/");
+    vi.stubEnv("AWS_REGION", "us-east-1");
+    vi.stubEnv("AWS_ACCESS_KEY_ID", "AKID");
+    vi.stubEnv("AWS_SECRET_ACCESS_KEY", "secret");
+
+    await archiveRunOutput({ id: "run-1", jobId: "job-1", runAt: new Date("2026-07-01T08:00:00Z") }, "hello");
+    expect(fetchMock.mock.calls[0][0]).toBe("http://minio:9000/reports/job-1/20260701T080000.000Z.txt");
+  });
+});
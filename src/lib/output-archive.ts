@@ -0,0 +1,101 @@
+import { createHash } from "node:crypto";
+import { prisma } from "@/lib/prisma";
+import { getGoogleAccessToken } from "@/lib/google-auth";
+import { signAwsRequest } from "@/lib/secret-backend";
+
+// Optional long-term archive of run outputs in object storage. OUTPUT_ARCHIVE_URL selects the bucket and prefix:
+// s3://bucket/prefix (AWS_REGION + AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, or an S3-compatible
+// OUTPUT_ARCHIVE_S3_ENDPOINT) or gs://bucket/prefix (OUTPUT_ARCHIVE_GCS_SERVICE_ACCOUNT_JSON). Each run is
+// written to <prefix>/<job id>/<run time>.txt and the object URI is recorded in run_histories.archive_key.
+export type ArchiveTarget = { scheme: "s3" | "gs"; bucket: string; prefix: string };
+
+const GCS_SCOPE = "https://www.googleapis.com/auth/devstorage.read_write";
+
+export function archiveTarget(value = process.env.OUTPUT_ARCHIVE_URL): ArchiveTarget | null {
+  const raw = value?.trim();
+  if (!raw) {
+    return null;
+  }
+  const match = /^(s3|gs):\/\/([^/]+)\/?(.*)$/.exec(raw);
+  if (!match) {
+    throw new Error(`OUTPUT_ARCHIVE_URL must look like s3://bucket/prefix or gs://bucket/prefix, got ${raw}`);
+  }
+  return { scheme: match[1] as ArchiveTarget["scheme"], bucket: match[2], prefix: match[3].replace(/^\/+|\/+$/g, "") };
+}
+
+// <prefix>/<job id>/20260701T080000.000Z.txt; the run time keeps keys sortable within a job.
+export function archiveKey(target: ArchiveTarget, jobId: string, runAt: Date) {
+  const stamp = runAt.toISOString().replace(/[:-]/g, "");
+  return [target.prefix, jobId, `${stamp}.txt`].filter(Boolean).join("/");
+}
+
+export function archiveUri(target: ArchiveTarget, key: string) {
+  return `${target.scheme}://${target.bucket}/${key}`;
+}
+
+function requiredEnv(name: string) {
+  const value = process.env[name]?.trim();
+  if (!value) {
+    throw new Error(`${name} is required for OUTPUT_ARCHIVE_URL`);
+  }
+  return value;
+}
+
+function encodeKey(key: string) {
+  return key.split("/").map(encodeURIComponent).join("/");
+}
+
+async function putS3(target: ArchiveTarget, key: string, body: string) {
+  const region = requiredEnv("AWS_REGION");
+  const endpoint = process.env.OUTPUT_ARCHIVE_S3_ENDPOINT?.trim().replace(/\/+$/, "");
+  // Custom endpoints (MinIO, R2) use path-style URLs; AWS uses the virtual-hosted bucket name.
+  const base = endpoint ? new URL(endpoint) : new URL(`https://${target.bucket}.s3.${region}.amazonaws.com`);
+  const path = `${endpoint ? `${base.pathname.replace(/\/+$/, "")}/${encodeURIComponent(target.bucket)}` : ""}/${encodeKey(key)}`;
+  const headers = signAwsRequest({
+    host: base.host,
+    region,
+    service: "s3",
+    method: "PUT",
+    path,
+    contentType: "text/plain; charset=utf-8",
+    headers: { "x-amz-content-sha256": createHash("sha256").update(body).digest("hex") },
+    body,
+    accessKeyId: requiredEnv("AWS_ACCESS_KEY_ID"),
+    secretAccessKey: requiredEnv("AWS_SECRET_ACCESS_KEY"),
+    sessionToken: process.env.AWS_SESSION_TOKEN?.trim() || undefined,
+  });
+  const res = await fetch(`${base.protocol}//${base.host}${path}`, { method: "PUT", headers, body });
+  if (!res.ok) {
+    throw new Error(`S3 archive upload failed: ${res.status}`);
+  }
+}
+
+async function putGcs(target: ArchiveTarget, key: string, body: string) {
+  const token = await getGoogleAccessToken(requiredEnv("OUTPUT_ARCHIVE_GCS_SERVICE_ACCOUNT_JSON"), GCS_SCOPE);
+  const url = `https://storage.googleapis.com/upload/storage/v1/b/${encodeURIComponent(target.bucket)}/o?uploadType=media&name=${encodeURIComponent(key)}`;
+  const res = await fetch(url, {
+    method: "POST",
+    headers: { "Content-Type": "text/plain; charset=utf-8", Authorization: `Bearer ${token}` },
+    body,
+  });
+  if (!res.ok) {
+    throw new Error(`GCS archive upload failed: ${res.status}`);
+  }
+}
+
+// Uploads the output and records its URI on the run. Returns null when no archive is configured.
+export async function archiveRunOutput(run: { id: string; jobId: string; runAt: Date }, output: string) {
+  const target = archiveTarget();
+  if (!target) {
+    return null;
+  }
+  const key = archiveKey(target, run.jobId, run.runAt);
+  if (target.scheme === "s3") {
+    await putS3(target, key, output);
+  } else {
+    await putGcs(target, key, output);
+  }
+  const uri = archiveUri(target, key);
+  await prisma.runHistory.update({ where: { id: run.id }, data: { archiveKey: uri } });
+  return uri;
+}
@@ -0,0 +1,34 @@
+import { describe, expect, it } from "vitest";
+import { diffDeliveryBody, formatDiffBlock, normalizeDeliveryDiff, unifiedDiff } from "./output-diff";
+
+describe("output diff", () => {
+  it("returns nothing for identical outputs", () => {
+    expect(unifiedDiff("a\nb", "a\nb")).toBe("");
+  });
+
+  it("builds unified hunks with context", () => {
+    const previous = ["1", "2", "3", "4", "5", "6", "7", "8", "9", "10"].join("\n");
+    const current = ["1", "2", "3", "4", "five", "6", "7", "8", "9", "10", "11"].join("\n");
+    expect(unifiedDiff(previous, current)).toBe(
+      ["@@ -2,9 +2,10 @@", " 2", " 3", " 4", "-5", "+five", " 6", " 7", " 8", " 9", " 10", "+11"].join("\n"),
+    );
+    const lines = Array.from({ length: 20 }, (_, i) => `${i}`);
+    const far = unifiedDiff(lines.join("\n"), ["x", ...lines.slice(1), "y"].join("\n"));
+    expect(far.split("\n").filter((line) => line.startsWith("@@"))).toEqual(["@@ -1,4 +1,4 @@", "@@ -18,3 +18,4 @@"]);
+  });
+
+  it("formats diff blocks and truncates long diffs", () => {
+    expect(formatDiffBlock("unified", "+x")).toBe("Changes since the previous run:\n```diff\n+x\n```");
+    expect(formatDiffBlock("summary", "x".repeat(4000))).toContain("[diff truncated]");
+    expect(formatDiffBlock("unified", "")).toBe("Changes since the previous run: none.");
+    expect(normalizeDeliveryDiff("summary")).toBe("summary");
+    expect(normalizeDeliveryDiff("yes")).toBe("off");
+  });
+
+  it("delivers only the change block for diff-only jobs", () => {
+    const block = formatDiffBlock("unified", "");
+    expect(diffDeliveryBody("out", block, false)).toBe(`out\n\n${block}`);
+    expect(diffDeliveryBody("out", block, true)).toBe("Changes since the previous run: none.");
+    expect(diffDeliveryBody("out", null, true)).toBe("out");
+  });
+});
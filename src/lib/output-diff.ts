@@ -0,0 +1,110 @@
+export const DELIVERY_DIFF_MODES = ["off", "unified", "summary"] as const;
+export type DeliveryDiffMode = (typeof DELIVERY_DIFF_MODES)[number];
+
+const DIFF_MAX_LINES = 2000;
+const DIFF_CONTEXT = 3;
+export const DIFF_TEXT_MAX = 3000;
+
+export function normalizeDeliveryDiff(value: unknown): DeliveryDiffMode {
+  return DELIVERY_DIFF_MODES.includes(value as DeliveryDiffMode) ? (value as DeliveryDiffMode) : "off";
+}
+
+type Op = { kind: " " | "-" | "+"; line: string; oldNo: number; newNo: number };
+
+// Line-level LCS; inputs are capped so the table stays small.
+function diffOps(a: string[], b: string[]): Op[] {
+  const n = a.length;
+  const m = b.length;
+  const lcs: number[][] = Array.from({ length: n + 1 }, () => new Array<number>(m + 1).fill(0));
+  for (let i = n - 1; i >= 0; i--) {
+    for (let j = m - 1; j >= 0; j--) {
+      lcs[i][j] = a[i] === b[j] ? lcs[i + 1][j + 1] + 1 : Math.max(lcs[i + 1][j], lcs[i][j + 1]);
+    }
+  }
+
+  const ops: Op[] = [];
+  let i = 0;
+  let j = 0;
+  while (i < n || j < m) {
+    if (i < n && j < m && a[i] === b[j]) {
+      ops.push({ kind: " ", line: a[i], oldNo: i + 1, newNo: j + 1 });
+      i++;
+      j++;
+    } else if (i < n && (j >= m || lcs[i + 1][j] >= lcs[i][j + 1])) {
+      ops.push({ kind: "-", line: a[i], oldNo: i + 1, newNo: j });
+      i++;
+    } else {
+      ops.push({ kind: "+", line: b[j], oldNo: i, newNo: j + 1 });
+      j++;
+    }
+  }
+  return ops;
+}
+
+// Unified diff (hunks with 3 lines of context) between two outputs; "" when they are identical.
+export function unifiedDiff(previous: string, current: string): string {
+  const a = previous.replace(/\r\n/g, "\n").split("\n").slice(0, DIFF_MAX_LINES);
+  const b = current.replace(/\r\n/g, "\n").split("\n").slice(0, DIFF_MAX_LINES);
+  const ops = diffOps(a, b);
+  const changed = ops.map((op, index) => (op.kind === " " ? -1 : index)).filter((index) => index >= 0);
+  if (!changed.length) {
+    return "";
+  }
+
+  const hunks: Array<[number, number]> = [];
+  for (const index of changed) {
+    const start = Math.max(0, index - DIFF_CONTEXT);
+    const end = Math.min(ops.length - 1, index + DIFF_CONTEXT);
+    const last = hunks[hunks.length - 1];
+    if (last && start <= last[1] + 1) {
+      last[1] = Math.max(last[1], end);
+    } else {
+      hunks.push([start, end]);
+    }
+  }
+
+  const out: string[] = [];
+  for (const [start, end] of hunks) {
+    const slice = ops.slice(start, end + 1);
+    const oldLines = slice.filter((op) => op.kind !== "+").length;
+    const newLines = slice.filter((op) => op.kind !== "-").length;
+    const oldStart = slice.find((op) => op.kind !== "+")?.oldNo ?? slice[0].oldNo;
+    const newStart = slice.find((op) => op.kind !== "-")?.newNo ?? slice[0].newNo;
+    out.push(`@@ -${oldStart},${oldLines} +${newStart},${newLines} @@`);
+    out.push(...slice.map((op) => `${op.kind}${op.line}`));
+  }
+  return out.join("\n");
+}
+
+export function changeSummaryPrompt(previous: string, current: string) {
+  return [
+    "Compare the previous and the current output of a scheduled report.",
+    "List what changed (added, removed, or updated facts) as short bullet points. If nothing meaningful changed, reply exactly: No meaningful changes.",
+    "",
+    "<previous>",
+    previous,
+    "</previous>",
+    "",
+    "<current>",
+    current,
+    "</current>",
+  ].join("\n");
+}
+
+// What a delivery carries: the output with the change block below it, or only the block for diff-only jobs. Without
+// a block (first run, failed summary) the output goes out on its own.
+export function diffDeliveryBody(output: string, diffBlock: string | null, diffOnly: boolean) {
+  if (!diffBlock) {
+    return output;
+  }
+  return diffOnly ? diffBlock : `${output}\n\n${diffBlock}`;
+}
+
+// Appended to the delivered message; long diffs are cut so they do not crowd out the output itself.
+export function formatDiffBlock(mode: DeliveryDiffMode, diff: string) {
+  if (!diff.trim()) {
+    return "Changes since the previous run: none.";
+  }
+  const text = diff.length > DIFF_TEXT_MAX ? `${diff.slice(0, DIFF_TEXT_MAX)}\n[diff truncated]` : diff;
+  return mode === "unified" ? `Changes since the previous run:\n\`\`\`diff\n${text}\n\`\`\`` : `Changes since the previous run:\n${text}`;
+}
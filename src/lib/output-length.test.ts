@@ -0,0 +1,35 @@
+import { describe, expect, it } from "vitest";
+
+import { TRUNCATION_MARKER, truncateOutput, withOutputLengthInstruction } from "./output-length";
+
+describe("output length", () => {
+  it("leaves outputs within the limit alone", () => {
+    expect(truncateOutput("Short answer.", 100)).toBe("Short answer.");
+    expect(truncateOutput("x".repeat(500), null)).toBe("x".repeat(500));
+  });
+
+  it("cuts at the last sentence boundary that fits and marks the cut", () => {
+    const text = "First sentence here. Second sentence is a bit longer! Third one does not fit at all, sadly.";
+    const out = truncateOutput(text, 70);
+    expect(out).toBe(`First sentence here. Second sentence is a bit longer! ${TRUNCATION_MARKER}`);
+    expect(out.length).toBeLessThanOrEqual(70);
+
+    expect(truncateOutput("- item one\n- item two\n- item three\n- item four", 35)).toBe(`- item one\n- item two ${TRUNCATION_MARKER}`);
+  });
+
+  it("falls back to a word boundary and then a hard cut", () => {
+    const words = truncateOutput("one two three four five six seven eight nine ten eleven twelve", 40);
+    expect(words).toBe(`one two three four five six ${TRUNCATION_MARKER}`);
+    expect(words.length).toBeLessThanOrEqual(40);
+
+    const hard = truncateOutput("y".repeat(200), 60);
+    expect(hard).toBe(`${"y".repeat(48)} ${TRUNCATION_MARKER}`);
+    expect(hard.length).toBe(60);
+  });
+
+  it("adds the length instruction to the system prompt", () => {
+    expect(withOutputLengthInstruction("Be terse.", null)).toBe("Be terse.");
+    expect(withOutputLengthInstruction(null, 280)).toContain("under 280 characters");
+    expect(withOutputLengthInstruction("Be terse.", 280)).toMatch(/^Be terse\.\n\nKeep the entire response under 280/);
+  });
+});
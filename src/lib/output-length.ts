@@ -0,0 +1,52 @@
+// Per-job output length cap (max_output_chars). The model is asked to stay within the limit; when it does not, the
+// delivered message is cut at the last sentence boundary that fits and marked, so length-limited channels (SMS,
+// Pushover) get a complete-looking message and digests stay scannable. The stored output is kept in full.
+
+export const MAX_OUTPUT_CHARS_MIN = 50;
+export const MAX_OUTPUT_CHARS_MAX = 100000;
+export const TRUNCATION_MARKER = "[truncated]";
+
+// Appended to the job's system prompt for the calls whose output is delivered (primary and post prompt).
+export function outputLengthInstruction(max: number | null | undefined) {
+  if (!max) {
+    return "";
+  }
+  return `Keep the entire response under ${max} characters. Prefer a shorter complete answer over a longer one that gets cut off.`;
+}
+
+export function withOutputLengthInstruction(systemPrompt: string | null | undefined, max: number | null | undefined) {
+  const instruction = outputLengthInstruction(max);
+  if (!instruction) {
+    return systemPrompt ?? null;
+  }
+  return systemPrompt?.trim() ? `${systemPrompt.trim()}\n\n${instruction}` : instruction;
+}
+
+// Returns `text` unchanged when it fits. Otherwise keeps the longest prefix ending at a sentence boundary (., !, ?
+// or a line break) that leaves room for the marker, falling back to a word boundary and then a hard cut when the
+// first sentence alone is too long.
+export function truncateOutput(text: string, max: number | null | undefined) {
+  if (!max || text.length <= max) {
+    return text;
+  }
+  const room = Math.max(0, max - TRUNCATION_MARKER.length - 1);
+  const head = text.slice(0, room + 1);
+
+  let cut = -1;
+  const sentenceEnd = /[.!?](?=\s)|\n/g;
+  for (let match = sentenceEnd.exec(head); match; match = sentenceEnd.exec(head)) {
+    const end = match[0] === "\n" ? match.index : match.index + 1;
+    if (end <= room) {
+      cut = end;
+    }
+  }
+  // A boundary in the first fifth would throw away most of the budget; a word boundary keeps more.
+  if (cut < room / 5) {
+    const space = head.lastIndexOf(" ", room);
+    cut = space > cut ? space : cut;
+  }
+  if (cut <= 0) {
+    cut = room;
+  }
+  return `${text.slice(0, cut).trimEnd()} ${TRUNCATION_MARKER}`.trimStart();
+}
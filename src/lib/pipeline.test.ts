@@ -0,0 +1,42 @@
+import { describe, expect, it, vi } from "vitest";
+import { normalizePipelineSteps, pipelineStepVariables, runPipelineSteps } from "./pipeline";
+
+describe("pipeline steps", () => {
+  it("normalizes stored steps and drops invalid ones", () => {
+    expect(normalizePipelineSteps([{ template: " Critique {{output}} " }])).toEqual([{ name: "", template: "Critique {{output}}" }]);
+    expect(normalizePipelineSteps([{ name: "x", template: "" }])).toEqual([]);
+    expect(normalizePipelineSteps(Array.from({ length: 6 }, () => ({ template: "a" })))).toEqual([]);
+    expect(normalizePipelineSteps(null)).toEqual([]);
+  });
+
+  it("numbers earlier outputs from the main prompt", () => {
+    expect(pipelineStepVariables(["draft", "critique"])).toEqual({ step_1: "draft", step_2: "critique" });
+  });
+
+  it("feeds each step the previous output and collects usage", async () => {
+    const run = vi
+      .fn()
+      .mockResolvedValueOnce({ output: "critique", llmUsage: { inputTokens: 1 } })
+      .mockResolvedValueOnce({ output: "final SECRET", llmUsage: { inputTokens: 2 } });
+    const result = await runPipelineSteps(
+      [
+        { name: "critique", template: "Critique: {{output}}" },
+        { name: "final", template: "Fix {{step_1}} using {{output}} for {{topic}}" },
+      ],
+      {
+        output: "draft",
+        variables: { topic: "news" },
+        citations: [],
+        usedWebSearch: false,
+        llmModel: "gpt-5-mini",
+        compileContext: {},
+        run,
+        clean: (output) => output.replace("SECRET", "[redacted]"),
+      },
+    );
+    expect(run.mock.calls.map((call) => call[0])).toEqual(["Critique: draft", "Fix draft using critique for news"]);
+    expect(result.output).toBe("final [redacted]");
+    expect(result.usages).toEqual([{ inputTokens: 1 }, { inputTokens: 2 }]);
+    expect(result.toolCalls).toEqual([null, null]);
+  });
+});
@@ -0,0 +1,68 @@
+import { z } from "zod";
+import type { GeneratedRunFile } from "@/lib/ai-result";
+import { compilePromptTemplate, type PromptCompileContext } from "@/lib/prompt-compile";
+import { buildPostPromptVariables } from "@/lib/post-prompt";
+
+// Multi-step jobs (draft -> critique -> final): after the main prompt, each pipeline step is sent to the same model
+// with the previous step's output as {{output}} and every earlier output as {{step_1}}, {{step_2}}, ... (step_1 is
+// the main prompt's). The last step's output is the run's output; the post prompt, if any, still runs after it.
+export const PIPELINE_STEPS_MAX = 5;
+
+export const pipelineStepSchema = z
+  .object({
+    name: z.string().trim().max(60).optional().default(""),
+    template: z.string().trim().min(1, "Pipeline steps need a prompt").max(8000),
+  })
+  .strict();
+export const pipelineStepsSchema = z.array(pipelineStepSchema).max(PIPELINE_STEPS_MAX);
+export type PipelineStep = z.infer<typeof pipelineStepSchema>;
+
+export function normalizePipelineSteps(value: unknown): PipelineStep[] {
+  const parsed = pipelineStepsSchema.safeParse(value);
+  return parsed.success ? parsed.data : [];
+}
+
+export function pipelineStepVariables(outputs: string[]) {
+  return Object.fromEntries(outputs.map((output, index) => [`step_${index + 1}`, output]));
+}
+
+type StepResult = { output: string; llmUsage?: unknown; llmToolCalls?: unknown; files?: GeneratedRunFile[] };
+
+export type PipelineResult = { output: string; usages: unknown[]; toolCalls: unknown[]; files: GeneratedRunFile[] };
+
+// Runs the steps in order; a failing step fails the run (the caller's retry and failure handling apply).
+export async function runPipelineSteps(
+  steps: PipelineStep[],
+  input: {
+    output: string;
+    variables: Record<string, string>;
+    citations: Array<{ url: string; title?: string }>;
+    usedWebSearch: boolean;
+    llmModel: string;
+    compileContext: PromptCompileContext;
+    run: (prompt: string) => Promise<StepResult>;
+    clean?: (output: string) => string;
+  },
+): Promise<PipelineResult> {
+  const outputs = [input.output];
+  const result: PipelineResult = { output: input.output, usages: [], toolCalls: [], files: [] };
+  for (const step of steps) {
+    const variables = {
+      ...buildPostPromptVariables({
+        baseVariables: input.variables,
+        output: result.output,
+        citations: input.citations,
+        usedWebSearch: input.usedWebSearch,
+        llmModel: input.llmModel,
+      }),
+      ...pipelineStepVariables(outputs),
+    };
+    const stepResult = await input.run(compilePromptTemplate(step.template, variables, input.compileContext));
+    result.output = input.clean ? input.clean(stepResult.output) : stepResult.output;
+    outputs.push(result.output);
+    result.usages.push(stepResult.llmUsage ?? null);
+    result.toolCalls.push(stepResult.llmToolCalls ?? null);
+    result.files.push(...(stepResult.files ?? []));
+  }
+  return result;
+}
@@ -0,0 +1,26 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { formatPreviousOutputsForPrompt, PREVIOUS_OUTPUT_TEXT_MAX } from "./previous-output";
+
+describe("formatPreviousOutputsForPrompt", () => {
+  it("uses the full latest output and previews for older runs", () => {
+    const block = formatPreviousOutputsForPrompt([
+      { runAt: new Date("2026-03-02T09:00:00.000Z"), outputText: "BTC 91,000", outputPreview: "BTC 91,000" },
+      { runAt: new Date("2026-03-01T09:00:00.000Z"), outputText: "long text", outputPreview: "BTC 89,500" },
+    ]);
+    expect(block).toContain("--- Run at 2026-03-02T09:00:00.000Z (latest) ---\nBTC 91,000");
+    expect(block).toContain("--- Run at 2026-03-01T09:00:00.000Z (summary) ---\nBTC 89,500");
+    expect(block).not.toContain("long text");
+  });
+
+  it("caps long outputs and skips empty ones", () => {
+    const block = formatPreviousOutputsForPrompt([
+      { runAt: new Date("2026-03-02T09:00:00.000Z"), outputText: "x".repeat(PREVIOUS_OUTPUT_TEXT_MAX + 10), outputPreview: null },
+    ]);
+    expect(block).toContain("[truncated]");
+    expect(formatPreviousOutputsForPrompt([{ runAt: new Date(), outputText: " ", outputPreview: null }])).toBe("");
+    expect(formatPreviousOutputsForPrompt([])).toBe("");
+  });
+});
@@ -0,0 +1,36 @@
+import { prisma } from "@/lib/prisma";
+import { GENERATED_RUN_STATUSES } from "@/lib/run-status";
+
+export const PREVIOUS_OUTPUT_RUNS_MAX = 5;
+export const PREVIOUS_OUTPUT_TEXT_MAX = 8000;
+
+type PreviousRun = { runAt: Date; outputText: string | null; outputPreview: string | null };
+
+// Most recent scheduled runs that generated an output, newest first.
+export async function loadPreviousOutputs(jobId: string, count: number): Promise<PreviousRun[]> {
+  return prisma.runHistory.findMany({
+    where: { jobId, isPreview: false, status: { in: GENERATED_RUN_STATUSES }, outputText: { not: null } },
+    orderBy: { runAt: "desc" },
+    take: Math.min(Math.max(1, count), PREVIOUS_OUTPUT_RUNS_MAX),
+    select: { runAt: true, outputText: true, outputPreview: true },
+  });
+}
+
+// Renders previous outputs as a block appended to the prompt: the latest run in full (capped), older runs by their
+// stored preview, so "what changed since last time" prompts stay bounded.
+export function formatPreviousOutputsForPrompt(runs: PreviousRun[]): string {
+  const sections = runs
+    .map((run, index) => {
+      const text = (index === 0 ? run.outputText : (run.outputPreview ?? run.outputText))?.trim();
+      if (!text) {
+        return null;
+      }
+      const capped = text.length > PREVIOUS_OUTPUT_TEXT_MAX ? `${text.slice(0, PREVIOUS_OUTPUT_TEXT_MAX)}\n[truncated]` : text;
+      return `--- Run at ${run.runAt.toISOString()}${index === 0 ? " (latest)" : " (summary)"} ---\n${capped}`;
+    })
+    .filter((section): section is string => !!section);
+  if (!sections.length) {
+    return "";
+  }
+  return `Output of previous runs of this job, newest first (use it to report what changed):\n${sections.join("\n\n")}`;
+}
@@ -1,10 +1,32 @@
 import { PrismaClient } from "@prisma/client";
+import { chaosConfig, chaosDbFault } from "@/lib/chaos";
+import { historyEncryption } from "@/lib/history-encryption";
 
 declare global {
   var prisma: PrismaClient | undefined;
 }
 
-export const prisma = globalThis.prisma ?? new PrismaClient();
+// Run history text is encrypted at rest when HISTORY_ENCRYPTION is on (history-encryption.ts). In chaos mode
+// (chaos.ts) queries fail at the configured db_error rate before reaching the database.
+function createClient() {
+  const client = new PrismaClient().$extends(historyEncryption) as unknown as PrismaClient;
+  if (!chaosConfig()) {
+    return client;
+  }
+  return client.$extends({
+    query: {
+      async $allOperations({ args, query }) {
+        const fault = chaosDbFault();
+        if (fault) {
+          throw fault;
+        }
+        return query(args);
+      },
+    },
+  }) as unknown as PrismaClient;
+}
+
+export const prisma = globalThis.prisma ?? createClient();
 
 if (process.env.NODE_ENV !== "production") {
   globalThis.prisma = prisma;
@@ -0,0 +1,24 @@
+import { describe, expect, it } from "vitest";
+import { compilePromptTemplate } from "./prompt-compile";
+
+describe("compilePromptTemplate", () => {
+  const ctx = { nowIso: "2026-03-02T23:30:00.000Z", timezone: "Asia/Seoul", jobName: "Morning news" };
+
+  it("renders runtime variables in the job time zone", () => {
+    expect(compilePromptTemplate("Summarize news for {{.Date}} ({{.Weekday}}) - {{.JobName}}", {}, ctx)).toBe(
+      "Summarize news for 2026-03-03 (Tuesday) - Morning news",
+    );
+    expect(compilePromptTemplate("{{ date }} {{ time }} {{ weekday }}", {}, ctx)).toBe("2026-03-03 08:30 Tuesday");
+  });
+
+  it("renders the previous run time, or never", () => {
+    expect(compilePromptTemplate("since {{.LastRunAt}}", {}, ctx)).toBe("since never");
+    expect(compilePromptTemplate("since {{.LastRunAt}}", {}, { ...ctx, lastRunAt: new Date("2026-03-01T23:30:00.000Z") })).toBe(
+      "since 2026-03-01T23:30:00.000Z",
+    );
+  });
+
+  it("lets job variables override built-ins", () => {
+    expect(compilePromptTemplate("{{.Date}}", { Date: "yesterday" }, ctx)).toBe("yesterday");
+  });
+});
@@ -1,10 +1,15 @@
+import { renderTemplate, type TemplateFunction } from "@/lib/template-functions";
+
 export type PromptCompileContext = {
   nowIso?: string;
   timezone?: string;
+  jobName?: string;
+  // Previous successful scheduled run, if any.
+  lastRunAt?: Date | null;
+  // Extra template functions for this run, e.g. secret lookups.
+  functions?: Record<string, TemplateFunction>;
 };
 
-const PLACEHOLDER_RE = /{{\s*([A-Za-z0-9_]+)\s*}}/g;
-
 function asDate(value: unknown): Date | null {
   if (typeof value !== "string" || !value.trim()) return null;
   const date = new Date(value);
@@ -24,7 +29,7 @@ function normalizeTimezone(value: unknown): string {
   }
 }
 
-function formatDateParts(now: Date, timezone: string): { date: string; time: string } {
+function formatDateParts(now: Date, timezone: string): { date: string; time: string; weekday: string } {
   const date = new Intl.DateTimeFormat("en-CA", {
     timeZone: timezone,
     year: "numeric",
@@ -39,7 +44,9 @@ function formatDateParts(now: Date, timezone: string): { date: string; time: str
     hour12: false,
   }).format(now);
 
-  return { date, time };
+  const weekday = new Intl.DateTimeFormat("en-US", { timeZone: timezone, weekday: "long" }).format(now);
+
+  return { date, time, weekday };
 }
 
 export function coerceStringVars(value: unknown): Record<string, string> {
@@ -61,19 +68,26 @@ export function compilePromptTemplate(template: string, variables: Record<string
   const timezone = normalizeTimezone(ctx?.timezone);
   const parts = formatDateParts(now, timezone);
 
+  const lastRunAt = ctx?.lastRunAt ? ctx.lastRunAt.toISOString() : "never";
   const builtins: Record<string, string> = {
     now_iso: now.toISOString(),
     timezone,
     date: parts.date,
     time: parts.time,
+    weekday: parts.weekday,
+    job_name: ctx?.jobName ?? "",
+    last_run_at: lastRunAt,
+    // Go-template style names: {{.Date}}, {{.Weekday}}, {{.JobName}}, {{.LastRunAt}}, ...
+    Now: now.toISOString(),
+    Timezone: timezone,
+    Date: parts.date,
+    Time: parts.time,
+    Weekday: parts.weekday,
+    JobName: ctx?.jobName ?? "",
+    LastRunAt: lastRunAt,
   };
 
   const values: Record<string, string> = { ...builtins, ...variables };
 
-  return template.replace(PLACEHOLDER_RE, (full, key: string) => {
-    if (Object.prototype.hasOwnProperty.call(values, key)) {
-      return values[key] ?? "";
-    }
-    return full;
-  });
+  return renderTemplate(template, values, { functions: ctx?.functions });
 }
@@ -0,0 +1,95 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("node:dns/promises", () => ({
+  lookup: vi.fn(async (hostname: string) => [{ address: hostname === "metadata.internal" ? "169.254.169.254" : "93.184.216.34", family: 4 }]),
+}));
+
+import { feedToText, fetchPromptInput, formatInputsForPrompt, htmlToText, inputBodyToText, normalizePromptInputs } from "./prompt-inputs";
+
+const rss = `<?xml version="1.0"?><rss version="2.0"><channel><title>News</title>
+<item><title>First &amp; best</title><link>https://example.com/1</link><pubDate>Mon, 05 Oct 2026 08:00:00 GMT</pubDate>
+<description><![CDATA[<p>Hello <b>world</b></p>]]></description></item>
+<item><title>Second</title><link>https://example.com/2</link></item></channel></rss>`;
+
+const atom = `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<entry><title>Post</title><link rel="alternate" href="https://blog.example/post"/><updated>2026-10-01T00:00:00Z</updated><summary>Short</summary></entry></feed>`;
+
+describe("prompt inputs", () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+    vi.unstubAllEnvs();
+  });
+
+  it("normalizes stored inputs", () => {
+    expect(normalizePromptInputs([{ url: "https://example.com/feed" }])).toEqual([{ url: "https://example.com/feed", label: "" }]);
+    expect(normalizePromptInputs([{ url: "ftp://example.com" }])).toEqual([]);
+    expect(normalizePromptInputs("nope")).toEqual([]);
+  });
+
+  it("strips html to text", () => {
+    expect(htmlToText("<html><head><title>x</title></head><body><script>bad()</script><p>One&nbsp;two</p><p>Three</p></body></html>")).toBe(
+      "One two\nThree",
+    );
+  });
+
+  it("lists rss and atom items", () => {
+    expect(feedToText(rss)).toBe(
+      [
+        "Feed: News",
+        "- First & best (Mon, 05 Oct 2026 08:00:00 GMT)",
+        "  https://example.com/1",
+        "  Hello world",
+        "- Second",
+        "  https://example.com/2",
+      ].join("\n"),
+    );
+    expect(feedToText(atom)).toBe(["Feed: Blog", "- Post (2026-10-01T00:00:00Z)", "  https://blog.example/post", "  Short"].join("\n"));
+    expect(inputBodyToText('{"a":1}', "application/json")).toBe('{"a":1}');
+  });
+
+  it("notes failed fetches instead of throwing", async () => {
+    vi.stubGlobal("fetch", vi.fn(async () => new Response("gone", { status: 404 })));
+    const failed = await fetchPromptInput({ url: "https://example.com/feed", label: "" });
+    expect(failed).toEqual({ url: "https://example.com/feed", label: "example.com", text: "", error: "HTTP 404" });
+
+    vi.stubEnv("DELIVERY_DESTINATION_POLICY", JSON.stringify({ deny: ["example.com"] }));
+    const blocked = await fetchPromptInput({ url: "https://example.com/feed", label: "Feed" });
+    expect(blocked.error).toContain("blocked");
+    expect(formatInputsForPrompt([blocked])).toContain("--- Feed (https://example.com/feed) ---\n[could not fetch: blocked:");
+  });
+
+  it("fetches and caps content", async () => {
+    vi.stubGlobal("fetch", vi.fn(async () => new Response(rss, { headers: { "content-type": "application/rss+xml" } })));
+    const result = await fetchPromptInput({ url: "https://example.com/feed", label: "News" });
+    expect(result.text).toContain("- First & best");
+    expect(formatInputsForPrompt([])).toBe("");
+  });
+
+  it("checks every redirect hop and blocks private addresses", async () => {
+    const fetchMock = vi.fn(async (url: string) =>
+      url === "https://example.com/feed"
+        ? new Response(null, { status: 302, headers: { location: "http://169.254.169.254/latest/meta-data/" } })
+        : new Response("secret", { status: 200 }),
+    );
+    vi.stubGlobal("fetch", fetchMock);
+    const redirected = await fetchPromptInput({ url: "https://example.com/feed", label: "Feed" });
+    expect(redirected).toMatchObject({ text: "", error: "blocked: 169.254.169.254 is a private network address" });
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+    expect(fetchMock.mock.calls[0][1]).toMatchObject({ redirect: "manual" });
+
+    const direct = await fetchPromptInput({ url: "http://metadata.internal/", label: "" });
+    expect(direct.error).toBe("blocked: metadata.internal is a private network address");
+    expect(fetchMock).toHaveBeenCalledTimes(1);
+
+    fetchMock.mockImplementation(async (url: string) =>
+      url === "https://example.com/feed"
+        ? new Response(null, { status: 301, headers: { location: "/moved" } })
+        : new Response("moved here", { status: 200 }),
+    );
+    expect((await fetchPromptInput({ url: "https://example.com/feed", label: "" })).text).toBe("moved here");
+    expect(fetchMock.mock.calls.at(-1)?.[0]).toBe("https://example.com/moved");
+
+    vi.stubEnv("INPUT_ALLOW_PRIVATE_NETWORKS", "1");
+    expect((await fetchPromptInput({ url: "http://metadata.internal/", label: "" })).text).toBe("moved here");
+  });
+});
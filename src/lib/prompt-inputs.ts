@@ -0,0 +1,186 @@
+import { DEFAULT_USER_AGENT } from "@/lib/channel";
+import { checkDestination, parseDestinationPolicy, type DestinationPolicy } from "@/lib/destination-policy";
+import type { UserPlan } from "@/lib/entitlements";
+import { promptInputsSchema, type PromptInput } from "@/lib/validation";
+
+// Job inputs: URLs (web pages, RSS/Atom feeds, plain text or JSON) fetched before the model call and appended to
+// the prompt as context, so "summarize this feed" jobs do not depend on the model's web search. Fetches follow the
+// delivery destination policy, and each input is stripped to text and capped.
+export const INPUT_TEXT_MAX = 8000;
+export const FEED_ITEMS_MAX = 20;
+export const INPUT_REDIRECTS_MAX = 5;
+const INPUT_BYTES_MAX = 2 * 1024 * 1024;
+const DEFAULT_INPUT_TIMEOUT_MS = 10_000;
+
+export type InputResult = { url: string; label: string; text: string; error?: string };
+
+export function normalizePromptInputs(value: unknown): PromptInput[] {
+  const parsed = promptInputsSchema.safeParse(value);
+  return parsed.success ? parsed.data : [];
+}
+
+const ENTITIES: Record<string, string> = { amp: "&", lt: "<", gt: ">", quot: '"', apos: "'", nbsp: " " };
+
+function decodeEntities(text: string) {
+  return text.replace(/&(#x[0-9a-f]+|#\d+|[a-z]+);/gi, (match, entity: string) => {
+    if (entity[0] === "#") {
+      const code = entity[1] === "x" || entity[1] === "X" ? parseInt(entity.slice(2), 16) : parseInt(entity.slice(1), 10);
+      return Number.isFinite(code) && code > 0 && code <= 0x10ffff ? String.fromCodePoint(code) : match;
+    }
+    return ENTITIES[entity.toLowerCase()] ?? match;
+  });
+}
+
+export function htmlToText(html: string) {
+  const text = html
+    .replace(/<!\[CDATA\[([\s\S]*?)\]\]>/g, "$1")
+    .replace(/<(script|style|noscript|svg|head)\b[\s\S]*?<\/\1>/gi, " ")
+    .replace(/<!--[\s\S]*?-->/g, " ")
+    .replace(/<br\s*\/?>/gi, "\n")
+    .replace(/<\/(p|div|li|h[1-6]|tr|section|article|header|footer|blockquote)>/gi, "\n")
+    .replace(/<[^>]+>/g, " ");
+  return decodeEntities(text)
+    .split("\n")
+    .map((line) => line.replace(/[ \t\r\f\v]+/g, " ").trim())
+    .filter(Boolean)
+    .join("\n");
+}
+
+function tagText(xml: string, tag: string) {
+  const match = new RegExp(`<${tag}\\b[^>]*>([\\s\\S]*?)</${tag}>`, "i").exec(xml);
+  return match ? htmlToText(match[1].replace(/<!\[CDATA\[([\s\S]*?)\]\]>/g, "$1")) : "";
+}
+
+function atomLink(xml: string) {
+  const links = [...xml.matchAll(/<link\b([^>]*)\/?>/gi)].map((match) => match[1]);
+  const preferred = links.find((attrs) => !/rel=["'](?!alternate)/i.test(attrs)) ?? links[0];
+  return preferred ? (/href=["']([^"']+)["']/i.exec(preferred)?.[1] ?? "") : "";
+}
+
+export function isFeed(body: string) {
+  return /<(rss|feed|rdf:RDF)\b/i.test(body.slice(0, 2000));
+}
+
+// RSS 2.0/RDF <item>s or Atom <entry>s as "- title (date)\n  link\n  summary" lines, newest as listed by the feed.
+export function feedToText(xml: string, maxItems = FEED_ITEMS_MAX) {
+  const atom = !/<item\b/i.test(xml);
+  const items = [...xml.matchAll(atom ? /<entry\b[\s\S]*?<\/entry>/gi : /<item\b[\s\S]*?<\/item>/gi)].map((match) => match[0]);
+  const feedTitle = tagText(xml.replace(/<(item|entry)\b[\s\S]*$/i, ""), "title");
+  const lines = items.slice(0, maxItems).map((item) => {
+    const title = tagText(item, "title") || "(untitled)";
+    const link = atom ? atomLink(item) : tagText(item, "link") || tagText(item, "guid");
+    const date = tagText(item, atom ? "updated" : "pubDate") || tagText(item, "published") || tagText(item, "dc:date");
+    const summary = tagText(item, atom ? "summary" : "description") || (atom ? tagText(item, "content") : "");
+    return [
+      `- ${title}${date ? ` (${date})` : ""}`,
+      link ? `  ${link}` : "",
+      summary ? `  ${summary.replace(/\n+/g, " ").slice(0, 500)}` : "",
+    ]
+      .filter(Boolean)
+      .join("\n");
+  });
+  return [feedTitle ? `Feed: ${feedTitle}` : "", ...lines].filter(Boolean).join("\n");
+}
+
+export function inputBodyToText(body: string, contentType: string) {
+  if (/xml|rss|atom/i.test(contentType) || isFeed(body)) {
+    return isFeed(body) ? feedToText(body) : htmlToText(body);
+  }
+  if (/html/i.test(contentType) || /^\s*<(!doctype|html)\b/i.test(body)) {
+    return htmlToText(body);
+  }
+  return body.trim();
+}
+
+function capText(text: string) {
+  return text.length > INPUT_TEXT_MAX ? `${text.slice(0, INPUT_TEXT_MAX)}\n[truncated]` : text;
+}
+
+async function readCapped(res: Response) {
+  if (!res.body) {
+    return "";
+  }
+  const reader = res.body.getReader();
+  const chunks: Uint8Array[] = [];
+  let size = 0;
+  while (size < INPUT_BYTES_MAX) {
+    const { done, value } = await reader.read();
+    if (done) {
+      break;
+    }
+    chunks.push(value);
+    size += value.byteLength;
+  }
+  await reader.cancel().catch(() => undefined);
+  return new TextDecoder().decode(Buffer.concat(chunks).subarray(0, INPUT_BYTES_MAX));
+}
+
+// Input bodies end up in the run output, so unlike deliveries, inputs never reach private or link-local addresses
+// (cloud metadata endpoints, internal services) unless INPUT_ALLOW_PRIVATE_NETWORKS=1 is set for self-hosted setups
+// that summarize intranet pages.
+export function inputDestinationPolicy(policy = parseDestinationPolicy(process.env.DELIVERY_DESTINATION_POLICY)): DestinationPolicy | null {
+  if (!policy || process.env.INPUT_ALLOW_PRIVATE_NETWORKS === "1") {
+    return policy;
+  }
+  return { ...policy, blockPrivateNetworks: true };
+}
+
+export async function fetchPromptInput(
+  input: PromptInput,
+  opts?: { plan?: UserPlan | null; userAgent?: string | null },
+): Promise<InputResult> {
+  const label = input.label || new URL(input.url).hostname;
+  const policy = inputDestinationPolicy();
+  const timeoutMs = Number(process.env.INPUT_FETCH_TIMEOUT_MS ?? DEFAULT_INPUT_TIMEOUT_MS);
+  const signal = AbortSignal.timeout(Number.isFinite(timeoutMs) && timeoutMs > 0 ? timeoutMs : DEFAULT_INPUT_TIMEOUT_MS);
+  try {
+    // Redirects are followed by hand so every hop passes the destination check, not just the configured URL.
+    let url = input.url;
+    let res: Response;
+    for (let hop = 0; ; hop++) {
+      const blocked = await checkDestination(url, opts?.plan, policy);
+      if (blocked) {
+        return { url: input.url, label, text: "", error: `blocked: ${blocked}` };
+      }
+      res = await fetch(url, {
+        headers: {
+          "User-Agent": opts?.userAgent?.trim() || DEFAULT_USER_AGENT,
+          Accept: "application/rss+xml, application/atom+xml, text/html, text/plain, application/json;q=0.9, */*;q=0.5",
+        },
+        redirect: "manual",
+        signal,
+      });
+      const location = res.status >= 300 && res.status < 400 ? res.headers.get("location") : null;
+      if (!location) {
+        break;
+      }
+      await res.body?.cancel().catch(() => undefined);
+      if (hop >= INPUT_REDIRECTS_MAX) {
+        return { url: input.url, label, text: "", error: `more than ${INPUT_REDIRECTS_MAX} redirects` };
+      }
+      url = new URL(location, url).toString();
+    }
+    if (!res.ok) {
+      return { url: input.url, label, text: "", error: `HTTP ${res.status}` };
+    }
+    const text = inputBodyToText(await readCapped(res), res.headers.get("content-type") ?? "");
+    return { url: input.url, label, text: capText(text) };
+  } catch (err) {
+    return { url: input.url, label, text: "", error: err instanceof Error ? err.message : String(err) };
+  }
+}
+
+// Inputs are fetched in parallel; a failed one is noted in the block rather than failing the run.
+export async function fetchPromptInputs(inputs: PromptInput[], opts?: { plan?: UserPlan | null; userAgent?: string | null }) {
+  return Promise.all(inputs.map((input) => fetchPromptInput(input, opts)));
+}
+
+export function formatInputsForPrompt(results: InputResult[]) {
+  if (!results.length) {
+    return "";
+  }
+  const sections = results.map(
+    (result) => `--- ${result.label} (${result.url}) ---\n${result.error ? `[could not fetch: ${result.error}]` : result.text || "[empty]"}`,
+  );
+  return `Content fetched from the job's input sources just now (use it as context):\n${sections.join("\n\n")}`;
+}
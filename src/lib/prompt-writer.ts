@@ -1,5 +1,5 @@
 import { generateText } from "ai";
-import { openai } from "@ai-sdk/openai";
+import { openaiProvider } from "@/lib/openai-keys";
 import { extractUsage } from "@/lib/ai-result";
 import { DEFAULT_LLM_MODEL } from "@/lib/llm-defaults";
 import { isRecord } from "@/lib/type-guards";
@@ -55,7 +55,7 @@ function buildEnhancerInstructions(allowStrongerRewrite: boolean): string {
 
 export async function enhancePrompt(input: EnhancePromptInput): Promise<EnhancePromptOutput> {
   const result = await generateText({
-    model: openai(DEFAULT_LLM_MODEL),
+    model: openaiProvider()(DEFAULT_LLM_MODEL),
     system: buildEnhancerInstructions(input.allowStrongerRewrite),
     prompt: input.prompt,
     timeout: 60_000,
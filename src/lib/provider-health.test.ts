@@ -0,0 +1,25 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { exceedsOutageThreshold, isProviderFailure } from "./provider-health";
+
+describe("provider health", () => {
+  it("classifies provider-side failures", () => {
+    expect(isProviderFailure(Object.assign(new Error("Service Unavailable"), { status: 503 }))).toBe(true);
+    expect(isProviderFailure(Object.assign(new Error("Too Many Requests"), { statusCode: 429 }))).toBe(true);
+    expect(isProviderFailure(new Error("Prompt run timed out after 180s (model=gpt-5-mini)."))).toBe(true);
+    expect(isProviderFailure(new TypeError("fetch failed"))).toBe(true);
+    expect(isProviderFailure(Object.assign(new Error("Bad Request"), { status: 400 }))).toBe(false);
+    expect(isProviderFailure(new Error("Web search enabled but no search results"))).toBe(false);
+    expect(isProviderFailure("nope")).toBe(false);
+  });
+
+  it("needs enough calls and a high enough error rate", () => {
+    const config = { errorRate: 0.8, minCalls: 5, windowMinutes: 10, probeSeconds: 300 };
+    expect(exceedsOutageThreshold(4, 4, config)).toBe(false);
+    expect(exceedsOutageThreshold(5, 4, config)).toBe(true);
+    expect(exceedsOutageThreshold(10, 7, config)).toBe(false);
+    expect(exceedsOutageThreshold(10, 10, { ...config, errorRate: 0 })).toBe(false);
+  });
+});
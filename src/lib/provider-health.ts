@@ -0,0 +1,144 @@
+import { prisma } from "@/lib/prisma";
+import { logger } from "@/lib/logger";
+import { isRecord } from "@/lib/type-guards";
+
+const PROVIDER = "openai";
+
+export type OutageConfig = {
+  // Failure ratio (0-1) within the window that switches the worker to degraded mode; 0 disables detection.
+  errorRate: number;
+  minCalls: number;
+  windowMinutes: number;
+  probeSeconds: number;
+};
+
+function envNumber(name: string, fallback: number, min: number) {
+  const value = Number(process.env[name] ?? fallback);
+  return Number.isFinite(value) && value >= min ? value : fallback;
+}
+
+export function outageConfig(): OutageConfig {
+  return {
+    errorRate: Math.min(envNumber("WORKER_OUTAGE_ERROR_RATE", 0.8, 0), 1),
+    minCalls: Math.floor(envNumber("WORKER_OUTAGE_MIN_CALLS", 5, 1)),
+    windowMinutes: Math.floor(envNumber("WORKER_OUTAGE_WINDOW_MINUTES", 10, 1)),
+    probeSeconds: Math.floor(envNumber("WORKER_OUTAGE_PROBE_SECONDS", 300, 10)),
+  };
+}
+
+// Provider-side failures: 5xx, 429, timeouts, and network errors (no HTTP status). Bad requests, empty
+// outputs, and missing search results say nothing about provider availability.
+export function isProviderFailure(err: unknown): boolean {
+  const status = isRecord(err) ? (err.status ?? err.statusCode) : undefined;
+  if (typeof status === "number") {
+    return status === 429 || status >= 500;
+  }
+  if (!(err instanceof Error)) {
+    return false;
+  }
+  const message = err.message.toLowerCase();
+  return (
+    err.name === "AbortError" ||
+    message.includes("timed out") ||
+    message.includes("timeout") ||
+    message.includes("fetch failed") ||
+    message.includes("econnreset") ||
+    message.includes("econnrefused") ||
+    message.includes("enotfound")
+  );
+}
+
+export function exceedsOutageThreshold(calls: number, failures: number, config: OutageConfig) {
+  return config.errorRate > 0 && calls >= config.minCalls && failures / calls >= config.errorRate;
+}
+
+// Posts to OPS_ALERT_WEBHOOK_URL (Slack-compatible `text`) in addition to the error log line.
+export async function alertOps(event: string, text: string, fields: Record<string, unknown> = {}) {
+  logger.error(text, { event, ...fields });
+  const url = process.env.OPS_ALERT_WEBHOOK_URL?.trim();
+  if (!url) {
+    return;
+  }
+  try {
+    const res = await fetch(url, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ text, event, ...fields }),
+    });
+    if (!res.ok) {
+      logger.warn("ops alert failed", { event, status_code: res.status });
+    }
+  } catch (err) {
+    logger.warn("ops alert failed", { event, error: err });
+  }
+}
+
+// Counts one LLM call toward the current window. Crossing the threshold enters degraded mode once
+// (alerting ops); any success leaves it.
+export async function recordLlmOutcome(ok: boolean, config = outageConfig()) {
+  const failed = ok ? 0 : 1;
+  const windowMinutes = config.windowMinutes;
+  try {
+    const rows = await prisma.$queryRaw<Array<{ calls: number; failures: number; degraded_since: Date | null }>>`
+      INSERT INTO provider_health (provider, window_start, calls, failures, updated_at)
+      VALUES (${PROVIDER}, now(), 1, ${failed}, now())
+      ON CONFLICT (provider) DO UPDATE SET
+        calls = CASE WHEN provider_health.window_start < now() - make_interval(mins => ${windowMinutes}::int) THEN 1 ELSE provider_health.calls + 1 END,
+        failures = CASE WHEN provider_health.window_start < now() - make_interval(mins => ${windowMinutes}::int) THEN ${failed} ELSE provider_health.failures + ${failed} END,
+        window_start = CASE WHEN provider_health.window_start < now() - make_interval(mins => ${windowMinutes}::int) THEN now() ELSE provider_health.window_start END,
+        updated_at = now()
+      RETURNING calls, failures, degraded_since;
+    `;
+    const state = rows[0];
+    if (!state) {
+      return;
+    }
+
+    if (ok && state.degraded_since) {
+      const cleared = await prisma.$executeRaw`
+        UPDATE provider_health SET degraded_since = NULL, last_probe_at = NULL, calls = 0, failures = 0, window_start = now()
+        WHERE provider = ${PROVIDER} AND degraded_since IS NOT NULL
+      `;
+      if (cleared) {
+        await alertOps("provider_recovered", `LLM provider ${PROVIDER} recovered; resuming scheduled runs`, {
+          provider: PROVIDER,
+          degraded_since: state.degraded_since,
+        });
+      }
+      return;
+    }
+
+    if (!ok && !state.degraded_since && exceedsOutageThreshold(state.calls, state.failures, config)) {
+      const entered = await prisma.$executeRaw`
+        UPDATE provider_health SET degraded_since = now(), last_probe_at = now()
+        WHERE provider = ${PROVIDER} AND degraded_since IS NULL
+      `;
+      if (entered) {
+        await alertOps("provider_outage", `LLM provider ${PROVIDER} outage detected; pausing LLM dispatch`, {
+          provider: PROVIDER,
+          calls: state.calls,
+          failures: state.failures,
+          window_minutes: windowMinutes,
+        });
+      }
+    }
+  } catch (err) {
+    logger.warn("provider health update failed", { error: err });
+  }
+}
+
+export async function getProviderHealth() {
+  const row = await prisma.providerHealth.findUnique({ where: { provider: PROVIDER } });
+  return { provider: PROVIDER, degradedSince: row?.degradedSince ?? null };
+}
+
+// While degraded, one worker at a time may run a single job as a probe every probeSeconds.
+export async function claimOutageProbe(config = outageConfig()) {
+  const claimed = await prisma.$executeRaw`
+    UPDATE provider_health SET last_probe_at = now()
+    WHERE provider = ${PROVIDER}
+      AND degraded_since IS NOT NULL
+      AND (last_probe_at IS NULL OR last_probe_at < now() - make_interval(secs => ${config.probeSeconds}::int))
+  `;
+  return claimed > 0;
+}
@@ -0,0 +1,69 @@
+import { createHash } from "node:crypto";
+import { logger, type Logger } from "@/lib/logger";
+
+// Operator QA sampling: QA_MIRROR_PERCENT of successful deliveries from users who opted in (users.qa_sharing)
+// are copied, scrubbed, to QA_MIRROR_WEBHOOK_URL (Slack-compatible `text`) for manual review of what the
+// service actually sends. Secrets are already redacted from outputs; the scrub also drops contact details,
+// URL paths and token-like strings. Job names and channel destinations are never included.
+
+const QA_TEXT_MAX = 3500;
+
+export type QaMirrorConfig = { webhookUrl: string; percent: number };
+
+export function qaMirrorConfig(): QaMirrorConfig | null {
+  const webhookUrl = process.env.QA_MIRROR_WEBHOOK_URL?.trim();
+  const percent = Number(process.env.QA_MIRROR_PERCENT ?? 0);
+  if (!webhookUrl || !Number.isFinite(percent) || percent <= 0) {
+    return null;
+  }
+  return { webhookUrl, percent: Math.min(percent, 100) };
+}
+
+// Salted so the QA sample is independent of quality scoring and the canary split, which bucket the same run ids.
+export function isQaSampled(runId: string, percent: number) {
+  if (percent >= 100) {
+    return true;
+  }
+  return createHash("sha256").update(`qa:${runId}`).digest().readUInt16BE(0) % 100 < percent;
+}
+
+export function scrubForQa(text: string) {
+  const scrubbed = text
+    .replace(/[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}/g, "[email]")
+    .replace(/\b(https?:\/\/[^/\s)>\]]+)[^\s)>\]]*/g, "$1/[…]")
+    .replace(/(?<![\w+])\+?\d{0,3}[\s.-]?\(?\d{2,4}\)?[\s.-]\d{3,4}[\s.-]\d{3,4}(?!\w)/g, "[phone]")
+    .replace(/\b[A-Za-z0-9_-]{32,}\b/g, "[token]");
+  return scrubbed.length > QA_TEXT_MAX ? `${scrubbed.slice(0, QA_TEXT_MAX)}\n[truncated]` : scrubbed;
+}
+
+export async function mirrorToQa(
+  run: { runId: string; channelType: string; llmModel: string | null; output: string },
+  log: Logger = logger,
+  config = qaMirrorConfig(),
+) {
+  if (!config || !isQaSampled(run.runId, config.percent)) {
+    return false;
+  }
+  try {
+    const res = await fetch(config.webhookUrl, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        text: scrubForQa(run.output),
+        event: "qa_sample",
+        run_id: run.runId,
+        channel_type: run.channelType,
+        llm_model: run.llmModel,
+      }),
+      signal: AbortSignal.timeout(10_000),
+    });
+    if (!res.ok) {
+      log.warn("qa sample not mirrored", { status_code: res.status });
+      return false;
+    }
+    return true;
+  } catch (err) {
+    log.warn("qa sample not mirrored", { error: err });
+    return false;
+  }
+}
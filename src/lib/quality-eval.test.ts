@@ -0,0 +1,55 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { detectQualityDrop, isSampledRun, judgePrompt, parseJudgeVerdict, qualityDropThreshold } from "./quality-eval";
+
+const runs = (promptVersionId: string, llmModel: string, scores: number[]) =>
+  scores.map((qualityScore) => ({ promptVersionId, llmModel, qualityScore }));
+
+describe("quality sampling", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("samples a stable share of runs", () => {
+    expect(isSampledRun("run-1", 0)).toBe(false);
+    expect(isSampledRun("run-1", 100)).toBe(true);
+    expect(isSampledRun("run-1", 30)).toBe(isSampledRun("run-1", 30));
+    const sampled = Array.from({ length: 1000 }, (_, i) => isSampledRun(`run-${i}`, 30)).filter(Boolean).length;
+    expect(sampled).toBeGreaterThan(220);
+    expect(sampled).toBeLessThan(380);
+  });
+
+  it("parses judge replies and rejects scores out of range", () => {
+    expect(parseJudgeVerdict('Sure: {"score": 7, "reason": "Misses sources."}')).toEqual({ score: 7, reason: "Misses sources." });
+    expect(parseJudgeVerdict('{"score": "8.25"}')).toEqual({ score: 8.3, reason: "" });
+    expect(parseJudgeVerdict('{"score": 11}')).toBeNull();
+    expect(parseJudgeVerdict("seven")).toBeNull();
+    expect(judgePrompt("Cites sources", "x".repeat(13_000))).toContain("[output truncated]");
+  });
+
+  it("flags a drop after a prompt or model change", () => {
+    const drop = detectQualityDrop([...runs("v2", "gpt-5-mini", [5, 6, 5]), ...runs("v1", "gpt-5-mini", [8, 8, 9, 8])]);
+    expect(drop).toMatchObject({ degraded: true, currentAverage: 5.3, baselineAverage: 8.3, key: "v2|gpt-5-mini" });
+    expect(drop?.previous).toEqual({ promptVersionId: "v1", llmModel: "gpt-5-mini" });
+
+    const modelChange = detectQualityDrop([...runs("v1", "gpt-5", [8, 7, 8]), ...runs("v1", "gpt-5-mini", [8, 8, 8])]);
+    expect(modelChange?.degraded).toBe(false);
+  });
+
+  it("waits for enough samples on both sides and only compares adjacent combinations", () => {
+    expect(detectQualityDrop(runs("v1", "gpt-5-mini", [3, 3, 3, 3]))).toBeNull();
+    expect(detectQualityDrop([...runs("v2", "gpt-5-mini", [3, 3]), ...runs("v1", "gpt-5-mini", [9, 9, 9])])).toBeNull();
+    const history = [...runs("v3", "gpt-5-mini", [6, 6, 6]), ...runs("v2", "gpt-5-mini", [6, 7, 6]), ...runs("v1", "gpt-5-mini", [9, 9, 9])];
+    expect(detectQualityDrop(history)?.degraded).toBe(false);
+  });
+
+  it("reads the drop threshold from the environment", () => {
+    vi.stubEnv("QUALITY_DROP_THRESHOLD", "3");
+    expect(qualityDropThreshold()).toBe(3);
+    expect(detectQualityDrop([...runs("v2", "m", [6, 6, 6]), ...runs("v1", "m", [8, 8, 8])])?.degraded).toBe(false);
+    vi.stubEnv("QUALITY_DROP_THRESHOLD", "nope");
+    expect(qualityDropThreshold()).toBe(1.5);
+  });
+});
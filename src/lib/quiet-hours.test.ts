@@ -0,0 +1,27 @@
+import { describe, expect, it } from "vitest";
+import { quietWindowEnd } from "./quiet-hours";
+
+describe("quiet hours", () => {
+  it("defers runs inside an overnight window to its end in the job time zone", () => {
+    const config = { start: "22:00", end: "07:00", timezone: "Europe/Berlin" };
+    // 01:30 Berlin (CEST, UTC+2)
+    expect(quietWindowEnd(new Date("2026-07-01T23:30:00.000Z"), config)?.toISOString()).toBe("2026-07-02T05:00:00.000Z");
+    // 23:00 Berlin, window ends the next morning
+    expect(quietWindowEnd(new Date("2026-07-01T21:00:00.000Z"), config)?.toISOString()).toBe("2026-07-02T05:00:00.000Z");
+    // 12:00 Berlin is outside the window
+    expect(quietWindowEnd(new Date("2026-07-01T10:00:00.000Z"), config)).toBeNull();
+  });
+
+  it("supports same-day windows and ignores empty ones", () => {
+    expect(quietWindowEnd(new Date("2026-07-01T12:30:00.000Z"), { start: "12:00", end: "13:00" })?.toISOString()).toBe(
+      "2026-07-01T13:00:00.000Z",
+    );
+    expect(quietWindowEnd(new Date("2026-07-01T12:30:00.000Z"), { start: "12:00", end: "12:00" })).toBeNull();
+    expect(quietWindowEnd(new Date("2026-07-01T12:30:00.000Z"), {})).toBeNull();
+  });
+
+  it("blacks out whole dates and chains into following quiet hours", () => {
+    const config = { start: "00:00", end: "06:00", dates: ["2026-12-25"] };
+    expect(quietWindowEnd(new Date("2026-12-25T15:00:00.000Z"), config)?.toISOString()).toBe("2026-12-26T06:00:00.000Z");
+  });
+});
@@ -0,0 +1,62 @@
+import { getPartsInTimeZone, zonedWallTimeToUtc } from "@/lib/timezone";
+
+export type QuietHoursConfig = {
+  // "HH:mm" wall-clock times in timezone; the window may wrap past midnight (22:00-07:00).
+  start?: string | null;
+  end?: string | null;
+  // "YYYY-MM-DD" dates in timezone that are blacked out for the whole day.
+  dates?: string[] | null;
+  timezone?: string | null;
+};
+
+function toMinutes(value: string | null | undefined): number | null {
+  const match = /^([01]\d|2[0-3]):([0-5]\d)$/.exec(value ?? "");
+  return match ? Number(match[1]) * 60 + Number(match[2]) : null;
+}
+
+function pad(n: number) {
+  return String(n).padStart(2, "0");
+}
+
+// End of the single blackout window (quiet hours or blackout date) containing `at`, or null.
+function windowEnd(at: Date, config: QuietHoursConfig, timeZone: string): Date | null {
+  const local = getPartsInTimeZone(at, timeZone);
+  const localDate = `${local.year}-${pad(local.month)}-${pad(local.day)}`;
+  if (config.dates?.includes(localDate)) {
+    return zonedWallTimeToUtc(local.year, local.month, local.day + 1, 0, 0, timeZone);
+  }
+
+  const start = toMinutes(config.start);
+  const end = toMinutes(config.end);
+  if (start == null || end == null || start === end) {
+    return null;
+  }
+  const minutes = local.hour * 60 + local.minute;
+  const endAt = (dayOffset: number) =>
+    zonedWallTimeToUtc(local.year, local.month, local.day + dayOffset, Math.floor(end / 60), end % 60, timeZone);
+
+  if (start < end) {
+    return minutes >= start && minutes < end ? endAt(0) : null;
+  }
+  if (minutes >= start) {
+    return endAt(1);
+  }
+  return minutes < end ? endAt(0) : null;
+}
+
+// Returns when the blackout covering `at` is over, following back-to-back windows
+// (e.g. quiet hours that run into a blackout date), or null if `at` is not blacked out.
+export function quietWindowEnd(at: Date, config: QuietHoursConfig): Date | null {
+  const timeZone = config.timezone?.trim() || "UTC";
+  let until: Date | null = null;
+  let cursor = at;
+  for (let i = 0; i < 400; i++) {
+    const next = windowEnd(cursor, config, timeZone);
+    if (!next || next.getTime() <= cursor.getTime()) {
+      break;
+    }
+    until = next;
+    cursor = next;
+  }
+  return until;
+}
@@ -0,0 +1,37 @@
+import { describe, expect, it } from "vitest";
+import { formatRepliesForPrompt, parseIncomingReply } from "./replies";
+
+describe("replies", () => {
+  it("parses Telegram replies to the bot message", () => {
+    const reply = parseIncomingReply({
+      update_id: 1,
+      message: { text: "Can you add EUR rates?", from: { username: "alice" }, reply_to_message: { message_id: 9 } },
+    });
+    expect(reply).toEqual({ source: "telegram", author: "alice", text: "Can you add EUR rates?" });
+  });
+
+  it("ignores Telegram messages that are not replies", () => {
+    expect(parseIncomingReply({ update_id: 1, message: { text: "hi", from: { username: "alice" } } })).toBeNull();
+  });
+
+  it("parses relayed Discord messages and skips bot authors", () => {
+    expect(parseIncomingReply({ content: "more detail please", author: { username: "bob" } })).toEqual({
+      source: "discord",
+      author: "bob",
+      text: "more detail please",
+    });
+    expect(parseIncomingReply({ content: "echo", author: { username: "hook", bot: true } })).toBeNull();
+  });
+
+  it("parses generic bodies and rejects empty text", () => {
+    expect(parseIncomingReply({ text: " shorter please " })).toEqual({ source: "generic", author: null, text: "shorter please" });
+    expect(parseIncomingReply({ text: "  " })).toBeNull();
+    expect(parseIncomingReply("nope")).toBeNull();
+  });
+
+  it("formats replies for the prompt", () => {
+    expect(formatRepliesForPrompt([])).toBe("");
+    const text = formatRepliesForPrompt([{ author: null, text: "add charts", createdAt: new Date("2026-03-01T08:00:00Z") }]);
+    expect(text).toContain("- [2026-03-01T08:00:00.000Z] reader: add charts");
+  });
+});
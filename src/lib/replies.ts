@@ -0,0 +1,56 @@
+export type IncomingReply = {
+  source: "telegram" | "discord" | "generic";
+  author: string | null;
+  text: string;
+};
+
+export const REPLY_TEXT_MAX = 2000;
+export const REPLIES_PER_RUN_MAX = 20;
+
+function asRecord(value: unknown): Record<string, unknown> | null {
+  return value && typeof value === "object" && !Array.isArray(value) ? (value as Record<string, unknown>) : null;
+}
+
+function asText(value: unknown): string | null {
+  return typeof value === "string" && value.trim() ? value.trim().slice(0, REPLY_TEXT_MAX) : null;
+}
+
+// Accepts a Telegram bot update, a Discord message object (as relayed by a bot), or a plain { text, author } body.
+// Returns null when the payload is not a reply we should keep (e.g. Telegram messages not replying to the bot).
+export function parseIncomingReply(body: unknown): IncomingReply | null {
+  const root = asRecord(body);
+  if (!root) {
+    return null;
+  }
+
+  const telegramMessage = asRecord(root.message) ?? asRecord(root.edited_message);
+  if (telegramMessage && "update_id" in root) {
+    const text = asText(telegramMessage.text);
+    if (!text || !asRecord(telegramMessage.reply_to_message)) {
+      return null;
+    }
+    const from = asRecord(telegramMessage.from);
+    return { source: "telegram", author: asText(from?.username) ?? asText(from?.first_name), text };
+  }
+
+  if ("content" in root && "author" in root) {
+    const text = asText(root.content);
+    const author = asRecord(root.author);
+    if (!text || author?.bot === true) {
+      return null;
+    }
+    return { source: "discord", author: asText(author?.username), text };
+  }
+
+  const text = asText(root.text);
+  return text ? { source: "generic", author: asText(root.author), text } : null;
+}
+
+// Renders captured replies as a block appended to the next run's prompt.
+export function formatRepliesForPrompt(replies: Array<{ author: string | null; text: string; createdAt: Date }>): string {
+  if (!replies.length) {
+    return "";
+  }
+  const lines = replies.map((reply) => `- [${reply.createdAt.toISOString()}] ${reply.author ?? "reader"}: ${reply.text}`);
+  return `Replies from readers since the previous delivery (consider them in this run):\n${lines.join("\n")}`;
+}
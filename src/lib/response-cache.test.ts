@@ -0,0 +1,48 @@
+import { describe, expect, it, vi } from "vitest";
+
+const { findUnique, upsert } = vi.hoisted(() => ({ findUnique: vi.fn(), upsert: vi.fn() }));
+vi.mock("@/lib/prisma", () => ({ prisma: { llmResponseCache: { findUnique, upsert } } }));
+
+import { readCachedResponse, responseCacheKey, writeCachedResponse } from "./response-cache";
+
+const base = { userId: "u1", model: "gpt-5-mini", prompt: "Summarize today's news", useWebSearch: false };
+
+describe("response cache", () => {
+  it("keys on user, model, prompt and the settings that change the answer", () => {
+    const key = responseCacheKey(base);
+    expect(responseCacheKey({ ...base })).toBe(key);
+    expect(responseCacheKey({ ...base, userId: "u2" })).not.toBe(key);
+    expect(responseCacheKey({ ...base, model: "openrouter/gpt-5-mini" })).not.toBe(key);
+    expect(responseCacheKey({ ...base, prompt: "Summarize today's news." })).not.toBe(key);
+    expect(responseCacheKey({ ...base, useWebSearch: true })).not.toBe(key);
+    expect(responseCacheKey({ ...base, generation: { temperature: 0.2 } })).not.toBe(key);
+  });
+
+  it("reuses unexpired entries without token usage", async () => {
+    const now = new Date("2026-05-10T09:00:00Z");
+    findUnique.mockResolvedValueOnce({
+      response: { output: "cached", usedWebSearch: false, citations: [], llmModel: "gpt-5-mini" },
+      expiresAt: new Date("2026-05-10T09:30:00Z"),
+    });
+    expect(await readCachedResponse("k", now)).toEqual({ output: "cached", usedWebSearch: false, citations: [], llmModel: "gpt-5-mini" });
+
+    findUnique.mockResolvedValueOnce({ response: { output: "old" }, expiresAt: now });
+    expect(await readCachedResponse("k", now)).toBeNull();
+  });
+
+  it("stores outputs for the TTL but skips ones with generated files", async () => {
+    const now = new Date("2026-05-10T09:00:00Z");
+    const result = { output: "fresh", usedWebSearch: false, citations: [], llmModel: "gpt-5-mini", llmUsage: { inputTokens: 5 } };
+    await writeCachedResponse("k", result, 30, now);
+    expect(upsert).toHaveBeenCalledWith(
+      expect.objectContaining({
+        where: { key: "k" },
+        update: { response: expect.not.objectContaining({ llmUsage: expect.anything() }), expiresAt: new Date("2026-05-10T09:30:00Z") },
+      }),
+    );
+
+    upsert.mockClear();
+    await writeCachedResponse("k", { ...result, files: [{ mediaType: "image/png", data: new Uint8Array() }] }, 30, now);
+    expect(upsert).not.toHaveBeenCalled();
+  });
+});
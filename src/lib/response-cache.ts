@@ -0,0 +1,72 @@
+import { createHash } from "node:crypto";
+import { Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { clock } from "@/lib/clock";
+import { isOpenRouterModel } from "@/lib/openrouter";
+import type { RunPromptResult } from "@/lib/llm";
+import type { LlmTool } from "@/lib/llm-tools";
+import type { GenerationParams } from "@/lib/generation-params";
+
+// Optional per-job response cache: a model call whose provider, model and rendered prompt (plus the settings that
+// change the answer) match a cached one from the same user within the job's TTL reuses that output instead of
+// paying for the call again, e.g. several jobs sharing a prompt or a run retried after a delivery failure.
+// Cached hits carry no token usage, so they cost nothing. Outputs with generated files are never cached.
+
+export const RESPONSE_CACHE_TTL_MAX_MINUTES = 7 * 24 * 60;
+
+export type ResponseCacheKeyInput = {
+  userId: string;
+  model: string;
+  prompt: string;
+  systemPrompt?: string | null;
+  useWebSearch: boolean;
+  tools?: LlmTool[];
+  generation?: GenerationParams;
+};
+
+export function responseCacheKey(input: ResponseCacheKeyInput) {
+  const provider = isOpenRouterModel(input.model) ? "openrouter" : "openai";
+  const parts = [
+    input.userId,
+    provider,
+    input.model.trim(),
+    createHash("sha256").update(input.prompt).digest("hex"),
+    input.systemPrompt ?? "",
+    input.useWebSearch,
+    input.tools ?? [],
+    input.generation ?? {},
+  ];
+  return createHash("sha256").update(JSON.stringify(parts)).digest("hex");
+}
+
+type CachedResponse = Pick<RunPromptResult, "output" | "usedWebSearch" | "citations" | "llmModel" | "servedModel">;
+
+export async function readCachedResponse(key: string, now = clock().now()): Promise<RunPromptResult | null> {
+  const row = await prisma.llmResponseCache.findUnique({ where: { key }, select: { response: true, expiresAt: true } });
+  if (!row || row.expiresAt <= now) {
+    return null;
+  }
+  const cached = row.response as CachedResponse;
+  return { ...cached, citations: cached.citations ?? [] };
+}
+
+export async function writeCachedResponse(key: string, result: RunPromptResult, ttlMinutes: number, now = clock().now()) {
+  if (result.files?.length) {
+    return;
+  }
+  const response: CachedResponse = {
+    output: result.output,
+    usedWebSearch: result.usedWebSearch,
+    citations: result.citations,
+    llmModel: result.llmModel,
+    servedModel: result.servedModel ?? null,
+  };
+  const expiresAt = new Date(now.getTime() + Math.min(ttlMinutes, RESPONSE_CACHE_TTL_MAX_MINUTES) * 60_000);
+  const data = { response: response as Prisma.InputJsonValue, expiresAt };
+  await prisma.llmResponseCache.upsert({ where: { key }, create: { key, ...data }, update: data });
+}
+
+export async function pruneResponseCache(now = clock().now()) {
+  const deleted = await prisma.llmResponseCache.deleteMany({ where: { expiresAt: { lte: now } } });
+  return deleted.count;
+}
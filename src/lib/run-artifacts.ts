@@ -0,0 +1,90 @@
+import { prisma } from "@/lib/prisma";
+import { signToken } from "@/lib/crypto";
+import { getAppUrl } from "@/lib/stripe";
+import { logger } from "@/lib/logger";
+import type { GeneratedRunFile } from "@/lib/ai-result";
+import type { ChannelAttachment } from "@/lib/channel";
+import { clock } from "@/lib/clock";
+
+const DEFAULT_TTL_DAYS = 30;
+const DEFAULT_MAX_BYTES = 10 * 1024 * 1024;
+const MAX_ARTIFACTS_PER_RUN = 10;
+
+const EXTENSIONS: Record<string, string> = {
+  "image/png": "png",
+  "image/jpeg": "jpg",
+  "image/gif": "gif",
+  "image/webp": "webp",
+  "application/pdf": "pdf",
+  "application/json": "json",
+  "text/plain": "txt",
+  "text/csv": "csv",
+  "audio/ogg": "ogg",
+  "audio/mpeg": "mp3",
+};
+
+function envPositiveInt(name: string, fallback: number) {
+  const value = Number(process.env[name] ?? fallback);
+  return Number.isFinite(value) && value > 0 ? Math.floor(value) : fallback;
+}
+
+export function artifactName(index: number, mediaType: string) {
+  return `artifact-${index + 1}.${EXTENSIONS[mediaType] ?? "bin"}`;
+}
+
+// Signed download URL, so links in Discord/Telegram messages work without a session.
+export function artifactUrl(id: string) {
+  return `${getAppUrl()}/api/artifacts/${id}?token=${signToken("run-artifact", id)}`;
+}
+
+// Stores generated files for a run; oversized files are skipped (and logged) rather than failing the run.
+export async function saveRunArtifacts(runHistoryId: string, files: GeneratedRunFile[], now = clock().now()) {
+  const maxBytes = envPositiveInt("RUN_ARTIFACT_MAX_BYTES", DEFAULT_MAX_BYTES);
+  const expiresAt = new Date(now.getTime() + envPositiveInt("RUN_ARTIFACT_TTL_DAYS", DEFAULT_TTL_DAYS) * 24 * 60 * 60 * 1000);
+  const kept = files.slice(0, MAX_ARTIFACTS_PER_RUN).filter((file) => {
+    if (file.data.byteLength <= maxBytes) {
+      return true;
+    }
+    logger.warn("run artifact skipped: too large", { run_id: runHistoryId, size_bytes: file.data.byteLength, max_bytes: maxBytes });
+    return false;
+  });
+  if (!kept.length) {
+    return;
+  }
+  await prisma.runArtifact.createMany({
+    data: kept.map((file, index) => ({
+      runHistoryId,
+      name: artifactName(index, file.mediaType),
+      mediaType: file.mediaType,
+      sizeBytes: file.data.byteLength,
+      content: Buffer.from(file.data),
+      expiresAt,
+    })),
+  });
+}
+
+export async function loadRunAttachments(runHistoryId: string, now = clock().now()): Promise<ChannelAttachment[]> {
+  const rows = await prisma.runArtifact.findMany({
+    where: { runHistoryId, expiresAt: { gt: now } },
+    select: { id: true, name: true, mediaType: true, sizeBytes: true },
+    orderBy: { name: "asc" },
+  });
+  // Audio is uploaded rather than linked (Telegram voice messages need the bytes), so only audio content is loaded.
+  const audioIds = rows.filter((row) => row.mediaType.startsWith("audio/")).map((row) => row.id);
+  const audio = audioIds.length
+    ? await prisma.runArtifact.findMany({ where: { id: { in: audioIds } }, select: { id: true, content: true } })
+    : [];
+  const content = new Map(audio.map((row) => [row.id, new Uint8Array(row.content)]));
+  return rows.map((row) => ({
+    name: row.name,
+    mediaType: row.mediaType,
+    sizeBytes: row.sizeBytes,
+    url: artifactUrl(row.id),
+    ...(content.has(row.id) ? { content: content.get(row.id) } : {}),
+  }));
+}
+
+export async function pruneExpiredArtifacts(now = clock().now()) {
+  const deleted = await prisma.runArtifact.deleteMany({ where: { expiresAt: { lte: now } } });
+  return deleted.count;
+}
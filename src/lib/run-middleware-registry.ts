@@ -0,0 +1,8 @@
+import type { RunMiddleware } from "@/lib/run-middleware";
+
+// Deployment-specific run middleware, applied in this order to every scheduled run. Keep deployment code in
+// its own modules and list it here, e.g.:
+//
+//   import { billingMiddleware } from "@/lib/acme-billing";
+//   export const deploymentMiddleware: RunMiddleware[] = [billingMiddleware];
+export const deploymentMiddleware: RunMiddleware[] = [];
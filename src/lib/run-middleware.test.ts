@@ -0,0 +1,60 @@
+import { describe, expect, it, vi } from "vitest";
+import { applyPostLlm, applyPreDelivery, applyPreLlm, notifyPostDelivery, type RunContext, type RunMiddleware } from "./run-middleware";
+
+const log = { warn: vi.fn() } as unknown as RunContext["log"];
+const ctx: RunContext = {
+  job: { id: "job-1", name: "Daily", userId: "user-1", tags: [], channelType: "discord" },
+  runHistoryId: "run-1",
+  scheduledFor: new Date("2026-04-12T09:00:00Z"),
+  manual: false,
+  log,
+};
+
+describe("run middleware", () => {
+  it("chains hooks in order and keeps the value when a hook returns nothing", async () => {
+    const chain: RunMiddleware[] = [
+      { name: "prefix", preLlm: (_, input) => ({ prompt: `Be brief.\n${input.prompt}` }) },
+      { name: "observer", preLlm: () => undefined },
+      { name: "suffix", preLlm: async (_, input) => ({ prompt: `${input.prompt}\nThanks.` }) },
+    ];
+    await expect(applyPreLlm(ctx, { prompt: "Summarize" }, chain)).resolves.toEqual({ prompt: "Be brief.\nSummarize\nThanks." });
+
+    const filter: RunMiddleware = { name: "filter", postLlm: (_, input) => ({ ...input, output: input.output.replace(/secret/g, "***") }) };
+    await expect(applyPostLlm(ctx, { output: "a secret", llmModel: "gpt-5-mini" }, [filter])).resolves.toEqual({
+      output: "a ***",
+      llmModel: "gpt-5-mini",
+    });
+  });
+
+  it("lets pre-delivery hooks skip and names the failing hook", async () => {
+    const quiet: RunMiddleware = { name: "quiet", preDelivery: (_, input) => ({ ...input, skip: "billing_hold" }) };
+    await expect(applyPreDelivery(ctx, { title: "t", body: "b" }, [quiet])).resolves.toMatchObject({ skip: "billing_hold" });
+
+    const broken: RunMiddleware = {
+      name: "billing",
+      preDelivery: () => {
+        throw new Error("quota service down");
+      },
+    };
+    await expect(applyPreDelivery(ctx, { title: "t", body: "b" }, [broken])).rejects.toThrow(
+      "Run middleware billing (preDelivery) failed: quota service down",
+    );
+  });
+
+  it("logs post-delivery errors without throwing", async () => {
+    const seen: unknown[] = [];
+    const chain: RunMiddleware[] = [
+      {
+        name: "broken",
+        postDelivery: () => {
+          throw new Error("nope");
+        },
+      },
+      { name: "audit", postDelivery: (_, result) => void seen.push(result) },
+    ];
+    const result = { delivered: true, error: null, attempts: 1, deferred: false };
+    await notifyPostDelivery(ctx, result, chain);
+    expect(seen).toEqual([result]);
+    expect(log.warn).toHaveBeenCalledWith("run middleware failed", expect.objectContaining({ middleware: "broken" }));
+  });
+});
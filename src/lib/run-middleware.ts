@@ -0,0 +1,92 @@
+import type { ChannelType } from "@prisma/client";
+import type { Logger } from "@/lib/logger";
+import { deploymentMiddleware } from "@/lib/run-middleware-registry";
+
+// Middleware around a scheduled run, for deployment-specific behavior (filters, billing hooks, extra logging)
+// that should not live in the runner itself. Hooks run in registration order and each one sees the previous
+// one's result. A throwing pre-LLM, post-LLM or pre-delivery hook fails the run like any other run error;
+// post-delivery hooks only observe, so their errors are logged and ignored.
+
+export type RunContext = {
+  job: { id: string; name: string; userId: string; tags: string[]; channelType: ChannelType };
+  runHistoryId: string;
+  scheduledFor: Date;
+  // true for run-now requests.
+  manual: boolean;
+  log: Logger;
+};
+
+export type PreLlmInput = { prompt: string };
+export type PostLlmInput = { output: string; llmModel: string | null };
+// skip: a short reason stored as the run's delivery skip reason; the output is kept but not delivered.
+export type PreDeliveryInput = { title: string; body: string; skip?: string | null };
+export type PostDeliveryResult = { delivered: boolean; error: string | null; attempts: number; deferred: boolean };
+
+export interface RunMiddleware {
+  name: string;
+  preLlm?(ctx: RunContext, input: PreLlmInput): Promise<PreLlmInput | void> | PreLlmInput | void;
+  postLlm?(ctx: RunContext, input: PostLlmInput): Promise<PostLlmInput | void> | PostLlmInput | void;
+  preDelivery?(ctx: RunContext, input: PreDeliveryInput): Promise<PreDeliveryInput | void> | PreDeliveryInput | void;
+  postDelivery?(ctx: RunContext, result: PostDeliveryResult): Promise<void> | void;
+}
+
+const registered: RunMiddleware[] = [];
+
+// Adds middleware after the deployment registry's entries (useful for tests and plugins loaded at startup).
+export function registerRunMiddleware(middleware: RunMiddleware) {
+  registered.push(middleware);
+}
+
+export function clearRunMiddleware() {
+  registered.length = 0;
+}
+
+export function runMiddleware(): RunMiddleware[] {
+  return [...deploymentMiddleware, ...registered];
+}
+
+async function chain<T>(
+  hook: "preLlm" | "postLlm" | "preDelivery",
+  ctx: RunContext,
+  input: T,
+  middleware = runMiddleware(),
+): Promise<T> {
+  let value = input;
+  for (const entry of middleware) {
+    const fn = entry[hook] as ((ctx: RunContext, input: T) => Promise<T | void> | T | void) | undefined;
+    if (!fn) {
+      continue;
+    }
+    try {
+      value = (await fn.call(entry, ctx, value)) ?? value;
+    } catch (err) {
+      throw new Error(`Run middleware ${entry.name} (${hook}) failed: ${err instanceof Error ? err.message : String(err)}`);
+    }
+  }
+  return value;
+}
+
+export function applyPreLlm(ctx: RunContext, input: PreLlmInput, middleware?: RunMiddleware[]) {
+  return chain("preLlm", ctx, input, middleware);
+}
+
+export function applyPostLlm(ctx: RunContext, input: PostLlmInput, middleware?: RunMiddleware[]) {
+  return chain("postLlm", ctx, input, middleware);
+}
+
+export function applyPreDelivery(ctx: RunContext, input: PreDeliveryInput, middleware?: RunMiddleware[]) {
+  return chain("preDelivery", ctx, input, middleware);
+}
+
+export async function notifyPostDelivery(ctx: RunContext, result: PostDeliveryResult, middleware = runMiddleware()) {
+  for (const entry of middleware) {
+    if (!entry.postDelivery) {
+      continue;
+    }
+    try {
+      await entry.postDelivery(ctx, result);
+    } catch (err) {
+      ctx.log.warn("run middleware failed", { middleware: entry.name, hook: "postDelivery", error: err });
+    }
+  }
+}
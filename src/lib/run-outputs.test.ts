@@ -0,0 +1,50 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { upsert, findUnique } = vi.hoisted(() => ({ upsert: vi.fn(), findUnique: vi.fn() }));
+
+vi.mock("@/lib/prisma", () => ({ prisma: { runOutput: { upsert, findUnique } } }));
+
+import { capUtf8, compressOutput, decompressOutput, loadRunOutput, saveRunOutput } from "./run-outputs";
+
+describe("run outputs", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("caps text by UTF-8 bytes without splitting characters", () => {
+    expect(capUtf8("abc", 3)).toEqual({ text: "abc", sizeBytes: 3, truncated: false });
+    expect(capUtf8("aé€", 5)).toEqual({ text: "aé", sizeBytes: 3, truncated: true });
+    expect(capUtf8("€€", 2)).toEqual({ text: "", sizeBytes: 0, truncated: true });
+  });
+
+  it("round-trips compressed outputs and applies RUN_OUTPUT_MAX_BYTES", () => {
+    const text = "# Digest\n\n".concat("- item\n".repeat(2000));
+    const stored = compressOutput(text);
+    expect(stored.truncated).toBe(false);
+    expect(stored.content.length).toBeLessThan(text.length / 10);
+    expect(decompressOutput(stored.content)).toBe(text);
+
+    vi.stubEnv("RUN_OUTPUT_MAX_BYTES", "100");
+    const capped = compressOutput(text);
+    expect(capped).toMatchObject({ sizeBytes: 100, truncated: true });
+    expect(decompressOutput(capped.content)).toBe(text.slice(0, 100));
+  });
+
+  it("seals the stored output when history encryption is on", async () => {
+    vi.stubEnv("SECRET_BACKEND", "env");
+    vi.stubEnv("CHANNEL_SECRET_KEYS", "v1:first");
+    vi.stubEnv("CHANNEL_SECRET_KEY", "");
+    vi.stubEnv("NEXTAUTH_SECRET", "");
+    vi.stubEnv("HISTORY_ENCRYPTION", "on");
+    await saveRunOutput("run-1", "quarterly numbers");
+    const content = upsert.mock.calls[0][0].create.content as Buffer;
+    expect(content.toString("utf8")).toMatch(/^enc:v1:/);
+
+    findUnique.mockResolvedValueOnce({ content, truncated: false });
+    expect(await loadRunOutput("run-1")).toEqual({ text: "quarterly numbers", truncated: false });
+
+    vi.stubEnv("CHANNEL_SECRET_KEYS", "v2:second");
+    findUnique.mockResolvedValueOnce({ content, truncated: false });
+    expect(await loadRunOutput("run-1")).toEqual({ text: "[encrypted with a key that is no longer configured]", truncated: false });
+  });
+});
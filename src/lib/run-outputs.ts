@@ -0,0 +1,67 @@
+import { gunzipSync, gzipSync } from "node:zlib";
+import { prisma } from "@/lib/prisma";
+import { signToken } from "@/lib/crypto";
+import { getAppUrl } from "@/lib/stripe";
+import { HISTORY_UNREADABLE, openHistoryBytes, sealHistoryBytes } from "@/lib/history-encryption";
+
+const DEFAULT_MAX_BYTES = 1024 * 1024;
+
+function maxOutputBytes() {
+  const value = Number(process.env.RUN_OUTPUT_MAX_BYTES ?? DEFAULT_MAX_BYTES);
+  return Number.isFinite(value) && value > 0 ? Math.floor(value) : DEFAULT_MAX_BYTES;
+}
+
+// Signed page with a run's full output, linked from Run History and from chat messages that were cut to their
+// first part. Like artifact links, it works without a session.
+export function runOutputPath(runHistoryId: string) {
+  return `/runs/${runHistoryId}/output?token=${signToken("run-output", runHistoryId)}`;
+}
+
+export function runOutputUrl(runHistoryId: string) {
+  return `${getAppUrl()}${runOutputPath(runHistoryId)}`;
+}
+
+// Cuts text to at most maxBytes of UTF-8 without splitting a character.
+export function capUtf8(text: string, maxBytes: number) {
+  const bytes = Buffer.from(text, "utf8");
+  if (bytes.length <= maxBytes) {
+    return { text, sizeBytes: bytes.length, truncated: false };
+  }
+  let end = maxBytes;
+  while (end > 0 && (bytes[end] & 0xc0) === 0x80) {
+    end--;
+  }
+  return { text: bytes.subarray(0, end).toString("utf8"), sizeBytes: end, truncated: true };
+}
+
+export function compressOutput(text: string, maxBytes = maxOutputBytes()) {
+  const capped = capUtf8(text, maxBytes);
+  return { content: gzipSync(Buffer.from(capped.text, "utf8")), sizeBytes: capped.sizeBytes, truncated: capped.truncated };
+}
+
+export function decompressOutput(content: Uint8Array) {
+  return gunzipSync(content).toString("utf8");
+}
+
+// With HISTORY_ENCRYPTION on, the compressed bytes are sealed like the run's other history text.
+export async function saveRunOutput(runHistoryId: string, text: string) {
+  const data = compressOutput(text);
+  const stored = { ...data, content: sealHistoryBytes(data.content) };
+  await prisma.runOutput.upsert({
+    where: { runHistoryId },
+    create: { runHistoryId, ...stored },
+    update: stored,
+  });
+  return data;
+}
+
+// Runs recorded before run_outputs existed only have run_histories.output_text.
+export async function loadRunOutput(runHistoryId: string): Promise<{ text: string; truncated: boolean } | null> {
+  const stored = await prisma.runOutput.findUnique({ where: { runHistoryId }, select: { content: true, truncated: true } });
+  if (stored) {
+    const content = openHistoryBytes(stored.content);
+    return { text: content ? decompressOutput(content) : HISTORY_UNREADABLE, truncated: stored.truncated };
+  }
+  const run = await prisma.runHistory.findUnique({ where: { id: runHistoryId }, select: { outputText: true } });
+  return run?.outputText ? { text: run.outputText, truncated: false } : null;
+}
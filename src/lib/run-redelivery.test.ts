@@ -0,0 +1,93 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const { findFirst, txFindFirst, create, queryRaw, loadRunOutput } = vi.hoisted(() => ({
+  findFirst: vi.fn(),
+  txFindFirst: vi.fn(),
+  create: vi.fn(),
+  queryRaw: vi.fn(),
+  loadRunOutput: vi.fn(),
+}));
+vi.mock("@/lib/prisma", () => ({
+  prisma: {
+    runHistory: { findFirst },
+    $transaction: (fn: (tx: unknown) => unknown) => fn({ runHistory: { findFirst: txFindFirst, create }, $queryRaw: queryRaw }),
+  },
+}));
+vi.mock("@/lib/run-outputs", () => ({ loadRunOutput }));
+
+import { fakeClock, setClock, systemClock } from "./clock";
+import { queueRedelivery } from "./run-redelivery";
+
+const source = {
+  id: "run_1",
+  jobId: "job_1",
+  promptVersionId: "pv_1",
+  status: "success",
+  outputText: "preview",
+  outputHash: "hash",
+  outputDiff: "Changes since the last run:\n+ new line",
+  llmModel: "gpt-5-mini",
+  usedWebSearch: false,
+  citations: null,
+  tags: ["daily"],
+  job: { channelType: "discord" },
+};
+
+afterEach(() => {
+  vi.clearAllMocks();
+  setClock(systemClock);
+});
+
+describe("run re-delivery", () => {
+  it("queues the full output without the change block at the clock's time", async () => {
+    setClock(fakeClock("2026-07-01T08:00:00Z"));
+    findFirst.mockResolvedValue(source);
+    loadRunOutput.mockResolvedValue({ text: "full output", truncated: false });
+    txFindFirst.mockResolvedValue(null);
+    create.mockResolvedValue({ id: "run_2" });
+
+    await expect(queueRedelivery("user_1", "run_1")).resolves.toEqual({ ok: true, runHistoryId: "run_2" });
+    expect(queryRaw).toHaveBeenCalledTimes(1);
+    const data = create.mock.calls[0][0].data;
+    expect(data).toMatchObject({
+      jobId: "job_1",
+      status: "running",
+      outputText: "full output",
+      runnerId: "redeliver",
+      redeliveredFromId: "run_1",
+      deliverAt: new Date("2026-07-01T08:00:00Z"),
+    });
+    expect(data).not.toHaveProperty("outputDiff");
+    expect(data).not.toHaveProperty("scheduledFor");
+  });
+
+  it("rejects a second request while the first re-delivery is pending", async () => {
+    findFirst.mockResolvedValue(source);
+    loadRunOutput.mockResolvedValue(null);
+    txFindFirst.mockResolvedValue({ id: "run_2" });
+
+    await expect(queueRedelivery("user_1", "run_1")).resolves.toEqual({
+      ok: false,
+      status: 409,
+      error: "A re-delivery of this run is already queued",
+    });
+    expect(txFindFirst).toHaveBeenCalledWith(expect.objectContaining({ where: { redeliveredFromId: "run_1", status: "running" } }));
+    expect(create).not.toHaveBeenCalled();
+  });
+
+  it("refuses runs it cannot re-deliver", async () => {
+    findFirst.mockResolvedValueOnce(null);
+    await expect(queueRedelivery("user_1", "run_x")).resolves.toMatchObject({ ok: false, status: 404 });
+
+    findFirst.mockResolvedValueOnce({ ...source, job: { channelType: "in_app" } });
+    await expect(queueRedelivery("user_1", "run_1")).resolves.toMatchObject({ ok: false, status: 409 });
+
+    findFirst.mockResolvedValueOnce({ ...source, status: "running" });
+    await expect(queueRedelivery("user_1", "run_1")).resolves.toMatchObject({ ok: false, status: 409 });
+
+    findFirst.mockResolvedValueOnce({ ...source, outputText: null });
+    loadRunOutput.mockResolvedValueOnce(null);
+    await expect(queueRedelivery("user_1", "run_1")).resolves.toMatchObject({ ok: false, error: "Nothing to re-deliver: the run produced no output" });
+    expect(create).not.toHaveBeenCalled();
+  });
+});
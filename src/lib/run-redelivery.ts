@@ -0,0 +1,60 @@
+import { ChannelType, type Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { clock } from "@/lib/clock";
+import { loadRunOutput } from "@/lib/run-outputs";
+
+const OUTPUT_PREVIEW_MAX = 1000;
+
+export type RedeliveryResult = { ok: true; runHistoryId: string } | { ok: false; status: 404 | 409; error: string };
+
+// Queues a past run's stored output for delivery again, without calling the model: a new run (runner_id
+// "redeliver", redelivered_from_id pointing at the source) in the deferred-delivery outbox, which the next worker
+// pass sends in full through the job's current channel. The change block is not carried over, so diff-only jobs
+// get the output itself. One re-delivery per source can be pending at a time; the source row is locked while
+// checking so a double submit cannot queue two.
+export async function queueRedelivery(userId: string, runId: string): Promise<RedeliveryResult> {
+  const source = await prisma.runHistory.findFirst({
+    where: { id: runId, isPreview: false, job: { userId } },
+    include: { job: { select: { channelType: true } } },
+  });
+  if (!source) {
+    return { ok: false, status: 404, error: "Not found" };
+  }
+  if (source.job.channelType === ChannelType.in_app) {
+    return { ok: false, status: 409, error: "In-app jobs have no external channel to deliver to" };
+  }
+  if (source.status === "running") {
+    return { ok: false, status: 409, error: "The run has not finished yet" };
+  }
+  const stored = await loadRunOutput(source.id);
+  const output = stored?.text ?? source.outputText;
+  if (!output) {
+    return { ok: false, status: 409, error: "Nothing to re-deliver: the run produced no output" };
+  }
+
+  return prisma.$transaction(async (tx): Promise<RedeliveryResult> => {
+    await tx.$queryRaw`SELECT id FROM "public"."run_histories" WHERE id = ${source.id} FOR UPDATE`;
+    const pending = await tx.runHistory.findFirst({ where: { redeliveredFromId: source.id, status: "running" }, select: { id: true } });
+    if (pending) {
+      return { ok: false, status: 409, error: "A re-delivery of this run is already queued" };
+    }
+    const run = await tx.runHistory.create({
+      data: {
+        jobId: source.jobId,
+        promptVersionId: source.promptVersionId,
+        status: "running",
+        outputText: output,
+        outputPreview: output.slice(0, OUTPUT_PREVIEW_MAX),
+        outputHash: source.outputHash,
+        llmModel: source.llmModel,
+        usedWebSearch: source.usedWebSearch,
+        citations: (source.citations ?? undefined) as Prisma.InputJsonValue | undefined,
+        runnerId: "redeliver",
+        redeliveredFromId: source.id,
+        deliverAt: clock().now(),
+        tags: source.tags,
+      },
+    });
+    return { ok: true, runHistoryId: run.id };
+  });
+}
@@ -0,0 +1,31 @@
+import { describe, expect, it } from "vitest";
+import { failureRunStatus, runStatusLabel, runStatusPillClass, runStatusTone, runTimingSummary } from "./run-status";
+
+describe("run status", () => {
+  it("picks the most specific failure status", () => {
+    expect(failureRunStatus("Daily run limit exceeded", { quotaBlocked: true })).toBe("skipped_quota");
+    expect(failureRunStatus("Telegram sendMessage failed: 400", { partialDelivery: true })).toBe("partial_delivery");
+    expect(failureRunStatus("Output blocked by the provider content filter")).toBe("blocked_moderation");
+    expect(failureRunStatus("Prompt run timed out after 60s (model=gpt-5)")).toBe("timeout");
+    expect(failureRunStatus("Webhook failed: 404")).toBe("fail");
+  });
+
+  it("maps statuses to pill tones and labels", () => {
+    expect(runStatusTone("success")).toBe("success");
+    expect(runStatusTone("timeout")).toBe("fail");
+    expect(runStatusTone("config_error")).toBe("fail");
+    expect(runStatusTone("skipped_unchanged")).toBe("neutral");
+    expect(runStatusLabel("skipped_quota")).toBe("skipped quota");
+    expect(runStatusPillClass("partial_delivery")).toBe("status-pill status-pill-fail");
+  });
+
+  it("summarizes where a run spent its time", () => {
+    const run = { llmDurationMs: 12_345, llmAttempts: 2, cacheHit: false, deliveryDurationMs: 800, deliveryAttempts: 1, failureStage: null };
+    expect(runTimingSummary(run)).toBe("model 12s (2 calls) · delivery 0.8s");
+    expect(runTimingSummary({ ...run, deliveryAttempts: 3, failureStage: "channel" })).toBe(
+      "model 12s (2 calls) · delivery 0.8s (3 attempts) · failed in the channel",
+    );
+    expect(runTimingSummary({ ...run, llmDurationMs: null, deliveryDurationMs: null, failureStage: "model" })).toBe("failed in the model");
+    expect(runTimingSummary({ ...run, cacheHit: true })).toBe("model: cached response · delivery 0.8s");
+  });
+});
@@ -0,0 +1,81 @@
+import type { RunStatus } from "@prisma/client";
+
+// Runs that produced an output, whether or not all of it was delivered. "Previous output" lookups (deliver_if,
+// diffs, previous-output memory, last_run_at) use these instead of success alone.
+export const GENERATED_RUN_STATUSES: RunStatus[] = ["success", "skipped_unchanged", "partial_delivery"];
+
+const MODERATION_RE = /content[ _-]?filter|moderation|content[ _-]?policy|flagged/i;
+const TIMEOUT_RE = /timed out|timeout|ETIMEDOUT/i;
+
+// Status written for a run that did not succeed, most specific first.
+export function failureRunStatus(errorMessage: string, opts: { quotaBlocked?: boolean; partialDelivery?: boolean } = {}): RunStatus {
+  if (opts.quotaBlocked) {
+    return "skipped_quota";
+  }
+  if (opts.partialDelivery) {
+    return "partial_delivery";
+  }
+  if (MODERATION_RE.test(errorMessage)) {
+    return "blocked_moderation";
+  }
+  if (TIMEOUT_RE.test(errorMessage)) {
+    return "timeout";
+  }
+  return "fail";
+}
+
+export type RunStatusTone = "success" | "fail" | "neutral";
+
+export function runStatusTone(status: RunStatus | string): RunStatusTone {
+  if (status === "success") {
+    return "success";
+  }
+  if (status === "fail" || status === "partial_delivery" || status === "blocked_moderation" || status === "timeout" || status === "config_error") {
+    return "fail";
+  }
+  return "neutral";
+}
+
+const PILL_CLASS: Record<RunStatusTone, string> = {
+  success: "status-pill status-pill-success",
+  fail: "status-pill status-pill-fail",
+  neutral: "status-pill status-pill-neutral",
+};
+
+export function runStatusPillClass(status: RunStatus | string) {
+  return PILL_CLASS[runStatusTone(status)];
+}
+
+export function runStatusLabel(status: RunStatus | string) {
+  return status.replace(/_/g, " ");
+}
+
+function seconds(ms: number) {
+  return ms < 10_000 ? `${(ms / 1000).toFixed(1)}s` : `${Math.round(ms / 1000)}s`;
+}
+
+// One-line timing breakdown for run history: where the time went and, for failures, whether the model or the
+// channel failed.
+export function runTimingSummary(run: {
+  llmDurationMs: number | null;
+  llmAttempts: number;
+  cacheHit: boolean;
+  deliveryDurationMs: number | null;
+  deliveryAttempts: number;
+  failureStage: string | null;
+}) {
+  const parts: string[] = [];
+  if (run.cacheHit) {
+    parts.push("model: cached response");
+  } else if (run.llmDurationMs != null) {
+    parts.push(`model ${seconds(run.llmDurationMs)}${run.llmAttempts > 1 ? ` (${run.llmAttempts} calls)` : ""}`);
+  }
+  if (run.deliveryDurationMs != null) {
+    const attempts = run.deliveryAttempts > 1 ? ` (${run.deliveryAttempts} attempts)` : "";
+    parts.push(`delivery ${seconds(run.deliveryDurationMs)}${attempts}`);
+  }
+  if (run.failureStage === "model" || run.failureStage === "channel") {
+    parts.push(`failed in the ${run.failureStage}`);
+  }
+  return parts.join(" · ");
+}
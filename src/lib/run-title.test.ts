@@ -0,0 +1,20 @@
+import { describe, expect, it } from "vitest";
+import { formatRunTitle, renderRunHeader } from "./run-title";
+
+const at = new Date("2026-10-05T07:30:00Z");
+const job = { name: "Daily brief", showHeader: true, headerTemplate: null, headerLocale: null };
+
+describe("run header", () => {
+  it("keeps the default header without a template and omits it when turned off", () => {
+    expect(renderRunHeader(job, at, "UTC")).toBe(formatRunTitle("Daily brief", at, "UTC"));
+    expect(renderRunHeader({ ...job, showHeader: false, headerTemplate: "{{job_name}}" }, at, "UTC")).toBe("");
+  });
+
+  it("renders templates with locale-aware dates in the job time zone", () => {
+    const templated = { ...job, headerTemplate: "{{job_name}} · {{date}} {{time}}", headerLocale: "de-DE" };
+    expect(renderRunHeader(templated, at, "Europe/Berlin")).toBe("Daily brief · 5. Oktober 2026 09:30");
+    expect(renderRunHeader({ ...templated, headerTemplate: "{{weekday}} ({{timezone}})", headerLocale: "xx-YY" }, at, "Nowhere/City")).toBe(
+      "Monday (UTC)",
+    );
+  });
+});
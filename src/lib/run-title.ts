@@ -1,4 +1,6 @@
 import { format } from "date-fns";
+import { renderTemplate } from "@/lib/template-functions";
+import { isValidTimeZone } from "@/lib/timezone";
 
 function defaultTimeZoneLabel(): string | null {
   try {
@@ -15,3 +17,41 @@ export function formatRunTitle(name: string, at: Date, tzLabel?: string): string
   const label = typeof tzLabel === "string" && tzLabel.trim() ? tzLabel.trim() : (defaultTimeZoneLabel() ?? "UTC");
   return `[${name}] ${ts} ${offset} ${label}`;
 }
+
+export type RunHeaderSettings = {
+  name: string;
+  showHeader: boolean;
+  headerTemplate: string | null;
+  headerLocale: string | null;
+};
+
+export function isValidLocale(locale: string) {
+  try {
+    return Intl.DateTimeFormat.supportedLocalesOf([locale]).length > 0;
+  } catch {
+    return false;
+  }
+}
+
+// The header line sent above a job's output. Jobs can turn it off (many prompts write their own title) or set a
+// template with {{job_name}}, {{date}}, {{time}}, {{datetime}}, {{weekday}} and {{timezone}}, formatted for
+// header_locale (default en-US) in the job's time zone; without a template the classic "[name] timestamp" is used.
+export function renderRunHeader(job: RunHeaderSettings, at: Date, timeZone = "UTC"): string {
+  if (!job.showHeader) {
+    return "";
+  }
+  if (!job.headerTemplate?.trim()) {
+    return formatRunTitle(job.name, at, timeZone);
+  }
+  const locale = job.headerLocale && isValidLocale(job.headerLocale) ? job.headerLocale : "en-US";
+  const tz = isValidTimeZone(timeZone) ? timeZone : "UTC";
+  const formatted = (options: Intl.DateTimeFormatOptions) => new Intl.DateTimeFormat(locale, { ...options, timeZone: tz }).format(at);
+  return renderTemplate(job.headerTemplate, {
+    job_name: job.name,
+    date: formatted({ dateStyle: "long" }),
+    time: formatted({ timeStyle: "short" }),
+    datetime: formatted({ dateStyle: "medium", timeStyle: "short" }),
+    weekday: formatted({ weekday: "long" }),
+    timezone: tz,
+  }).trim();
+}
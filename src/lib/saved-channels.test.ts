@@ -0,0 +1,55 @@
+import { afterEach, beforeEach, describe, expect, it, vi } from "vitest";
+
+const { findFirst, findUnique } = vi.hoisted(() => ({ findFirst: vi.fn(), findUnique: vi.fn() }));
+vi.mock("@/lib/prisma", () => ({ prisma: { savedChannel: { findFirst, findUnique } } }));
+
+import { ChannelType } from "@prisma/client";
+import { decryptString, encryptString } from "./crypto";
+import { resolveJobChannel, toDbJobChannel } from "./saved-channels";
+
+beforeEach(() => {
+  vi.stubEnv("CHANNEL_SECRET_KEY", "test-secret");
+});
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+  vi.resetAllMocks();
+});
+
+describe("saved channels", () => {
+  it("copies a referenced channel onto the job", async () => {
+    const config = { webhookUrlEnc: encryptString("https://discord.test/new") };
+    findFirst.mockResolvedValueOnce({ id: "ch-1", channelType: ChannelType.discord, channelConfig: config });
+    await expect(toDbJobChannel("user-1", { channelId: "ch-1" })).resolves.toEqual({
+      channelId: "ch-1",
+      channelType: ChannelType.discord,
+      channelConfig: config,
+    });
+    expect(findFirst).toHaveBeenCalledWith({ where: { id: "ch-1", userId: "user-1" } });
+
+    findFirst.mockResolvedValueOnce(null);
+    await expect(toDbJobChannel("user-1", { channelId: "ch-2" })).rejects.toThrow("Saved channel not found");
+  });
+
+  it("keeps inline channels and falls back to the default channel", async () => {
+    const inline = await toDbJobChannel("user-1", { channel: { type: "in_app" } });
+    expect(inline).toEqual({ channelId: null, channelType: ChannelType.in_app, channelConfig: { v: 2, kind: "in_app" } });
+    expect(findFirst).not.toHaveBeenCalled();
+
+    findFirst.mockResolvedValueOnce(null);
+    await expect(toDbJobChannel("user-1", {})).rejects.toThrow("no default channel");
+  });
+
+  it("resolves the saved channel at delivery time", async () => {
+    const job = { channelId: "ch-1", channelType: ChannelType.discord, channelConfig: { webhookUrlEnc: encryptString("https://old") } };
+    findUnique.mockResolvedValueOnce({ channelType: ChannelType.discord, channelConfig: { webhookUrlEnc: encryptString("https://new") } });
+    const resolved = await resolveJobChannel(job);
+    expect(decryptString((resolved.channelConfig as { webhookUrlEnc: string }).webhookUrlEnc)).toBe("https://new");
+
+    // A deleted channel leaves the job's own copy.
+    findUnique.mockResolvedValueOnce(null);
+    await expect(resolveJobChannel(job)).resolves.toBe(job);
+    await expect(resolveJobChannel({ ...job, channelId: null })).resolves.toMatchObject({ channelType: ChannelType.discord });
+    expect(findUnique).toHaveBeenCalledTimes(2);
+  });
+});
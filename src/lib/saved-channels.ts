@@ -0,0 +1,148 @@
+import { ChannelType, Prisma, type SavedChannel } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import type { SendChannelInput } from "@/lib/channel";
+import { ChannelConfigError } from "@/lib/channel-config";
+import { pluginSettingsError } from "@/lib/channel-plugins";
+import { loadUserSecrets, resolveHeaderSecrets, usesSecrets } from "@/lib/secrets";
+import { toDbChannelConfig, toMaskedChannel, toRunnableChannel, type IncomingChannel, type StoredChannel } from "@/lib/jobs";
+
+// Saved channels are named channel configs owned by a user. Jobs reference one through jobs.channel_id and keep a
+// copy of its config, which is rewritten whenever the saved channel changes; deleting the channel detaches its
+// jobs, which keep delivering to the last config. The worker still resolves the reference at delivery time, so
+// a changed webhook URL or bot token applies to runs already in flight.
+
+// toDbChannelConfig for a channel a user is saving: plugin channels are first checked by the plugin itself, which
+// may run an executable, so this is async and stays out of the channel schema.
+export async function toSavableChannelConfig(channel: IncomingChannel) {
+  if (channel.type === "plugin") {
+    const error = await pluginSettingsError(channel.config.plugin, channel.config.settings);
+    if (error) {
+      throw new ChannelConfigError("invalid", ChannelType.plugin, [`settings: ${error}`]);
+    }
+  }
+  return toDbChannelConfig(channel);
+}
+
+export function toApiSavedChannel(channel: SavedChannel) {
+  return {
+    id: channel.id,
+    name: channel.name,
+    isDefault: channel.isDefault,
+    createdAt: channel.createdAt,
+    updatedAt: channel.updatedAt,
+    channel: toMaskedChannel(channel),
+  };
+}
+
+// Picker entry for the job editor; no config is sent to the browser.
+export function toSavedChannelOption(channel: SavedChannel) {
+  return { id: channel.id, name: channel.name, type: channel.channelType as Exclude<ChannelType, "in_app"> };
+}
+
+export async function listSavedChannels(userId: string) {
+  return prisma.savedChannel.findMany({ where: { userId }, orderBy: { name: "asc" } });
+}
+
+export async function getDefaultSavedChannel(userId: string) {
+  return prisma.savedChannel.findFirst({ where: { userId, isDefault: true } });
+}
+
+// Channel columns for a job create/update: a saved channel reference, an inline channel, or the account default.
+export async function toDbJobChannel(userId: string, input: { channel?: IncomingChannel; channelId?: string | null }) {
+  if (input.channelId) {
+    const saved = await prisma.savedChannel.findFirst({ where: { id: input.channelId, userId } });
+    if (!saved) {
+      throw new Error("Saved channel not found");
+    }
+    return { channelId: saved.id, channelType: saved.channelType, channelConfig: saved.channelConfig as Prisma.InputJsonValue };
+  }
+  if (input.channel) {
+    return { channelId: null, ...(await toSavableChannelConfig(input.channel)) };
+  }
+  const fallback = await getDefaultSavedChannel(userId);
+  if (!fallback) {
+    throw new Error("channel is required (no default channel is set)");
+  }
+  return { channelId: fallback.id, channelType: fallback.channelType, channelConfig: fallback.channelConfig as Prisma.InputJsonValue };
+}
+
+export async function createSavedChannel(userId: string, input: { name: string; channel: IncomingChannel; isDefault: boolean }) {
+  const { channelType, channelConfig } = await toSavableChannelConfig(input.channel);
+  if (channelType === ChannelType.in_app) {
+    throw new Error("In-app delivery cannot be saved as a channel");
+  }
+  return prisma.$transaction(async (tx) => {
+    if (input.isDefault) {
+      await tx.savedChannel.updateMany({ where: { userId, isDefault: true }, data: { isDefault: false } });
+    }
+    return tx.savedChannel.create({ data: { userId, name: input.name, channelType, channelConfig, isDefault: input.isDefault } });
+  });
+}
+
+// Returns null when the channel does not exist or belongs to someone else.
+export async function updateSavedChannel(
+  userId: string,
+  id: string,
+  input: { name?: string; channel?: IncomingChannel; isDefault?: boolean },
+) {
+  const db = input.channel ? await toSavableChannelConfig(input.channel) : null;
+  if (db?.channelType === ChannelType.in_app) {
+    throw new Error("In-app delivery cannot be saved as a channel");
+  }
+  return prisma.$transaction(async (tx) => {
+    const existing = await tx.savedChannel.findFirst({ where: { id, userId }, select: { id: true } });
+    if (!existing) {
+      return null;
+    }
+    if (input.isDefault) {
+      await tx.savedChannel.updateMany({ where: { userId, isDefault: true, id: { not: id } }, data: { isDefault: false } });
+    }
+    const updated = await tx.savedChannel.update({
+      where: { id },
+      data: {
+        ...(input.name != null ? { name: input.name } : {}),
+        ...(input.isDefault != null ? { isDefault: input.isDefault } : {}),
+        ...(db ?? {}),
+      },
+    });
+    const jobs = db
+      ? await tx.job.updateMany({
+          where: { channelId: id, userId },
+          data: { channelType: db.channelType, channelConfig: db.channelConfig, credentialCheckedAt: null, credentialError: null },
+        })
+      : { count: 0 };
+    return { channel: updated, jobsUpdated: jobs.count };
+  });
+}
+
+// The job copies already match the channel, so the foreign key's SET NULL is all detaching takes.
+export async function deleteSavedChannel(userId: string, id: string) {
+  const jobs = await prisma.job.count({ where: { channelId: id, userId } });
+  const deleted = await prisma.savedChannel.deleteMany({ where: { id, userId } });
+  return deleted.count ? { jobsDetached: jobs } : null;
+}
+
+// The job's current channel: its saved channel when it references one, else the job's own config.
+export async function resolveJobChannel(job: StoredChannel & { channelId: string | null }): Promise<StoredChannel> {
+  if (!job.channelId) {
+    return job;
+  }
+  const saved = await prisma.savedChannel.findUnique({
+    where: { id: job.channelId },
+    select: { channelType: true, channelConfig: true },
+  });
+  return saved ?? job;
+}
+
+// Webhook headers may reference the owner's secrets ({{secret:NAME}}); they are resolved here, at delivery time,
+// so the stored config never holds the token and a rotated secret applies to every job using it.
+export async function resolveChannelSecrets(userId: string, channel: SendChannelInput): Promise<SendChannelInput> {
+  if (channel.type !== "webhook" || !usesSecrets(channel.headers)) {
+    return channel;
+  }
+  return { ...channel, headers: resolveHeaderSecrets(channel.headers, await loadUserSecrets(userId)) };
+}
+
+export async function runnableJobChannel(job: StoredChannel & { channelId: string | null; userId: string }) {
+  return resolveChannelSecrets(job.userId, toRunnableChannel(await resolveJobChannel(job)));
+}
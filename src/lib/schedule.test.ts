@@ -1,8 +1,17 @@
 import { describe, expect, it } from "vitest";
 
-import { computeNextRunAt } from "./schedule";
+import { computeFailureRetryAt, computeNextRunAt, offsetSchedule, scheduleConfigError } from "./schedule";
 
 describe("schedule", () => {
+  it("reports stored schedules that no longer parse", () => {
+    expect(scheduleConfigError({ scheduleType: "cron", scheduleTime: "00:00", scheduleCron: "*/15 * * * *" })).toBeNull();
+    expect(scheduleConfigError({ scheduleType: "cron", scheduleTime: "00:00", scheduleCron: "61 * * * *" })).toEqual(expect.any(String));
+    expect(scheduleConfigError({ scheduleType: "weekly", scheduleTime: "09:00", scheduleDayOfWeek: 9 })).toBe(
+      "Day of week must be 0-6 for weekly schedule",
+    );
+    expect(scheduleConfigError({ scheduleType: "once", scheduleTime: "00:00", runAt: new Date("2020-01-01T00:00:00Z") })).toBeNull();
+  });
+
   it("daily schedules use UTC time-of-day", () => {
     const base = new Date("2026-01-01T10:00:00.000Z");
     const next = computeNextRunAt({ scheduleType: "daily", scheduleTime: "09:00" }, base);
@@ -21,4 +30,80 @@ describe("schedule", () => {
     );
     expect(next.toISOString()).toBe("2026-01-08T00:00:00.000Z");
   });
+
+  it("daily schedules keep the wall-clock time in the job time zone across DST", () => {
+    const input = { scheduleType: "daily" as const, scheduleTime: "08:00", timezone: "Europe/Berlin" };
+    // Winter (UTC+1) and summer (UTC+2).
+    expect(computeNextRunAt(input, new Date("2026-03-28T12:00:00.000Z")).toISOString()).toBe("2026-03-29T06:00:00.000Z");
+    expect(computeNextRunAt(input, new Date("2026-03-27T12:00:00.000Z")).toISOString()).toBe("2026-03-28T07:00:00.000Z");
+  });
+
+  it("weekly schedules use the day of week in the job time zone", () => {
+    const base = new Date("2026-01-01T00:00:00.000Z");
+    const next = computeNextRunAt(
+      { scheduleType: "weekly", scheduleTime: "09:00", scheduleDayOfWeek: 1, timezone: "America/New_York" },
+      base,
+    );
+    expect(next.toISOString()).toBe("2026-01-05T14:00:00.000Z");
+  });
+
+  it("cron schedules are evaluated in the job time zone", () => {
+    const base = new Date("2026-07-01T00:00:00.000Z");
+    const next = computeNextRunAt({ scheduleType: "cron", scheduleTime: "00:00", scheduleCron: "30 8 * * *", timezone: "Asia/Seoul" }, base);
+    expect(next.toISOString()).toBe("2026-07-01T23:30:00.000Z");
+  });
+
+  it("one-time schedules return runAt and reject past times", () => {
+    const base = new Date("2026-07-01T00:00:00.000Z");
+    const runAt = new Date("2026-07-02T08:30:00.000Z");
+    expect(computeNextRunAt({ scheduleType: "once", scheduleTime: "00:00", runAt }, base).toISOString()).toBe(runAt.toISOString());
+    expect(() => computeNextRunAt({ scheduleType: "once", scheduleTime: "00:00", runAt }, new Date("2026-07-03T00:00:00.000Z"))).toThrow(
+      "Run time must be in the future",
+    );
+    expect(() => computeNextRunAt({ scheduleType: "once", scheduleTime: "00:00" }, base)).toThrow();
+  });
+
+  it("failure retries back off exponentially and yield to the regular schedule", () => {
+    const policy = { maxRetries: 3, baseSeconds: 60, maxSeconds: 600 };
+    const now = new Date("2026-07-01T00:00:00.000Z");
+    const regular = new Date("2026-07-02T00:00:00.000Z");
+    expect(computeFailureRetryAt(0, policy, regular, now)?.toISOString()).toBe("2026-07-01T00:01:00.000Z");
+    expect(computeFailureRetryAt(2, policy, regular, now)?.toISOString()).toBe("2026-07-01T00:04:00.000Z");
+    expect(computeFailureRetryAt(3, policy, regular, now)).toBeNull();
+    expect(computeFailureRetryAt(0, policy, new Date("2026-07-01T00:00:30.000Z"), now)).toBeNull();
+    expect(computeFailureRetryAt(2, { ...policy, maxSeconds: 90 }, null, now)?.toISOString()).toBe("2026-07-01T00:01:30.000Z");
+  });
+
+  it("schedule offsets wrap the time of day and carry into the day of week", () => {
+    expect(offsetSchedule({ scheduleType: "daily", scheduleTime: "09:00" }, 90).scheduleTime).toBe("10:30");
+    expect(offsetSchedule({ scheduleType: "daily", scheduleTime: "00:30" }, -60).scheduleTime).toBe("23:30");
+    expect(offsetSchedule({ scheduleType: "weekly", scheduleTime: "23:00", scheduleDayOfWeek: 6 }, 120)).toMatchObject({
+      scheduleTime: "01:00",
+      scheduleDayOfWeek: 0,
+    });
+    expect(offsetSchedule({ scheduleType: "weekly", scheduleTime: "01:00", scheduleDayOfWeek: 0 }, -120)).toMatchObject({
+      scheduleTime: "23:00",
+      scheduleDayOfWeek: 6,
+    });
+    const runAt = new Date("2026-07-02T08:30:00.000Z");
+    expect(offsetSchedule({ scheduleType: "once", scheduleTime: "00:00", runAt }, 60).runAt?.toISOString()).toBe("2026-07-02T09:30:00.000Z");
+    expect(() => offsetSchedule({ scheduleType: "cron", scheduleTime: "00:00", scheduleCron: "0 9 * * *" }, 60)).toThrow(
+      "Schedule offset is not supported for cron jobs",
+    );
+    expect(offsetSchedule({ scheduleType: "cron", scheduleTime: "00:00", scheduleCron: "0 9 * * *" }, 0).scheduleTime).toBe("00:00");
+  });
+
+  it("weekdays-only daily schedules skip weekends in the job time zone", () => {
+    // Friday 2026-07-03 10:00 UTC; the next weekday 09:00 is Monday.
+    const friday = new Date("2026-07-03T10:00:00.000Z");
+    expect(computeNextRunAt({ scheduleType: "daily", scheduleTime: "09:00", weekdaysOnly: true }, friday).toISOString()).toBe(
+      "2026-07-06T09:00:00.000Z",
+    );
+    expect(computeNextRunAt({ scheduleType: "daily", scheduleTime: "09:00" }, friday).toISOString()).toBe("2026-07-04T09:00:00.000Z");
+    // Friday 2026-07-03 23:30 in Seoul is still Friday UTC, but the next 08:00 in Seoul is Saturday there.
+    const seoulFriday = new Date("2026-07-03T14:30:00.000Z");
+    expect(
+      computeNextRunAt({ scheduleType: "daily", scheduleTime: "08:00", timezone: "Asia/Seoul", weekdaysOnly: true }, seoulFriday).toISOString(),
+    ).toBe("2026-07-05T23:00:00.000Z");
+  });
 });
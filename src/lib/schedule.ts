@@ -1,30 +1,146 @@
 import { CronExpressionParser } from "cron-parser";
+import { getPartsInTimeZone, getWeekdayIndexInTimeZone, zonedWallTimeToUtc } from "@/lib/timezone";
+import { clock } from "@/lib/clock";
 
 export type ScheduleInput = {
-  scheduleType: "daily" | "weekly" | "cron";
+  scheduleType: "daily" | "weekly" | "cron" | "once";
   scheduleTime: string;
   scheduleDayOfWeek?: number | null;
   scheduleCron?: string | null;
+  // IANA zone for scheduleTime/scheduleDayOfWeek/scheduleCron. Null keeps the legacy UTC behavior.
+  timezone?: string | null;
+  // Instant for one-shot jobs (scheduleType=once).
+  runAt?: Date | null;
+  // Daily schedules only: skip Saturday and Sunday in the job time zone.
+  weekdaysOnly?: boolean | null;
 };
 
+export type CatchupPolicy = "skip" | "run_once" | "run_all";
+
+export function normalizeCatchupPolicy(value: unknown): CatchupPolicy {
+  return value === "skip" || value === "run_all" ? value : "run_once";
+}
+
+export type FailureBackoffPolicy = {
+  maxRetries: number;
+  baseSeconds: number;
+  maxSeconds: number;
+};
+
+// When to retry a failed run (exponential backoff), or null once retries are used up or the
+// regular schedule would come first anyway.
+export function computeFailureRetryAt(
+  attempt: number,
+  policy: FailureBackoffPolicy,
+  regularNext: Date | null,
+  now = clock().now(),
+): Date | null {
+  if (attempt >= policy.maxRetries) {
+    return null;
+  }
+  const delaySeconds = Math.min(policy.baseSeconds * 2 ** attempt, policy.maxSeconds);
+  const retryAt = new Date(now.getTime() + delaySeconds * 1000);
+  return regularNext && retryAt.getTime() >= regularNext.getTime() ? null : retryAt;
+}
+
 export function assertTimeFormat(time: string) {
   if (!/^([01]\d|2[0-3]):([0-5]\d)$/.test(time)) {
     throw new Error("Time must be HH:mm format");
   }
 }
 
-export function computeNextRunAt(input: ScheduleInput, base = new Date()) {
+// Shifts a schedule by a number of minutes, e.g. to stagger cloned regional jobs. Daily and weekly
+// times wrap around the day (weekly carries into the day of week); cron cannot be shifted reliably.
+export function offsetSchedule(
+  input: ScheduleInput,
+  minutes: number,
+): Pick<ScheduleInput, "scheduleTime" | "scheduleDayOfWeek" | "runAt"> {
+  const unchanged = { scheduleTime: input.scheduleTime, scheduleDayOfWeek: input.scheduleDayOfWeek ?? null, runAt: input.runAt ?? null };
+  if (!minutes) {
+    return unchanged;
+  }
+  if (input.scheduleType === "cron") {
+    throw new Error("Schedule offset is not supported for cron jobs");
+  }
+  if (input.scheduleType === "once") {
+    if (!input.runAt) {
+      throw new Error("Run time is required for one-time jobs");
+    }
+    return { ...unchanged, runAt: new Date(input.runAt.getTime() + minutes * 60 * 1000) };
+  }
+
+  assertTimeFormat(input.scheduleTime);
+  const [hour, minute] = input.scheduleTime.split(":").map(Number);
+  const total = hour * 60 + minute + minutes;
+  const dayShift = Math.floor(total / 1440);
+  const wrapped = ((total % 1440) + 1440) % 1440;
+  const scheduleTime = `${String(Math.floor(wrapped / 60)).padStart(2, "0")}:${String(wrapped % 60).padStart(2, "0")}`;
+  const scheduleDayOfWeek =
+    input.scheduleType === "weekly" && input.scheduleDayOfWeek != null
+      ? (((input.scheduleDayOfWeek + dayShift) % 7) + 7) % 7
+      : unchanged.scheduleDayOfWeek;
+  return { scheduleTime, scheduleDayOfWeek, runAt: unchanged.runAt };
+}
+
+function isWeekend(dayOfWeek: number) {
+  return dayOfWeek === 0 || dayOfWeek === 6;
+}
+
+function zonedTimeZone(input: ScheduleInput): string | null {
+  const timeZone = input.timezone?.trim();
+  return timeZone && timeZone !== "UTC" ? timeZone : null;
+}
+
+// Walks forward day by day in the job's zone so the wall-clock time stays fixed across DST changes.
+function computeZonedNextRunAt(input: ScheduleInput, hour: number, minute: number, timeZone: string, base: Date) {
+  const local = getPartsInTimeZone(base, timeZone);
+  for (let offset = 0; offset <= 8; offset++) {
+    const candidate = zonedWallTimeToUtc(local.year, local.month, local.day + offset, hour, minute, timeZone);
+    if (candidate.getTime() <= base.getTime()) {
+      continue;
+    }
+    if (input.scheduleType === "weekly" && getWeekdayIndexInTimeZone(candidate, timeZone) !== input.scheduleDayOfWeek) {
+      continue;
+    }
+    if (input.scheduleType === "daily" && input.weekdaysOnly && isWeekend(getWeekdayIndexInTimeZone(candidate, timeZone))) {
+      continue;
+    }
+    return candidate;
+  }
+  throw new Error("Failed to compute next run time");
+}
+
+export function computeNextRunAt(input: ScheduleInput, base = clock().now()) {
+  if (input.scheduleType === "once") {
+    if (!input.runAt) {
+      throw new Error("Run time is required for one-time jobs");
+    }
+    if (input.runAt.getTime() <= base.getTime()) {
+      throw new Error("Run time must be in the future");
+    }
+    return new Date(input.runAt);
+  }
+
   if (input.scheduleType === "cron") {
     if (!input.scheduleCron) {
       throw new Error("Cron expression is required");
     }
-    const it = CronExpressionParser.parse(input.scheduleCron, { currentDate: base });
+    const it = CronExpressionParser.parse(input.scheduleCron, { currentDate: base, tz: zonedTimeZone(input) ?? "UTC" });
     return it.next().toDate();
   }
 
   assertTimeFormat(input.scheduleTime);
   const [hour, minute] = input.scheduleTime.split(":").map(Number);
 
+  if (input.scheduleType === "weekly" && (input.scheduleDayOfWeek == null || input.scheduleDayOfWeek < 0 || input.scheduleDayOfWeek > 6)) {
+    throw new Error("Day of week must be 0-6 for weekly schedule");
+  }
+
+  const timeZone = zonedTimeZone(input);
+  if (timeZone) {
+    return computeZonedNextRunAt(input, hour, minute, timeZone, base);
+  }
+
   if (input.scheduleType === "daily") {
     const candidateMs = Date.UTC(
       base.getUTCFullYear(),
@@ -36,15 +152,15 @@ export function computeNextRunAt(input: ScheduleInput, base = new Date()) {
       0,
     );
     const baseMs = base.getTime();
-    return new Date(candidateMs > baseMs ? candidateMs : candidateMs + 24 * 60 * 60 * 1000);
-  }
-
-  if (input.scheduleDayOfWeek == null || input.scheduleDayOfWeek < 0 || input.scheduleDayOfWeek > 6) {
-    throw new Error("Day of week must be 0-6 for weekly schedule");
+    let next = new Date(candidateMs > baseMs ? candidateMs : candidateMs + 24 * 60 * 60 * 1000);
+    while (input.weekdaysOnly && isWeekend(next.getUTCDay())) {
+      next = new Date(next.getTime() + 24 * 60 * 60 * 1000);
+    }
+    return next;
   }
 
   const currentDow = base.getUTCDay();
-  const deltaDays = (input.scheduleDayOfWeek - currentDow + 7) % 7;
+  const deltaDays = (input.scheduleDayOfWeek! - currentDow + 7) % 7;
 
   const candidateMs = Date.UTC(
     base.getUTCFullYear(),
@@ -58,3 +174,17 @@ export function computeNextRunAt(input: ScheduleInput, base = new Date()) {
   const baseMs = base.getTime();
   return new Date(candidateMs > baseMs ? candidateMs : candidateMs + 7 * 24 * 60 * 60 * 1000);
 }
+
+// What is wrong with a stored recurring schedule (e.g. a cron expression that no longer parses), or null. One-time
+// jobs are not checked: their run time is in the past by the time they are claimed.
+export function scheduleConfigError(input: ScheduleInput) {
+  if (input.scheduleType === "once") {
+    return null;
+  }
+  try {
+    computeNextRunAt(input);
+    return null;
+  } catch (err) {
+    return err instanceof Error ? err.message : String(err);
+  }
+}
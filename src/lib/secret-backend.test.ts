@@ -0,0 +1,65 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/google-auth", () => ({ getGoogleAccessToken: async () => "token" }));
+
+import { decryptString, encryptString, setDataKey } from "./crypto";
+import { initSecretBackend, secretBackendName, signAwsRequest, unwrapDataKey } from "./secret-backend";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+  vi.unstubAllGlobals();
+  setDataKey(null);
+});
+
+describe("secret backend", () => {
+  it("defaults to the env backend and rejects unknown names", () => {
+    vi.stubEnv("SECRET_BACKEND", "");
+    expect(() => secretBackendName()).toThrow("Unknown SECRET_BACKEND");
+    vi.stubEnv("SECRET_BACKEND", "Vault-Transit");
+    expect(secretBackendName()).toBe("vault-transit");
+  });
+
+  it("signs KMS requests with SigV4", () => {
+    const input = {
+      host: "kms.us-east-1.amazonaws.com",
+      region: "us-east-1",
+      service: "kms",
+      target: "TrentService.Decrypt",
+      body: "{}",
+      accessKeyId: "AKIDEXAMPLE",
+      secretAccessKey: "secret",
+      now: new Date("2026-04-01T12:00:00.000Z"),
+    };
+    const headers = signAwsRequest(input);
+    expect(headers["x-amz-date"]).toBe("20260401T120000Z");
+    expect(headers.authorization).toMatch(
+      /^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE\/20260401\/us-east-1\/kms\/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target, Signature=[0-9a-f]{64}$/,
+    );
+    expect(signAwsRequest(input).authorization).toBe(headers.authorization);
+    expect(signAwsRequest({ ...input, body: "{\"a\":1}" }).authorization).not.toBe(headers.authorization);
+    expect(signAwsRequest({ ...input, sessionToken: "tok" }).authorization).toContain("x-amz-security-token");
+  });
+
+  it("unwraps the data key through Vault transit and uses it for channel secrets", async () => {
+    const key = Buffer.alloc(32, 7);
+    const fetchMock = vi.fn(async () => new Response(JSON.stringify({ data: { plaintext: key.toString("base64") } })));
+    vi.stubGlobal("fetch", fetchMock);
+    vi.stubEnv("SECRET_BACKEND", "vault-transit");
+    vi.stubEnv("VAULT_ADDR", "https://vault.example/");
+    vi.stubEnv("VAULT_TOKEN", "s.token");
+    vi.stubEnv("VAULT_TRANSIT_KEY", "promptloop");
+    vi.stubEnv("SECRET_WRAPPED_KEY", "vault:v1:abc");
+
+    expect(() => encryptString("x")).toThrow("not initialized");
+    await initSecretBackend();
+    expect(fetchMock).toHaveBeenCalledWith("https://vault.example/v1/transit/decrypt/promptloop", expect.objectContaining({ method: "POST" }));
+    expect(decryptString(encryptString("https://hooks.example/abc"))).toBe("https://hooks.example/abc");
+  });
+
+  it("rejects data keys of the wrong size", async () => {
+    vi.stubGlobal("fetch", vi.fn(async () => new Response(JSON.stringify({ plaintext: Buffer.alloc(16).toString("base64") }))));
+    vi.stubEnv("GCP_KMS_KEY_NAME", "projects/p/locations/global/keyRings/r/cryptoKeys/k");
+    vi.stubEnv("GCP_KMS_SERVICE_ACCOUNT_JSON", "{}");
+    await expect(unwrapDataKey("gcp-kms", "abc")).rejects.toThrow("32-byte key (got 16 bytes)");
+  });
+});
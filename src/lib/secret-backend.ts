@@ -0,0 +1,167 @@
+import { createHash, createHmac } from "node:crypto";
+import { setDataKey } from "@/lib/crypto";
+import { getGoogleAccessToken } from "@/lib/google-auth";
+
+// Where the AES key for channel credentials comes from. "env" derives it from CHANNEL_SECRET_KEY/NEXTAUTH_SECRET;
+// the others unwrap SECRET_WRAPPED_KEY (a 32-byte data key encrypted by the KMS) once at startup, so ciphertexts
+// never depend on a static env secret and encrypt/decrypt stay synchronous.
+export const SECRET_BACKENDS = ["env", "aws-kms", "gcp-kms", "vault-transit"] as const;
+export type SecretBackend = (typeof SECRET_BACKENDS)[number];
+
+const GCP_KMS_SCOPE = "https://www.googleapis.com/auth/cloudkms";
+
+export function secretBackendName(): SecretBackend {
+  const raw = (process.env.SECRET_BACKEND ?? "env").trim().toLowerCase();
+  if (!SECRET_BACKENDS.includes(raw as SecretBackend)) {
+    throw new Error(`Unknown SECRET_BACKEND: ${raw} (expected ${SECRET_BACKENDS.join(", ")})`);
+  }
+  return raw as SecretBackend;
+}
+
+function requiredEnv(name: string) {
+  const value = process.env[name]?.trim();
+  if (!value) {
+    throw new Error(`${name} is required for SECRET_BACKEND=${process.env.SECRET_BACKEND}`);
+  }
+  return value;
+}
+
+function sha256Hex(value: string) {
+  return createHash("sha256").update(value).digest("hex");
+}
+
+function hmac(key: string | Buffer, value: string) {
+  return createHmac("sha256", key).update(value).digest();
+}
+
+// AWS Signature Version 4 headers. Defaults to a JSON-RPC style POST to "/" (KMS); S3 passes its method, object
+// path, content type and x-amz-content-sha256 header.
+export function signAwsRequest(input: {
+  host: string;
+  region: string;
+  service: string;
+  target?: string;
+  method?: string;
+  path?: string;
+  contentType?: string;
+  headers?: Record<string, string>;
+  body: string;
+  accessKeyId: string;
+  secretAccessKey: string;
+  sessionToken?: string;
+  now?: Date;
+}) {
+  const amzDate = (input.now ?? new Date()).toISOString().replace(/[:-]|\.\d{3}/g, "");
+  const dateStamp = amzDate.slice(0, 8);
+  const headers: Record<string, string> = {
+    "content-type": input.contentType ?? "application/x-amz-json-1.1",
+    host: input.host,
+    "x-amz-date": amzDate,
+    ...(input.sessionToken ? { "x-amz-security-token": input.sessionToken } : {}),
+    ...(input.target ? { "x-amz-target": input.target } : {}),
+    ...input.headers,
+  };
+  const names = Object.keys(headers).sort();
+  const signedHeaders = names.join(";");
+  const canonicalRequest = [
+    input.method ?? "POST",
+    input.path ?? "/",
+    "",
+    ...names.map((name) => `${name}:${headers[name]}`),
+    "",
+    signedHeaders,
+    sha256Hex(input.body),
+  ].join("\n");
+  const scope = `${dateStamp}/${input.region}/${input.service}/aws4_request`;
+  const stringToSign = ["AWS4-HMAC-SHA256", amzDate, scope, sha256Hex(canonicalRequest)].join("\n");
+  const signingKey = hmac(hmac(hmac(hmac(`AWS4${input.secretAccessKey}`, dateStamp), input.region), input.service), "aws4_request");
+  const signature = createHmac("sha256", signingKey).update(stringToSign).digest("hex");
+  return {
+    ...headers,
+    authorization: `AWS4-HMAC-SHA256 Credential=${input.accessKeyId}/${scope}, SignedHeaders=${signedHeaders}, Signature=${signature}`,
+  };
+}
+
+async function awsKmsDecrypt(wrapped: string) {
+  const region = requiredEnv("AWS_REGION");
+  const host = `kms.${region}.amazonaws.com`;
+  const keyId = process.env.AWS_KMS_KEY_ID?.trim();
+  const body = JSON.stringify({ CiphertextBlob: wrapped, ...(keyId ? { KeyId: keyId } : {}) });
+  const res = await fetch(`https://${host}/`, {
+    method: "POST",
+    headers: signAwsRequest({
+      host,
+      region,
+      service: "kms",
+      target: "TrentService.Decrypt",
+      body,
+      accessKeyId: requiredEnv("AWS_ACCESS_KEY_ID"),
+      secretAccessKey: requiredEnv("AWS_SECRET_ACCESS_KEY"),
+      sessionToken: process.env.AWS_SESSION_TOKEN?.trim() || undefined,
+    }),
+    body,
+  });
+  if (!res.ok) {
+    throw new Error(`AWS KMS decrypt failed: ${res.status}`);
+  }
+  const data = (await res.json()) as { Plaintext?: string };
+  return data.Plaintext ?? "";
+}
+
+async function gcpKmsDecrypt(wrapped: string) {
+  const keyName = requiredEnv("GCP_KMS_KEY_NAME");
+  const token = await getGoogleAccessToken(requiredEnv("GCP_KMS_SERVICE_ACCOUNT_JSON"), GCP_KMS_SCOPE);
+  const res = await fetch(`https://cloudkms.googleapis.com/v1/${keyName}:decrypt`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json", Authorization: `Bearer ${token}` },
+    body: JSON.stringify({ ciphertext: wrapped }),
+  });
+  if (!res.ok) {
+    throw new Error(`GCP KMS decrypt failed: ${res.status}`);
+  }
+  const data = (await res.json()) as { plaintext?: string };
+  return data.plaintext ?? "";
+}
+
+async function vaultTransitDecrypt(wrapped: string) {
+  const addr = requiredEnv("VAULT_ADDR").replace(/\/+$/, "");
+  const mount = process.env.VAULT_TRANSIT_MOUNT?.trim() || "transit";
+  const res = await fetch(`${addr}/v1/${mount}/decrypt/${encodeURIComponent(requiredEnv("VAULT_TRANSIT_KEY"))}`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json", "X-Vault-Token": requiredEnv("VAULT_TOKEN") },
+    body: JSON.stringify({ ciphertext: wrapped }),
+  });
+  if (!res.ok) {
+    throw new Error(`Vault transit decrypt failed: ${res.status}`);
+  }
+  const data = (await res.json()) as { data?: { plaintext?: string } };
+  return data.data?.plaintext ?? "";
+}
+
+// Every backend returns the data key base64-encoded.
+export async function unwrapDataKey(backend: Exclude<SecretBackend, "env">, wrapped: string) {
+  const plaintext =
+    backend === "aws-kms" ? await awsKmsDecrypt(wrapped) : backend === "gcp-kms" ? await gcpKmsDecrypt(wrapped) : await vaultTransitDecrypt(wrapped);
+  const key = Buffer.from(plaintext, "base64");
+  if (key.length !== 32) {
+    throw new Error(`SECRET_WRAPPED_KEY must unwrap to a 32-byte key (got ${key.length} bytes)`);
+  }
+  return key;
+}
+
+let initialized: Promise<void> | null = null;
+
+// Called once per process (instrumentation register hook) before any channel credential is read.
+export function initSecretBackend() {
+  initialized ??= (async () => {
+    const backend = secretBackendName();
+    if (backend === "env") {
+      return;
+    }
+    setDataKey(await unwrapDataKey(backend, requiredEnv("SECRET_WRAPPED_KEY")), process.env.SECRET_WRAPPED_KEY_ID?.trim() || undefined);
+  })().catch((err: unknown) => {
+    initialized = null;
+    throw err;
+  });
+  return initialized;
+}
@@ -0,0 +1,36 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { renderTemplate } from "./template-functions";
+import { redactSecrets, resolveHeaderSecrets, resolveSecretRefs, secretTemplateFunctions, usesSecrets } from "./secrets";
+
+describe("secrets", () => {
+  const secrets = { NEWSAPI_KEY: "abcd1234efgh", PIN: "12" };
+
+  it("resolves secrets in templates through the secret function", () => {
+    const functions = secretTemplateFunctions(secrets);
+    expect(renderTemplate('key={{ secret "NEWSAPI_KEY" }} other={{ secret "MISSING" }}', {}, { functions })).toBe(
+      "key=abcd1234efgh other=",
+    );
+    expect(renderTemplate('{{ secret "NEWSAPI_KEY" }}', {})).toBe('{{ secret "NEWSAPI_KEY" }}');
+  });
+
+  it("detects templates that reference secrets", () => {
+    expect(usesSecrets("fetch with {{ secret \"NEWSAPI_KEY\" }}")).toBe(true);
+    expect(usesSecrets("{{ date }}", null)).toBe(false);
+  });
+
+  it("resolves secret references in webhook headers", () => {
+    expect(resolveSecretRefs("Bearer {{secret:NEWSAPI_KEY}} / {{ secret: MISSING }}", secrets)).toBe("Bearer abcd1234efgh / {{ secret: MISSING }}");
+    expect(usesSecrets('{"Authorization":"Bearer {{secret:NEWSAPI_KEY}}"}')).toBe(true);
+
+    const headers = resolveHeaderSecrets('{"Authorization":"Bearer {{secret:TOKEN}}","X-Id":"7"}', { TOKEN: 'a"b' });
+    expect(JSON.parse(headers)).toEqual({ Authorization: 'Bearer a"b', "X-Id": "7" });
+    expect(resolveHeaderSecrets("not json", secrets)).toBe("not json");
+  });
+
+  it("redacts secret values but ignores very short ones", () => {
+    expect(redactSecrets("called ?apiKey=abcd1234efgh with pin 12", secrets)).toBe("called ?apiKey=[secret:NEWSAPI_KEY] with pin 12");
+  });
+});
@@ -0,0 +1,75 @@
+import { prisma } from "@/lib/prisma";
+import { decryptString } from "@/lib/crypto";
+import { isRecord } from "@/lib/type-guards";
+import type { TemplateFunction } from "@/lib/template-functions";
+
+export const SECRET_NAME_RE = /^[A-Z][A-Z0-9_]{0,63}$/;
+
+// Values shorter than this are not redacted; they would match too much unrelated text.
+const REDACT_MIN_LENGTH = 4;
+
+const SECRET_CALL_RE = /{{[^{}]*\bsecret\b[^{}]*}}/;
+
+// Cheap check so runs that never reference secrets skip loading and decrypting them.
+export function usesSecrets(...templates: Array<string | null | undefined>) {
+  return templates.some((template) => !!template && SECRET_CALL_RE.test(template));
+}
+
+export async function loadUserSecrets(userId: string): Promise<Record<string, string>> {
+  const rows = await prisma.userSecret.findMany({ where: { userId }, select: { name: true, valueEnc: true } });
+  return Object.fromEntries(rows.map((row) => [row.name, decryptString(row.valueEnc)]));
+}
+
+// Header values may reference a secret by name ("Authorization": "Bearer {{secret:API_TOKEN}}") so rotating a
+// token means editing one secret instead of every job. Unknown names are left in place; delivery rejects them.
+const SECRET_REF_RE = /{{\s*secret:\s*([A-Za-z0-9_-]+)\s*}}/g;
+
+export function resolveSecretRefs(text: string, secrets: Record<string, string>): string {
+  return text.replace(SECRET_REF_RE, (ref, name: string) =>
+    Object.prototype.hasOwnProperty.call(secrets, name) ? secrets[name] : ref,
+  );
+}
+
+// Resolves references inside a webhook headers JSON object; values are substituted before re-encoding so a
+// secret containing quotes cannot break the JSON.
+export function resolveHeaderSecrets(headers: string, secrets: Record<string, string>): string {
+  let parsed: unknown;
+  try {
+    parsed = JSON.parse(headers);
+  } catch {
+    return headers;
+  }
+  if (!isRecord(parsed)) {
+    return headers;
+  }
+  const resolved = Object.fromEntries(
+    Object.entries(parsed).map(([key, value]) => [key, typeof value === "string" ? resolveSecretRefs(value, secrets) : value]),
+  );
+  return JSON.stringify(resolved);
+}
+
+// {{ secret "NEWSAPI_KEY" }}; unknown names render as an empty string.
+export function secretTemplateFunctions(secrets: Record<string, string>): Record<string, TemplateFunction> {
+  return {
+    secret: ([name = ""]) => (Object.prototype.hasOwnProperty.call(secrets, name) ? secrets[name] : ""),
+  };
+}
+
+export function redactSecretsInJson<T>(value: T, secrets: Record<string, string>): T {
+  if (value == null || !Object.keys(secrets).length) {
+    return value;
+  }
+  return JSON.parse(redactSecrets(JSON.stringify(value), secrets)) as T;
+}
+
+// Replaces secret values that leak into outputs, tool-call logs, or errors before they are stored or sent.
+export function redactSecrets(text: string, secrets: Record<string, string>): string {
+  let out = text;
+  const entries = Object.entries(secrets)
+    .filter(([, value]) => value.length >= REDACT_MIN_LENGTH)
+    .sort(([, a], [, b]) => b.length - a.length);
+  for (const [name, value] of entries) {
+    out = out.split(value).join(`[secret:${name}]`);
+  }
+  return out;
+}
@@ -0,0 +1,22 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { formatRuntime, formatSlowRunNotice, isSlowRun } from "./slow-runs";
+
+describe("slow runs", () => {
+  it("only flags runs past a declared expected runtime", () => {
+    expect(isSlowRun(61_000, 60)).toBe(true);
+    expect(isSlowRun(60_000, 60)).toBe(false);
+    expect(isSlowRun(600_000, null)).toBe(false);
+    expect(isSlowRun(600_000, 0)).toBe(false);
+  });
+
+  it("formats the notice", () => {
+    expect(formatRuntime(4_400)).toBe("4s");
+    expect(formatRuntime(120_000)).toBe("2m");
+    expect(formatSlowRunNotice(252_000, 60)).toBe(
+      "This run took 4m 12s, longer than the expected 1m. Check the prompt for growing output or tool loops.",
+    );
+  });
+});
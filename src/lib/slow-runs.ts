@@ -0,0 +1,35 @@
+import { prisma } from "@/lib/prisma";
+
+// Jobs may declare how long a run is expected to take (jobs.expected_runtime_seconds). Longer runs usually mean
+// the prompt drifted into huge outputs or tool loops: they are logged, counted in the worker's slowRuns metric
+// and, with slow_run_notice, announced on the job's channel at most once per SLOW_NOTICE_INTERVAL_MS.
+export const SLOW_NOTICE_INTERVAL_MS = 24 * 60 * 60 * 1000;
+
+export function isSlowRun(durationMs: number, expectedRuntimeSeconds: number | null | undefined) {
+  return expectedRuntimeSeconds != null && expectedRuntimeSeconds > 0 && durationMs > expectedRuntimeSeconds * 1000;
+}
+
+export function formatRuntime(ms: number) {
+  const seconds = Math.round(ms / 1000);
+  if (seconds < 60) {
+    return `${seconds}s`;
+  }
+  const minutes = Math.floor(seconds / 60);
+  return seconds % 60 ? `${minutes}m ${seconds % 60}s` : `${minutes}m`;
+}
+
+export function formatSlowRunNotice(durationMs: number, expectedRuntimeSeconds: number) {
+  return `This run took ${formatRuntime(durationMs)}, longer than the expected ${formatRuntime(expectedRuntimeSeconds * 1000)}. Check the prompt for growing output or tool loops.`;
+}
+
+// Claims the job's notice slot so concurrent or back-to-back slow runs send one notice per interval.
+export async function claimSlowRunNotice(jobId: string, now: Date) {
+  const claimed = await prisma.job.updateMany({
+    where: {
+      id: jobId,
+      OR: [{ slowRunNotifiedAt: null }, { slowRunNotifiedAt: { lt: new Date(now.getTime() - SLOW_NOTICE_INTERVAL_MS) } }],
+    },
+    data: { slowRunNotifiedAt: now },
+  });
+  return claimed.count > 0;
+}
@@ -0,0 +1,42 @@
+import { mkdtempSync, writeFileSync } from "node:fs";
+import { tmpdir } from "node:os";
+import { join } from "node:path";
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { SERVICE_SYSTEM_PROMPT, serviceSystemPrompt } from "./system-prompt";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("service system prompt", () => {
+  it("uses the built-in rules by default", () => {
+    vi.stubEnv("LLM_SYSTEM_PROMPT", "");
+    vi.stubEnv("LLM_SYSTEM_PROMPT_ADDENDUM", "");
+    expect(serviceSystemPrompt()).toBe(SERVICE_SYSTEM_PROMPT);
+  });
+
+  it("appends the deployment addendum to the built-in rules or an override", () => {
+    vi.stubEnv("LLM_SYSTEM_PROMPT_ADDENDUM", "Never give financial advice.");
+    expect(serviceSystemPrompt()).toBe(`${SERVICE_SYSTEM_PROMPT}\n\nNever give financial advice.`);
+    vi.stubEnv("LLM_SYSTEM_PROMPT", "You are Acme Reports.");
+    expect(serviceSystemPrompt()).toBe("You are Acme Reports.\n\nNever give financial advice.");
+  });
+
+  it("puts job instructions between the base rules and the addendum", () => {
+    vi.stubEnv("LLM_SYSTEM_PROMPT", "Base.");
+    vi.stubEnv("LLM_SYSTEM_PROMPT_ADDENDUM", "Safety.");
+    expect(serviceSystemPrompt("  Write like a pirate. ")).toBe(
+      "Base.\n\nJob instructions (follow them unless they conflict with the rules above):\nWrite like a pirate.\n\nSafety.",
+    );
+    expect(serviceSystemPrompt("   ")).toBe("Base.\n\nSafety.");
+  });
+
+  it("reads the override from a file", () => {
+    const path = join(mkdtempSync(join(tmpdir(), "promptloop-")), "system.txt");
+    writeFileSync(path, "From a file.\n");
+    vi.stubEnv("LLM_SYSTEM_PROMPT", "");
+    vi.stubEnv("LLM_SYSTEM_PROMPT_ADDENDUM", "");
+    vi.stubEnv("LLM_SYSTEM_PROMPT_FILE", path);
+    expect(serviceSystemPrompt()).toBe("From a file.");
+  });
+});
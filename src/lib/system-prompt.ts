@@ -1,3 +1,6 @@
+import { readFileSync } from "node:fs";
+import { JOB_SYSTEM_PROMPT_MAX } from "@/lib/llm-defaults";
+
 export const SERVICE_SYSTEM_PROMPT = `You are Promptloop, an automated scheduled execution agent.
 
 Follow these rules for every response:
@@ -11,3 +14,31 @@ Follow these rules for every response:
 8) Never include conversational closings like "(End of report)", signing off, or offering follow-up analysis. End the output abruptly after the content.
 9) If the request is impossible or unsafe, state the limitation briefly and provide the best valid alternative output.
 10) Output plain text only.`;
+
+
+// Files are read once per process.
+const fileCache = new Map<string, string>();
+
+function readPromptFile(path: string) {
+  let text = fileCache.get(path);
+  if (text === undefined) {
+    text = readFileSync(path, "utf8").trim();
+    fileCache.set(path, text);
+  }
+  return text;
+}
+
+// Per-deployment instruction policy. LLM_SYSTEM_PROMPT (or LLM_SYSTEM_PROMPT_FILE) replaces the built-in rules;
+// LLM_SYSTEM_PROMPT_ADDENDUM (or LLM_SYSTEM_PROMPT_ADDENDUM_FILE) is appended to whichever base is used, e.g. for
+// compliance text, branding, or safety rules. A job's own system prompt goes between the two, so the deployment
+// addendum always comes last.
+export function serviceSystemPrompt(jobSystemPrompt?: string | null) {
+  const overrideFile = process.env.LLM_SYSTEM_PROMPT_FILE?.trim();
+  const addendumFile = process.env.LLM_SYSTEM_PROMPT_ADDENDUM_FILE?.trim();
+  const base = process.env.LLM_SYSTEM_PROMPT?.trim() || (overrideFile ? readPromptFile(overrideFile) : "") || SERVICE_SYSTEM_PROMPT;
+  const addendum = process.env.LLM_SYSTEM_PROMPT_ADDENDUM?.trim() || (addendumFile ? readPromptFile(addendumFile) : "");
+  const job = jobSystemPrompt?.trim().slice(0, JOB_SYSTEM_PROMPT_MAX);
+  return [base, job ? `Job instructions (follow them unless they conflict with the rules above):\n${job}` : "", addendum]
+    .filter(Boolean)
+    .join("\n\n");
+}
@@ -0,0 +1,41 @@
+import { describe, expect, it } from "vitest";
+import { renderTemplate } from "./template-functions";
+
+describe("template functions", () => {
+  const values = { name: "Daily Brief", body: "hello world", now_iso: "2026-03-01T08:00:00.000Z" };
+
+  it("keeps plain variables and leaves unknown placeholders untouched", () => {
+    expect(renderTemplate("{{name}} / {{ missing }}", values)).toBe("Daily Brief / {{ missing }}");
+  });
+
+  it("accepts Go-template style field references", () => {
+    expect(renderTemplate("{{.name}} / {{ .body | upper }} / {{.missing}}", values)).toBe("Daily Brief / HELLO WORLD / {{.missing}}");
+    expect(renderTemplate("{{ .upper }}", values)).toBe("{{ .upper }}");
+  });
+
+  it("calls functions with variables, literals, and pipes", () => {
+    expect(renderTemplate("{{ upper name }}", values)).toBe("DAILY BRIEF");
+    expect(renderTemplate('{{ body | truncate 5 "..." | upper }}', values)).toBe("HELLO...");
+    expect(renderTemplate("{{ urlencode body }}", values)).toBe("hello%20world");
+    expect(renderTemplate("{{ json body }}", values)).toBe('"hello world"');
+    expect(renderTemplate('{{ default missing "n/a" }}', values)).toBe("n/a");
+    expect(renderTemplate('{{ random_choice "a|b" "c" }}', values, { random: () => 0 })).toBe("a|b");
+  });
+
+  it("does date math and formatting", () => {
+    expect(renderTemplate('{{ date_add now_iso "-1d" }}', values)).toBe("2026-02-28T08:00:00.000Z");
+    expect(renderTemplate('{{ now_iso | date_add "2h" | date_format "YYYY-MM-DD HH:mm" "Asia/Seoul" }}', values)).toBe(
+      "2026-03-01 19:00",
+    );
+  });
+
+  it("leaves malformed expressions alone and applies escaping to expansions only", () => {
+    expect(renderTemplate("{{ upper name extra( }}", values)).toBe("{{ upper name extra( }}");
+    expect(renderTemplate("<a>{{ body }}</a>", { body: "<b>" }, { escape: (v) => v.replace(/</g, "&lt;") })).toBe("<a>&lt;b></a>");
+  });
+
+  it("bounds the number of expansions", () => {
+    const out = renderTemplate("{{name}}".repeat(600), values);
+    expect(out.match(/Daily Brief/g)?.length).toBe(500);
+  });
+});
@@ -0,0 +1,173 @@
+import { getPartsInTimeZone, isValidTimeZone } from "@/lib/timezone";
+
+// Shared {{ ... }} templating for prompts and channel message/payload templates.
+//
+//   {{ name }}                         variable
+//   {{ .Name }}                        variable, Go-template style (never a function call)
+//   {{ upper name }}                   function call; args are variables, "quoted strings", or numbers
+//   {{ body | truncate 200 | upper }}  pipes pass the previous result as the first argument
+//
+// Templates cannot loop or recurse: function results are never re-scanned, and the number of expansions
+// and the size of each result are capped, so a template's cost is bounded by its length.
+
+export type TemplateFunction = (args: string[]) => string;
+
+export type RenderTemplateOptions = {
+  // Applied to each expansion (e.g. XML escaping); not applied to untouched text.
+  escape?: (value: string) => string;
+  // Extra functions for this render only (e.g. secret lookups); they cannot shadow the built-ins.
+  functions?: Record<string, TemplateFunction>;
+  random?: () => number;
+};
+
+const EXPRESSION_RE = /{{\s*([^{}]+?)\s*}}/g;
+const TOKEN_RE = /\s*(?:"((?:[^"\\]|\\.)*)"|(-?\d+(?:\.\d+)?)(?![\w.])|(\.?[A-Za-z_][A-Za-z0-9_]*))/y;
+const MAX_EXPANSIONS = 500;
+const MAX_RESULT_CHARS = 100_000;
+const MAX_ARGS = 32;
+
+type Token = { kind: "string" | "number" | "ident" | "field"; value: string };
+
+function tokenize(segment: string): Token[] | null {
+  const tokens: Token[] = [];
+  TOKEN_RE.lastIndex = 0;
+  while (TOKEN_RE.lastIndex < segment.length) {
+    if (!segment.slice(TOKEN_RE.lastIndex).trim()) {
+      break;
+    }
+    const match = TOKEN_RE.exec(segment);
+    if (!match) {
+      return null;
+    }
+    if (match[1] !== undefined) tokens.push({ kind: "string", value: match[1].replace(/\\(.)/g, "$1") });
+    else if (match[2] !== undefined) tokens.push({ kind: "number", value: match[2] });
+    else if (match[3].startsWith(".")) tokens.push({ kind: "field", value: match[3].slice(1) });
+    else tokens.push({ kind: "ident", value: match[3] });
+    if (tokens.length > MAX_ARGS) {
+      return null;
+    }
+  }
+  return tokens;
+}
+
+// Splits on pipes outside quoted strings.
+function splitPipes(expression: string): string[] {
+  const segments: string[] = [];
+  let start = 0;
+  let quoted = false;
+  for (let i = 0; i < expression.length; i++) {
+    const ch = expression[i];
+    if (quoted && ch === "\\") {
+      i++;
+    } else if (ch === '"') {
+      quoted = !quoted;
+    } else if (ch === "|" && !quoted) {
+      segments.push(expression.slice(start, i));
+      start = i + 1;
+    }
+  }
+  segments.push(expression.slice(start));
+  return segments;
+}
+
+function parseDate(value: string): Date | null {
+  if (!value.trim()) return null;
+  const date = new Date(value);
+  return Number.isNaN(date.getTime()) ? null : date;
+}
+
+const DURATION_MS: Record<string, number> = { m: 60_000, h: 3_600_000, d: 86_400_000, w: 604_800_000 };
+
+function pad(n: number, width = 2) {
+  return String(n).padStart(width, "0");
+}
+
+function builtinFunctions(random: () => number): Record<string, TemplateFunction> {
+  return {
+    upper: ([value = ""]) => value.toUpperCase(),
+    lower: ([value = ""]) => value.toLowerCase(),
+    trim: ([value = ""]) => value.trim(),
+    truncate: ([value = "", max = "100", suffix = ""]) => {
+      const limit = Math.max(0, Math.min(Math.floor(Number(max) || 0), MAX_RESULT_CHARS));
+      return value.length > limit ? `${value.slice(0, limit)}${suffix}` : value;
+    },
+    default: ([value = "", fallback = ""]) => (value.trim() ? value : fallback),
+    json: ([value = ""]) => JSON.stringify(value),
+    urlencode: ([value = ""]) => encodeURIComponent(value),
+    random_choice: (args) => (args.length ? args[Math.min(args.length - 1, Math.floor(random() * args.length))] : ""),
+    // date_add now_iso "-1d" -> ISO timestamp; units m, h, d, w.
+    date_add: ([value = "", amount = ""]) => {
+      const date = parseDate(value);
+      const match = /^(-?\d+)([mhdw])$/.exec(amount.trim());
+      if (!date || !match) return "";
+      return new Date(date.getTime() + Number(match[1]) * DURATION_MS[match[2]]).toISOString();
+    },
+    // date_format now_iso "YYYY-MM-DD HH:mm" "Europe/Berlin"
+    date_format: ([value = "", pattern = "YYYY-MM-DD", timeZone = "UTC"]) => {
+      const date = parseDate(value);
+      if (!date) return "";
+      const p = getPartsInTimeZone(date, isValidTimeZone(timeZone) ? timeZone : "UTC");
+      const fields: Record<string, string> = {
+        YYYY: String(p.year),
+        MM: pad(p.month),
+        DD: pad(p.day),
+        HH: pad(p.hour),
+        mm: pad(p.minute),
+        ss: pad(p.second),
+      };
+      return pattern.replace(/YYYY|MM|DD|HH|mm|ss/g, (token) => fields[token]);
+    },
+  };
+}
+
+function argValue(token: Token, values: Record<string, string>): string {
+  if (token.kind === "string" || token.kind === "number") return token.value;
+  return Object.prototype.hasOwnProperty.call(values, token.value) ? values[token.value] : "";
+}
+
+// Returns null when the expression cannot be evaluated, so the placeholder is left untouched.
+function evaluate(
+  expression: string,
+  values: Record<string, string>,
+  functions: Record<string, TemplateFunction>,
+): string | null {
+  const segments = splitPipes(expression);
+  let current: string | null = null;
+
+  for (const [index, segment] of segments.entries()) {
+    const tokens = tokenize(segment);
+    if (!tokens || !tokens.length) {
+      return null;
+    }
+    const [head, ...rest] = tokens;
+    const fn = head.kind === "ident" && Object.prototype.hasOwnProperty.call(functions, head.value) ? functions[head.value] : null;
+
+    if (!fn) {
+      // Plain value: only valid as the first segment and without arguments. Unknown variables stay as written.
+      if (index > 0 || rest.length) return null;
+      if ((head.kind === "ident" || head.kind === "field") && !Object.prototype.hasOwnProperty.call(values, head.value)) return null;
+      current = argValue(head, values);
+      continue;
+    }
+
+    const args = rest.map((token) => argValue(token, values));
+    current = fn(current === null ? args : [current, ...args]).slice(0, MAX_RESULT_CHARS);
+  }
+
+  return current;
+}
+
+export function renderTemplate(template: string, values: Record<string, string>, opts?: RenderTemplateOptions): string {
+  const functions = { ...(opts?.functions ?? {}), ...builtinFunctions(opts?.random ?? Math.random) };
+  let expansions = 0;
+  return template.replace(EXPRESSION_RE, (full, expression: string) => {
+    if (++expansions > MAX_EXPANSIONS) {
+      return full;
+    }
+    const result = evaluate(expression, values, functions);
+    if (result === null) {
+      return full;
+    }
+    return opts?.escape ? opts.escape(result) : result;
+  });
+}
@@ -0,0 +1,31 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { newTenantShare, recordTenantClaim, tenantClaimFilter, tenantFairnessPolicy } from "./tenant-fairness";
+
+describe("tenant fairness", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+  });
+
+  it("reads the caps, ignoring invalid values", () => {
+    expect(tenantFairnessPolicy()).toEqual({ perTick: 0, perHour: 0 });
+    vi.stubEnv("WORKER_USER_RUNS_PER_TICK", "5");
+    vi.stubEnv("WORKER_USER_RUNS_PER_HOUR", "-3");
+    expect(tenantFairnessPolicy()).toEqual({ perTick: 5, perHour: 0 });
+  });
+
+  it("puts served users last and caps them per tick", () => {
+    const share = newTenantShare({ perTick: 2, perHour: 100 }, ["busy"]);
+    expect(tenantClaimFilter(share)).toEqual({ served: [], capped: ["busy"] });
+    recordTenantClaim(share, "a");
+    recordTenantClaim(share, "b");
+    expect(tenantClaimFilter(share)).toEqual({ served: ["a", "b"], capped: ["busy"] });
+    recordTenantClaim(share, "a");
+    expect(tenantClaimFilter(share).capped).toEqual(["busy", "a"]);
+
+    const uncapped = newTenantShare({ perTick: 0, perHour: 0 });
+    for (let i = 0; i < 10; i++) {
+      recordTenantClaim(uncapped, "a");
+    }
+    expect(tenantClaimFilter(uncapped)).toEqual({ served: ["a"], capped: [] });
+  });
+});
@@ -0,0 +1,36 @@
+// Fair share of the due-job queue between users, so one account with hundreds of every-minute jobs cannot starve
+// everyone else's daily digests. Within a tick, users who have not had a job claimed yet go ahead of those who
+// have (after job priority), which round-robins across tenants. Optional caps stop claiming a user's jobs for the
+// rest of the tick (WORKER_USER_RUNS_PER_TICK) or while they already ran that many jobs in the past hour
+// (WORKER_USER_RUNS_PER_HOUR); held jobs stay due and run in a later tick. 0 turns a cap off.
+
+export type TenantFairnessPolicy = { perTick: number; perHour: number };
+
+// Per-tick claim bookkeeping; `capped` starts with the users already over the hourly cap.
+export type TenantShare = { policy: TenantFairnessPolicy; claims: Map<string, number>; capped: Set<string> };
+
+function envCap(name: string) {
+  const value = Number(process.env[name] ?? 0);
+  return Number.isInteger(value) && value > 0 ? value : 0;
+}
+
+export function tenantFairnessPolicy(): TenantFairnessPolicy {
+  return { perTick: envCap("WORKER_USER_RUNS_PER_TICK"), perHour: envCap("WORKER_USER_RUNS_PER_HOUR") };
+}
+
+export function newTenantShare(policy: TenantFairnessPolicy, overHourlyCap: string[] = []): TenantShare {
+  return { policy, claims: new Map(), capped: new Set(overHourlyCap) };
+}
+
+export function recordTenantClaim(share: TenantShare, userId: string) {
+  const count = (share.claims.get(userId) ?? 0) + 1;
+  share.claims.set(userId, count);
+  if (share.policy.perTick > 0 && count >= share.policy.perTick) {
+    share.capped.add(userId);
+  }
+}
+
+// Users served this tick (claimed after the others) and users whose jobs are not claimed at all.
+export function tenantClaimFilter(share: TenantShare) {
+  return { served: Array.from(share.claims.keys()), capped: Array.from(share.capped) };
+}
@@ -0,0 +1,42 @@
+import { describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { formatThrottleDigest, normalizeThrottleWindow, throttleWindowStart } from "./throttle";
+
+describe("delivery throttle", () => {
+  it("uses rolling windows", () => {
+    const now = new Date("2026-04-09T12:30:00Z");
+    expect(throttleWindowStart("hour", now).toISOString()).toBe("2026-04-09T11:30:00.000Z");
+    expect(throttleWindowStart("day", now).toISOString()).toBe("2026-04-08T12:30:00.000Z");
+    expect(normalizeThrottleWindow("day")).toBe("day");
+    expect(normalizeThrottleWindow("week")).toBe("hour");
+  });
+
+  it("formats held outputs as one digest", () => {
+    const text = formatThrottleDigest(
+      [
+        { runAt: new Date("2026-04-09T08:00:00Z"), output: "first\n" },
+        { runAt: new Date("2026-04-09T09:00:00Z"), output: "second" },
+      ],
+      { limit: 2, window: "hour", timeZone: "Asia/Seoul", remaining: 3 },
+    );
+    expect(text).toBe(
+      [
+        "2 outputs were held back by this job's delivery limit (2 per hour).",
+        "",
+        "### 2026-04-09 17:00",
+        "",
+        "first",
+        "",
+        "---",
+        "",
+        "### 2026-04-09 18:00",
+        "",
+        "second",
+        "",
+        "3 more will follow in the next digest.",
+      ].join("\n"),
+    );
+  });
+});
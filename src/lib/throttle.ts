@@ -0,0 +1,59 @@
+import { prisma } from "@/lib/prisma";
+import { clock } from "@/lib/clock";
+import { getPartsInTimeZone } from "@/lib/timezone";
+
+// Per-job delivery throttle: at most throttle_limit messages per rolling hour or day. Outputs over the limit are
+// held (run_histories.throttled_at) and sent together as one digest message once the window has room again.
+export const THROTTLE_WINDOWS = ["hour", "day"] as const;
+export type ThrottleWindow = (typeof THROTTLE_WINDOWS)[number];
+
+// Outputs per digest message; a longer backlog is sent over several digests.
+export const DIGEST_MAX_RUNS = 20;
+
+const WINDOW_MS: Record<ThrottleWindow, number> = { hour: 60 * 60 * 1000, day: 24 * 60 * 60 * 1000 };
+
+export function normalizeThrottleWindow(value: unknown): ThrottleWindow {
+  return THROTTLE_WINDOWS.includes(value as ThrottleWindow) ? (value as ThrottleWindow) : "hour";
+}
+
+export function throttleWindowStart(window: ThrottleWindow, now = clock().now()) {
+  return new Date(now.getTime() - WINDOW_MS[window]);
+}
+
+// Messages sent since `since`. Runs delivered together in one digest share a delivered_at, so counting distinct
+// timestamps counts the digest once.
+export async function messagesSince(jobId: string, since: Date) {
+  const rows = await prisma.$queryRaw<Array<{ messages: bigint }>>`
+    SELECT count(DISTINCT delivered_at) AS messages
+    FROM run_histories
+    WHERE job_id = ${jobId}::uuid AND delivered_at >= ${since}
+  `;
+  return Number(rows[0]?.messages ?? 0);
+}
+
+export async function hasThrottleRoom(
+  job: { id: string; throttleLimit: number | null; throttleWindow: string },
+  now = clock().now(),
+) {
+  if (!job.throttleLimit) {
+    return true;
+  }
+  const used = await messagesSince(job.id, throttleWindowStart(normalizeThrottleWindow(job.throttleWindow), now));
+  return used < job.throttleLimit;
+}
+
+function formatStamp(at: Date, timeZone: string) {
+  const parts = getPartsInTimeZone(at, timeZone);
+  const pad = (value: number) => String(value).padStart(2, "0");
+  return `${parts.year}-${pad(parts.month)}-${pad(parts.day)} ${pad(parts.hour % 24)}:${pad(parts.minute)}`;
+}
+
+export function formatThrottleDigest(
+  runs: Array<{ runAt: Date; output: string }>,
+  opts: { limit: number; window: ThrottleWindow; timeZone: string; remaining?: number },
+) {
+  const header = `${runs.length} output${runs.length === 1 ? " was" : "s were"} held back by this job's delivery limit (${opts.limit} per ${opts.window}).`;
+  const more = opts.remaining ? `\n\n${opts.remaining} more will follow in the next digest.` : "";
+  const sections = runs.map((run) => `### ${formatStamp(run.runAt, opts.timeZone)}\n\n${run.output.trim()}`);
+  return `${header}\n\n${sections.join("\n\n---\n\n")}${more}`;
+}
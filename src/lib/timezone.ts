@@ -25,7 +25,7 @@ function parseTimeString(value: string) {
   return { hour: Number(match[1]), minute: Number(match[2]) };
 }
 
-function getPartsInTimeZone(date: Date, timeZone: string): DateParts {
+export function getPartsInTimeZone(date: Date, timeZone: string): DateParts {
   const parts = new Intl.DateTimeFormat("en-US", {
     timeZone,
     year: "numeric",
@@ -76,6 +76,48 @@ export function getTimeZoneOffsetMinutes(date: Date, timeZone: string): number {
   return Math.round((asUtcMs - date.getTime()) / 60000);
 }
 
+export function isValidTimeZone(timeZone: string): boolean {
+  try {
+    new Intl.DateTimeFormat("en-US", { timeZone });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+// Resolves a wall-clock time in timeZone to an instant. Day overflow (day=32 etc.) rolls over like Date.UTC.
+// Times skipped by a DST jump resolve to the equivalent instant after the jump.
+export function zonedWallTimeToUtc(
+  year: number,
+  month: number,
+  day: number,
+  hour: number,
+  minute: number,
+  timeZone: string,
+): Date {
+  const asUtcMs = Date.UTC(year, month - 1, day, hour, minute, 0, 0);
+  const firstOffset = getTimeZoneOffsetMinutes(new Date(asUtcMs), timeZone);
+  const candidateMs = asUtcMs - firstOffset * 60000;
+  const secondOffset = getTimeZoneOffsetMinutes(new Date(candidateMs), timeZone);
+  return new Date(secondOffset === firstOffset ? candidateMs : asUtcMs - secondOffset * 60000);
+}
+
+// Formats an instant as a datetime-local value ("YYYY-MM-DDTHH:mm") in timeZone.
+export function formatDateTimeLocalInTimeZone(date: Date, timeZone: string): string {
+  const p = getPartsInTimeZone(date, timeZone);
+  const pad = (n: number) => String(n).padStart(2, "0");
+  return `${p.year}-${pad(p.month)}-${pad(p.day)}T${pad(p.hour)}:${pad(p.minute)}`;
+}
+
+export function parseDateTimeLocalInTimeZone(value: string, timeZone: string): Date | null {
+  const match = /^(\d{4})-(\d{2})-(\d{2})T(\d{2}):(\d{2})/.exec(value);
+  if (!match) {
+    return null;
+  }
+  const [, year, month, day, hour, minute] = match.map(Number);
+  return zonedWallTimeToUtc(year, month, day, hour, minute, timeZone);
+}
+
 export function formatUtcOffset(timeZone: string, date = new Date()): string {
   const offsetMinutes = getTimeZoneOffsetMinutes(date, timeZone);
   const sign = offsetMinutes >= 0 ? "+" : "-";
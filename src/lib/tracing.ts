@@ -0,0 +1,20 @@
+import { SpanStatusCode, trace, type Attributes, type Span } from "@opentelemetry/api";
+
+// Spans go through the global OpenTelemetry API. instrumentation.ts registers the OTLP exporter (otlp-traces.ts)
+// when OTEL_EXPORTER_OTLP_ENDPOINT is set; otherwise they are no-ops.
+const tracer = trace.getTracer("promptloop");
+
+export async function withSpan<T>(name: string, attributes: Attributes, fn: (span: Span) => Promise<T>): Promise<T> {
+  return tracer.startActiveSpan(name, { attributes }, async (span) => {
+    try {
+      return await fn(span);
+    } catch (err) {
+      const message = err instanceof Error ? err.message : String(err);
+      span.recordException(err instanceof Error ? err : message);
+      span.setStatus({ code: SpanStatusCode.ERROR, message });
+      throw err;
+    } finally {
+      span.end();
+    }
+  });
+}
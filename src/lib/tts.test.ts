@@ -0,0 +1,37 @@
+import { describe, expect, it } from "vitest";
+import { normalizeTtsVoice } from "./llm-defaults";
+import { speechText, TTS_INPUT_MAX } from "./tts";
+
+describe("tts", () => {
+  it("strips markdown that should not be read aloud", () => {
+    const output = [
+      "# Morning briefing",
+      "",
+      "- **Markets** are up, see [the report](https://example.com/r).",
+      "- Rain after `noon`.",
+      "",
+      "```js",
+      "console.log(1);",
+      "```",
+      "| City | Temp |",
+      "| --- | ---: |",
+      "| Oslo | 4 |",
+      "More at https://example.com/more",
+    ].join("\n");
+    expect(speechText(output)).toBe(
+      ["Morning briefing", "", "Markets are up, see the report.", "Rain after noon.", "", "City Temp", "Oslo 4", "More at"].join("\n"),
+    );
+  });
+
+  it("cuts long outputs at a sentence end", () => {
+    const text = speechText("This is one sentence. ".repeat(400));
+    expect(text.length).toBeLessThanOrEqual(TTS_INPUT_MAX);
+    expect(text.endsWith("sentence.")).toBe(true);
+  });
+
+  it("accepts only known voices", () => {
+    expect(normalizeTtsVoice("nova")).toBe("nova");
+    expect(normalizeTtsVoice("robot")).toBeNull();
+    expect(normalizeTtsVoice(null)).toBeNull();
+  });
+});
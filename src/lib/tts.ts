@@ -0,0 +1,49 @@
+import { experimental_generateSpeech as generateSpeech } from "ai";
+import { openaiProvider } from "@/lib/openai-keys";
+import type { GeneratedRunFile } from "@/lib/ai-result";
+import type { TtsVoice } from "@/lib/llm-defaults";
+
+// The speech API accepts at most 4096 characters per request.
+export const TTS_INPUT_MAX = 4096;
+const DEFAULT_TTS_MODEL = "gpt-4o-mini-tts";
+const DEFAULT_TTS_TIMEOUT_MS = 60_000;
+
+// Markdown read aloud is noise: code blocks, link targets, table rules and emphasis markers are dropped.
+// Long outputs are cut at the last sentence end before the limit.
+export function speechText(output: string) {
+  const text = output
+    .replace(/\r\n/g, "\n")
+    .replace(/```[\s\S]*?(```|$)/g, "")
+    .replace(/!\[[^\]]*\]\([^)]*\)/g, "")
+    .replace(/\[([^\]]+)\]\([^)]*\)/g, "$1")
+    .replace(/https?:\/\/\S+/g, "")
+    .replace(/^[ \t:|-]*-[ \t:|-]*\|[ \t:|-]*(?:\n|$)/gm, "")
+    .replace(/\|/g, " ")
+    .replace(/^#{1,6}[ \t]+/gm, "")
+    .replace(/^[ \t]*([-*+]|\d+\.)[ \t]+/gm, "")
+    .replace(/^[ \t]*>[ \t]?/gm, "")
+    .replace(/(\*\*|__|\*|_|~~|`)/g, "")
+    .replace(/[ \t]+/g, " ")
+    .replace(/ *\n */g, "\n")
+    .replace(/\n{3,}/g, "\n\n")
+    .trim();
+  if (text.length <= TTS_INPUT_MAX) {
+    return text;
+  }
+  const cut = text.slice(0, TTS_INPUT_MAX);
+  const end = Math.max(cut.lastIndexOf(". "), cut.lastIndexOf(".\n"), cut.lastIndexOf("\n\n"));
+  return end > TTS_INPUT_MAX / 2 ? cut.slice(0, end + 1).trim() : cut;
+}
+
+// Ogg/Opus, which Telegram plays as a voice message and Discord plays inline.
+export async function synthesizeSpeech(text: string, voice: TtsVoice): Promise<GeneratedRunFile> {
+  const timeoutMs = Number(process.env.TTS_TIMEOUT_MS ?? DEFAULT_TTS_TIMEOUT_MS);
+  const { audio } = await generateSpeech({
+    model: openaiProvider().speech(process.env.TTS_MODEL?.trim() || DEFAULT_TTS_MODEL),
+    text,
+    voice,
+    outputFormat: "opus",
+    abortSignal: AbortSignal.timeout(Number.isFinite(timeoutMs) && timeoutMs > 0 ? timeoutMs : DEFAULT_TTS_TIMEOUT_MS),
+  });
+  return { mediaType: "audio/ogg", data: audio.uint8Array };
+}
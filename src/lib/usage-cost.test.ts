@@ -0,0 +1,48 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { estimateCostUsd, runUsageColumns, summarizeUsage } from "./usage-cost";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("usage cost", () => {
+  it("reads AI SDK usage and sums primary + post prompt usage", () => {
+    expect(summarizeUsage({ inputTokens: 1200, outputTokens: 300, totalTokens: 1500 })).toEqual({
+      promptTokens: 1200,
+      completionTokens: 300,
+    });
+    expect(
+      summarizeUsage({ primary: { inputTokens: 1000, outputTokens: 200 }, post: { inputTokens: 400, outputTokens: 100 } }),
+    ).toEqual({ promptTokens: 1400, completionTokens: 300 });
+    expect(
+      summarizeUsage({ primary: { inputTokens: 1000, outputTokens: 200 }, post: null, diff: { inputTokens: 50, outputTokens: 10 } }),
+    ).toEqual({ promptTokens: 1050, completionTokens: 210 });
+    expect(summarizeUsage(null)).toBeNull();
+    expect(summarizeUsage({ primary: null, post: null })).toBeNull();
+  });
+
+  it("prices known models, including dated snapshots and provider prefixes", () => {
+    const tokens = { promptTokens: 1_000_000, completionTokens: 100_000 };
+    expect(estimateCostUsd("gpt-5-mini", tokens)).toBe(0.45);
+    expect(estimateCostUsd("openai/gpt-5-mini-2025-08-07", tokens)).toBe(0.45);
+    expect(estimateCostUsd("gpt-5", tokens)).toBe(2.25);
+    expect(estimateCostUsd("my-local-model", tokens)).toBeNull();
+  });
+
+  it("lets LLM_PRICING_JSON override prices", () => {
+    vi.stubEnv("LLM_PRICING_JSON", JSON.stringify({ "my-local-model": { input: 1, output: 1 } }));
+    expect(estimateCostUsd("my-local-model", { promptTokens: 500_000, completionTokens: 500_000 })).toBe(1);
+  });
+
+  it("builds run history columns", () => {
+    expect(runUsageColumns("gpt-5-mini", { inputTokens: 2000, outputTokens: 500 })).toEqual({
+      promptTokens: 2000,
+      completionTokens: 500,
+      costUsd: 0.0015,
+    });
+    expect(runUsageColumns("gpt-5-mini", undefined)).toEqual({ promptTokens: null, completionTokens: null, costUsd: null });
+  });
+});
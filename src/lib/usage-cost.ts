@@ -0,0 +1,140 @@
+import { Prisma } from "@prisma/client";
+import { prisma } from "@/lib/prisma";
+import { isRecord } from "@/lib/type-guards";
+
+export type TokenCounts = { promptTokens: number; completionTokens: number };
+
+// USD per 1M tokens (OpenAI list prices). Override or extend with LLM_PRICING_JSON,
+// e.g. {"gpt-5-mini": {"input": 0.25, "output": 2}}.
+const DEFAULT_PRICING: Record<string, { input: number; output: number }> = {
+  "gpt-5": { input: 1.25, output: 10 },
+  "gpt-5-mini": { input: 0.25, output: 2 },
+  "gpt-5-nano": { input: 0.05, output: 0.4 },
+  "gpt-4.1": { input: 2, output: 8 },
+  "gpt-4.1-mini": { input: 0.4, output: 1.6 },
+  "gpt-4.1-nano": { input: 0.1, output: 0.4 },
+  "gpt-4o": { input: 2.5, output: 10 },
+  "gpt-4o-mini": { input: 0.15, output: 0.6 },
+};
+
+function pricingTable() {
+  const raw = process.env.LLM_PRICING_JSON?.trim();
+  if (!raw) {
+    return DEFAULT_PRICING;
+  }
+  try {
+    const parsed = JSON.parse(raw) as unknown;
+    return isRecord(parsed) ? { ...DEFAULT_PRICING, ...(parsed as typeof DEFAULT_PRICING) } : DEFAULT_PRICING;
+  } catch {
+    return DEFAULT_PRICING;
+  }
+}
+
+function tokenCount(value: unknown) {
+  return typeof value === "number" && Number.isFinite(value) && value >= 0 ? value : 0;
+}
+
+function countsFromUsage(usage: unknown): TokenCounts | null {
+  if (!isRecord(usage)) {
+    return null;
+  }
+  return {
+    promptTokens: tokenCount(usage.inputTokens ?? usage.promptTokens),
+    completionTokens: tokenCount(usage.outputTokens ?? usage.completionTokens),
+  };
+}
+
+// Accepts the stored llm_usage shape: an AI SDK usage object, or { primary, steps?, post, diff? } when pipeline
+// steps, a post prompt or a change summary ran.
+export function summarizeUsage(usage: unknown): TokenCounts | null {
+  if (isRecord(usage) && ("primary" in usage || "post" in usage)) {
+    const steps = Array.isArray(usage.steps) ? usage.steps : [];
+    const parts = [usage.primary, ...steps, usage.post, usage.diff].map(countsFromUsage).filter(
+      (part): part is TokenCounts => !!part,
+    );
+    if (!parts.length) {
+      return null;
+    }
+    return {
+      promptTokens: parts.reduce((sum, part) => sum + part.promptTokens, 0),
+      completionTokens: parts.reduce((sum, part) => sum + part.completionTokens, 0),
+    };
+  }
+  return countsFromUsage(usage);
+}
+
+// Dated snapshots ("gpt-5-mini-2025-08-07") use the price of their base model; unknown models have no estimate.
+export function estimateCostUsd(model: string | null | undefined, tokens: TokenCounts): number | null {
+  const id = (model ?? "").trim().toLowerCase().replace(/^openai\//, "");
+  if (!id) {
+    return null;
+  }
+  const table = pricingTable();
+  const key = table[id] ? id : Object.keys(table).filter((name) => id.startsWith(`${name}-`)).sort((a, b) => b.length - a.length)[0];
+  const price = key ? table[key] : undefined;
+  if (!price) {
+    return null;
+  }
+  const cost = (tokens.promptTokens * price.input + tokens.completionTokens * price.output) / 1_000_000;
+  return Math.round(cost * 1_000_000) / 1_000_000;
+}
+
+// Run history columns for a finished LLM call.
+export function runUsageColumns(model: string | null | undefined, usage: unknown) {
+  const tokens = summarizeUsage(usage);
+  return {
+    promptTokens: tokens?.promptTokens ?? null,
+    completionTokens: tokens?.completionTokens ?? null,
+    costUsd: tokens ? estimateCostUsd(model, tokens) : null,
+  };
+}
+
+export type UsageRollupRow = {
+  jobId: string;
+  jobName: string;
+  runs: number;
+  promptTokens: number;
+  completionTokens: number;
+  costUsd: number;
+};
+
+// Per-job token and cost totals for one user since a point in time (previews included: they are billed too).
+export async function getUsageRollup(userId: string, opts: { since: Date; jobId?: string }) {
+  const rows = await prisma.$queryRaw<
+    Array<{ job_id: string; job_name: string; runs: bigint; prompt_tokens: bigint | null; completion_tokens: bigint | null; cost_usd: number | null }>
+  >`
+    SELECT
+      j.id AS job_id,
+      j.name AS job_name,
+      COUNT(r.id) AS runs,
+      SUM(r.prompt_tokens) AS prompt_tokens,
+      SUM(r.completion_tokens) AS completion_tokens,
+      SUM(r.cost_usd) AS cost_usd
+    FROM run_histories r
+    JOIN jobs j ON j.id = r.job_id
+    WHERE j.user_id = ${userId}::uuid
+      AND r.run_at >= ${opts.since}
+      ${opts.jobId ? Prisma.sql`AND j.id = ${opts.jobId}::uuid` : Prisma.empty}
+    GROUP BY j.id, j.name
+    ORDER BY cost_usd DESC NULLS LAST, runs DESC
+  `;
+
+  const jobs: UsageRollupRow[] = rows.map((row) => ({
+    jobId: row.job_id,
+    jobName: row.job_name,
+    runs: Number(row.runs),
+    promptTokens: Number(row.prompt_tokens ?? 0),
+    completionTokens: Number(row.completion_tokens ?? 0),
+    costUsd: Number(row.cost_usd ?? 0),
+  }));
+  const total = jobs.reduce(
+    (sum, job) => ({
+      runs: sum.runs + job.runs,
+      promptTokens: sum.promptTokens + job.promptTokens,
+      completionTokens: sum.completionTokens + job.completionTokens,
+      costUsd: Math.round((sum.costUsd + job.costUsd) * 1_000_000) / 1_000_000,
+    }),
+    { runs: 0, promptTokens: 0, completionTokens: 0, costUsd: 0 },
+  );
+  return { since: opts.since, total, jobs };
+}
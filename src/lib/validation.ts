@@ -1,13 +1,54 @@
 import { z } from "zod";
-import { DEFAULT_LLM_MODEL, DEFAULT_WEB_SEARCH_MODE } from "@/lib/llm-defaults";
+import { DEFAULT_LLM_MODEL, DEFAULT_WEB_SEARCH_MODE, JOB_SYSTEM_PROMPT_MAX, TTS_VOICES } from "@/lib/llm-defaults";
+import { isValidTimeZone } from "@/lib/timezone";
+import { DELIVER_IF_MODES, isValidDeliverIfPattern } from "@/lib/deliver-if";
+import { DELIVERY_DIFF_MODES } from "@/lib/output-diff";
+import { MAX_OUTPUT_CHARS_MAX, MAX_OUTPUT_CHARS_MIN } from "@/lib/output-length";
+import { THROTTLE_WINDOWS } from "@/lib/throttle";
+import { isValidRetrySchedule } from "@/lib/delivery-retry";
+import { llmToolsSchema } from "@/lib/llm-tools";
+import { pipelineStepsSchema } from "@/lib/pipeline";
+import { JOB_PRIORITIES } from "@/lib/job-priority";
+import { MODEL_FALLBACKS_MAX } from "@/lib/model-fallback";
+import { RESPONSE_CACHE_TTL_MAX_MINUTES } from "@/lib/response-cache";
+import { AUTO_DISABLE_POLICIES } from "@/lib/auto-disable";
+import { CHANNEL_PLUGIN_NAME_RE, pluginConfigError } from "@/lib/channel-plugins";
+import { insecureTlsAllowed } from "@/lib/webhook-transport";
+import { JOB_MAX_OUTPUT_TOKENS_LIMIT, REASONING_EFFORTS, TEXT_VERBOSITIES } from "@/lib/generation-params";
 
-const discordConfigSchema = z.object({
-  webhookUrl: z.string().url(),
-});
+// OpenAI ids ("gpt-5-mini", "openai/gpt-5-mini") or OpenRouter ids ("openrouter/anthropic/claude-3.5-haiku").
+const LLM_MODEL_RE = /^(?:openai\/|openrouter\/(?:[A-Za-z0-9._-]+\/)?)?[A-Za-z0-9._:-]+$/;
+
+// Job input sources (prompt-inputs.ts): pages or feeds fetched before the model call.
+export const PROMPT_INPUTS_MAX = 5;
+export const promptInputSchema = z
+  .object({
+    url: z
+      .string()
+      .trim()
+      .url()
+      .max(2000)
+      .regex(/^https?:\/\//i, "Input URLs must use http or https"),
+    label: z.string().trim().max(60).optional().default(""),
+  })
+  .strict();
+export const promptInputsSchema = z.array(promptInputSchema).max(PROMPT_INPUTS_MAX);
+export type PromptInput = z.infer<typeof promptInputSchema>;
+
+const discordConfigSchema = z
+  .object({
+    webhookUrl: z.string().url(),
+    // Post into an existing thread or forum post, or start a new forum post per run named by this template.
+    threadId: z.string().trim().max(25).regex(/^\d*$/, "Thread ID must be a numeric Discord ID").default(""),
+    threadName: z.string().max(500).default(""),
+  })
+  .refine((config) => !(config.threadId && config.threadName.trim()), { message: "Set either a thread ID or a new thread name", path: ["threadName"] });
 
 const telegramConfigSchema = z.object({
   botToken: z.string().min(10),
   chatId: z.string().min(1),
+  // Forum topic (message_thread_id) in a supergroup with topics enabled.
+  messageThreadId: z.string().trim().max(20).regex(/^\d*$/, "Topic ID must be numeric").default(""),
 });
 
 const webhookConfigSchema = z.object({
@@ -15,7 +56,29 @@ const webhookConfigSchema = z.object({
   method: z.enum(["GET", "POST", "PUT", "PATCH", "DELETE"]).default("POST"),
   headers: z.string().default("{}"),
   payload: z.string().default(""),
+  graphqlQuery: z.string().max(8000).default(""),
+  bodyFormat: z.enum(["json", "xml"]).default("json"),
+  gzip: z.boolean().default(false),
+  signingSecret: z.string().max(256).default(""),
+  captureResponse: z.boolean().default(false),
+  proxyUrl: z.string().max(2000).default(""),
+  caBundle: z.string().max(65_536).default(""),
+  insecureSkipVerify: z.boolean().default(false),
 }).superRefine((value, ctx) => {
+  if (value.proxyUrl.trim() && !/^https?:\/\/[^/\s]+\/?$/i.test(value.proxyUrl.trim())) {
+    ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["proxyUrl"], message: "Proxy must be an http:// or https:// URL without a path" });
+  }
+  if (value.caBundle.trim() && !value.caBundle.includes("-----BEGIN CERTIFICATE-----")) {
+    ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["caBundle"], message: "CA bundle must contain PEM certificates" });
+  }
+  if (value.insecureSkipVerify && !insecureTlsAllowed()) {
+    ctx.addIssue({
+      code: z.ZodIssueCode.custom,
+      path: ["insecureSkipVerify"],
+      message: "Skipping TLS verification is not allowed on this deployment",
+    });
+  }
+
   try {
     const parsedHeaders = JSON.parse(value.headers || "{}");
     if (typeof parsedHeaders !== "object" || parsedHeaders === null || Array.isArray(parsedHeaders)) {
@@ -25,19 +88,329 @@ const webhookConfigSchema = z.object({
     ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["headers"], message: "Headers must be valid JSON" });
   }
 
-  if (value.payload.trim()) {
+  if (value.bodyFormat === "xml") {
+    if (!value.payload.trim()) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["payload"], message: "XML body mode requires a payload template" });
+    }
+    if (value.graphqlQuery.trim()) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["bodyFormat"], message: "GraphQL mode sends JSON" });
+    }
+  } else if (value.payload.trim()) {
     try {
       JSON.parse(value.payload);
     } catch {
       ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["payload"], message: "Payload must be valid JSON" });
     }
   }
+
+  if (value.graphqlQuery.trim()) {
+    if (value.method !== "POST") {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["method"], message: "GraphQL mode requires POST" });
+    }
+    if (!/^\s*(?:mutation|query)\b/.test(value.graphqlQuery)) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["graphqlQuery"], message: "GraphQL document must start with mutation or query" });
+    }
+  }
 });
 
+const homeAssistantConfigSchema = z.object({
+  baseUrl: z.string().url(),
+  token: z.string().min(10),
+  service: z
+    .string()
+    .min(1)
+    .max(128)
+    .regex(/^(?:notify\.)?[a-z0-9_]+$/, "Service must be a notify service like mobile_app_pixel"),
+});
+
+const elasticsearchConfigSchema = z.object({
+  url: z.string().url(),
+  index: z
+    .string()
+    .min(1)
+    .max(255)
+    .regex(/^[a-z0-9][a-z0-9._-]*$/, "Index must be lowercase and may contain . _ -"),
+  apiKey: z.string().max(512).default(""),
+  username: z.string().max(256).default(""),
+  password: z.string().max(512).default(""),
+});
+
+const clickhouseConfigSchema = z.object({
+  url: z.string().url(),
+  table: z
+    .string()
+    .min(1)
+    .max(255)
+    .regex(/^[A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)?$/, "Table must be table or database.table"),
+  username: z.string().max(256).default(""),
+  password: z.string().max(512).default(""),
+});
+
+function checkServiceAccountJson(value: { serviceAccountJson: string }, ctx: z.RefinementCtx) {
+  try {
+    const parsed = JSON.parse(value.serviceAccountJson) as { client_email?: unknown; private_key?: unknown };
+    if (typeof parsed.client_email !== "string" || typeof parsed.private_key !== "string") {
+      ctx.addIssue({
+        code: z.ZodIssueCode.custom,
+        path: ["serviceAccountJson"],
+        message: "Service account JSON must include client_email and private_key",
+      });
+    }
+  } catch {
+    ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["serviceAccountJson"], message: "Service account key must be valid JSON" });
+  }
+}
+
+const bigqueryConfigSchema = z
+  .object({
+    projectId: z.string().min(1).max(128).regex(/^[a-z][a-z0-9:.-]*$/, "Project ID is invalid"),
+    dataset: z.string().min(1).max(1024).regex(/^[A-Za-z0-9_]+$/, "Dataset may contain letters, numbers, and _"),
+    table: z.string().min(1).max(1024).regex(/^[A-Za-z0-9_-]+$/, "Table may contain letters, numbers, _ and -"),
+    serviceAccountJson: z.string().min(1).max(8000),
+  })
+  .superRefine(checkServiceAccountJson);
+
+// Google Sheets: one appended row per run. columns lists the cells left to right: timestamp, job, title, output,
+// run_id, or the name of a structured-output field.
+const googleSheetsConfigSchema = z
+  .object({
+    spreadsheetId: z
+      .string()
+      .min(1)
+      .max(2048)
+      .regex(/^(https:\/\/docs\.google\.com\/spreadsheets\/d\/)?[A-Za-z0-9_-]{20,}/, "Paste the spreadsheet URL or id"),
+    sheet: z.string().max(100).default(""),
+    columns: z
+      .string()
+      .max(1000)
+      .regex(/^[A-Za-z0-9_. ,-]*$/, "Columns are comma-separated field names")
+      .default(""),
+    serviceAccountJson: z.string().min(1).max(8000),
+  })
+  .superRefine(checkServiceAccountJson);
+
+const redisConfigSchema = z.object({
+  restUrl: z.string().url(),
+  token: z.string().min(1).max(512),
+  mode: z.enum(["set", "xadd"]).default("set"),
+  key: z.string().min(1).max(512),
+  ttlSeconds: z
+    .string()
+    .regex(/^\d{0,9}$/, "TTL must be a whole number of seconds")
+    .default(""),
+});
+
+// Pushover application tokens and user/group keys are 30 alphanumeric characters.
+const pushoverConfigSchema = z.object({
+  appToken: z.string().regex(/^[A-Za-z0-9]{30}$/, "Application token must be 30 letters and digits"),
+  userKey: z.string().regex(/^[A-Za-z0-9]{30}$/, "User or group key must be 30 letters and digits"),
+  // Blank keeps Pushover's default (0). Emergency priority (2) needs acknowledgement settings and is not offered.
+  priority: z.enum(["", "-2", "-1", "0", "1"]).default(""),
+  sound: z
+    .string()
+    .max(32)
+    .regex(/^[a-z0-9_]*$/, "Sound must be a Pushover sound name like pushover or cosmic")
+    .default(""),
+});
+
+const E164_RE = /^\+[1-9]\d{6,14}$/;
+
+const twilioConfigSchema = z.object({
+  accountSid: z.string().regex(/^AC[0-9a-fA-F]{32}$/, "Account SID must start with AC followed by 32 hex characters"),
+  authToken: z.string().min(16).max(128),
+  // A sending number, or a Messaging Service SID (MG...) to let Twilio pick one.
+  from: z
+    .string()
+    .refine((value) => E164_RE.test(value) || /^MG[0-9a-fA-F]{32}$/.test(value), "From must be a number like +15551234567 or a Messaging Service SID"),
+  to: z.string().regex(E164_RE, "To must be a number like +15551234567"),
+  // summary: outputs that do not fit one short SMS are cut to a summary; full: split into as many messages as needed.
+  mode: z.enum(["summary", "full"]).default("summary"),
+});
+
+const googleChatConfigSchema = z.object({
+  webhookUrl: z
+    .string()
+    .url()
+    .regex(/^https:\/\/chat\.googleapis\.com\/v1\/spaces\//, "Webhook URL must be a Google Chat incoming webhook"),
+  format: z.enum(["card", "text"]).default("card"),
+  // Replies go to this thread; blank starts one thread per run.
+  threadKey: z.string().max(100).default(""),
+});
+
+const SNS_TOPIC_ARN_RE = /^arn:aws(?:-cn|-us-gov)?:sns:[a-z0-9-]+:\d{12}:[A-Za-z0-9_-]{1,256}(?:\.fifo)?$/;
+const SQS_QUEUE_URL_RE = /^https:\/\/sqs\.[a-z0-9-]+\.amazonaws\.com(?:\.cn)?\/\d{12}\/[A-Za-z0-9_-]{1,80}(?:\.fifo)?$/;
+
+// SNS topic or SQS queue. Blank keys use the worker's web identity role when the deployment enables it.
+const awsConfigSchema = z
+  .object({
+    service: z.enum(["sns", "sqs"]),
+    target: z.string().min(1).max(512),
+    accessKeyId: z.string().max(128).default(""),
+    secretAccessKey: z.string().max(256).default(""),
+  })
+  .superRefine((value, ctx) => {
+    if (value.service === "sns" && !SNS_TOPIC_ARN_RE.test(value.target)) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["target"], message: "Target must be an SNS topic ARN" });
+    }
+    if (value.service === "sqs" && !SQS_QUEUE_URL_RE.test(value.target)) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["target"], message: "Target must be an SQS queue URL" });
+    }
+    if (!value.accessKeyId.trim() !== !value.secretAccessKey) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["secretAccessKey"], message: "Set both the access key ID and secret, or neither" });
+    }
+  });
+
+// Kafka through a REST proxy: Confluent REST v3 (Confluent Cloud, needs the cluster id) or the v2 API (Confluent
+// REST Proxy, Redpanda HTTP Proxy, Karapace).
+const kafkaConfigSchema = z
+  .object({
+    restUrl: z.string().url(),
+    api: z.enum(["v3", "v2"]).default("v3"),
+    clusterId: z.string().max(128).regex(/^[A-Za-z0-9_-]*$/, "Cluster ID may contain letters, numbers, _ and -").default(""),
+    topic: z.string().min(1).max(249).regex(/^[A-Za-z0-9._-]+$/, "Topic may contain letters, numbers, . _ and -"),
+    username: z.string().max(256).default(""),
+    password: z.string().max(512).default(""),
+  })
+  .superRefine((value, ctx) => {
+    if (value.api === "v3" && !value.clusterId) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["clusterId"], message: "Cluster ID is required for the v3 API" });
+    }
+  });
+
+// NATS core publish, or a JetStream publish that waits for the stream's ack. Publishing needs a concrete subject,
+// so the * and > wildcards are rejected.
+const natsConfigSchema = z.object({
+  url: z
+    .string()
+    .max(2048)
+    .regex(/^(nats|tls):\/\/[^\s/]+\/?$/, "Use nats://host:port or tls://host:port"),
+  subject: z.string().min(1).max(256).regex(/^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$/, "Subject tokens may contain letters, numbers, _ and -"),
+  mode: z.enum(["core", "jetstream"]).default("core"),
+  username: z.string().max(256).default(""),
+  password: z.string().max(512).default(""),
+  token: z.string().max(512).default(""),
+});
+
+// MQTT 3.1.1 publish. The topic must be concrete, so the + and # wildcards are rejected.
+const mqttConfigSchema = z.object({
+  brokerUrl: z
+    .string()
+    .max(2048)
+    .regex(/^mqtts?:\/\/[^\s/]+\/?$/, "Use mqtt://host:port or mqtts://host:port"),
+  topic: z.string().min(1).max(256).regex(/^[^+#\u0000]+$/, "Topic may not contain the + or # wildcards"),
+  qos: z.enum(["0", "1", "2"]).default("1"),
+  retain: z.enum(["on", "off"]).default("off"),
+  format: z.enum(["json", "text"]).default("json"),
+  username: z.string().max(256).default(""),
+  password: z.string().max(512).default(""),
+});
+
+// Local development: append to a file under CHANNEL_FILE_DIR, or print to the worker's stdout.
+const fileConfigSchema = z
+  .object({
+    target: z.enum(["file", "stdout"]).default("file"),
+    path: z.string().max(512).regex(/^[^\u0000]*$/, "Path may not contain NUL characters").default(""),
+  })
+  .superRefine((value, ctx) => {
+    if (value.target === "file" && !value.path.trim()) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["path"], message: "Path is required when writing to a file" });
+    }
+  });
+
+// Notion: append blocks to a page, or add one row per run to a database. The id may be pasted as the page URL.
+const notionConfigSchema = z.object({
+  token: z.string().min(1).max(256),
+  target: z.enum(["page", "database"]).default("page"),
+  parentId: z
+    .string()
+    .min(1)
+    .max(2048)
+    .regex(/[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}/i, "Paste the Notion page or database URL or id"),
+  titleProperty: z.string().max(200).default(""),
+});
+
+// Jira: one issue per run. Jira Cloud authenticates with the account email and an API token; leave the email blank
+// to send the token as a Data Center personal access token.
+const jiraConfigSchema = z.object({
+  siteUrl: z.string().url(),
+  email: z.string().max(320).default(""),
+  apiToken: z.string().min(1).max(512),
+  projectKey: z.string().min(1).max(20).regex(/^[A-Z][A-Z0-9_]*$/, "Project key is upper-case letters, numbers and _"),
+  issueType: z.string().min(1).max(100).default("Task"),
+  summaryTemplate: z.string().max(500).default(""),
+  labels: z
+    .string()
+    .max(500)
+    .regex(/^[^\s,]*(\s*,\s*[^\s,]+)*\s*$/, "Labels are comma-separated and may not contain spaces")
+    .default(""),
+});
+
+// Plugin: a delivery target installed on this deployment (channel-plugins.ts). The plugin checks its own settings
+// on save (toSavableChannelConfig); the schema only checks that it is installed.
+const pluginConfigSchema = z
+  .object({
+    plugin: z.string().regex(CHANNEL_PLUGIN_NAME_RE, "Plugin name is lower-case letters, numbers, _ and -"),
+    settings: z.string().max(20_000).default("{}"),
+  })
+  .superRefine((value, ctx) => {
+    const error = pluginConfigError(value.plugin, value.settings);
+    if (error) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["settings"], message: error });
+    }
+  });
+
 const inAppChannelSchema = z.object({
   type: z.literal("in_app"),
 });
 
+// Config schema per external channel type; channel-config.ts derives the published JSON Schema from these.
+export const channelConfigSchemas = {
+  discord: discordConfigSchema,
+  telegram: telegramConfigSchema,
+  webhook: webhookConfigSchema,
+  home_assistant: homeAssistantConfigSchema,
+  elasticsearch: elasticsearchConfigSchema,
+  clickhouse: clickhouseConfigSchema,
+  bigquery: bigqueryConfigSchema,
+  redis: redisConfigSchema,
+  pushover: pushoverConfigSchema,
+  twilio: twilioConfigSchema,
+  google_chat: googleChatConfigSchema,
+  aws: awsConfigSchema,
+  kafka: kafkaConfigSchema,
+  nats: natsConfigSchema,
+  mqtt: mqttConfigSchema,
+  file: fileConfigSchema,
+  notion: notionConfigSchema,
+  google_sheets: googleSheetsConfigSchema,
+  jira: jiraConfigSchema,
+  plugin: pluginConfigSchema,
+};
+
+export const jobChannelSchema = z.discriminatedUnion("type", [
+  z.object({ type: z.literal("discord"), config: discordConfigSchema }),
+  z.object({ type: z.literal("telegram"), config: telegramConfigSchema }),
+  inAppChannelSchema,
+  z.object({ type: z.literal("webhook"), config: webhookConfigSchema }),
+  z.object({ type: z.literal("home_assistant"), config: homeAssistantConfigSchema }),
+  z.object({ type: z.literal("elasticsearch"), config: elasticsearchConfigSchema }),
+  z.object({ type: z.literal("clickhouse"), config: clickhouseConfigSchema }),
+  z.object({ type: z.literal("bigquery"), config: bigqueryConfigSchema }),
+  z.object({ type: z.literal("redis"), config: redisConfigSchema }),
+  z.object({ type: z.literal("pushover"), config: pushoverConfigSchema }),
+  z.object({ type: z.literal("twilio"), config: twilioConfigSchema }),
+  z.object({ type: z.literal("google_chat"), config: googleChatConfigSchema }),
+  z.object({ type: z.literal("aws"), config: awsConfigSchema }),
+  z.object({ type: z.literal("kafka"), config: kafkaConfigSchema }),
+  z.object({ type: z.literal("nats"), config: natsConfigSchema }),
+  z.object({ type: z.literal("mqtt"), config: mqttConfigSchema }),
+  z.object({ type: z.literal("file"), config: fileConfigSchema }),
+  z.object({ type: z.literal("notion"), config: notionConfigSchema }),
+  z.object({ type: z.literal("google_sheets"), config: googleSheetsConfigSchema }),
+  z.object({ type: z.literal("jira"), config: jiraConfigSchema }),
+  z.object({ type: z.literal("plugin"), config: pluginConfigSchema }),
+]);
+
 export const previewSchema = z.object({
   template: z.string().min(1).max(8000),
   postPrompt: z.string().max(8000).optional().default(""),
@@ -47,10 +420,18 @@ export const previewSchema = z.object({
   llmModel: z
     .string()
     .max(128)
-    .regex(/^(?:openai\/)?[A-Za-z0-9._:-]+$/, "llmModel must be an OpenAI model id like gpt-5-mini")
+    .regex(LLM_MODEL_RE, "llmModel must be an OpenAI model id like gpt-5-mini, or an OpenRouter id like openrouter/anthropic/claude-3.5-haiku")
     .optional()
     .default(DEFAULT_LLM_MODEL),
   webSearchMode: z.enum(["native", "parallel"]).optional().default(DEFAULT_WEB_SEARCH_MODE),
+  llmTools: llmToolsSchema.optional().default([]),
+  pipelineSteps: pipelineStepsSchema.optional().default([]),
+  inputs: promptInputsSchema.optional().default([]),
+  systemPrompt: z.string().max(JOB_SYSTEM_PROMPT_MAX).optional().default(""),
+  maxOutputTokens: z.number().int().min(16).max(JOB_MAX_OUTPUT_TOKENS_LIMIT).nullable().optional().default(null),
+  temperature: z.number().min(0).max(2).nullable().optional().default(null),
+  reasoningEffort: z.enum(REASONING_EFFORTS).nullable().optional().default(null),
+  verbosity: z.enum(TEXT_VERBOSITIES).nullable().optional().default(null),
   testSend: z.boolean().optional().default(false),
   name: z.string().max(100).optional().default("Preview"),
   nowIso: z.string().optional(),
@@ -60,6 +441,23 @@ export const previewSchema = z.object({
       z.object({ type: z.literal("discord"), config: discordConfigSchema }),
       z.object({ type: z.literal("telegram"), config: telegramConfigSchema }),
       z.object({ type: z.literal("webhook"), config: webhookConfigSchema }),
+      z.object({ type: z.literal("home_assistant"), config: homeAssistantConfigSchema }),
+      z.object({ type: z.literal("elasticsearch"), config: elasticsearchConfigSchema }),
+      z.object({ type: z.literal("clickhouse"), config: clickhouseConfigSchema }),
+      z.object({ type: z.literal("bigquery"), config: bigqueryConfigSchema }),
+      z.object({ type: z.literal("redis"), config: redisConfigSchema }),
+      z.object({ type: z.literal("pushover"), config: pushoverConfigSchema }),
+      z.object({ type: z.literal("twilio"), config: twilioConfigSchema }),
+      z.object({ type: z.literal("google_chat"), config: googleChatConfigSchema }),
+      z.object({ type: z.literal("aws"), config: awsConfigSchema }),
+      z.object({ type: z.literal("kafka"), config: kafkaConfigSchema }),
+      z.object({ type: z.literal("nats"), config: natsConfigSchema }),
+      z.object({ type: z.literal("mqtt"), config: mqttConfigSchema }),
+      z.object({ type: z.literal("file"), config: fileConfigSchema }),
+      z.object({ type: z.literal("notion"), config: notionConfigSchema }),
+      z.object({ type: z.literal("google_sheets"), config: googleSheetsConfigSchema }),
+      z.object({ type: z.literal("jira"), config: jiraConfigSchema }),
+      z.object({ type: z.literal("plugin"), config: pluginConfigSchema }),
     ])
     .optional(),
 }).superRefine((value, ctx) => {
@@ -93,21 +491,118 @@ export const jobUpsertSchema = z
     llmModel: z
       .string()
       .max(128)
-      .regex(/^(?:openai\/)?[A-Za-z0-9._:-]+$/, "llmModel must be an OpenAI model id like gpt-5-mini")
+      .regex(LLM_MODEL_RE, "llmModel must be an OpenAI model id like gpt-5-mini, or an OpenRouter id like openrouter/anthropic/claude-3.5-haiku")
       .optional()
       .default(DEFAULT_LLM_MODEL),
+    fallbackModels: z
+      .array(z.string().trim().max(128).regex(LLM_MODEL_RE, "fallbackModels must be model ids like gpt-4.1-mini"))
+      .max(MODEL_FALLBACKS_MAX)
+      .optional()
+      .default([]),
     webSearchMode: z.enum(["native", "parallel"]).optional().default(DEFAULT_WEB_SEARCH_MODE),
-    scheduleType: z.enum(["daily", "weekly", "cron"]),
+    llmTools: llmToolsSchema.optional().default([]),
+    pipelineSteps: pipelineStepsSchema.optional().default([]),
+    inputs: promptInputsSchema.optional().default([]),
+    systemPrompt: z.string().max(JOB_SYSTEM_PROMPT_MAX).optional().default(""),
+    maxOutputTokens: z.number().int().min(16).max(JOB_MAX_OUTPUT_TOKENS_LIMIT).nullable().optional().default(null),
+    temperature: z.number().min(0).max(2).nullable().optional().default(null),
+    reasoningEffort: z.enum(REASONING_EFFORTS).nullable().optional().default(null),
+    verbosity: z.enum(TEXT_VERBOSITIES).nullable().optional().default(null),
+    showHeader: z.boolean().optional().default(true),
+    headerTemplate: z.string().max(300).optional().default(""),
+    headerLocale: z
+      .string()
+      .trim()
+      .regex(/^([A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*)?$/, "headerLocale must be a locale like en-US or de")
+      .optional()
+      .default(""),
+    scheduleType: z.enum(["daily", "weekly", "cron", "once"]),
     scheduleTime: z.string().optional().nullable(),
     scheduleDayOfWeek: z.number().int().min(0).max(6).optional().nullable(),
     scheduleCron: z.string().optional().nullable(),
-    channel: z.discriminatedUnion("type", [
-      z.object({ type: z.literal("discord"), config: discordConfigSchema }),
-      z.object({ type: z.literal("telegram"), config: telegramConfigSchema }),
-      inAppChannelSchema,
-      z.object({ type: z.literal("webhook"), config: webhookConfigSchema }),
-    ]),
+    runAt: z.string().datetime({ offset: true }).optional().nullable(),
+    weekdaysOnly: z.boolean().optional().default(false),
+    catchupPolicy: z.enum(["skip", "run_once", "run_all"]).optional().default("run_once"),
+    quietHoursStart: z
+      .string()
+      .regex(/^([01]\d|2[0-3]):([0-5]\d)$/, "Quiet hours must be HH:mm")
+      .optional()
+      .nullable(),
+    quietHoursEnd: z
+      .string()
+      .regex(/^([01]\d|2[0-3]):([0-5]\d)$/, "Quiet hours must be HH:mm")
+      .optional()
+      .nullable(),
+    blackoutDates: z
+      .array(z.string().regex(/^\d{4}-\d{2}-\d{2}$/, "Blackout dates must be YYYY-MM-DD"))
+      .max(366)
+      .optional()
+      .default([]),
+    timezone: z
+      .string()
+      .max(64)
+      .refine(isValidTimeZone, "timezone must be an IANA time zone like Europe/Berlin")
+      .optional()
+      .nullable(),
+    // Omitted when channelId references a saved channel; with neither, the account's default channel is used.
+    channel: jobChannelSchema.optional(),
+    channelId: z.string().uuid().nullable().optional().default(null),
     enabled: z.boolean().default(true),
+    userAgent: z
+      .string()
+      .max(256)
+      .regex(/^[\x20-\x7e]*$/, "User-Agent must be printable ASCII")
+      .optional()
+      .default(""),
+    deliveryDelayMinutes: z.number().int().min(0).max(10080).optional().default(0),
+    acceptReplies: z.boolean().optional().default(false),
+    outageNotice: z.boolean().optional().default(false),
+    failureNotice: z.boolean().optional().default(false),
+    includePreviousOutput: z.boolean().optional().default(false),
+    previousOutputCount: z.number().int().min(1).max(5).optional().default(1),
+    deliverIf: z.enum(DELIVER_IF_MODES).optional().default("always"),
+    deliverIfPattern: z.string().max(500).refine(isValidDeliverIfPattern, "deliverIfPattern must be a valid regular expression").optional().default(""),
+    deliveryDiff: z.enum(DELIVERY_DIFF_MODES).optional().default("off"),
+    deliveryDiffOnly: z.boolean().optional().default(false),
+    fullOutputLink: z.boolean().optional().default(false),
+    throttleLimit: z.number().int().min(1).max(1000).nullable().optional().default(null),
+    throttleWindow: z.enum(THROTTLE_WINDOWS).optional().default("hour"),
+    qualitySampleRate: z.number().int().min(0).max(100).optional().default(0),
+    qualityRubric: z.string().max(4000).optional().default(""),
+    historyRetentionDays: z.number().int().min(0).max(3650).nullable().optional().default(null),
+    responseCacheTtlMinutes: z.number().int().min(1).max(RESPONSE_CACHE_TTL_MAX_MINUTES).nullable().optional().default(null),
+    autoDisableThreshold: z.number().int().min(0).max(1000).nullable().optional().default(null),
+    autoDisablePolicy: z.enum(AUTO_DISABLE_POLICIES).nullable().optional().default(null),
+    expectedRuntimeSeconds: z.number().int().min(1).max(3600).nullable().optional().default(null),
+    slowRunNotice: z.boolean().optional().default(false),
+    maxOutputChars: z.number().int().min(MAX_OUTPUT_CHARS_MIN).max(MAX_OUTPUT_CHARS_MAX).nullable().optional().default(null),
+    ttsVoice: z.enum(TTS_VOICES).nullable().optional().default(null),
+    dependsOnJobId: z.string().uuid().nullable().optional().default(null),
+    includeUpstreamOutput: z.boolean().optional().default(false),
+    webhookRetrySchedule: z
+      .string()
+      .max(200)
+      .refine(isValidRetrySchedule, "webhookRetrySchedule must be up to 10 comma-separated durations like 1m,10m,1h,6h (max 7d each)")
+      .optional()
+      .default(""),
+    environment: z
+      .string()
+      .trim()
+      .regex(/^[a-z0-9_-]{1,32}$/, "environment must be 1-32 lowercase letters, digits, _ or -")
+      .optional()
+      .default("production"),
+    priority: z.enum(JOB_PRIORITIES).optional().default("normal"),
+    concurrencyGroup: z
+      .string()
+      .trim()
+      .regex(/^([A-Za-z0-9_.:-]{1,64})?$/, "concurrencyGroup must be up to 64 letters, digits, _ . : or -")
+      .optional()
+      .default(""),
+    tags: z
+      .array(z.string().trim().min(1).max(64).regex(/^[A-Za-z0-9_.:/-]+$/, "Tags may only contain letters, digits, and _ . : / -"))
+      .max(20)
+      .optional()
+      .default([]),
   })
   .superRefine((value, ctx) => {
     if (value.variables != null) {
@@ -121,10 +616,11 @@ export const jobUpsertSchema = z
       }
     }
 
-    if (value.scheduleType !== "cron" && !value.scheduleTime) {
+    const usesTime = value.scheduleType === "daily" || value.scheduleType === "weekly";
+    if (usesTime && !value.scheduleTime) {
       ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["scheduleTime"], message: "Required for daily/weekly" });
     }
-    if (value.scheduleType !== "cron" && value.scheduleTime && !/^([01]\d|2[0-3]):([0-5]\d)$/.test(value.scheduleTime)) {
+    if (usesTime && value.scheduleTime && !/^([01]\d|2[0-3]):([0-5]\d)$/.test(value.scheduleTime)) {
       ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["scheduleTime"], message: "Time must be HH:mm" });
     }
     if (value.scheduleType === "weekly" && value.scheduleDayOfWeek == null) {
@@ -133,13 +629,113 @@ export const jobUpsertSchema = z
     if (value.scheduleType === "cron" && !value.scheduleCron) {
       ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["scheduleCron"], message: "Required for cron" });
     }
+    if (!value.quietHoursStart !== !value.quietHoursEnd) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["quietHoursEnd"], message: "Set both quiet hours start and end" });
+    }
+    if (value.scheduleType === "once" && !value.runAt) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["runAt"], message: "Required for one-time jobs" });
+    }
+    if (value.deliverIf === "regex" && !value.deliverIfPattern.trim()) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["deliverIfPattern"], message: "Required for regex delivery condition" });
+    }
+    if (value.qualitySampleRate > 0 && !value.qualityRubric.trim()) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["qualityRubric"], message: "Required when quality sampling is on" });
+    }
   })
   .transform((value) => ({
     ...value,
-    scheduleTime: value.scheduleType === "cron" ? "00:00" : (value.scheduleTime ?? "00:00"),
+    scheduleTime: value.scheduleType === "daily" || value.scheduleType === "weekly" ? (value.scheduleTime ?? "00:00") : "00:00",
+    runAt: value.scheduleType === "once" && value.runAt ? new Date(value.runAt) : null,
+    weekdaysOnly: value.scheduleType === "daily" && value.weekdaysOnly,
   }));
 
+const savedChannelNameSchema = z.string().trim().min(1).max(100);
+
+export const savedChannelCreateSchema = z.object({
+  name: savedChannelNameSchema,
+  channel: jobChannelSchema,
+  isDefault: z.boolean().optional().default(false),
+});
+
+export const savedChannelUpdateSchema = z.object({
+  name: savedChannelNameSchema.optional(),
+  // Omitted keeps the stored config and its secrets.
+  channel: jobChannelSchema.optional(),
+  isDefault: z.boolean().optional(),
+});
+
+export const apiTokenCreateSchema = z.object({
+  name: z.string().trim().min(1).max(100),
+  // null: the token does not expire.
+  expiresInDays: z.number().int().min(1).max(3650).nullable().optional().default(null),
+});
+
+export const jobCloneSchema = z.object({
+  name: z.string().min(1).max(100).optional(),
+  // Shifts the copy's schedule (daily/weekly time, one-time runAt); not supported for cron jobs.
+  scheduleOffsetMinutes: z.number().int().min(-10080).max(10080).optional().default(0),
+  // Replaces the source channel; omitted keeps the source channel and its secrets.
+  channel: jobChannelSchema.optional(),
+  // Copies start disabled unless asked otherwise, so a clone never double-delivers by accident.
+  enabled: z.boolean().optional().default(false),
+});
+
+export const jobBulkSchema = z
+  .object({
+    // Jobs are selected by id and/or by tags (all listed tags must match).
+    ids: z.array(z.string().uuid()).max(500).optional(),
+    tags: z.array(z.string().trim().min(1).max(64)).max(20).optional(),
+    set: z.object({
+      enabled: z.boolean().optional(),
+      scheduleOffsetMinutes: z.number().int().min(-10080).max(10080).optional(),
+      schedule: z
+        .object({
+          scheduleType: z.enum(["daily", "weekly", "cron"]),
+          scheduleTime: z.string().regex(/^([01]\d|2[0-3]):([0-5]\d)$/, "Time must be HH:mm").optional(),
+          weekdaysOnly: z.boolean().optional(),
+          scheduleDayOfWeek: z.number().int().min(0).max(6).optional(),
+          scheduleCron: z.string().max(128).optional(),
+        })
+        .optional(),
+      channel: jobChannelSchema.optional(),
+    }),
+  })
+  .superRefine((value, ctx) => {
+    if (!value.ids?.length && !value.tags?.length) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["ids"], message: "Select jobs by ids or tags" });
+    }
+    const { enabled, scheduleOffsetMinutes, schedule, channel } = value.set;
+    if (enabled == null && scheduleOffsetMinutes == null && !schedule && !channel) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["set"], message: "Nothing to change" });
+    }
+    if (scheduleOffsetMinutes != null && schedule) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["set", "schedule"], message: "Use either schedule or scheduleOffsetMinutes" });
+    }
+    if (schedule && schedule.scheduleType !== "cron" && !schedule.scheduleTime) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["set", "schedule", "scheduleTime"], message: "Required for daily/weekly" });
+    }
+    if (schedule?.scheduleType === "weekly" && schedule.scheduleDayOfWeek == null) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["set", "schedule", "scheduleDayOfWeek"], message: "Required for weekly" });
+    }
+    if (schedule?.scheduleType === "cron" && !schedule.scheduleCron) {
+      ctx.addIssue({ code: z.ZodIssueCode.custom, path: ["set", "schedule", "scheduleCron"], message: "Required for cron" });
+    }
+  });
+
 export const promptWriterEnhanceSchema = z.object({
   prompt: z.string().min(1).max(8000),
   allowStrongerRewrite: z.boolean().optional().default(false),
 });
+
+export type JobUpsertInput = z.infer<typeof jobUpsertSchema>;
+
+// POST /api/cron/sync: the spec files of one declarative sync run (see src/lib/job-sync.ts).
+export const jobSyncSchema = z.object({
+  owner: z.string().email(),
+  files: z
+    .array(z.object({ path: z.string().min(1).max(500), content: z.string().max(100_000) }))
+    .max(500),
+  dryRun: z.boolean().optional().default(false),
+});
+export type JobCloneInput = z.infer<typeof jobCloneSchema>;
+export type JobBulkInput = z.infer<typeof jobBulkSchema>;
@@ -0,0 +1,24 @@
+import { describe, expect, it } from "vitest";
+
+import { WEBHOOK_PRESETS, findWebhookPreset, renderXmlTemplate } from "./webhook-presets";
+
+describe("webhook presets", () => {
+  it("ships valid JSON headers and payloads", () => {
+    for (const preset of WEBHOOK_PRESETS) {
+      expect(() => JSON.parse(preset.headers)).not.toThrow();
+      if (preset.payload.trim() && preset.bodyFormat !== "xml") {
+        expect(() => JSON.parse(preset.payload)).not.toThrow();
+      }
+    }
+  });
+
+  it("finds presets by key", () => {
+    expect(findWebhookPreset("ifttt")?.name).toBe("IFTTT Maker");
+    expect(findWebhookPreset("missing")).toBeNull();
+  });
+
+  it("escapes values rendered into XML templates", () => {
+    const xml = renderXmlTemplate("<Body>{{body}}</Body><X>{{missing}}</X>", { body: 'a < b & "c"' });
+    expect(xml).toBe("<Body>a &lt; b &amp; &quot;c&quot;</Body><X>{{missing}}</X>");
+  });
+});
@@ -0,0 +1,109 @@
+import { renderTemplate } from "@/lib/template-functions";
+
+export type WebhookPreset = {
+  key: string;
+  name: string;
+  description: string;
+  urlHint: string;
+  method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
+  headers: string;
+  payload: string;
+  bodyFormat?: "json" | "xml";
+};
+
+// Payload strings may use {{title}}, {{body}}, {{content}} and meta keys such as {{jobId}};
+// they are substituted into JSON string values at delivery time.
+export const WEBHOOK_PRESETS: WebhookPreset[] = [
+  {
+    key: "ifttt",
+    name: "IFTTT Maker",
+    description: "Triggers an IFTTT Webhooks applet with value1=title, value2=body.",
+    urlHint: "https://maker.ifttt.com/trigger/<event>/with/key/<key>",
+    method: "POST",
+    headers: JSON.stringify({ "Content-Type": "application/json" }, null, 2),
+    payload: JSON.stringify({ value1: "{{title}}", value2: "{{body}}", value3: "{{runHistoryId}}" }, null, 2),
+  },
+  {
+    key: "n8n",
+    name: "n8n Webhook",
+    description: "Sends title, body, and run metadata to an n8n Webhook node.",
+    urlHint: "https://<your-n8n>/webhook/<path>",
+    method: "POST",
+    headers: JSON.stringify({ "Content-Type": "application/json" }, null, 2),
+    payload: JSON.stringify({ title: "{{title}}", body: "{{body}}", jobId: "{{jobId}}", runHistoryId: "{{runHistoryId}}" }, null, 2),
+  },
+  {
+    key: "pipedream",
+    name: "Pipedream",
+    description: "Posts the full message to a Pipedream HTTP trigger.",
+    urlHint: "https://<id>.m.pipedream.net",
+    method: "POST",
+    headers: JSON.stringify({ "Content-Type": "application/json" }, null, 2),
+    payload: JSON.stringify({ title: "{{title}}", body: "{{body}}", content: "{{content}}" }, null, 2),
+  },
+  {
+    key: "webhook_site",
+    name: "webhook.site (testing)",
+    description: "Uses the default payload so you can inspect exactly what Promptloop sends.",
+    urlHint: "https://webhook.site/<uuid>",
+    method: "POST",
+    headers: JSON.stringify({ "Content-Type": "application/json" }, null, 2),
+    payload: "",
+  },
+  {
+    key: "soap",
+    name: "SOAP 1.1 envelope",
+    description: "Posts an XML SOAP envelope; replace the body element and SOAPAction with what the service expects.",
+    urlHint: "https://<legacy-host>/service.asmx",
+    method: "POST",
+    headers: JSON.stringify({ "Content-Type": "text/xml; charset=utf-8", SOAPAction: "urn:PostReport" }, null, 2),
+    payload: [
+      '<?xml version="1.0" encoding="utf-8"?>',
+      '<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">',
+      "  <soap:Body>",
+      "    <PostReport>",
+      "      <Title>{{title}}</Title>",
+      "      <Body>{{body}}</Body>",
+      "      <RunId>{{runHistoryId}}</RunId>",
+      "    </PostReport>",
+      "  </soap:Body>",
+      "</soap:Envelope>",
+    ].join("\n"),
+    bodyFormat: "xml",
+  },
+];
+
+export function findWebhookPreset(key: string): WebhookPreset | null {
+  return WEBHOOK_PRESETS.find((p) => p.key === key) ?? null;
+}
+
+export function renderWebhookPayload(value: unknown, vars: Record<string, string>): unknown {
+  if (typeof value === "string") {
+    return renderTemplate(value, vars);
+  }
+  if (Array.isArray(value)) {
+    return value.map((v) => renderWebhookPayload(v, vars));
+  }
+  if (value && typeof value === "object") {
+    const out: Record<string, unknown> = {};
+    for (const [k, v] of Object.entries(value)) {
+      out[k] = renderWebhookPayload(v, vars);
+    }
+    return out;
+  }
+  return value;
+}
+
+function escapeXml(value: string) {
+  return value
+    .replace(/&/g, "&amp;")
+    .replace(/</g, "&lt;")
+    .replace(/>/g, "&gt;")
+    .replace(/"/g, "&quot;")
+    .replace(/'/g, "&apos;");
+}
+
+// XML body mode: placeholders are substituted into the raw template with XML escaping applied.
+export function renderXmlTemplate(template: string, vars: Record<string, string>): string {
+  return renderTemplate(template, vars, { escape: escapeXml });
+}
@@ -0,0 +1,51 @@
+import { createServer, type Server } from "node:http";
+import type { AddressInfo } from "node:net";
+import { afterEach, describe, expect, it } from "vitest";
+import { transportFetch, usesCustomTransport } from "./webhook-transport";
+
+let servers: Server[] = [];
+
+afterEach(async () => {
+  await Promise.all(servers.map((server) => new Promise((resolve) => server.close(resolve))));
+  servers = [];
+});
+
+async function listen(server: Server) {
+  servers.push(server);
+  await new Promise<void>((resolve) => server.listen(0, "127.0.0.1", resolve));
+  return `http://127.0.0.1:${(server.address() as AddressInfo).port}`;
+}
+
+describe("webhook transport", () => {
+  it("is only used when proxy or TLS options are set", () => {
+    expect(usesCustomTransport({})).toBe(false);
+    expect(usesCustomTransport({ proxyUrl: " " })).toBe(false);
+    expect(usesCustomTransport({ proxyUrl: "http://proxy:3128" })).toBe(true);
+    expect(usesCustomTransport({ insecureSkipVerify: true })).toBe(true);
+  });
+
+  it("sends plain HTTP through the proxy with the absolute URL and proxy credentials", async () => {
+    const seen: Array<{ url?: string; auth?: string; body: string }> = [];
+    const proxy = await listen(
+      createServer((req, res) => {
+        let body = "";
+        req.on("data", (chunk) => (body += chunk));
+        req.on("end", () => {
+          seen.push({ url: req.url, auth: req.headers["proxy-authorization"], body });
+          res.writeHead(201, { "Content-Type": "application/json" }).end('{"id":"r-1"}');
+        });
+      }),
+    );
+
+    const res = await transportFetch(
+      "http://hooks.internal/in?x=1",
+      { method: "POST", headers: { "Content-Type": "application/json" }, body: '{"text":"hi"}' },
+      { proxyUrl: proxy.replace("http://", "http://bot:s%40cret@") },
+    );
+    expect(res.status).toBe(201);
+    await expect(res.json()).resolves.toEqual({ id: "r-1" });
+    expect(seen).toEqual([
+      { url: "http://hooks.internal/in?x=1", auth: `Basic ${Buffer.from("bot:s@cret").toString("base64")}`, body: '{"text":"hi"}' },
+    ]);
+  });
+});
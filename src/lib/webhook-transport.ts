@@ -0,0 +1,124 @@
+import { request as httpRequest, type IncomingMessage, type RequestOptions } from "node:http";
+import { request as httpsRequest } from "node:https";
+import type { Socket } from "node:net";
+import { connect as tlsConnect } from "node:tls";
+
+// Outbound transport for webhook channels that need more than fetch offers: an HTTP(S) proxy (HTTPS targets are
+// tunnelled with CONNECT), a private CA bundle (PEM) trusted instead of the public roots, or, when the deployment
+// allows it (WEBHOOK_ALLOW_INSECURE_TLS), no certificate verification at all. Webhooks without these options keep
+// using fetch.
+
+const TIMEOUT_MS = 30_000;
+// Larger response bodies are cut; callers only read the status, GraphQL errors and the captured response.
+const MAX_RESPONSE_BYTES = 1024 * 1024;
+
+export type WebhookTransport = { proxyUrl?: string; caBundle?: string; insecureSkipVerify?: boolean };
+
+export function usesCustomTransport(transport: WebhookTransport) {
+  return Boolean(transport.proxyUrl?.trim() || transport.caBundle?.trim() || transport.insecureSkipVerify);
+}
+
+export function insecureTlsAllowed() {
+  return ["1", "true", "on"].includes((process.env.WEBHOOK_ALLOW_INSECURE_TLS ?? "").trim().toLowerCase());
+}
+
+function proxyAuthorization(proxy: URL): Record<string, string> {
+  if (!proxy.username) {
+    return {};
+  }
+  const credentials = `${decodeURIComponent(proxy.username)}:${decodeURIComponent(proxy.password)}`;
+  return { "Proxy-Authorization": `Basic ${Buffer.from(credentials).toString("base64")}` };
+}
+
+function defaultPort(url: URL) {
+  return Number(url.port || (url.protocol === "https:" ? 443 : 80));
+}
+
+function connectTunnel(proxy: URL, host: string, port: number) {
+  return new Promise<Socket>((resolve, reject) => {
+    const req = (proxy.protocol === "https:" ? httpsRequest : httpRequest)({
+      host: proxy.hostname,
+      port: defaultPort(proxy),
+      method: "CONNECT",
+      path: `${host}:${port}`,
+      headers: { Host: `${host}:${port}`, ...proxyAuthorization(proxy) },
+    });
+    req.setTimeout(TIMEOUT_MS, () => req.destroy(new Error("Proxy connection timed out")));
+    req.once("connect", (res, socket) => {
+      if (res.statusCode === 200) {
+        resolve(socket);
+        return;
+      }
+      socket.destroy();
+      reject(new Error(`Proxy refused the tunnel: ${res.statusCode}`));
+    });
+    req.once("error", reject);
+    req.end();
+  });
+}
+
+function toResponse(res: IncomingMessage, body: Buffer) {
+  const headers = new Headers();
+  for (const [name, value] of Object.entries(res.headers)) {
+    for (const item of Array.isArray(value) ? value : value == null ? [] : [value]) {
+      headers.append(name, item);
+    }
+  }
+  const status = res.statusCode ?? 502;
+  const empty = status === 204 || status === 205 || status === 304;
+  return new Response(empty ? null : new Uint8Array(body), { status, statusText: res.statusMessage, headers });
+}
+
+export async function transportFetch(
+  rawUrl: string,
+  init: { method: string; headers: Record<string, string>; body?: string | Uint8Array },
+  transport: WebhookTransport,
+): Promise<Response> {
+  const url = new URL(rawUrl);
+  const proxy = transport.proxyUrl?.trim() ? new URL(transport.proxyUrl.trim()) : null;
+  const secure = url.protocol === "https:";
+  const tls = { ca: transport.caBundle?.trim() || undefined, rejectUnauthorized: !transport.insecureSkipVerify };
+  const path = `${url.pathname}${url.search}`;
+
+  let send = secure ? httpsRequest : httpRequest;
+  let options: RequestOptions;
+  if (proxy && !secure) {
+    // Plain HTTP goes to the proxy with the absolute URL as the request target.
+    send = proxy.protocol === "https:" ? httpsRequest : httpRequest;
+    options = {
+      host: proxy.hostname,
+      port: defaultPort(proxy),
+      path: url.href,
+      headers: { ...init.headers, Host: url.host, ...proxyAuthorization(proxy) },
+    };
+  } else if (proxy) {
+    const socket = await connectTunnel(proxy, url.hostname, defaultPort(url));
+    options = {
+      host: url.hostname,
+      port: defaultPort(url),
+      path,
+      headers: init.headers,
+      createConnection: () => tlsConnect({ socket, servername: url.hostname, ...tls }),
+    };
+  } else {
+    options = { host: url.hostname, port: defaultPort(url), path, headers: init.headers, ...(secure ? tls : {}) };
+  }
+
+  return new Promise<Response>((resolve, reject) => {
+    const req = send({ ...options, method: init.method }, (res) => {
+      const chunks: Buffer[] = [];
+      let size = 0;
+      res.on("data", (chunk: Buffer) => {
+        size += chunk.length;
+        if (size <= MAX_RESPONSE_BYTES) {
+          chunks.push(chunk);
+        }
+      });
+      res.on("end", () => resolve(toResponse(res, Buffer.concat(chunks))));
+      res.on("error", reject);
+    });
+    req.setTimeout(TIMEOUT_MS, () => req.destroy(new Error("Webhook request timed out")));
+    req.on("error", reject);
+    req.end(init.body);
+  });
+}
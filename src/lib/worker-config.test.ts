@@ -0,0 +1,64 @@
+import { describe, expect, it } from "vitest";
+import { workerConfigFromEnv } from "./worker-config";
+
+describe("worker config", () => {
+  it("uses the defaults without env vars", () => {
+    expect(workerConfigFromEnv({})).toEqual({
+      environment: "production",
+      maxJobs: 25,
+      timeBudgetMs: 250_000,
+      concurrency: 1,
+      claimBatch: 25,
+      claimRetries: 3,
+      lockStaleMinutes: 10,
+      lockHeartbeatSeconds: 60,
+      catchupGraceMinutes: 15,
+      smoothingWindowSeconds: 0,
+      deliveryMaxRetries: 3,
+      llmMaxRetries: 2,
+      failureBackoff: { maxRetries: 3, baseSeconds: 60, maxSeconds: 3600 },
+      partialDeliveryRetrySchedule: "1m,10m,1h",
+      shutdownTimeoutMs: 20_000,
+    });
+  });
+
+  it("reads the env vars", () => {
+    const config = workerConfigFromEnv({
+      WORKER_ENV: " staging ",
+      WORKER_MAX_JOBS_PER_RUN: "10",
+      WORKER_CONCURRENCY: "4",
+      WORKER_LOCK_HEARTBEAT_SECONDS: "30",
+      WORKER_FAILURE_RETRIES: "0",
+      PARTIAL_DELIVERY_RETRY_SCHEDULE: "5m",
+    });
+    expect(config).toMatchObject({
+      environment: "staging",
+      maxJobs: 10,
+      concurrency: 4,
+      lockHeartbeatSeconds: 30,
+      failureBackoff: { maxRetries: 0, baseSeconds: 60, maxSeconds: 3600 },
+      partialDeliveryRetrySchedule: "5m",
+    });
+  });
+
+  it("falls back to the defaults for invalid values and caps concurrency", () => {
+    const config = workerConfigFromEnv({
+      WORKER_MAX_JOBS_PER_RUN: "0",
+      WORKER_TIME_BUDGET_MS: "500",
+      WORKER_CONCURRENCY: "50",
+      WORKER_LOCK_STALE_MINUTES: "abc",
+      WORKER_LOCK_HEARTBEAT_SECONDS: "1",
+      WORKER_CATCHUP_GRACE_MINUTES: "-5",
+      WORKER_LLM_MAX_RETRIES: "0",
+    });
+    expect(config).toMatchObject({
+      maxJobs: 25,
+      timeBudgetMs: 250_000,
+      concurrency: 20,
+      lockStaleMinutes: 10,
+      lockHeartbeatSeconds: 60,
+      catchupGraceMinutes: 15,
+      llmMaxRetries: 2,
+    });
+  });
+});
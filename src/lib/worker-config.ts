@@ -0,0 +1,76 @@
+// Settings of the job runner. runWorker() takes them explicitly so the runner can be embedded or driven from tests;
+// the cron routes build them from the environment with workerConfigFromEnv(). Settings owned by the modules a tick
+// calls into are not part of it and are still read from the environment there: per-user claim caps
+// (tenant-fairness.ts), auto-disable (auto-disable.ts), outage detection (provider-health.ts), maintenance election
+// (maintenance-leader.ts), dormant jobs (dormant-jobs.ts) and worker heartbeats (worker-identity.ts).
+
+const DEFAULT_WORKER_ENV = "production";
+const DEFAULT_MAX_JOBS = 25;
+const DEFAULT_TIME_BUDGET_MS = 250_000;
+const DEFAULT_CONCURRENCY = 1;
+const MAX_CONCURRENCY = 20;
+const DEFAULT_CLAIM_BATCH = 25;
+const DEFAULT_CLAIM_RETRIES = 3;
+const DEFAULT_LOCK_STALE_MINUTES = 10;
+const DEFAULT_LOCK_HEARTBEAT_SECONDS = 60;
+const DEFAULT_CATCHUP_GRACE_MINUTES = 15;
+const DEFAULT_DELIVERY_MAX_RETRIES = 3;
+const DEFAULT_LLM_MAX_RETRIES = 2;
+const DEFAULT_PARTIAL_DELIVERY_RETRY_SCHEDULE = "1m,10m,1h";
+const DEFAULT_SHUTDOWN_TIMEOUT_MS = 20_000;
+
+export type WorkerConfig = {
+  // Only jobs of this environment are run (WORKER_ENV).
+  environment: string;
+  maxJobs: number;
+  timeBudgetMs: number;
+  concurrency: number;
+  claimBatch: number;
+  claimRetries: number;
+  lockStaleMinutes: number;
+  lockHeartbeatSeconds: number;
+  catchupGraceMinutes: number;
+  // 0 disables smoothing; capped below the catch-up grace by the runner.
+  smoothingWindowSeconds: number;
+  deliveryMaxRetries: number;
+  llmMaxRetries: number;
+  failureBackoff: { maxRetries: number; baseSeconds: number; maxSeconds: number };
+  partialDeliveryRetrySchedule: string;
+  // How long SIGTERM waits for runs in flight before their locks are released and the process exits.
+  shutdownTimeoutMs: number;
+};
+
+type Env = Record<string, string | undefined>;
+
+function envNumber(env: Env, name: string, fallback: number, valid: (value: number) => boolean) {
+  const value = Number(env[name] ?? fallback);
+  return Number.isFinite(value) && valid(value) ? value : fallback;
+}
+
+function envInt(env: Env, name: string, fallback: number, min = 0) {
+  return Math.floor(envNumber(env, name, fallback, (value) => value >= min));
+}
+
+export function workerConfigFromEnv(env: Env = process.env): WorkerConfig {
+  return {
+    environment: env.WORKER_ENV?.trim() || DEFAULT_WORKER_ENV,
+    maxJobs: envInt(env, "WORKER_MAX_JOBS_PER_RUN", DEFAULT_MAX_JOBS, 1),
+    timeBudgetMs: Math.floor(envNumber(env, "WORKER_TIME_BUDGET_MS", DEFAULT_TIME_BUDGET_MS, (value) => value > 1000)),
+    concurrency: Math.min(envInt(env, "WORKER_CONCURRENCY", DEFAULT_CONCURRENCY, 1), MAX_CONCURRENCY),
+    claimBatch: envInt(env, "WORKER_CLAIM_BATCH", DEFAULT_CLAIM_BATCH, 1),
+    claimRetries: envInt(env, "WORKER_CLAIM_RETRIES", DEFAULT_CLAIM_RETRIES),
+    lockStaleMinutes: envNumber(env, "WORKER_LOCK_STALE_MINUTES", DEFAULT_LOCK_STALE_MINUTES, (value) => value > 0),
+    lockHeartbeatSeconds: envNumber(env, "WORKER_LOCK_HEARTBEAT_SECONDS", DEFAULT_LOCK_HEARTBEAT_SECONDS, (value) => value >= 5),
+    catchupGraceMinutes: envNumber(env, "WORKER_CATCHUP_GRACE_MINUTES", DEFAULT_CATCHUP_GRACE_MINUTES, (value) => value >= 0),
+    smoothingWindowSeconds: envInt(env, "WORKER_SMOOTHING_WINDOW_SECONDS", 0),
+    deliveryMaxRetries: envInt(env, "WORKER_DELIVERY_MAX_RETRIES", DEFAULT_DELIVERY_MAX_RETRIES, 1),
+    llmMaxRetries: envInt(env, "WORKER_LLM_MAX_RETRIES", DEFAULT_LLM_MAX_RETRIES, 1),
+    failureBackoff: {
+      maxRetries: envInt(env, "WORKER_FAILURE_RETRIES", 3),
+      baseSeconds: envInt(env, "WORKER_FAILURE_BACKOFF_SECONDS", 60, 1),
+      maxSeconds: envInt(env, "WORKER_FAILURE_BACKOFF_MAX_SECONDS", 3600, 1),
+    },
+    partialDeliveryRetrySchedule: env.PARTIAL_DELIVERY_RETRY_SCHEDULE ?? DEFAULT_PARTIAL_DELIVERY_RETRY_SCHEDULE,
+    shutdownTimeoutMs: envInt(env, "WORKER_SHUTDOWN_TIMEOUT_MS", DEFAULT_SHUTDOWN_TIMEOUT_MS),
+  };
+}
@@ -0,0 +1,28 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+vi.mock("@/lib/prisma", () => ({ prisma: {} }));
+
+import { hostname } from "os";
+import { workerDeadAfterSeconds, workerHeartbeatSeconds, workerId } from "./worker-identity";
+
+afterEach(() => {
+  vi.unstubAllEnvs();
+});
+
+describe("worker identity", () => {
+  it("is the hostname plus a stable per-process uuid", () => {
+    const id = workerId();
+    expect(id.startsWith(`${hostname()}:`)).toBe(true);
+    expect(id.slice(hostname().length + 1)).toMatch(/^[0-9a-f-]{36}$/);
+    expect(workerId()).toBe(id);
+  });
+
+  it("keeps the dead-worker window at least two heartbeats long", () => {
+    expect(workerHeartbeatSeconds()).toBe(30);
+    expect(workerDeadAfterSeconds()).toBe(120);
+    vi.stubEnv("WORKER_HEARTBEAT_SECONDS", "90");
+    expect(workerDeadAfterSeconds()).toBe(180);
+    vi.stubEnv("WORKER_HEARTBEAT_SECONDS", "1");
+    expect(workerHeartbeatSeconds()).toBe(30);
+  });
+});
@@ -0,0 +1,102 @@
+import { hostname } from "os";
+import { randomUUID } from "crypto";
+import { prisma } from "@/lib/prisma";
+import { clock, type ClockTimer } from "@/lib/clock";
+import { logger } from "@/lib/logger";
+
+const DEFAULT_HEARTBEAT_SECONDS = 30;
+const DEFAULT_DEAD_AFTER_SECONDS = 120;
+// Heartbeat rows of workers gone this long are deleted; their locks were reaped long before.
+const FORGET_AFTER_HOURS = 24;
+
+let id: string | null = null;
+let heartbeatTimer: ClockTimer | null = null;
+
+// Identifies this worker process in jobs.locked_by and worker_heartbeats: the hostname plus a per-process uuid,
+// so two processes on one host (or a restarted one) never share an id.
+export function workerId() {
+  id ??= `${hostname()}:${randomUUID()}`;
+  return id;
+}
+
+function envSeconds(name: string, fallback: number, min: number) {
+  const value = Number(process.env[name] ?? fallback);
+  return Number.isFinite(value) && value >= min ? Math.floor(value) : fallback;
+}
+
+// WORKER_HEARTBEAT_SECONDS (default 30) and WORKER_DEAD_AFTER_SECONDS (default 120, at least twice the heartbeat):
+// a worker whose heartbeat is older than the latter is considered gone.
+export function workerHeartbeatSeconds() {
+  return envSeconds("WORKER_HEARTBEAT_SECONDS", DEFAULT_HEARTBEAT_SECONDS, 5);
+}
+
+export function workerDeadAfterSeconds() {
+  return Math.max(envSeconds("WORKER_DEAD_AFTER_SECONDS", DEFAULT_DEAD_AFTER_SECONDS, 10), workerHeartbeatSeconds() * 2);
+}
+
+export async function recordWorkerHeartbeat(environment: string) {
+  const now = clock().now();
+  await prisma.workerHeartbeat.upsert({
+    where: { id: workerId() },
+    create: { id: workerId(), hostname: hostname(), environment, startedAt: now, lastHeartbeatAt: now },
+    update: { lastHeartbeatAt: now, environment },
+  });
+}
+
+// Records a heartbeat now and keeps refreshing it while the process lives. Idempotent.
+export async function startWorkerHeartbeat(environment: string) {
+  await recordWorkerHeartbeat(environment);
+  if (heartbeatTimer) {
+    return;
+  }
+  heartbeatTimer = clock().setInterval(() => {
+    recordWorkerHeartbeat(environment).catch((err) => logger.warn("worker heartbeat failed", { worker_id: workerId(), error: err }));
+  }, workerHeartbeatSeconds() * 1000);
+}
+
+// Graceful shutdown: the locks are released by the caller, so the row can go right away.
+export async function stopWorkerHeartbeat() {
+  heartbeatTimer?.clear();
+  heartbeatTimer = null;
+  await prisma.workerHeartbeat.deleteMany({ where: { id: workerId() } });
+}
+
+// Releases job locks held by workers that stopped heartbeating. Their in-flight runs can no longer finish (the final
+// update is fenced on locked_at), so they are recorded as cancelled, and their run-now requests go back to pending.
+// Locks without locked_by (taken before worker ids existed) still expire through WORKER_LOCK_STALE_MINUTES.
+export async function reapDeadWorkerLocks() {
+  const deadAfter = workerDeadAfterSeconds();
+  const rows = await prisma.$queryRaw<Array<{ id: string; locked_by: string }>>`
+    WITH dead AS (
+      SELECT id FROM worker_heartbeats
+      WHERE last_heartbeat_at < now() - make_interval(secs => ${deadAfter}::int)
+    ),
+    candidate AS (
+      SELECT j.id, j.locked_by
+      FROM jobs j
+      WHERE j.locked_at IS NOT NULL
+        AND j.locked_by IN (SELECT id FROM dead)
+      FOR UPDATE SKIP LOCKED
+    )
+    UPDATE jobs
+    SET locked_at = NULL, locked_by = NULL
+    FROM candidate
+    WHERE jobs.id = candidate.id
+    RETURNING jobs.id, candidate.locked_by;
+  `;
+
+  for (const row of rows) {
+    await prisma.runHistory.updateMany({
+      where: { jobId: row.id, status: "running", deliverAt: null },
+      data: { status: "cancelled", errorMessage: "Worker stopped responding during the run" },
+    });
+    await prisma.runRequest.updateMany({ where: { jobId: row.id, status: "running" }, data: { status: "pending", startedAt: null } });
+    logger.warn("released lock of dead worker", { job_id: row.id, worker_id: row.locked_by });
+  }
+
+  await prisma.$executeRaw`
+    DELETE FROM worker_heartbeats
+    WHERE last_heartbeat_at < now() - make_interval(hours => ${FORGET_AFTER_HOURS}::int)
+  `;
+  return rows.length;
+}
@@ -0,0 +1,43 @@
+import { describe, expect, it } from "vitest";
+
+import { nextPollDelayMs } from "../../scripts/worker-loop.mjs";
+
+const INTERVAL = 60_000;
+const MAX = 300_000;
+const NOW = Date.parse("2026-05-01T12:00:00Z");
+
+const poll = (data: unknown, idleMs = 0) => nextPollDelayMs(data, idleMs, INTERVAL, MAX, NOW);
+
+describe("worker poll delay", () => {
+  it("waits the base interval after a tick that drained the queue", () => {
+    expect(poll({ ok: true, processed: 3, queueDrained: true })).toEqual({ waitMs: INTERVAL, idleMs: 0 });
+  });
+
+  it("polls again right away while due jobs are left over", () => {
+    expect(poll({ ok: true, processed: 25, queueDrained: false }, 120_000)).toEqual({ waitMs: 0, idleMs: 0 });
+  });
+
+  it("doubles the wait on idle ticks up to the maximum", () => {
+    const waits: number[] = [];
+    let idleMs = 0;
+    for (let tick = 0; tick < 5; tick++) {
+      const next = poll({ ok: true, processed: 0, queueDrained: true, nextDueAt: null }, idleMs);
+      waits.push(next.waitMs);
+      idleMs = next.idleMs;
+    }
+    expect(waits).toEqual([60_000, 120_000, 240_000, 300_000, 300_000]);
+  });
+
+  it("wakes up when the next job is due", () => {
+    const nextDueAt = new Date(NOW + 45_000).toISOString();
+    expect(poll({ ok: true, processed: 0, queueDrained: true, nextDueAt }, 120_000)).toEqual({ waitMs: 45_000, idleMs: 240_000 });
+    const overdue = new Date(NOW - 1000).toISOString();
+    expect(poll({ ok: true, processed: 0, queueDrained: true, nextDueAt: overdue }, 120_000).waitMs).toBe(240_000);
+  });
+
+  it("resets the backoff after an error or a degraded tick", () => {
+    expect(poll(null, 240_000)).toEqual({ waitMs: INTERVAL, idleMs: 0 });
+    expect(poll({ ok: false, error: "Unauthorized" }, 240_000)).toEqual({ waitMs: INTERVAL, idleMs: 0 });
+    expect(poll({ ok: true, degraded: true, processed: 0 }, 240_000)).toEqual({ waitMs: INTERVAL, idleMs: 0 });
+  });
+});
@@ -0,0 +1,87 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+import { formatOtlp, formatPrometheus, metricSamples, parseHeaderList, pushMetrics, recordTickMetrics } from "./worker-metrics";
+
+describe("worker metrics", () => {
+  afterEach(() => {
+    vi.unstubAllEnvs();
+    vi.unstubAllGlobals();
+  });
+
+  it("sums tick results into counters", () => {
+    recordTickMetrics({ processed: 2, success: 1, degraded: false }, 1500);
+    recordTickMetrics({ processed: 3, success: 3, degraded: true }, 500);
+    const byName = Object.fromEntries(metricSamples().map((sample) => [sample.name, sample.value]));
+    expect(byName.promptloop_worker_ticks_total).toBe(2);
+    expect(byName.promptloop_worker_processed_total).toBe(5);
+    expect(byName.promptloop_worker_success_total).toBe(4);
+    expect(byName.promptloop_worker_degraded).toBe(1);
+    expect(byName.promptloop_worker_last_tick_duration_seconds).toBe(0.5);
+  });
+
+  it("keeps the queue depth as a gauge of the last tick", () => {
+    recordTickMetrics({ claimBatches: 2, queueDepth: 40 }, 100);
+    recordTickMetrics({ claimBatches: 1, queueDepth: 7 }, 100);
+    const byName = Object.fromEntries(metricSamples().map((sample) => [sample.name, sample.value]));
+    expect(byName.promptloop_worker_queue_depth).toBe(7);
+    expect(byName.promptloop_worker_claim_batches_total).toBe(3);
+    expect(byName).not.toHaveProperty("promptloop_worker_queue_depth_total");
+  });
+
+  it("formats Prometheus text and OTLP JSON", () => {
+    const samples = [
+      { name: "promptloop_worker_fail_total", type: "counter" as const, help: "Failures.", value: 4 },
+      { name: "promptloop_worker_degraded", type: "gauge" as const, help: "Outage.", value: 0 },
+    ];
+    expect(formatPrometheus(samples, { environment: 'prod "eu"' })).toBe(
+      [
+        "# HELP promptloop_worker_fail_total Failures.",
+        "# TYPE promptloop_worker_fail_total counter",
+        'promptloop_worker_fail_total{environment="prod \\"eu\\""} 4',
+        "# HELP promptloop_worker_degraded Outage.",
+        "# TYPE promptloop_worker_degraded gauge",
+        'promptloop_worker_degraded{environment="prod \\"eu\\""} 0',
+        "",
+      ].join("\n"),
+    );
+
+    const otlp = formatOtlp(samples, { "service.name": "w" }, 2000);
+    const [counter, gauge] = otlp.resourceMetrics[0].scopeMetrics[0].metrics;
+    expect(counter).toMatchObject({ sum: { isMonotonic: true, aggregationTemporality: 2, dataPoints: [{ asDouble: 4, timeUnixNano: "2000000000" }] } });
+    expect(gauge).toMatchObject({ gauge: { dataPoints: [{ asDouble: 0 }] } });
+  });
+
+  it("parses header lists", () => {
+    expect(parseHeaderList("Authorization=Bearer%20abc, x-tenant = t1,broken")).toEqual({ Authorization: "Bearer abc", "x-tenant": "t1" });
+    expect(parseHeaderList(undefined)).toEqual({});
+  });
+
+  it("pushes to a Pushgateway or an OTLP collector", async () => {
+    const fetchMock = vi.fn().mockResolvedValue(new Response(null, { status: 200 }));
+    vi.stubGlobal("fetch", fetchMock);
+
+    expect(await pushMetrics("prod")).toBe(false);
+    expect(fetchMock).not.toHaveBeenCalled();
+
+    vi.stubEnv("METRICS_PUSH_URL", "http://gateway:9091/");
+    vi.stubEnv("METRICS_PUSH_INSTANCE", "fly-1");
+    vi.stubEnv("METRICS_PUSH_HEADERS", "authorization=Basic%20eA==");
+    expect(await pushMetrics("prod")).toBe(true);
+    const [url, init] = fetchMock.mock.calls[0];
+    expect(url).toBe("http://gateway:9091/metrics/job/promptloop_worker/instance/fly-1");
+    expect(init.method).toBe("PUT");
+    expect(init.headers.authorization).toBe("Basic eA==");
+    expect(init.body).toContain('promptloop_worker_ticks_total{environment="prod"}');
+
+    vi.stubEnv("METRICS_PUSH_FORMAT", "otlp");
+    vi.stubEnv("METRICS_PUSH_URL", "http://collector:4318");
+    expect(await pushMetrics("prod")).toBe(true);
+    expect(fetchMock.mock.calls[1][0]).toBe("http://collector:4318/v1/metrics");
+    expect(JSON.parse(fetchMock.mock.calls[1][1].body).resourceMetrics[0].resource.attributes).toContainEqual({
+      key: "deployment.environment",
+      value: { stringValue: "prod" },
+    });
+
+    fetchMock.mockResolvedValueOnce(new Response(null, { status: 500 }));
+    expect(await pushMetrics("prod")).toBe(false);
+  });
+});
@@ -0,0 +1,181 @@
+import { hostname } from "os";
+import { logger } from "@/lib/logger";
+import { clockOffsetMs, nowMs } from "@/lib/clock";
+
+// Worker counters kept in process memory since start. They are served by /api/cron/metrics for Prometheus to
+// scrape and, where the worker can't be reached (Fly machines, Cloud Run jobs), pushed after every tick to
+// METRICS_PUSH_URL: a Pushgateway (METRICS_PUSH_FORMAT=prometheus, default) or an OTLP/HTTP collector (otlp).
+export const METRICS_PUSH_FORMATS = ["prometheus", "otlp"] as const;
+export type MetricsPushFormat = (typeof METRICS_PUSH_FORMATS)[number];
+
+const PREFIX = "promptloop_worker_";
+const PUSH_TIMEOUT_MS = 5000;
+const PUSHGATEWAY_JOB = "promptloop_worker";
+
+export type MetricSample = { name: string; type: "counter" | "gauge"; help: string; value: number };
+
+const counters = new Map<string, number>();
+let ticks = 0;
+let tickErrors = 0;
+let claims = 0;
+let degraded = 0;
+let lastTickSeconds = 0;
+let lastTickDurationSeconds = 0;
+let queueDepth = 0;
+const startedSeconds = Math.floor(nowMs() / 1000);
+// Result fields that describe the tick's state rather than count work; reported as gauges, not summed.
+const GAUGE_FIELDS = new Set(["clockOffsetMs", "queueDepth"]);
+
+function snakeCase(key: string) {
+  return key.replace(/[A-Z]/g, (letter) => `_${letter.toLowerCase()}`);
+}
+
+// Adds one tick's result counts (numeric fields of RunDueJobsResult) to the running totals.
+export function recordTickMetrics(result: Record<string, number | boolean | Date | null>, durationMs: number) {
+  ticks++;
+  for (const [key, value] of Object.entries(result)) {
+    if (typeof value === "number" && Number.isFinite(value) && !GAUGE_FIELDS.has(key)) {
+      counters.set(key, (counters.get(key) ?? 0) + value);
+    }
+  }
+  degraded = result.degraded === true ? 1 : 0;
+  queueDepth = typeof result.queueDepth === "number" ? result.queueDepth : 0;
+  lastTickSeconds = nowMs() / 1000;
+  lastTickDurationSeconds = durationMs / 1000;
+}
+
+export function recordTickError() {
+  tickErrors++;
+}
+
+// Due jobs and run-now requests claimed by this process.
+export function recordClaim(count = 1) {
+  claims += count;
+}
+
+export function workerCounters() {
+  return { ticks, tickErrors, claims };
+}
+
+export function metricSamples(): MetricSample[] {
+  return [
+    { name: `${PREFIX}ticks_total`, type: "counter", help: "Worker ticks completed.", value: ticks },
+    { name: `${PREFIX}tick_errors_total`, type: "counter", help: "Worker ticks that threw.", value: tickErrors },
+    { name: `${PREFIX}claims_total`, type: "counter", help: "Jobs and run-now requests claimed.", value: claims },
+    ...[...counters.entries()].map(([key, value]): MetricSample => ({
+      name: `${PREFIX}${snakeCase(key)}_total`,
+      type: "counter",
+      help: `Sum of ${key} over worker ticks.`,
+      value,
+    })),
+    { name: `${PREFIX}degraded`, type: "gauge", help: "1 while the last tick saw a provider outage.", value: degraded },
+    { name: `${PREFIX}last_tick_timestamp_seconds`, type: "gauge", help: "End of the last tick (unix seconds).", value: lastTickSeconds },
+    { name: `${PREFIX}last_tick_duration_seconds`, type: "gauge", help: "Duration of the last tick.", value: lastTickDurationSeconds },
+    { name: `${PREFIX}queue_depth`, type: "gauge", help: "Due, unlocked jobs left after the last claim.", value: queueDepth },
+    { name: `${PREFIX}clock_offset_seconds`, type: "gauge", help: "Database clock minus this worker's clock.", value: clockOffsetMs() / 1000 },
+    { name: `${PREFIX}start_time_seconds`, type: "gauge", help: "Process start (unix seconds).", value: startedSeconds },
+  ];
+}
+
+// Prometheus text exposition format (0.0.4).
+export function formatPrometheus(samples: MetricSample[], labels: Record<string, string> = {}) {
+  const labelText = Object.entries(labels)
+    .map(([key, value]) => `${key}="${value.replace(/\\/g, "\\\\").replace(/"/g, '\\"').replace(/\n/g, "\\n")}"`)
+    .join(",");
+  return samples
+    .flatMap((sample) => [
+      `# HELP ${sample.name} ${sample.help}`,
+      `# TYPE ${sample.name} ${sample.type}`,
+      `${sample.name}${labelText ? `{${labelText}}` : ""} ${sample.value}`,
+    ])
+    .join("\n")
+    .concat("\n");
+}
+
+// OTLP/HTTP JSON body (ExportMetricsServiceRequest); counters become cumulative monotonic sums.
+export function formatOtlp(samples: MetricSample[], attributes: Record<string, string>, timeMs = nowMs()) {
+  const timeUnixNano = `${BigInt(Math.floor(timeMs)) * 1_000_000n}`;
+  const startTimeUnixNano = `${BigInt(startedSeconds) * 1_000_000_000n}`;
+  const resourceAttributes = Object.entries(attributes).map(([key, value]) => ({ key, value: { stringValue: value } }));
+  return {
+    resourceMetrics: [
+      {
+        resource: { attributes: resourceAttributes },
+        scopeMetrics: [
+          {
+            scope: { name: "promptloop" },
+            metrics: samples.map((sample) => {
+              const dataPoints = [{ asDouble: sample.value, startTimeUnixNano, timeUnixNano }];
+              return sample.type === "counter"
+                ? { name: sample.name, description: sample.help, sum: { dataPoints, aggregationTemporality: 2, isMonotonic: true } }
+                : { name: sample.name, description: sample.help, gauge: { dataPoints } };
+            }),
+          },
+        ],
+      },
+    ],
+  };
+}
+
+export function metricsPushFormat(): MetricsPushFormat {
+  const value = process.env.METRICS_PUSH_FORMAT?.trim().toLowerCase();
+  return METRICS_PUSH_FORMATS.includes(value as MetricsPushFormat) ? (value as MetricsPushFormat) : "prometheus";
+}
+
+// "key=value,key2=value2", the same shape as OTEL_EXPORTER_OTLP_HEADERS.
+export function parseHeaderList(value: string | undefined) {
+  const headers: Record<string, string> = {};
+  for (const part of (value ?? "").split(",")) {
+    const index = part.indexOf("=");
+    if (index > 0) {
+      headers[decodeURIComponent(part.slice(0, index).trim())] = decodeURIComponent(part.slice(index + 1).trim());
+    }
+  }
+  return headers;
+}
+
+function metricsInstance() {
+  return process.env.METRICS_PUSH_INSTANCE?.trim() || hostname();
+}
+
+// Pushes the current totals to METRICS_PUSH_URL. Best effort: a failed push is logged and the next tick retries.
+// Returns whether a push was made.
+export async function pushMetrics(environment: string) {
+  const url = process.env.METRICS_PUSH_URL?.trim().replace(/\/+$/, "");
+  if (!url) {
+    return false;
+  }
+  const format = metricsPushFormat();
+  const headers = parseHeaderList(process.env.METRICS_PUSH_HEADERS);
+  const instance = metricsInstance();
+  try {
+    const response =
+      format === "otlp"
+        ? await fetch(url.endsWith("/v1/metrics") ? url : `${url}/v1/metrics`, {
+            method: "POST",
+            headers: { "content-type": "application/json", ...headers },
+            body: JSON.stringify(
+              formatOtlp(metricSamples(), {
+                "service.name": process.env.OTEL_SERVICE_NAME?.trim() || "promptloop-worker",
+                "service.instance.id": instance,
+                "deployment.environment": environment,
+              }),
+            ),
+            signal: AbortSignal.timeout(PUSH_TIMEOUT_MS),
+          })
+        : // PUT replaces the whole group, so series of this instance never go stale inside the gateway.
+          await fetch(`${url}/metrics/job/${PUSHGATEWAY_JOB}/instance/${encodeURIComponent(instance)}`, {
+            method: "PUT",
+            headers: { "content-type": "text/plain; version=0.0.4", ...headers },
+            body: formatPrometheus(metricSamples(), { environment }),
+            signal: AbortSignal.timeout(PUSH_TIMEOUT_MS),
+          });
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+    return true;
+  } catch (err) {
+    logger.warn("metrics push failed", { format, error: err });
+    return false;
+  }
+}
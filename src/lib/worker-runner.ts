@@ -1,24 +1,84 @@
-import { ChannelType } from "@prisma/client";
+import { AsyncLocalStorage } from "node:async_hooks";
+import { ChannelType, Prisma, type Job } from "@prisma/client";
 import { prisma } from "@/lib/prisma";
 import { runPrompt } from "@/lib/llm";
-import { sendChannelMessage, ChannelRequestError } from "@/lib/channel";
-import { toRunnableChannel } from "@/lib/jobs";
-import { computeNextRunAt } from "@/lib/schedule";
+import { normalizeLlmTools, type LlmTool } from "@/lib/llm-tools";
+import { sendChannelMessage, ChannelRequestError, retryAfterMaxMs, type ChannelAttachment, type SendChannelInput } from "@/lib/channel";
+import { runnableJobChannel } from "@/lib/saved-channels";
+import { runCredentialChecks } from "@/lib/channel-health";
+import { ChannelConfigError, upgradeChannelConfigs } from "@/lib/channel-config";
+import { reapDeadWorkerLocks, startWorkerHeartbeat, stopWorkerHeartbeat, workerId } from "@/lib/worker-identity";
+import { pushMetrics, recordClaim, recordTickError, recordTickMetrics } from "@/lib/worker-metrics";
+import { applyPostLlm, applyPreDelivery, applyPreLlm, notifyPostDelivery, type PreDeliveryInput, type RunContext } from "@/lib/run-middleware";
+import {
+  computeFailureRetryAt,
+  computeNextRunAt,
+  normalizeCatchupPolicy,
+  scheduleConfigError,
+  type FailureBackoffPolicy,
+} from "@/lib/schedule";
 import { enforceDailyRunLimit } from "@/lib/limits";
 import { getOrCreatePublishedPromptVersion } from "@/lib/prompt-version";
-import { normalizeLlmModel, normalizeWebSearchMode, type WebSearchMode } from "@/lib/llm-defaults";
+import { DEFAULT_WEB_SEARCH_MODE, normalizeLlmModel, normalizeTtsVoice, normalizeWebSearchMode, type WebSearchMode } from "@/lib/llm-defaults";
 import { compilePromptTemplate, coerceStringVars } from "@/lib/prompt-compile";
 import { buildPostPromptVariables, normalizePostPromptConfig } from "@/lib/post-prompt";
-import { formatRunTitle } from "@/lib/run-title";
+import { normalizePipelineSteps, runPipelineSteps } from "@/lib/pipeline";
+import { generationParamsFromJob, type GenerationParams } from "@/lib/generation-params";
+import { fetchPromptInputs, formatInputsForPrompt, normalizePromptInputs } from "@/lib/prompt-inputs";
+import { formatRunTitle, renderRunHeader } from "@/lib/run-title";
+import { formatRepliesForPrompt, REPLIES_PER_RUN_MAX } from "@/lib/replies";
+import { formatPreviousOutputsForPrompt, loadPreviousOutputs } from "@/lib/previous-output";
+import { deliverySkipReason, normalizeDeliverIf, outputHash } from "@/lib/deliver-if";
+import { changeSummaryPrompt, diffDeliveryBody, formatDiffBlock, normalizeDeliveryDiff, unifiedDiff } from "@/lib/output-diff";
+import { truncateOutput, withOutputLengthInstruction } from "@/lib/output-length";
+import { outboxMessage } from "@/lib/outbox-message";
+import { flushTraces } from "@/lib/otlp-traces";
+import { quietWindowEnd } from "@/lib/quiet-hours";
+import { loadUserSecrets, redactSecrets, secretTemplateFunctions, usesSecrets } from "@/lib/secrets";
+import { recordDeadLetter } from "@/lib/dead-letters";
+import { runUsageColumns } from "@/lib/usage-cost";
+import { isAutoModel, routeJobModels } from "@/lib/model-router";
+import { assignCanary } from "@/lib/canary";
+import { nextModelIndex, withFallbackModels } from "@/lib/model-fallback";
+import { pruneResponseCache, readCachedResponse, responseCacheKey, writeCachedResponse } from "@/lib/response-cache";
+import { debugCaptureEntry, debugPayloadFromError, type DebugCaptureEntry } from "@/lib/debug-capture";
+import { formatFailureNotice } from "@/lib/failure-notice";
+import { autoDisableSettings, pausedUntil, repeatedFailureAction } from "@/lib/auto-disable";
+import { newTenantShare, recordTenantClaim, tenantClaimFilter, tenantFairnessPolicy, type TenantShare } from "@/lib/tenant-fairness";
+import { pauseDormantJobs } from "@/lib/dormant-jobs";
+import { reencryptStaleSecrets } from "@/lib/key-rotation";
+import { nextDeliveryRetryAt } from "@/lib/delivery-retry";
+import { failureRunStatus, GENERATED_RUN_STATUSES } from "@/lib/run-status";
+import { claimSlowRunNotice, formatSlowRunNotice, isSlowRun } from "@/lib/slow-runs";
+import { speechText, synthesizeSpeech } from "@/lib/tts";
+import { checkMonthlyBudget, claimBudgetNotice, formatBudgetNotice } from "@/lib/budgets";
+import { claimOutageProbe, getProviderHealth, isProviderFailure, recordLlmOutcome } from "@/lib/provider-health";
+import { loadRunAttachments, pruneExpiredArtifacts, saveRunArtifacts } from "@/lib/run-artifacts";
+import { runOutputUrl, saveRunOutput } from "@/lib/run-outputs";
+import { archiveRunOutput } from "@/lib/output-archive";
+import { isSampledRun, scoreRunQuality } from "@/lib/quality-eval";
+import { mirrorToQa } from "@/lib/qa-mirror";
+import { dependencyRecheckAt, dependencyWindowStart, findUpstreamRun, formatUpstreamOutputForPrompt } from "@/lib/job-dependencies";
+import { withSpan } from "@/lib/tracing";
+import { clock, clockOffsetMs, nowMs, type ClockTimer } from "@/lib/clock";
+import { syncClockWithDatabase } from "@/lib/db-clock";
+import { pruneRunHistories } from "@/lib/history-retention";
+import { asMaintenanceLeader } from "@/lib/maintenance-leader";
+import { DIGEST_MAX_RUNS, formatThrottleDigest, hasThrottleRoom, normalizeThrottleWindow } from "@/lib/throttle";
+import { isRecord } from "@/lib/type-guards";
+import { logger, type Logger } from "@/lib/logger";
+import { installErrorReportingHook, reportError } from "@/lib/error-reporting";
+import type { Span } from "@opentelemetry/api";
+import type { UserPlan } from "@/lib/entitlements";
+import { workerConfigFromEnv, type WorkerConfig } from "@/lib/worker-config";
 
-const DEFAULT_LOCK_STALE_MINUTES = 10;
-const MAX_FAILS_BEFORE_DISABLE = 10;
 const OUTPUT_PREVIEW_MAX = 1000;
 const ERROR_MAX = 500;
-
-function sleep(ms: number) {
-  return new Promise((r) => setTimeout(r, ms));
-}
+const RENDERED_PARTS_MAX = 50;
+const RENDERED_PART_MAX = 20_000;
+const RESPONSE_BODY_MAX = 4000;
+const OUTAGE_NOTICE_TEXT =
+  "This scheduled run was postponed because the AI provider is currently unavailable. It will run once the provider recovers.";
 
 function retryBackoff(attempt: number) {
   const base = 400;
@@ -48,96 +108,437 @@ function truncate(value: string, max: number) {
   return value.slice(0, max);
 }
 
-async function lockNextDueJob() {
-  const staleMinutes = Number(process.env.WORKER_LOCK_STALE_MINUTES ?? DEFAULT_LOCK_STALE_MINUTES);
-  const stale = Number.isFinite(staleMinutes) && staleMinutes > 0 ? staleMinutes : DEFAULT_LOCK_STALE_MINUTES;
+// Settings of the tick in progress (runWorker); outside a tick, e.g. for run-now requests, they come from the env.
+const configStore = new AsyncLocalStorage<WorkerConfig>();
 
-  const rows = await prisma.$queryRaw<Array<{ id: string; locked_at: Date }>>`
-    WITH candidate AS (
+function workerConfig() {
+  return configStore.getStore() ?? workerConfigFromEnv();
+}
+
+function lockStaleMinutes() {
+  return workerConfig().lockStaleMinutes;
+}
+
+// Spreads synchronized slots (everything due at :00) over this many seconds; 0 disables smoothing. Capped below
+// the catch-up grace so a smoothed run is never treated as missed.
+function smoothingWindowSeconds() {
+  const seconds = workerConfig().smoothingWindowSeconds;
+  return Math.min(seconds, Math.max(0, Math.floor(catchupGraceMs() / 1000) - 60));
+}
+
+// Jobs are scoped by environment so a staging worker pointed at a copied database never delivers production jobs.
+export function workerEnvironment() {
+  return workerConfig().environment;
+}
+
+// Jobs sharing a concurrency group (per owner) never run at the same time: a job is claimable only while no
+// sibling holds a fresh lock. The advisory lock covers two workers claiming different siblings in the same
+// instant; it is released when the claim statement commits, by which time locked_at is visible to the next one.
+function concurrencyGroupFree(table: string, stale: number) {
+  const t = Prisma.raw(table);
+  return Prisma.sql`(${t}.concurrency_group IS NULL OR (
+    NOT EXISTS (
+      SELECT 1
+      FROM jobs sibling
+      WHERE sibling.user_id = ${t}.user_id
+        AND sibling.concurrency_group = ${t}.concurrency_group
+        AND sibling.id <> ${t}.id
+        AND sibling.locked_at >= now() - make_interval(mins => ${stale}::int)
+    )
+    AND pg_try_advisory_xact_lock(hashtext('concurrency-group:' || ${t}.user_id::text || ':' || ${t}.concurrency_group))
+  ))`;
+}
+
+// Rows locked per claim statement before ranking, so a batch can skip siblings and spread across users.
+const CLAIM_SCAN_FACTOR = 4;
+const CLAIM_RETRY_BASE_MS = 100;
+// Serialization failure, deadlock, lock not available.
+const TRANSIENT_CLAIM_ERRORS = new Set(["40001", "40P01", "55P03"]);
+
+// Claims up to `limit` due jobs in one statement and reports how many due, unlocked jobs are left after it (an upper
+// bound: it ignores caps and concurrency groups), so the tick can tell a drained queue from a full one without a
+// separate COUNT query. Within a batch at most one job per concurrency group is taken and users are interleaved
+// (their first due job, then their second, ...); with a per-tick user cap each user gets one job per batch.
+async function lockDueJobs(limit: number, share: TenantShare | null = null) {
+  const stale = lockStaleMinutes();
+  const environment = workerEnvironment();
+  const smoothing = smoothingWindowSeconds();
+  const { served, capped } = share ? tenantClaimFilter(share) : { served: [], capped: [] };
+  const perUser = share?.policy.perTick ? 1 : 0;
+
+  // With smoothing, each recurring job gets a stable offset (hash of its id) within the window; one-shot jobs and
+  // failure retries are not delayed. The slot itself (next_run_at) is unchanged, so scheduledFor stays exact.
+  const rows = await prisma.$queryRaw<Array<{ id: string | null; locked_at: Date | null; user_id: string | null; remaining: number }>>`
+    WITH locked_candidate AS (
+      SELECT id, user_id, concurrency_group, priority, next_run_at
+      FROM jobs
+      WHERE enabled = true
+        AND environment = ${environment}
+        AND next_run_at <= now()
+        AND next_run_at + make_interval(secs => CASE
+              WHEN ${smoothing}::int > 0 AND schedule_type <> 'once' AND retry_attempt = 0
+                THEN ((hashtext(id::text) % ${smoothing}::int) + ${smoothing}::int) % ${smoothing}::int
+              ELSE 0
+            END) <= now()
+        AND (paused_until IS NULL OR paused_until <= now())
+        AND (locked_at IS NULL OR locked_at < now() - make_interval(mins => ${stale}::int))
+        AND NOT (user_id = ANY(${capped}::uuid[]))
+        AND ${concurrencyGroupFree("jobs", stale)}
+      ORDER BY priority DESC, (user_id = ANY(${served}::uuid[])), next_run_at
+      LIMIT ${limit * CLAIM_SCAN_FACTOR}
+      FOR UPDATE SKIP LOCKED
+    ),
+    ranked AS (
+      SELECT id, user_id, priority, next_run_at,
+        row_number() OVER (PARTITION BY user_id ORDER BY priority DESC, next_run_at) AS user_rank,
+        row_number() OVER (PARTITION BY user_id, COALESCE(concurrency_group, id::text) ORDER BY priority DESC, next_run_at) AS group_rank
+      FROM locked_candidate
+    ),
+    candidate AS (
       SELECT id
+      FROM ranked
+      WHERE group_rank = 1 AND (${perUser}::int = 0 OR user_rank <= ${perUser}::int)
+      ORDER BY priority DESC, (user_id = ANY(${served}::uuid[])), user_rank, next_run_at
+      LIMIT ${limit}
+    ),
+    claimed AS (
+      UPDATE jobs
+      SET locked_at = date_trunc('milliseconds', now()), locked_by = ${workerId()}
+      FROM candidate
+      WHERE jobs.id = candidate.id
+      RETURNING jobs.id, jobs.locked_at, jobs.user_id
+    ),
+    due AS (
+      SELECT count(*)::int AS total
       FROM jobs
       WHERE enabled = true
+        AND environment = ${environment}
         AND next_run_at <= now()
+        AND (paused_until IS NULL OR paused_until <= now())
         AND (locked_at IS NULL OR locked_at < now() - make_interval(mins => ${stale}::int))
-      ORDER BY next_run_at
+    )
+    SELECT claimed.id, claimed.locked_at, claimed.user_id, GREATEST(due.total - (SELECT count(*)::int FROM claimed), 0) AS remaining
+    FROM due
+    LEFT JOIN claimed ON true;
+  `;
+
+  const locks: JobLock[] = [];
+  for (const row of rows) {
+    if (!row.id || !row.locked_at || !row.user_id) {
+      continue;
+    }
+    if (share) {
+      recordTenantClaim(share, row.user_id);
+    }
+    locks.push({ id: row.id, lockedAt: row.locked_at });
+  }
+  return { locks, remaining: rows[0]?.remaining ?? 0 };
+}
+
+function isTransientClaimError(err: unknown) {
+  if (!isRecord(err)) {
+    return false;
+  }
+  // P2034: transaction conflict or deadlock; raw query failures (P2010) carry the Postgres code in meta.
+  return err.code === "P2034" || (isRecord(err.meta) && TRANSIENT_CLAIM_ERRORS.has(String(err.meta.code)));
+}
+
+// A claim statement that hit a serialization failure, deadlock or lock timeout was rolled back as a whole, so it is
+// run again, up to WORKER_CLAIM_RETRIES times (default: 3) with exponential backoff from 100ms plus jitter.
+async function withClaimRetry<T>(claim: () => Promise<T>, random = Math.random): Promise<T> {
+  const retries = workerConfig().claimRetries;
+  for (let attempt = 0; ; attempt++) {
+    try {
+      return await claim();
+    } catch (err) {
+      if (attempt >= retries || !isTransientClaimError(err)) {
+        throw err;
+      }
+      const delayMs = CLAIM_RETRY_BASE_MS * 2 ** attempt;
+      logger.warn("job claim retried", { attempt: attempt + 1, delay_ms: delayMs, error: err });
+      await clock().sleep(delayMs + Math.floor(random() * (delayMs / 2)));
+    }
+  }
+}
+
+// Jobs claimed for slots that stopped before picking them up (time budget, drain, a failed slot) are unlocked
+// untouched; their slot stays due for the next tick.
+async function releaseUnstartedJobs(jobs: Array<{ lock: JobLock; heartbeat: LockHeartbeat }>) {
+  await Promise.allSettled(
+    jobs.map(async ({ lock, heartbeat }) => {
+      await heartbeat.stop();
+      await prisma.job.updateMany({ where: { id: lock.id, lockedAt: lock.lockedAt }, data: { lockedAt: null } });
+      activeLocks.delete(lock);
+    }),
+  );
+}
+
+// Users whose jobs ran at least `cap` times in the past hour (scheduled and run-now runs alike).
+async function usersOverHourlyCap(cap: number) {
+  const rows = await prisma.$queryRaw<Array<{ user_id: string }>>`
+    SELECT j.user_id
+    FROM run_histories r
+    JOIN jobs j ON j.id = r.job_id
+    WHERE r.run_at >= now() - interval '1 hour'
+    GROUP BY j.user_id
+    HAVING count(*) >= ${cap}::int
+  `;
+  return rows.map((row) => row.user_id);
+}
+
+// A snoozed job is not claimable before its snooze ends, even when its slot is already past.
+async function nextDueRunAt() {
+  const [row] = await prisma.$queryRaw<Array<{ next: Date | null }>>`
+    SELECT min(GREATEST(next_run_at, COALESCE(paused_until, next_run_at))) AS next
+    FROM jobs
+    WHERE enabled = true AND environment = ${workerEnvironment()}
+  `;
+  return row?.next ?? null;
+}
+
+// Claims the oldest pending "run now" request whose job is not locked (or the given request, in any environment),
+// and locks the job for it in the same statement. Requests left running by a crashed worker become claimable
+// again after the stale window.
+async function claimRunRequest(requestId: string | null = null) {
+  const stale = lockStaleMinutes();
+  const environment = workerEnvironment();
+
+  const rows = await prisma.$queryRaw<Array<{ id: string; job_id: string; created_at: Date; locked_at: Date }>>`
+    WITH candidate AS (
+      SELECT r.id, r.job_id
+      FROM run_requests r
+      JOIN jobs j ON j.id = r.job_id
+      WHERE (r.status = 'pending' OR (r.status = 'running' AND r.started_at < now() - make_interval(mins => ${stale}::int)))
+        AND (${requestId}::uuid IS NULL OR r.id = ${requestId}::uuid)
+        AND (${requestId}::uuid IS NOT NULL OR j.environment = ${environment})
+        AND (j.locked_at IS NULL OR j.locked_at < now() - make_interval(mins => ${stale}::int))
+        AND ${concurrencyGroupFree("j", stale)}
+      ORDER BY r.created_at
       LIMIT 1
-      FOR UPDATE SKIP LOCKED
+      FOR UPDATE OF r, j SKIP LOCKED
+    ),
+    locked AS (
+      UPDATE jobs
+      SET locked_at = date_trunc('milliseconds', now()), locked_by = ${workerId()}
+      FROM candidate
+      WHERE jobs.id = candidate.job_id
+      RETURNING jobs.id, jobs.locked_at
     )
-    UPDATE jobs
-    SET locked_at = date_trunc('milliseconds', now())
-    FROM candidate
-    WHERE jobs.id = candidate.id
-    RETURNING jobs.id, jobs.locked_at;
+    UPDATE run_requests
+    SET status = 'running', started_at = now()
+    FROM candidate, locked
+    WHERE run_requests.id = candidate.id
+    RETURNING run_requests.id, run_requests.job_id, run_requests.created_at, locked.locked_at;
   `;
 
   if (!rows.length) {
     return null;
   }
+  const row = rows[0];
+  return { lock: { id: row.job_id, lockedAt: row.locked_at }, request: { id: row.id, requestedAt: row.created_at } };
+}
+
+// Claims one run whose deferred delivery is due. deliver_at is pushed forward as a lease so a crashed
+// worker's claim becomes due again after the stale window instead of being delivered twice in parallel.
+async function claimDueDelivery() {
+  const stale = lockStaleMinutes();
+  const environment = workerEnvironment();
+
+  const rows = await prisma.$queryRaw<Array<{ id: string }>>`
+    WITH candidate AS (
+      SELECT id
+      FROM run_histories
+      WHERE deliver_at <= now()
+        AND delivered_at IS NULL
+        AND status = 'running'
+        AND job_id IN (SELECT id FROM jobs WHERE environment = ${environment})
+      ORDER BY deliver_at
+      LIMIT 1
+      FOR UPDATE SKIP LOCKED
+    )
+    UPDATE run_histories
+    SET deliver_at = now() + make_interval(mins => ${stale}::int)
+    FROM candidate
+    WHERE run_histories.id = candidate.id
+    RETURNING run_histories.id;
+  `;
 
-  return { id: rows[0].id, lockedAt: rows[0].locked_at };
+  return rows.length ? rows[0].id : null;
 }
 
-async function recordDeliveryAttempt(runHistoryId: string, attempt: number, status: string, statusCode?: number, errorMessage?: string) {
+async function recordDeliveryAttempt(
+  runHistoryId: string,
+  attempt: number,
+  status: string,
+  rendered: string[],
+  partsDelivered: number,
+  statusCode?: number,
+  errorMessage?: string,
+) {
   await prisma.deliveryAttempt.create({
     data: {
       runHistoryId,
       attempt,
       status,
+      partsDelivered,
       statusCode: statusCode ?? null,
       errorMessage: errorMessage ?? null,
+      renderedMessage: rendered.length ? rendered.slice(0, RENDERED_PARTS_MAX).map((part) => truncate(part, RENDERED_PART_MAX)) : undefined,
     },
   });
 }
 
+// Webhook response capture: the last attempt's response replaces the previous one on the run.
+async function recordWebhookResponse(runHistoryId: string, response: { status: number; body: string } | null) {
+  if (!response) {
+    return;
+  }
+  await prisma.runHistory.update({
+    where: { id: runHistoryId },
+    data: { responseStatus: response.status, responseBody: truncate(response.body, RESPONSE_BODY_MAX) },
+  });
+}
+
 async function deliverWithRetryAndReceipts(
   runHistoryId: string,
-  channel: ReturnType<typeof toRunnableChannel>,
+  channel: SendChannelInput,
   title: string,
   output: string,
-  opts?: { citations?: { url: string; title?: string }[]; usedWebSearch?: boolean; meta?: Record<string, unknown> },
+  opts?: {
+    citations?: { url: string; title?: string }[];
+    attachments?: ChannelAttachment[];
+    usedWebSearch?: boolean;
+    meta?: Record<string, unknown>;
+    userAgent?: string | null;
+    plan?: UserPlan | null;
+    log?: Logger;
+    // Attempt numbers continue across durable retries of the same run.
+    firstAttempt?: number;
+    // Leading message parts an earlier attempt already delivered; they are not sent again.
+    deliveredParts?: number;
+    // Cut long chat messages to their first part plus a signed link to the full output.
+    fullOutputLink?: boolean;
+  },
 ) {
-  const maxRetries = Number(process.env.WORKER_DELIVERY_MAX_RETRIES ?? 3);
-  const retries = Number.isFinite(maxRetries) && maxRetries > 0 ? Math.floor(maxRetries) : 3;
+  const log = opts?.log ?? logger;
+  let fullOutputUrl: string | null = null;
+  if (opts?.fullOutputLink) {
+    try {
+      fullOutputUrl = runOutputUrl(runHistoryId);
+    } catch (err) {
+      log.warn("full output link not available", { error: err });
+    }
+  }
+  const retries = workerConfig().deliveryMaxRetries;
+  const firstAttempt = opts?.firstAttempt ?? 1;
+  const lastAttempt = firstAttempt + retries - 1;
+  let deliveredParts = opts?.deliveredParts ?? 0;
+  // Persisted per part so a crash mid-send cannot lead to duplicate chunks on the next attempt. The first part
+  // also stores the message itself, which a resumed delivery sends again (outboxMessage).
+  const onPartDelivered = async (parts: number) => {
+    deliveredParts = parts;
+    await prisma.runHistory.update({
+      where: { id: runHistoryId },
+      data: parts === 1 ? { deliveredParts: parts, deliveryTitle: title, deliveryBody: output } : { deliveredParts: parts },
+    });
+  };
 
-  for (let attempt = 1; attempt <= retries; attempt++) {
+  for (let attempt = firstAttempt; attempt <= lastAttempt; attempt++) {
+    const attemptStartedAt = nowMs();
+    const rendered: string[] = [];
+    let response: { status: number; body: string } | null = null;
     try {
-      await sendChannelMessage(channel, title, output, {
-        citations: opts?.citations,
-        usedWebSearch: opts?.usedWebSearch,
-        meta: { ...(opts?.meta ?? {}), runHistoryId },
-      });
-      await recordDeliveryAttempt(runHistoryId, attempt, "success");
-      return { attempts: attempt, lastError: null as string | null };
+      await withSpan("promptloop.channel.deliver", { "promptloop.channel.type": channel.type, "promptloop.delivery.attempt": attempt }, () =>
+        sendChannelMessage(channel, title, output, {
+          citations: opts?.citations,
+          attachments: opts?.attachments,
+          usedWebSearch: opts?.usedWebSearch,
+          meta: { ...(opts?.meta ?? {}), runHistoryId },
+          userAgent: opts?.userAgent,
+          plan: opts?.plan,
+          onRendered: (body) => rendered.push(body),
+          onResponse: (captured) => {
+            response = captured;
+          },
+          resumeFromPart: deliveredParts,
+          onPartDelivered,
+          fullOutputUrl,
+        }),
+      );
+      await recordWebhookResponse(runHistoryId, response);
+      await recordDeliveryAttempt(runHistoryId, attempt, "success", rendered, deliveredParts);
+      log.info("delivery succeeded", { attempt, parts_delivered: deliveredParts, duration_ms: nowMs() - attemptStartedAt });
+      return { attempts: attempt, lastError: null as string | null, retryable: false, partial: false };
     } catch (err) {
       const statusCode = err instanceof ChannelRequestError ? err.status : undefined;
       const message = err instanceof Error ? err.message : String(err);
-      await recordDeliveryAttempt(runHistoryId, attempt, "fail", statusCode, truncate(message, ERROR_MAX));
+      await recordWebhookResponse(runHistoryId, response);
+      await recordDeliveryAttempt(runHistoryId, attempt, "fail", rendered, deliveredParts, statusCode, truncate(message, ERROR_MAX));
+      log.warn("delivery attempt failed", {
+        attempt,
+        status_code: statusCode,
+        parts_delivered: deliveredParts,
+        duration_ms: nowMs() - attemptStartedAt,
+        error: message,
+      });
 
-      if (!statusCode || !shouldRetryStatus(statusCode) || attempt >= retries) {
-        return { attempts: attempt, lastError: truncate(message, ERROR_MAX) };
+      // Retries resume after the delivered parts, so a partial delivery is retried like any other failure.
+      const partial = deliveredParts > 0;
+      // Network errors have no status code; they are worth a durable retry but not an immediate one.
+      const retryable = !statusCode || shouldRetryStatus(statusCode);
+      // A rate-limited channel is retried after the wait it asked for, unless that is longer than we hold a run.
+      const retryAfterMs = err instanceof ChannelRequestError ? err.retryAfterMs : null;
+      if (
+        !statusCode ||
+        !shouldRetryStatus(statusCode) ||
+        attempt >= lastAttempt ||
+        (retryAfterMs != null && retryAfterMs > retryAfterMaxMs())
+      ) {
+        return { attempts: attempt, lastError: truncate(message, ERROR_MAX), retryable, partial };
       }
-      await sleep(retryBackoff(attempt));
+      await clock().sleep(retryAfterMs ?? retryBackoff(attempt - firstAttempt + 1));
     }
   }
 
-  return { attempts: retries, lastError: "Delivery failed" };
+  return { attempts: lastAttempt, lastError: "Delivery failed", retryable: true, partial: deliveredParts > 0 };
 }
 
-  async function runPromptWithRetry(prompt: string, opts: { model: string; useWebSearch: boolean; webSearchMode: WebSearchMode }) {
-  const maxRetries = Number(process.env.WORKER_LLM_MAX_RETRIES ?? 2);
-  const retries = Number.isFinite(maxRetries) && maxRetries > 0 ? Math.floor(maxRetries) : 2;
+  async function runPromptWithRetry(
+  prompt: string,
+  opts: {
+    model: string;
+    useWebSearch: boolean;
+    webSearchMode: WebSearchMode;
+    tools?: LlmTool[];
+    captureDebug?: boolean;
+    systemPrompt?: string | null;
+    generation?: GenerationParams;
+  },
+  // Called before every provider call, so runs can count their LLM attempts (llm_attempts).
+  onAttempt?: () => void,
+) {
+  const retries = workerConfig().llmMaxRetries;
 
   let lastErr: unknown;
   for (let attempt = 1; attempt <= retries; attempt++) {
     try {
-      return await runPrompt(prompt, opts);
+      onAttempt?.();
+      const result = await withSpan(
+        "promptloop.llm.run",
+        { "promptloop.llm.model": opts.model, "promptloop.llm.web_search": opts.useWebSearch, "promptloop.llm.attempt": attempt },
+        () => runPrompt(prompt, opts),
+      );
+      await recordLlmOutcome(true);
+      return result;
     } catch (err) {
       lastErr = err;
       const status = errorStatus(err);
+      logger.warn("llm attempt failed", { model: opts.model, attempt, status_code: status ?? undefined, error: err });
       if (!status || !shouldRetryStatus(status) || attempt >= retries) {
+        await recordLlmOutcome(!isProviderFailure(err));
         throw err;
       }
-      await sleep(retryBackoff(attempt));
+      await clock().sleep(retryBackoff(attempt));
     }
   }
 
@@ -153,272 +554,930 @@ export type RunDueJobsResult = {
   fail: number;
   disabled: number;
   duplicates: number;
+  skipped: number;
+  quietDeferred: number;
+  // Dependent jobs held because their upstream job has not succeeded in this period yet.
+  waitingOnUpstream: number;
   quotaBlocked: number;
+  budgetExceeded: number;
+  // Jobs disabled at claim time because their stored schedule or channel config is unusable.
+  configErrors: number;
+  deferredDeliveries: number;
+  expiredArtifacts: number;
+  // Response cache entries past their TTL, deleted this tick.
+  expiredCacheEntries: number;
+  // Runs deleted by the history retention policy (RUN_HISTORY_RETENTION_DAYS / per-job setting).
+  prunedRuns: number;
+  // Jobs paused by the dormant-job policy (WORKER_DORMANT_WEEKS).
+  dormantPaused: number;
+  // One-off "run now" requests executed in this tick.
+  runRequests: number;
+  // Channel configs and user secrets moved off a retired CHANNEL_SECRET_KEYS entry.
+  secretsReencrypted: number;
+  // Provider outage mode: LLM dispatch paused (except a periodic probe run).
+  degraded: boolean;
+  outageNotices: number;
+  // Digest messages carrying outputs held by per-job delivery throttles.
+  throttleDigests: number;
+  // Jobs whose channel credentials were validated (CHANNEL_CREDENTIAL_CHECK_HOURS).
+  credentialChecks: number;
+  // Job locks released because the worker holding them stopped heartbeating.
+  reapedLocks: number;
+  // Unversioned job and saved channel configs rewritten at the current channel_config version.
+  channelConfigsUpgraded: number;
+  // Successful runs that took longer than their job's expected runtime.
+  slowRuns: number;
+  // This worker won the maintenance lock and ran the housekeeping steps (reaping, pruning, upgrades) this tick.
+  maintenanceLeader: boolean;
+  // Database time minus this worker's clock; scheduling uses the corrected time (db-clock.ts).
+  clockOffsetMs: number;
+  // Claim statements run and the due, unlocked jobs left after the last one (lockDueJobs).
+  claimBatches: number;
+  queueDepth: number;
+  // A claim found nothing due; false means the tick stopped at its job limit or time budget with work left, so a
+  // poller should tick again right away.
+  queueDrained: boolean;
+  // Earliest next_run_at in this environment once the queue is drained, so an idle poller can sleep until then.
+  nextDueAt: Date | null;
 };
 
-export async function runDueJobs(opts: { timeBudgetMs: number; maxJobs: number; runnerId?: string }): Promise<RunDueJobsResult> {
-  const startedAt = Date.now();
-  const result: RunDueJobsResult = { processed: 0, success: 0, fail: 0, disabled: 0, duplicates: 0, quotaBlocked: 0 };
+type JobOutcome = {
+  status: "success" | "fail" | "duplicate" | "skipped" | "quiet" | "waiting" | "budget_exceeded" | "config_error";
+  disabled?: boolean;
+  quotaBlocked?: boolean;
+  runHistoryId?: string | null;
+  // Took longer than the job's expected runtime.
+  slow?: boolean;
+};
 
-  while (true) {
-    if (result.processed >= opts.maxJobs) {
-      return result;
-    }
-    if (Date.now() - startedAt >= opts.timeBudgetMs) {
-      return result;
-    }
+// runId is set once the run under this lock is created, runRequestId for run-now claims, so a shutdown can hand
+// the slot back (releaseActiveLocks).
+type JobLock = { id: string; lockedAt: Date; runId?: string; runRequestId?: string };
 
-    const lock = await lockNextDueJob();
-    if (!lock) {
-      return result;
-    }
+// A claimed "run now" request: the run is recorded for requestedAt and leaves the job's schedule alone.
+type RunRequestClaim = { id: string; requestedAt: Date };
 
-    const job = await prisma.job.findUnique({ where: { id: lock.id }, include: { publishedPromptVersion: true } });
-    if (!job) {
-      result.processed++;
-      result.fail++;
-      continue;
-    }
+type LockHeartbeat = { stop: () => Promise<void> };
 
-    const scheduledFor = job.nextRunAt;
-    const pv = job.publishedPromptVersion ?? (await getOrCreatePublishedPromptVersion(job.id));
-    const vars = coerceStringVars(pv.variables);
-    const prompt = compilePromptTemplate(pv.template, vars, { nowIso: scheduledFor.toISOString(), timezone: "UTC" });
+// Locks held by this process, so they can be released if it is asked to shut down.
+const activeLocks = new Set<JobLock>();
+let shuttingDown = false;
+let shutdownHookInstalled = false;
+// Pre-stop drain: no new jobs or deliveries are claimed, in-flight ticks run to completion.
+let draining = false;
+let activeTicks = 0;
 
-    const title = formatRunTitle(job.name, new Date(), "UTC");
+function lockHeartbeatMs() {
+  return workerConfig().lockHeartbeatSeconds * 1000;
+}
 
-    let runHistoryId: string | null = null;
-    try {
-      const created = await prisma.runHistory.create({
-        data: {
-          jobId: job.id,
-          promptVersionId: pv.id,
-          scheduledFor,
-          status: "running",
-          outputText: null,
-          outputPreview: null,
-          errorMessage: null,
-          isPreview: false,
-          runnerId: opts.runnerId ?? null,
-          deliveredAt: null,
-          deliveryAttempts: 0,
-          deliveryLastError: null,
-        },
-        select: { id: true },
-      });
-      runHistoryId = created.id;
-    } catch (err) {
-      const isUnique =
-        typeof err === "object" &&
-        err !== null &&
-        "code" in err &&
-        (err as { code?: unknown }).code === "P2002";
-      if (!isUnique) {
-        throw err;
+// Periodically moves locked_at forward while a job is in flight so long runs are not treated as stale.
+// lock.lockedAt is updated in place because it doubles as the fencing token for the final job update;
+// stop() must be awaited before that update so a tick cannot race it.
+function startLockHeartbeat(lock: JobLock): LockHeartbeat {
+  let inFlight: Promise<void> = Promise.resolve();
+  let stopped = false;
+
+  const timer: ClockTimer = clock().setInterval(() => {
+    inFlight = inFlight.then(async () => {
+      if (stopped) return;
+      const next = clock().now();
+      try {
+        const updated = await prisma.job.updateMany({ where: { id: lock.id, lockedAt: lock.lockedAt }, data: { lockedAt: next } });
+        if (updated.count === 1) {
+          lock.lockedAt = next;
+        } else {
+          logger.warn("lock heartbeat lost the job lock", { job_id: lock.id });
+          stopped = true;
+          timer.clear();
+        }
+      } catch (err) {
+        logger.warn("lock heartbeat failed", { job_id: lock.id, error: err });
       }
+    });
+  }, lockHeartbeatMs());
+
+  return {
+    stop: async () => {
+      stopped = true;
+      timer.clear();
+      await inFlight;
+    },
+  };
+}
+
+// Runs started under these locks can no longer finish (their final update needs the lock). A run without output
+// is deleted, so its slot stays due and the next claim creates it again; a run-now request goes back to pending.
+// A run that already has its output stays running and is delivered by recoverInterruptedRun on the next claim.
+export async function releaseActiveLocks() {
+  const locks = Array.from(activeLocks);
+  await Promise.allSettled(
+    locks.map(async (lock) => {
+      const released = await prisma.job.updateMany({ where: { id: lock.id, lockedAt: lock.lockedAt }, data: { lockedAt: null } });
+      if (!released.count) {
+        return;
+      }
+      const reset = lock.runId
+        ? (await prisma.runHistory.deleteMany({ where: { id: lock.runId, status: "running", outputText: null } })).count > 0
+        : true;
+      if (reset && lock.runRequestId) {
+        await prisma.runRequest.updateMany({ where: { id: lock.runRequestId, status: "running" }, data: { status: "pending", startedAt: null } });
+      }
+    }),
+  );
+  if (locks.length) {
+    logger.warn("released in-flight job locks on shutdown", { count: locks.length });
+  }
+}
+
+export function setDraining(value: boolean) {
+  draining = value;
+}
+
+export function drainStatus() {
+  return { draining, activeTicks, inFlightJobs: activeLocks.size };
+}
+
+// Resolves once no worker tick is running (true) or the timeout passed with work still in flight (false).
+export async function waitForDrain(timeoutMs: number) {
+  const deadline = nowMs() + timeoutMs;
+  while (activeTicks > 0 && nowMs() < deadline) {
+    await clock().sleep(250);
+  }
+  return activeTicks === 0;
+}
 
+// SIGTERM: stops claiming, waits up to timeoutMs for the runs in flight, then releases the locks of runs that are
+// still going (releaseActiveLocks) so another worker can take their slots once this process has exited. The hook
+// then sends the spans of the drained runs before exiting.
+export async function shutdownWorker(timeoutMs: number) {
+  shuttingDown = true;
+  setDraining(true);
+  const drained = await waitForDrain(timeoutMs);
+  if (!drained) {
+    logger.warn("worker shutdown timed out", { timeout_ms: timeoutMs, ...drainStatus() });
+  }
+  await releaseActiveLocks();
+  await stopWorkerHeartbeat().catch(() => undefined);
+  return drained;
+}
+
+function installShutdownHook() {
+  if (shutdownHookInstalled) return;
+  shutdownHookInstalled = true;
+  installErrorReportingHook();
+  process.once("SIGTERM", () => {
+    void shutdownWorker(workerConfig().shutdownTimeoutMs)
+      .catch((err) => logger.error("worker shutdown failed", { error: err }))
+      .then(() => flushTraces())
+      .finally(() => process.exit(0));
+  });
+}
+
+// Housekeeping steps never fail the tick; their errors are logged and sent to error reporting.
+function stepFailed<T>(message: string, fallback: T) {
+  return (err: unknown) => {
+    logger.warn(message, { error: err });
+    void reportError(err, { stage: "maintenance", step: message, worker_env: workerEnvironment() });
+    return fallback;
+  };
+}
+
+function failureBackoffPolicy(): FailureBackoffPolicy {
+  return workerConfig().failureBackoff;
+}
+
+function catchupGraceMs() {
+  return workerConfig().catchupGraceMinutes * 60 * 1000;
+}
+
+// Next run after the one scheduled for scheduledFor. run_all walks forward from the missed slot so each missed
+// occurrence runs in turn; other policies continue from now. One-shot jobs are disabled after their run, so
+// their nextRunAt only matters if a quota block keeps them enabled.
+function nextRunAfter(
+  job: Pick<Job, "scheduleType" | "scheduleTime" | "scheduleDayOfWeek" | "scheduleCron" | "timezone" | "weekdaysOnly" | "catchupPolicy">,
+  scheduledFor: Date,
+) {
+  if (job.scheduleType === "once") {
+    return new Date(nowMs() + 10 * 60 * 1000);
+  }
+  return computeNextRunAt(
+    {
+      scheduleType: job.scheduleType,
+      scheduleTime: job.scheduleTime,
+      scheduleDayOfWeek: job.scheduleDayOfWeek,
+      scheduleCron: job.scheduleCron,
+      timezone: job.timezone,
+      weekdaysOnly: job.weekdaysOnly,
+    },
+    normalizeCatchupPolicy(job.catchupPolicy) === "run_all" ? scheduledFor : clock().now(),
+  );
+}
+
+async function processLockedJob(
+  lock: JobLock,
+  opts: { runnerId?: string; runRequest?: RunRequestClaim },
+  span: Span,
+  heartbeat: LockHeartbeat,
+): Promise<JobOutcome> {
+  const job = await prisma.job.findUnique({
+    where: { id: lock.id },
+    include: { publishedPromptVersion: true, user: { select: { plan: true, qaSharing: true } } },
+  });
+  if (!job) {
+    logger.warn("locked job not found", { job_id: lock.id });
+    return { status: "fail" };
+  }
+  const jobStartedAt = nowMs();
+  let log = logger.with({ job_id: job.id, job_name: job.name, channel_type: job.channelType });
+  span.setAttributes({
+    "promptloop.job.name": job.name,
+    "promptloop.channel.type": job.channelType,
+    "promptloop.llm.model": normalizeLlmModel(job.llmModel),
+    "promptloop.job.tags": job.tags,
+  });
+
+  const manual = opts.runRequest ?? null;
+  const scheduledFor = manual ? manual.requestedAt : job.nextRunAt;
+  const oneShot = job.scheduleType === "once";
+
+  const configError = await jobConfigError(job, log);
+  if (configError) {
+    return disableMisconfigured(job, lock, heartbeat, configError, log, manual);
+  }
+
+  // First claim after a snooze: slots that fell inside it are skipped whatever the catch-up policy, and the job
+  // resumes at its next regular slot. A one-time job missed during the snooze runs now instead.
+  if (!manual && job.pausedUntil) {
+    if (!oneShot && scheduledFor < job.pausedUntil) {
       let nextRunAt: Date;
       try {
-        nextRunAt = computeNextRunAt(
-          {
-            scheduleType: job.scheduleType,
-            scheduleTime: job.scheduleTime,
-            scheduleDayOfWeek: job.scheduleDayOfWeek,
-            scheduleCron: job.scheduleCron,
-          },
-          new Date(),
-        );
+        nextRunAt = nextRunAfter({ ...job, catchupPolicy: "skip" }, scheduledFor);
       } catch {
-        nextRunAt = new Date(Date.now() + 10 * 60 * 1000);
+        nextRunAt = new Date(nowMs() + 10 * 60 * 1000);
       }
-
+      await heartbeat.stop();
+      // The snooze is cleared only if the owner has not changed it since the claim.
+      await prisma.job.updateMany({ where: { id: job.id, lockedAt: lock.lockedAt, pausedUntil: job.pausedUntil }, data: { pausedUntil: null } });
       await prisma.job.updateMany({ where: { id: job.id, lockedAt: lock.lockedAt }, data: { lockedAt: null, nextRunAt } });
-      result.processed++;
-      result.duplicates++;
-      continue;
+      log.info("job run skipped: snoozed", { scheduled_for: scheduledFor, paused_until: job.pausedUntil, next_run_at: nextRunAt });
+      return { status: "skipped" };
     }
+    await prisma.job.updateMany({ where: { id: job.id, lockedAt: lock.lockedAt, pausedUntil: job.pausedUntil }, data: { pausedUntil: null } });
+  }
 
-    let output = "";
-    let error: unknown;
+  if (!manual && normalizeCatchupPolicy(job.catchupPolicy) === "skip" && nowMs() - scheduledFor.getTime() > catchupGraceMs()) {
+    let nextRunAt: Date;
     try {
-      await enforceDailyRunLimit(job.userId);
-      const llm = await runPromptWithRetry(prompt, {
-        model: normalizeLlmModel(job.llmModel),
-        useWebSearch: job.allowWebSearch,
-        webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+      nextRunAt = nextRunAfter(job, scheduledFor);
+    } catch {
+      nextRunAt = new Date(nowMs() + 10 * 60 * 1000);
+    }
+    await heartbeat.stop();
+    await prisma.job.updateMany({
+      where: { id: job.id, lockedAt: lock.lockedAt },
+      data: { lockedAt: null, nextRunAt, ...(oneShot ? { enabled: false } : {}) },
+    });
+    log.info("job run skipped: missed scheduled time", { scheduled_for: scheduledFor, next_run_at: nextRunAt });
+    return { status: "skipped" };
+  }
+
+  // Quiet hours hold back scheduled slots only; a run the owner asked for runs now.
+  const quietUntil = manual
+    ? null
+    : quietWindowEnd(clock().now(), {
+        start: job.quietHoursStart,
+        end: job.quietHoursEnd,
+        dates: job.blackoutDates,
+        timezone: job.timezone,
       });
-      output = llm.output;
+  if (quietUntil) {
+    await heartbeat.stop();
+    await prisma.job.updateMany({ where: { id: job.id, lockedAt: lock.lockedAt }, data: { lockedAt: null, nextRunAt: quietUntil } });
+    log.info("job run deferred: quiet hours", { scheduled_for: scheduledFor, next_run_at: quietUntil });
+    return { status: "quiet" };
+  }
+
+  const budget = await checkMonthlyBudget(job.userId);
+  if (budget.exceeded) {
+    return skipOverBudget(job, lock, heartbeat, budget, log, manual);
+  }
+  const pv = job.publishedPromptVersion ?? (await getOrCreatePublishedPromptVersion(job.id));
+  const vars = coerceStringVars(pv.variables);
+  const timezone = job.timezone ?? "UTC";
+  // Secrets are resolved only into the prompt sent to the model and redacted from anything stored or delivered.
+  const pipelineSteps = normalizePipelineSteps(job.pipelineSteps);
+  const secrets = usesSecrets(pv.template, pv.postPrompt ?? job.postPrompt, ...pipelineSteps.map((step) => step.template))
+    ? await loadUserSecrets(job.userId)
+    : {};
+  const secretFunctions = secretTemplateFunctions(secrets);
+  const lastRun = await prisma.runHistory.findFirst({
+    where: { jobId: job.id, isPreview: false, status: { in: GENERATED_RUN_STATUSES } },
+    orderBy: { runAt: "desc" },
+    select: { runAt: true },
+  });
+  // Run-now requests do not wait for the upstream job; they use its latest output, if any.
+  const upstreamRun = job.dependsOnJobId
+    ? await findUpstreamRun(job.dependsOnJobId, manual ? null : dependencyWindowStart(lastRun?.runAt ?? null, scheduledFor))
+    : null;
+  if (job.dependsOnJobId && !upstreamRun && !manual) {
+    let regularNextRunAt: Date;
+    try {
+      regularNextRunAt = nextRunAfter(job, scheduledFor);
+    } catch {
+      regularNextRunAt = new Date(nowMs() + 10 * 60 * 1000);
+    }
+    const recheckAt = dependencyRecheckAt(clock().now(), regularNextRunAt);
+    await heartbeat.stop();
+    await prisma.job.updateMany({ where: { id: job.id, lockedAt: lock.lockedAt }, data: { lockedAt: null, nextRunAt: recheckAt } });
+    log.info("job run deferred: waiting for upstream job", { upstream_job_id: job.dependsOnJobId, scheduled_for: scheduledFor, next_run_at: recheckAt });
+    return { status: "waiting" };
+  }
+  const compileContext = {
+    nowIso: scheduledFor.toISOString(),
+    timezone,
+    jobName: job.name,
+    lastRunAt: lastRun?.runAt ?? null,
+    functions: secretFunctions,
+  };
+  const compiledPrompt = compilePromptTemplate(pv.template, vars, compileContext);
+  const pendingReplies = job.acceptReplies
+    ? await prisma.jobReply.findMany({
+        where: { jobId: job.id, consumedAt: null },
+        orderBy: { createdAt: "asc" },
+        take: REPLIES_PER_RUN_MAX,
+      })
+    : [];
+  const repliesBlock = formatRepliesForPrompt(pendingReplies);
+  const previousBlock = job.includePreviousOutput
+    ? formatPreviousOutputsForPrompt(await loadPreviousOutputs(job.id, job.previousOutputCount))
+    : "";
+  const upstreamBlock = job.includeUpstreamOutput && upstreamRun ? formatUpstreamOutputForPrompt(upstreamRun) : "";
+  const inputResults = await fetchPromptInputs(normalizePromptInputs(job.inputs), { plan: job.user.plan, userAgent: job.userAgent });
+  for (const input of inputResults.filter((result) => result.error)) {
+    log.warn("job input not fetched", { url: input.url, error: input.error });
+  }
+  const inputsBlock = formatInputsForPrompt(inputResults);
+  const prompt = [compiledPrompt, inputsBlock, upstreamBlock, previousBlock, repliesBlock].filter(Boolean).join("\n\n");
+
+  const title = renderRunHeader(job, clock().now(), timezone);
+
+  let runHistoryId: string | null = null;
+  let runStartedAt = clock().now();
+  try {
+    const created = await prisma.runHistory.create({
+      data: {
+        jobId: job.id,
+        promptVersionId: pv.id,
+        scheduledFor,
+        status: "running",
+        outputText: null,
+        outputPreview: null,
+        errorMessage: null,
+        isPreview: false,
+        runnerId: opts.runnerId ?? null,
+        tags: job.tags,
+        deliveredAt: null,
+        deliveryAttempts: 0,
+        deliveryLastError: null,
+      },
+      select: { id: true, runAt: true },
+    });
+    runHistoryId = created.id;
+    runStartedAt = created.runAt;
+    lock.runId = created.id;
+  } catch (err) {
+    const isUnique =
+      typeof err === "object" &&
+      err !== null &&
+      "code" in err &&
+      (err as { code?: unknown }).code === "P2002";
+    if (!isUnique) {
+      throw err;
+    }
+    if (manual) {
+      await heartbeat.stop();
+      await prisma.job.updateMany({ where: { id: job.id, lockedAt: lock.lockedAt }, data: { lockedAt: null } });
+      log.info("run now skipped: duplicate run", { requested_at: scheduledFor });
+      return { status: "duplicate" };
+    }
+
+    let nextRunAt: Date;
+    try {
+      nextRunAt = nextRunAfter(job, scheduledFor);
+    } catch {
+      nextRunAt = new Date(nowMs() + 10 * 60 * 1000);
+    }
+
+    const recovered = await recoverInterruptedRun(job, scheduledFor, log);
+    await heartbeat.stop();
+    await prisma.job.updateMany({
+      where: { id: job.id, lockedAt: lock.lockedAt },
+      data: { lockedAt: null, nextRunAt, ...(oneShot ? { enabled: false } : {}) },
+    });
+    log.info("job run skipped: duplicate scheduled run", { scheduled_for: scheduledFor, recovered });
+    return { status: "duplicate" };
+  }
+  span.setAttribute("promptloop.run.id", runHistoryId);
+  log = log.with({ run_id: runHistoryId });
+  log.info("job run started", { scheduled_for: scheduledFor, run_request_id: manual?.id });
+  const hookContext: RunContext = { job, runHistoryId, scheduledFor, manual: !!manual, log };
 
-      const postPromptConfig = normalizePostPromptConfig({
-        enabled: pv.postPromptEnabled ?? job.postPromptEnabled,
-        template: pv.postPrompt ?? job.postPrompt,
+  // Debug mode: the next N runs keep their scrubbed provider request/response payloads.
+  let debugEntries: DebugCaptureEntry[] | null = null;
+  if (job.debugRunsRemaining > 0) {
+    const claimed = await prisma.job.updateMany({
+      where: { id: job.id, debugRunsRemaining: { gt: 0 } },
+      data: { debugRunsRemaining: { decrement: 1 } },
+    });
+    debugEntries = claimed.count ? [] : null;
+  }
+  // Model call steps answered from the job's response cache.
+  const cachedSteps = new Set<DebugCaptureEntry["step"]>();
+  // Provider calls made by this run (retries, upgrades, fallbacks, post prompt and pipeline steps included).
+  let llmAttempts = 0;
+  const callModel = async (
+    step: DebugCaptureEntry["step"],
+    promptText: string,
+    callOpts: { model: string; useWebSearch: boolean; webSearchMode: WebSearchMode; tools?: LlmTool[] },
+  ) => {
+    try {
+      // The change summary is a service prompt, not part of the job's output, so it keeps the default rules and
+      // generation settings. The length limit only applies to calls whose output is delivered.
+      const jobCall = step !== "diff";
+      const systemPrompt = !jobCall
+        ? null
+        : step === "pipeline"
+          ? job.systemPrompt
+          : withOutputLengthInstruction(job.systemPrompt, job.maxOutputChars);
+      const generation = jobCall ? generationParamsFromJob(job) : undefined;
+      // Debug runs always call the model so the capture has real provider payloads.
+      const cacheKey =
+        jobCall && job.responseCacheTtlMinutes && !debugEntries
+          ? responseCacheKey({ userId: job.userId, prompt: promptText, systemPrompt, generation, ...callOpts })
+          : null;
+      const cached = cacheKey ? await readCachedResponse(cacheKey).catch(() => null) : null;
+      if (cached) {
+        cachedSteps.add(step);
+        log.info("llm response cache hit", { step, model: callOpts.model });
+        return cached;
+      }
+      const result = await runPromptWithRetry(promptText, { ...callOpts, systemPrompt, generation, captureDebug: !!debugEntries }, () => {
+        llmAttempts++;
       });
-      let postPromptApplied = false;
-      let usageToStore: unknown = llm.llmUsage ?? null;
-      let toolCallsToStore: unknown = llm.llmToolCalls ?? null;
-
-      if (postPromptConfig.enabled) {
-        const postPrompt = compilePromptTemplate(
-          postPromptConfig.template,
-          buildPostPromptVariables({
-            baseVariables: vars,
-            output: llm.output,
-            citations: llm.citations,
-            usedWebSearch: llm.usedWebSearch,
-            llmModel: llm.llmModel ?? normalizeLlmModel(job.llmModel),
-          }),
-          { nowIso: scheduledFor.toISOString(), timezone: "UTC" },
+      if (debugEntries && result.debug) {
+        debugEntries.push(debugCaptureEntry({ step, model: callOpts.model, payload: result.debug }, secrets));
+      }
+      if (cacheKey && job.responseCacheTtlMinutes) {
+        await writeCachedResponse(cacheKey, result, job.responseCacheTtlMinutes).catch((err) =>
+          log.warn("llm response cache write failed", { error: err }),
         );
+      }
+      return result;
+    } catch (err) {
+      debugEntries?.push(
+        debugCaptureEntry(
+          { step, model: callOpts.model, payload: debugPayloadFromError(err), error: err instanceof Error ? err.message : String(err) },
+          secrets,
+        ),
+      );
+      throw err;
+    }
+  };
 
-        const post = await runPromptWithRetry(postPrompt, {
-          model: normalizeLlmModel(job.llmModel),
-          useWebSearch: false,
+  const deliverAt = job.deliveryDelayMinutes ? new Date(scheduledFor.getTime() + job.deliveryDelayMinutes * 60_000) : null;
+  let deferred = false;
+  // The message as delivered, for the operator QA sample.
+  let qaSample: { output: string; llmModel: string | null } | null = null;
+  let deliveryFailed = false;
+  let deliveryPartial = false;
+  // True while the run is waiting on the model, so a failure can be attributed to the model or the channel.
+  let inModelCalls = false;
+  let deliveryDurationMs: number | null = null;
+  let deliverySkip: string | null = null;
+  let output = "";
+  let error: unknown;
+  try {
+    await enforceDailyRunLimit(job.userId);
+    const { prompt: llmPrompt } = await applyPreLlm(hookContext, { prompt });
+    // "auto" jobs start on the cheapest suitable model and move up the ladder when a model cannot produce a
+    // usable result (empty output, missing search results, rejected request). Provider outages do not upgrade.
+    // Runs in a model canary's cohort try the canary model first and fall back to the routed models.
+    const routed = isAutoModel(job.llmModel) ? await routeJobModels(job, llmPrompt) : [normalizeLlmModel(job.llmModel)];
+    const canary = assignCanary(runHistoryId, routed);
+    const { models: candidates, fallbackStart } = withFallbackModels(canary?.candidates ?? routed, job.fallbackModels);
+    if (canary) {
+      await prisma.runHistory.update({ where: { id: runHistoryId }, data: { canaryCohort: canary.cohort, cohortModel: canary.model } });
+    }
+    const llmStartedAt = nowMs();
+    inModelCalls = true;
+    let model = candidates[0];
+    let llm: Awaited<ReturnType<typeof runPromptWithRetry>> | null = null;
+    for (let index = 0; !llm; ) {
+      model = candidates[index];
+      try {
+        llm = await callModel("primary", llmPrompt, {
+          model,
+          useWebSearch: job.allowWebSearch,
           webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+          tools: normalizeLlmTools(job.llmTools),
         });
+      } catch (llmErr) {
+        const next = nextModelIndex(index, fallbackStart, candidates.length, isProviderFailure(llmErr));
+        if (next == null) {
+          throw llmErr;
+        }
+        const event = next >= fallbackStart ? "model fallback after failed attempt" : "model upgraded after failed attempt";
+        log.warn(event, { from_model: model, to_model: candidates[next], error: llmErr });
+        index = next;
+      }
+    }
+    if (!llm) {
+      throw new Error("LLM execution failed");
+    }
+    const llmDurationMs = nowMs() - llmStartedAt;
+    if (candidates.indexOf(model) >= fallbackStart || cachedSteps.has("primary")) {
+      await prisma.runHistory.update({
+        where: { id: runHistoryId },
+        data: { modelFallback: candidates.indexOf(model) >= fallbackStart, cacheHit: cachedSteps.has("primary") },
+      });
+    }
+    if (isAutoModel(job.llmModel)) {
+      await prisma.runHistory.update({
+        where: { id: runHistoryId },
+        // A canary model is outside the ladder; falling back from it to the routed model is not an upgrade.
+        data: { routedModel: routed[0], modelUpgraded: routed.indexOf(model) > 0 },
+      });
+    }
+    output = redactSecrets(llm.output, secrets);
 
-        output = post.output;
-        usageToStore = { primary: llm.llmUsage ?? null, post: post.llmUsage ?? null };
-        toolCallsToStore = { primary: llm.llmToolCalls ?? null, post: post.llmToolCalls ?? null };
-        postPromptApplied = true;
+    const postPromptConfig = normalizePostPromptConfig({
+      enabled: pv.postPromptEnabled ?? job.postPromptEnabled,
+      template: pv.postPrompt ?? job.postPrompt,
+    });
+    let postPromptApplied = false;
+    let usageToStore: unknown = llm.llmUsage ?? null;
+    let toolCallsToStore: unknown = llm.llmToolCalls ?? null;
+    const files = [...(llm.files ?? [])];
+
+    let stepUsages: unknown[] = [];
+    let stepToolCalls: unknown[] = [];
+    if (pipelineSteps.length) {
+      const pipeline = await runPipelineSteps(pipelineSteps, {
+        output,
+        variables: vars,
+        citations: llm.citations,
+        usedWebSearch: llm.usedWebSearch,
+        llmModel: llm.llmModel ?? model,
+        compileContext,
+        run: (stepPrompt) => callModel("pipeline", stepPrompt, { model, useWebSearch: false, webSearchMode: normalizeWebSearchMode(job.webSearchMode) }),
+        clean: (stepOutput) => redactSecrets(stepOutput, secrets),
+      });
+      output = pipeline.output;
+      files.push(...pipeline.files);
+      stepUsages = pipeline.usages;
+      stepToolCalls = pipeline.toolCalls;
+      usageToStore = { primary: llm.llmUsage ?? null, steps: stepUsages };
+      toolCallsToStore = { primary: llm.llmToolCalls ?? null, steps: stepToolCalls };
+      log.info("pipeline steps applied", { steps: pipelineSteps.length });
+    }
+
+    if (postPromptConfig.enabled) {
+      const postPrompt = compilePromptTemplate(
+        postPromptConfig.template,
+        buildPostPromptVariables({
+          baseVariables: vars,
+          output,
+          citations: llm.citations,
+          usedWebSearch: llm.usedWebSearch,
+          llmModel: llm.llmModel ?? model,
+        }),
+        compileContext,
+      );
+
+      const post = await callModel("post", postPrompt, {
+        model,
+        useWebSearch: false,
+        webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+      });
+
+      output = redactSecrets(post.output, secrets);
+      files.push(...(post.files ?? []));
+      const steps = stepUsages.length ? { steps: stepUsages } : {};
+      usageToStore = { primary: llm.llmUsage ?? null, ...steps, post: post.llmUsage ?? null };
+      toolCallsToStore = { primary: llm.llmToolCalls ?? null, ...(stepToolCalls.length ? { steps: stepToolCalls } : {}), post: post.llmToolCalls ?? null };
+      postPromptApplied = true;
+    }
+    ({ output } = await applyPostLlm(hookContext, { output, llmModel: llm.llmModel ?? model }));
+
+    const deliverIf = normalizeDeliverIf(job.deliverIf);
+    const deliveryDiff = normalizeDeliveryDiff(job.deliveryDiff);
+    const previousRun =
+      deliverIf === "changed" || deliveryDiff !== "off"
+        ? await prisma.runHistory.findFirst({
+            where: { jobId: job.id, isPreview: false, status: { in: GENERATED_RUN_STATUSES }, outputText: { not: null }, id: { not: runHistoryId } },
+            orderBy: { runAt: "desc" },
+            select: { outputHash: true, outputText: true },
+          })
+        : null;
+    let skipReason =
+      job.channelType === ChannelType.in_app
+        ? null
+        : deliverySkipReason({ mode: deliverIf, pattern: job.deliverIfPattern, output, previousHash: previousRun?.outputHash });
+    deliverySkip = skipReason;
+
+    // The change block is best effort: without a previous output there is nothing to compare, and a failed
+    // summary call delivers the output alone.
+    let diffBlock: string | null = null;
+    if (deliveryDiff !== "off" && job.channelType !== ChannelType.in_app && !skipReason && previousRun?.outputText != null) {
+      try {
+        if (deliveryDiff === "unified") {
+          diffBlock = formatDiffBlock("unified", unifiedDiff(previousRun.outputText, output));
+        } else {
+          const summary = await callModel("diff", changeSummaryPrompt(previousRun.outputText, output), {
+            model,
+            useWebSearch: false,
+            webSearchMode: normalizeWebSearchMode(job.webSearchMode),
+          });
+          diffBlock = formatDiffBlock("summary", redactSecrets(summary.output, secrets));
+          const base: Record<string, unknown> =
+            isRecord(usageToStore) && ("primary" in usageToStore || "post" in usageToStore)
+              ? usageToStore
+              : { primary: usageToStore ?? null, post: null };
+          usageToStore = { ...base, diff: summary.llmUsage ?? null };
+        }
+      } catch (diffErr) {
+        log.warn("delivery diff not computed", { error: diffErr });
+      }
+    }
+    let deliveredOutput = truncateOutput(diffDeliveryBody(output, diffBlock, job.deliveryDiffOnly), job.maxOutputChars);
+    let deliveryTitle = title;
+    // Deferred deliveries run the pre-delivery hooks when they are sent (deliverDueRun).
+    if (job.channelType !== ChannelType.in_app && !skipReason && !(deliverAt && deliverAt.getTime() > nowMs())) {
+      const hooked = await applyPreDelivery(hookContext, { title, body: deliveredOutput });
+      deliveryTitle = hooked.title;
+      deliveredOutput = hooked.body;
+      skipReason = hooked.skip?.trim() ? truncate(hooked.skip.trim(), 64) : null;
+      deliverySkip = skipReason;
+    }
+
+    await prisma.runHistory.update({
+      where: { id: runHistoryId },
+      data: {
+        outputText: output,
+        outputPreview: truncate(output, OUTPUT_PREVIEW_MAX),
+        outputHash: outputHash(output),
+        deliverySkipReason: skipReason,
+        outputDiff: diffBlock,
+      },
+    });
+    // The compressed copy is for reading the run later; delivery keeps working from output_text if it fails.
+    try {
+      const stored = await saveRunOutput(runHistoryId, output);
+      if (stored.truncated) {
+        log.warn("full output truncated", { size_bytes: stored.sizeBytes });
+      }
+    } catch (outputErr) {
+      log.warn("full output not stored", { error: outputErr });
+    }
+    try {
+      const archived = await archiveRunOutput({ id: runHistoryId, jobId: job.id, runAt: runStartedAt }, output);
+      if (archived) {
+        log.info("output archived", { archive_key: archived });
+      }
+    } catch (archiveErr) {
+      log.warn("output not archived", { error: archiveErr });
+    }
+
+    // The spoken version rides along as an audio artifact; without it the text is still delivered.
+    const ttsVoice = normalizeTtsVoice(job.ttsVoice);
+    const spoken = ttsVoice && !skipReason ? speechText(output) : "";
+    if (ttsVoice && spoken) {
+      try {
+        files.push(await synthesizeSpeech(spoken, ttsVoice));
+      } catch (ttsErr) {
+        log.warn("speech not generated", { voice: ttsVoice, error: ttsErr });
       }
+    }
 
+    // Artifacts are best effort: a storage problem drops the links, not the run.
+    let attachments: ChannelAttachment[] = [];
+    if (files.length) {
+      try {
+        await saveRunArtifacts(runHistoryId, files);
+        attachments = await loadRunAttachments(runHistoryId);
+      } catch (artifactErr) {
+        log.warn("run artifacts not stored", { error: artifactErr });
+      }
+    }
+
+    const llmUsageJson = usageToStore == null ? null : JSON.stringify(usageToStore);
+    const llmToolCallsJson = toolCallsToStore == null ? null : redactSecrets(JSON.stringify(toolCallsToStore), secrets);
+    const citationsJson = JSON.stringify(llm.citations);
+    const usage = runUsageColumns(llm.llmModel ?? model, usageToStore);
+
+    inModelCalls = false;
+    await prisma.$executeRaw`
+      UPDATE "public"."run_histories"
+      SET
+        "llm_model" = ${llm.llmModel ?? null},
+        "served_model" = ${llm.servedModel ?? null},
+        "llm_duration_ms" = ${llmDurationMs},
+        "llm_attempts" = ${llmAttempts},
+        "llm_usage" = ${llmUsageJson}::jsonb,
+        "prompt_tokens" = ${usage.promptTokens},
+        "completion_tokens" = ${usage.completionTokens},
+        "cost_usd" = ${usage.costUsd},
+        "llm_tool_calls" = ${llmToolCallsJson}::jsonb,
+        "used_web_search" = ${llm.usedWebSearch},
+        "citations" = ${citationsJson}::jsonb
+      WHERE "id" = ${runHistoryId}::uuid
+    `;
+
+    if (job.channelType === ChannelType.in_app) {
       await prisma.runHistory.update({
         where: { id: runHistoryId },
         data: {
-          outputText: output,
-          outputPreview: truncate(output, OUTPUT_PREVIEW_MAX),
+          deliveredAt: clock().now(),
+          deliveryAttempts: 0,
+          deliveryLastError: null,
         },
       });
-
-      const llmUsageJson = usageToStore == null ? null : JSON.stringify(usageToStore);
-      const llmToolCallsJson = toolCallsToStore == null ? null : JSON.stringify(toolCallsToStore);
-      const citationsJson = JSON.stringify(llm.citations);
-
-      await prisma.$executeRaw`
-        UPDATE "public"."run_histories"
-        SET
-          "llm_model" = ${llm.llmModel ?? null},
-          "llm_usage" = ${llmUsageJson}::jsonb,
-          "llm_tool_calls" = ${llmToolCallsJson}::jsonb,
-          "used_web_search" = ${llm.usedWebSearch},
-          "citations" = ${citationsJson}::jsonb
-        WHERE "id" = ${runHistoryId}::uuid
-      `;
-
-      if (job.channelType === ChannelType.in_app) {
+    } else if (skipReason) {
+      // deliver_if held the output back; the run still succeeds and keeps its output in history.
+      log.info("delivery skipped", { deliver_if: deliverIf, reason: skipReason });
+    } else if (deliverAt && deliverAt.getTime() > nowMs()) {
+      // Output stays in the outbox; deliverDueRuns picks it up once deliver_at passes.
+      await prisma.runHistory.update({ where: { id: runHistoryId }, data: { deliverAt } });
+      deferred = true;
+      log.info("delivery deferred", { deliver_at: deliverAt });
+    } else if (!(await hasThrottleRoom(job))) {
+      // Over the job's delivery limit: the output waits for the next throttle digest.
+      await prisma.runHistory.update({ where: { id: runHistoryId }, data: { throttledAt: clock().now() } });
+      log.info("delivery throttled", { throttle_limit: job.throttleLimit, throttle_window: job.throttleWindow });
+    } else {
+      const deliveryStartedAt = nowMs();
+      const delivery = await deliverWithRetryAndReceipts(runHistoryId, await runnableJobChannel(job), deliveryTitle, deliveredOutput, {
+        citations: llm.citations,
+        attachments,
+        usedWebSearch: llm.usedWebSearch,
+        meta: {
+          jobId: job.id,
+          jobName: job.name,
+          promptVersionId: pv.id,
+          scheduledFor: scheduledFor.toISOString(),
+          llmModel: llm.llmModel ?? null,
+          llmUsage: llm.llmUsage ?? null,
+          postPromptApplied,
+          postPromptWarning: postPromptConfig.warning,
+          tags: job.tags,
+        },
+        userAgent: job.userAgent,
+        plan: job.user.plan,
+        log,
+        fullOutputLink: job.fullOutputLink,
+      });
+      deliveryDurationMs = nowMs() - deliveryStartedAt;
+      const retryDeliveryAt = delivery.lastError && delivery.retryable ? durableRetryAt(job, 0, delivery.partial) : null;
+      await notifyPostDelivery(hookContext, {
+        delivered: !delivery.lastError,
+        error: delivery.lastError,
+        attempts: delivery.attempts,
+        deferred: !!retryDeliveryAt,
+      });
+      if (retryDeliveryAt) {
+        // The output is kept in the outbox and retried on the job's schedule instead of regenerating it.
         await prisma.runHistory.update({
           where: { id: runHistoryId },
           data: {
-            deliveredAt: new Date(),
-            deliveryAttempts: 0,
-            deliveryLastError: null,
+            deliverAt: retryDeliveryAt,
+            deliveryRetries: 1,
+            deliveryAttempts: delivery.attempts,
+            deliveryLastError: delivery.lastError,
+            deliveryDurationMs,
           },
         });
+        deferred = true;
+        log.warn("delivery retry scheduled", { retry: 1, retry_at: retryDeliveryAt, error: delivery.lastError });
+      } else if (delivery.lastError) {
+        deliveryFailed = true;
+        deliveryPartial = delivery.partial;
+        throw new Error(delivery.lastError);
       } else {
-        const delivery = await deliverWithRetryAndReceipts(runHistoryId, toRunnableChannel(job), title, output, {
-          citations: llm.citations,
-          usedWebSearch: llm.usedWebSearch,
-          meta: {
-            jobId: job.id,
-            promptVersionId: pv.id,
-            scheduledFor: scheduledFor.toISOString(),
-            llmModel: llm.llmModel ?? null,
-            llmUsage: llm.llmUsage ?? null,
-            postPromptApplied,
-            postPromptWarning: postPromptConfig.warning,
-          },
-        });
-        if (delivery.lastError) {
-          throw new Error(delivery.lastError);
-        }
-
         await prisma.runHistory.update({
           where: { id: runHistoryId },
           data: {
-            deliveredAt: new Date(),
+            deliveredAt: clock().now(),
             deliveryAttempts: delivery.attempts,
             deliveryLastError: null,
+            deliveryDurationMs,
+            deliveryTitle: null,
+            deliveryBody: null,
           },
         });
+        qaSample = { output: deliveredOutput, llmModel: llm.llmModel ?? null };
       }
-    } catch (err) {
-      error = err;
     }
+  } catch (err) {
+    error = err;
+  }
 
-    let nextRunAt: Date;
+  if (debugEntries?.length) {
+    await prisma.runHistory
+      .update({ where: { id: runHistoryId }, data: { debugCapture: debugEntries as unknown as Prisma.InputJsonValue } })
+      .catch((debugErr) => log.warn("debug capture not stored", { error: debugErr }));
+  }
+
+  await heartbeat.stop();
+
+  // Run-now requests only release the lock: the schedule, retry and failure state belong to scheduled slots.
+  let nextRunAt = job.nextRunAt;
+  if (!manual) {
     try {
-      nextRunAt = computeNextRunAt(
-        {
-          scheduleType: job.scheduleType,
-          scheduleTime: job.scheduleTime,
-          scheduleDayOfWeek: job.scheduleDayOfWeek,
-          scheduleCron: job.scheduleCron,
-        },
-        new Date(),
-      );
+      nextRunAt = nextRunAfter(job, scheduledFor);
     } catch (scheduleErr) {
-      nextRunAt = new Date(Date.now() + 10 * 60 * 1000);
+      nextRunAt = new Date(nowMs() + 10 * 60 * 1000);
       error = new Error(`Schedule calculation error: ${scheduleErr instanceof Error ? scheduleErr.message : String(scheduleErr)}`);
     }
+  }
 
-    if (!error) {
-      const finished = await prisma.$transaction(async (tx) => {
-        const updated = await tx.job.updateMany({
-          where: { id: job.id, lockedAt: lock.lockedAt },
-          data: { lockedAt: null, failCount: 0, nextRunAt },
-        });
-        if (updated.count !== 1) {
-          return { updated: false as const };
-        }
-        await tx.runHistory.update({
-          where: { id: runHistoryId },
-          data: {
-            status: "success",
-            errorMessage: null,
-          },
-        });
-        return { updated: true as const };
+  if (!error) {
+    const finished = await prisma.$transaction(async (tx) => {
+      const updated = await tx.job.updateMany({
+        where: { id: job.id, lockedAt: lock.lockedAt },
+        data: manual
+          ? { lockedAt: null }
+          : {
+              lockedAt: null,
+              failCount: 0,
+              retryAttempt: 0,
+              autoPausedUntil: null,
+              nextRunAt,
+              ...(oneShot ? { enabled: false, completedAt: clock().now() } : {}),
+            },
       });
-      result.processed++;
-      if (finished.updated) {
-        result.success++;
-      } else {
-        result.fail++;
+      if (updated.count !== 1) {
+        return { updated: false as const };
       }
-      continue;
+      await tx.runHistory.update({
+        where: { id: runHistoryId },
+        data: {
+          status: deferred ? "running" : deliverySkip === "unchanged" ? "skipped_unchanged" : "success",
+          errorMessage: null,
+        },
+      });
+      if (pendingReplies.length) {
+        await tx.jobReply.updateMany({
+          where: { id: { in: pendingReplies.map((reply) => reply.id) } },
+          data: { consumedAt: clock().now(), runHistoryId },
+        });
+      }
+      return { updated: true as const };
+    });
+    if (!finished.updated) {
+      log.warn("job run finished but lock was lost", { duration_ms: nowMs() - jobStartedAt });
+      return { status: "fail", runHistoryId };
     }
+    const durationMs = nowMs() - jobStartedAt;
+    log.info("job run succeeded", { duration_ms: durationMs });
+    const slow = isSlowRun(durationMs, job.expectedRuntimeSeconds);
+    if (slow) {
+      await reportSlowRun(job, scheduledFor, durationMs, log);
+    }
+    // Quality sampling runs after the lock is released; the judge call is not part of the run's usage or budget.
+    if (isSampledRun(runHistoryId, job.qualitySampleRate)) {
+      await scoreRunQuality(
+        job,
+        runHistoryId,
+        output,
+        (judgePrompt, judgeModel) => runPromptWithRetry(judgePrompt, { model: judgeModel, useWebSearch: false, webSearchMode: DEFAULT_WEB_SEARCH_MODE }),
+        log,
+      ).catch((qualityErr) => log.warn("run quality not scored", { error: qualityErr }));
+    }
+    if (qaSample && job.user.qaSharing) {
+      await mirrorToQa({ runId: runHistoryId, channelType: job.channelType, ...qaSample }, log);
+    }
+    return { status: "success", runHistoryId, slow };
+  }
 
-    const errorMessage = truncate(error instanceof Error ? error.message : String(error), ERROR_MAX);
-    const quotaBlocked = errorMessage.startsWith("Daily run limit exceeded");
-
-    const finished = await prisma.$transaction(async (tx) => {
-      const base = { updated: false, disabled: false, quotaBlocked: false };
+  const errorMessage = truncate(redactSecrets(error instanceof Error ? error.message : String(error), secrets), ERROR_MAX);
+  const quotaBlocked = errorMessage.startsWith("Daily run limit exceeded");
+  // Timing and attribution columns, so run history shows whether the model or the channel failed.
+  const failureTiming = {
+    failureStage: deliveryFailed ? "channel" : inModelCalls ? "model" : "run",
+    llmAttempts,
+    deliveryDurationMs,
+  };
+  // Transient failures retry with backoff first; only a slot that exhausts its retries counts toward disabling.
+  const retryAt = quotaBlocked || manual ? null : computeFailureRetryAt(job.retryAttempt, failureBackoffPolicy(), oneShot ? null : nextRunAt);
 
-      if (quotaBlocked) {
-        const updated = await tx.job.updateMany({ where: { id: job.id, lockedAt: lock.lockedAt }, data: { lockedAt: null, nextRunAt } });
-        if (updated.count !== 1) {
-          return base;
-        }
-        await tx.runHistory.update({
-          where: { id: runHistoryId },
-          data: {
-            status: "fail",
-            errorMessage,
-          },
-        });
-        return { updated: true, disabled: false, quotaBlocked: true };
-      }
+  const finished = await prisma.$transaction(async (tx) => {
+    const base = { updated: false, disabled: false, pausedUntil: null, quotaBlocked: false };
 
-      const nextFailCount = job.failCount + 1;
-      const disable = nextFailCount >= MAX_FAILS_BEFORE_DISABLE;
+    if (quotaBlocked || manual) {
       const updated = await tx.job.updateMany({
         where: { id: job.id, lockedAt: lock.lockedAt },
-        data: {
-          lockedAt: null,
-          failCount: nextFailCount,
-          enabled: disable ? false : undefined,
-          nextRunAt,
-        },
+        data: manual ? { lockedAt: null } : { lockedAt: null, nextRunAt },
       });
       if (updated.count !== 1) {
         return base;
@@ -426,23 +1485,898 @@ export async function runDueJobs(opts: { timeBudgetMs: number; maxJobs: number;
       await tx.runHistory.update({
         where: { id: runHistoryId },
         data: {
-          status: "fail",
+          status: failureRunStatus(errorMessage, { quotaBlocked, partialDelivery: deliveryPartial }),
           errorMessage,
+          ...failureTiming,
+        },
+      });
+      return { updated: true, disabled: false, pausedUntil: null, quotaBlocked };
+    }
+
+    const nextFailCount = retryAt ? job.failCount : job.failCount + 1;
+    const action = retryAt ? null : oneShot ? "disable" : repeatedFailureAction(nextFailCount, autoDisableSettings(job));
+    const disable = action === "disable";
+    // Pausing keeps the job enabled and starts the failure count over; it next runs after the cooldown.
+    const pauseUntil = action === "pause" ? pausedUntil(job, autoDisableSettings(job), clock().now()) : null;
+    const updated = await tx.job.updateMany({
+      where: { id: job.id, lockedAt: lock.lockedAt },
+      data: {
+        lockedAt: null,
+        failCount: pauseUntil ? 0 : nextFailCount,
+        retryAttempt: retryAt ? job.retryAttempt + 1 : 0,
+        enabled: disable ? false : undefined,
+        autoPausedUntil: pauseUntil,
+        nextRunAt: retryAt ?? pauseUntil ?? nextRunAt,
+      },
+    });
+    if (updated.count !== 1) {
+      return base;
+    }
+    await tx.runHistory.update({
+      where: { id: runHistoryId },
+      data: {
+        status: failureRunStatus(errorMessage, { partialDelivery: deliveryPartial }),
+        errorMessage,
+        ...failureTiming,
+      },
+    });
+    return { updated: true, disabled: disable, pausedUntil: pauseUntil, quotaBlocked: false };
+  });
+  log.error("job run failed", {
+    duration_ms: nowMs() - jobStartedAt,
+    error: errorMessage,
+    quota_blocked: finished.quotaBlocked,
+    disabled: finished.disabled,
+    paused_until: finished.pausedUntil ?? undefined,
+    retry_at: retryAt ?? undefined,
+    lock_lost: !finished.updated,
+  });
+  // Keep the output and error chain when nothing will retry this slot: the job was disabled or paused, or a
+  // generated result could not be delivered.
+  if (finished.updated && (finished.disabled || finished.pausedUntil || (deliveryFailed && !retryAt))) {
+    await recordDeadLetter({
+      jobId: job.id,
+      userId: job.userId,
+      runHistoryId,
+      reason: finished.disabled ? "auto_disabled" : finished.pausedUntil ? "auto_paused" : "delivery_failed",
+      outputText: output,
+      errorMessage,
+    });
+  }
+  // A failed delivery means the channel itself is the problem, so only generation failures get a notice.
+  if (finished.updated && job.failureNotice && job.channelType !== ChannelType.in_app && !deliveryFailed && !manual) {
+    const notice = formatFailureNotice({
+      errorMessage,
+      retryAt,
+      nextRunAt: oneShot ? null : nextRunAt,
+      disabled: finished.disabled,
+      pausedUntil: finished.pausedUntil,
+      timeZone: job.timezone ?? "UTC",
+    });
+    try {
+      await sendChannelMessage(await runnableJobChannel(job), formatRunTitle(job.name, scheduledFor, job.timezone ?? "UTC"), notice, {
+        userAgent: job.userAgent,
+        plan: job.user.plan,
+        meta: { kind: "failure_notice", jobId: job.id, scheduledFor: scheduledFor.toISOString(), tags: job.tags },
+      });
+      log.info("failure notice sent", { retry_at: retryAt ?? undefined });
+    } catch (noticeErr) {
+      log.warn("failure notice failed", { error: noticeErr });
+    }
+  }
+  return { status: "fail", disabled: finished.disabled, quotaBlocked: finished.quotaBlocked, runHistoryId };
+}
+
+async function reportSlowRun(job: Job & { user: { plan: UserPlan } }, scheduledFor: Date, durationMs: number, log: Logger) {
+  const expected = job.expectedRuntimeSeconds ?? 0;
+  log.warn("slow run", { duration_ms: durationMs, expected_runtime_seconds: expected });
+  if (!job.slowRunNotice || job.channelType === ChannelType.in_app || !(await claimSlowRunNotice(job.id, clock().now()))) {
+    return;
+  }
+  try {
+    await sendChannelMessage(
+      await runnableJobChannel(job),
+      formatRunTitle(job.name, scheduledFor, job.timezone ?? "UTC"),
+      formatSlowRunNotice(durationMs, expected),
+      {
+        userAgent: job.userAgent,
+        plan: job.user.plan,
+        meta: { kind: "slow_run", jobId: job.id, scheduledFor: scheduledFor.toISOString(), durationMs, tags: job.tags },
+      },
+    );
+    log.info("slow run notice sent");
+  } catch (err) {
+    log.warn("slow run notice failed", { error: err });
+  }
+}
+
+// Problems that make every run fail whatever the model answers: a recurring schedule that no longer parses, or a
+// channel config that cannot be decrypted or fails its schema. Other lookup errors are left to the delivery step.
+async function jobConfigError(job: Job, log: Logger) {
+  const schedule = scheduleConfigError(job);
+  if (schedule) {
+    return `Invalid schedule: ${schedule}`;
+  }
+  if (job.channelType === ChannelType.in_app) {
+    return null;
+  }
+  try {
+    await runnableJobChannel(job);
+    return null;
+  } catch (err) {
+    if (err instanceof ChannelConfigError) {
+      if (err.kind === "decrypt") {
+        void reportError(err, { stage: "channel_config", job_id: job.id, job_name: job.name, channel_type: job.channelType, user_id: job.userId });
+      }
+      return err.message;
+    }
+    log.warn("channel check before run failed", { error: err });
+    return null;
+  }
+}
+
+// Records the slot as config_error without calling the model and disables the job, keeping the message on it
+// (jobs.config_error) until the owner saves or re-enables it. Failure counts are untouched.
+async function disableMisconfigured(
+  job: Job,
+  lock: JobLock,
+  heartbeat: LockHeartbeat,
+  message: string,
+  log: Logger,
+  manual: RunRequestClaim | null,
+): Promise<JobOutcome> {
+  const scheduledFor = manual ? manual.requestedAt : job.nextRunAt;
+  const runHistoryId = await prisma.runHistory
+    .create({
+      data: {
+        jobId: job.id,
+        promptVersionId: job.publishedPromptVersionId,
+        scheduledFor,
+        status: "config_error",
+        errorMessage: truncate(message, ERROR_MAX),
+        isPreview: false,
+        tags: job.tags,
+      },
+      select: { id: true },
+    })
+    .then((run) => run.id)
+    .catch((err: unknown) => {
+      if (isRecord(err) && err.code === "P2002") {
+        return null;
+      }
+      throw err;
+    });
+
+  await heartbeat.stop();
+  await prisma.job.updateMany({
+    where: { id: job.id, lockedAt: lock.lockedAt },
+    data: { lockedAt: null, enabled: false, configError: truncate(message, ERROR_MAX) },
+  });
+  log.warn("job disabled: configuration error", { scheduled_for: scheduledFor, error: message });
+  return { status: "config_error", disabled: true, runHistoryId };
+}
+
+// Records the slot as budget_exceeded without calling the model and moves the job to its next slot; the failure
+// streak is untouched. The owner is told once per month through this job's channel.
+async function skipOverBudget(
+  job: Job,
+  lock: JobLock,
+  heartbeat: LockHeartbeat,
+  budget: Awaited<ReturnType<typeof checkMonthlyBudget>>,
+  log: Logger,
+  manual: RunRequestClaim | null,
+): Promise<JobOutcome> {
+  const scheduledFor = manual ? manual.requestedAt : job.nextRunAt;
+  const oneShot = job.scheduleType === "once";
+  const notice = formatBudgetNotice(budget);
+
+  let nextRunAt = job.nextRunAt;
+  if (!manual) {
+    try {
+      nextRunAt = nextRunAfter(job, scheduledFor);
+    } catch {
+      nextRunAt = new Date(nowMs() + 10 * 60 * 1000);
+    }
+  }
+
+  const runHistoryId = await prisma.runHistory
+    .create({
+      data: {
+        jobId: job.id,
+        promptVersionId: job.publishedPromptVersionId,
+        scheduledFor,
+        status: "budget_exceeded",
+        errorMessage: truncate(notice, ERROR_MAX),
+        isPreview: false,
+        tags: job.tags,
+      },
+      select: { id: true },
+    })
+    .then((run) => run.id)
+    .catch((err: unknown) => {
+      // A unique violation means this slot was already recorded.
+      if (isRecord(err) && err.code === "P2002") {
+        return null;
+      }
+      throw err;
+    });
+
+  await heartbeat.stop();
+  await prisma.job.updateMany({
+    where: { id: job.id, lockedAt: lock.lockedAt },
+    data: manual ? { lockedAt: null } : { lockedAt: null, nextRunAt, ...(oneShot ? { enabled: false } : {}) },
+  });
+  log.warn("job run skipped: monthly budget exceeded", {
+    scheduled_for: scheduledFor,
+    month: budget.month,
+    spent_usd: budget.spentUsd,
+    limit_usd: budget.limitUsd,
+  });
+
+  if (job.channelType !== ChannelType.in_app && (await claimBudgetNotice(job.userId, budget.month))) {
+    try {
+      await sendChannelMessage(await runnableJobChannel(job), formatRunTitle(job.name, scheduledFor, job.timezone ?? "UTC"), notice, {
+        userAgent: job.userAgent,
+        meta: { kind: "budget_exceeded", jobId: job.id, runHistoryId, month: budget.month, tags: job.tags },
+      });
+      log.info("budget notice sent", { month: budget.month });
+    } catch (err) {
+      log.warn("budget notice failed", { error: err });
+    }
+  }
+  return { status: "budget_exceeded", runHistoryId };
+}
+
+// A slot whose run is still "running" when its job is claimed again was interrupted: the previous worker lost the
+// job lock (crash, kill) before finishing it. Delivery state lives in the run record, outside the job update, so
+// the run is finished from there instead of being generated and delivered again: a recorded successful attempt
+// means the message went out, and an undelivered output goes to the outbox, which skips the parts already sent
+// (delivered_parts). Returns what was done, or null when the slot was finished normally.
+async function recoverInterruptedRun(
+  job: Pick<Job, "id" | "channelType">,
+  scheduledFor: Date,
+  log: Logger,
+): Promise<"delivered" | "resumed" | "finished" | "lost" | null> {
+  const run = await prisma.runHistory.findFirst({
+    where: { jobId: job.id, scheduledFor, isPreview: false, status: "running", deliverAt: null, deliveredAt: null },
+    select: { id: true, outputText: true, deliveredParts: true, deliverySkipReason: true, throttledAt: true },
+  });
+  if (!run) {
+    return null;
+  }
+  const runLog = log.with({ run_id: run.id });
+
+  const sent = await prisma.deliveryAttempt.findFirst({
+    where: { runHistoryId: run.id, status: "success" },
+    orderBy: { attempt: "desc" },
+    select: { attempt: true, createdAt: true },
+  });
+  if (sent) {
+    await prisma.runHistory.update({
+      where: { id: run.id },
+      data: { status: "success", deliveredAt: sent.createdAt, deliveryAttempts: sent.attempt, deliveryLastError: null },
+    });
+    runLog.info("interrupted run recovered: already delivered", { attempts: sent.attempt });
+    return "delivered";
+  }
+  if (run.outputText == null) {
+    await prisma.runHistory.update({
+      where: { id: run.id },
+      data: { status: "cancelled", errorMessage: "The worker stopped during the run" },
+    });
+    runLog.warn("interrupted run recovered: no output to deliver");
+    return "lost";
+  }
+  if (job.channelType === ChannelType.in_app || run.deliverySkipReason || run.throttledAt) {
+    await prisma.runHistory.update({
+      where: { id: run.id },
+      data: { status: "success", ...(job.channelType === ChannelType.in_app ? { deliveredAt: clock().now() } : {}) },
+    });
+    runLog.info("interrupted run recovered: nothing to deliver");
+    return "finished";
+  }
+  await prisma.runHistory.update({ where: { id: run.id }, data: { deliverAt: clock().now() } });
+  runLog.warn("interrupted run recovered: delivery resumed", { parts_delivered: run.deliveredParts });
+  return "resumed";
+}
+
+// Durable retries apply to webhook channels with a retry schedule, and to any channel that already delivered some
+// parts of a multi-part message (PARTIAL_DELIVERY_RETRY_SCHEDULE): the outbox resumes after the delivered parts,
+// where failing the run would regenerate the output and send every part again on the job's failure retry. Other
+// failures fail after the immediate retries.
+function durableRetryAt(job: Pick<Job, "channelType" | "webhookRetrySchedule">, retriesScheduled: number, partial: boolean) {
+  const schedule =
+    job.channelType === ChannelType.webhook && job.webhookRetrySchedule?.trim()
+      ? job.webhookRetrySchedule
+      : partial
+        ? workerConfig().partialDeliveryRetrySchedule
+        : null;
+  return schedule ? nextDeliveryRetryAt(schedule, retriesScheduled) : null;
+}
+
+async function deliverDueRun(runHistoryId: string) {
+  const run = await prisma.runHistory.findUnique({ where: { id: runHistoryId }, include: { job: { include: { user: { select: { plan: true } } } } } });
+  if (!run) {
+    return;
+  }
+  const { job } = run;
+  const log = logger.with({ job_id: job.id, job_name: job.name, channel_type: job.channelType, run_id: run.id });
+
+  // The throttle applies to the first delivery only; a durable retry re-sends a message already counted.
+  if (run.deliveryAttempts === 0 && !(await hasThrottleRoom(job))) {
+    await prisma.runHistory.update({ where: { id: run.id }, data: { status: "success", throttledAt: clock().now(), deliverAt: null } });
+    log.info("delivery throttled", { throttle_limit: job.throttleLimit, throttle_window: job.throttleWindow });
+    return;
+  }
+
+  const hookContext: RunContext = {
+    job,
+    runHistoryId: run.id,
+    scheduledFor: run.scheduledFor ?? run.runAt,
+    manual: false,
+    log,
+  };
+  let attempts = run.deliveryAttempts;
+  let lastError: string | null = null;
+  let retryable = false;
+  let partial = false;
+  const deliveryStartedAt = nowMs();
+  try {
+    const { stored, ...message } = outboxMessage(run, job);
+    // A stored message already went through the hooks when its first part was sent.
+    const hooked: PreDeliveryInput = stored ? message : await applyPreDelivery(hookContext, message);
+    if (hooked.skip?.trim()) {
+      await prisma.runHistory.update({
+        where: { id: run.id },
+        data: { status: "success", deliverySkipReason: truncate(hooked.skip.trim(), 64), deliverAt: null },
+      });
+      log.info("delivery skipped", { reason: hooked.skip });
+      return;
+    }
+    const attachments = await loadRunAttachments(run.id).catch((err) => {
+      log.warn("run artifacts not loaded", { error: err });
+      return [];
+    });
+    const delivery = await deliverWithRetryAndReceipts(
+      run.id,
+      await runnableJobChannel(job),
+      hooked.title,
+      hooked.body,
+      {
+        citations: Array.isArray(run.citations) ? (run.citations as { url: string; title?: string }[]) : [],
+        attachments,
+        usedWebSearch: run.usedWebSearch,
+        meta: {
+          jobId: job.id,
+          jobName: job.name,
+          promptVersionId: run.promptVersionId,
+          scheduledFor: run.scheduledFor?.toISOString() ?? null,
+          llmModel: run.llmModel,
+          llmUsage: run.llmUsage,
+          tags: run.tags,
+        },
+        userAgent: job.userAgent,
+        plan: job.user.plan,
+        log,
+        fullOutputLink: job.fullOutputLink,
+        firstAttempt: run.deliveryAttempts + 1,
+        deliveredParts: run.deliveredParts,
+      },
+    );
+    attempts = delivery.attempts;
+    lastError = delivery.lastError;
+    retryable = delivery.retryable;
+    partial = delivery.partial;
+  } catch (err) {
+    lastError = truncate(err instanceof Error ? err.message : String(err), ERROR_MAX);
+  }
+
+  // The latest delivery's wall time; earlier outbox attempts are in delivery_attempts.
+  const deliveryDurationMs = nowMs() - deliveryStartedAt;
+  const retryAt = lastError && retryable ? durableRetryAt(job, run.deliveryRetries, partial) : null;
+  await notifyPostDelivery(hookContext, { delivered: !lastError, error: lastError, attempts, deferred: !!retryAt });
+  if (retryAt) {
+    await prisma.runHistory.update({
+      where: { id: run.id },
+      data: {
+        deliverAt: retryAt,
+        deliveryRetries: { increment: 1 },
+        deliveryAttempts: attempts,
+        deliveryLastError: lastError,
+        deliveryDurationMs,
+      },
+    });
+    log.warn("delivery retry scheduled", { retry: run.deliveryRetries + 1, retry_at: retryAt, error: lastError });
+    return;
+  }
+
+  await prisma.runHistory.update({
+    where: { id: run.id },
+    data: lastError
+      ? {
+          status: failureRunStatus(lastError, { partialDelivery: partial }),
+          errorMessage: lastError,
+          failureStage: "channel",
+          deliveryAttempts: attempts,
+          deliveryDurationMs,
+          deliverAt: null,
+        }
+      : {
+          status: "success",
+          deliveredAt: clock().now(),
+          deliveryAttempts: attempts,
+          deliveryLastError: null,
+          deliveryDurationMs,
+          deliverAt: null,
+          deliveryTitle: null,
+          deliveryBody: null,
         },
+  });
+  if (lastError) {
+    log.error("deferred delivery failed", { error: lastError });
+    await recordDeadLetter({
+      jobId: job.id,
+      userId: job.userId,
+      runHistoryId: run.id,
+      reason: "delivery_failed",
+      outputText: run.outputText,
+      errorMessage: lastError,
+    });
+  } else {
+    log.info("deferred delivery succeeded", { attempts });
+  }
+}
+
+// Sends a one-line "postponed" notice for critical (outage_notice) jobs held by a provider outage, once per slot.
+async function sendOutageNotices(limit: number) {
+  const environment = workerEnvironment();
+  const rows = await prisma.$queryRaw<Array<{ id: string }>>`
+    WITH candidate AS (
+      SELECT id
+      FROM jobs
+      WHERE enabled = true
+        AND outage_notice = true
+        AND environment = ${environment}
+        AND channel_type <> 'in_app'
+        AND next_run_at <= now()
+        AND (outage_notified_for IS NULL OR outage_notified_for <> next_run_at)
+      ORDER BY next_run_at
+      LIMIT ${limit}
+      FOR UPDATE SKIP LOCKED
+    )
+    UPDATE jobs
+    SET outage_notified_for = jobs.next_run_at
+    FROM candidate
+    WHERE jobs.id = candidate.id
+    RETURNING jobs.id;
+  `;
+
+  let sent = 0;
+  for (const { id } of rows) {
+    const job = await prisma.job.findUnique({ where: { id } });
+    if (!job) {
+      continue;
+    }
+    const log = logger.with({ job_id: job.id, job_name: job.name, channel_type: job.channelType });
+    try {
+      await sendChannelMessage(await runnableJobChannel(job), formatRunTitle(job.name, job.nextRunAt, job.timezone ?? "UTC"), OUTAGE_NOTICE_TEXT, {
+        userAgent: job.userAgent,
+        meta: { kind: "outage_notice", jobId: job.id, scheduledFor: job.nextRunAt.toISOString(), tags: job.tags },
       });
-      return { updated: true, disabled: disable, quotaBlocked: false };
+      sent++;
+      log.info("outage notice sent", { scheduled_for: job.nextRunAt });
+    } catch (err) {
+      log.warn("outage notice failed", { error: err });
+    }
+  }
+  return sent;
+}
+
+// Sends outputs held by a delivery throttle as one digest message per job once the throttle window has room.
+// The runs are claimed by setting delivered_at (shared by the whole digest) and released again if sending fails.
+async function sendThrottleDigests(limit: number) {
+  const environment = workerEnvironment();
+  const jobs = await prisma.job.findMany({
+    where: {
+      environment,
+      runHistories: { some: { throttledAt: { not: null }, deliveredAt: null, isPreview: false } },
+    },
+    include: { user: { select: { plan: true } } },
+    take: limit,
+  });
+
+  let sent = 0;
+  for (const job of jobs) {
+    const log = logger.with({ job_id: job.id, job_name: job.name, channel_type: job.channelType });
+    if (job.throttleLimit && !(await hasThrottleRoom(job))) {
+      continue;
+    }
+    const held = { jobId: job.id, throttledAt: { not: null }, deliveredAt: null, isPreview: false } satisfies Prisma.RunHistoryWhereInput;
+    const runs = await prisma.runHistory.findMany({
+      where: held,
+      orderBy: { runAt: "asc" },
+      take: DIGEST_MAX_RUNS,
+      select: { id: true, runAt: true, outputText: true, outputDiff: true },
     });
-    result.processed++;
-    if (finished.updated) {
+    if (!runs.length) {
+      continue;
+    }
+    const total = await prisma.runHistory.count({ where: held });
+    const runIds = runs.map((run) => run.id);
+    const digestAt = clock().now();
+    const claimed = await prisma.runHistory.updateMany({ where: { id: { in: runIds }, deliveredAt: null }, data: { deliveredAt: digestAt } });
+    if (claimed.count !== runs.length) {
+      // Another worker is sending this digest.
+      await prisma.runHistory.updateMany({ where: { id: { in: runIds }, deliveredAt: digestAt }, data: { deliveredAt: null } });
+      continue;
+    }
+
+    const text = formatThrottleDigest(
+      runs.map((run) => ({
+        runAt: run.runAt,
+        output: truncateOutput(diffDeliveryBody(run.outputText ?? "", run.outputDiff, job.deliveryDiffOnly), job.maxOutputChars),
+      })),
+      {
+        limit: job.throttleLimit ?? 0,
+        window: normalizeThrottleWindow(job.throttleWindow),
+        timeZone: job.timezone ?? "UTC",
+        remaining: total - runs.length,
+      },
+    );
+    try {
+      await sendChannelMessage(await runnableJobChannel(job), formatRunTitle(job.name, digestAt, job.timezone ?? "UTC"), text, {
+        userAgent: job.userAgent,
+        plan: job.user.plan,
+        meta: { kind: "throttle_digest", jobId: job.id, runIds, tags: job.tags },
+      });
+      sent++;
+      log.info("throttle digest sent", { runs: runs.length, remaining: total - runs.length });
+    } catch (err) {
+      await prisma.runHistory.updateMany({ where: { id: { in: runIds }, deliveredAt: digestAt }, data: { deliveredAt: null } });
+      log.warn("throttle digest failed", { error: err });
+    }
+  }
+  return sent;
+}
+
+// Delivers runs whose output was generated earlier and held until their deliver_at time.
+async function deliverDueRuns(opts: { startedAt: number; timeBudgetMs: number; maxJobs: number }) {
+  let delivered = 0;
+  while (delivered < opts.maxJobs && !shuttingDown && !draining && nowMs() - opts.startedAt < opts.timeBudgetMs) {
+    const runHistoryId = await claimDueDelivery();
+    if (!runHistoryId) {
+      break;
+    }
+    await withSpan("promptloop.run.deliver_deferred", { "promptloop.run.id": runHistoryId }, () => deliverDueRun(runHistoryId));
+    delivered++;
+  }
+  return delivered;
+}
+
+async function processRunRequest(claim: NonNullable<Awaited<ReturnType<typeof claimRunRequest>>>, runnerId?: string) {
+  claim.lock.runRequestId = claim.request.id;
+  activeLocks.add(claim.lock);
+  const heartbeat = startLockHeartbeat(claim.lock);
+  let outcome: JobOutcome;
+  try {
+    outcome = await withSpan(
+      "promptloop.job.run_now",
+      { "promptloop.job.id": claim.lock.id, "promptloop.run_request.id": claim.request.id },
+      (span) => processLockedJob(claim.lock, { runnerId, runRequest: claim.request }, span, heartbeat),
+    );
+  } finally {
+    await heartbeat.stop();
+    activeLocks.delete(claim.lock);
+  }
+  await prisma.runRequest.update({
+    where: { id: claim.request.id },
+    data: { status: "done", finishedAt: clock().now(), runHistoryId: outcome.runHistoryId ?? null },
+  });
+  return outcome;
+}
+
+// Runs queued "run now" requests, oldest first, within the tick's job limit and time budget.
+async function runRequestedJobs(opts: RunDueJobsOptions & { startedAt: number }) {
+  let ran = 0;
+  while (ran < opts.maxJobs && !shuttingDown && !draining && nowMs() - opts.startedAt < opts.timeBudgetMs) {
+    const claim = await claimRunRequest();
+    if (!claim) {
+      break;
+    }
+    recordClaim();
+    await processRunRequest(claim, opts.runnerId);
+    ran++;
+  }
+  return ran;
+}
+
+// Executes one job synchronously (CLI `run`): the run is queued and processed here as a run-now request, so it is
+// recorded the same way and leaves the schedule alone. "queued" means another worker holds the job's lock; the
+// request then runs on a later tick.
+export async function runJobNow(jobId: string, opts: { runnerId?: string; config?: WorkerConfig } = {}) {
+  return configStore.run(opts.config ?? workerConfigFromEnv(), () => runRequestedJob(jobId, opts.runnerId));
+}
+
+async function runRequestedJob(jobId: string, runnerId: string | undefined) {
+  const job = await prisma.job.findUnique({ where: { id: jobId }, select: { id: true, userId: true } });
+  if (!job) {
+    return null;
+  }
+  activeTicks++;
+  try {
+    const request = await prisma.runRequest.create({ data: { jobId: job.id, userId: job.userId } });
+    const claim = await claimRunRequest(request.id);
+    if (!claim) {
+      return { requestId: request.id, status: "queued" as const, run: null };
+    }
+    const outcome = await processRunRequest(claim, runnerId);
+    const run = outcome.runHistoryId
+      ? await prisma.runHistory.findUnique({
+          where: { id: outcome.runHistoryId },
+          select: { id: true, status: true, outputText: true, errorMessage: true, deliveredAt: true, llmModel: true, costUsd: true },
+        })
+      : null;
+    return { requestId: request.id, status: outcome.status, run };
+  } finally {
+    activeTicks--;
+  }
+}
+
+type RunDueJobsOptions = {
+  timeBudgetMs: number;
+  maxJobs: number;
+  runnerId?: string;
+  concurrency?: number;
+};
+
+// One worker tick with explicit settings: releases dead locks, sends deferred deliveries and runs due jobs until
+// config.maxJobs or config.timeBudgetMs is reached. The runner's own settings come from config only.
+export async function runWorker(config: WorkerConfig, opts: { runnerId?: string } = {}): Promise<RunDueJobsResult> {
+  return configStore.run(config, () =>
+    runTracked({ maxJobs: config.maxJobs, timeBudgetMs: config.timeBudgetMs, concurrency: config.concurrency, runnerId: opts.runnerId }),
+  );
+}
+
+// runWorker() with the settings from the environment; opts override the tick limits.
+export async function runDueJobs(opts: RunDueJobsOptions): Promise<RunDueJobsResult> {
+  const { runnerId, ...limits } = opts;
+  return runWorker({ ...workerConfigFromEnv(), ...limits, concurrency: limits.concurrency ?? 1 }, { runnerId });
+}
+
+async function runTracked(opts: RunDueJobsOptions): Promise<RunDueJobsResult> {
+  activeTicks++;
+  const startedAt = nowMs();
+  try {
+    const result = await runTick(opts);
+    recordTickMetrics(result, nowMs() - startedAt);
+    return result;
+  } catch (err) {
+    recordTickError();
+    void reportError(err, { stage: "tick", worker_env: workerEnvironment() });
+    throw err;
+  } finally {
+    activeTicks--;
+    await pushMetrics(workerEnvironment());
+  }
+}
+
+async function runTick(opts: RunDueJobsOptions): Promise<RunDueJobsResult> {
+  const startedAt = nowMs();
+  const result: RunDueJobsResult = {
+    processed: 0,
+    success: 0,
+    fail: 0,
+    disabled: 0,
+    duplicates: 0,
+    skipped: 0,
+    quietDeferred: 0,
+    waitingOnUpstream: 0,
+    quotaBlocked: 0,
+    budgetExceeded: 0,
+    configErrors: 0,
+    deferredDeliveries: 0,
+    expiredArtifacts: 0,
+    expiredCacheEntries: 0,
+    prunedRuns: 0,
+    dormantPaused: 0,
+    runRequests: 0,
+    secretsReencrypted: 0,
+    degraded: false,
+    outageNotices: 0,
+    throttleDigests: 0,
+    credentialChecks: 0,
+    reapedLocks: 0,
+    channelConfigsUpgraded: 0,
+    slowRuns: 0,
+    maintenanceLeader: false,
+    clockOffsetMs: 0,
+    claimBatches: 0,
+    queueDepth: 0,
+    queueDrained: false,
+    nextDueAt: null,
+  };
+  const concurrency = Math.max(1, Math.floor(opts.concurrency ?? 1));
+  installShutdownHook();
+  result.clockOffsetMs = await syncClockWithDatabase().catch((err) => {
+    logger.warn("database clock sync failed", { error: err });
+    return clockOffsetMs();
+  });
+  await startWorkerHeartbeat(workerEnvironment()).catch((err) => logger.warn("worker heartbeat failed", { error: err }));
+  // Housekeeping runs on one worker per tick; the others go straight to deliveries and due jobs.
+  result.maintenanceLeader =
+    (await asMaintenanceLeader(async () => {
+      result.reapedLocks = await reapDeadWorkerLocks().catch(stepFailed("dead worker lock reaping failed", 0));
+      result.expiredArtifacts = await pruneExpiredArtifacts().catch(stepFailed("expired artifact cleanup failed", 0));
+      result.expiredCacheEntries = await pruneResponseCache().catch(stepFailed("response cache cleanup failed", 0));
+      result.prunedRuns = await pruneRunHistories().catch(stepFailed("run history pruning failed", 0));
+      result.dormantPaused = await pauseDormantJobs(opts.maxJobs).catch(stepFailed("dormant job check failed", 0));
+      result.secretsReencrypted = await reencryptStaleSecrets(opts.maxJobs).catch(stepFailed("secret re-encryption failed", 0));
+      result.channelConfigsUpgraded = await upgradeChannelConfigs(opts.maxJobs).catch(stepFailed("channel config upgrade failed", 0));
+      result.credentialChecks = await runCredentialChecks(opts.maxJobs).catch(stepFailed("channel credential checks failed", 0));
+      return true;
+    }).catch(stepFailed("maintenance leader election failed", null))) ?? false;
+  result.deferredDeliveries = await deliverDueRuns({ startedAt, timeBudgetMs: opts.timeBudgetMs, maxJobs: opts.maxJobs });
+  result.throttleDigests = await sendThrottleDigests(opts.maxJobs).catch(stepFailed("throttle digests failed", 0));
+
+  // During a provider outage only one probe job runs per probe interval. Held jobs stay due, so on
+  // recovery their catch-up policy decides whether missed slots are skipped, run once, or backfilled.
+  let maxJobs = opts.maxJobs;
+  const health = await getProviderHealth();
+  if (health.degradedSince) {
+    result.degraded = true;
+    result.outageNotices = await sendOutageNotices(opts.maxJobs);
+    maxJobs = (await claimOutageProbe()) ? 1 : 0;
+    logger.warn("provider outage: LLM dispatch paused", {
+      provider: health.provider,
+      degraded_since: health.degradedSince,
+      probe: maxJobs > 0,
+      outage_notices: result.outageNotices,
+    });
+  } else {
+    // Run-now requests go first and use up part of the tick's job limit; during an outage they stay queued.
+    result.runRequests = await runRequestedJobs({ ...opts, startedAt });
+    maxJobs = Math.max(0, maxJobs - result.runRequests);
+  }
+  // Counts claims in flight as well as finished jobs so parallel slots never exceed maxJobs.
+  let claimed = 0;
+  const fairness = tenantFairnessPolicy();
+  const share = newTenantShare(
+    fairness,
+    fairness.perHour && maxJobs > 0
+      ? await usersOverHourlyCap(fairness.perHour).catch((err) => {
+          logger.warn("hourly run cap lookup failed", { error: err });
+          return [];
+        })
+      : [],
+  );
+
+  // Slots take claimed jobs from a shared queue. When it runs empty, one claim statement locks a batch sized to the
+  // idle slots (at most WORKER_CLAIM_BATCH) and the other idle slots wait for it rather than claiming on their own.
+  // Every run still uses its own queries/transactions.
+  const claimBatch = workerConfig().claimBatch;
+  const ready: Array<{ lock: JobLock; heartbeat: LockHeartbeat }> = [];
+  let running = 0;
+  let claiming: Promise<void> | null = null;
+
+  const claimJobs = async () => {
+    const limit = Math.min(claimBatch, concurrency - running, maxJobs - claimed);
+    if (limit <= 0) {
+      return;
+    }
+    claimed += limit;
+    let locks: JobLock[] = [];
+    try {
+      const batch = await withClaimRetry(() => lockDueJobs(limit, share));
+      locks = batch.locks;
+      result.claimBatches++;
+      result.queueDepth = batch.remaining;
+      if (!locks.length || batch.remaining === 0) {
+        result.queueDrained = true;
+      }
+    } finally {
+      claimed -= limit - locks.length;
+    }
+    recordClaim(locks.length);
+    for (const lock of locks) {
+      activeLocks.add(lock);
+      ready.push({ lock, heartbeat: startLockHeartbeat(lock) });
+    }
+  };
+
+  const nextJob = async () => {
+    if (shuttingDown || draining || nowMs() - startedAt >= opts.timeBudgetMs) {
+      return null;
+    }
+    while (!ready.length) {
+      if (result.queueDrained || claimed >= maxJobs) {
+        return null;
+      }
+      claiming ??= claimJobs().finally(() => {
+        claiming = null;
+      });
+      await claiming;
+    }
+    return ready.shift() ?? null;
+  };
+
+  const runSlot = async () => {
+    while (true) {
+      const next = await nextJob();
+      if (!next) {
+        return;
+      }
+      const { lock, heartbeat } = next;
+      running++;
+      let outcome: JobOutcome;
+      try {
+        outcome = await withSpan("promptloop.job.run", { "promptloop.job.id": lock.id }, async (span) => {
+          const jobOutcome = await processLockedJob(lock, opts, span, heartbeat);
+          span.setAttribute("promptloop.run.status", jobOutcome.status);
+          return jobOutcome;
+        });
+      } catch (err) {
+        void reportError(err, { stage: "job", job_id: lock.id, worker_env: workerEnvironment() });
+        throw err;
+      } finally {
+        await heartbeat.stop();
+        activeLocks.delete(lock);
+        running--;
+      }
+
+      result.processed++;
+      if (outcome.slow) {
+        result.slowRuns++;
+      }
+      if (outcome.status === "duplicate") {
+        result.duplicates++;
+        continue;
+      }
+      if (outcome.status === "skipped") {
+        result.skipped++;
+        continue;
+      }
+      if (outcome.status === "quiet") {
+        result.quietDeferred++;
+        continue;
+      }
+      if (outcome.status === "waiting") {
+        result.waitingOnUpstream++;
+        continue;
+      }
+      if (outcome.status === "config_error") {
+        result.configErrors++;
+        result.disabled++;
+        continue;
+      }
+      if (outcome.status === "budget_exceeded") {
+        result.budgetExceeded++;
+        continue;
+      }
+      if (outcome.status === "success") {
+        result.success++;
+        continue;
+      }
       result.fail++;
-      if (finished.quotaBlocked) {
+      if (outcome.quotaBlocked) {
         result.quotaBlocked++;
       }
-      if (finished.disabled) {
+      if (outcome.disabled) {
         result.disabled++;
       }
-    } else {
-      result.fail++;
     }
+  };
+
+  const slots = await Promise.allSettled(Array.from({ length: concurrency }, () => runSlot()));
+  await releaseUnstartedJobs(ready.splice(0));
+  const failed = slots.find((slot): slot is PromiseRejectedResult => slot.status === "rejected");
+  if (failed) {
+    throw failed.reason;
+  }
+  if (result.queueDrained) {
+    result.nextDueAt = await nextDueRunAt().catch((err) => {
+      logger.warn("next due run lookup failed", { error: err });
+      return null;
+    });
   }
+  return result;
 }
@@ -5,19 +5,38 @@ export type JobFormState = {
   prompt: string;
   postPrompt: string;
   postPromptEnabled: boolean;
+  // Follow-up prompts run in order after the main prompt; each sees the previous output as {{output}}.
+  pipelineSteps: Array<{ name: string; template: string }>;
+  // Input source URLs, one per line; fetched before each run and appended to the prompt.
+  inputUrls: string;
+  // Optional persona/tone/format instructions sent as part of the system prompt.
+  systemPrompt: string;
   variables: string;
   llmModel: string;
+  // Comma-separated models tried in order when the model fails; blank uses the deployment default.
+  fallbackModels: string;
   useWebSearch: boolean;
   webSearchMode: WebSearchMode;
-  scheduleType: "daily" | "weekly" | "cron";
+  codeInterpreter: boolean;
+  // Comma-separated vector store ids for file_search; blank disables it.
+  fileSearchVectorStores: string;
+  scheduleType: "daily" | "weekly" | "cron" | "once";
   time: string;
   scheduleTimeZone: string;
   timeIsUtc: boolean;
   dayOfWeek?: number;
+  weekdaysOnly: boolean;
   cron?: string;
+  // datetime-local value ("YYYY-MM-DDTHH:mm") in scheduleTimeZone, for one-time jobs.
+  runAt: string;
+  catchupPolicy: "skip" | "run_once" | "run_all";
+  quietHoursStart: string;
+  quietHoursEnd: string;
+  // Comma-separated YYYY-MM-DD dates.
+  blackoutDates: string;
   channel:
-    | { type: "discord"; config: { webhookUrl: string } }
-    | { type: "telegram"; config: { botToken: string; chatId: string } }
+    | { type: "discord"; config: { webhookUrl: string; threadId?: string; threadName?: string } }
+    | { type: "telegram"; config: { botToken: string; chatId: string; messageThreadId?: string } }
     | { type: "in_app" }
     | {
         type: "webhook";
@@ -26,10 +45,136 @@ export type JobFormState = {
           method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
           headers: string;
           payload: string;
+          graphqlQuery?: string;
+          bodyFormat?: "json" | "xml";
+          gzip?: boolean;
+          signingSecret?: string;
+          captureResponse?: boolean;
+          proxyUrl?: string;
+          caBundle?: string;
+          insecureSkipVerify?: boolean;
         };
-      };
-  channelPrefillSource?: "last_job" | null;
+      }
+    | { type: "home_assistant"; config: { baseUrl: string; token: string; service: string } }
+    | {
+        type: "elasticsearch";
+        config: { url: string; index: string; apiKey: string; username: string; password: string };
+      }
+    | { type: "clickhouse"; config: { url: string; table: string; username: string; password: string } }
+    | {
+        type: "bigquery";
+        config: { projectId: string; dataset: string; table: string; serviceAccountJson: string };
+      }
+    | {
+        type: "redis";
+        config: { restUrl: string; token: string; mode: "set" | "xadd"; key: string; ttlSeconds: string };
+      }
+    | {
+        type: "pushover";
+        config: { appToken: string; userKey: string; priority: "" | "-2" | "-1" | "0" | "1"; sound: string };
+      }
+    | {
+        type: "twilio";
+        config: { accountSid: string; authToken: string; from: string; to: string; mode: "summary" | "full" };
+      }
+    | { type: "google_chat"; config: { webhookUrl: string; format: "card" | "text"; threadKey: string } }
+    | { type: "aws"; config: { service: "sns" | "sqs"; target: string; accessKeyId: string; secretAccessKey: string } }
+    | {
+        type: "kafka";
+        config: { restUrl: string; api: "v3" | "v2"; clusterId: string; topic: string; username: string; password: string };
+      }
+    | {
+        type: "nats";
+        config: { url: string; subject: string; mode: "core" | "jetstream"; username: string; password: string; token: string };
+      }
+    | {
+        type: "mqtt";
+        config: {
+          brokerUrl: string;
+          topic: string;
+          qos: "0" | "1" | "2";
+          retain: "on" | "off";
+          format: "json" | "text";
+          username: string;
+          password: string;
+        };
+      }
+    | { type: "file"; config: { target: "file" | "stdout"; path: string } }
+    | { type: "notion"; config: { token: string; target: "page" | "database"; parentId: string; titleProperty: string } }
+    | { type: "google_sheets"; config: { spreadsheetId: string; sheet: string; columns: string; serviceAccountJson: string } }
+    | {
+        type: "jira";
+        config: {
+          siteUrl: string;
+          email: string;
+          apiToken: string;
+          projectKey: string;
+          issueType: string;
+          summaryTemplate: string;
+          labels: string;
+        };
+      }
+    | { type: "plugin"; config: { plugin: string; settings: string } };
+  channelPrefillSource?: "last_job" | "default_channel" | null;
+  // Saved channel the job delivers to; "" uses the channel configured above.
+  channelId: string;
+  // Read-only; the user's saved channels offered by the channel picker.
+  savedChannels: Array<{ id: string; name: string; type: Exclude<JobFormState["channel"]["type"], "in_app"> }>;
   enabled: boolean;
+  userAgent: string;
+  deliveryDelayMinutes: string;
+  acceptReplies: boolean;
+  outageNotice: boolean;
+  failureNotice: boolean;
+  includePreviousOutput: boolean;
+  previousOutputCount: string;
+  deliverIf: "always" | "changed" | "nonempty" | "regex";
+  deliverIfPattern: string;
+  deliveryDiff: "off" | "unified" | "summary";
+  deliveryDiffOnly: boolean;
+  fullOutputLink: boolean;
+  // Blank means no delivery throttle.
+  throttleLimit: string;
+  throttleWindow: "hour" | "day";
+  // Percentage (0-100) of runs scored against qualityRubric.
+  qualitySampleRate: string;
+  qualityRubric: string;
+  // Blank uses the deployment default.
+  historyRetentionDays: string;
+  // Blank disables the response cache.
+  responseCacheTtlMinutes: string;
+  // Blank uses the deployment default; 0 never stops the job.
+  autoDisableThreshold: string;
+  // "" uses the deployment default.
+  autoDisablePolicy: "" | "disable" | "pause";
+  // Blank means no slow-run alert.
+  expectedRuntimeSeconds: string;
+  slowRunNotice: boolean;
+  maxOutputChars: string;
+  // Blank means no audio delivery.
+  ttsVoice: string;
+  // Generation settings; blank keeps the provider default.
+  maxOutputTokens: string;
+  temperature: string;
+  reasoningEffort: string;
+  verbosity: string;
+  showHeader: boolean;
+  headerTemplate: string;
+  headerLocale: string;
+  // Blank means the job does not wait for another job.
+  dependsOnJobId: string;
+  includeUpstreamOutput: boolean;
+  // The owner's other jobs, for the upstream job picker.
+  upstreamJobs: Array<{ id: string; name: string }>;
+  webhookRetrySchedule: string;
+  // Comma-separated in the editor; saved as a string array.
+  tags: string;
+  environment: string;
+  priority: "low" | "normal" | "high";
+  // Blank means no group.
+  concurrencyGroup: string;
+  // Read-only; set by the edit page so the reply endpoint can be shown.
+  replyEndpointPath?: string;
   preview: {
     loading: boolean;
     status: "idle" | "success" | "fail";
@@ -47,18 +192,87 @@ export const defaultJobFormState: JobFormState = {
   prompt: "",
   postPrompt: "",
   postPromptEnabled: false,
+  pipelineSteps: [],
+  inputUrls: "",
+  systemPrompt: "",
   variables: "{}",
   llmModel: DEFAULT_LLM_MODEL,
+  fallbackModels: "",
   useWebSearch: false,
   webSearchMode: DEFAULT_WEB_SEARCH_MODE,
+  codeInterpreter: false,
+  fileSearchVectorStores: "",
   scheduleType: "daily",
   time: "09:00",
   scheduleTimeZone: "",
   timeIsUtc: false,
   dayOfWeek: 1,
+  weekdaysOnly: false,
   cron: "",
+  runAt: "",
+  catchupPolicy: "run_once",
+  quietHoursStart: "",
+  quietHoursEnd: "",
+  blackoutDates: "",
   channel: { type: "discord", config: { webhookUrl: "" } },
   channelPrefillSource: null,
+  channelId: "",
+  savedChannels: [],
   enabled: true,
+  userAgent: "",
+  deliveryDelayMinutes: "",
+  acceptReplies: false,
+  outageNotice: false,
+  failureNotice: false,
+  includePreviousOutput: false,
+  previousOutputCount: "1",
+  deliverIf: "always",
+  deliverIfPattern: "",
+  deliveryDiff: "off",
+  deliveryDiffOnly: false,
+  fullOutputLink: false,
+  throttleLimit: "",
+  throttleWindow: "hour",
+  qualitySampleRate: "0",
+  qualityRubric: "",
+  historyRetentionDays: "",
+  responseCacheTtlMinutes: "",
+  autoDisableThreshold: "",
+  autoDisablePolicy: "",
+  expectedRuntimeSeconds: "",
+  slowRunNotice: false,
+  maxOutputChars: "",
+  ttsVoice: "",
+  maxOutputTokens: "",
+  temperature: "",
+  reasoningEffort: "",
+  verbosity: "",
+  showHeader: true,
+  headerTemplate: "",
+  headerLocale: "",
+  dependsOnJobId: "",
+  includeUpstreamOutput: false,
+  upstreamJobs: [],
+  webhookRetrySchedule: "",
+  tags: "",
+  environment: "production",
+  priority: "normal",
+  concurrencyGroup: "",
   preview: { loading: false, status: "idle" },
 };
+
+// Input source lines are "url" or "label | url".
+export function inputsFromForm(inputUrls: string) {
+  return inputUrls
+    .split("\n")
+    .map((line) => line.trim())
+    .filter(Boolean)
+    .map((line) => {
+      const split = line.lastIndexOf("|");
+      return split < 0 ? { url: line } : { label: line.slice(0, split).trim(), url: line.slice(split + 1).trim() };
+    });
+}
+
+export function inputsToForm(inputs: Array<{ url: string; label?: string }>) {
+  return inputs.map((input) => (input.label ? `${input.label} | ${input.url}` : input.url)).join("\n");
+}
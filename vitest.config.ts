@@ -0,0 +1,14 @@
+import { fileURLToPath } from "node:url";
+import { configDefaults, defineConfig } from "vitest/config";
+
+export default defineConfig({
+  resolve: {
+    alias: {
+      "@": fileURLToPath(new URL("./src", import.meta.url)),
+    },
+  },
+  test: {
+    // Integration tests need Docker; they run through vitest.integration.config.ts.
+    exclude: [...configDefaults.exclude, "src/**/*.integration.test.ts"],
+  },
+});
@@ -0,0 +1,19 @@
+import { fileURLToPath } from "node:url";
+import { defineConfig } from "vitest/config";
+
+// End-to-end suite (`npm run test:integration`): a throwaway Postgres in Docker, the real worker loop, the mock
+// model and a local fake channel. Needs Docker, or INTEGRATION_DATABASE_URL pointing at an empty database.
+export default defineConfig({
+  resolve: {
+    alias: {
+      "@": fileURLToPath(new URL("./src", import.meta.url)),
+    },
+  },
+  test: {
+    include: ["src/**/*.integration.test.ts"],
+    globalSetup: ["src/integration/global-setup.ts"],
+    fileParallelism: false,
+    testTimeout: 60_000,
+    hookTimeout: 180_000,
+  },
+});